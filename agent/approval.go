@@ -0,0 +1,249 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"boatman/stream"
+)
+
+// rejectMessage is what the agent is told when the user rejects a tool call
+const rejectMessage = "The user rejected this action. Ask them how to proceed instead of retrying it."
+
+// PendingAction is a tool call paused until the user approves or rejects it
+type PendingAction struct {
+	ID          string    `json:"id"` // The CLI's permission request ID
+	ToolName    string    `json:"toolName"`
+	ToolUseID   string    `json:"toolUseId,omitempty"`
+	Description string    `json:"description"`
+	FilePath    string    `json:"filePath,omitempty"`
+	RequestedAt time.Time `json:"requestedAt"`
+
+	// input is sent back unchanged when the call is approved
+	input map[string]any
+}
+
+// SetApprovalHandler sets a function called with the pending actions whenever they change
+func (s *Session) SetApprovalHandler(handler func([]PendingAction)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onApproval = handler
+}
+
+// PendingActions returns the tool calls waiting for approval, oldest first
+func (s *Session) PendingActions() []PendingAction {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]PendingAction{}, s.pendingActions...)
+}
+
+// Approve lets a pending tool call run. An empty actionID approves the oldest one.
+func (s *Session) Approve(actionID string) error {
+	action, err := s.takePendingAction(actionID)
+	if err != nil {
+		return err
+	}
+
+	// The approval wait doesn't count toward a command's runtime
+	s.mu.Lock()
+	if cmd, ok := s.runningCommands[action.ToolUseID]; ok {
+		cmd.StartedAt = time.Now()
+	}
+	s.mu.Unlock()
+
+	return s.sendPermissionResponse(action.ID, map[string]any{
+		"behavior":     "allow",
+		"updatedInput": action.input,
+	})
+}
+
+// Reject denies a pending tool call and tells the agent the user refused it.
+// An empty actionID rejects the oldest one.
+func (s *Session) Reject(actionID string) error {
+	action, err := s.takePendingAction(actionID)
+	if err != nil {
+		return err
+	}
+	return s.sendPermissionResponse(action.ID, map[string]any{
+		"behavior": "deny",
+		"message":  rejectMessage,
+	})
+}
+
+// takePendingAction removes an action from the queue, resuming the session
+// once nothing is left waiting
+func (s *Session) takePendingAction(actionID string) (PendingAction, error) {
+	s.mu.Lock()
+	index := -1
+	for i, action := range s.pendingActions {
+		if actionID == "" || action.ID == actionID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		s.mu.Unlock()
+		if actionID == "" {
+			return PendingAction{}, fmt.Errorf("no action is waiting for approval")
+		}
+		return PendingAction{}, fmt.Errorf("pending action not found: %s", actionID)
+	}
+
+	action := s.pendingActions[index]
+	s.pendingActions = append(s.pendingActions[:index:index], s.pendingActions[index+1:]...)
+	if len(s.pendingActions) == 0 && s.Status == SessionStatusWaiting {
+		s.setStatus(SessionStatusRunning)
+	}
+	pending, handler := s.pendingActionsLocked()
+	s.mu.Unlock()
+
+	if handler != nil {
+		handler(pending)
+	}
+	return action, nil
+}
+
+// handleControlRequest handles a request the CLI makes over the control
+// protocol. Tool permission checks are queued for the user; anything else is
+// answered with an error so the CLI doesn't wait forever.
+func (s *Session) handleControlRequest(event stream.ControlRequest) {
+	requestID := event.RequestID
+	request := event.Request
+	subtype := request.Subtype
+	if requestID == "" {
+		return
+	}
+	if subtype != "can_use_tool" {
+		s.writeRunInput(map[string]any{
+			"type": "control_response",
+			"response": map[string]any{
+				"subtype":    "error",
+				"request_id": requestID,
+				"error":      "unsupported control request: " + subtype,
+			},
+		})
+		return
+	}
+
+	toolName := request.ToolName
+	toolUseID := request.ToolUseID
+	input := request.Input
+	if input == nil {
+		input = map[string]any{}
+	}
+	filePath, _ := input["file_path"].(string)
+	if filePath == "" {
+		filePath, _ = input["notebook_path"].(string)
+	}
+
+	s.mu.Lock()
+	s.pendingActions = append(s.pendingActions, PendingAction{
+		ID:          requestID,
+		ToolName:    toolName,
+		ToolUseID:   toolUseID,
+		Description: redactString(s.formatToolUseDescription(toolName, input)),
+		FilePath:    filePath,
+		RequestedAt: time.Now(),
+		input:       input,
+	})
+	s.runHasOutput = true
+	s.setStatus(SessionStatusWaiting)
+	pending, handler := s.pendingActionsLocked()
+	s.mu.Unlock()
+
+	if handler != nil {
+		handler(pending)
+	}
+	s.warnProtectedWrite(toolName, input)
+}
+
+// clearPendingActions drops actions left over when a run ends; the process
+// that asked for them is gone
+func (s *Session) clearPendingActions() {
+	s.mu.Lock()
+	if len(s.pendingActions) == 0 {
+		s.mu.Unlock()
+		return
+	}
+	s.pendingActions = nil
+	pending, handler := s.pendingActionsLocked()
+	s.mu.Unlock()
+
+	if handler != nil {
+		handler(pending)
+	}
+}
+
+// pendingActionsLocked copies the pending actions for the approval handler.
+// Note: This method expects the caller to hold s.mu lock
+func (s *Session) pendingActionsLocked() ([]PendingAction, func([]PendingAction)) {
+	return append([]PendingAction{}, s.pendingActions...), s.onApproval
+}
+
+// sendPermissionResponse answers a can_use_tool request
+func (s *Session) sendPermissionResponse(requestID string, decision map[string]any) error {
+	return s.writeRunInput(map[string]any{
+		"type": "control_response",
+		"response": map[string]any{
+			"subtype":    "success",
+			"request_id": requestID,
+			"response":   decision,
+		},
+	})
+}
+
+// sendUserPrompt writes the prompt that starts a run
+func (s *Session) sendUserPrompt(prompt string, images ...promptImage) error {
+	return s.writeRunInput(map[string]any{
+		"type": "user",
+		"message": map[string]any{
+			"role":    "user",
+			"content": promptContent(prompt, images),
+		},
+	})
+}
+
+// writeRunInput sends one stream-json message to the running claude process
+func (s *Session) writeRunInput(msg map[string]any) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	input := s.runInput
+	s.mu.RUnlock()
+	if input == nil {
+		return fmt.Errorf("claude is not running")
+	}
+
+	s.runInputMu.Lock()
+	defer s.runInputMu.Unlock()
+	if _, err := input.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write to claude: %w", err)
+	}
+	return nil
+}
+
+// closeRunInput ends the run's input so claude exits once the turn is over
+func (s *Session) closeRunInput() {
+	s.mu.Lock()
+	input := s.runInput
+	s.runInput = nil
+	s.mu.Unlock()
+
+	if input != nil {
+		s.runInputMu.Lock()
+		input.Close()
+		s.runInputMu.Unlock()
+	}
+}
+
+// setRunInput records where stream-json messages for the current run are written
+func (s *Session) setRunInput(input io.WriteCloser) {
+	s.mu.Lock()
+	s.runInput = input
+	s.mu.Unlock()
+}
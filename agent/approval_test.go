@@ -0,0 +1,222 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// recordingInput captures what a session writes to claude's stdin
+type recordingInput struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (r *recordingInput) Close() error {
+	r.closed = true
+	return nil
+}
+
+// messages decodes each line written so far
+func (r *recordingInput) messages(t *testing.T) []map[string]any {
+	t.Helper()
+	var messages []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(r.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var msg map[string]any
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			t.Fatalf("invalid stdin line %q: %v", line, err)
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+func newApprovalSession(t *testing.T) (*Session, *recordingInput, *[][]PendingAction) {
+	t.Helper()
+	s := NewSession("test", "/project")
+	input := &recordingInput{}
+	s.setRunInput(input)
+
+	var updates [][]PendingAction
+	s.SetApprovalHandler(func(actions []PendingAction) {
+		updates = append(updates, actions)
+	})
+
+	s.mu.Lock()
+	s.setStatus(SessionStatusRunning)
+	s.mu.Unlock()
+	return s, input, &updates
+}
+
+func permissionRequest(id, tool string, input map[string]any) string {
+	data, _ := json.Marshal(map[string]any{
+		"type":       "control_request",
+		"request_id": id,
+		"request": map[string]any{
+			"subtype":     "can_use_tool",
+			"tool_name":   tool,
+			"tool_use_id": "toolu_" + id,
+			"input":       input,
+		},
+	})
+	return string(data)
+}
+
+func parseLine(s *Session, line string) {
+	var builder strings.Builder
+	var messageID string
+	s.parseStreamLine(line, &builder, &messageID)
+}
+
+func TestPermissionRequestQueuesAction(t *testing.T) {
+	s, _, updates := newApprovalSession(t)
+
+	parseLine(s, permissionRequest("req-1", "Write", map[string]any{"file_path": "/project/main.go", "content": "package main"}))
+
+	if s.Status != SessionStatusWaiting {
+		t.Errorf("Expected status waiting, got %s", s.Status)
+	}
+	pending := s.PendingActions()
+	if len(pending) != 1 {
+		t.Fatalf("Expected 1 pending action, got %d", len(pending))
+	}
+	action := pending[0]
+	if action.ID != "req-1" || action.ToolName != "Write" || action.ToolUseID != "toolu_req-1" {
+		t.Errorf("Unexpected action %+v", action)
+	}
+	if action.FilePath != "/project/main.go" {
+		t.Errorf("Expected file path /project/main.go, got %q", action.FilePath)
+	}
+	if action.Description == "" {
+		t.Error("Expected a description")
+	}
+	if len(*updates) != 1 || len((*updates)[0]) != 1 {
+		t.Errorf("Expected the handler to see 1 pending action, got %v", *updates)
+	}
+}
+
+func TestApproveSendsAllow(t *testing.T) {
+	s, input, updates := newApprovalSession(t)
+	toolInput := map[string]any{"command": "go test ./..."}
+	parseLine(s, permissionRequest("req-1", "Bash", toolInput))
+	parseLine(s, permissionRequest("req-2", "Bash", map[string]any{"command": "rm -rf build"}))
+
+	// An empty ID approves the oldest action
+	if err := s.Approve(""); err != nil {
+		t.Fatalf("Approve failed: %v", err)
+	}
+	if s.Status != SessionStatusWaiting {
+		t.Errorf("Expected to keep waiting while an action is pending, got %s", s.Status)
+	}
+
+	messages := input.messages(t)
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message on stdin, got %d", len(messages))
+	}
+	response := messages[0]["response"].(map[string]any)
+	if response["subtype"] != "success" || response["request_id"] != "req-1" {
+		t.Errorf("Unexpected control response %v", response)
+	}
+	decision := response["response"].(map[string]any)
+	if decision["behavior"] != "allow" {
+		t.Errorf("Expected allow, got %v", decision["behavior"])
+	}
+	updated := decision["updatedInput"].(map[string]any)
+	if updated["command"] != "go test ./..." {
+		t.Errorf("Expected the original input to be sent back, got %v", updated)
+	}
+
+	if err := s.Reject("req-2"); err != nil {
+		t.Fatalf("Reject failed: %v", err)
+	}
+	if s.Status != SessionStatusRunning {
+		t.Errorf("Expected status running once nothing is pending, got %s", s.Status)
+	}
+	last := (*updates)[len(*updates)-1]
+	if len(last) != 0 {
+		t.Errorf("Expected the handler to see no pending actions, got %v", last)
+	}
+}
+
+func TestRejectSendsDeny(t *testing.T) {
+	s, input, _ := newApprovalSession(t)
+	parseLine(s, permissionRequest("req-1", "Edit", map[string]any{"file_path": "/project/a.go"}))
+
+	if err := s.Reject("req-1"); err != nil {
+		t.Fatalf("Reject failed: %v", err)
+	}
+
+	messages := input.messages(t)
+	decision := messages[0]["response"].(map[string]any)["response"].(map[string]any)
+	if decision["behavior"] != "deny" {
+		t.Errorf("Expected deny, got %v", decision["behavior"])
+	}
+	if decision["message"] != rejectMessage {
+		t.Errorf("Expected reject message, got %v", decision["message"])
+	}
+}
+
+func TestApproveErrors(t *testing.T) {
+	s, _, _ := newApprovalSession(t)
+
+	if err := s.Approve(""); err == nil {
+		t.Error("Expected an error with nothing pending")
+	}
+
+	parseLine(s, permissionRequest("req-1", "Bash", nil))
+	if err := s.Reject("req-9"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected not found error, got %v", err)
+	}
+	if len(s.PendingActions()) != 1 {
+		t.Error("Expected a failed reject to leave the action pending")
+	}
+}
+
+func TestUnsupportedControlRequest(t *testing.T) {
+	s, input, _ := newApprovalSession(t)
+
+	parseLine(s, `{"type":"control_request","request_id":"req-1","request":{"subtype":"mcp_message"}}`)
+
+	messages := input.messages(t)
+	if len(messages) != 1 {
+		t.Fatalf("Expected an error response, got %d messages", len(messages))
+	}
+	response := messages[0]["response"].(map[string]any)
+	if response["subtype"] != "error" || response["request_id"] != "req-1" {
+		t.Errorf("Unexpected control response %v", response)
+	}
+	if len(s.PendingActions()) != 0 {
+		t.Error("Expected no pending actions")
+	}
+}
+
+func TestResultClosesInput(t *testing.T) {
+	s, input, _ := newApprovalSession(t)
+
+	parseLine(s, `{"type":"result","result":"done"}`)
+
+	if !input.closed {
+		t.Error("Expected stdin to be closed after the result")
+	}
+	if err := s.sendUserPrompt("again"); err == nil {
+		t.Error("Expected writes after the run to fail")
+	}
+}
+
+func TestClearPendingActions(t *testing.T) {
+	s, _, updates := newApprovalSession(t)
+	parseLine(s, permissionRequest("req-1", "Bash", nil))
+
+	s.clearPendingActions()
+
+	if len(s.PendingActions()) != 0 {
+		t.Error("Expected pending actions to be cleared")
+	}
+	if last := (*updates)[len(*updates)-1]; len(last) != 0 {
+		t.Errorf("Expected the handler to see no pending actions, got %v", last)
+	}
+}
@@ -0,0 +1,54 @@
+package agent
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"boatman/project"
+)
+
+// maxAttachmentBytes is the most of one attached file inlined in a prompt
+const maxAttachmentBytes = 64 << 10
+
+// maxInlineAttachmentBytes bounds all the files inlined in one prompt; the
+// rest are referenced by path for the agent to read itself
+const maxInlineAttachmentBytes = 256 << 10
+
+// buildAttachmentPrompt adds files the user attached to a prompt. Small text
+// files are inlined; binary, large or excess ones are listed by path. Paths
+// are relative to the first root, or absolute within any of them. It returns
+// the prompt and the attached paths as shown to the user.
+func buildAttachmentPrompt(roots []string, content string, attachments []string) (string, []string, error) {
+	workspace := project.NewWorkspace(roots[0], roots[1:]...)
+
+	var inlined, referenced strings.Builder
+	var paths []string
+	budget := maxInlineAttachmentBytes
+	for _, attachment := range attachments {
+		preview, err := workspace.PreviewFile(attachment, maxAttachmentBytes)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to attach %s: %w", attachment, err)
+		}
+		paths = append(paths, preview.Path)
+
+		switch {
+		case preview.Binary:
+			fmt.Fprintf(&referenced, "- %s (binary, %d bytes)\n", attachment, preview.Size)
+		case preview.Truncated || len(preview.Content) > budget:
+			fmt.Fprintf(&referenced, "- %s (%d bytes, too large to include)\n", attachment, preview.Size)
+		default:
+			budget -= len(preview.Content)
+			fmt.Fprintf(&inlined, "%s:\n%s\n", attachment, codeFence(preview.Content, strings.TrimPrefix(path.Ext(preview.Path), ".")))
+		}
+	}
+
+	prompt := content
+	if inlined.Len() > 0 {
+		prompt += "\n\nI've attached these files:\n\n" + strings.TrimRight(inlined.String(), "\n")
+	}
+	if referenced.Len() > 0 {
+		prompt += "\n\nThese files are attached too; read them if you need them:\n" + strings.TrimRight(referenced.String(), "\n")
+	}
+	return prompt, paths, nil
+}
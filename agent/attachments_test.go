@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildAttachmentPrompt(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"main.go":   "package main\n\n// Uses ``` in a comment\n",
+		"logo.png":  "\x89PNG\x00",
+		"large.txt": strings.Repeat("x", maxAttachmentBytes+1),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	prompt, paths, err := buildAttachmentPrompt([]string{root}, "Why does this fail?", []string{"main.go", "logo.png", "large.txt"})
+	if err != nil {
+		t.Fatalf("buildAttachmentPrompt() error = %v", err)
+	}
+	if !reflect.DeepEqual(paths, []string{"main.go", "logo.png", "large.txt"}) {
+		t.Errorf("Expected the attached paths, got %v", paths)
+	}
+
+	expected := "Why does this fail?\n\n" +
+		"I've attached these files:\n\n" +
+		"main.go:\n````go\npackage main\n\n// Uses ``` in a comment\n````\n\n" +
+		"These files are attached too; read them if you need them:\n" +
+		"- logo.png (binary, 5 bytes)\n" +
+		"- large.txt (65537 bytes, too large to include)"
+	if prompt != expected {
+		t.Errorf("Expected prompt:\n%s\ngot:\n%s", expected, prompt)
+	}
+
+	if _, _, err := buildAttachmentPrompt([]string{root}, "Hi", []string{"../secret"}); err == nil {
+		t.Error("Expected files outside the workspace to be refused")
+	}
+}
+
+func TestSendMessageWithAttachments(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "README.md"), []byte("# Demo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	session := NewSession("attachments", root)
+	session.ctx, session.cancel = context.WithCancel(context.Background())
+	defer session.cancel()
+	runner := &fakeRunner{}
+	session.runner = runner
+	done := make(chan struct{}, 1)
+	session.SetStatusHandler(func(status SessionStatus) {
+		if status != SessionStatusRunning {
+			done <- struct{}{}
+		}
+	})
+	authConfig := AuthConfig{ClaudeCLIPath: fakeClaudeBinary(t)}
+
+	if err := session.SendMessageWithAttachments("Summarize this", []string{"missing.md"}, nil, authConfig); err == nil {
+		t.Error("Expected a missing attachment to fail the send")
+	}
+	if err := session.SendMessageWithAttachments("Summarize this", []string{"README.md"}, nil, authConfig); err != nil {
+		t.Fatalf("SendMessageWithAttachments failed: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the run")
+	}
+
+	// The history keeps what the user typed, with the attachments alongside
+	msg := session.GetMessages()[0]
+	if msg.Content != "Summarize this" || msg.Metadata == nil || !reflect.DeepEqual(msg.Metadata.Attachments, []string{"README.md"}) {
+		t.Errorf("Expected the message with its attachment, got %+v", msg)
+	}
+	// while claude gets the file too
+	if !strings.Contains(runner.stdin.String(), "# Demo") {
+		t.Errorf("Expected the file inlined in the prompt, got %q", runner.stdin.String())
+	}
+}
@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// SessionAttention is a session that is stuck until the user acts on it
+type SessionAttention struct {
+	SessionID   string        `json:"sessionId"`
+	ProjectPath string        `json:"projectPath"`
+	Status      SessionStatus `json:"status"` // SessionStatusWaiting or SessionStatusError
+	Since       time.Time     `json:"since"`
+}
+
+// needsAttention reports whether a session in status waits on the user
+func needsAttention(status SessionStatus) bool {
+	return status == SessionStatusWaiting || status == SessionStatusError
+}
+
+// AttentionTracker keeps the sessions waiting for approval or stopped by an
+// error, for badges and sounds that make them hard to miss
+type AttentionTracker struct {
+	mu       sync.Mutex
+	sessions map[string]SessionAttention
+	// listener is told the sessions needing attention whenever they change,
+	// and whether a session newly needs it
+	listener func(sessions []SessionAttention, raised bool)
+	now      func() time.Time
+}
+
+// NewAttentionTracker creates a tracker with no sessions needing attention
+func NewAttentionTracker() *AttentionTracker {
+	return &AttentionTracker{
+		sessions: make(map[string]SessionAttention),
+		now:      time.Now,
+	}
+}
+
+// SetListener sets the function told about changes to the sessions needing
+// attention. It is called from status handlers, which hold the session's
+// lock, so it must not call back into the session.
+func (t *AttentionTracker) SetListener(listener func(sessions []SessionAttention, raised bool)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.listener = listener
+}
+
+// Update records a session's new status
+func (t *AttentionTracker) Update(sessionID, projectPath string, status SessionStatus) {
+	t.mu.Lock()
+	current, tracked := t.sessions[sessionID]
+	raised := false
+	switch {
+	case needsAttention(status):
+		if tracked && current.Status == status {
+			t.mu.Unlock()
+			return
+		}
+		t.sessions[sessionID] = SessionAttention{SessionID: sessionID, ProjectPath: projectPath, Status: status, Since: t.now()}
+		raised = true
+	case tracked:
+		delete(t.sessions, sessionID)
+	default:
+		t.mu.Unlock()
+		return
+	}
+	sessions, listener := t.sessionsLocked(), t.listener
+	t.mu.Unlock()
+
+	if listener != nil {
+		listener(sessions, raised)
+	}
+}
+
+// Remove forgets a session, e.g. once it's deleted
+func (t *AttentionTracker) Remove(sessionID string) {
+	t.mu.Lock()
+	if _, ok := t.sessions[sessionID]; !ok {
+		t.mu.Unlock()
+		return
+	}
+	delete(t.sessions, sessionID)
+	sessions, listener := t.sessionsLocked(), t.listener
+	t.mu.Unlock()
+
+	if listener != nil {
+		listener(sessions, false)
+	}
+}
+
+// Sessions returns the sessions needing attention, longest waiting first
+func (t *AttentionTracker) Sessions() []SessionAttention {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sessionsLocked()
+}
+
+// sessionsLocked lists the sessions needing attention, longest waiting first.
+// Note: This method expects the caller to hold t.mu lock
+func (t *AttentionTracker) sessionsLocked() []SessionAttention {
+	sessions := make([]SessionAttention, 0, len(t.sessions))
+	for _, session := range t.sessions {
+		sessions = append(sessions, session)
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].Since.Equal(sessions[j].Since) {
+			return sessions[i].Since.Before(sessions[j].Since)
+		}
+		return sessions[i].SessionID < sessions[j].SessionID
+	})
+	return sessions
+}
+
+// Attention returns the tracker of sessions waiting on the user
+func (m *Manager) Attention() *AttentionTracker {
+	return m.attention
+}
+
+// GetSessionsNeedingAttention returns the sessions waiting for approval or
+// stopped by an error, longest waiting first
+func (m *Manager) GetSessionsNeedingAttention() []SessionAttention {
+	return m.attention.Sessions()
+}
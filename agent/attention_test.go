@@ -0,0 +1,76 @@
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAttentionTracker(t *testing.T) {
+	tracker := NewAttentionTracker()
+	clock := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return clock }
+
+	type change struct {
+		count  int
+		raised bool
+	}
+	var changes []change
+	tracker.SetListener(func(sessions []SessionAttention, raised bool) {
+		changes = append(changes, change{len(sessions), raised})
+	})
+
+	tracker.Update("a", "/tmp/api", SessionStatusRunning)
+	tracker.Update("a", "/tmp/api", SessionStatusWaiting)
+	tracker.Update("a", "/tmp/api", SessionStatusWaiting) // Unchanged
+	clock = clock.Add(time.Minute)
+	tracker.Update("b", "/tmp/web", SessionStatusError)
+
+	sessions := tracker.Sessions()
+	if len(sessions) != 2 || sessions[0].SessionID != "a" || sessions[1].SessionID != "b" {
+		t.Fatalf("Expected a then b, longest waiting first, got %+v", sessions)
+	}
+	if sessions[0].Status != SessionStatusWaiting || sessions[0].ProjectPath != "/tmp/api" {
+		t.Errorf("Expected a waiting in /tmp/api, got %+v", sessions[0])
+	}
+
+	tracker.Update("a", "/tmp/api", SessionStatusRunning)
+	tracker.Remove("b")
+	tracker.Remove("b") // Already gone
+
+	if sessions := tracker.Sessions(); len(sessions) != 0 {
+		t.Errorf("Expected no sessions needing attention, got %+v", sessions)
+	}
+	expected := []change{{1, true}, {2, true}, {1, false}, {0, false}}
+	if len(changes) != len(expected) {
+		t.Fatalf("Expected changes %v, got %v", expected, changes)
+	}
+	for i := range expected {
+		if changes[i] != expected[i] {
+			t.Errorf("Expected change %d to be %v, got %v", i, expected[i], changes[i])
+		}
+	}
+}
+
+func TestManagerTracksAttention(t *testing.T) {
+	m := NewManager()
+	session := NewSession("needs-you", "/tmp/project")
+	m.mu.Lock()
+	m.sessions[session.ID] = session
+	m.setupSessionHandlers(session, session.ID)
+	m.mu.Unlock()
+
+	session.mu.Lock()
+	session.setStatus(SessionStatusWaiting)
+	session.mu.Unlock()
+
+	if sessions := m.GetSessionsNeedingAttention(); len(sessions) != 1 || sessions[0].SessionID != session.ID {
+		t.Fatalf("Expected the waiting session to need attention, got %+v", sessions)
+	}
+
+	if err := m.DeleteSession(session.ID); err != nil {
+		t.Fatalf("Failed to delete session: %v", err)
+	}
+	if sessions := m.GetSessionsNeedingAttention(); len(sessions) != 0 {
+		t.Errorf("Expected a deleted session to be forgotten, got %+v", sessions)
+	}
+}
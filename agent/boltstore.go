@@ -0,0 +1,399 @@
+package agent
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// Buckets in the session database
+var (
+	sessionsBucket    = []byte("sessions")     // Session ID -> SessionData
+	archivesBucket    = []byte("archives")     // Session ID -> bucket of archived messages in order
+	archiveMetaBucket = []byte("archive_meta") // Session ID -> ArchiveData without messages
+	metaBucket        = []byte("meta")
+)
+
+// filesImportedKey records that the JSON session files were imported
+var filesImportedKey = []byte("filesImported")
+
+// BoltStore persists sessions in a bbolt database file
+type BoltStore struct {
+	db *bolt.DB
+}
+
+// DefaultStorePath returns where the session database lives
+func DefaultStorePath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".boatman", "sessions.db"), nil
+}
+
+// OpenBoltStore opens or creates the session database at path. It fails
+// rather than waiting if another process has the database open.
+func OpenBoltStore(path string) (*BoltStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session store: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{sessionsBucket, archivesBucket, archiveMetaBucket, metaBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize session store: %w", err)
+	}
+
+	return &BoltStore{db: db}, nil
+}
+
+// SaveSession writes a session, replacing any earlier version
+func (s *BoltStore) SaveSession(data SessionData) error {
+	encoded, err := marshalSealed(data, false)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionsBucket).Put([]byte(data.ID), encoded)
+	})
+}
+
+// LoadSession reads one session
+func (s *BoltStore) LoadSession(sessionID string) (*SessionData, error) {
+	var data SessionData
+	err := s.db.View(func(tx *bolt.Tx) error {
+		encoded := tx.Bucket(sessionsBucket).Get([]byte(sessionID))
+		if encoded == nil {
+			return fmt.Errorf("%w: %s", ErrSessionNotFound, sessionID)
+		}
+		return unmarshalSealed(encoded, &data)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// LoadSessions reads every session. Sessions that fail to decode are skipped.
+func (s *BoltStore) LoadSessions() ([]SessionData, error) {
+	var sessions []SessionData
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionsBucket).ForEach(func(key, value []byte) error {
+			var data SessionData
+			if err := unmarshalSealed(value, &data); err != nil {
+				logger.Warn("Failed to load session", "session", string(key), "error", err)
+				return nil
+			}
+			sessions = append(sessions, data)
+			return nil
+		})
+	})
+	return sessions, err
+}
+
+// DeleteSession removes a session. Its archive is kept until DeleteArchive.
+func (s *BoltStore) DeleteSession(sessionID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(sessionsBucket).Delete([]byte(sessionID))
+	})
+}
+
+// ArchiveMessages appends messages to a session's archive
+func (s *BoltStore) ArchiveMessages(meta ArchiveData, messages []Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return archiveMessagesTx(tx, meta, messages)
+	})
+}
+
+// archiveMessagesTx appends archived messages and merges the latest metadata
+func archiveMessagesTx(tx *bolt.Tx, meta ArchiveData, messages []Message) error {
+	key := []byte(meta.SessionID)
+
+	// Keep the latest known metadata
+	stored := ArchiveData{SessionID: meta.SessionID}
+	metaBkt := tx.Bucket(archiveMetaBucket)
+	if existing := metaBkt.Get(key); existing != nil {
+		if err := unmarshalSealed(existing, &stored); err != nil {
+			return fmt.Errorf("failed to unmarshal archive metadata: %w", err)
+		}
+	}
+	if meta.ProjectPath != "" {
+		stored.ProjectPath = meta.ProjectPath
+	}
+	if meta.Tags != nil {
+		stored.Tags = meta.Tags
+	}
+	stored.Messages = nil
+	encoded, err := marshalSealed(stored, false)
+	if err != nil {
+		return err
+	}
+	if err := metaBkt.Put(key, encoded); err != nil {
+		return err
+	}
+
+	bucket, err := tx.Bucket(archivesBucket).CreateBucketIfNotExists(key)
+	if err != nil {
+		return err
+	}
+	if len(messages) == 0 {
+		return nil
+	}
+
+	// Archived messages newer than those added, like a branch rewound before
+	// the messages leading up to it were trimmed, move after them
+	var newer []Message
+	cursor := bucket.Cursor()
+	for key, value := cursor.Last(); key != nil; key, value = cursor.Prev() {
+		var msg Message
+		if err := unmarshalSealed(value, &msg); err != nil {
+			return fmt.Errorf("failed to unmarshal archived message: %w", err)
+		}
+		if !msg.Timestamp.After(messages[0].Timestamp) {
+			break
+		}
+		newer = append([]Message{msg}, newer...)
+	}
+
+	seq := bucket.Sequence() - uint64(len(newer))
+	for _, msg := range mergeByTime(newer, messages) {
+		encoded, err := marshalSealed(msg, false)
+		if err != nil {
+			return fmt.Errorf("failed to marshal archived message: %w", err)
+		}
+		seq++
+		if err := bucket.Put(sequenceKey(seq), encoded); err != nil {
+			return err
+		}
+	}
+	return bucket.SetSequence(seq)
+}
+
+// LoadArchive reads a session's archived messages in timestamp order
+func (s *BoltStore) LoadArchive(sessionID string) (*ArchiveData, error) {
+	archive := &ArchiveData{SessionID: sessionID, Messages: []Message{}}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		key := []byte(sessionID)
+		if encoded := tx.Bucket(archiveMetaBucket).Get(key); encoded != nil {
+			if err := unmarshalSealed(encoded, archive); err != nil {
+				return fmt.Errorf("failed to unmarshal archive metadata: %w", err)
+			}
+			archive.Messages = []Message{}
+		}
+
+		bucket := tx.Bucket(archivesBucket).Bucket(key)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(_, value []byte) error {
+			var msg Message
+			if err := unmarshalSealed(value, &msg); err != nil {
+				return fmt.Errorf("failed to unmarshal archived message: %w", err)
+			}
+			archive.Messages = append(archive.Messages, msg)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// LoadArchivePage reads up to limit of a session's archived messages from
+// offset, seeking straight to the first one
+func (s *BoltStore) LoadArchivePage(sessionID string, offset, limit int) ([]Message, int, error) {
+	messages := []Message{}
+	total := 0
+	err := s.db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(archivesBucket).Bucket([]byte(sessionID))
+		if bucket == nil {
+			return nil
+		}
+		// Archived messages are kept in contiguous sequences, so sequence N is
+		// message N-1
+		total = int(bucket.Sequence())
+
+		cursor := bucket.Cursor()
+		for key, value := cursor.Seek(sequenceKey(uint64(offset) + 1)); key != nil && len(messages) < limit; key, value = cursor.Next() {
+			var msg Message
+			if err := unmarshalSealed(value, &msg); err != nil {
+				return fmt.Errorf("failed to unmarshal archived message: %w", err)
+			}
+			messages = append(messages, msg)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return messages, total, nil
+}
+
+// DeleteArchive removes a session's archived messages
+func (s *BoltStore) DeleteArchive(sessionID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		key := []byte(sessionID)
+		if err := tx.Bucket(archiveMetaBucket).Delete(key); err != nil {
+			return err
+		}
+		if tx.Bucket(archivesBucket).Bucket(key) == nil {
+			return nil
+		}
+		return tx.Bucket(archivesBucket).DeleteBucket(key)
+	})
+}
+
+// ImportFiles copies sessions and archives saved as JSON files into the store.
+// It runs once; later calls return 0. Sessions already in the store are kept,
+// and the files are left in place.
+func (s *BoltStore) ImportFiles(sessionsDir, archivesDir string) (int, error) {
+	imported := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		meta := tx.Bucket(metaBucket)
+		if meta.Get(filesImportedKey) != nil {
+			return nil
+		}
+
+		sessions := tx.Bucket(sessionsBucket)
+		for _, id := range jsonFileIDs(sessionsDir) {
+			if sessions.Get([]byte(id)) != nil {
+				continue
+			}
+			raw, err := os.ReadFile(filepath.Join(sessionsDir, id+".json"))
+			if err != nil {
+				return err
+			}
+			var data SessionData
+			if err := unmarshalSealed(raw, &data); err != nil {
+				logger.Warn("Skipping unreadable session file", "session", id, "error", err)
+				continue
+			}
+			if err := sessions.Put([]byte(id), raw); err != nil {
+				return err
+			}
+			imported++
+		}
+
+		for _, id := range jsonFileIDs(archivesDir) {
+			if tx.Bucket(archivesBucket).Bucket([]byte(id)) != nil {
+				continue
+			}
+			archive, err := loadArchivePages(archivesDir, id)
+			if err != nil {
+				logger.Warn("Skipping unreadable archive file", "session", id, "error", err)
+				continue
+			}
+			if err := archiveMessagesTx(tx, *archive, archive.Messages); err != nil {
+				return err
+			}
+		}
+
+		return meta.Put(filesImportedKey, []byte(time.Now().Format(time.RFC3339)))
+	})
+	return imported, err
+}
+
+// Reseal rewrites every session, archive record, and archived message for
+// the current encryption setting
+func (s *BoltStore) Reseal() (int, error) {
+	count := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		sessions, err := resealBucket(tx.Bucket(sessionsBucket))
+		if err != nil {
+			return fmt.Errorf("failed to reseal sessions: %w", err)
+		}
+		if _, err := resealBucket(tx.Bucket(archiveMetaBucket)); err != nil {
+			return fmt.Errorf("failed to reseal archives: %w", err)
+		}
+		archives := tx.Bucket(archivesBucket)
+		var ids [][]byte
+		archives.ForEach(func(key, _ []byte) error {
+			ids = append(ids, append([]byte(nil), key...))
+			return nil
+		})
+		for _, id := range ids {
+			if _, err := resealBucket(archives.Bucket(id)); err != nil {
+				return fmt.Errorf("failed to reseal archive %s: %w", id, err)
+			}
+		}
+		count = sessions + len(ids)
+		return nil
+	})
+	return count, err
+}
+
+// resealBucket rewrites a bucket's values for the current encryption setting.
+// Nested buckets are left alone.
+func resealBucket(bucket *bolt.Bucket) (int, error) {
+	resealed := make(map[string][]byte)
+	err := bucket.ForEach(func(key, value []byte) error {
+		if value == nil {
+			return nil
+		}
+		data, err := resealData(value)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		resealed[string(key)] = data
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	// Values can't be replaced while iterating
+	for key, data := range resealed {
+		if err := bucket.Put([]byte(key), data); err != nil {
+			return 0, err
+		}
+	}
+	return len(resealed), nil
+}
+
+// Close closes the database
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
+
+// jsonFileIDs lists the names of the .json files in dir without the extension
+func jsonFileIDs(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var ids []string
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	return ids
+}
+
+// sequenceKey encodes a sequence number so keys sort in insertion order
+func sequenceKey(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
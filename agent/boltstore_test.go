@@ -0,0 +1,182 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// useTestStore opens a store in a temp dir and makes it the active store for the test
+func useTestStore(t *testing.T) *BoltStore {
+	t.Helper()
+	store, err := OpenBoltStore(filepath.Join(t.TempDir(), "sessions.db"))
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	UseStore(store)
+	t.Cleanup(func() {
+		UseStore(nil)
+		store.Close()
+	})
+	return store
+}
+
+func TestBoltStoreSessions(t *testing.T) {
+	useTestStore(t)
+
+	session := NewSession("bolt-session", "/tmp/project")
+	session.conversationID = "conv-1"
+	session.Mode = "firefighter"
+	session.ModeConfig = map[string]interface{}{"scope": "payments"}
+	session.Tags = []string{"urgent"}
+	session.Messages = append(session.Messages, Message{ID: "msg-1", Role: "user", Content: "Hello", Timestamp: time.Now()})
+
+	if err := SaveSession(session); err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+
+	loaded, err := LoadSession(session.ID)
+	if err != nil {
+		t.Fatalf("Failed to load session: %v", err)
+	}
+	if loaded.conversationID != "conv-1" {
+		t.Errorf("Expected conversation ID conv-1, got %q", loaded.conversationID)
+	}
+	if loaded.Mode != "firefighter" || loaded.ModeConfig["scope"] != "payments" {
+		t.Errorf("Expected mode to be restored, got %q %v", loaded.Mode, loaded.ModeConfig)
+	}
+	if len(loaded.Messages) != 1 || loaded.Messages[0].Content != "Hello" {
+		t.Errorf("Expected 1 restored message, got %v", loaded.Messages)
+	}
+	if len(loaded.Tags) != 1 || loaded.Tags[0] != "urgent" {
+		t.Errorf("Expected tags [urgent], got %v", loaded.Tags)
+	}
+
+	all, err := LoadAllSessions()
+	if err != nil {
+		t.Fatalf("Failed to load sessions: %v", err)
+	}
+	if len(all) != 1 {
+		t.Errorf("Expected 1 session, got %d", len(all))
+	}
+
+	if err := DeleteSessionFile(session.ID); err != nil {
+		t.Fatalf("Failed to delete session: %v", err)
+	}
+	if _, err := LoadSession(session.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected ErrSessionNotFound, got %v", err)
+	}
+}
+
+func TestBoltStoreResetsRunningStatus(t *testing.T) {
+	useTestStore(t)
+
+	session := NewSession("bolt-running", "/tmp/project")
+	session.Status = SessionStatusRunning
+	if err := SaveSession(session); err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+
+	loaded, err := LoadSession(session.ID)
+	if err != nil {
+		t.Fatalf("Failed to load session: %v", err)
+	}
+	if loaded.Status != SessionStatusIdle {
+		t.Errorf("Expected status idle, got %s", loaded.Status)
+	}
+}
+
+func TestBoltStoreArchive(t *testing.T) {
+	useTestStore(t)
+
+	meta := ArchiveData{SessionID: "bolt-archive", ProjectPath: "/tmp/project", Tags: []string{"client-x"}}
+	for i, content := range []string{"one", "two", "three"} {
+		msg := Message{ID: content, Role: "user", Content: content, Timestamp: time.Now()}
+		if i == 0 {
+			if err := ArchiveSessionMessages(meta, []Message{msg}); err != nil {
+				t.Fatalf("Failed to archive messages: %v", err)
+			}
+			continue
+		}
+		if err := ArchiveMessages(meta.SessionID, []Message{msg}); err != nil {
+			t.Fatalf("Failed to archive messages: %v", err)
+		}
+	}
+
+	archive, err := LoadArchive(meta.SessionID)
+	if err != nil {
+		t.Fatalf("Failed to load archive: %v", err)
+	}
+	if len(archive.Messages) != 3 {
+		t.Fatalf("Expected 3 archived messages, got %d", len(archive.Messages))
+	}
+	for i, want := range []string{"one", "two", "three"} {
+		if archive.Messages[i].Content != want {
+			t.Errorf("Expected message %d to be %q, got %q", i, want, archive.Messages[i].Content)
+		}
+	}
+	if archive.ProjectPath != "/tmp/project" || len(archive.Tags) != 1 {
+		t.Errorf("Expected metadata to be kept, got %q %v", archive.ProjectPath, archive.Tags)
+	}
+
+	if err := DeleteArchiveFile(meta.SessionID); err != nil {
+		t.Fatalf("Failed to delete archive: %v", err)
+	}
+	archive, err = LoadArchive(meta.SessionID)
+	if err != nil {
+		t.Fatalf("Failed to load deleted archive: %v", err)
+	}
+	if len(archive.Messages) != 0 {
+		t.Errorf("Expected an empty archive, got %d messages", len(archive.Messages))
+	}
+}
+
+func TestBoltStoreImportFiles(t *testing.T) {
+	dir := t.TempDir()
+	sessionsDir := filepath.Join(dir, "sessions")
+	archivesDir := filepath.Join(dir, "archives")
+	os.MkdirAll(sessionsDir, 0755)
+	os.MkdirAll(archivesDir, 0755)
+
+	data, _ := json.Marshal(SessionData{ID: "file-session", ProjectPath: "/tmp/project", ConversationID: "conv-9"})
+	os.WriteFile(filepath.Join(sessionsDir, "file-session.json"), data, 0644)
+	os.WriteFile(filepath.Join(sessionsDir, "broken.json"), []byte("{"), 0644)
+	archive, _ := json.Marshal([]Message{{ID: "old", Role: "user", Content: "archived"}})
+	os.WriteFile(filepath.Join(archivesDir, "file-session.json"), archive, 0644)
+
+	store := useTestStore(t)
+	count, err := store.ImportFiles(sessionsDir, archivesDir)
+	if err != nil {
+		t.Fatalf("ImportFiles failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 imported session, got %d", count)
+	}
+
+	loaded, err := LoadSession("file-session")
+	if err != nil {
+		t.Fatalf("Failed to load imported session: %v", err)
+	}
+	if loaded.conversationID != "conv-9" {
+		t.Errorf("Expected conversation ID conv-9, got %q", loaded.conversationID)
+	}
+	messages, err := LoadArchivedMessages("file-session")
+	if err != nil {
+		t.Fatalf("Failed to load imported archive: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Content != "archived" {
+		t.Errorf("Expected the archived message to be imported, got %v", messages)
+	}
+
+	// The import only runs once
+	count, err = store.ImportFiles(sessionsDir, archivesDir)
+	if err != nil || count != 0 {
+		t.Errorf("Expected a second import to do nothing, got %d, %v", count, err)
+	}
+	if _, err := os.Stat(filepath.Join(sessionsDir, "file-session.json")); err != nil {
+		t.Errorf("Expected the session file to be kept: %v", err)
+	}
+}
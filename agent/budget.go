@@ -0,0 +1,196 @@
+package agent
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrBudgetExceeded is returned when sending a message would go over a cost budget
+var ErrBudgetExceeded = errors.New("cost budget exceeded")
+
+// budgetWarnRatio is the share of a budget spent before the user is warned
+const budgetWarnRatio = 0.8
+
+// Budget caps spending in USD. A zero limit is off.
+type Budget struct {
+	MaxPerSession float64
+	MaxPerDay     float64
+}
+
+// Budget scopes
+const (
+	BudgetScopeSession = "session"
+	BudgetScopeDay     = "day"
+)
+
+// BudgetAlert reports that spending crossed the warning threshold or the limit
+type BudgetAlert struct {
+	Scope    string  `json:"scope"` // BudgetScopeSession or BudgetScopeDay
+	Spent    float64 `json:"spent"`
+	Limit    float64 `json:"limit"`
+	Exceeded bool    `json:"exceeded"` // False for the early warning
+}
+
+// Message describes the alert for the session transcript
+func (a BudgetAlert) Message() string {
+	scope := "This session"
+	if a.Scope == BudgetScopeDay {
+		scope = "Today's usage"
+	}
+	if a.Exceeded {
+		return fmt.Sprintf("💸 %s has reached its $%.2f budget ($%.2f spent). New messages are blocked until the budget is raised.", scope, a.Limit, a.Spent)
+	}
+	return fmt.Sprintf("💸 %s has used %.0f%% of its $%.2f budget ($%.2f spent).", scope, a.Spent/a.Limit*100, a.Limit, a.Spent)
+}
+
+// CostTracker totals spending per session and per local day and checks it
+// against the configured budget
+type CostTracker struct {
+	mu           sync.Mutex
+	budgetGetter func() Budget
+	sessions     map[string]float64
+	days         map[string]float64 // YYYY-MM-DD -> cost
+	alerted      map[string]bool    // Thresholds already reported, keyed by scope, period, and level
+	now          func() time.Time
+}
+
+// NewCostTracker creates a tracker with no budget
+func NewCostTracker() *CostTracker {
+	return &CostTracker{
+		sessions: make(map[string]float64),
+		days:     make(map[string]float64),
+		alerted:  make(map[string]bool),
+		now:      time.Now,
+	}
+}
+
+// SetBudgetGetter sets the function that returns the current budget
+func (c *CostTracker) SetBudgetGetter(getter func() Budget) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.budgetGetter = getter
+}
+
+// Record adds a cost incurred at the given time and returns any thresholds
+// the new total crossed
+func (c *CostTracker) Record(sessionID string, cost float64, at time.Time) []BudgetAlert {
+	if cost <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	day := at.Local().Format("2006-01-02")
+	c.sessions[sessionID] += cost
+	c.days[day] += cost
+
+	alerts := c.crossedLocked(BudgetScopeSession, sessionID, c.sessions[sessionID])
+	// Costs from earlier days don't count against today's budget
+	if day == c.now().Local().Format("2006-01-02") {
+		alerts = append(alerts, c.crossedLocked(BudgetScopeDay, day, c.days[day])...)
+	}
+	return alerts
+}
+
+// crossedLocked reports the thresholds total reached that weren't reported before.
+// Note: This method expects the caller to hold c.mu lock
+func (c *CostTracker) crossedLocked(scope, period string, total float64) []BudgetAlert {
+	limit := c.limitLocked(scope)
+	if limit <= 0 {
+		return nil
+	}
+
+	var alerts []BudgetAlert
+	for _, exceeded := range []bool{false, true} {
+		threshold := limit * budgetWarnRatio
+		if exceeded {
+			threshold = limit
+		}
+		key := fmt.Sprintf("%s:%s:%v", scope, period, exceeded)
+		if total < threshold || c.alerted[key] {
+			continue
+		}
+		c.alerted[key] = true
+		alerts = append(alerts, BudgetAlert{Scope: scope, Spent: total, Limit: limit, Exceeded: exceeded})
+	}
+
+	// Only the most severe new alert is worth showing
+	if len(alerts) > 1 {
+		alerts = alerts[len(alerts)-1:]
+	}
+	return alerts
+}
+
+// limitLocked returns the budget limit for a scope.
+// Note: This method expects the caller to hold c.mu lock
+func (c *CostTracker) limitLocked(scope string) float64 {
+	if c.budgetGetter == nil {
+		return 0
+	}
+	budget := c.budgetGetter()
+	if scope == BudgetScopeDay {
+		return budget.MaxPerDay
+	}
+	return budget.MaxPerSession
+}
+
+// Check returns ErrBudgetExceeded if the session or today's spending has reached its limit
+func (c *CostTracker) Check(sessionID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if limit := c.limitLocked(BudgetScopeSession); limit > 0 && c.sessions[sessionID] >= limit {
+		return fmt.Errorf("%w: this session has spent $%.2f of its $%.2f limit", ErrBudgetExceeded, c.sessions[sessionID], limit)
+	}
+	day := c.now().Local().Format("2006-01-02")
+	if limit := c.limitLocked(BudgetScopeDay); limit > 0 && c.days[day] >= limit {
+		return fmt.Errorf("%w: $%.2f has been spent today against a $%.2f daily limit", ErrBudgetExceeded, c.days[day], limit)
+	}
+	return nil
+}
+
+// SessionCost returns what a session has spent
+func (c *CostTracker) SessionCost(sessionID string) float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.sessions[sessionID]
+}
+
+// TodayCost returns what has been spent today across all sessions
+func (c *CostTracker) TodayCost() float64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.days[c.now().Local().Format("2006-01-02")]
+}
+
+// Seed counts the costs already recorded in a session's messages without
+// raising alerts, e.g. for sessions restored at startup
+func (c *CostTracker) Seed(sessionID string, messages []Message) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, msg := range messages {
+		if msg.Metadata == nil || msg.Metadata.CostInfo == nil || msg.Metadata.CostInfo.TotalCost <= 0 {
+			continue
+		}
+		cost := msg.Metadata.CostInfo.TotalCost
+		c.sessions[sessionID] += cost
+		c.days[msg.Timestamp.Local().Format("2006-01-02")] += cost
+	}
+}
+
+// recordCost adds a session's new cost to the totals and tells the user when
+// it crosses a budget threshold. It runs from the message handler, which holds
+// the session lock, so the alert message is added separately.
+func (m *Manager) recordCost(session *Session, cost float64, at time.Time) {
+	for _, alert := range m.costs.Record(session.ID, cost, at) {
+		m.events().Emit("agent:budget", map[string]interface{}{
+			"sessionId": session.ID,
+			"alert":     alert,
+		})
+		go session.addSystemMessage(alert.Message())
+	}
+}
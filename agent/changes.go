@@ -0,0 +1,479 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"boatman/diff"
+	"boatman/git"
+)
+
+// maxChangeFileSize caps the content a change set keeps for each file;
+// larger files are listed without a diff
+const maxChangeFileSize = 1 << 20
+
+// maxChangeSets is how many turns' change sets a session keeps, dropping the oldest
+const maxChangeSets = 50
+
+// fileEditTools maps the tools that change a file to the input field naming it
+var fileEditTools = map[string]string{
+	"Edit":         "file_path",
+	"MultiEdit":    "file_path",
+	"Write":        "file_path",
+	"NotebookEdit": "notebook_path",
+}
+
+// FileChange is a file an assistant turn changed, with its content from
+// before and after the turn
+type FileChange struct {
+	Path     string   `json:"path"` // Relative to the project, or absolute outside it
+	Before   string   `json:"before,omitempty"`
+	After    string   `json:"after,omitempty"`
+	Existed  bool     `json:"existed"` // The file existed before the turn
+	Exists   bool     `json:"exists"`  // The file exists after the turn
+	TooLarge bool     `json:"tooLarge,omitempty"`
+	Withheld bool     `json:"withheld,omitempty"` // Protected or may hold secrets, so its content isn't kept
+	ToolIDs  []string `json:"toolIds,omitempty"`  // Tool calls that changed the file; empty for shell commands
+
+	// For files whose content isn't kept, the git blob holding the content
+	// from before and a hash of the content after, so they can still be reverted
+	BeforeBlob string `json:"beforeBlob,omitempty"`
+	AfterHash  string `json:"afterHash,omitempty"`
+}
+
+// keepsContent reports whether the change holds the file's content rather
+// than only where to find it
+func (f FileChange) keepsContent() bool {
+	return !f.TooLarge && !f.Withheld
+}
+
+// secretFilePatterns are protected path globs for files that usually hold
+// credentials. Their content is kept out of change sets, which are saved with
+// the session, along with that of the session's protected paths.
+var secretFilePatterns = []string{".env", ".env.*", "secrets/", "*.pem", "*.key"}
+
+// ChangeSet is the files changed by one assistant turn, found from its Edit
+// and Write calls and, for Bash, from git status
+type ChangeSet struct {
+	MessageID   string       `json:"messageId"` // User message that started the turn
+	StartedAt   time.Time    `json:"startedAt"`
+	CompletedAt time.Time    `json:"completedAt"`
+	Files       []FileChange `json:"files"`
+}
+
+// turnChanges tracks the change set of the turn in progress
+type turnChanges struct {
+	set *ChangeSet
+	// dirty holds the files git reported changed when the turn started, so
+	// changes made by shell commands can be told apart; nil outside git
+	dirty map[string]bool
+	// tree is the working tree as the turn started, written to git, which
+	// holds the files' content from before; empty outside git
+	tree string
+	// withheld matches files whose content isn't kept
+	withheld PathGuard
+	usedBash bool
+}
+
+// startChangeSet begins recording the files changed by the turn answering
+// the latest user message
+func (s *Session) startChangeSet(authConfig AuthConfig) {
+	s.mu.RLock()
+	messageID := s.turnMessageIDLocked("")
+	s.mu.RUnlock()
+	if messageID == "" {
+		return
+	}
+
+	tracking := &turnChanges{
+		set: &ChangeSet{MessageID: messageID, StartedAt: time.Now()},
+		withheld: PathGuard{
+			Root:      s.ProjectPath,
+			Protected: append(append([]string(nil), secretFilePatterns...), authConfig.ProtectedPaths...),
+		},
+	}
+	repo := git.NewRepository(s.ProjectPath)
+	if repo.IsGitRepo() {
+		if status, err := repo.GetStatus(); err == nil {
+			tracking.dirty = statusPaths(status)
+		}
+		if tree, err := repo.WriteWorkTree(); err == nil {
+			tracking.tree = tree
+		} else {
+			logger.Warn("Failed to record the working tree", "session", s.ID, "error", err)
+		}
+	}
+
+	s.mu.Lock()
+	s.currentChanges = tracking
+	s.mu.Unlock()
+}
+
+// recordToolChangeLocked notes a tool call that may change files, keeping the
+// file's content from before the first call of the turn to touch it.
+// Note: This method expects the caller to hold s.mu lock
+func (s *Session) recordToolChangeLocked(toolName, toolID string, input map[string]any) {
+	tracking := s.currentChanges
+	if tracking == nil {
+		return
+	}
+	if toolName == "Bash" {
+		tracking.usedBash = true
+		return
+	}
+
+	field, ok := fileEditTools[toolName]
+	if !ok {
+		return
+	}
+	path, _ := input[field].(string)
+	if path == "" {
+		return
+	}
+
+	path = s.changePath(path)
+	for i := range tracking.set.Files {
+		if file := &tracking.set.Files[i]; file.Path == path {
+			file.ToolIDs = append(file.ToolIDs, toolID)
+			return
+		}
+	}
+
+	file := FileChange{Path: path, ToolIDs: []string{toolID}}
+	file.Withheld = tracking.withheld.protects(s.absChangePath(path))
+	s.readBefore(tracking, &file)
+	tracking.set.Files = append(tracking.set.Files, file)
+}
+
+// readBefore records a file's content from before the turn. The CLI may
+// have changed the file by the time its tool call is seen, so the content
+// comes from the tree written when the turn started. Only files that tree
+// can't have, ignored ones or those outside the repository, are read from
+// disk. Content that isn't kept is left in git instead, when there is one.
+func (s *Session) readBefore(tracking *turnChanges, file *FileChange) {
+	abs := s.absChangePath(file.Path)
+	if tracking.tree != "" {
+		repo := git.NewRepository(s.ProjectPath)
+		rel, err := filepath.Rel(s.ProjectPath, abs)
+		if err == nil {
+			if id, err := repo.BlobID(tracking.tree, rel); err == nil {
+				if blob, err := repo.ReadBlob(id); err == nil {
+					file.Existed = true
+					file.TooLarge = len(blob) > maxChangeFileSize
+					if !file.keepsContent() {
+						file.BeforeBlob = id
+					} else {
+						file.Before = string(blob)
+					}
+					return
+				}
+			} else if ignored, err := repo.IsIgnored(rel); err == nil && !ignored {
+				return // Not in the tree, so it didn't exist
+			}
+		}
+	}
+
+	before, existed, tooLarge := readChangeFile(abs)
+	file.Existed = existed
+	file.TooLarge = tooLarge
+	if file.keepsContent() {
+		file.Before = before
+	} else if existed && tracking.dirty != nil {
+		// Keep the content in git instead
+		file.BeforeBlob, _ = git.NewRepository(s.ProjectPath).HashObject(abs)
+	}
+}
+
+// finishChangeSet records what the turn's files look like now and keeps the
+// change set if anything actually changed
+func (s *Session) finishChangeSet() {
+	s.mu.Lock()
+	tracking := s.currentChanges
+	s.currentChanges = nil
+	s.mu.Unlock()
+	if tracking == nil {
+		return
+	}
+
+	set := tracking.set
+	if tracking.usedBash && tracking.dirty != nil {
+		s.addShellChanges(set, tracking)
+	}
+
+	// Files whose content isn't kept are listed whether or not it changed
+	changed := set.Files[:0]
+	for _, file := range set.Files {
+		s.readAfter(&file)
+		if !file.keepsContent() || file.Existed != file.Exists || file.Before != file.After {
+			changed = append(changed, file)
+		}
+	}
+	set.Files = changed
+	set.CompletedAt = time.Now()
+	if len(set.Files) == 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.storeChangeSetLocked(*set)
+}
+
+// addShellChanges adds the files git reports changed that were clean when the
+// turn started. Clean files matched HEAD, so that is their content from before.
+func (s *Session) addShellChanges(set *ChangeSet, tracking *turnChanges) {
+	repo := git.NewRepository(s.ProjectPath)
+	status, err := repo.GetStatus()
+	if err != nil {
+		return
+	}
+
+	// Status paths are relative to the top of the work tree, which may be
+	// above the project, so git is asked about them from there
+	top, err := repo.TopLevel()
+	if err != nil {
+		return
+	}
+	prefix, err := repo.Prefix()
+	if err != nil {
+		return
+	}
+	root := git.NewRepository(top)
+
+	tracked := make(map[string]bool, len(set.Files))
+	for _, file := range set.Files {
+		tracked[file.Path] = true
+	}
+	for statusPath := range statusPaths(status) {
+		// Untracked directories are listed whole and can't be diffed as a file
+		if tracking.dirty[statusPath] || strings.HasSuffix(statusPath, "/") {
+			continue
+		}
+
+		var path string
+		if rel, ok := strings.CutPrefix(statusPath, prefix); ok {
+			path = filepath.FromSlash(rel)
+		} else {
+			path = filepath.Join(top, filepath.FromSlash(statusPath))
+		}
+		if tracked[path] {
+			continue
+		}
+
+		file := FileChange{Path: path, Withheld: tracking.withheld.protects(s.absChangePath(path))}
+		if before, err := root.ShowFile("HEAD", statusPath); err == nil {
+			file.Existed = true
+			file.TooLarge = len(before) > maxChangeFileSize
+			if file.keepsContent() {
+				file.Before = before
+			} else {
+				file.BeforeBlob, _ = root.BlobID("HEAD", statusPath)
+			}
+		}
+		set.Files = append(set.Files, file)
+	}
+}
+
+// readAfter records a file's current content as its content after the turn
+func (s *Session) readAfter(file *FileChange) {
+	after, exists, tooLarge := readChangeFile(s.absChangePath(file.Path))
+	file.After = after
+	file.Exists = exists
+	if tooLarge {
+		file.TooLarge = true
+	}
+	if !file.keepsContent() {
+		file.Before, file.After = "", ""
+		file.AfterHash = ""
+		if exists {
+			file.AfterHash, _ = hashFile(s.absChangePath(file.Path))
+		}
+	}
+}
+
+// storeChangeSetLocked keeps a finished change set. A turn that runs again,
+// such as a regenerated response, adds to its earlier change set.
+// Note: This method expects the caller to hold s.mu lock
+func (s *Session) storeChangeSetLocked(set ChangeSet) {
+	for i := range s.changeSets {
+		existing := &s.changeSets[i]
+		if existing.MessageID != set.MessageID {
+			continue
+		}
+		for _, file := range set.Files {
+			if earlier := findFileChange(existing.Files, file.Path); earlier != nil {
+				// Keep the content from before the first run
+				earlier.After = file.After
+				earlier.AfterHash = file.AfterHash
+				earlier.Exists = file.Exists
+				if !file.keepsContent() && earlier.keepsContent() {
+					// Only this run saw the file grow too large or become
+					// protected; its content from before is dropped
+					earlier.TooLarge = file.TooLarge
+					earlier.Withheld = file.Withheld
+					earlier.Before, earlier.After = "", ""
+				}
+				earlier.ToolIDs = append(earlier.ToolIDs, file.ToolIDs...)
+				continue
+			}
+			existing.Files = append(existing.Files, file)
+		}
+		existing.CompletedAt = set.CompletedAt
+		s.UpdatedAt = time.Now()
+		return
+	}
+
+	s.changeSets = append(s.changeSets, set)
+	if len(s.changeSets) > maxChangeSets {
+		s.changeSets = append([]ChangeSet(nil), s.changeSets[len(s.changeSets)-maxChangeSets:]...)
+	}
+	s.UpdatedAt = time.Now()
+}
+
+// GetTurnChanges returns the diffs of the files changed by the turn that
+// messageID belongs to, which may be its user message or any reply. A turn
+// still running is diffed against the files as they are now.
+func (s *Session) GetTurnChanges(messageID string) []diff.FileDiff {
+	s.mu.RLock()
+	turnID := s.turnMessageIDLocked(messageID)
+	set, running := s.changeSetLocked(turnID)
+	s.mu.RUnlock()
+
+	diffs := []diff.FileDiff{}
+	if set == nil {
+		return diffs
+	}
+	for _, file := range set.Files {
+		if running {
+			s.readAfter(&file)
+		}
+		diffs = append(diffs, fileChangeDiff(file))
+	}
+	return diffs
+}
+
+// changeSetLocked returns a copy of the change set for a turn, and whether
+// the turn is still running.
+// Note: This method expects the caller to hold s.mu lock
+func (s *Session) changeSetLocked(turnID string) (*ChangeSet, bool) {
+	if tracking := s.currentChanges; tracking != nil && tracking.set.MessageID == turnID {
+		set := *tracking.set
+		set.Files = append([]FileChange(nil), set.Files...)
+		return &set, true
+	}
+	for i := range s.changeSets {
+		if s.changeSets[i].MessageID == turnID {
+			set := s.changeSets[i]
+			set.Files = append([]FileChange(nil), set.Files...)
+			return &set, false
+		}
+	}
+	return nil, false
+}
+
+// turnMessageIDLocked returns the user message that started the turn
+// messageID belongs to, or the latest user message for an empty ID.
+// Note: This method expects the caller to hold s.mu lock
+func (s *Session) turnMessageIDLocked(messageID string) string {
+	end := len(s.Messages) - 1
+	if messageID != "" {
+		end = -1
+		for i, msg := range s.Messages {
+			if msg.ID == messageID {
+				end = i
+				break
+			}
+		}
+		if end < 0 {
+			return messageID // Trimmed from the transcript; it may still name a turn
+		}
+	}
+
+	for i := end; i >= 0; i-- {
+		if s.Messages[i].Role == "user" {
+			return s.Messages[i].ID
+		}
+	}
+	return ""
+}
+
+// changePath names a file relative to the project when it is inside it
+func (s *Session) changePath(path string) string {
+	abs := s.absChangePath(path)
+	rel, err := filepath.Rel(s.ProjectPath, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return abs
+	}
+	return rel
+}
+
+// absChangePath resolves a change set path against the project
+func (s *Session) absChangePath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(s.ProjectPath, path)
+}
+
+// fileChangeDiff diffs a file's content from before and after its turn
+func fileChangeDiff(file FileChange) diff.FileDiff {
+	var fd diff.FileDiff
+	if !file.keepsContent() {
+		// Shown like a binary file, without content
+		fd = diff.FileDiff{OldPath: file.Path, NewPath: file.Path, Hunks: []diff.Hunk{}, IsBinary: true}
+	} else {
+		fd = diff.Generate(file.Before, file.After, file.Path)
+	}
+	fd.IsNew = !file.Existed
+	fd.IsDelete = !file.Exists
+	return fd
+}
+
+// findFileChange returns the change to path in files, or nil
+func findFileChange(files []FileChange, path string) *FileChange {
+	for i := range files {
+		if files[i].Path == path {
+			return &files[i]
+		}
+	}
+	return nil
+}
+
+// readChangeFile reads a file for a change set. Missing files and
+// directories read as not existing; large files aren't read.
+func readChangeFile(path string) (content string, exists, tooLarge bool) {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() {
+		return "", false, false
+	}
+	if info.Size() > maxChangeFileSize {
+		return "", true, true
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, false
+	}
+	return string(data), true, false
+}
+
+// statusPaths returns every path git status lists
+func statusPaths(status *git.Status) map[string]bool {
+	paths := make(map[string]bool)
+	for _, list := range [][]string{status.Modified, status.Added, status.Deleted, status.Untracked} {
+		for _, path := range list {
+			paths[path] = true
+		}
+	}
+	return paths
+}
+
+// GetTurnChanges returns the diffs of the files changed by the turn a message belongs to
+func (m *Manager) GetTurnChanges(sessionID, messageID string) ([]diff.FileDiff, error) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return session.GetTurnChanges(messageID), nil
+}
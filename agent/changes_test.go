@@ -0,0 +1,197 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"boatman/stream"
+)
+
+// startTestTurn adds a user message and starts recording its changes
+func startTestTurn(session *Session, content string) string {
+	msg := Message{ID: "msg-" + content, Role: "user", Content: content}
+	session.Messages = append(session.Messages, msg)
+	session.startChangeSet(AuthConfig{})
+	return msg.ID
+}
+
+// writeProjectFile writes a file in the project, failing the test on error
+func writeProjectFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+}
+
+func TestTurnChangesFromEdits(t *testing.T) {
+	dir := t.TempDir()
+	writeProjectFile(t, dir, "main.go", "package main\n\nfunc main() {}\n")
+	writeProjectFile(t, dir, "read.go", "package main\n")
+
+	session := NewSession("test-changes", dir)
+	turnID := startTestTurn(session, "first")
+
+	session.handleToolUse(stream.ToolUse{ID: "tool-1", Name: "Edit", Input: map[string]any{"file_path": filepath.Join(dir, "main.go")}})
+	session.handleToolUse(stream.ToolUse{ID: "tool-2", Name: "Write", Input: map[string]any{"file_path": "new.go"}})
+	session.handleToolUse(stream.ToolUse{ID: "tool-3", Name: "Edit", Input: map[string]any{"file_path": filepath.Join(dir, "read.go")}})
+	writeProjectFile(t, dir, "main.go", "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n")
+	writeProjectFile(t, dir, "new.go", "package main\n")
+
+	// Diffs are available while the turn runs
+	if diffs := session.GetTurnChanges(turnID); len(diffs) != 3 {
+		t.Errorf("Expected 3 files in the running turn, got %d", len(diffs))
+	}
+	session.finishChangeSet()
+
+	// The edit that changed nothing drops out; any message of the turn finds it
+	toolMessageID := session.Messages[len(session.Messages)-1].ID
+	diffs := session.GetTurnChanges(toolMessageID)
+	if len(diffs) != 2 {
+		t.Fatalf("Expected 2 changed files, got %d", len(diffs))
+	}
+	if diffs[0].NewPath != "main.go" || diffs[0].IsNew || len(diffs[0].Hunks) != 1 {
+		t.Errorf("Unexpected diff for main.go: %+v", diffs[0])
+	}
+	if diffs[1].NewPath != "new.go" || !diffs[1].IsNew {
+		t.Errorf("Expected new.go as a new file, got %+v", diffs[1])
+	}
+
+	// A later turn has its own change set
+	laterID := startTestTurn(session, "second")
+	session.finishChangeSet()
+	if diffs := session.GetTurnChanges(laterID); len(diffs) != 0 {
+		t.Errorf("Expected no changes for the later turn, got %d", len(diffs))
+	}
+
+	// Change sets survive saving and loading
+	data := newSessionData(session)
+	if len(data.ChangeSets) != 1 || data.ChangeSets[0].MessageID != turnID {
+		t.Errorf("Expected the change set to be persisted, got %+v", data.ChangeSets)
+	}
+	if diffs := sessionFromData(data).GetTurnChanges(turnID); len(diffs) != 2 {
+		t.Errorf("Expected 2 changed files after loading, got %d", len(diffs))
+	}
+}
+
+func TestTurnChangesFromBash(t *testing.T) {
+	dir := initGitProject(t)
+	writeProjectFile(t, dir, "dirty.txt", "changed before the turn")
+
+	session := NewSession("test-bash-changes", dir)
+	turnID := startTestTurn(session, "run a script")
+	session.handleToolUse(stream.ToolUse{ID: "tool-1", Name: "Bash", Input: map[string]any{"command": "./script.sh"}})
+	writeProjectFile(t, dir, "file.txt", "rewritten")
+	writeProjectFile(t, dir, "dirty.txt", "changed again")
+	writeProjectFile(t, dir, "created.txt", "created")
+	session.finishChangeSet()
+
+	diffs := session.GetTurnChanges(turnID)
+	paths := map[string]bool{}
+	for _, fd := range diffs {
+		paths[fd.NewPath] = true
+	}
+	if len(diffs) != 2 || !paths["file.txt"] || !paths["created.txt"] {
+		t.Errorf("Expected file.txt and created.txt, got %v", paths)
+	}
+}
+
+func TestTurnChangesFromBashInRepoSubdirectory(t *testing.T) {
+	repo := initGitProject(t)
+	dir := filepath.Join(repo, "app")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("Failed to create project directory: %v", err)
+	}
+	writeProjectFile(t, dir, "main.go", "package main\n")
+	for _, args := range [][]string{
+		{"add", "."},
+		{"-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-m", "Add app"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, output)
+		}
+	}
+
+	session := NewSession("test-bash-subdir", dir)
+	startTestTurn(session, "run a script")
+	session.handleToolUse(stream.ToolUse{ID: "tool-1", Name: "Bash", Input: map[string]any{"command": "./script.sh"}})
+	writeProjectFile(t, dir, "main.go", "package main\n\nfunc main() {}\n")
+	writeProjectFile(t, repo, "file.txt", "rewritten")
+	session.finishChangeSet()
+
+	changes := map[string]FileChange{}
+	for _, file := range session.changeSets[0].Files {
+		changes[file.Path] = file
+	}
+	if len(changes) != 2 {
+		t.Fatalf("Expected 2 changed files, got %v", changes)
+	}
+	if file, ok := changes["main.go"]; !ok || !file.Existed || file.Before != "package main\n" {
+		t.Errorf("Expected main.go relative to the project with its content from HEAD, got %+v", file)
+	}
+	top, err := filepath.EvalSymlinks(repo) // git reports the top resolved
+	if err != nil {
+		t.Fatalf("Failed to resolve %s: %v", repo, err)
+	}
+	outside := filepath.Join(top, "file.txt")
+	if file, ok := changes[outside]; !ok || file.Before != "original" || file.After != "rewritten" {
+		t.Errorf("Expected %s outside the project by its absolute path, got %+v", outside, changes)
+	}
+}
+
+// editingRunner changes files as it starts, like the CLI applying edits
+// before their tool calls are read from its output
+type editingRunner struct {
+	fakeRunner
+	edit func()
+}
+
+func (r *editingRunner) Start(ctx context.Context, spec ProcessSpec) (Process, error) {
+	r.edit()
+	return r.fakeRunner.Start(ctx, spec)
+}
+
+func TestTurnChangesWhenFilesChangeBeforeTheirToolCall(t *testing.T) {
+	dir := initGitProject(t)
+	writeProjectFile(t, dir, "dirty.txt", "changed before the turn")
+
+	toolUse := func(id, name, path string) string {
+		return `{"type":"tool_use","id":"` + id + `","name":"` + name +
+			`","input":{"file_path":"` + filepath.Join(dir, path) + `"}}` + "\n"
+	}
+	runner := &editingRunner{edit: func() {
+		writeProjectFile(t, dir, "file.txt", "edited")
+		writeProjectFile(t, dir, "dirty.txt", "edited again")
+		writeProjectFile(t, dir, "new.txt", "created")
+	}}
+	runner.stdout = toolUse("tool-1", "Edit", "file.txt") + toolUse("tool-2", "Edit", "dirty.txt") +
+		toolUse("tool-3", "Write", "new.txt") + `{"type":"result","subtype":"success","result":"Done"}` + "\n"
+
+	session := NewSession("test-early-edits", dir)
+	session.runner = runner
+	session.Messages = append(session.Messages, Message{ID: "msg-edit", Role: "user", Content: "edit the files"})
+	session.runWithRateLimitRetry("edit the files", AuthConfig{ClaudeCLIPath: fakeClaudeBinary(t)})
+
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+	if len(session.changeSets) != 1 {
+		t.Fatalf("Expected 1 change set, got %d", len(session.changeSets))
+	}
+	changes := map[string]FileChange{}
+	for _, file := range session.changeSets[0].Files {
+		changes[file.Path] = file
+	}
+	if file := changes["file.txt"]; !file.Existed || file.Before != "original" || file.After != "edited" {
+		t.Errorf("Expected file.txt's content from before the turn, got %+v", file)
+	}
+	if file := changes["dirty.txt"]; !file.Existed || file.Before != "changed before the turn" || file.After != "edited again" {
+		t.Errorf("Expected dirty.txt's uncommitted content from before the turn, got %+v", file)
+	}
+	if file, ok := changes["new.txt"]; !ok || file.Existed || file.After != "created" {
+		t.Errorf("Expected new.txt as created by the turn, got %+v", file)
+	}
+}
@@ -0,0 +1,245 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"boatman/stream"
+)
+
+// ClaudeHistorySessionPrefix starts the IDs of sessions imported from the
+// claude CLI's own transcripts, keeping them apart from Boatman's session IDs
+const ClaudeHistorySessionPrefix = "claude-"
+
+// ClaudeProjectsDir returns the directory where the claude CLI keeps its
+// transcripts, one subdirectory per project
+func ClaudeProjectsDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".claude", "projects"), nil
+}
+
+// claudeTranscriptLine is one entry of a claude CLI transcript
+type claudeTranscriptLine struct {
+	Type        string                   `json:"type"`
+	UUID        string                   `json:"uuid"`
+	SessionID   string                   `json:"sessionId"`
+	Timestamp   time.Time                `json:"timestamp"`
+	Cwd         string                   `json:"cwd"`
+	IsMeta      bool                     `json:"isMeta"`
+	IsSidechain bool                     `json:"isSidechain"` // Sub-agent traffic, kept out of the transcript
+	Message     *claudeTranscriptMessage `json:"message"`
+}
+
+// claudeTranscriptMessage is the API message of a transcript entry. Content is
+// either a plain string or an array of blocks.
+type claudeTranscriptMessage struct {
+	ID      string          `json:"id"`
+	Role    string          `json:"role"`
+	Model   string          `json:"model"`
+	Content json.RawMessage `json:"content"`
+	Usage   *stream.Usage   `json:"usage"`
+}
+
+// blocks returns the message content as blocks, wrapping plain text in a text block
+func (m *claudeTranscriptMessage) blocks() []stream.ContentBlock {
+	if isJSONString(m.Content) {
+		var text string
+		if json.Unmarshal(m.Content, &text) != nil {
+			return nil
+		}
+		return []stream.ContentBlock{{Type: "text", Text: text}}
+	}
+	var blocks []stream.ContentBlock
+	if json.Unmarshal(m.Content, &blocks) != nil {
+		return nil
+	}
+	return blocks
+}
+
+func isJSONString(data json.RawMessage) bool {
+	return len(bytes.TrimSpace(data)) > 0 && bytes.TrimSpace(data)[0] == '"'
+}
+
+// ParseClaudeTranscript reads a claude CLI transcript (JSONL) into a session
+// with the original timestamps and tool metadata. The session's conversation
+// ID is the CLI's, so it can be resumed. Lines that can't be parsed are
+// skipped. It returns nil if the transcript has no messages.
+func ParseClaudeTranscript(r io.Reader) (*Session, error) {
+	var session *Session
+	var model string
+	var usage CostInfo
+	var lastTime time.Time
+	seenUsage := make(map[string]bool) // The CLI repeats a message's usage on each of its lines
+
+	reader := bufio.NewReader(r)
+	for {
+		raw, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, fmt.Errorf("failed to read transcript: %w", readErr)
+		}
+
+		var line claudeTranscriptLine
+		if len(bytes.TrimSpace(raw)) > 0 && json.Unmarshal(raw, &line) == nil &&
+			(line.Type == "user" || line.Type == "assistant") &&
+			line.Message != nil && !line.IsMeta && !line.IsSidechain && line.SessionID != "" {
+
+			if session == nil {
+				session = NewSession(ClaudeHistorySessionPrefix+line.SessionID, line.Cwd)
+				session.conversationID = line.SessionID
+				session.CreatedAt = line.Timestamp
+			}
+			session.appendTranscriptLine(line)
+			lastTime = line.Timestamp
+
+			if line.Message.Model != "" {
+				model = line.Message.Model
+			}
+			if u := line.Message.Usage; u != nil && !seenUsage[line.Message.ID] {
+				seenUsage[line.Message.ID] = true
+				usage.InputTokens += u.InputTokens
+				usage.OutputTokens += u.OutputTokens
+				usage.CacheWriteTokens += u.CacheCreationInputTokens
+				usage.CacheReadTokens += u.CacheReadInputTokens
+				usage.TotalCost += calculateCost(line.Message.Model, *u)
+			}
+		}
+
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	if session == nil || len(session.Messages) == 0 {
+		return nil, nil
+	}
+
+	session.Model = model
+	session.UpdatedAt = lastTime
+	if usage.InputTokens > 0 || usage.OutputTokens > 0 {
+		session.Messages = append(session.Messages, Message{
+			ID:        "msg-usage-" + session.conversationID,
+			Role:      "system",
+			Content:   "📊 Token usage: " + usageSummary(usage),
+			Timestamp: lastTime,
+			Metadata:  &MessageMetadata{CostInfo: &usage, Agent: session.mainAgentCopy()},
+		})
+	}
+	return session, nil
+}
+
+// appendTranscriptLine adds a transcript entry's messages, shaped like the
+// ones a live run produces
+func (s *Session) appendTranscriptLine(line claudeTranscriptLine) {
+	for i, block := range line.Message.blocks() {
+		msg := Message{
+			ID:        fmt.Sprintf("msg-%s-%d", line.UUID, i),
+			Role:      line.Message.Role,
+			Timestamp: line.Timestamp,
+			Metadata:  &MessageMetadata{Agent: s.mainAgentCopy()},
+		}
+
+		switch block.Type {
+		case "text":
+			if strings.TrimSpace(block.Text) == "" {
+				continue
+			}
+			msg.Content = block.Text
+		case "tool_use":
+			var input any
+			if block.Input != nil {
+				input = block.Input
+			}
+			raw, _ := json.Marshal(input)
+			msg.Role = "assistant"
+			msg.Content = s.formatToolUseDescription(block.Name, input)
+			msg.Metadata.ToolUse = &ToolUse{ToolName: block.Name, ToolID: block.ID, Input: raw}
+		case "tool_result":
+			content := string(block.Content)
+			msg.Role = "system"
+			msg.Content = s.formatToolResultDescription(block.ToolUseID, content, block.IsError)
+			msg.Metadata.ToolResult = &ToolResult{ToolID: block.ToolUseID, Content: content, IsError: block.IsError}
+		default:
+			// Thinking and image blocks aren't shown in the transcript
+			continue
+		}
+
+		redactMessage(&msg)
+		s.Messages = append(s.Messages, msg)
+	}
+}
+
+// mainAgentCopy returns a copy of the session's main agent for message metadata
+func (s *Session) mainAgentCopy() *AgentInfo {
+	agentCopy := *s.agents["main"]
+	return &agentCopy
+}
+
+// ImportClaudeHistory imports the claude CLI transcripts under dir (the CLI's
+// projects directory when empty), saving them and adding them to the manager.
+// Transcripts already imported are refreshed unless they are open or the saved
+// copy is at least as new. It returns how many sessions were imported.
+func (m *Manager) ImportClaudeHistory(dir string) (int, error) {
+	if dir == "" {
+		var err error
+		if dir, err = ClaudeProjectsDir(); err != nil {
+			return 0, err
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*.jsonl"))
+	if err != nil {
+		return 0, err
+	}
+
+	imported := 0
+	var errs []error
+	for _, file := range files {
+		session, err := parseClaudeTranscriptFile(file)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(file), err))
+			continue
+		}
+		if session == nil {
+			continue
+		}
+		if _, err := m.GetSession(session.ID); err == nil {
+			continue
+		}
+		if saved, err := LoadSession(session.ID); err == nil && !saved.UpdatedAt.Before(session.UpdatedAt) {
+			continue
+		}
+
+		if err := SaveSession(session); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", filepath.Base(file), err))
+			continue
+		}
+
+		m.mu.Lock()
+		if _, ok := m.sessions[session.ID]; !ok {
+			m.adoptSession(session)
+			imported++
+		}
+		m.mu.Unlock()
+	}
+	return imported, errors.Join(errs...)
+}
+
+func parseClaudeTranscriptFile(path string) (*Session, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseClaudeTranscript(f)
+}
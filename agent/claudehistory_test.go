@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"boatman/stream"
+)
+
+const claudeTranscript = `{"type":"summary","summary":"Fix the login bug","leafUuid":"u4"}
+{"type":"user","uuid":"u1","sessionId":"cli-123","timestamp":"2025-06-01T10:00:00.000Z","cwd":"/work/app","message":{"role":"user","content":"Fix the login bug"}}
+{"type":"user","uuid":"u0","sessionId":"cli-123","timestamp":"2025-06-01T10:00:00.500Z","cwd":"/work/app","isMeta":true,"message":{"role":"user","content":"Caveat: local command output"}}
+{"type":"assistant","uuid":"u2","sessionId":"cli-123","timestamp":"2025-06-01T10:00:05.000Z","cwd":"/work/app","message":{"id":"api-1","role":"assistant","model":"claude-opus-4-1-20250805","content":[{"type":"thinking","thinking":"hmm"},{"type":"text","text":"Let me look."}],"usage":{"input_tokens":1000,"output_tokens":100}}}
+{"type":"assistant","uuid":"u3","sessionId":"cli-123","timestamp":"2025-06-01T10:00:06.000Z","cwd":"/work/app","message":{"id":"api-1","role":"assistant","model":"claude-opus-4-1-20250805","content":[{"type":"tool_use","id":"toolu_1","name":"Read","input":{"file_path":"/work/app/login.go"}}],"usage":{"input_tokens":1000,"output_tokens":100}}}
+{"type":"user","uuid":"u4","sessionId":"cli-123","timestamp":"2025-06-01T10:00:07.000Z","cwd":"/work/app","message":{"role":"user","content":[{"type":"tool_result","tool_use_id":"toolu_1","content":[{"type":"text","text":"package login"}]}]}}
+{"type":"assistant","uuid":"u5","sessionId":"cli-123","timestamp":"2025-06-01T10:00:08.000Z","cwd":"/work/app","isSidechain":true,"message":{"id":"api-2","role":"assistant","content":[{"type":"text","text":"sub-agent chatter"}]}}
+not json
+`
+
+func TestParseClaudeTranscript(t *testing.T) {
+	session, err := ParseClaudeTranscript(strings.NewReader(claudeTranscript))
+	if err != nil {
+		t.Fatalf("ParseClaudeTranscript failed: %v", err)
+	}
+	if session == nil {
+		t.Fatal("Expected a session")
+	}
+
+	if session.ID != "claude-cli-123" || session.conversationID != "cli-123" {
+		t.Errorf("Expected IDs claude-cli-123/cli-123, got %s/%s", session.ID, session.conversationID)
+	}
+	if session.ProjectPath != "/work/app" {
+		t.Errorf("Expected project /work/app, got %s", session.ProjectPath)
+	}
+	if session.Model != "claude-opus-4-1-20250805" {
+		t.Errorf("Expected the transcript's model, got %s", session.Model)
+	}
+	if want := time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC); !session.CreatedAt.Equal(want) {
+		t.Errorf("Expected created at %v, got %v", want, session.CreatedAt)
+	}
+	if want := time.Date(2025, 6, 1, 10, 0, 7, 0, time.UTC); !session.UpdatedAt.Equal(want) {
+		t.Errorf("Expected updated at %v, got %v", want, session.UpdatedAt)
+	}
+
+	// user prompt, assistant text, tool use, tool result, usage summary
+	if len(session.Messages) != 5 {
+		for _, msg := range session.Messages {
+			t.Logf("%s: %s", msg.Role, msg.Content)
+		}
+		t.Fatalf("Expected 5 messages, got %d", len(session.Messages))
+	}
+
+	if msg := session.Messages[0]; msg.Role != "user" || msg.Content != "Fix the login bug" {
+		t.Errorf("Unexpected first message %+v", msg)
+	}
+	if msg := session.Messages[1]; msg.Role != "assistant" || msg.Content != "Let me look." {
+		t.Errorf("Unexpected assistant message %+v", msg)
+	}
+
+	toolUse := session.Messages[2].Metadata.ToolUse
+	if toolUse == nil || toolUse.ToolName != "Read" || toolUse.ToolID != "toolu_1" {
+		t.Fatalf("Expected Read tool metadata, got %+v", toolUse)
+	}
+	if !strings.Contains(string(toolUse.Input), "login.go") {
+		t.Errorf("Expected tool input to be kept, got %s", toolUse.Input)
+	}
+
+	result := session.Messages[3]
+	if result.Metadata.ToolResult == nil || result.Metadata.ToolResult.Content != "package login" {
+		t.Errorf("Expected the tool result content, got %+v", result.Metadata.ToolResult)
+	}
+	if !strings.HasPrefix(result.Content, "✅ Tool result:") {
+		t.Errorf("Expected a tool result line, got %q", result.Content)
+	}
+	if !result.Timestamp.Equal(time.Date(2025, 6, 1, 10, 0, 7, 0, time.UTC)) {
+		t.Errorf("Expected the original timestamp, got %v", result.Timestamp)
+	}
+
+	// The usage repeated across one API message's lines counts once
+	cost := session.Messages[4].Metadata.CostInfo
+	if cost == nil || cost.InputTokens != 1000 || cost.OutputTokens != 100 {
+		t.Fatalf("Expected usage 1000/100, got %+v", cost)
+	}
+	if want := calculateCost("claude-opus-4-1", stream.Usage{InputTokens: 1000, OutputTokens: 100}); cost.TotalCost != want {
+		t.Errorf("Expected cost %.4f, got %.4f", want, cost.TotalCost)
+	}
+}
+
+func TestParseClaudeTranscriptEmpty(t *testing.T) {
+	session, err := ParseClaudeTranscript(strings.NewReader(`{"type":"summary","summary":"nothing"}` + "\n"))
+	if err != nil {
+		t.Fatalf("ParseClaudeTranscript failed: %v", err)
+	}
+	if session != nil {
+		t.Errorf("Expected no session, got %+v", session)
+	}
+}
+
+func TestManagerImportClaudeHistory(t *testing.T) {
+	useTestStore(t)
+
+	dir := t.TempDir()
+	projectDir := filepath.Join(dir, "-work-app")
+	os.MkdirAll(projectDir, 0755)
+	os.WriteFile(filepath.Join(projectDir, "cli-123.jsonl"), []byte(claudeTranscript), 0644)
+	os.WriteFile(filepath.Join(projectDir, "empty.jsonl"), []byte("\n"), 0644)
+
+	m := NewManager()
+	count, err := m.ImportClaudeHistory(dir)
+	if err != nil {
+		t.Fatalf("ImportClaudeHistory failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 imported session, got %d", count)
+	}
+
+	if _, err := m.GetSession("claude-cli-123"); err != nil {
+		t.Errorf("Expected the imported session in the manager: %v", err)
+	}
+	saved, err := LoadSession("claude-cli-123")
+	if err != nil {
+		t.Fatalf("Expected the imported session to be saved: %v", err)
+	}
+	if saved.conversationID != "cli-123" {
+		t.Errorf("Expected the saved session to be resumable, got conversation %q", saved.conversationID)
+	}
+
+	// Importing again leaves open sessions alone
+	count, err = m.ImportClaudeHistory(dir)
+	if err != nil || count != 0 {
+		t.Errorf("Expected a repeat import to do nothing, got %d, %v", count, err)
+	}
+}
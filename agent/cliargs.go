@@ -0,0 +1,117 @@
+package agent
+
+import "strings"
+
+// readOnlyTools never change the project, so every approval mode allows them
+var readOnlyTools = []string{
+	"Read",
+	"Glob",
+	"Grep",
+	"LS",
+	"NotebookRead",
+	"WebFetch",
+	"WebSearch",
+	"TodoWrite",
+	"Task",
+}
+
+// buildClaudeArgs assembles the claude CLI arguments for a run. The prompt is
+// sent on stdin as stream-json so approval decisions can follow it.
+func buildClaudeArgs(conversationID, model string, authConfig AuthConfig, guardSettings string) []string {
+	args := []string{
+		"-p",
+		"--input-format", "stream-json",
+		"--output-format", "stream-json",
+		"--verbose",
+	}
+
+	// Add conversation resume if we have one
+	if conversationID != "" {
+		args = append(args, "-r", conversationID)
+	}
+
+	if model != "" {
+		args = append(args, "--model", model)
+	}
+
+	// The session's other roots, besides the project it runs in
+	if len(authConfig.AdditionalDirs) > 0 {
+		args = append(append(args, "--add-dir"), authConfig.AdditionalDirs...)
+	}
+
+	// MCP servers are automatically loaded from ~/.claude/claude_mcp_config.json
+	// unless the project restricts them to a filtered config. Boatman's own
+	// servers are added either way.
+	var mcpConfigs []string
+	if authConfig.MCPConfigPath != "" {
+		mcpConfigs = append(mcpConfigs, authConfig.MCPConfigPath)
+	}
+	if authConfig.BuiltinMCPConfig != "" {
+		mcpConfigs = append(mcpConfigs, authConfig.BuiltinMCPConfig)
+	}
+	if len(mcpConfigs) > 0 {
+		args = append(append(args, "--mcp-config"), mcpConfigs...)
+	}
+	if authConfig.MCPConfigPath != "" {
+		args = append(args, "--strict-mcp-config")
+	}
+
+	if guardSettings != "" {
+		args = append(args, "--settings", guardSettings)
+	}
+
+	systemPrompt := authConfig.AppendSystemPrompt
+	if authConfig.PlanOnly {
+		systemPrompt = joinSystemPrompts(systemPrompt, planSystemPrompt)
+	}
+	if systemPrompt != "" {
+		args = append(args, "--append-system-prompt", systemPrompt)
+	}
+
+	// Plan runs can't change the project whatever the approval mode
+	if authConfig.PlanOnly {
+		return append(args, planModeArgs(authConfig.ToolPolicy.Denied)...)
+	}
+	args = append(args, approvalModeArgs(authConfig.ApprovalMode, authConfig.ToolPolicy)...)
+	// The guard hook asks about writes to protected paths even in full-auto,
+	// and the asks need somewhere to go
+	if authConfig.ApprovalMode == "full-auto" && len(authConfig.ProtectedPaths) > 0 {
+		args = append(args, "--permission-prompt-tool", "stdio")
+	}
+	if len(authConfig.ToolPolicy.Denied) > 0 {
+		args = append(args, "--disallowedTools", strings.Join(authConfig.ToolPolicy.Denied, ","))
+	}
+	return args
+}
+
+// approvalModeArgs maps an approval mode to claude permission flags. Tool calls
+// that aren't allowed up front are sent back over stdin as permission requests
+// and wait for the user to approve or reject them. The policy's allowed tools
+// join the read-only ones, less any it denies.
+func approvalModeArgs(mode string, policy ToolPolicy) []string {
+	tools := appendTools(append([]string(nil), readOnlyTools...), policy.Allowed)
+	allowed := strings.Join(withoutTools(tools, policy.Denied), ",")
+
+	switch mode {
+	case "full-auto":
+		// Every tool runs without approval
+		return []string{"--permission-mode", "bypassPermissions"}
+	case "auto-edit":
+		// File edits are accepted automatically; shell commands still need approval
+		return []string{"--permission-mode", "acceptEdits", "--allowedTools", allowed, "--permission-prompt-tool", "stdio"}
+	default:
+		// "suggest": read-only tools run, everything else waits for approval
+		return []string{"--permission-mode", "default", "--allowedTools", allowed, "--permission-prompt-tool", "stdio"}
+	}
+}
+
+// joinSystemPrompts combines non-empty system prompt snippets, separated by a blank line
+func joinSystemPrompts(prompts ...string) string {
+	var parts []string
+	for _, p := range prompts {
+		if p = strings.TrimSpace(p); p != "" {
+			parts = append(parts, p)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildClaudeArgsApprovalModes(t *testing.T) {
+	readOnly := "Read,Glob,Grep,LS,NotebookRead,WebFetch,WebSearch,TodoWrite,Task"
+	base := []string{"-p", "--input-format", "stream-json", "--output-format", "stream-json", "--verbose"}
+	prompt := []string{"--permission-prompt-tool", "stdio"}
+
+	tests := []struct {
+		mode     string
+		expected []string
+	}{
+		{"suggest", append(append(append([]string{}, base...), "--permission-mode", "default", "--allowedTools", readOnly), prompt...)},
+		{"auto-edit", append(append(append([]string{}, base...), "--permission-mode", "acceptEdits", "--allowedTools", readOnly), prompt...)},
+		{"full-auto", append(append([]string{}, base...), "--permission-mode", "bypassPermissions")},
+		{"", append(append(append([]string{}, base...), "--permission-mode", "default", "--allowedTools", readOnly), prompt...)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			args := buildClaudeArgs("", "", AuthConfig{ApprovalMode: tt.mode}, "")
+			if !reflect.DeepEqual(args, tt.expected) {
+				t.Errorf("Expected args %q, got %q", tt.expected, args)
+			}
+			for _, arg := range args {
+				if arg == "--dangerously-skip-permissions" {
+					t.Error("Expected no --dangerously-skip-permissions flag")
+				}
+			}
+		})
+	}
+}
+
+func TestBuildClaudeArgsOptions(t *testing.T) {
+	args := buildClaudeArgs("conv-123", "opus", AuthConfig{
+		ApprovalMode:       "full-auto",
+		MCPConfigPath:      "/tmp/mcp.json",
+		AppendSystemPrompt: "Answer in French.",
+	}, `{"hooks":{}}`)
+
+	expected := []string{
+		"-p",
+		"--input-format", "stream-json",
+		"--output-format", "stream-json",
+		"--verbose",
+		"-r", "conv-123",
+		"--model", "opus",
+		"--mcp-config", "/tmp/mcp.json", "--strict-mcp-config",
+		"--settings", `{"hooks":{}}`,
+		"--append-system-prompt", "Answer in French.",
+		"--permission-mode", "bypassPermissions",
+	}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected args %q, got %q", expected, args)
+	}
+}
+
+func TestBuildClaudeArgsBuiltinMCPConfig(t *testing.T) {
+	builtin := `{"mcpServers":{}}`
+	tests := []struct {
+		name     string
+		config   AuthConfig
+		expected []string
+	}{
+		{"alone", AuthConfig{BuiltinMCPConfig: builtin}, []string{"--mcp-config", builtin}},
+		{"with project config", AuthConfig{MCPConfigPath: "/tmp/mcp.json", BuiltinMCPConfig: builtin},
+			[]string{"--mcp-config", "/tmp/mcp.json", builtin, "--strict-mcp-config"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := buildClaudeArgs("", "", tt.config, "")
+			start := -1
+			for i, arg := range args {
+				if arg == "--mcp-config" {
+					start = i
+				}
+			}
+			if start < 0 || start+len(tt.expected) > len(args) || !reflect.DeepEqual(args[start:start+len(tt.expected)], tt.expected) {
+				t.Errorf("Expected %q in args, got %q", tt.expected, args)
+			}
+		})
+	}
+}
+
+func TestBuildClaudeArgsAdditionalDirs(t *testing.T) {
+	args := buildClaudeArgs("", "", AuthConfig{AdditionalDirs: []string{"/src/api", "/src/shared"}}, "")
+	for i, arg := range args {
+		if arg == "--add-dir" {
+			if i+2 >= len(args) || args[i+1] != "/src/api" || args[i+2] != "/src/shared" {
+				t.Errorf("Expected both directories after --add-dir, got %q", args)
+			}
+			return
+		}
+	}
+	t.Errorf("Expected --add-dir in args, got %q", args)
+}
@@ -0,0 +1,211 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// commandCheckInterval is how often running Bash commands are checked
+	commandCheckInterval = 5 * time.Second
+	// stillRunningAfter is how long a command runs before it is reported as still running
+	stillRunningAfter = 15 * time.Second
+)
+
+// RunningCommand is a Bash tool invocation that hasn't returned a result yet
+type RunningCommand struct {
+	ToolID         string    `json:"toolId"`
+	Command        string    `json:"command"`
+	StartedAt      time.Time `json:"startedAt"`
+	ElapsedSeconds int       `json:"elapsedSeconds"`
+	OverLimit      bool      `json:"overLimit"` // Exceeded the configured max runtime
+	Finished       bool      `json:"finished"`  // Result arrived; clears the indicator
+
+	reported bool // Progress has been sent to the command handler
+}
+
+// SetCommandHandler sets the callback for long-running command updates
+func (s *Session) SetCommandHandler(handler func(RunningCommand)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onCommand = handler
+}
+
+// SetCommandTimeout sets the max runtime for a single Bash command (0 disables the limit)
+func (s *Session) SetCommandTimeout(timeout time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.commandTimeout = timeout
+}
+
+// GetRunningCommands returns the Bash commands that are still running, oldest first
+func (s *Session) GetRunningCommands() []RunningCommand {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.runningCommandsLocked(time.Now())
+}
+
+// runningCommandsLocked snapshots running commands. Caller must hold s.mu.
+func (s *Session) runningCommandsLocked(now time.Time) []RunningCommand {
+	commands := make([]RunningCommand, 0, len(s.runningCommands))
+	for _, cmd := range s.runningCommands {
+		c := *cmd
+		c.ElapsedSeconds = int(now.Sub(c.StartedAt).Seconds())
+		commands = append(commands, c)
+	}
+	sort.Slice(commands, func(i, j int) bool {
+		return commands[i].StartedAt.Before(commands[j].StartedAt)
+	})
+	return commands
+}
+
+// trackCommandStart records a Bash tool invocation. Caller must hold s.mu.
+func (s *Session) trackCommandStart(toolID, command string) {
+	if toolID == "" {
+		return
+	}
+	if s.runningCommands == nil {
+		s.runningCommands = make(map[string]*RunningCommand)
+	}
+	s.runningCommands[toolID] = &RunningCommand{
+		ToolID:    toolID,
+		Command:   redactString(command),
+		StartedAt: time.Now(),
+	}
+}
+
+// trackCommandFinish stops tracking a command and returns it if the UI was
+// told it was running, so the indicator can be cleared. Caller must hold s.mu.
+func (s *Session) trackCommandFinish(toolID string) *RunningCommand {
+	cmd, ok := s.runningCommands[toolID]
+	if !ok {
+		return nil
+	}
+	delete(s.runningCommands, toolID)
+	if !cmd.reported {
+		return nil
+	}
+	cmd.Finished = true
+	cmd.ElapsedSeconds = int(time.Since(cmd.StartedAt).Seconds())
+	return cmd
+}
+
+// watchCommands periodically checks running commands until done is closed
+func (s *Session) watchCommands(done <-chan struct{}) {
+	ticker := time.NewTicker(commandCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			s.checkRunningCommands(now)
+		}
+	}
+}
+
+// checkRunningCommands reports commands that are still running and flags those
+// over the max runtime, offering to kill them
+func (s *Session) checkRunningCommands(now time.Time) {
+	s.mu.Lock()
+	var updates, exceeded []RunningCommand
+	for _, cmd := range s.runningCommands {
+		elapsed := now.Sub(cmd.StartedAt)
+		if elapsed < stillRunningAfter {
+			continue
+		}
+		cmd.reported = true
+		cmd.ElapsedSeconds = int(elapsed.Seconds())
+		if s.commandTimeout > 0 && elapsed >= s.commandTimeout && !cmd.OverLimit {
+			cmd.OverLimit = true
+			exceeded = append(exceeded, *cmd)
+		}
+		updates = append(updates, *cmd)
+	}
+	limit := s.commandTimeout
+	handler := s.onCommand
+	s.mu.Unlock()
+
+	for _, cmd := range exceeded {
+		s.addSystemMessage(fmt.Sprintf("⏱️  Command has been running for %s, over the %s limit: %s\nKill it to stop the run; the agent is told with your next message.",
+			time.Duration(cmd.ElapsedSeconds)*time.Second, limit, truncateString(cmd.Command, 200)))
+	}
+	if handler != nil {
+		for _, cmd := range updates {
+			handler(cmd)
+		}
+	}
+}
+
+// KillRunningCommands stops the current run because its commands are taking too
+// long. The killed commands are reported in the transcript once the run has
+// shut down, and to claude with the next prompt.
+func (s *Session) KillRunningCommands() error {
+	s.mu.Lock()
+	if s.runCancel == nil || len(s.runningCommands) == 0 {
+		s.mu.Unlock()
+		return fmt.Errorf("no command is running")
+	}
+	s.killedCommands = s.runningCommandsLocked(time.Now())
+	cancel := s.runCancel
+	s.mu.Unlock()
+
+	cancel()
+	return nil
+}
+
+// finishCommandTracking clears command state at the end of a run and reports
+// any commands that were killed
+func (s *Session) finishCommandTracking() {
+	s.mu.Lock()
+	killed := s.killedCommands
+	var cleared []RunningCommand
+	for _, cmd := range s.runningCommands {
+		if cmd.reported {
+			c := *cmd
+			c.Finished = true
+			cleared = append(cleared, c)
+		}
+	}
+	s.runningCommands = nil
+	s.killedCommands = nil
+	s.unreportedKills = append(s.unreportedKills, killed...)
+	s.runCancel = nil
+	handler := s.onCommand
+	s.mu.Unlock()
+
+	for _, cmd := range killed {
+		s.addSystemMessage(fmt.Sprintf("🛑 Killed command after %s, the agent is told with your next message: %s",
+			time.Duration(cmd.ElapsedSeconds)*time.Second, truncateString(cmd.Command, 200)))
+	}
+	if handler != nil {
+		for _, cmd := range cleared {
+			handler(cmd)
+		}
+	}
+}
+
+// withKilledCommands prefixes prompt with the commands killed since the last
+// prompt. Claude never saw them finish, so without this it would assume they
+// were still running or had succeeded.
+func (s *Session) withKilledCommands(prompt string) string {
+	s.mu.Lock()
+	killed := s.unreportedKills
+	s.unreportedKills = nil
+	s.mu.Unlock()
+	if len(killed) == 0 {
+		return prompt
+	}
+
+	var b strings.Builder
+	b.WriteString("The user killed these commands from the previous turn because they ran too long. They didn't finish:\n")
+	for _, cmd := range killed {
+		fmt.Fprintf(&b, "- %s (after %s)\n", truncateString(cmd.Command, 200), time.Duration(cmd.ElapsedSeconds)*time.Second)
+	}
+	b.WriteString("\n")
+	b.WriteString(prompt)
+	return b.String()
+}
@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"boatman/stream"
+)
+
+func bashToolUse(id, command string) stream.ToolUse {
+	return stream.ToolUse{
+		Name:  "Bash",
+		ID:    id,
+		Input: map[string]any{"command": command},
+	}
+}
+
+func TestRunningCommandTracking(t *testing.T) {
+	session := NewSession("test-commands", "/tmp/test")
+
+	var updates []RunningCommand
+	session.SetCommandHandler(func(cmd RunningCommand) {
+		updates = append(updates, cmd)
+	})
+	session.SetCommandTimeout(time.Minute)
+
+	session.handleToolUse(bashToolUse("tool-1", "npm test"))
+	session.handleToolUse(stream.ToolUse{
+		Name:  "Read",
+		ID:    "tool-2",
+		Input: map[string]any{"file_path": "main.go"},
+	})
+
+	running := session.GetRunningCommands()
+	if len(running) != 1 {
+		t.Fatalf("Expected 1 running command, got %d", len(running))
+	}
+	if running[0].Command != "npm test" {
+		t.Errorf("Expected command 'npm test', got %s", running[0].Command)
+	}
+
+	// Fresh commands aren't reported yet
+	session.checkRunningCommands(time.Now())
+	if len(updates) != 0 {
+		t.Errorf("Expected no updates for a fresh command, got %d", len(updates))
+	}
+
+	// Still running but under the limit
+	session.checkRunningCommands(time.Now().Add(30 * time.Second))
+	if len(updates) != 1 || updates[0].OverLimit {
+		t.Fatalf("Expected one still-running update under the limit, got %+v", updates)
+	}
+
+	// Over the limit: flagged once, with a system message offering to kill it
+	session.checkRunningCommands(time.Now().Add(2 * time.Minute))
+	session.checkRunningCommands(time.Now().Add(3 * time.Minute))
+	if !updates[len(updates)-1].OverLimit {
+		t.Error("Expected command to be flagged over the limit")
+	}
+
+	warnings := 0
+	for _, msg := range session.GetMessages() {
+		if strings.Contains(msg.Content, "over the 1m0s limit") {
+			warnings++
+		}
+	}
+	if warnings != 1 {
+		t.Errorf("Expected 1 over-limit warning, got %d", warnings)
+	}
+
+	// The result clears the indicator
+	updates = nil
+	session.handleToolResult(stream.ToolResult{ToolUseID: "tool-1", Content: "ok"})
+	if len(updates) != 1 || !updates[0].Finished {
+		t.Errorf("Expected a finished update, got %+v", updates)
+	}
+	if len(session.GetRunningCommands()) != 0 {
+		t.Error("Expected no running commands after the result")
+	}
+}
+
+func TestCommandTimeoutDisabled(t *testing.T) {
+	session := NewSession("test-no-limit", "/tmp/test")
+
+	var updates []RunningCommand
+	session.SetCommandHandler(func(cmd RunningCommand) {
+		updates = append(updates, cmd)
+	})
+
+	session.handleToolUse(bashToolUse("tool-1", "sleep 1000"))
+	session.checkRunningCommands(time.Now().Add(24 * time.Hour))
+
+	if len(updates) != 1 {
+		t.Fatalf("Expected 1 update, got %d", len(updates))
+	}
+	if updates[0].OverLimit {
+		t.Error("Expected no limit when the timeout is disabled")
+	}
+}
+
+func TestKillRunningCommands(t *testing.T) {
+	session := NewSession("test-kill", "/tmp/test")
+
+	if err := session.KillRunningCommands(); err == nil {
+		t.Error("Expected error when no command is running")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	session.mu.Lock()
+	session.runCancel = cancel
+	session.mu.Unlock()
+
+	session.handleToolUse(bashToolUse("tool-1", "make integration"))
+
+	if err := session.KillRunningCommands(); err != nil {
+		t.Fatalf("KillRunningCommands failed: %v", err)
+	}
+	if ctx.Err() == nil {
+		t.Error("Expected the run context to be cancelled")
+	}
+
+	session.finishCommandTracking()
+
+	found := false
+	for _, msg := range session.GetMessages() {
+		if strings.Contains(msg.Content, "Killed command") && strings.Contains(msg.Content, "make integration") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected a report of the killed command")
+	}
+	if len(session.GetRunningCommands()) != 0 {
+		t.Error("Expected running commands to be cleared")
+	}
+
+	// Claude hears about it with the next prompt, and only that one
+	prompt := session.withKilledCommands("what next?")
+	if !strings.Contains(prompt, "make integration") || !strings.HasSuffix(prompt, "what next?") {
+		t.Errorf("Expected the killed command before the prompt, got %q", prompt)
+	}
+	if prompt := session.withKilledCommands("and now?"); prompt != "and now?" {
+		t.Errorf("Expected the kill reported once, got %q", prompt)
+	}
+}
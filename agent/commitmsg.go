@@ -0,0 +1,82 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// commitDiffLimit caps how much of the staged diff is sent for a commit message
+const commitDiffLimit = 60_000
+
+// commitMessagePrompt asks for a conventional commit message; the diff follows it
+const commitMessagePrompt = `Write a git commit message for the staged changes below, following the Conventional Commits format:
+
+<type>(<optional scope>): <summary>
+
+<optional body>
+
+Use one of these types: feat, fix, docs, style, refactor, perf, test, build, ci, chore, revert.
+Keep the summary under 72 characters, in the imperative mood, without a trailing period.
+Add a short body only when the summary can't explain why the change was made; wrap it at 72 characters.
+Reply with the commit message only, without quotes, code fences, or commentary.
+
+Staged changes:
+`
+
+// conventionalSubject matches a Conventional Commits subject line, e.g. "fix(api)!: handle nil"
+var conventionalSubject = regexp.MustCompile(`^(?i)([a-z]+)(\([^)]+\))?!?: \S`)
+
+// GenerateCommitMessage asks claude for a conventional commit message
+// describing a staged diff of the project
+func (m *Manager) GenerateCommitMessage(ctx context.Context, projectPath, stagedDiff string) (string, error) {
+	if strings.TrimSpace(stagedDiff) == "" {
+		return "", fmt.Errorf("no staged changes to describe")
+	}
+	if len(stagedDiff) > commitDiffLimit {
+		stagedDiff = stagedDiff[:commitDiffLimit] + "\n[diff truncated]\n"
+	}
+
+	reply, err := m.RunPrompt(ctx, projectPath, commitMessagePrompt+stagedDiff)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate commit message: %w", err)
+	}
+
+	message := cleanCommitMessage(reply)
+	if message == "" {
+		return "", fmt.Errorf("claude returned an empty commit message")
+	}
+	return message, nil
+}
+
+// cleanCommitMessage strips the wrapping a model may add around a commit
+// message and makes sure the subject follows Conventional Commits
+func cleanCommitMessage(reply string) string {
+	message := strings.TrimSpace(reply)
+
+	// Code fences, with or without a language
+	if strings.HasPrefix(message, "```") {
+		message = strings.TrimPrefix(message, "```")
+		if newline := strings.Index(message, "\n"); newline >= 0 {
+			message = message[newline+1:]
+		}
+		message = strings.TrimSuffix(strings.TrimSpace(message), "```")
+	}
+	message = strings.Trim(strings.TrimSpace(message), "\"'`")
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return ""
+	}
+
+	lines := strings.Split(message, "\n")
+	subject := strings.TrimSuffix(strings.TrimSpace(lines[0]), ".")
+	if match := conventionalSubject.FindStringSubmatchIndex(subject); match != nil {
+		// Types are lowercase, e.g. "Fix:" becomes "fix:"
+		subject = strings.ToLower(subject[:match[3]]) + subject[match[3]:]
+	} else {
+		subject = "chore: " + subject
+	}
+	lines[0] = subject
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCleanCommitMessage(t *testing.T) {
+	tests := []struct {
+		name     string
+		reply    string
+		expected string
+	}{
+		{"plain", "feat(api): add session export", "feat(api): add session export"},
+		{"body kept", "fix: handle nil usage\n\nThe CLI omits usage on errors.", "fix: handle nil usage\n\nThe CLI omits usage on errors."},
+		{"code fence", "```text\nrefactor: split runner\n```", "refactor: split runner"},
+		{"quoted", "\"docs: explain retries.\"", "docs: explain retries"},
+		{"uppercase type", "Fix(ui)!: drop the old layout", "fix(ui)!: drop the old layout"},
+		{"not conventional", "Update dependencies", "chore: Update dependencies"},
+		{"empty", "```\n```", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanCommitMessage(tt.reply); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestGenerateCommitMessage(t *testing.T) {
+	binary := fakeClaudeBinary(t)
+	newManager := func(runner *fakeRunner) *Manager {
+		m := NewManager()
+		m.runner = runner
+		m.SetAuthConfigGetter(func() AuthConfig {
+			return AuthConfig{ClaudeCLIPath: binary}
+		})
+		return m
+	}
+	diff := "diff --git a/main.go b/main.go\n+func main() {}\n"
+
+	t.Run("returns the cleaned reply", func(t *testing.T) {
+		runner := &fakeRunner{stdout: `{"type":"result","subtype":"success","result":"` + "```\\nfeat: add main\\n```" + `"}`}
+		m := newManager(runner)
+
+		message, err := m.GenerateCommitMessage(context.Background(), "/tmp/project", diff)
+		if err != nil {
+			t.Fatalf("GenerateCommitMessage failed: %v", err)
+		}
+		if message != "feat: add main" {
+			t.Errorf("Expected the cleaned message, got %q", message)
+		}
+		if !strings.Contains(runner.stdin.String(), diff) {
+			t.Error("Expected the diff in the prompt")
+		}
+		if runner.spec.Dir != "/tmp/project" || !strings.Contains(strings.Join(runner.spec.Args, " "), "--max-turns 1") {
+			t.Errorf("Expected a single-turn run in the project, got %+v", runner.spec)
+		}
+	})
+
+	t.Run("no staged changes", func(t *testing.T) {
+		runner := &fakeRunner{}
+		if _, err := newManager(runner).GenerateCommitMessage(context.Background(), "/tmp/project", " \n"); err == nil {
+			t.Error("Expected an error without a diff")
+		}
+		if runner.starts != 0 {
+			t.Error("Expected claude not to run")
+		}
+	})
+
+	t.Run("claude fails", func(t *testing.T) {
+		runner := &fakeRunner{
+			stdout:  `{"type":"result","is_error":true,"result":"Invalid API key · Please run /login"}`,
+			waitErr: exitError(1),
+		}
+		_, err := newManager(runner).GenerateCommitMessage(context.Background(), "/tmp/project", diff)
+		if !errors.Is(err, ErrAuth) {
+			t.Errorf("Expected ErrAuth, got %v", err)
+		}
+	})
+}
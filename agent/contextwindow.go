@@ -0,0 +1,144 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+
+	"boatman/stream"
+)
+
+// Context window sizes in tokens. Models select the 1M-token window with a
+// "[1m]" suffix, e.g. "sonnet[1m]".
+const (
+	defaultContextWindow  = 200_000
+	extendedContextWindow = 1_000_000
+)
+
+// contextWarnRatio is how full the context window gets before the user is warned
+const contextWarnRatio = 0.9
+
+// ContextWindowFor returns the context window of a model in tokens
+func ContextWindowFor(model string) int {
+	if strings.HasSuffix(strings.ToLower(model), "[1m]") {
+		return extendedContextWindow
+	}
+	return defaultContextWindow
+}
+
+// TurnUsage is the tokens of one API call
+type TurnUsage struct {
+	InputTokens      int `json:"inputTokens"`
+	OutputTokens     int `json:"outputTokens"`
+	CacheWriteTokens int `json:"cacheWriteTokens"`
+	CacheReadTokens  int `json:"cacheReadTokens"`
+}
+
+// contextTokens is how much of the context window the call's conversation
+// fills, which the next call sends again
+func (u TurnUsage) contextTokens() int {
+	return u.InputTokens + u.CacheWriteTokens + u.CacheReadTokens + u.OutputTokens
+}
+
+// ContextUsage reports how full a session's context window is
+type ContextUsage struct {
+	Model           string  `json:"model"`
+	ContextWindow   int     `json:"contextWindow"`
+	UsedTokens      int     `json:"usedTokens"` // Estimated from the latest call
+	RemainingTokens int     `json:"remainingTokens"`
+	PercentUsed     float64 `json:"percentUsed"`
+	NearLimit       bool    `json:"nearLimit"` // Within 10% of the window
+	Turns           int     `json:"turns"`
+	// Totals across the conversation
+	TotalInputTokens      int `json:"totalInputTokens"`
+	TotalOutputTokens     int `json:"totalOutputTokens"`
+	TotalCacheWriteTokens int `json:"totalCacheWriteTokens"`
+	TotalCacheReadTokens  int `json:"totalCacheReadTokens"`
+}
+
+// ContextTracker accumulates a conversation's token usage per turn to
+// estimate how much of the context window is left. Session guards it with
+// its lock.
+type ContextTracker struct {
+	last   TurnUsage
+	total  TurnUsage
+	turns  int
+	warned bool // The near-limit warning was shown and usage hasn't dropped since
+}
+
+// Record adds the usage of a turn. It returns true when the conversation first
+// comes within 10% of the model's context window.
+func (c *ContextTracker) Record(usage stream.Usage, model string) bool {
+	turn := TurnUsage{
+		InputTokens:      usage.InputTokens,
+		OutputTokens:     usage.OutputTokens,
+		CacheWriteTokens: usage.CacheCreationInputTokens,
+		CacheReadTokens:  usage.CacheReadInputTokens,
+	}
+	c.last = turn
+	c.total.InputTokens += turn.InputTokens
+	c.total.OutputTokens += turn.OutputTokens
+	c.total.CacheWriteTokens += turn.CacheWriteTokens
+	c.total.CacheReadTokens += turn.CacheReadTokens
+	c.turns++
+
+	// Warn again if the conversation was compacted and fills up once more
+	near := c.Usage(model).NearLimit
+	warn := near && !c.warned
+	c.warned = near
+	return warn
+}
+
+// Reset forgets the conversation, e.g. when a new one is started
+func (c *ContextTracker) Reset() {
+	*c = ContextTracker{}
+}
+
+// Usage reports how full the context window of model is
+func (c *ContextTracker) Usage(model string) ContextUsage {
+	window := ContextWindowFor(model)
+	used := c.last.contextTokens()
+	return ContextUsage{
+		Model:                 model,
+		ContextWindow:         window,
+		UsedTokens:            used,
+		RemainingTokens:       max(window-used, 0),
+		PercentUsed:           float64(used) / float64(window) * 100,
+		NearLimit:             float64(used) >= float64(window)*contextWarnRatio,
+		Turns:                 c.turns,
+		TotalInputTokens:      c.total.InputTokens,
+		TotalOutputTokens:     c.total.OutputTokens,
+		TotalCacheWriteTokens: c.total.CacheWriteTokens,
+		TotalCacheReadTokens:  c.total.CacheReadTokens,
+	}
+}
+
+// contextWarning is the transcript message shown when the context window is nearly full
+func contextWarning(usage ContextUsage) string {
+	return fmt.Sprintf("⚠️  Context window is %.0f%% full (%d of %d tokens). Run /compact or start a new session before it runs out.",
+		usage.PercentUsed, usage.UsedTokens, usage.ContextWindow)
+}
+
+// contextTrackerFromMessages rebuilds a restored session's tracker from its usage messages
+func contextTrackerFromMessages(messages []Message, model string) ContextTracker {
+	var tracker ContextTracker
+	for _, msg := range messages {
+		if msg.Metadata == nil || msg.Metadata.CostInfo == nil {
+			continue
+		}
+		cost := msg.Metadata.CostInfo
+		tracker.Record(stream.Usage{
+			InputTokens:              cost.InputTokens,
+			OutputTokens:             cost.OutputTokens,
+			CacheCreationInputTokens: cost.CacheWriteTokens,
+			CacheReadInputTokens:     cost.CacheReadTokens,
+		}, model)
+	}
+	return tracker
+}
+
+// GetContextUsage reports how full the session's context window is
+func (s *Session) GetContextUsage() ContextUsage {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.contextTracker.Usage(s.Model)
+}
@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"boatman/stream"
+)
+
+func TestContextWindowFor(t *testing.T) {
+	tests := []struct {
+		model    string
+		expected int
+	}{
+		{"", defaultContextWindow},
+		{"claude-sonnet-4-5-20250929", defaultContextWindow},
+		{"sonnet[1m]", extendedContextWindow},
+		{"claude-sonnet-4-5[1M]", extendedContextWindow},
+	}
+
+	for _, tt := range tests {
+		if got := ContextWindowFor(tt.model); got != tt.expected {
+			t.Errorf("ContextWindowFor(%q): expected %d, got %d", tt.model, tt.expected, got)
+		}
+	}
+}
+
+func TestContextTracker(t *testing.T) {
+	t.Run("usage comes from the latest turn", func(t *testing.T) {
+		var tracker ContextTracker
+		tracker.Record(stream.Usage{InputTokens: 1000, OutputTokens: 200}, "sonnet")
+		tracker.Record(stream.Usage{InputTokens: 100, OutputTokens: 300, CacheReadInputTokens: 1200, CacheCreationInputTokens: 400}, "sonnet")
+
+		usage := tracker.Usage("sonnet")
+		if usage.UsedTokens != 2000 {
+			t.Errorf("Expected 2000 used tokens, got %d", usage.UsedTokens)
+		}
+		if usage.RemainingTokens != defaultContextWindow-2000 {
+			t.Errorf("Expected %d remaining tokens, got %d", defaultContextWindow-2000, usage.RemainingTokens)
+		}
+		if usage.PercentUsed != 1 {
+			t.Errorf("Expected 1%% used, got %v", usage.PercentUsed)
+		}
+		if usage.Turns != 2 || usage.TotalInputTokens != 1100 || usage.TotalOutputTokens != 500 {
+			t.Errorf("Expected totals for 2 turns, got %+v", usage)
+		}
+		if usage.TotalCacheReadTokens != 1200 || usage.TotalCacheWriteTokens != 400 {
+			t.Errorf("Expected cache totals 1200/400, got %d/%d", usage.TotalCacheReadTokens, usage.TotalCacheWriteTokens)
+		}
+	})
+
+	t.Run("warns once near the limit", func(t *testing.T) {
+		var tracker ContextTracker
+		if tracker.Record(stream.Usage{InputTokens: 170_000}, "sonnet") {
+			t.Error("Expected no warning at 85%")
+		}
+		if !tracker.Record(stream.Usage{InputTokens: 185_000}, "sonnet") {
+			t.Error("Expected a warning at 92.5%")
+		}
+		if tracker.Record(stream.Usage{InputTokens: 190_000}, "sonnet") {
+			t.Error("Expected the warning only once")
+		}
+
+		// After compacting, filling up again warns again
+		tracker.Record(stream.Usage{InputTokens: 20_000}, "sonnet")
+		if !tracker.Record(stream.Usage{InputTokens: 195_000}, "sonnet") {
+			t.Error("Expected a new warning after the conversation was compacted")
+		}
+	})
+
+	t.Run("larger windows", func(t *testing.T) {
+		var tracker ContextTracker
+		if tracker.Record(stream.Usage{InputTokens: 185_000}, "sonnet[1m]") {
+			t.Error("Expected no warning for a 1M window")
+		}
+	})
+
+	t.Run("over the limit", func(t *testing.T) {
+		var tracker ContextTracker
+		tracker.Record(stream.Usage{InputTokens: 250_000}, "sonnet")
+		if usage := tracker.Usage("sonnet"); usage.RemainingTokens != 0 || !usage.NearLimit {
+			t.Errorf("Expected no tokens remaining, got %+v", usage)
+		}
+	})
+}
+
+func TestSessionContextUsage(t *testing.T) {
+	session := NewSession("test-context", "/tmp/project")
+	session.Model = "sonnet"
+
+	session.handleUsageInfo(stream.Usage{InputTokens: 50_000, OutputTokens: 1000}, true)
+	if usage := session.GetContextUsage(); usage.UsedTokens != 51_000 || usage.NearLimit {
+		t.Errorf("Expected 51000 tokens used, got %+v", usage)
+	}
+
+	session.handleUsageInfo(stream.Usage{InputTokens: 1000, CacheReadInputTokens: 183_000, OutputTokens: 2000}, true)
+
+	warnings := 0
+	for _, msg := range session.GetMessages() {
+		if strings.Contains(msg.Content, "Context window is 93% full") {
+			warnings++
+		}
+	}
+	if warnings != 1 {
+		t.Errorf("Expected one context warning, got %d", warnings)
+	}
+
+	// Restored sessions pick up where they were
+	restored := sessionFromData(newSessionData(session))
+	if usage := restored.GetContextUsage(); usage.UsedTokens != 186_000 || usage.Turns != 2 {
+		t.Errorf("Expected the restored session's usage, got %+v", usage)
+	}
+}
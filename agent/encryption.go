@@ -0,0 +1,293 @@
+package agent
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"boatman/persist"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// sealedPrefix starts session data encrypted with AES-GCM. JSON never starts
+// with it, so plain and sealed data can be told apart when loading.
+var sealedPrefix = []byte("boatman-sealed:v1\n")
+
+// keyCheckText is sealed with a session key so a passphrase can be verified
+const keyCheckText = "boatman session key"
+
+// ErrSessionsLocked is returned when sealed session data is read, or any is
+// saved, before the encryption key is set
+var ErrSessionsLocked = errors.New("sessions are encrypted and locked")
+
+var (
+	encryptionMu sync.RWMutex
+	sealKey      cipher.AEAD   // Seals data on save; nil saves it plain
+	openKeys     []cipher.AEAD // Every key set so far, newest first
+	encryptSaves bool
+)
+
+// SetEncryption turns sealing of saved sessions and archives on or off. key,
+// a 32 byte AES-256 key, seals them and is added to the keys that open sealed
+// data; keys set earlier still open what they sealed, so ResealSessions can
+// move everything to a new key. Turning encryption on without any key locks
+// saving until one is set, so nothing is written in plaintext while waiting
+// for a passphrase.
+func SetEncryption(key []byte, encrypt bool) error {
+	var aead cipher.AEAD
+	if key != nil {
+		var err error
+		if aead, err = newAEAD(key); err != nil {
+			return err
+		}
+	}
+
+	encryptionMu.Lock()
+	defer encryptionMu.Unlock()
+	if aead != nil {
+		sealKey = aead
+		openKeys = append([]cipher.AEAD{aead}, openKeys...)
+	}
+	encryptSaves = encrypt
+	return nil
+}
+
+// EncryptionLocked reports whether sessions are encrypted but no key is set yet
+func EncryptionLocked() bool {
+	encryptionMu.RLock()
+	defer encryptionMu.RUnlock()
+	return encryptSaves && sealKey == nil
+}
+
+// newAEAD returns AES-256-GCM for key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("session key must be 32 bytes, got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// KeyFromPassphrase derives a session key from a passphrase with scrypt
+func KeyFromPassphrase(passphrase string, salt []byte) ([]byte, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("passphrase can't be empty")
+	}
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+}
+
+// NewKeyCheck seals a known value with key so the key can be verified later
+// without opening a session
+func NewKeyCheck(key []byte) (string, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := sealWith(aead, []byte(keyCheckText))
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// VerifyKeyCheck reports whether key is the one a check was made with
+func VerifyKeyCheck(key []byte, check string) bool {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return false
+	}
+	sealed, err := base64.StdEncoding.DecodeString(check)
+	if err != nil {
+		return false
+	}
+	plain, err := openWith(aead, sealed)
+	return err == nil && string(plain) == keyCheckText
+}
+
+// sealWith encrypts data as the prefix, a random nonce, and the ciphertext
+func sealWith(aead cipher.AEAD, data []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	sealed := append(append([]byte(nil), sealedPrefix...), nonce...)
+	return aead.Seal(sealed, nonce, data, nil), nil
+}
+
+// openWith decrypts data sealed by sealWith
+func openWith(aead cipher.AEAD, data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(data, sealedPrefix)
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("sealed data is truncated")
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, nil)
+}
+
+// isSealed reports whether data was encrypted by sealData
+func isSealed(data []byte) bool {
+	return bytes.HasPrefix(data, sealedPrefix)
+}
+
+// sealData encrypts data for saving when encryption is on
+func sealData(data []byte) ([]byte, error) {
+	encryptionMu.RLock()
+	defer encryptionMu.RUnlock()
+	if !encryptSaves {
+		return data, nil
+	}
+	if sealKey == nil {
+		return nil, ErrSessionsLocked
+	}
+	return sealWith(sealKey, data)
+}
+
+// openData decrypts sealed data with whichever key sealed it, passing plain
+// data through unchanged
+func openData(data []byte) ([]byte, error) {
+	if !isSealed(data) {
+		return data, nil
+	}
+
+	encryptionMu.RLock()
+	keys := openKeys
+	encryptionMu.RUnlock()
+	if len(keys) == 0 {
+		return nil, ErrSessionsLocked
+	}
+	for _, key := range keys {
+		if plain, err := openWith(key, data); err == nil {
+			return plain, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to decrypt session data: no key opens it")
+}
+
+// marshalSealed encodes v as JSON, sealed when encryption is on
+func marshalSealed(v any, indent bool) ([]byte, error) {
+	var data []byte
+	var err error
+	if indent {
+		data, err = json.MarshalIndent(v, "", "  ")
+	} else {
+		data, err = json.Marshal(v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sealData(data)
+}
+
+// unmarshalSealed decodes JSON that may be sealed
+func unmarshalSealed(data []byte, v any) error {
+	plain, err := openData(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(plain, v)
+}
+
+// resealData re-encrypts saved data for the current encryption setting
+func resealData(data []byte) ([]byte, error) {
+	plain, err := openData(data)
+	if err != nil {
+		return nil, err
+	}
+	return sealData(plain)
+}
+
+// ResealSessions rewrites every saved session and archive for the current
+// encryption setting: sealing plain ones when encryption is on, moving
+// sealed ones to the latest key, and decrypting them when it's off. It
+// returns how many sessions and archives were rewritten.
+func ResealSessions() (int, error) {
+	if store := ActiveStore(); store != nil {
+		return store.Reseal()
+	}
+
+	sessionsDir, err := GetSessionsDir()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get sessions directory: %w", err)
+	}
+	archivesDir, err := GetArchivesDir()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get archives directory: %w", err)
+	}
+
+	count := 0
+	for _, dir := range []string{sessionsDir, archivesDir} {
+		for _, id := range jsonFileIDs(dir) {
+			if err := resealFile(filepath.Join(dir, id+".json")); err != nil {
+				return count, fmt.Errorf("failed to reseal %s: %w", id, err)
+			}
+			if dir == archivesDir {
+				for _, page := range archivePageFiles(archivesDir, id) {
+					if err := resealFile(page); err != nil {
+						return count, fmt.Errorf("failed to reseal %s: %w", id, err)
+					}
+				}
+			}
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ChangeEncryption switches saving to key and encrypt, as SetEncryption
+// does, reseals everything already saved, then calls commit to record the
+// new setting. If resealing or commit fails, the previous setting is
+// restored and what was resealed is moved back to it, so the saved
+// sessions and the recorded setting never disagree. It returns how many
+// sessions and archives were rewritten.
+func ChangeEncryption(key []byte, encrypt bool, commit func() error) (int, error) {
+	encryptionMu.RLock()
+	prevKey, prevEncrypt := sealKey, encryptSaves
+	encryptionMu.RUnlock()
+
+	if err := SetEncryption(key, encrypt); err != nil {
+		return 0, err
+	}
+
+	count, err := ResealSessions()
+	if err == nil {
+		err = commit()
+	}
+	if err != nil {
+		// The new key stays among the open keys, so what it sealed can be
+		// read to move it back
+		encryptionMu.Lock()
+		sealKey, encryptSaves = prevKey, prevEncrypt
+		encryptionMu.Unlock()
+		if _, rollbackErr := ResealSessions(); rollbackErr != nil {
+			logger.Warn("Failed to restore the previous session encryption", "error", rollbackErr)
+		}
+		return 0, err
+	}
+	return count, nil
+}
+
+// resealFile rewrites a saved file for the current encryption setting. It's
+// replaced atomically, so a crash mid-reseal can't leave it half written.
+func resealFile(filename string) error {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	resealed, err := resealData(data)
+	if err != nil {
+		return err
+	}
+	return persist.WriteFile(filename, resealed, 0600)
+}
@@ -0,0 +1,325 @@
+package agent
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useTestEncryption resets session encryption when the test ends
+func useTestEncryption(t *testing.T) {
+	t.Helper()
+	forgetKeys()
+	t.Cleanup(forgetKeys)
+}
+
+// testKey returns a 32 byte key filled with b
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+// forgetKeys drops every key, as if the app had just started
+func forgetKeys() {
+	encryptionMu.Lock()
+	defer encryptionMu.Unlock()
+	sealKey = nil
+	openKeys = nil
+	encryptSaves = false
+}
+
+func TestSealAndOpenData(t *testing.T) {
+	useTestEncryption(t)
+	plain := []byte(`{"id":"session"}`)
+
+	// Plain data passes through either way while encryption is off
+	data, err := sealData(plain)
+	if err != nil || !bytes.Equal(data, plain) {
+		t.Fatalf("Expected data saved plain, got %q, %v", data, err)
+	}
+	if data, err := openData(plain); err != nil || !bytes.Equal(data, plain) {
+		t.Errorf("Expected plain data to load unchanged, got %q, %v", data, err)
+	}
+
+	if err := SetEncryption(testKey(1), true); err != nil {
+		t.Fatalf("SetEncryption() error = %v", err)
+	}
+	sealed, err := sealData(plain)
+	if err != nil {
+		t.Fatalf("sealData() error = %v", err)
+	}
+	if !isSealed(sealed) || bytes.Contains(sealed, []byte("session")) {
+		t.Errorf("Expected sealed data, got %q", sealed)
+	}
+	opened, err := openData(sealed)
+	if err != nil || !bytes.Equal(opened, plain) {
+		t.Errorf("Expected the data back, got %q, %v", opened, err)
+	}
+
+	// A later key seals new data, but the first still opens what it sealed
+	if err := SetEncryption(testKey(2), true); err != nil {
+		t.Fatalf("SetEncryption() error = %v", err)
+	}
+	if opened, err := openData(sealed); err != nil || !bytes.Equal(opened, plain) {
+		t.Errorf("Expected the earlier key to open its data, got %q, %v", opened, err)
+	}
+
+	// Tampered data doesn't open
+	tampered := append([]byte(nil), sealed...)
+	tampered[len(tampered)-1] ^= 0xff
+	if _, err := openData(tampered); err == nil {
+		t.Error("Expected tampered data to fail to open")
+	}
+}
+
+func TestEncryptionLocked(t *testing.T) {
+	useTestEncryption(t)
+	if err := SetEncryption(testKey(1), true); err != nil {
+		t.Fatalf("SetEncryption() error = %v", err)
+	}
+	sealed, err := sealData([]byte("{}"))
+	if err != nil {
+		t.Fatalf("sealData() error = %v", err)
+	}
+
+	forgetKeys()
+	if err := SetEncryption(nil, true); err != nil {
+		t.Fatalf("SetEncryption() error = %v", err)
+	}
+	if !EncryptionLocked() {
+		t.Fatal("Expected encryption locked without a key")
+	}
+	if _, err := sealData([]byte("{}")); !errors.Is(err, ErrSessionsLocked) {
+		t.Errorf("Expected saving to wait for the key, got %v", err)
+	}
+	if _, err := openData(sealed); !errors.Is(err, ErrSessionsLocked) {
+		t.Errorf("Expected loading to wait for the key, got %v", err)
+	}
+
+	if err := SetEncryption(testKey(1), true); err != nil {
+		t.Fatalf("SetEncryption() error = %v", err)
+	}
+	if EncryptionLocked() {
+		t.Error("Expected encryption unlocked once the key is set")
+	}
+
+	if err := SetEncryption(testKey(1)[:16], true); err == nil {
+		t.Error("Expected a short key to be rejected")
+	}
+}
+
+func TestKeyFromPassphrase(t *testing.T) {
+	salt := []byte("0123456789abcdef")
+	key, err := KeyFromPassphrase("correct horse", salt)
+	if err != nil {
+		t.Fatalf("KeyFromPassphrase() error = %v", err)
+	}
+	check, err := NewKeyCheck(key)
+	if err != nil {
+		t.Fatalf("NewKeyCheck() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		passphrase string
+		salt       []byte
+		valid      bool
+	}{
+		{"same passphrase", "correct horse", salt, true},
+		{"wrong passphrase", "wrong horse", salt, false},
+		{"other salt", "correct horse", []byte("fedcba9876543210"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidate, err := KeyFromPassphrase(tt.passphrase, tt.salt)
+			if err != nil {
+				t.Fatalf("KeyFromPassphrase() error = %v", err)
+			}
+			if got := VerifyKeyCheck(candidate, check); got != tt.valid {
+				t.Errorf("Expected VerifyKeyCheck() = %v, got %v", tt.valid, got)
+			}
+		})
+	}
+
+	if _, err := KeyFromPassphrase("", salt); err == nil {
+		t.Error("Expected an empty passphrase to be rejected")
+	}
+}
+
+func TestEncryptedSessionFiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	useTestEncryption(t)
+	if err := SetEncryption(testKey(1), true); err != nil {
+		t.Fatalf("SetEncryption() error = %v", err)
+	}
+
+	session := NewSession("sealed-session", "/tmp/project")
+	session.Messages = append(session.Messages, Message{ID: "msg-1", Role: "user", Content: "top secret plan", Timestamp: time.Now()})
+	if err := SaveSession(session); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+	meta := ArchiveData{SessionID: session.ID, ProjectPath: "/tmp/project"}
+	if err := ArchiveSessionMessages(meta, session.Messages); err != nil {
+		t.Fatalf("ArchiveSessionMessages() error = %v", err)
+	}
+
+	sessionsDir, _ := GetSessionsDir()
+	archivesDir, _ := GetArchivesDir()
+	files := []string{
+		filepath.Join(sessionsDir, session.ID+".json"),
+		archivePagePath(archivesDir, session.ID, 0),
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", file, err)
+		}
+		if strings.Contains(string(data), "top secret plan") {
+			t.Errorf("Expected %s encrypted, got %q", file, data)
+		}
+	}
+
+	loaded, err := LoadSession(session.ID)
+	if err != nil {
+		t.Fatalf("LoadSession() error = %v", err)
+	}
+	if len(loaded.Messages) != 1 || loaded.Messages[0].Content != "top secret plan" {
+		t.Errorf("Expected the session decrypted on load, got %+v", loaded.Messages)
+	}
+	archive, err := LoadArchive(session.ID)
+	if err != nil || len(archive.Messages) != 1 {
+		t.Fatalf("Expected the archive decrypted on load, got %+v, %v", archive, err)
+	}
+
+	// Turning encryption off writes everything back as plain JSON
+	if err := SetEncryption(nil, false); err != nil {
+		t.Fatalf("SetEncryption() error = %v", err)
+	}
+	count, err := ResealSessions()
+	if err != nil {
+		t.Fatalf("ResealSessions() error = %v", err)
+	}
+	if count != 2 {
+		t.Errorf("Expected 2 files rewritten, got %d", count)
+	}
+	for _, file := range files {
+		data, _ := os.ReadFile(file)
+		if !strings.Contains(string(data), "top secret plan") {
+			t.Errorf("Expected %s decrypted, got %q", file, data)
+		}
+	}
+}
+
+func TestBoltStoreReseal(t *testing.T) {
+	useTestStore(t)
+	useTestEncryption(t)
+	if err := SetEncryption(testKey(1), true); err != nil {
+		t.Fatalf("SetEncryption() error = %v", err)
+	}
+
+	session := NewSession("bolt-sealed", "/tmp/project")
+	if err := SaveSession(session); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+	msg := Message{ID: "msg-1", Role: "user", Content: "hello", Timestamp: time.Now()}
+	if err := ArchiveSessionMessages(ArchiveData{SessionID: session.ID}, []Message{msg}); err != nil {
+		t.Fatalf("ArchiveSessionMessages() error = %v", err)
+	}
+
+	// Move everything to a new key, then load it with only that key
+	if err := SetEncryption(testKey(2), true); err != nil {
+		t.Fatalf("SetEncryption() error = %v", err)
+	}
+	if _, err := ResealSessions(); err != nil {
+		t.Fatalf("ResealSessions() error = %v", err)
+	}
+	forgetKeys()
+	if err := SetEncryption(testKey(2), true); err != nil {
+		t.Fatalf("SetEncryption() error = %v", err)
+	}
+
+	if _, err := LoadSession(session.ID); err != nil {
+		t.Errorf("Expected the session sealed with the new key, got %v", err)
+	}
+	archive, err := LoadArchive(session.ID)
+	if err != nil || len(archive.Messages) != 1 {
+		t.Errorf("Expected the archive sealed with the new key, got %+v, %v", archive, err)
+	}
+}
+
+func TestChangeEncryptionRollsBack(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	useTestEncryption(t)
+
+	session := NewSession("plain-session", "/tmp/project")
+	session.Messages = append(session.Messages, Message{ID: "msg-1", Role: "user", Content: "top secret plan", Timestamp: time.Now()})
+	if err := SaveSession(session); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+	sessionsDir, _ := GetSessionsDir()
+	file := filepath.Join(sessionsDir, session.ID+".json")
+
+	t.Run("reseal error", func(t *testing.T) {
+		// Sealed with a key that's gone, so it can't be resealed
+		broken := filepath.Join(sessionsDir, "zz-broken.json")
+		if err := os.WriteFile(broken, append(append([]byte(nil), sealedPrefix...), bytes.Repeat([]byte{0}, 40)...), 0600); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(broken)
+
+		committed := false
+		_, err := ChangeEncryption(testKey(1), true, func() error {
+			committed = true
+			return nil
+		})
+		if err == nil {
+			t.Fatal("Expected the reseal error")
+		}
+		if committed {
+			t.Error("Expected the new setting not to be committed")
+		}
+		assertPlain(t, file)
+	})
+
+	t.Run("commit error", func(t *testing.T) {
+		_, err := ChangeEncryption(testKey(2), true, func() error {
+			return errors.New("disk full")
+		})
+		if err == nil || err.Error() != "disk full" {
+			t.Fatalf("Expected the commit error, got %v", err)
+		}
+		assertPlain(t, file)
+	})
+
+	// Saves carry on with the old setting
+	if EncryptionLocked() {
+		t.Error("Expected encryption to stay off")
+	}
+	if data, err := sealData([]byte("{}")); err != nil || isSealed(data) {
+		t.Errorf("Expected saves to stay plain, got %q, %v", data, err)
+	}
+
+	count, err := ChangeEncryption(testKey(3), true, func() error { return nil })
+	if err != nil || count != 1 {
+		t.Fatalf("ChangeEncryption() = %d, %v", count, err)
+	}
+	if data, _ := os.ReadFile(file); !isSealed(data) {
+		t.Error("Expected the session sealed once the change succeeds")
+	}
+}
+
+// assertPlain fails the test unless file is saved unencrypted
+func assertPlain(t *testing.T, file string) {
+	t.Helper()
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", file, err)
+	}
+	if isSealed(data) || !strings.Contains(string(data), "top secret plan") {
+		t.Errorf("Expected %s back in plain text, got %q", file, data)
+	}
+}
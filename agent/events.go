@@ -0,0 +1,83 @@
+package agent
+
+import (
+	"context"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// EventSink receives the session events the manager reports, e.g. to update
+// the UI. Implementations must not block; they are called from session
+// handlers while the session is locked.
+type EventSink interface {
+	EmitMessage(sessionID string, msg Message)
+	EmitStatus(sessionID string, status SessionStatus)
+	EmitTask(sessionID string, task Task)
+	// Emit sends any other event, such as "agent:approval", with its payload
+	Emit(name string, data map[string]interface{})
+}
+
+// WailsSink sends events to the frontend through the Wails runtime
+type WailsSink struct {
+	ctx context.Context
+}
+
+// NewWailsSink creates a sink for the context Wails passes to the app's startup hook
+func NewWailsSink(ctx context.Context) *WailsSink {
+	return &WailsSink{ctx: ctx}
+}
+
+// EmitMessage sends "agent:message"
+func (w *WailsSink) EmitMessage(sessionID string, msg Message) {
+	w.Emit("agent:message", map[string]interface{}{
+		"sessionId": sessionID,
+		"message":   msg,
+	})
+}
+
+// EmitStatus sends "agent:status"
+func (w *WailsSink) EmitStatus(sessionID string, status SessionStatus) {
+	w.Emit("agent:status", map[string]interface{}{
+		"sessionId": sessionID,
+		"status":    status,
+	})
+}
+
+// EmitTask sends "agent:task"
+func (w *WailsSink) EmitTask(sessionID string, task Task) {
+	w.Emit("agent:task", map[string]interface{}{
+		"sessionId": sessionID,
+		"task":      task,
+	})
+}
+
+// Emit sends an event to the frontend
+func (w *WailsSink) Emit(name string, data map[string]interface{}) {
+	runtime.EventsEmit(w.ctx, name, data)
+}
+
+// NopSink discards events, for running sessions headless
+type NopSink struct{}
+
+func (NopSink) EmitMessage(string, Message)         {}
+func (NopSink) EmitStatus(string, SessionStatus)    {}
+func (NopSink) EmitTask(string, Task)               {}
+func (NopSink) Emit(string, map[string]interface{}) {}
+
+// SetEventSink sets where session events are sent; nil discards them
+func (m *Manager) SetEventSink(sink EventSink) {
+	if sink == nil {
+		sink = NopSink{}
+	}
+	m.sinkMu.Lock()
+	defer m.sinkMu.Unlock()
+	m.sink = sink
+}
+
+// events returns the sink for session events. It has its own lock because
+// session handlers can't take m.mu.
+func (m *Manager) events() EventSink {
+	m.sinkMu.RLock()
+	defer m.sinkMu.RUnlock()
+	return m.sink
+}
@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingSink keeps the events it receives
+type recordingSink struct {
+	mu       sync.Mutex
+	messages []Message
+	statuses []SessionStatus
+	tasks    []Task
+	events   []string
+}
+
+func (r *recordingSink) EmitMessage(sessionID string, msg Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = append(r.messages, msg)
+}
+
+func (r *recordingSink) EmitStatus(sessionID string, status SessionStatus) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statuses = append(r.statuses, status)
+}
+
+func (r *recordingSink) EmitTask(sessionID string, task Task) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tasks = append(r.tasks, task)
+}
+
+func (r *recordingSink) Emit(name string, data map[string]interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, name)
+}
+
+func TestManagerEventSink(t *testing.T) {
+	useTestStore(t)
+
+	sink := &recordingSink{}
+	m := NewManager()
+	m.SetEventSink(sink)
+
+	session, err := m.CreateSession("/test/path")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	session.addSystemMessage("hello")
+	var responseBuilder strings.Builder
+	var currentMessageID string
+	session.parseStreamLine(`{"type":"task_create","task":{"id":"task-1","subject":"Test Task","status":"pending"}}`, &responseBuilder, &currentMessageID)
+	session.parseStreamLine(`{"type":"error","error":{"message":"boom"}}`, &responseBuilder, &currentMessageID)
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if len(sink.messages) < 2 || sink.messages[0].Content != "hello" {
+		t.Errorf("Expected the session's messages, got %+v", sink.messages)
+	}
+	if len(sink.tasks) != 1 || sink.tasks[0].ID != "task-1" {
+		t.Errorf("Expected task-1, got %+v", sink.tasks)
+	}
+	if len(sink.statuses) != 1 || sink.statuses[0] != SessionStatusError {
+		t.Errorf("Expected the error status, got %v", sink.statuses)
+	}
+}
+
+func TestManagerRegenerateReplaceEmitsHistory(t *testing.T) {
+	useTestStore(t)
+
+	sink := &recordingSink{}
+	m := NewManager()
+	m.SetEventSink(sink)
+
+	session, err := m.CreateSession("/test/path")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	history := newRegenerateTestSession()
+	history.cancel()
+	session.mu.Lock()
+	session.ctx, session.cancel = context.WithCancel(context.Background())
+	session.Messages = history.Messages
+	session.mu.Unlock()
+	defer session.cancel()
+
+	if err := m.RegenerateLastResponse(session.ID, RegenerateOptions{Replace: true}); err != nil {
+		t.Fatalf("RegenerateLastResponse failed: %v", err)
+	}
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	if !slices.Contains(sink.events, "agent:history") {
+		t.Errorf("Expected removing the old response to emit the history, got %v", sink.events)
+	}
+}
+
+func TestManagerWithoutSink(t *testing.T) {
+	useTestStore(t)
+
+	// Headless managers discard events
+	m := NewManager()
+	m.SetEventSink(nil)
+	session, err := m.CreateSession("/test/path")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	session.addSystemMessage("hello")
+
+	if _, ok := m.events().(NopSink); !ok {
+		t.Errorf("Expected a no-op sink, got %T", m.events())
+	}
+}
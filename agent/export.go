@@ -0,0 +1,466 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"boatman/diff"
+)
+
+// ExportFormat is a file format a session transcript can be exported to
+type ExportFormat string
+
+const (
+	ExportMarkdown ExportFormat = "markdown"
+	ExportJSON     ExportFormat = "json"
+	ExportHTML     ExportFormat = "html"
+)
+
+// ParseExportFormat accepts a format name or a file extension like "md"
+func ParseExportFormat(name string) (ExportFormat, error) {
+	switch strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), ".")) {
+	case "markdown", "md":
+		return ExportMarkdown, nil
+	case "json":
+		return ExportJSON, nil
+	case "html", "htm":
+		return ExportHTML, nil
+	}
+	return "", fmt.Errorf("unsupported export format %q", name)
+}
+
+// Extension returns the file extension for the format, including the dot
+func (f ExportFormat) Extension() string {
+	switch f {
+	case ExportMarkdown:
+		return ".md"
+	case ExportHTML:
+		return ".html"
+	}
+	return ".json"
+}
+
+// SessionExport is the JSON form of an exported session
+type SessionExport struct {
+	ID          string                 `json:"id"`
+	ProjectPath string                 `json:"projectPath"`
+	Model       string                 `json:"model,omitempty"`
+	Mode        string                 `json:"mode,omitempty"`
+	ModeConfig  map[string]interface{} `json:"modeConfig,omitempty"`
+	Tags        []string               `json:"tags,omitempty"`
+	CreatedAt   time.Time              `json:"createdAt"`
+	UpdatedAt   time.Time              `json:"updatedAt"`
+	ExportedAt  time.Time              `json:"exportedAt"`
+	Usage       CostInfo               `json:"usage"` // Totals over the whole transcript
+	Messages    []Message              `json:"messages"`
+	Tasks       []Task                 `json:"tasks,omitempty"`
+
+	changeSets []ChangeSet // Diffs the file-editing tool calls
+}
+
+// Export renders the session's full transcript, including archived messages,
+// as Markdown, JSON, or a standalone HTML page
+func (s *Session) Export(format ExportFormat) ([]byte, error) {
+	doc := s.exportSnapshot()
+
+	archived, err := LoadArchivedMessages(s.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load archived messages: %w", err)
+	}
+	doc.Messages = append(archived, doc.Messages...)
+
+	for _, msg := range doc.Messages {
+		if msg.Metadata == nil || msg.Metadata.CostInfo == nil || msg.Metadata.Superseded {
+			continue
+		}
+		cost := msg.Metadata.CostInfo
+		doc.Usage.InputTokens += cost.InputTokens
+		doc.Usage.OutputTokens += cost.OutputTokens
+		doc.Usage.CacheWriteTokens += cost.CacheWriteTokens
+		doc.Usage.CacheReadTokens += cost.CacheReadTokens
+		doc.Usage.TotalCost += cost.TotalCost
+	}
+
+	switch format {
+	case ExportMarkdown:
+		return exportMarkdown(doc), nil
+	case ExportJSON:
+		return json.MarshalIndent(doc, "", "  ")
+	case ExportHTML:
+		return exportHTML(doc)
+	}
+	return nil, fmt.Errorf("unsupported export format %q", format)
+}
+
+// exportSnapshot copies what an export needs while holding the read lock
+func (s *Session) exportSnapshot() SessionExport {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return SessionExport{
+		ID:          s.ID,
+		ProjectPath: s.ProjectPath,
+		Model:       s.Model,
+		Mode:        s.Mode,
+		ModeConfig:  s.ModeConfig,
+		Tags:        append([]string(nil), s.Tags...),
+		CreatedAt:   s.CreatedAt,
+		UpdatedAt:   s.UpdatedAt,
+		ExportedAt:  time.Now(),
+		Messages:    append([]Message(nil), s.Messages...),
+		Tasks:       append([]Task(nil), s.Tasks...),
+		changeSets:  append([]ChangeSet(nil), s.changeSets...),
+	}
+}
+
+// exportEntry is one rendered transcript entry shared by the Markdown and HTML exports
+type exportEntry struct {
+	Role      string
+	Label     string
+	Time      string
+	Content   string
+	ToolName  string
+	ToolInput string         // Pretty-printed input when the call isn't shown as a diff
+	Diff      *diff.FileDiff // The change a file-editing call made
+	WholeFile bool           // Diff covers the whole file rather than the edited text, so its line numbers are the file's
+	Result    string
+	IsError   bool
+	Agent     string // Sub-agent that produced the entry, if not the main one
+}
+
+// exportEntries turns messages into transcript entries, leaving out
+// responses that were replaced by a regeneration. File-editing tool calls
+// are diffed with the help of the turns' change sets.
+func exportEntries(messages []Message, changeSets []ChangeSet) []exportEntry {
+	changes := exportChanges(changeSets)
+	entries := make([]exportEntry, 0, len(messages))
+	for _, msg := range messages {
+		meta := msg.Metadata
+		if meta != nil && meta.Superseded {
+			continue
+		}
+
+		entry := exportEntry{
+			Role:    msg.Role,
+			Label:   roleLabel(msg.Role),
+			Time:    msg.Timestamp.Local().Format("2006-01-02 15:04:05"),
+			Content: msg.Content,
+		}
+		if meta != nil {
+			if meta.Agent != nil && meta.Agent.AgentID != "" && meta.Agent.AgentID != "main" {
+				entry.Agent = meta.Agent.AgentType
+				if meta.Agent.Description != "" {
+					entry.Agent += ": " + meta.Agent.Description
+				}
+			}
+			if tool := meta.ToolUse; tool != nil {
+				entry.ToolName = tool.ToolName
+				var change *exportChange
+				if c, ok := changes[tool.ToolID]; ok {
+					change = &c
+				}
+				if fd, wholeFile, ok := toolDiff(tool.ToolName, tool.Input, change); ok {
+					entry.Diff = &fd
+					entry.WholeFile = wholeFile
+				} else {
+					entry.ToolInput = prettyJSON(tool.Input)
+				}
+			}
+			if result := meta.ToolResult; result != nil {
+				entry.Label = "Tool result"
+				entry.Result = result.Content
+				entry.IsError = result.IsError
+				if entry.Content == result.Content {
+					entry.Content = ""
+				}
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func roleLabel(role string) string {
+	switch role {
+	case "user":
+		return "User"
+	case "assistant":
+		return "Assistant"
+	case "system":
+		return "System"
+	}
+	return role
+}
+
+// exportChange is a file change from a turn's change set, as seen from one
+// of the tool calls that made it
+type exportChange struct {
+	File  FileChange
+	First bool // The call was the first in its turn to change the file
+	Only  bool // No other call in the turn changed the file
+}
+
+// exportChanges indexes the files in change sets by the tool calls that changed them
+func exportChanges(changeSets []ChangeSet) map[string]exportChange {
+	changes := make(map[string]exportChange)
+	for _, set := range changeSets {
+		for _, file := range set.Files {
+			for i, toolID := range file.ToolIDs {
+				changes[toolID] = exportChange{File: file, First: i == 0, Only: len(file.ToolIDs) == 1}
+			}
+		}
+	}
+	return changes
+}
+
+// toolDiff diffs the change made by a file-editing tool call, reporting
+// whether the diff covers the whole file. A call that alone changed its file
+// in the turn gets the turn's diff of the file. Otherwise edits are diffed
+// from their input, and a Write against the file's content from before the
+// turn if it came first; a Write over content that isn't known has no diff.
+func toolDiff(toolName string, input json.RawMessage, change *exportChange) (diff.FileDiff, bool, bool) {
+	if _, ok := fileEditTools[toolName]; !ok {
+		return diff.FileDiff{}, false, false
+	}
+	if change != nil && change.Only {
+		return fileChangeDiff(change.File), true, true
+	}
+
+	var args struct {
+		FilePath  string `json:"file_path"`
+		OldString string `json:"old_string"`
+		NewString string `json:"new_string"`
+		Content   string `json:"content"`
+		Edits     []struct {
+			OldString string `json:"old_string"`
+			NewString string `json:"new_string"`
+		} `json:"edits"`
+	}
+	if len(input) == 0 || json.Unmarshal(input, &args) != nil || args.FilePath == "" {
+		return diff.FileDiff{}, false, false
+	}
+
+	switch toolName {
+	case "Edit":
+		fd := diff.Generate(args.OldString, args.NewString, args.FilePath)
+		fd.IsNew = false
+		return fd, false, true
+	case "MultiEdit":
+		fd := diff.FileDiff{OldPath: args.FilePath, NewPath: args.FilePath, Hunks: []diff.Hunk{}}
+		for _, edit := range args.Edits {
+			fd.Hunks = append(fd.Hunks, diff.Generate(edit.OldString, edit.NewString, args.FilePath).Hunks...)
+		}
+		return fd, false, true
+	case "Write":
+		if change == nil || !change.First || !change.File.keepsContent() {
+			return diff.FileDiff{}, false, false
+		}
+		fd := diff.Generate(change.File.Before, args.Content, change.File.Path)
+		fd.IsNew = !change.File.Existed
+		return fd, true, true
+	}
+	return diff.FileDiff{}, false, false
+}
+
+func prettyJSON(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, raw, "", "  "); err != nil {
+		return string(raw)
+	}
+	return out.String()
+}
+
+// exportTitle names the session after its project
+func exportTitle(doc SessionExport) string {
+	if name := filepath.Base(doc.ProjectPath); name != "." && name != string(filepath.Separator) {
+		return "Session: " + name
+	}
+	return "Session " + doc.ID
+}
+
+// exportMarkdown renders the transcript as Markdown
+func exportMarkdown(doc SessionExport) []byte {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# %s\n\n", exportTitle(doc))
+	fmt.Fprintf(&b, "- **Project:** `%s`\n", doc.ProjectPath)
+	if doc.Model != "" {
+		fmt.Fprintf(&b, "- **Model:** %s\n", doc.Model)
+	}
+	if doc.Mode != "" && doc.Mode != "standard" {
+		fmt.Fprintf(&b, "- **Mode:** %s\n", doc.Mode)
+	}
+	if len(doc.Tags) > 0 {
+		fmt.Fprintf(&b, "- **Tags:** %s\n", strings.Join(doc.Tags, ", "))
+	}
+	fmt.Fprintf(&b, "- **Created:** %s\n", doc.CreatedAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "- **Exported:** %s\n", doc.ExportedAt.Local().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "- **Token usage:** %s\n", usageSummary(doc.Usage))
+
+	b.WriteString("\n## Transcript\n")
+	for _, entry := range exportEntries(doc.Messages, doc.changeSets) {
+		fmt.Fprintf(&b, "\n### %s · %s\n\n", entry.Label, entry.Time)
+		if entry.Agent != "" {
+			fmt.Fprintf(&b, "_Agent: %s_\n\n", entry.Agent)
+		}
+		if entry.Content != "" {
+			if entry.Role == "system" {
+				b.WriteString("> " + strings.ReplaceAll(entry.Content, "\n", "\n> ") + "\n\n")
+			} else {
+				b.WriteString(entry.Content + "\n\n")
+			}
+		}
+		if entry.ToolName != "" {
+			fmt.Fprintf(&b, "**Tool:** `%s`\n\n", entry.ToolName)
+			if entry.Diff != nil {
+				b.WriteString(codeFence(diff.FormatUnified(*entry.Diff), "diff") + "\n")
+			} else if entry.ToolInput != "" {
+				b.WriteString(codeFence(entry.ToolInput, "json") + "\n")
+			}
+		}
+		if entry.Result != "" || entry.IsError {
+			if entry.IsError {
+				b.WriteString("**Error:**\n\n")
+			}
+			b.WriteString(codeFence(entry.Result, "") + "\n")
+		}
+	}
+
+	if len(doc.Tasks) > 0 {
+		b.WriteString("\n## Tasks\n\n")
+		for _, task := range doc.Tasks {
+			check := " "
+			if task.Status == "completed" {
+				check = "x"
+			}
+			fmt.Fprintf(&b, "- [%s] %s\n", check, task.Subject)
+		}
+	}
+
+	return []byte(strings.TrimRight(b.String(), "\n") + "\n")
+}
+
+// codeFence wraps text in a fence longer than any backtick run inside it
+func codeFence(text, lang string) string {
+	longest, run := 0, 0
+	for _, r := range text {
+		if r == '`' {
+			run++
+			if run > longest {
+				longest = run
+			}
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", max(3, longest+1))
+	return fence + lang + "\n" + strings.TrimSuffix(text, "\n") + "\n" + fence + "\n"
+}
+
+// renderEntryDiff renders an entry's diff with the diff package's HTML
+// renderer. Line numbers are only shown when they're the file's.
+func renderEntryDiff(entry exportEntry) template.HTML {
+	opts := diff.HTMLOptions{Layout: diff.HTMLLayoutUnified, HideLineNumbers: !entry.WholeFile}
+	return template.HTML(diff.RenderHTML([]diff.FileDiff{*entry.Diff}, opts))
+}
+
+var exportHTMLTemplate = template.Must(template.New("export").Funcs(template.FuncMap{
+	"renderDiff": renderEntryDiff,
+	"localTime":  func(t time.Time) string { return t.Local().Format("2006-01-02 15:04:05") },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; background: #0f172a; color: #e2e8f0; margin: 0; padding: 2rem; }
+main { max-width: 960px; margin: 0 auto; }
+h1 { font-size: 1.5rem; margin-bottom: 0.5rem; }
+dl { display: grid; grid-template-columns: max-content 1fr; gap: 0.25rem 1rem; color: #94a3b8; font-size: 0.875rem; }
+dt { font-weight: 600; }
+dd { margin: 0; }
+.entry { border: 1px solid #334155; border-radius: 0.5rem; margin: 1rem 0; padding: 0.75rem 1rem; background: #1e293b; }
+.entry.user { border-color: #3b82f6; }
+.entry.system { background: #0f172a; color: #94a3b8; }
+.entry header { display: flex; justify-content: space-between; font-size: 0.75rem; color: #94a3b8; margin-bottom: 0.5rem; }
+.agent { font-size: 0.75rem; color: #a78bfa; }
+.content { white-space: pre-wrap; word-wrap: break-word; }
+.tool { font-size: 0.8rem; color: #fbbf24; margin-top: 0.5rem; }
+pre { background: #020617; border-radius: 0.375rem; padding: 0.75rem; overflow-x: auto; font-size: 0.8rem; }
+pre.error { border-left: 3px solid #ef4444; }
+.boatman-diff { background: #ffffff; color: #24292f; border-radius: 0.375rem; margin-top: 0.5rem; }
+ul.tasks { list-style: none; padding: 0; }
+</style>
+</head>
+<body>
+<main>
+<h1>{{.Title}}</h1>
+<dl>
+<dt>Project</dt><dd>{{.Doc.ProjectPath}}</dd>
+{{- if .Doc.Model}}
+<dt>Model</dt><dd>{{.Doc.Model}}</dd>
+{{- end}}
+{{- if .Doc.Tags}}
+<dt>Tags</dt><dd>{{range $i, $tag := .Doc.Tags}}{{if $i}}, {{end}}{{$tag}}{{end}}</dd>
+{{- end}}
+<dt>Created</dt><dd>{{localTime .Doc.CreatedAt}}</dd>
+<dt>Exported</dt><dd>{{localTime .Doc.ExportedAt}}</dd>
+<dt>Token usage</dt><dd>{{.Usage}}</dd>
+</dl>
+{{range .Entries}}
+<section class="entry {{.Role}}">
+<header><span>{{.Label}}</span><time>{{.Time}}</time></header>
+{{- if .Agent}}
+<div class="agent">Agent: {{.Agent}}</div>
+{{- end}}
+{{- if .Content}}
+<div class="content">{{.Content}}</div>
+{{- end}}
+{{- if .ToolName}}
+<div class="tool">Tool: {{.ToolName}}</div>
+{{- if .Diff}}
+{{renderDiff .}}
+{{- else if .ToolInput}}
+<pre>{{.ToolInput}}</pre>
+{{- end}}
+{{- end}}
+{{- if or .Result .IsError}}
+<pre{{if .IsError}} class="error"{{end}}>{{.Result}}</pre>
+{{- end}}
+</section>
+{{end}}
+{{- if .Doc.Tasks}}
+<h2>Tasks</h2>
+<ul class="tasks">
+{{- range .Doc.Tasks}}
+<li>{{if eq .Status "completed"}}☑{{else}}☐{{end}} {{.Subject}}</li>
+{{- end}}
+</ul>
+{{- end}}
+</main>
+</body>
+</html>
+`))
+
+// exportHTML renders the transcript as a standalone HTML page
+func exportHTML(doc SessionExport) ([]byte, error) {
+	var out bytes.Buffer
+	err := exportHTMLTemplate.Execute(&out, map[string]interface{}{
+		"Title":   exportTitle(doc),
+		"Doc":     doc,
+		"Usage":   usageSummary(doc.Usage),
+		"Entries": exportEntries(doc.Messages, doc.changeSets),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render HTML export: %w", err)
+	}
+	return out.Bytes(), nil
+}
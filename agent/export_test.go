@@ -0,0 +1,206 @@
+package agent
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"boatman/diff"
+)
+
+// newExportSession builds a session with a user prompt, an edit, a tool result, and usage
+func newExportSession(t *testing.T) *Session {
+	t.Helper()
+	useTestStore(t)
+
+	session := NewSession("export-session", "/work/payments")
+	session.Model = "sonnet"
+	session.Tags = []string{"bug"}
+	now := time.Now()
+
+	editInput, _ := json.Marshal(map[string]string{
+		"file_path":  "main.go",
+		"old_string": "return nil",
+		"new_string": "return err",
+	})
+	session.Messages = []Message{
+		{ID: "m1", Role: "user", Content: "Fix the <bug> in main.go", Timestamp: now},
+		{ID: "m2", Role: "assistant", Content: "Old answer", Timestamp: now, Metadata: &MessageMetadata{Superseded: true}},
+		{ID: "m3", Role: "assistant", Content: "Editing main.go", Timestamp: now, Metadata: &MessageMetadata{
+			ToolUse: &ToolUse{ToolName: "Edit", ToolID: "t1", Input: editInput},
+		}},
+		{ID: "m4", Role: "assistant", Content: "```go\nok\n```", Timestamp: now, Metadata: &MessageMetadata{
+			ToolResult: &ToolResult{ToolID: "t1", Content: "```go\nok\n```"},
+		}},
+		{ID: "m5", Role: "system", Content: "📊 Token usage", Timestamp: now, Metadata: &MessageMetadata{
+			CostInfo: &CostInfo{InputTokens: 100, OutputTokens: 50, TotalCost: 0.25},
+		}},
+	}
+	session.Tasks = []Task{{ID: "task-1", Subject: "Fix bug", Status: "completed"}}
+	return session
+}
+
+func TestParseExportFormat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ExportFormat
+		wantErr  bool
+	}{
+		{"markdown", ExportMarkdown, false},
+		{".md", ExportMarkdown, false},
+		{"JSON", ExportJSON, false},
+		{"htm", ExportHTML, false},
+		{"pdf", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseExportFormat(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSessionExportMarkdown(t *testing.T) {
+	session := newExportSession(t)
+
+	data, err := session.Export(ExportMarkdown)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	out := string(data)
+
+	for _, want := range []string{
+		"# Session: payments",
+		"- **Model:** sonnet",
+		"Fix the <bug> in main.go",
+		"**Tool:** `Edit`",
+		"```diff\ndiff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-return nil\n+return err\n```",
+		"````\n```go\nok\n```\n````",
+		"100 input, 50 output (≈$0.2500)",
+		"- [x] Fix bug",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected markdown to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "Old answer") {
+		t.Error("Expected superseded messages to be left out")
+	}
+}
+
+func TestSessionExportJSON(t *testing.T) {
+	session := newExportSession(t)
+	ArchiveMessages(session.ID, []Message{{ID: "m0", Role: "user", Content: "Earlier prompt"}})
+
+	data, err := session.Export(ExportJSON)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	var doc SessionExport
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Export isn't valid JSON: %v", err)
+	}
+	if len(doc.Messages) != 6 || doc.Messages[0].Content != "Earlier prompt" {
+		t.Errorf("Expected archived messages first in the full transcript, got %d messages", len(doc.Messages))
+	}
+	if doc.Usage.InputTokens != 100 || doc.Usage.TotalCost != 0.25 {
+		t.Errorf("Expected usage totals, got %+v", doc.Usage)
+	}
+	if doc.ProjectPath != "/work/payments" || len(doc.Tasks) != 1 {
+		t.Errorf("Unexpected session details %+v", doc)
+	}
+}
+
+func TestSessionExportHTML(t *testing.T) {
+	session := newExportSession(t)
+
+	data, err := session.Export(ExportHTML)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	out := string(data)
+
+	if !strings.HasPrefix(out, "<!DOCTYPE html>") {
+		t.Error("Expected a standalone HTML document")
+	}
+	if !strings.Contains(out, "Fix the &lt;bug&gt; in main.go") {
+		t.Error("Expected message content to be escaped")
+	}
+	if !strings.Contains(out, `<div class="boatman-diff">`) || !strings.Contains(out, "return err") {
+		t.Errorf("Expected the edit diff to be rendered, got:\n%s", out)
+	}
+	if strings.Contains(out, "Old answer") {
+		t.Error("Expected superseded messages to be left out")
+	}
+}
+
+func TestToolDiffWrite(t *testing.T) {
+	input, _ := json.Marshal(map[string]string{"file_path": "/work/payments/notes.txt", "content": "one\ntwo\n"})
+
+	// Overwriting a file diffs against its content from before the turn
+	change := &exportChange{
+		File:  FileChange{Path: "notes.txt", Before: "one\nold\n", After: "one\ntwo\n", Existed: true, Exists: true, ToolIDs: []string{"t1", "t2"}},
+		First: true,
+	}
+	fd, wholeFile, ok := toolDiff("Write", input, change)
+	if !ok || !wholeFile {
+		t.Fatalf("Expected a whole-file diff for Write, got ok=%v wholeFile=%v", ok, wholeFile)
+	}
+	if got := diff.FormatUnified(fd); got != "diff --git a/notes.txt b/notes.txt\n--- a/notes.txt\n+++ b/notes.txt\n@@ -1,2 +1,2 @@\n one\n-old\n+two\n" {
+		t.Errorf("Unexpected diff:\n%s", got)
+	}
+
+	// A later Write in the turn overwrote content that isn't known
+	change.First = false
+	if _, _, ok := toolDiff("Write", input, change); ok {
+		t.Error("Expected no diff for a Write over unknown content")
+	}
+	if _, _, ok := toolDiff("Write", input, nil); ok {
+		t.Error("Expected no diff for a Write without a change set")
+	}
+
+	if _, _, ok := toolDiff("Bash", json.RawMessage(`{"command":"ls"}`), nil); ok {
+		t.Error("Expected no diff for Bash")
+	}
+}
+
+func TestSessionExportChangeSetDiff(t *testing.T) {
+	session := newExportSession(t)
+	writeInput, _ := json.Marshal(map[string]string{"file_path": "/work/payments/config.yml", "content": "port: 9090\n"})
+	session.Messages = append(session.Messages, Message{ID: "m6", Role: "assistant", Timestamp: time.Now(), Metadata: &MessageMetadata{
+		ToolUse: &ToolUse{ToolName: "Write", ToolID: "t2", Input: writeInput},
+	}})
+	session.changeSets = []ChangeSet{{MessageID: "m1", Files: []FileChange{{
+		Path:    "config.yml",
+		Before:  "host: local\nport: 8080\n",
+		After:   "port: 9090\n",
+		Existed: true,
+		Exists:  true,
+		ToolIDs: []string{"t2"},
+	}}}}
+
+	data, err := session.Export(ExportMarkdown)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if want := "@@ -1,2 +1 @@\n-host: local\n-port: 8080\n+port: 9090\n"; !strings.Contains(string(data), want) {
+		t.Errorf("Expected the Write diffed against the overwritten file, got:\n%s", data)
+	}
+
+	data, err = session.Export(ExportHTML)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	out := string(data)
+	if !strings.Contains(out, `<div class="boatman-diff">`) || !strings.Contains(out, "config.yml") || !strings.Contains(out, "host: local") {
+		t.Errorf("Expected the diff rendered by diff.RenderHTML, got:\n%s", out)
+	}
+}
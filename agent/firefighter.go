@@ -3,10 +3,16 @@ package agent
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
+
+	"boatman/logging"
 )
 
+// firefighterLogger logs firefighter monitoring problems
+var firefighterLogger = logging.For("firefighter")
+
 // FirefighterMonitor manages active monitoring for a firefighter session
 type FirefighterMonitor struct {
 	session          *Session
@@ -17,6 +23,15 @@ type FirefighterMonitor struct {
 	onAlert          func(Alert)
 	onInvestigation  func(Investigation)
 	mu               sync.RWMutex
+
+	// Rule-based monitoring: without rules the agent is prompted to check
+	// the services itself on every check
+	rules      MonitorRules
+	fetch      AlertFetcher
+	prompted   map[string]bool      // Firing alerts the agent was prompted about
+	lastFired  map[string]time.Time // When each rule last fired
+	authConfig func() (AuthConfig, error)
+
 	ctx              context.Context
 	cancel           context.CancelFunc
 }
@@ -31,6 +46,7 @@ type Alert struct {
 	FirstSeen   time.Time `json:"firstSeen"`
 	Count       int       `json:"count"`
 	URL         string    `json:"url"`
+	Tags        []string  `json:"tags,omitempty"`
 	LinearID    string    `json:"linearId,omitempty"`    // Linear issue ID if from ticket
 	SlackThread string    `json:"slackThread,omitempty"` // Slack thread if from alert
 }
@@ -58,6 +74,8 @@ func NewFirefighterMonitor(session *Session) *FirefighterMonitor {
 		isActive:      false,
 		checkInterval: 5 * time.Minute, // Check every 5 minutes by default
 		seenIssues:    make(map[string]bool),
+		prompted:      make(map[string]bool),
+		lastFired:     make(map[string]time.Time),
 		ctx:           ctx,
 		cancel:        cancel,
 	}
@@ -99,6 +117,25 @@ func (fm *FirefighterMonitor) SetCheckInterval(interval time.Duration) {
 	fm.checkInterval = interval
 }
 
+// SetRules makes the monitor poll with fetch and prompt the agent only about
+// alerts the rules match. Set them before Start; the interval applies then.
+func (fm *FirefighterMonitor) SetRules(rules MonitorRules, fetch AlertFetcher) {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	fm.rules = rules
+	fm.fetch = fetch
+	if rules.Interval > 0 {
+		fm.checkInterval = rules.Interval
+	}
+}
+
+// SetAuthConfigGetter sets how the monitor gets the auth config for its prompts
+func (fm *FirefighterMonitor) SetAuthConfigGetter(getter func() (AuthConfig, error)) {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	fm.authConfig = getter
+}
+
 // SetAlertHandler sets the callback for new alerts
 func (fm *FirefighterMonitor) SetAlertHandler(handler func(Alert)) {
 	fm.mu.Lock()
@@ -139,18 +176,129 @@ func (fm *FirefighterMonitor) performCheck() {
 		return
 	}
 	fm.lastCheckTime = time.Now()
+	useRules := fm.fetch != nil && len(fm.rules.Rules) > 0
 	fm.mu.Unlock()
 
+	if useRules {
+		fm.checkRules()
+		return
+	}
+
 	// Build monitoring prompt
 	prompt := fm.buildMonitoringPrompt()
 
 	// Send to Claude for analysis
 	// This will trigger the normal message flow, but with a specialized prompt
-	if err := fm.session.SendMessage(prompt, AuthConfig{}); err != nil {
-		fmt.Printf("[firefighter] Failed to send monitoring check: %v\n", err)
+	fm.sendPrompt(prompt, "monitoring check")
+}
+
+// checkRules fetches the firing alerts and prompts the agent about the new
+// ones the rules match
+func (fm *FirefighterMonitor) checkRules() {
+	alerts, err := fm.fetch(fm.ctx)
+	if err != nil {
+		// Alerts from the services that answered are still checked
+		firefighterLogger.Warn("Failed to fetch alerts", "session", fm.session.ID, "error", err)
+	}
+
+	fm.mu.Lock()
+	if err == nil {
+		// Forget alerts that stopped firing so they prompt again if they return
+		firing := make(map[string]bool, len(alerts))
+		for _, alert := range alerts {
+			firing[alertKey(alert)] = true
+		}
+		for key := range fm.prompted {
+			if !firing[key] {
+				delete(fm.prompted, key)
+			}
+		}
+	}
+	unseen := make([]Alert, 0, len(alerts))
+	for _, alert := range alerts {
+		if !fm.seenIssues[alert.ID] {
+			unseen = append(unseen, alert)
+		}
+	}
+	matches := evaluateRules(fm.rules.Rules, unseen, fm.prompted, fm.lastFired, time.Now())
+	for _, match := range matches {
+		for _, alert := range match.Alerts {
+			fm.prompted[alertKey(alert)] = true
+		}
+	}
+	onAlert := fm.onAlert
+	fm.mu.Unlock()
+
+	if len(matches) == 0 {
+		return
+	}
+	for _, match := range matches {
+		for _, alert := range match.Alerts {
+			fm.session.RecordIncidentAlert(alert)
+			if onAlert != nil {
+				onAlert(alert)
+			}
+		}
+	}
+	fm.sendPrompt(fm.buildRuleMatchPrompt(matches), "rule match")
+}
+
+// sendPrompt sends a prompt from the monitor to the session, logging failures
+func (fm *FirefighterMonitor) sendPrompt(prompt, what string) {
+	fm.mu.RLock()
+	getter := fm.authConfig
+	fm.mu.RUnlock()
+
+	authConfig := AuthConfig{}
+	if getter != nil {
+		var err error
+		if authConfig, err = getter(); err != nil {
+			firefighterLogger.Warn("Failed to send "+what, "session", fm.session.ID, "error", err)
+			return
+		}
+	}
+	if err := fm.session.SendMessage(prompt, authConfig); err != nil {
+		firefighterLogger.Warn("Failed to send "+what, "session", fm.session.ID, "error", err)
 	}
 }
 
+// buildRuleMatchPrompt creates a prompt for the alerts monitoring rules matched
+func (fm *FirefighterMonitor) buildRuleMatchPrompt(matches []ruleMatch) string {
+	var b strings.Builder
+	count := 0
+	for _, match := range matches {
+		fmt.Fprintf(&b, "\n### Rule: %s\n\n", match.Rule.Name)
+		for _, alert := range match.Alerts {
+			count++
+			fmt.Fprintf(&b, "- [%s] %s", alert.Source, alert.Title)
+			if alert.Severity != "" {
+				fmt.Fprintf(&b, " (severity: %s)", alert.Severity)
+			}
+			if alert.URL != "" {
+				fmt.Fprintf(&b, " - %s", alert.URL)
+			}
+			b.WriteString("\n")
+			if alert.ID != "" {
+				fmt.Fprintf(&b, "  ID: %s\n", alert.ID)
+			}
+		}
+	}
+
+	return fmt.Sprintf(`🔥 FIREFIGHTER MONITORING ALERT 🔥
+
+Your monitoring rules matched %d new alert(s):
+%s
+## Your Task
+
+1. Use the Datadog and Bugsnag MCP tools to pull the details of each alert
+2. Group alerts that share a cause and investigate each group
+3. Find the root cause in the codebase and recent deploys
+4. Assess impact and urgency
+5. Recommend mitigation and, if it's safe and clear, prepare a fix in an isolated worktree
+
+Report what you find, ending with a summary of each root cause and the steps that resolve it.`, count, b.String())
+}
+
 // buildMonitoringPrompt creates a prompt for proactive monitoring
 func (fm *FirefighterMonitor) buildMonitoringPrompt() string {
 	return `🔥 FIREFIGHTER MONITORING CHECK 🔥
@@ -243,6 +391,8 @@ func (fm *FirefighterMonitor) GetStatus() map[string]interface{} {
 		"checkInterval": fm.checkInterval.String(),
 		"lastCheck":     fm.lastCheckTime,
 		"seenIssues":    len(fm.seenIssues),
+		"rules":         len(fm.rules.Rules),
+		"firingAlerts":  len(fm.prompted),
 	}
 }
 
@@ -264,6 +414,14 @@ func (fm *FirefighterMonitor) ClearSeenIssues() {
 func (fm *FirefighterMonitor) InvestigateLinearTicket(linearIssueID string) error {
 	prompt := fm.buildTicketInvestigationPrompt(linearIssueID)
 
+	fm.session.RecordIncidentAlert(Alert{
+		ID:        linearIssueID,
+		Source:    "linear",
+		Title:     "Linear ticket " + linearIssueID,
+		FirstSeen: time.Now(),
+		LinearID:  linearIssueID,
+	})
+
 	// Send to Claude for investigation
 	if err := fm.session.SendMessage(prompt, AuthConfig{}); err != nil {
 		return fmt.Errorf("failed to send investigation request: %w", err)
@@ -344,6 +502,15 @@ Begin investigation now.`, linearIssueID, linearIssueID, linearIssueID)
 func (fm *FirefighterMonitor) InvestigateSlackAlert(slackThreadID, alertMessage string) error {
 	prompt := fm.buildSlackAlertPrompt(slackThreadID, alertMessage)
 
+	fm.session.RecordIncidentAlert(Alert{
+		ID:          slackThreadID,
+		Source:      "slack",
+		Title:       alertTitle(alertMessage),
+		Description: alertMessage,
+		FirstSeen:   time.Now(),
+		SlackThread: slackThreadID,
+	})
+
 	// Send to Claude for investigation
 	if err := fm.session.SendMessage(prompt, AuthConfig{}); err != nil {
 		return fmt.Errorf("failed to send alert investigation: %w", err)
@@ -352,6 +519,53 @@ func (fm *FirefighterMonitor) InvestigateSlackAlert(slackThreadID, alertMessage
 	return nil
 }
 
+// InvestigateAlert triggers investigation of an alert received by webhook
+func (fm *FirefighterMonitor) InvestigateAlert(alert Alert, authConfig AuthConfig) error {
+	fm.session.RecordIncidentAlert(alert)
+
+	if err := fm.session.SendMessage(fm.buildAlertPrompt(alert), authConfig); err != nil {
+		return fmt.Errorf("failed to send alert investigation: %w", err)
+	}
+
+	return nil
+}
+
+// buildAlertPrompt creates a prompt for investigating an alert from a monitoring webhook
+func (fm *FirefighterMonitor) buildAlertPrompt(alert Alert) string {
+	var details strings.Builder
+	fmt.Fprintf(&details, "- **Source:** %s\n", alert.Source)
+	fmt.Fprintf(&details, "- **Alert:** %s\n", alert.Title)
+	if alert.Severity != "" {
+		fmt.Fprintf(&details, "- **Severity:** %s\n", alert.Severity)
+	}
+	if alert.ID != "" {
+		fmt.Fprintf(&details, "- **ID:** %s\n", alert.ID)
+	}
+	if alert.URL != "" {
+		fmt.Fprintf(&details, "- **Link:** %s\n", alert.URL)
+	}
+	if alert.Description != "" {
+		fmt.Fprintf(&details, "\n**Details:**\n%s\n", alert.Description)
+	}
+
+	return fmt.Sprintf(`🔥 FIREFIGHTER ALERT RECEIVED 🔥
+
+A %s alert just fired:
+
+%s
+## Your Task
+
+1. Use the %s tools to pull the full alert: what triggered it, when it started, and how widespread it is
+2. Correlate with the other monitoring tools (Datadog logs and metrics, Bugsnag errors) and recent deploys
+3. Find the root cause in the codebase
+4. Assess impact and urgency
+5. Recommend mitigation (rollback, feature flag, fix) and, if it's safe and clear, prepare a fix in an isolated worktree
+
+Report what you find as you go, ending with a summary of the root cause and the steps that resolve it.
+
+Begin investigation now.`, alert.Source, details.String(), alert.Source)
+}
+
 // buildSlackAlertPrompt creates a prompt for investigating a Slack alert
 func (fm *FirefighterMonitor) buildSlackAlertPrompt(slackThreadID, alertMessage string) string {
 	return fmt.Sprintf(`🔥 FIREFIGHTER SLACK ALERT RESPONSE 🔥
@@ -405,3 +619,15 @@ You have been tagged in Slack for an urgent issue.
 
 Begin investigation now. Prioritize quick Slack acknowledgment.`, slackThreadID, alertMessage)
 }
+
+// alertTitle shortens an alert message to its first line, for a title
+func alertTitle(message string) string {
+	title, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	if runes := []rune(title); len(runes) > 80 {
+		title = string(runes[:80]) + "..."
+	}
+	if title == "" {
+		return "Slack alert"
+	}
+	return title
+}
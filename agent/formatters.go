@@ -0,0 +1,380 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ToolFormatter declares how calls to a tool are summarized in session output.
+// Formatters are loaded from JSON files so new MCP servers get readable output
+// without code changes, for example:
+//
+//	{
+//	  "tool": "mcp__linear__*",
+//	  "icon": "📋",
+//	  "template": "Linear {tool}: {query}",
+//	  "fields": {"query": {"path": "filter.title", "maxLength": 60}},
+//	  "result": {"path": "issues", "transform": "count", "template": "{result} issues"}
+//	}
+type ToolFormatter struct {
+	Tool     string               `json:"tool"` // Tool name or glob, e.g. "mcp__linear__*"
+	Icon     string               `json:"icon,omitempty"`
+	Template string               `json:"template"` // Placeholders like {query} are filled from Fields or input paths
+	Fields   map[string]FieldSpec `json:"fields,omitempty"`
+	Result   *ResultSpec          `json:"result,omitempty"`
+}
+
+// FieldSpec extracts a value from a tool's input. A bare JSON string is shorthand for Path.
+type FieldSpec struct {
+	Path      string `json:"path"`                // Dotted path into the input, e.g. "filter.service" or "items.0.id"
+	Default   string `json:"default,omitempty"`   // Used when the path is missing
+	MaxLength int    `json:"maxLength,omitempty"` // Truncate longer values
+	Transform string `json:"transform,omitempty"` // "basename", "upper", "lower", "count", or "join"
+}
+
+// UnmarshalJSON accepts either a path string or a full field spec
+func (f *FieldSpec) UnmarshalJSON(data []byte) error {
+	var p string
+	if err := json.Unmarshal(data, &p); err == nil {
+		*f = FieldSpec{Path: p}
+		return nil
+	}
+	type plain FieldSpec
+	return json.Unmarshal(data, (*plain)(f))
+}
+
+// ResultSpec post-processes a tool's result before it is shown. Path, Default,
+// MaxLength, and Transform work as in FieldSpec when the result is JSON.
+type ResultSpec struct {
+	Path      string        `json:"path,omitempty"`
+	Default   string        `json:"default,omitempty"`
+	MaxLength int           `json:"maxLength,omitempty"`
+	Transform string        `json:"transform,omitempty"`
+	Template  string        `json:"template,omitempty"` // e.g. "{result} issues found"; {result} is the processed value
+	MaxLines  int           `json:"maxLines,omitempty"`
+	Replace   []Replacement `json:"replace,omitempty"`
+}
+
+// field returns the result's extraction settings as a field spec
+func (r ResultSpec) field() FieldSpec {
+	return FieldSpec{Path: r.Path, Default: r.Default, MaxLength: r.MaxLength, Transform: r.Transform}
+}
+
+// Replacement is a regular expression rewrite applied to tool results
+type Replacement struct {
+	Pattern string `json:"pattern"`
+	With    string `json:"with"`
+
+	re *regexp.Regexp
+}
+
+// toolFormatterFile is the on-disk format: one formatter or a list of them
+type toolFormatterFile struct {
+	Formatters []ToolFormatter `json:"formatters"`
+}
+
+// placeholderPattern matches {name} placeholders in templates
+var placeholderPattern = regexp.MustCompile(`\{([A-Za-z0-9_.\-]+)\}`)
+
+// ToolFormatterRegistry holds the user-defined tool formatters
+type ToolFormatterRegistry struct {
+	mu         sync.RWMutex
+	formatters []ToolFormatter
+}
+
+// NewToolFormatterRegistry creates an empty registry
+func NewToolFormatterRegistry() *ToolFormatterRegistry {
+	return &ToolFormatterRegistry{}
+}
+
+// Register validates and adds a formatter. Later registrations take precedence.
+func (r *ToolFormatterRegistry) Register(f ToolFormatter) error {
+	if f.Tool == "" {
+		return fmt.Errorf("formatter is missing a tool name")
+	}
+	if _, err := path.Match(f.Tool, ""); err != nil {
+		return fmt.Errorf("invalid tool pattern %q: %w", f.Tool, err)
+	}
+	if f.Template == "" {
+		return fmt.Errorf("formatter for %s is missing a template", f.Tool)
+	}
+	if f.Result != nil {
+		for i := range f.Result.Replace {
+			re, err := regexp.Compile(f.Result.Replace[i].Pattern)
+			if err != nil {
+				return fmt.Errorf("formatter for %s has an invalid replace pattern: %w", f.Tool, err)
+			}
+			f.Result.Replace[i].re = re
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.formatters = append(r.formatters, f)
+	return nil
+}
+
+// LoadDir replaces the registry's formatters with those in dir's *.json files.
+// A missing directory leaves the registry empty.
+func (r *ToolFormatterRegistry) LoadDir(dir string) (int, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+	sort.Strings(files)
+
+	loaded := NewToolFormatterRegistry()
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return 0, err
+		}
+
+		formatters, err := parseToolFormatters(data)
+		if err != nil {
+			return 0, fmt.Errorf("%s: %w", filepath.Base(file), err)
+		}
+		for _, f := range formatters {
+			if err := loaded.Register(f); err != nil {
+				return 0, fmt.Errorf("%s: %w", filepath.Base(file), err)
+			}
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.formatters = loaded.formatters
+	return len(r.formatters), nil
+}
+
+// parseToolFormatters decodes a file holding {"formatters": [...]}, a list, or a single formatter
+func parseToolFormatters(data []byte) ([]ToolFormatter, error) {
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "[") {
+		var list []ToolFormatter
+		err := json.Unmarshal(data, &list)
+		return list, err
+	}
+
+	var file toolFormatterFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	if len(file.Formatters) > 0 {
+		return file.Formatters, nil
+	}
+
+	var single ToolFormatter
+	if err := json.Unmarshal(data, &single); err != nil {
+		return nil, err
+	}
+	return []ToolFormatter{single}, nil
+}
+
+// List returns the registered formatters
+func (r *ToolFormatterRegistry) List() []ToolFormatter {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	formatters := make([]ToolFormatter, len(r.formatters))
+	copy(formatters, r.formatters)
+	return formatters
+}
+
+// find returns the most recently registered formatter matching toolName
+func (r *ToolFormatterRegistry) find(toolName string) (ToolFormatter, bool) {
+	if r == nil {
+		return ToolFormatter{}, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := len(r.formatters) - 1; i >= 0; i-- {
+		if ok, _ := path.Match(r.formatters[i].Tool, toolName); ok {
+			return r.formatters[i], true
+		}
+	}
+	return ToolFormatter{}, false
+}
+
+// FormatToolUse summarizes a tool call with a matching formatter
+func (r *ToolFormatterRegistry) FormatToolUse(toolName string, input any) (string, bool) {
+	f, ok := r.find(toolName)
+	if !ok {
+		return "", false
+	}
+
+	server, tool := splitMCPToolName(toolName)
+	text := placeholderPattern.ReplaceAllStringFunc(f.Template, func(placeholder string) string {
+		name := placeholder[1 : len(placeholder)-1]
+		switch name {
+		case "tool":
+			return tool
+		case "server":
+			return server
+		case "toolName":
+			return toolName
+		}
+
+		spec, ok := f.Fields[name]
+		if !ok {
+			spec = FieldSpec{Path: name}
+		}
+		return redactString(spec.extract(input))
+	})
+
+	if f.Icon != "" {
+		text = f.Icon + " " + text
+	}
+	return text, true
+}
+
+// FormatToolResult post-processes a tool result with a matching formatter
+func (r *ToolFormatterRegistry) FormatToolResult(toolName, content string) (string, bool) {
+	f, ok := r.find(toolName)
+	if !ok || f.Result == nil {
+		return "", false
+	}
+	spec := f.Result
+	field := spec.field()
+
+	result := content
+	if spec.Path != "" || spec.Transform != "" {
+		var parsed any
+		if err := json.Unmarshal([]byte(content), &parsed); err == nil {
+			result = field.extract(parsed)
+		} else if spec.Path == "" {
+			result = field.transform(content)
+		}
+	}
+
+	for _, rep := range spec.Replace {
+		if rep.re != nil {
+			result = rep.re.ReplaceAllString(result, rep.With)
+		}
+	}
+
+	if spec.MaxLines > 0 {
+		lines := strings.Split(result, "\n")
+		if len(lines) > spec.MaxLines {
+			result = strings.Join(lines[:spec.MaxLines], "\n") + fmt.Sprintf("\n... (%d more lines)", len(lines)-spec.MaxLines)
+		}
+	}
+
+	if spec.Template != "" {
+		result = strings.ReplaceAll(spec.Template, "{result}", result)
+	}
+	return result, true
+}
+
+// extract resolves the field's path in value and applies its transform and length limit
+func (f FieldSpec) extract(value any) string {
+	v, ok := lookupPath(value, f.Path)
+	if !ok {
+		return f.Default
+	}
+
+	var text string
+	switch f.Transform {
+	case "count":
+		switch c := v.(type) {
+		case []any:
+			text = strconv.Itoa(len(c))
+		case map[string]any:
+			text = strconv.Itoa(len(c))
+		default:
+			text = "1"
+		}
+	case "join":
+		if list, ok := v.([]any); ok {
+			parts := make([]string, 0, len(list))
+			for _, item := range list {
+				parts = append(parts, stringify(item))
+			}
+			text = strings.Join(parts, ", ")
+		} else {
+			text = stringify(v)
+		}
+	default:
+		text = f.transform(stringify(v))
+	}
+
+	if f.MaxLength > 0 && len(text) > f.MaxLength {
+		text = text[:f.MaxLength] + "..."
+	}
+	return text
+}
+
+// transform applies string transforms to text
+func (f FieldSpec) transform(text string) string {
+	switch f.Transform {
+	case "basename":
+		return filepath.Base(text)
+	case "upper":
+		return strings.ToUpper(text)
+	case "lower":
+		return strings.ToLower(text)
+	}
+	return text
+}
+
+// lookupPath walks a dotted path through maps and slices. An empty path returns value.
+func lookupPath(value any, p string) (any, bool) {
+	if p == "" {
+		return value, value != nil
+	}
+	current := value
+	for _, key := range strings.Split(p, ".") {
+		switch c := current.(type) {
+		case map[string]any:
+			next, ok := c[key]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []any:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, false
+			}
+			current = c[i]
+		default:
+			return nil, false
+		}
+	}
+	return current, current != nil
+}
+
+// stringify renders a JSON value for display
+func stringify(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case nil:
+		return ""
+	case float64, bool:
+		return fmt.Sprint(val)
+	default:
+		data, _ := json.Marshal(val)
+		return string(data)
+	}
+}
+
+// splitMCPToolName splits "mcp__server__tool" into its server and tool names.
+// Other tool names are returned as the tool with no server.
+func splitMCPToolName(toolName string) (server, tool string) {
+	if !strings.HasPrefix(toolName, "mcp__") {
+		return "", toolName
+	}
+	rest := strings.TrimPrefix(toolName, "mcp__")
+	if i := strings.Index(rest, "__"); i >= 0 {
+		return rest[:i], rest[i+2:]
+	}
+	return rest, rest
+}
@@ -0,0 +1,231 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"boatman/stream"
+)
+
+func TestToolFormatterRegistryFormatToolUse(t *testing.T) {
+	registry := NewToolFormatterRegistry()
+	formatters := []ToolFormatter{
+		{
+			Tool:     "mcp__linear__*",
+			Icon:     "📋",
+			Template: "Linear {tool}: {query}",
+			Fields:   map[string]FieldSpec{"query": {Path: "filter.title", MaxLength: 10, Default: "(all)"}},
+		},
+		{
+			Tool:     "mcp__datadog__search_logs",
+			Template: "Searching {service} logs for {query} in {files}",
+			Fields: map[string]FieldSpec{
+				"service": {Path: "service", Transform: "upper"},
+				"files":   {Path: "paths", Transform: "join"},
+			},
+		},
+	}
+	for _, f := range formatters {
+		if err := registry.Register(f); err != nil {
+			t.Fatalf("Register failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		tool     string
+		input    any
+		expected string
+		matched  bool
+	}{
+		{
+			"glob match with nested field",
+			"mcp__linear__search_issues",
+			map[string]any{"filter": map[string]any{"title": "login bug"}},
+			"📋 Linear search_issues: login bug",
+			true,
+		},
+		{
+			"truncated field",
+			"mcp__linear__search_issues",
+			map[string]any{"filter": map[string]any{"title": "a very long issue title"}},
+			"📋 Linear search_issues: a very lon...",
+			true,
+		},
+		{
+			"default for missing field",
+			"mcp__linear__list_issues",
+			map[string]any{},
+			"📋 Linear list_issues: (all)",
+			true,
+		},
+		{
+			"transforms and direct input paths",
+			"mcp__datadog__search_logs",
+			map[string]any{"service": "api", "query": "status:error", "paths": []any{"a", "b"}},
+			"Searching API logs for status:error in a, b",
+			true,
+		},
+		{
+			"secrets are masked",
+			"mcp__datadog__search_logs",
+			map[string]any{"service": "api", "query": "token=abcdef0123456789"},
+			"Searching API logs for token=[REDACTED] in ",
+			true,
+		},
+		{"no match", "mcp__github__get_issue", map[string]any{}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := registry.FormatToolUse(tt.tool, tt.input)
+			if ok != tt.matched {
+				t.Fatalf("Expected matched=%v, got %v", tt.matched, ok)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestToolFormatterRegistryFormatToolResult(t *testing.T) {
+	registry := NewToolFormatterRegistry()
+	registry.Register(ToolFormatter{
+		Tool:     "mcp__linear__search_issues",
+		Template: "Searching issues",
+		Result:   &ResultSpec{Path: "issues", Transform: "count", Template: "{result} issues found"},
+	})
+	registry.Register(ToolFormatter{
+		Tool:     "mcp__bugsnag__*",
+		Template: "Bugsnag {tool}",
+		Result: &ResultSpec{
+			MaxLines: 2,
+			Replace:  []Replacement{{Pattern: `\s+at `, With: " @ "}},
+		},
+	})
+	registry.Register(ToolFormatter{Tool: "mcp__plain__*", Template: "Plain"})
+
+	got, ok := registry.FormatToolResult("mcp__linear__search_issues", `{"issues":[{"id":1},{"id":2}]}`)
+	if !ok || got != "2 issues found" {
+		t.Errorf("Expected '2 issues found', got %q (matched=%v)", got, ok)
+	}
+
+	got, ok = registry.FormatToolResult("mcp__bugsnag__get_error", "Error\n   at main.go\nline 3\nline 4")
+	if !ok || got != "Error @ main.go\nline 3\n... (1 more lines)" {
+		t.Errorf("Unexpected post-processed result: %q", got)
+	}
+
+	if _, ok := registry.FormatToolResult("mcp__plain__tool", "x"); ok {
+		t.Error("Expected formatter without result spec not to handle results")
+	}
+}
+
+func TestToolFormatterRegistryRegisterValidation(t *testing.T) {
+	registry := NewToolFormatterRegistry()
+
+	invalid := []ToolFormatter{
+		{Template: "missing tool"},
+		{Tool: "mcp__x__*"},
+		{Tool: "[", Template: "bad glob"},
+		{Tool: "mcp__x__*", Template: "x", Result: &ResultSpec{Replace: []Replacement{{Pattern: "("}}}},
+	}
+	for _, f := range invalid {
+		if err := registry.Register(f); err == nil {
+			t.Errorf("Expected error registering %+v", f)
+		}
+	}
+	if len(registry.List()) != 0 {
+		t.Errorf("Expected no formatters, got %d", len(registry.List()))
+	}
+}
+
+func TestToolFormatterRegistryLoadDir(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"a-linear.json": `{"formatters": [{"tool": "mcp__linear__*", "template": "Linear {tool}", "fields": {"q": "query"}}]}`,
+		"b-single.json": `{"tool": "mcp__slack__post", "icon": "💬", "template": "Posting to {channel}"}`,
+		"c-list.json":   `[{"tool": "mcp__linear__create_issue", "template": "Creating {title}"}]`,
+		"ignored.txt":   `not a formatter`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	registry := NewToolFormatterRegistry()
+	count, err := registry.LoadDir(dir)
+	if err != nil {
+		t.Fatalf("LoadDir failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 formatters, got %d", count)
+	}
+
+	// Later files take precedence over earlier globs
+	got, _ := registry.FormatToolUse("mcp__linear__create_issue", map[string]any{"title": "Fix login"})
+	if got != "Creating Fix login" {
+		t.Errorf("Expected later formatter to win, got %q", got)
+	}
+
+	// Invalid files fail the reload and keep the previous formatters
+	os.WriteFile(filepath.Join(dir, "d-broken.json"), []byte(`{"tool": "x"}`), 0644)
+	if _, err := registry.LoadDir(dir); err == nil || !strings.Contains(err.Error(), "d-broken.json") {
+		t.Errorf("Expected error naming the broken file, got %v", err)
+	}
+	if len(registry.List()) != 3 {
+		t.Errorf("Expected previous formatters to be kept, got %d", len(registry.List()))
+	}
+
+	// A missing directory loads nothing
+	count, err = registry.LoadDir(filepath.Join(dir, "missing"))
+	if err != nil || count != 0 {
+		t.Errorf("Expected empty load for missing dir, got %d (%v)", count, err)
+	}
+}
+
+func TestSessionUsesToolFormatters(t *testing.T) {
+	session := NewSession("test-formatters", "/tmp/test")
+
+	// Unformatted MCP tools get a readable default
+	if got := session.formatToolUseDescription("mcp__datadog__query_metrics", map[string]any{}); got != "🔌 datadog: query_metrics" {
+		t.Errorf("Expected MCP default description, got %q", got)
+	}
+
+	registry := NewToolFormatterRegistry()
+	registry.Register(ToolFormatter{
+		Tool:     "mcp__datadog__*",
+		Icon:     "📊",
+		Template: "Datadog {tool}: {query}",
+		Result:   &ResultSpec{Path: "series", Transform: "count", Template: "{result} series"},
+	})
+	session.SetToolFormatters(registry)
+
+	session.handleToolUse(stream.ToolUse{
+		Name:  "mcp__datadog__query_metrics",
+		ID:    "tool-1",
+		Input: map[string]any{"query": "avg:cpu"},
+	})
+	session.handleToolResult(stream.ToolResult{
+		ToolUseID: "tool-1",
+		Content:   `{"series":[1,2,3]}`,
+	})
+
+	messages := session.GetMessages()
+	if len(messages) != 2 {
+		t.Fatalf("Expected 2 messages, got %d", len(messages))
+	}
+	if messages[0].Content != "📊 Datadog query_metrics: avg:cpu" {
+		t.Errorf("Unexpected tool use description: %q", messages[0].Content)
+	}
+	if !strings.HasSuffix(messages[1].Content, "Tool result: 3 series") {
+		t.Errorf("Unexpected tool result: %q", messages[1].Content)
+	}
+	if messages[1].Metadata.ToolResult.Content != `{"series":[1,2,3]}` {
+		t.Errorf("Expected raw result to be stored, got %q", messages[1].Metadata.ToolResult.Content)
+	}
+}
@@ -0,0 +1,465 @@
+package agent
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// GuardHookCommand is the subcommand the app binary runs as a claude PreToolUse hook
+const GuardHookCommand = "fs-guard-hook"
+
+// guardedTools are the tools whose inputs the filesystem guard inspects
+const guardedTools = "Write|Edit|MultiEdit|NotebookEdit|Bash"
+
+// alwaysAllowedPaths are device files commands commonly write to
+var alwaysAllowedPaths = []string{"/dev/null", "/dev/stdout", "/dev/stderr", "/dev/tty"}
+
+// PathGuard restricts file writes to a project directory plus allowlisted
+// paths, and holds writes to protected paths for the user's approval
+type PathGuard struct {
+	Root    string
+	Allowed []string
+	// Protected are globs like ".env" or "secrets/" that writes need approval for
+	Protected []string
+	// Unconfined lets writes go outside Root and Allowed; only Protected applies
+	Unconfined bool
+}
+
+// GuardDecision is the outcome of checking a tool invocation
+type GuardDecision struct {
+	Allowed bool
+	// Ask holds a call that would otherwise be allowed for the user's approval
+	Ask    bool
+	Reason string
+	Path   string // The path that was blocked or needs approval
+}
+
+// CheckToolUse inspects a tool's input and decides whether it may run
+func (g PathGuard) CheckToolUse(toolName string, input json.RawMessage) GuardDecision {
+	var fields struct {
+		FilePath     string `json:"file_path"`
+		NotebookPath string `json:"notebook_path"`
+		Command      string `json:"command"`
+	}
+	if len(input) > 0 {
+		if err := json.Unmarshal(input, &fields); err != nil {
+			return GuardDecision{Reason: fmt.Sprintf("could not parse %s input: %v", toolName, err)}
+		}
+	}
+
+	var targets []string
+	switch toolName {
+	case "Write", "Edit", "MultiEdit":
+		targets = []string{fields.FilePath}
+	case "NotebookEdit":
+		targets = []string{fields.NotebookPath}
+	case "Bash":
+		targets = bashWriteTargets(fields.Command)
+	default:
+		return GuardDecision{Allowed: true}
+	}
+
+	var protected string
+	for _, target := range targets {
+		if target == "" {
+			continue
+		}
+		if !g.Unconfined && !g.allows(target) {
+			return GuardDecision{
+				Reason: fmt.Sprintf("%s would modify %s, which is outside the project directory %s. "+
+					"Ask the user to add this path to the guardrail allowlist if the change is intended.",
+					toolName, target, g.Root),
+				Path: target,
+			}
+		}
+		if protected == "" && g.protects(target) {
+			protected = target
+		}
+	}
+
+	if protected != "" {
+		return GuardDecision{
+			Ask:    true,
+			Reason: fmt.Sprintf("%s would modify %s, which is a protected path. The user must approve this change.", toolName, protected),
+			Path:   protected,
+		}
+	}
+	return GuardDecision{Allowed: true}
+}
+
+// allows reports whether path is inside the project root or an allowlisted path
+func (g PathGuard) allows(path string) bool {
+	resolved := g.resolve(path)
+	for _, device := range alwaysAllowedPaths {
+		if resolved == device {
+			return true
+		}
+	}
+
+	roots := append([]string{g.Root}, g.Allowed...)
+	for _, root := range roots {
+		if root == "" {
+			continue
+		}
+		if isWithin(g.resolve(root), resolved) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve makes path absolute (relative to the project root), expands ~, and
+// follows symlinks in the longest existing prefix so links cannot escape the root
+func (g PathGuard) resolve(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(g.Root, path)
+	}
+	path = filepath.Clean(path)
+
+	// Resolve the deepest existing ancestor and re-append the rest
+	existing, rest := path, ""
+	for {
+		if resolved, err := filepath.EvalSymlinks(existing); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return path
+		}
+		rest = filepath.Join(filepath.Base(existing), rest)
+		existing = parent
+	}
+}
+
+// isWithin reports whether path is root or a descendant of it
+func isWithin(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// writeCommands maps commands that modify files to which of their arguments are written.
+// "all" means every non-flag argument, "last" means only the final one.
+var writeCommands = map[string]string{
+	"rm":       "all",
+	"rmdir":    "all",
+	"mv":       "all",
+	"touch":    "all",
+	"mkdir":    "all",
+	"tee":      "all",
+	"chmod":    "all",
+	"chown":    "all",
+	"truncate": "all",
+	"cp":       "last",
+	"ln":       "last",
+	"install":  "last",
+	"rsync":    "last",
+}
+
+// bashWriteTargets returns the paths a shell command may write to. This is a
+// heuristic: it covers redirections, common file-modifying commands, in-place
+// sed, and dd's of=. Relative targets after a cd are resolved against the
+// directory it moved to.
+func bashWriteTargets(command string) []string {
+	var targets []string
+	dir := "" // Where cd moved to, relative to the starting directory unless absolute
+
+	for _, segment := range splitShellCommands(command) {
+		start := len(targets)
+		words := shellWords(segment)
+		if len(words) == 0 {
+			continue
+		}
+
+		var args []string
+		for i := 0; i < len(words); i++ {
+			word := words[i]
+
+			// Redirections: "> file", ">>file", "2>file", "&>file"
+			if idx := strings.Index(word, ">"); idx >= 0 && strings.Trim(word[:idx], "0123456789&") == "" {
+				target := strings.TrimLeft(word[idx:], ">|")
+				if strings.HasPrefix(target, "&") {
+					continue // fd duplication like 2>&1
+				}
+				if target == "" && i+1 < len(words) {
+					i++
+					target = words[i]
+				}
+				if target != "" {
+					targets = append(targets, target)
+				}
+				continue
+			}
+			args = append(args, word)
+		}
+		if len(args) == 0 {
+			continue
+		}
+
+		name := filepath.Base(args[0])
+		if name == "sudo" && len(args) > 1 {
+			args = args[1:]
+			name = filepath.Base(args[0])
+		}
+
+		var operands []string
+		for _, arg := range args[1:] {
+			if name == "dd" && strings.HasPrefix(arg, "of=") {
+				targets = append(targets, strings.TrimPrefix(arg, "of="))
+				continue
+			}
+			if !strings.HasPrefix(arg, "-") {
+				operands = append(operands, arg)
+			}
+		}
+
+		switch {
+		case name == "sed" && hasInPlaceFlag(args[1:]):
+			// The script is the first operand unless passed with -e
+			if len(operands) > 1 {
+				targets = append(targets, operands[1:]...)
+			}
+		case writeCommands[name] == "all":
+			targets = append(targets, operands...)
+		case writeCommands[name] == "last" && len(operands) > 0:
+			targets = append(targets, operands[len(operands)-1])
+		}
+
+		if dir != "" {
+			for i := start; i < len(targets); i++ {
+				targets[i] = inDir(dir, targets[i])
+			}
+		}
+		if name == "cd" {
+			if len(operands) == 0 {
+				dir = inDir(dir, "~")
+			} else {
+				dir = inDir(dir, operands[0])
+			}
+		}
+	}
+
+	return targets
+}
+
+// inDir resolves a path given in a shell that cd'd to dir, expanding ~ so a
+// later "cd .." leaves the home directory rather than cancelling it out
+func inDir(dir, path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	if dir == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// hasInPlaceFlag reports whether sed arguments include -i or --in-place
+func hasInPlaceFlag(args []string) bool {
+	for _, arg := range args {
+		if arg == "--in-place" || strings.HasPrefix(arg, "--in-place=") ||
+			(strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && strings.Contains(arg, "i")) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitShellCommands splits a command line on ;, &&, ||, | and newlines outside quotes
+func splitShellCommands(command string) []string {
+	var segments []string
+	var current strings.Builder
+	var quote rune
+
+	for _, r := range command {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+			current.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote = r
+			current.WriteRune(r)
+		case r == ';' || r == '&' || r == '|' || r == '\n':
+			// Keep ">&" and ">|" redirections intact
+			s := current.String()
+			if (r == '&' || r == '|') && strings.HasSuffix(s, ">") {
+				current.WriteRune(r)
+				continue
+			}
+			if strings.TrimSpace(s) != "" {
+				segments = append(segments, s)
+			}
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if strings.TrimSpace(current.String()) != "" {
+		segments = append(segments, current.String())
+	}
+	return segments
+}
+
+// shellWords splits a command into words, honouring single and double quotes.
+// An unquoted > starts a new word even without a space before it, so
+// "echo x>file" yields the redirection ">file"; an fd prefix like 2> or
+// another > stays attached.
+func shellWords(command string) []string {
+	var words []string
+	var current strings.Builder
+	var quote rune
+	inWord := false
+
+	for _, r := range command {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case r == '>':
+			if inWord && strings.Trim(current.String(), "0123456789&>") != "" {
+				words = append(words, current.String())
+				current.Reset()
+			}
+			current.WriteRune(r)
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, current.String())
+				current.Reset()
+				inWord = false
+			}
+		default:
+			current.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, current.String())
+	}
+	return words
+}
+
+// guardHookSettings returns claude --settings JSON that runs the filesystem
+// guard as a PreToolUse hook for the guarded tools
+func guardHookSettings(executable string, guard PathGuard) (string, error) {
+	parts := []string{shellQuote(executable), GuardHookCommand, "--root", shellQuote(guard.Root)}
+	for _, path := range guard.Allowed {
+		parts = append(parts, "--allow", shellQuote(path))
+	}
+	for _, pattern := range guard.Protected {
+		parts = append(parts, "--protect", shellQuote(pattern))
+	}
+	if guard.Unconfined {
+		parts = append(parts, "--unconfined")
+	}
+
+	settings := map[string]interface{}{
+		"hooks": map[string]interface{}{
+			"PreToolUse": []map[string]interface{}{
+				{
+					"matcher": guardedTools,
+					"hooks": []map[string]interface{}{
+						{"type": "command", "command": strings.Join(parts, " ")},
+					},
+				},
+			},
+		},
+	}
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// shellQuote quotes s for use as a single POSIX shell word
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// RunGuardHook reads a PreToolUse hook payload from in and, if the tool call
+// is not allowed, writes a deny decision to out, or an ask decision for a
+// protected path so the call goes to the approval queue
+func RunGuardHook(guard PathGuard, in io.Reader, out io.Writer) error {
+	var payload struct {
+		ToolName  string          `json:"tool_name"`
+		ToolInput json.RawMessage `json:"tool_input"`
+	}
+	if err := json.NewDecoder(in).Decode(&payload); err != nil {
+		return fmt.Errorf("failed to read hook input: %w", err)
+	}
+
+	decision := guard.CheckToolUse(payload.ToolName, payload.ToolInput)
+	if decision.Allowed {
+		// Say nothing so the CLI's own permission handling still applies
+		return nil
+	}
+
+	permission := "deny"
+	if decision.Ask {
+		permission = "ask"
+	}
+	return json.NewEncoder(out).Encode(map[string]interface{}{
+		"hookSpecificOutput": map[string]interface{}{
+			"hookEventName":            "PreToolUse",
+			"permissionDecision":       permission,
+			"permissionDecisionReason": decision.Reason,
+		},
+	})
+}
+
+// stringList collects repeated flag values
+type stringList []string
+
+func (l *stringList) String() string     { return strings.Join(*l, ",") }
+func (l *stringList) Set(v string) error { *l = append(*l, v); return nil }
+
+// GuardHookMain runs the filesystem guard hook with command-line args and
+// returns the process exit code. Failures exit with 2 so the CLI blocks the
+// tool call rather than letting it through unchecked.
+func GuardHookMain(args []string) int {
+	fs := flag.NewFlagSet(GuardHookCommand, flag.ContinueOnError)
+	root := fs.String("root", "", "Project directory writes must stay within")
+	var allowed, protected stringList
+	fs.Var(&allowed, "allow", "Additional path writes are allowed under (repeatable)")
+	fs.Var(&protected, "protect", "Glob of paths writes need approval for (repeatable)")
+	unconfined := fs.Bool("unconfined", false, "Allow writes outside the root; only protected paths are checked")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *root == "" {
+		fmt.Fprintln(os.Stderr, "filesystem guard: --root is required")
+		return 2
+	}
+
+	guard := PathGuard{Root: *root, Allowed: allowed, Protected: protected, Unconfined: *unconfined}
+	if err := RunGuardHook(guard, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "filesystem guard: %v\n", err)
+		return 2
+	}
+	return 0
+}
@@ -0,0 +1,170 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPathGuardCheckToolUse(t *testing.T) {
+	root := t.TempDir()
+	allowed := t.TempDir()
+	outside := t.TempDir()
+
+	guard := PathGuard{Root: root, Allowed: []string{allowed}}
+
+	tests := []struct {
+		name    string
+		tool    string
+		input   map[string]interface{}
+		allowed bool
+	}{
+		{"write inside root", "Write", map[string]interface{}{"file_path": filepath.Join(root, "main.go")}, true},
+		{"write relative path", "Write", map[string]interface{}{"file_path": "pkg/file.go"}, true},
+		{"write outside root", "Write", map[string]interface{}{"file_path": filepath.Join(outside, "x.go")}, false},
+		{"edit escaping with dotdot", "Edit", map[string]interface{}{"file_path": filepath.Join(root, "..", "x.go")}, false},
+		{"edit allowlisted path", "Edit", map[string]interface{}{"file_path": filepath.Join(allowed, "notes.md")}, true},
+		{"multi edit outside", "MultiEdit", map[string]interface{}{"file_path": "/etc/hosts"}, false},
+		{"notebook outside", "NotebookEdit", map[string]interface{}{"notebook_path": filepath.Join(outside, "a.ipynb")}, false},
+		{"read is not guarded", "Read", map[string]interface{}{"file_path": "/etc/passwd"}, true},
+		{"bash read only", "Bash", map[string]interface{}{"command": "cat /etc/passwd | grep root"}, true},
+		{"bash redirect inside", "Bash", map[string]interface{}{"command": "echo hi > out.txt"}, true},
+		{"bash redirect outside", "Bash", map[string]interface{}{"command": "echo hi >> " + outside + "/out.txt"}, false},
+		{"bash redirect without spaces", "Bash", map[string]interface{}{"command": "echo x>/etc/hosts"}, false},
+		{"bash append without spaces", "Bash", map[string]interface{}{"command": "cat a>>../../b"}, false},
+		{"bash redirect dotdot without spaces", "Bash", map[string]interface{}{"command": "cat a>../../b"}, false},
+		{"bash quoted redirect without spaces", "Bash", map[string]interface{}{"command": `echo x>"` + outside + `/out.txt"`}, false},
+		{"bash redirect inside without spaces", "Bash", map[string]interface{}{"command": "echo x>out.txt 2>&1"}, true},
+		{"bash quoted greater than", "Bash", map[string]interface{}{"command": "echo 'a>/etc/hosts'"}, true},
+		{"bash devnull", "Bash", map[string]interface{}{"command": "go build ./... 2>/dev/null"}, true},
+		{"bash fd duplication", "Bash", map[string]interface{}{"command": "go test ./... 2>&1 | tail"}, true},
+		{"bash rm outside", "Bash", map[string]interface{}{"command": "go vet && rm -rf " + outside}, false},
+		{"bash cp into root", "Bash", map[string]interface{}{"command": "cp /etc/hosts ./hosts"}, true},
+		{"bash cp out of root", "Bash", map[string]interface{}{"command": "cp main.go /tmp/elsewhere/main.go"}, false},
+		{"bash sed in place outside", "Bash", map[string]interface{}{"command": "sed -i 's/a/b/' " + outside + "/f"}, false},
+		{"bash sed stdout", "Bash", map[string]interface{}{"command": "sed 's/a/b/' " + outside + "/f"}, true},
+		{"bash cd outside", "Bash", map[string]interface{}{"command": "cd .. && touch x"}, false},
+		{"bash cd to read", "Bash", map[string]interface{}{"command": "cd ../x && ls"}, true},
+		{"bash cd into subdir", "Bash", map[string]interface{}{"command": "cd pkg && touch ../x"}, true},
+		{"bash cd twice", "Bash", map[string]interface{}{"command": "cd pkg; cd ../.. && rm x"}, false},
+		{"bash cd absolute", "Bash", map[string]interface{}{"command": "cd " + outside + " && rm -rf build"}, false},
+		{"bash cd home and up", "Bash", map[string]interface{}{"command": "cd && cd .. && touch x"}, false},
+		{"bash quoted path", "Bash", map[string]interface{}{"command": "touch '" + outside + "/a b'"}, false},
+		{"bash dd of", "Bash", map[string]interface{}{"command": "dd if=/dev/zero of=" + outside + "/disk"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, _ := json.Marshal(tt.input)
+			decision := guard.CheckToolUse(tt.tool, input)
+			if decision.Allowed != tt.allowed {
+				t.Errorf("Expected allowed=%v, got %v (reason: %s)", tt.allowed, decision.Allowed, decision.Reason)
+			}
+			if !decision.Allowed && decision.Reason == "" {
+				t.Error("Expected a reason for a blocked tool call")
+			}
+		})
+	}
+}
+
+func TestPathGuardSymlinkEscape(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+
+	link := filepath.Join(root, "escape")
+	if err := os.Symlink(outside, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	guard := PathGuard{Root: root}
+	input, _ := json.Marshal(map[string]string{"file_path": filepath.Join(link, "new.txt")})
+	if guard.CheckToolUse("Write", input).Allowed {
+		t.Error("Expected write through a symlink leaving the root to be blocked")
+	}
+}
+
+func TestRunGuardHook(t *testing.T) {
+	root := t.TempDir()
+	guard := PathGuard{Root: root}
+
+	// Allowed calls produce no output so normal permission handling applies
+	var out bytes.Buffer
+	in := `{"tool_name":"Write","tool_input":{"file_path":"ok.txt"}}`
+	if err := RunGuardHook(guard, strings.NewReader(in), &out); err != nil {
+		t.Fatalf("RunGuardHook failed: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected no output for allowed call, got %s", out.String())
+	}
+
+	out.Reset()
+	in = `{"tool_name":"Write","tool_input":{"file_path":"/etc/blocked"}}`
+	if err := RunGuardHook(guard, strings.NewReader(in), &out); err != nil {
+		t.Fatalf("RunGuardHook failed: %v", err)
+	}
+
+	var result struct {
+		HookSpecificOutput struct {
+			HookEventName            string `json:"hookEventName"`
+			PermissionDecision       string `json:"permissionDecision"`
+			PermissionDecisionReason string `json:"permissionDecisionReason"`
+		} `json:"hookSpecificOutput"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("Failed to parse hook output: %v", err)
+	}
+	if result.HookSpecificOutput.HookEventName != "PreToolUse" {
+		t.Errorf("Expected hookEventName PreToolUse, got %s", result.HookSpecificOutput.HookEventName)
+	}
+	if result.HookSpecificOutput.PermissionDecision != "deny" {
+		t.Errorf("Expected deny, got %s", result.HookSpecificOutput.PermissionDecision)
+	}
+	if !strings.Contains(result.HookSpecificOutput.PermissionDecisionReason, "/etc/blocked") {
+		t.Errorf("Expected reason to mention the path, got %s", result.HookSpecificOutput.PermissionDecisionReason)
+	}
+
+	if err := RunGuardHook(guard, strings.NewReader("not json"), &out); err == nil {
+		t.Error("Expected error for malformed hook input")
+	}
+}
+
+func TestGuardHookSettings(t *testing.T) {
+	settings, err := guardHookSettings("/Applications/Boat Man/boatman", PathGuard{
+		Root:    "/work/it's here",
+		Allowed: []string{"/tmp/scratch"},
+	})
+	if err != nil {
+		t.Fatalf("guardHookSettings failed: %v", err)
+	}
+
+	var parsed struct {
+		Hooks struct {
+			PreToolUse []struct {
+				Matcher string `json:"matcher"`
+				Hooks   []struct {
+					Type    string `json:"type"`
+					Command string `json:"command"`
+				} `json:"hooks"`
+			} `json:"PreToolUse"`
+		} `json:"hooks"`
+	}
+	if err := json.Unmarshal([]byte(settings), &parsed); err != nil {
+		t.Fatalf("Failed to parse settings: %v", err)
+	}
+	if len(parsed.Hooks.PreToolUse) != 1 || len(parsed.Hooks.PreToolUse[0].Hooks) != 1 {
+		t.Fatalf("Expected one PreToolUse hook, got %s", settings)
+	}
+
+	entry := parsed.Hooks.PreToolUse[0]
+	if entry.Matcher != guardedTools {
+		t.Errorf("Expected matcher %s, got %s", guardedTools, entry.Matcher)
+	}
+
+	expected := `'/Applications/Boat Man/boatman' fs-guard-hook --root '/work/it'\''s here' --allow '/tmp/scratch'`
+	if entry.Hooks[0].Command != expected {
+		t.Errorf("Expected command %s, got %s", expected, entry.Hooks[0].Command)
+	}
+}
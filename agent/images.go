@@ -0,0 +1,172 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // Registered for thumbnails
+	"image/jpeg"
+	_ "image/png" // Registered for thumbnails
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+)
+
+// maxImageBytes is the largest image claude accepts
+const maxImageBytes = 5 << 20
+
+// maxImagesPerMessage bounds how many images one message can carry
+const maxImagesPerMessage = 10
+
+// thumbnailSize is the longest side of the thumbnails kept in the transcript
+const thumbnailSize = 200
+
+// imageMediaTypes are the image formats claude can read
+var imageMediaTypes = []string{"image/png", "image/jpeg", "image/gif", "image/webp"}
+
+// ImageAttachment is an image sent with a message, such as a pasted
+// screenshot. It carries the image itself as base64 Data, or the Path of a
+// file holding it, e.g. a temp file the screenshot was saved to.
+type ImageAttachment struct {
+	Data string `json:"data,omitempty"`
+	Path string `json:"path,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// ImageThumbnail is what the transcript keeps of an image sent with a message
+type ImageThumbnail struct {
+	Name      string `json:"name,omitempty"`
+	MediaType string `json:"mediaType"`
+	Width     int    `json:"width,omitempty"`
+	Height    int    `json:"height,omitempty"`
+	// Thumbnail is a base64 JPEG preview; empty for formats that can't be decoded, like WebP
+	Thumbnail string `json:"thumbnail,omitempty"`
+}
+
+// promptImage is an image as sent to claude
+type promptImage struct {
+	MediaType string
+	Data      string // Base64
+}
+
+// loadImages reads and checks the images attached to a message, returning
+// them as sent to claude and as kept in the transcript
+func loadImages(attachments []ImageAttachment) ([]promptImage, []ImageThumbnail, error) {
+	if len(attachments) > maxImagesPerMessage {
+		return nil, nil, fmt.Errorf("a message can have at most %d images", maxImagesPerMessage)
+	}
+
+	var images []promptImage
+	var thumbnails []ImageThumbnail
+	for i, attachment := range attachments {
+		name := attachment.Name
+		if name == "" {
+			name = fmt.Sprintf("image %d", i+1)
+		}
+		data, err := readImage(attachment)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to attach %s: %w", name, err)
+		}
+		if len(data) > maxImageBytes {
+			return nil, nil, fmt.Errorf("%s is over the %d MB image limit", name, maxImageBytes>>20)
+		}
+		mediaType := http.DetectContentType(data)
+		if !slices.Contains(imageMediaTypes, mediaType) {
+			return nil, nil, fmt.Errorf("%s isn't a PNG, JPEG, GIF or WebP image", name)
+		}
+
+		images = append(images, promptImage{MediaType: mediaType, Data: base64.StdEncoding.EncodeToString(data)})
+		thumbnails = append(thumbnails, newImageThumbnail(attachment.Name, mediaType, data))
+	}
+	return images, thumbnails, nil
+}
+
+// readImage returns an attachment's image bytes. Data may be a data: URL, as
+// the clipboard gives it.
+func readImage(attachment ImageAttachment) ([]byte, error) {
+	if attachment.Data != "" {
+		data := attachment.Data
+		if strings.HasPrefix(data, "data:") {
+			if _, encoded, ok := strings.Cut(data, ","); ok {
+				data = encoded
+			}
+		}
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if attachment.Path != "" {
+		return os.ReadFile(attachment.Path)
+	}
+	return nil, fmt.Errorf("no image data")
+}
+
+// newImageThumbnail describes an image for the transcript, with a small
+// preview when its format can be decoded
+func newImageThumbnail(name, mediaType string, data []byte) ImageThumbnail {
+	thumbnail := ImageThumbnail{Name: name, MediaType: mediaType}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return thumbnail
+	}
+	bounds := img.Bounds()
+	thumbnail.Width, thumbnail.Height = bounds.Dx(), bounds.Dy()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, scaleImage(img, thumbnailSize), &jpeg.Options{Quality: 80}); err == nil {
+		thumbnail.Thumbnail = base64.StdEncoding.EncodeToString(buf.Bytes())
+	}
+	return thumbnail
+}
+
+// scaleImage shrinks img to fit within size pixels on its longest side,
+// sampling the nearest pixel, over a white background for transparent images
+func scaleImage(img image.Image, size int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > size || height > size {
+		if width >= height {
+			width, height = size, max(1, height*size/bounds.Dx())
+		} else {
+			width, height = max(1, width*size/bounds.Dy()), size
+		}
+	}
+
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(scaled, scaled.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			src := img.At(bounds.Min.X+x*bounds.Dx()/width, bounds.Min.Y+y*bounds.Dy()/height)
+			r, g, b, a := src.RGBA()
+			if a == 0 {
+				continue
+			}
+			// Blend over white; RGBA values are premultiplied by alpha
+			white := 0xffff - a
+			scaled.Set(x, y, color.RGBA64{R: uint16(r + white), G: uint16(g + white), B: uint16(b + white), A: 0xffff})
+		}
+	}
+	return scaled
+}
+
+// promptContent is the content of the stream-json user message for a prompt:
+// plain text, or content blocks when images go with it
+func promptContent(prompt string, images []promptImage) any {
+	if len(images) == 0 {
+		return prompt
+	}
+	blocks := make([]map[string]any, 0, len(images)+1)
+	for _, img := range images {
+		blocks = append(blocks, map[string]any{
+			"type": "image",
+			"source": map[string]any{
+				"type":       "base64",
+				"media_type": img.MediaType,
+				"data":       img.Data,
+			},
+		})
+	}
+	return append(blocks, map[string]any{"type": "text", "text": prompt})
+}
@@ -0,0 +1,135 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testPNG returns a width x height red PNG
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestLoadImages(t *testing.T) {
+	data := testPNG(t, 800, 400)
+	path := filepath.Join(t.TempDir(), "screenshot.png")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	encoded := base64.StdEncoding.EncodeToString(data)
+
+	tests := []struct {
+		name       string
+		attachment ImageAttachment
+		wantErr    bool
+	}{
+		{"base64", ImageAttachment{Data: encoded}, false},
+		{"data URL", ImageAttachment{Data: "data:image/png;base64," + encoded}, false},
+		{"temp file", ImageAttachment{Path: path, Name: "screenshot.png"}, false},
+		{"not an image", ImageAttachment{Data: base64.StdEncoding.EncodeToString([]byte("hello"))}, true},
+		{"bad base64", ImageAttachment{Data: "not base64!"}, true},
+		{"empty", ImageAttachment{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			images, thumbnails, err := loadImages([]ImageAttachment{tt.attachment})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadImages() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if images[0].MediaType != "image/png" || images[0].Data != encoded {
+				t.Errorf("Expected the PNG as base64, got %s", images[0].MediaType)
+			}
+			thumbnail := thumbnails[0]
+			if thumbnail.Name != tt.attachment.Name || thumbnail.Width != 800 || thumbnail.Height != 400 {
+				t.Errorf("Unexpected thumbnail %+v", thumbnail)
+			}
+			preview, err := base64.StdEncoding.DecodeString(thumbnail.Thumbnail)
+			if err != nil {
+				t.Fatalf("Expected a base64 thumbnail: %v", err)
+			}
+			config, format, err := image.DecodeConfig(bytes.NewReader(preview))
+			if err != nil || format != "jpeg" || config.Width != thumbnailSize || config.Height != thumbnailSize/2 {
+				t.Errorf("Expected a %dx%d JPEG thumbnail, got %s %+v, %v", thumbnailSize, thumbnailSize/2, format, config, err)
+			}
+		})
+	}
+
+	if _, _, err := loadImages(make([]ImageAttachment, maxImagesPerMessage+1)); err == nil {
+		t.Error("Expected too many images to be refused")
+	}
+}
+
+func TestSendMessageWithImages(t *testing.T) {
+	session := NewSession("images", t.TempDir())
+	session.ctx, session.cancel = context.WithCancel(context.Background())
+	defer session.cancel()
+	runner := &fakeRunner{}
+	session.runner = runner
+	done := make(chan struct{}, 1)
+	session.SetStatusHandler(func(status SessionStatus) {
+		if status != SessionStatusRunning {
+			done <- struct{}{}
+		}
+	})
+
+	images := []ImageAttachment{{Data: base64.StdEncoding.EncodeToString(testPNG(t, 10, 10)), Name: "error.png"}}
+	if err := session.SendMessageWithAttachments("What's this error?", nil, images, AuthConfig{ClaudeCLIPath: fakeClaudeBinary(t)}); err != nil {
+		t.Fatalf("SendMessageWithAttachments failed: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the run")
+	}
+
+	msg := session.GetMessages()[0]
+	if msg.Metadata == nil || len(msg.Metadata.Images) != 1 || msg.Metadata.Images[0].Name != "error.png" {
+		t.Errorf("Expected the image kept in the transcript, got %+v", msg.Metadata)
+	}
+
+	// claude gets the image as a content block before the text
+	var input struct {
+		Message struct {
+			Content []struct {
+				Type   string `json:"type"`
+				Text   string `json:"text"`
+				Source struct {
+					MediaType string `json:"media_type"`
+				} `json:"source"`
+			} `json:"content"`
+		} `json:"message"`
+	}
+	line, _, _ := strings.Cut(runner.stdin.String(), "\n")
+	if err := json.Unmarshal([]byte(line), &input); err != nil {
+		t.Fatalf("Failed to parse the prompt: %v", err)
+	}
+	content := input.Message.Content
+	if len(content) != 2 || content[0].Type != "image" || content[0].Source.MediaType != "image/png" || content[1].Text != "What's this error?" {
+		t.Errorf("Expected an image block then the text, got %+v", content)
+	}
+}
@@ -0,0 +1,354 @@
+package agent
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"boatman/diff"
+)
+
+// TimelineKind is what an incident timeline entry records
+type TimelineKind string
+
+const (
+	TimelineAlert      TimelineKind = "alert"      // An alert was detected
+	TimelineAction     TimelineKind = "action"     // The agent did something
+	TimelineResolution TimelineKind = "resolution" // A step toward resolving the incident
+)
+
+// maxIncidentActions caps the agent actions kept in an incident's timeline;
+// later ones are only counted
+const maxIncidentActions = 200
+
+// incidentReadOnlyTools are tools that only look at the project, which would
+// crowd out the actions that matter in a postmortem
+var incidentReadOnlyTools = map[string]bool{
+	"Read":      true,
+	"Glob":      true,
+	"Grep":      true,
+	"LS":        true,
+	"TodoWrite": true,
+}
+
+// TimelineEntry is one timestamped event of an incident
+type TimelineEntry struct {
+	Time     time.Time    `json:"time"`
+	Kind     TimelineKind `json:"kind"`
+	Summary  string       `json:"summary"`
+	Detail   string       `json:"detail,omitempty"`
+	Source   string       `json:"source,omitempty"`   // Where an alert came from, e.g. "datadog"
+	Severity string       `json:"severity,omitempty"` // An alert's severity
+	URL      string       `json:"url,omitempty"`
+}
+
+// Incident is the timeline of a firefighter session: the alerts it handled,
+// what the agent did about them, and how they were resolved
+type Incident struct {
+	Title          string          `json:"title"`
+	Severity       string          `json:"severity,omitempty"`
+	StartedAt      time.Time       `json:"startedAt"`
+	ResolvedAt     time.Time       `json:"resolvedAt,omitempty"`
+	Resolution     string          `json:"resolution,omitempty"` // Summary given when resolved
+	Timeline       []TimelineEntry `json:"timeline"`
+	OmittedActions int             `json:"omittedActions,omitempty"` // Actions past maxIncidentActions
+}
+
+// IsResolved reports whether the incident has been resolved
+func (inc *Incident) IsResolved() bool {
+	return !inc.ResolvedAt.IsZero()
+}
+
+// clone returns a copy that shares nothing with the incident
+func (inc *Incident) clone() *Incident {
+	if inc == nil {
+		return nil
+	}
+	copied := *inc
+	copied.Timeline = append([]TimelineEntry(nil), inc.Timeline...)
+	return &copied
+}
+
+// actionCount returns how many agent actions the timeline holds
+func (inc *Incident) actionCount() int {
+	count := 0
+	for _, entry := range inc.Timeline {
+		if entry.Kind == TimelineAction {
+			count++
+		}
+	}
+	return count
+}
+
+// incidentLocked returns the session's incident, opening one at now if there
+// is none yet.
+// Note: This method expects the caller to hold s.mu lock
+func (s *Session) incidentLocked(now time.Time) *Incident {
+	if s.incident == nil {
+		s.incident = &Incident{StartedAt: now}
+	}
+	return s.incident
+}
+
+// RecordIncidentAlert adds a detected alert to the session's incident. The
+// first alert names the incident, and an alert after the incident was
+// resolved reopens it.
+func (s *Session) RecordIncidentAlert(alert Alert) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	incident := s.incidentLocked(now)
+	if incident.Title == "" {
+		incident.Title = alert.Title
+	}
+	if incident.Severity == "" {
+		incident.Severity = alert.Severity
+	}
+	incident.ResolvedAt = time.Time{}
+	incident.Resolution = ""
+
+	incident.Timeline = append(incident.Timeline, TimelineEntry{
+		Time:     now,
+		Kind:     TimelineAlert,
+		Summary:  alert.Title,
+		Detail:   redactString(alert.Description),
+		Source:   alert.Source,
+		Severity: alert.Severity,
+		URL:      alert.URL,
+	})
+}
+
+// RecordIncidentAction adds something the agent did to the incident timeline
+func (s *Session) RecordIncidentAction(summary, detail string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recordIncidentActionLocked(summary, detail)
+}
+
+// recordIncidentActionLocked adds an agent action to the incident timeline,
+// only counting it once the timeline holds maxIncidentActions actions.
+// Note: This method expects the caller to hold s.mu lock
+func (s *Session) recordIncidentActionLocked(summary, detail string) {
+	now := time.Now()
+	incident := s.incidentLocked(now)
+	if incident.actionCount() >= maxIncidentActions {
+		incident.OmittedActions++
+		return
+	}
+	incident.Timeline = append(incident.Timeline, TimelineEntry{
+		Time:    now,
+		Kind:    TimelineAction,
+		Summary: summary,
+		Detail:  redactString(detail),
+	})
+}
+
+// RecordResolutionStep adds a step taken to resolve the incident
+func (s *Session) RecordResolutionStep(step string) error {
+	step = strings.TrimSpace(step)
+	if step == "" {
+		return fmt.Errorf("resolution step is empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	incident := s.incidentLocked(now)
+	incident.Timeline = append(incident.Timeline, TimelineEntry{
+		Time:    now,
+		Kind:    TimelineResolution,
+		Summary: redactString(step),
+	})
+	return nil
+}
+
+// ResolveIncident marks the session's incident resolved, with a summary of
+// how; further alerts reopen it
+func (s *Session) ResolveIncident(summary string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.incident == nil {
+		return fmt.Errorf("session has no incident to resolve")
+	}
+	s.incident.ResolvedAt = time.Now()
+	s.incident.Resolution = redactString(strings.TrimSpace(summary))
+	return nil
+}
+
+// GetIncident returns a copy of the session's incident, or nil if nothing
+// has been recorded
+func (s *Session) GetIncident() *Incident {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.incident.clone()
+}
+
+// GeneratePostmortem renders the session's incident timeline as a Markdown
+// postmortem, with the file changes made during the incident
+func (s *Session) GeneratePostmortem() (string, error) {
+	s.mu.RLock()
+	incident := s.incident.clone()
+	projectPath := s.ProjectPath
+	changeSets := append([]ChangeSet(nil), s.changeSets...)
+	s.mu.RUnlock()
+
+	if incident == nil {
+		return "", fmt.Errorf("session has no incident timeline")
+	}
+	return postmortemMarkdown(incident, projectPath, incidentChanges(incident, changeSets), time.Now()), nil
+}
+
+// incidentChanges diffs the files changed by turns that finished after the
+// incident started, from their content before the first such turn to after
+// the last
+func incidentChanges(incident *Incident, changeSets []ChangeSet) []diff.FileDiff {
+	var files []FileChange
+	for _, set := range changeSets {
+		if set.CompletedAt.Before(incident.StartedAt) {
+			continue
+		}
+		for _, file := range set.Files {
+			earlier := findFileChange(files, file.Path)
+			if earlier == nil {
+				files = append(files, file)
+				continue
+			}
+			earlier.After, earlier.Exists = file.After, file.Exists
+			if file.TooLarge {
+				earlier.TooLarge = true
+			}
+			if file.Withheld {
+				earlier.Withheld = true
+			}
+		}
+	}
+
+	diffs := make([]diff.FileDiff, 0, len(files))
+	for _, file := range files {
+		diffs = append(diffs, fileChangeDiff(file))
+	}
+	return diffs
+}
+
+// postmortemMarkdown renders an incident as a Markdown postmortem, with the
+// changes made during it as an HTML block so they read as diffs wherever the
+// postmortem is shared; now ends the duration of an unresolved incident
+func postmortemMarkdown(incident *Incident, projectPath string, changes []diff.FileDiff, now time.Time) string {
+	const timeLayout = "2006-01-02 15:04:05"
+	var b strings.Builder
+
+	title := incident.Title
+	if title == "" {
+		title = "Incident in " + filepath.Base(projectPath)
+	}
+	fmt.Fprintf(&b, "# Postmortem: %s\n\n", title)
+
+	end := now
+	if incident.IsResolved() {
+		fmt.Fprintf(&b, "- **Status:** Resolved\n")
+		end = incident.ResolvedAt
+	} else {
+		fmt.Fprintf(&b, "- **Status:** Ongoing\n")
+	}
+	if incident.Severity != "" {
+		fmt.Fprintf(&b, "- **Severity:** %s\n", incident.Severity)
+	}
+	if projectPath != "" {
+		fmt.Fprintf(&b, "- **Project:** `%s`\n", projectPath)
+	}
+	fmt.Fprintf(&b, "- **Started:** %s\n", incident.StartedAt.Local().Format(timeLayout))
+	if incident.IsResolved() {
+		fmt.Fprintf(&b, "- **Resolved:** %s\n", incident.ResolvedAt.Local().Format(timeLayout))
+	}
+	fmt.Fprintf(&b, "- **Duration:** %s\n", end.Sub(incident.StartedAt).Round(time.Second))
+
+	b.WriteString("\n## Summary\n\n")
+	if incident.Resolution != "" {
+		b.WriteString(incident.Resolution + "\n")
+	} else if incident.IsResolved() {
+		b.WriteString("_Resolved without a summary._\n")
+	} else {
+		b.WriteString("_Not resolved yet._\n")
+	}
+
+	var alerts, steps []TimelineEntry
+	for _, entry := range incident.Timeline {
+		switch entry.Kind {
+		case TimelineAlert:
+			alerts = append(alerts, entry)
+		case TimelineResolution:
+			steps = append(steps, entry)
+		}
+	}
+
+	if len(alerts) > 0 {
+		b.WriteString("\n## Alerts\n\n")
+		for _, alert := range alerts {
+			line := "**" + alert.Summary + "**"
+			if alert.URL != "" {
+				line = fmt.Sprintf("[%s](%s)", line, alert.URL)
+			}
+			var labels []string
+			if alert.Source != "" {
+				labels = append(labels, alert.Source)
+			}
+			if alert.Severity != "" {
+				labels = append(labels, alert.Severity)
+			}
+			if len(labels) > 0 {
+				line += " (" + strings.Join(labels, ", ") + ")"
+			}
+			fmt.Fprintf(&b, "- %s\n", line)
+			if alert.Detail != "" {
+				fmt.Fprintf(&b, "  > %s\n", strings.ReplaceAll(alert.Detail, "\n", "\n  > "))
+			}
+		}
+	}
+
+	b.WriteString("\n## Timeline\n\n")
+	for _, entry := range incident.Timeline {
+		fmt.Fprintf(&b, "- `%s` %s\n", entry.Time.Local().Format(timeLayout), timelineLine(entry))
+	}
+	if incident.OmittedActions > 0 {
+		fmt.Fprintf(&b, "- _%d more agent actions not recorded_\n", incident.OmittedActions)
+	}
+	if incident.IsResolved() {
+		fmt.Fprintf(&b, "- `%s` **Resolved**\n", incident.ResolvedAt.Local().Format(timeLayout))
+	}
+
+	if len(steps) > 0 {
+		b.WriteString("\n## Resolution Steps\n\n")
+		for i, step := range steps {
+			fmt.Fprintf(&b, "%d. %s\n", i+1, step.Summary)
+		}
+	}
+
+	if len(changes) > 0 {
+		b.WriteString("\n## Changes\n\n")
+		b.WriteString(diff.RenderHTML(changes, diff.HTMLOptions{Layout: diff.HTMLLayoutUnified}) + "\n")
+	}
+
+	b.WriteString("\n## Follow-up Actions\n\n- [ ] _Add follow-up actions_\n")
+	return b.String()
+}
+
+// timelineLine describes a timeline entry in one line of Markdown
+func timelineLine(entry TimelineEntry) string {
+	switch entry.Kind {
+	case TimelineAlert:
+		if entry.Source != "" {
+			return fmt.Sprintf("**Alert** (%s): %s", entry.Source, entry.Summary)
+		}
+		return "**Alert:** " + entry.Summary
+	case TimelineResolution:
+		return "**Resolution:** " + entry.Summary
+	}
+	if entry.Detail != "" {
+		return entry.Summary + ": " + strings.Join(strings.Fields(entry.Detail), " ")
+	}
+	return entry.Summary
+}
@@ -0,0 +1,147 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"boatman/stream"
+)
+
+func TestIncidentTimeline(t *testing.T) {
+	session := NewSession("incident-session", "/work/payments")
+	session.Mode = "firefighter"
+
+	if _, err := session.GeneratePostmortem(); err == nil {
+		t.Error("Expected an error without an incident")
+	}
+
+	session.RecordIncidentAlert(Alert{Source: "datadog", Severity: "critical", Title: "Checkout 5xx spike", URL: "https://datadog/monitor/1"})
+	session.handleToolUse(stream.ToolUse{ID: "tool-1", Name: "Read", Input: map[string]any{"file_path": "main.go"}})
+	session.handleToolUse(stream.ToolUse{ID: "tool-2", Name: "mcp__datadog__query_logs", Input: map[string]any{"query": "status:error"}})
+	if err := session.RecordResolutionStep("Rolled back deploy 42"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := session.ResolveIncident("A bad deploy broke checkout; rolling back fixed it."); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	incident := session.GetIncident()
+	var kinds []string
+	for _, entry := range incident.Timeline {
+		kinds = append(kinds, string(entry.Kind))
+	}
+	if got := strings.Join(kinds, ","); got != "alert,action,resolution" {
+		t.Errorf("Expected read-only tools to be left out of the timeline, got %s", got)
+	}
+	if incident.Title != "Checkout 5xx spike" || incident.Severity != "critical" || !incident.IsResolved() {
+		t.Errorf("Unexpected incident %+v", incident)
+	}
+
+	postmortem, err := session.GeneratePostmortem()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"# Postmortem: Checkout 5xx spike\n",
+		"- **Status:** Resolved\n",
+		"- **Severity:** critical\n",
+		"A bad deploy broke checkout; rolling back fixed it.\n",
+		"- [**Checkout 5xx spike**](https://datadog/monitor/1) (datadog, critical)\n",
+		"**Alert** (datadog): Checkout 5xx spike\n",
+		"🔌 datadog: query_logs\n",
+		"1. Rolled back deploy 42\n",
+	} {
+		if !strings.Contains(postmortem, want) {
+			t.Errorf("Expected postmortem to contain %q, got:\n%s", want, postmortem)
+		}
+	}
+
+	// A new alert reopens the incident
+	session.RecordIncidentAlert(Alert{Source: "bugsnag", Title: "NilPointer in checkout"})
+	if incident := session.GetIncident(); incident.IsResolved() || incident.Resolution != "" || incident.Title != "Checkout 5xx spike" {
+		t.Errorf("Expected the incident to be reopened, got %+v", incident)
+	}
+}
+
+func TestIncidentTimeline_StandardSession(t *testing.T) {
+	session := NewSession("standard-session", "/work/payments")
+	session.handleToolUse(stream.ToolUse{ID: "tool-1", Name: "Bash", Input: map[string]any{"command": "ls"}})
+
+	if incident := session.GetIncident(); incident != nil {
+		t.Errorf("Expected no incident outside firefighter sessions, got %+v", incident)
+	}
+	if err := session.ResolveIncident("done"); err == nil {
+		t.Error("Expected an error resolving without an incident")
+	}
+}
+
+func TestIncidentTimeline_ActionCap(t *testing.T) {
+	session := NewSession("busy-session", "/work/payments")
+	for i := 0; i < maxIncidentActions+5; i++ {
+		session.RecordIncidentAction("💻 Running: kubectl get pods", "")
+	}
+
+	incident := session.GetIncident()
+	if len(incident.Timeline) != maxIncidentActions || incident.OmittedActions != 5 {
+		t.Errorf("Expected %d actions and 5 omitted, got %d and %d", maxIncidentActions, len(incident.Timeline), incident.OmittedActions)
+	}
+}
+
+func TestPostmortemMarkdown_Ongoing(t *testing.T) {
+	started := time.Date(2024, 3, 10, 12, 0, 0, 0, time.Local)
+	incident := &Incident{StartedAt: started, Timeline: []TimelineEntry{
+		{Time: started, Kind: TimelineAction, Summary: "💻 Running: kubectl get pods", Detail: "took\n  2s"},
+	}}
+
+	got := postmortemMarkdown(incident, "/work/payments", nil, started.Add(90*time.Minute))
+	for _, want := range []string{
+		"# Postmortem: Incident in payments\n",
+		"- **Status:** Ongoing\n",
+		"- **Duration:** 1h30m0s\n",
+		"_Not resolved yet._\n",
+		"- `2024-03-10 12:00:00` 💻 Running: kubectl get pods: took 2s\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected postmortem to contain %q, got:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "## Alerts") || strings.Contains(got, "## Resolution Steps") || strings.Contains(got, "## Changes") {
+		t.Errorf("Expected no empty sections, got:\n%s", got)
+	}
+}
+
+func TestGeneratePostmortem_Changes(t *testing.T) {
+	session := NewSession("incident-changes", "/work/payments")
+	session.RecordIncidentAlert(Alert{Source: "datadog", Title: "Checkout 5xx spike"})
+	started := session.GetIncident().StartedAt
+
+	session.changeSets = []ChangeSet{
+		{MessageID: "before", CompletedAt: started.Add(-time.Minute), Files: []FileChange{
+			{Path: "old.go", Before: "a\n", After: "b\n", Existed: true, Exists: true},
+		}},
+		{MessageID: "first", CompletedAt: started.Add(time.Minute), Files: []FileChange{
+			{Path: "config.yml", Before: "timeout: 1s\n", After: "timeout: 5s\n", Existed: true, Exists: true},
+		}},
+		{MessageID: "second", CompletedAt: started.Add(2 * time.Minute), Files: []FileChange{
+			{Path: "config.yml", Before: "timeout: 5s\n", After: "timeout: 10s\n", Existed: true, Exists: true},
+		}},
+	}
+
+	postmortem, err := session.GeneratePostmortem()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, section, found := strings.Cut(postmortem, "## Changes")
+	if !found || !strings.Contains(section, `<div class="boatman-diff">`) {
+		t.Fatalf("Expected the changes rendered by diff.RenderHTML, got:\n%s", postmortem)
+	}
+	for _, want := range []string{"config.yml", "timeout: 1s", "timeout: 10s"} {
+		if !strings.Contains(section, want) {
+			t.Errorf("Expected the changes to contain %q, got:\n%s", want, section)
+		}
+	}
+	if strings.Contains(section, "timeout: 5s") || strings.Contains(section, "old.go") {
+		t.Errorf("Expected one diff per file of the changes made during the incident, got:\n%s", section)
+	}
+}
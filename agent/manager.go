@@ -4,9 +4,11 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"time"
+
+	"boatman/git"
 
 	"github.com/google/uuid"
-	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // AuthConfig holds authentication configuration
@@ -16,6 +18,34 @@ type AuthConfig struct {
 	GCPProjectID string
 	GCPRegion    string
 	ApprovalMode string // "suggest", "auto-edit", "full-auto"
+	// MCPConfigPath, when set, restricts the session to the MCP servers in this config file
+	MCPConfigPath string
+	// BuiltinMCPConfig is inline MCP config for servers Boatman provides, which
+	// are attached alongside the user's servers
+	BuiltinMCPConfig string
+	// FilesystemGuardrails blocks Write/Edit/Bash file changes outside the
+	// project directory and GuardrailAllowedPaths
+	FilesystemGuardrails  bool
+	GuardrailAllowedPaths []string
+	// AdditionalDirs are directories besides the project the session can
+	// work in, passed with --add-dir
+	AdditionalDirs []string
+	// ProtectedPaths are globs like ".env" or "secrets/" whose writes wait for
+	// approval in every approval mode
+	ProtectedPaths []string
+	// AppendSystemPrompt is added to claude's system prompt for every message
+	AppendSystemPrompt string
+	// ClaudeCLIPath is the claude binary to run; empty finds it on PATH
+	ClaudeCLIPath string
+	// RetryPolicy controls retrying runs that fail transiently
+	RetryPolicy RetryPolicy
+	// PlanOnly restricts the run to read-only tools and asks for a plan with
+	// proposed diffs instead of changes
+	PlanOnly bool
+	// ToolPolicy allows or denies tools on top of the approval mode
+	ToolPolicy ToolPolicy
+	// Model overrides the session's model for this run only
+	Model string
 }
 
 // ConfigGetter retrieves memory management configuration
@@ -27,6 +57,7 @@ type ConfigGetter interface {
 	GetAutoCleanupSessions() bool
 	GetMaxAgentsPerSession() int
 	GetKeepCompletedAgents() bool
+	GetMaxCommandRuntimeSeconds() int
 }
 
 // Manager handles multiple agent sessions
@@ -37,25 +68,95 @@ type Manager struct {
 	defaultModel     string
 	authConfigGetter func() AuthConfig
 	configGetter     ConfigGetter
+	// mcpConfigResolver returns the MCP config file for a project, or "" to use
+	// the default. sessionServers, when not nil, overrides the project's servers.
+	mcpConfigResolver func(projectPath string, sessionServers []string) (string, error)
+	// systemPromptResolver returns a project's addition to the user's system prompt
+	systemPromptResolver func(projectPath string) string
+	// projectDefaultsResolver returns a project's model and approval mode
+	projectDefaultsResolver func(projectPath string) ProjectDefaults
+	// toolPolicyResolver returns a project's tool policy
+	toolPolicyResolver func(projectPath string) ToolPolicy
+	// projectMemoryResolver returns a project's shared notes and the tool to add to them
+	projectMemoryResolver func(projectPath string) ProjectMemory
+	// streamBatchInterval is how long sessions gather streamed text before sending it
+	streamBatchInterval time.Duration
+	// statusListener observes every session's status changes, e.g. for notifications
+	statusListener func(session *Session, status SessionStatus)
+	// costListener observes every session's total cost as it grows
+	costListener func(session *Session, cost float64)
+	// toolFormatters summarize tool calls that have no built-in description
+	toolFormatters *ToolFormatterRegistry
+	// costs totals spending against the cost budget
+	costs *CostTracker
+	// attention tracks sessions waiting for approval or stopped by an error
+	attention *AttentionTracker
+	// scheduler limits how many sessions run claude at once
+	scheduler *RunScheduler
+	// runner starts claude for prompts outside a session; nil uses ExecRunner
+	runner ProcessRunner
+
+	// pendingSaves holds a timer per session with changes not yet in the store
+	saveMu       sync.Mutex
+	pendingSaves map[string]*time.Timer
+
+	// sink receives session events; see events()
+	sinkMu sync.RWMutex
+	sink   EventSink
 }
 
+// sessionSaveDelay batches a burst of session changes into one save
+const sessionSaveDelay = 500 * time.Millisecond
+
 // NewManager creates a new agent manager
 func NewManager() *Manager {
 	return &Manager{
-		sessions:     make(map[string]*Session),
-		defaultModel: "sonnet",
+		sessions:       make(map[string]*Session),
+		defaultModel:   fallbackModel,
+		toolFormatters: NewToolFormatterRegistry(),
+		costs:          NewCostTracker(),
+		attention:      NewAttentionTracker(),
+		scheduler:      NewRunScheduler(DefaultMaxConcurrentRuns),
+		pendingSaves:   make(map[string]*time.Timer),
+		sink:           NopSink{},
+
+		streamBatchInterval: DefaultStreamBatchInterval,
 	}
 }
 
-// SetContext sets the Wails runtime context
+// ToolFormatters returns the registry of user-defined tool formatters shared by all sessions
+func (m *Manager) ToolFormatters() *ToolFormatterRegistry {
+	return m.toolFormatters
+}
+
+// Costs returns the tracker of spending across all sessions
+func (m *Manager) Costs() *CostTracker {
+	return m.costs
+}
+
+// SetBudgetGetter sets the function that returns the cost budget. Messages
+// are blocked once a session or the day reaches its limit.
+func (m *Manager) SetBudgetGetter(getter func() Budget) {
+	m.costs.SetBudgetGetter(getter)
+}
+
+// SetContext sets the Wails runtime context and sends session events to the
+// frontend through it
 func (m *Manager) SetContext(ctx context.Context) {
 	m.ctx = ctx
+	m.SetEventSink(NewWailsSink(ctx))
 }
 
-// SetDefaultModel sets the default model for new sessions
+// fallbackModel is the default model when the user hasn't picked one
+const fallbackModel = "sonnet"
+
+// SetDefaultModel sets the default model for new sessions; empty uses fallbackModel
 func (m *Manager) SetDefaultModel(model string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if model == "" {
+		model = fallbackModel
+	}
 	m.defaultModel = model
 }
 
@@ -78,6 +179,54 @@ func (m *Manager) SetAPIKeyGetter(getter func() string) {
 	}
 }
 
+// SetMCPConfigResolver sets the function that picks the MCP config file for a
+// project, or for a session that chose its own servers
+func (m *Manager) SetMCPConfigResolver(resolver func(projectPath string, sessionServers []string) (string, error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mcpConfigResolver = resolver
+}
+
+// SetToolPolicyResolver sets the function that returns a project's tool policy
+func (m *Manager) SetToolPolicyResolver(resolver func(projectPath string) ToolPolicy) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.toolPolicyResolver = resolver
+}
+
+// SetProjectDefaultsResolver sets the function that returns the model and
+// approval mode a project's sessions use unless they choose their own
+func (m *Manager) SetProjectDefaultsResolver(resolver func(projectPath string) ProjectDefaults) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.projectDefaultsResolver = resolver
+}
+
+// SetSystemPromptResolver sets the function that returns a project's system prompt addition
+func (m *Manager) SetSystemPromptResolver(resolver func(projectPath string) string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.systemPromptResolver = resolver
+}
+
+// SetStatusListener sets a function called on status changes of sessions created
+// afterwards. It may run while the session's lock is held, so it must not call
+// back into the session.
+func (m *Manager) SetStatusListener(listener func(session *Session, status SessionStatus)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.statusListener = listener
+}
+
+// SetCostListener sets a function called with a session's total cost each time
+// it grows, for sessions created afterwards. Like the status listener, it may
+// run while the session's lock is held.
+func (m *Manager) SetCostListener(listener func(session *Session, cost float64)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.costListener = listener
+}
+
 // SetConfigGetter sets the config getter for memory management settings
 func (m *Manager) SetConfigGetter(getter ConfigGetter) {
 	m.mu.Lock()
@@ -99,6 +248,7 @@ func (m *Manager) CreateSession(projectPath string) (*Session, error) {
 
 	sessionID := uuid.New().String()
 	session := NewSession(sessionID, projectPath)
+	session.Model = m.projectModelLocked(projectPath)
 
 	// Set up event handlers
 	m.setupSessionHandlers(session, sessionID)
@@ -113,9 +263,13 @@ func (m *Manager) CreateSession(projectPath string) (*Session, error) {
 		maxAgents := m.configGetter.GetMaxAgentsPerSession()
 		keepCompleted := m.configGetter.GetKeepCompletedAgents()
 		session.SetAgentCleanupSettings(maxAgents, keepCompleted)
+
+		// Set per-command runtime limit
+		session.SetCommandTimeout(time.Duration(m.configGetter.GetMaxCommandRuntimeSeconds()) * time.Second)
 	}
 
 	m.sessions[sessionID] = session
+	m.saveSession(session)
 	return session, nil
 }
 
@@ -126,6 +280,7 @@ func (m *Manager) CreateFirefighterSession(projectPath string, scope string) (*S
 
 	sessionID := uuid.New().String()
 	session := NewSession(sessionID, projectPath)
+	session.Model = m.projectModelLocked(projectPath)
 
 	session.Mode = "firefighter"
 	session.ModeConfig = map[string]interface{}{
@@ -146,9 +301,13 @@ func (m *Manager) CreateFirefighterSession(projectPath string, scope string) (*S
 		maxAgents := m.configGetter.GetMaxAgentsPerSession()
 		keepCompleted := m.configGetter.GetKeepCompletedAgents()
 		session.SetAgentCleanupSettings(maxAgents, keepCompleted)
+
+		// Set per-command runtime limit
+		session.SetCommandTimeout(time.Duration(m.configGetter.GetMaxCommandRuntimeSeconds()) * time.Second)
 	}
 
 	m.sessions[sessionID] = session
+	m.saveSession(session)
 	return session, nil
 }
 
@@ -181,39 +340,118 @@ func (m *Manager) CreateBoatmanModeSession(projectPath string, input string, mod
 		maxAgents := m.configGetter.GetMaxAgentsPerSession()
 		keepCompleted := m.configGetter.GetKeepCompletedAgents()
 		session.SetAgentCleanupSettings(maxAgents, keepCompleted)
+
+		// Set per-command runtime limit
+		session.SetCommandTimeout(time.Duration(m.configGetter.GetMaxCommandRuntimeSeconds()) * time.Second)
 	}
 
 	m.sessions[sessionID] = session
+	m.saveSession(session)
 	return session, nil
 }
 
 // setupSessionHandlers sets up event handlers for a session
 func (m *Manager) setupSessionHandlers(session *Session, sessionID string) {
+	session.SetToolFormatters(m.toolFormatters)
+
+	costListener := m.costListener
+
+	// Usage messages are emitted again, e.g. when a regenerate supersedes
+	// them, so each one's cost is only recorded the first time
+	var costMu sync.Mutex
+	costRecorded := make(map[string]bool)
+	firstCost := func(messageID string) bool {
+		costMu.Lock()
+		defer costMu.Unlock()
+		if costRecorded[messageID] {
+			return false
+		}
+		costRecorded[messageID] = true
+		return true
+	}
+
 	session.SetMessageHandler(func(msg Message) {
-		if m.ctx != nil {
-			runtime.EventsEmit(m.ctx, "agent:message", map[string]interface{}{
-				"sessionId": sessionID,
-				"message":   msg,
-			})
+		m.events().EmitMessage(sessionID, msg)
+		m.scheduleSave(sessionID)
+
+		if msg.Metadata != nil && msg.Metadata.CostInfo != nil && firstCost(msg.ID) {
+			m.recordCost(session, msg.Metadata.CostInfo.TotalCost, msg.Timestamp)
+			if costListener != nil {
+				costListener(session, m.costs.SessionCost(sessionID))
+			}
 		}
 	})
 
+	// Streamed text is sent as it's appended rather than re-sending the whole message
+	session.SetStreamBatchInterval(m.streamBatchInterval)
+	session.SetMessageDeltaHandler(func(messageID, delta string) {
+		m.events().Emit("agent:message-delta", map[string]interface{}{
+			"sessionId": sessionID,
+			"messageId": messageID,
+			"delta":     delta,
+		})
+	})
+
+	session.SetAgentMessageHandler(func(agentID string, msg Message) {
+		m.events().Emit("agent:subagent-message", map[string]interface{}{
+			"sessionId": sessionID,
+			"agentId":   agentID,
+			"message":   msg,
+		})
+		m.scheduleSave(sessionID)
+	})
+
 	session.SetTaskHandler(func(task Task) {
-		if m.ctx != nil {
-			runtime.EventsEmit(m.ctx, "agent:task", map[string]interface{}{
-				"sessionId": sessionID,
-				"task":      task,
-			})
-		}
+		m.events().EmitTask(sessionID, task)
+		m.scheduleSave(sessionID)
 	})
 
+	// Callers hold m.mu, so the listener is read here rather than in the handler
+	listener := m.statusListener
 	session.SetStatusHandler(func(status SessionStatus) {
-		if m.ctx != nil {
-			runtime.EventsEmit(m.ctx, "agent:status", map[string]interface{}{
-				"sessionId": sessionID,
-				"status":    status,
-			})
+		m.events().EmitStatus(sessionID, status)
+		m.attention.Update(sessionID, session.ProjectPath, status)
+
+		if listener != nil {
+			listener(session, status)
 		}
+		m.scheduleSave(sessionID)
+	})
+
+	session.SetCommandHandler(func(cmd RunningCommand) {
+		m.events().Emit("agent:command", map[string]interface{}{
+			"sessionId": sessionID,
+			"command":   cmd,
+		})
+	})
+
+	session.SetApprovalHandler(func(actions []PendingAction) {
+		m.events().Emit("agent:approval", map[string]interface{}{
+			"sessionId": sessionID,
+			"actions":   actions,
+		})
+	})
+
+	session.SetRateLimitHandler(func(info RateLimitInfo) {
+		m.events().Emit("agent:ratelimit", map[string]interface{}{
+			"sessionId": sessionID,
+			"rateLimit": info,
+		})
+	})
+
+	session.SetSnapshotHandler(func(snapshot *git.Snapshot) {
+		m.events().Emit("agent:snapshot", map[string]interface{}{
+			"sessionId": sessionID,
+			"snapshot":  snapshot,
+		})
+	})
+
+	session.SetRunScheduler(m.scheduler)
+	session.SetQueueHandler(func(position int) {
+		m.events().Emit("agent:queue", map[string]interface{}{
+			"sessionId": sessionID,
+			"position":  position,
+		})
 	})
 }
 
@@ -241,6 +479,86 @@ func (m *Manager) ListSessions() []*Session {
 	return sessions
 }
 
+// InvestigateAlert hands an alert received by webhook to the project's most
+// recent firefighter session, creating and starting one if there is none. An
+// idle session starts investigating at once, while a busy one only records
+// the alert on its incident timeline. It reports whether an investigation
+// started.
+func (m *Manager) InvestigateAlert(projectPath string, alert Alert) (*Session, bool, error) {
+	var session *Session
+	var busy bool
+	var latest time.Time
+	for _, candidate := range m.ListSessions() {
+		ok, candidateBusy, updatedAt := candidate.alertTarget(projectPath)
+		if ok && (session == nil || updatedAt.After(latest)) {
+			session, busy, latest = candidate, candidateBusy, updatedAt
+		}
+	}
+
+	if session == nil {
+		created, err := m.CreateFirefighterSession(projectPath, "")
+		if err != nil {
+			return nil, false, err
+		}
+		if err := m.StartSession(created.ID); err != nil {
+			return created, false, err
+		}
+		session = created
+	}
+
+	if busy {
+		session.RecordIncidentAlert(alert)
+		m.saveSession(session)
+		return session, false, nil
+	}
+
+	if err := m.costs.Check(session.ID); err != nil {
+		session.RecordIncidentAlert(alert)
+		m.saveSession(session)
+		return session, false, err
+	}
+	authConfig, err := m.authConfigFor(session)
+	if err != nil {
+		return session, false, err
+	}
+	if err := session.InvestigateAlert(alert, authConfig); err != nil {
+		return session, false, err
+	}
+	return session, true, nil
+}
+
+// StartFirefighterMonitoring starts monitoring for a firefighter session.
+// With rules and a fetcher the monitor polls the services itself and prompts
+// the agent only about the alerts the rules match; without them it prompts
+// the agent to check the services on every check.
+func (m *Manager) StartFirefighterMonitoring(sessionID string, rules MonitorRules, fetch AlertFetcher) error {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if session.IsFirefighterMonitoringActive() {
+		return fmt.Errorf("monitor already active")
+	}
+	for _, rule := range rules.Rules {
+		if err := ValidateMonitorRule(rule); err != nil {
+			return err
+		}
+	}
+
+	monitor, err := session.firefighterMonitorFor()
+	if err != nil {
+		return err
+	}
+	monitor.SetAuthConfigGetter(func() (AuthConfig, error) {
+		if err := m.costs.Check(session.ID); err != nil {
+			return AuthConfig{}, err
+		}
+		return m.authConfigFor(session)
+	})
+	monitor.SetRules(rules, fetch)
+	return session.StartFirefighterMonitoring()
+}
+
 // StartSession starts an agent session
 func (m *Manager) StartSession(sessionID string) error {
 	session, err := m.GetSession(sessionID)
@@ -249,10 +567,14 @@ func (m *Manager) StartSession(sessionID string) error {
 	}
 
 	m.mu.RLock()
-	model := m.defaultModel
+	model := m.projectModelLocked(session.ProjectPath)
 	configGetter := m.configGetter
 	m.mu.RUnlock()
 
+	if override := session.GetModelOverride(); override != "" {
+		model = override
+	}
+
 	// Update trim settings in case they changed
 	if configGetter != nil {
 		maxMessages := configGetter.GetMaxMessagesPerSession()
@@ -263,6 +585,9 @@ func (m *Manager) StartSession(sessionID string) error {
 		maxAgents := configGetter.GetMaxAgentsPerSession()
 		keepCompleted := configGetter.GetKeepCompletedAgents()
 		session.SetAgentCleanupSettings(maxAgents, keepCompleted)
+
+		// Update per-command runtime limit
+		session.SetCommandTimeout(time.Duration(configGetter.GetMaxCommandRuntimeSeconds()) * time.Second)
 	}
 
 	return session.Start(model)
@@ -289,25 +614,276 @@ func (m *Manager) DeleteSession(sessionID string) error {
 
 	session.Stop()
 	delete(m.sessions, sessionID)
+	m.attention.Remove(sessionID)
+
+	m.saveMu.Lock()
+	if timer, ok := m.pendingSaves[sessionID]; ok {
+		timer.Stop()
+		delete(m.pendingSaves, sessionID)
+	}
+	m.saveMu.Unlock()
+
+	if ActiveStore() != nil {
+		if err := DeleteSessionFile(sessionID); err != nil {
+			return fmt.Errorf("failed to delete stored session: %w", err)
+		}
+		if err := DeleteArchiveFile(sessionID); err != nil {
+			return fmt.Errorf("failed to delete stored archive: %w", err)
+		}
+	}
 	return nil
 }
 
+// saveSession writes a session to the active store. Without a store sessions
+// are only saved when explicitly asked, e.g. on tag changes.
+func (m *Manager) saveSession(session *Session) {
+	if ActiveStore() == nil {
+		return
+	}
+	if err := SaveSession(session); err != nil {
+		logger.Warn("Failed to save session", "session", session.ID, "error", err)
+	}
+}
+
+// scheduleSave saves a session shortly after it changes. Session handlers run
+// with the session locked, so the save can't happen inline.
+func (m *Manager) scheduleSave(sessionID string) {
+	if ActiveStore() == nil {
+		return
+	}
+
+	m.saveMu.Lock()
+	defer m.saveMu.Unlock()
+	if _, ok := m.pendingSaves[sessionID]; ok {
+		return
+	}
+	m.pendingSaves[sessionID] = time.AfterFunc(sessionSaveDelay, func() {
+		m.flushSave(sessionID)
+	})
+}
+
+// flushSave saves a session with a pending save, unless it was deleted since
+func (m *Manager) flushSave(sessionID string) {
+	m.saveMu.Lock()
+	delete(m.pendingSaves, sessionID)
+	m.saveMu.Unlock()
+
+	m.mu.RLock()
+	session, ok := m.sessions[sessionID]
+	m.mu.RUnlock()
+	if ok {
+		m.saveSession(session)
+	}
+}
+
+// FlushSaves writes every session with unsaved changes now, e.g. before the store closes
+func (m *Manager) FlushSaves() {
+	m.saveMu.Lock()
+	sessionIDs := make([]string, 0, len(m.pendingSaves))
+	for sessionID, timer := range m.pendingSaves {
+		timer.Stop()
+		sessionIDs = append(sessionIDs, sessionID)
+	}
+	m.saveMu.Unlock()
+
+	for _, sessionID := range sessionIDs {
+		m.flushSave(sessionID)
+	}
+}
+
+// authConfigFor builds the auth config used to launch claude for a session
+func (m *Manager) authConfigFor(session *Session) (AuthConfig, error) {
+	var authConfig AuthConfig
+	m.mu.RLock()
+	getter := m.authConfigGetter
+	resolver := m.mcpConfigResolver
+	promptResolver := m.systemPromptResolver
+	toolResolver := m.toolPolicyResolver
+	defaultsResolver := m.projectDefaultsResolver
+	memoryResolver := m.projectMemoryResolver
+	m.mu.RUnlock()
+
+	if getter != nil {
+		authConfig = getter()
+	}
+
+	// The session's approval mode wins over its project's, which wins over the user's
+	if mode := session.GetApprovalModeOverride(); mode != "" {
+		authConfig.ApprovalMode = mode
+	} else if defaultsResolver != nil {
+		if mode := defaultsResolver(session.ProjectPath).ApprovalMode; mode != "" {
+			authConfig.ApprovalMode = mode
+		}
+	}
+
+	// Only attach the MCP servers enabled for this session or its project
+	if resolver != nil {
+		path, err := resolver(session.ProjectPath, session.GetEnabledMCPServers())
+		if err != nil {
+			return authConfig, fmt.Errorf("failed to resolve MCP config: %w", err)
+		}
+		authConfig.MCPConfigPath = path
+	}
+
+	// The project's addition follows the user-level snippet, unless the
+	// session overrides both
+	if override := session.GetSystemPromptOverride(); override != "" {
+		authConfig.AppendSystemPrompt = override
+	} else if promptResolver != nil {
+		authConfig.AppendSystemPrompt = joinSystemPrompts(authConfig.AppendSystemPrompt, promptResolver(session.ProjectPath))
+	}
+
+	// The session's tool policy adds to its project's
+	var projectTools ToolPolicy
+	if toolResolver != nil {
+		projectTools = toolResolver(session.ProjectPath)
+	}
+
+	// The project's notes come last, with the tool that adds to them
+	if memoryResolver != nil {
+		memory := memoryResolver(session.ProjectPath)
+		authConfig.AppendSystemPrompt = joinSystemPrompts(authConfig.AppendSystemPrompt, memory.Prompt)
+		authConfig.BuiltinMCPConfig = memory.MCPConfig
+		if memory.Tool != "" {
+			projectTools.Allowed = appendTools(append([]string(nil), projectTools.Allowed...), []string{memory.Tool})
+		}
+	}
+	authConfig.ToolPolicy = mergeToolPolicies(projectTools, session.GetToolPolicy())
+
+	authConfig.PlanOnly = session.IsPlanMode()
+
+	// A session spanning several directories may change files in all of them
+	if additional := session.GetAdditionalPaths(); len(additional) > 0 {
+		authConfig.AdditionalDirs = additional
+		authConfig.GuardrailAllowedPaths = append(append([]string(nil), authConfig.GuardrailAllowedPaths...), additional...)
+	}
+
+	return authConfig, nil
+}
+
 // SendMessage sends a message to a session
 func (m *Manager) SendMessage(sessionID, content string) error {
+	return m.SendMessageWithAttachments(sessionID, content, nil, nil)
+}
+
+// SendMessageWithAttachments sends a message to a session with workspace
+// files and images attached
+func (m *Manager) SendMessageWithAttachments(sessionID, content string, attachments []string, images []ImageAttachment) error {
 	session, err := m.GetSession(sessionID)
 	if err != nil {
 		return err
 	}
 
-	// Get auth config
-	var authConfig AuthConfig
-	m.mu.RLock()
-	if m.authConfigGetter != nil {
-		authConfig = m.authConfigGetter()
+	if err := m.costs.Check(sessionID); err != nil {
+		return err
+	}
+
+	authConfig, err := m.authConfigFor(session)
+	if err != nil {
+		return err
+	}
+
+	return session.SendMessageWithAttachments(content, attachments, images, authConfig)
+}
+
+// RegenerateLastResponse re-runs the last user turn of a session
+func (m *Manager) RegenerateLastResponse(sessionID string, opts RegenerateOptions) error {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if err := m.costs.Check(sessionID); err != nil {
+		return err
+	}
+
+	authConfig, err := m.authConfigFor(session)
+	if err != nil {
+		return err
+	}
+
+	if err := session.RegenerateLast(authConfig, opts); err != nil {
+		return err
+	}
+
+	// The old response was removed rather than marked, which no message
+	// event says
+	if opts.Replace {
+		m.emitHistory(session)
+	}
+	return nil
+}
+
+// EditAndResendMessage rewinds a session to an earlier user message and resends it with new content
+func (m *Manager) EditAndResendMessage(sessionID, messageID, content string) error {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if err := m.costs.Check(sessionID); err != nil {
+		return err
+	}
+
+	authConfig, err := m.authConfigFor(session)
+	if err != nil {
+		return err
 	}
-	m.mu.RUnlock()
 
-	return session.SendMessage(content, authConfig)
+	if err := session.EditAndResend(messageID, content, authConfig); err != nil {
+		return err
+	}
+
+	m.emitHistory(session)
+	return nil
+}
+
+// emitHistory tells the frontend messages were removed from a session's
+// history, so it reloads the history a page at a time rather than being
+// sent all of it
+func (m *Manager) emitHistory(session *Session) {
+	_, total, err := session.GetMessagePage(0, 0)
+	if err != nil {
+		logger.Warn("Failed to count session messages", "session", session.ID, "error", err)
+	}
+	m.events().Emit("agent:history", map[string]interface{}{
+		"sessionId": session.ID,
+		"total":     total,
+	})
+}
+
+// EstimateMessage estimates the token usage and cost of sending content to a session
+func (m *Manager) EstimateMessage(sessionID, content string) (*MessageEstimate, error) {
+	return m.EstimateMessageWithAttachments(sessionID, content, nil, nil)
+}
+
+// EstimateMessageWithAttachments estimates the token usage and cost of sending
+// content to a session with workspace files and images attached, including
+// the system prompts appended for the session
+func (m *Manager) EstimateMessageWithAttachments(sessionID, content string, attachments []string, images []ImageAttachment) (*MessageEstimate, error) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	authConfig, err := m.authConfigFor(session)
+	if err != nil {
+		return nil, err
+	}
+	estimate, err := session.EstimateMessageWithAttachments(content, attachments, images, authConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &estimate, nil
+}
+
+// GetContextUsage reports how full a session's context window is
+func (m *Manager) GetContextUsage(sessionID string) (*ContextUsage, error) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	usage := session.GetContextUsage()
+	return &usage, nil
 }
 
 // ApproveAction approves a pending action
@@ -319,6 +895,15 @@ func (m *Manager) ApproveAction(sessionID, actionID string) error {
 	return session.Approve(actionID)
 }
 
+// GetPendingActions returns the tool calls in a session waiting for approval
+func (m *Manager) GetPendingActions(sessionID string) ([]PendingAction, error) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return session.PendingActions(), nil
+}
+
 // RejectAction rejects a pending action
 func (m *Manager) RejectAction(sessionID, actionID string) error {
 	session, err := m.GetSession(sessionID)
@@ -346,6 +931,52 @@ func (m *Manager) GetSessionTasks(sessionID string) ([]Task, error) {
 	return session.GetTasks(), nil
 }
 
+// GetSessionAgents returns a session's main agent and sub-agents
+func (m *Manager) GetSessionAgents(sessionID string) ([]AgentInfo, error) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return session.GetAgents(), nil
+}
+
+// GetAgentMessages returns a sub-agent's transcript
+func (m *Manager) GetAgentMessages(sessionID, agentID string) ([]Message, error) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return session.GetAgentMessages(agentID)
+}
+
+// GetRunningCommands returns a session's Bash commands that are still running
+func (m *Manager) GetRunningCommands(sessionID string) ([]RunningCommand, error) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return session.GetRunningCommands(), nil
+}
+
+// CancelRun aborts the prompt a session is processing and returns it to idle
+func (m *Manager) CancelRun(sessionID string) error {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	return session.CancelCurrentRun()
+}
+
+// KillRunningCommands kills a session's current run and reports its running
+// commands, to the user now and to claude with the next prompt
+func (m *Manager) KillRunningCommands(sessionID string) error {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	return session.KillRunningCommands()
+}
+
 // StopAllSessions stops all running sessions
 func (m *Manager) StopAllSessions() {
 	m.mu.Lock()
@@ -411,3 +1042,48 @@ func (m *Manager) SetFavorite(sessionID string, favorite bool) error {
 	session.SetFavorite(favorite)
 	return SaveSession(session)
 }
+
+// RecordResolutionStep adds a resolution step to a session's incident timeline
+func (m *Manager) RecordResolutionStep(sessionID, step string) error {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if err := session.RecordResolutionStep(step); err != nil {
+		return err
+	}
+	return SaveSession(session)
+}
+
+// ResolveIncident marks a session's incident resolved
+func (m *Manager) ResolveIncident(sessionID, summary string) error {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if err := session.ResolveIncident(summary); err != nil {
+		return err
+	}
+	return SaveSession(session)
+}
+
+// SendTemplatedMessage resolves {{variable}} placeholders in a prompt template and sends the result.
+// Session-derived variables are filled in automatically; vars supplies or overrides the rest.
+func (m *Manager) SendTemplatedMessage(sessionID, template string, vars map[string]string) error {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	resolved := session.TemplateContext()
+	for k, v := range vars {
+		resolved[k] = v
+	}
+
+	content, err := RenderTemplate(template, resolved)
+	if err != nil {
+		return err
+	}
+
+	return m.SendMessage(sessionID, content)
+}
@@ -2,6 +2,9 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
@@ -118,6 +121,265 @@ func TestSetAPIKeyGetter(t *testing.T) {
 	}
 }
 
+// TestAuthConfigForMCPResolver tests that the MCP config is resolved per project and session
+func TestAuthConfigForMCPResolver(t *testing.T) {
+	m := NewManager()
+	m.SetAuthConfigGetter(func() AuthConfig {
+		return AuthConfig{Method: "anthropic-api", APIKey: "key"}
+	})
+
+	restricted := NewSession("s1", "/restricted")
+	open := NewSession("s2", "/open")
+
+	// Without a resolver the default MCP config is used
+	config, err := m.authConfigFor(restricted)
+	if err != nil {
+		t.Fatalf("authConfigFor failed: %v", err)
+	}
+	if config.MCPConfigPath != "" {
+		t.Errorf("expected empty MCP config path, got %s", config.MCPConfigPath)
+	}
+
+	m.SetMCPConfigResolver(func(projectPath string, sessionServers []string) (string, error) {
+		if sessionServers != nil {
+			return "/tmp/session.json", nil
+		}
+		if projectPath == "/restricted" {
+			return "/tmp/restricted.json", nil
+		}
+		return "", nil
+	})
+
+	config, err = m.authConfigFor(restricted)
+	if err != nil {
+		t.Fatalf("authConfigFor failed: %v", err)
+	}
+	if config.MCPConfigPath != "/tmp/restricted.json" {
+		t.Errorf("expected MCP config path /tmp/restricted.json, got %s", config.MCPConfigPath)
+	}
+	if config.APIKey != "key" {
+		t.Errorf("expected API key to be preserved, got %s", config.APIKey)
+	}
+
+	config, err = m.authConfigFor(open)
+	if err != nil {
+		t.Fatalf("authConfigFor failed: %v", err)
+	}
+	if config.MCPConfigPath != "" {
+		t.Errorf("expected empty MCP config path, got %s", config.MCPConfigPath)
+	}
+
+	// A session's own selection overrides its project's, even when empty
+	open.SetEnabledMCPServers([]string{})
+	config, err = m.authConfigFor(open)
+	if err != nil {
+		t.Fatalf("authConfigFor failed: %v", err)
+	}
+	if config.MCPConfigPath != "/tmp/session.json" {
+		t.Errorf("expected MCP config path /tmp/session.json, got %s", config.MCPConfigPath)
+	}
+
+	// Selections survive saving and loading; nil stays distinct from empty
+	reload := func(session *Session) []string {
+		raw, err := json.Marshal(newSessionData(session))
+		if err != nil {
+			t.Fatalf("failed to marshal session: %v", err)
+		}
+		var data SessionData
+		if err := json.Unmarshal(raw, &data); err != nil {
+			t.Fatalf("failed to unmarshal session: %v", err)
+		}
+		return sessionFromData(data).GetEnabledMCPServers()
+	}
+	if got := reload(open); got == nil || len(got) != 0 {
+		t.Errorf("expected an empty server list after loading, got %v", got)
+	}
+	if got := reload(restricted); got != nil {
+		t.Errorf("expected no session selection after loading, got %v", got)
+	}
+
+	m.SetMCPConfigResolver(func(projectPath string, sessionServers []string) (string, error) {
+		return "", errors.New("disk full")
+	})
+	if _, err := m.authConfigFor(open); err == nil {
+		t.Error("expected resolver error to be returned")
+	}
+}
+
+// TestAuthConfigForSystemPrompt tests that project system prompts follow the user-level one
+func TestAuthConfigForSystemPrompt(t *testing.T) {
+	m := NewManager()
+	m.SetAuthConfigGetter(func() AuthConfig {
+		return AuthConfig{AppendSystemPrompt: "Use British spelling."}
+	})
+	m.SetSystemPromptResolver(func(projectPath string) string {
+		if projectPath == "/go" {
+			return "  Run gofmt before finishing.\n"
+		}
+		return ""
+	})
+
+	tests := []struct {
+		projectPath string
+		expected    string
+	}{
+		{"/go", "Use British spelling.\n\nRun gofmt before finishing."},
+		{"/other", "Use British spelling."},
+	}
+
+	for _, tt := range tests {
+		config, err := m.authConfigFor(NewSession("s", tt.projectPath))
+		if err != nil {
+			t.Fatalf("authConfigFor failed: %v", err)
+		}
+		if config.AppendSystemPrompt != tt.expected {
+			t.Errorf("expected system prompt %q, got %q", tt.expected, config.AppendSystemPrompt)
+		}
+	}
+}
+
+// TestAuthConfigForSystemPromptOverride tests that a session's own prompt replaces the user and project ones
+func TestAuthConfigForSystemPromptOverride(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m := NewManager()
+	m.SetAuthConfigGetter(func() AuthConfig {
+		return AuthConfig{AppendSystemPrompt: "Use British spelling."}
+	})
+	m.SetSystemPromptResolver(func(projectPath string) string {
+		return "Run gofmt before finishing."
+	})
+
+	session, err := m.CreateSession("/go")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	if err := m.SetSessionSystemPrompt(session.ID, "  Only review, never edit.\n"); err != nil {
+		t.Fatalf("SetSessionSystemPrompt failed: %v", err)
+	}
+	config, err := m.authConfigFor(session)
+	if err != nil {
+		t.Fatalf("authConfigFor failed: %v", err)
+	}
+	if config.AppendSystemPrompt != "Only review, never edit." {
+		t.Errorf("expected the session's prompt alone, got %q", config.AppendSystemPrompt)
+	}
+
+	// Clearing the override goes back to the user and project prompts
+	if err := m.SetSessionSystemPrompt(session.ID, ""); err != nil {
+		t.Fatalf("SetSessionSystemPrompt failed: %v", err)
+	}
+	config, _ = m.authConfigFor(session)
+	if expected := "Use British spelling.\n\nRun gofmt before finishing."; config.AppendSystemPrompt != expected {
+		t.Errorf("expected system prompt %q, got %q", expected, config.AppendSystemPrompt)
+	}
+}
+
+// TestAuthConfigForProjectMemory tests that a project's notes and memory tool reach its sessions
+func TestAuthConfigForProjectMemory(t *testing.T) {
+	m := NewManager()
+	m.SetAuthConfigGetter(func() AuthConfig {
+		return AuthConfig{AppendSystemPrompt: "Use British spelling."}
+	})
+	m.SetToolPolicyResolver(func(projectPath string) ToolPolicy {
+		return ToolPolicy{Allowed: []string{"Read"}}
+	})
+	m.SetProjectMemoryResolver(func(projectPath string) ProjectMemory {
+		return ProjectMemory{Prompt: "Project memory:\n- Uses Go modules.", MCPConfig: `{"mcpServers":{}}`, Tool: "mcp__memory__remember"}
+	})
+
+	config, err := m.authConfigFor(NewSession("s", "/go"))
+	if err != nil {
+		t.Fatalf("authConfigFor failed: %v", err)
+	}
+	if expected := "Use British spelling.\n\nProject memory:\n- Uses Go modules."; config.AppendSystemPrompt != expected {
+		t.Errorf("expected system prompt %q, got %q", expected, config.AppendSystemPrompt)
+	}
+	if config.BuiltinMCPConfig != `{"mcpServers":{}}` {
+		t.Errorf("expected the memory server config, got %q", config.BuiltinMCPConfig)
+	}
+	if !reflect.DeepEqual(config.ToolPolicy.Allowed, []string{"Read", "mcp__memory__remember"}) {
+		t.Errorf("expected the memory tool allowed, got %v", config.ToolPolicy.Allowed)
+	}
+}
+
+// TestStatusListener tests that the listener sees status changes, including
+// those made while the manager's lock is held
+func TestStatusListener(t *testing.T) {
+	m := NewManager()
+
+	var seen []SessionStatus
+	m.SetStatusListener(func(session *Session, status SessionStatus) {
+		seen = append(seen, status)
+	})
+
+	session, err := m.CreateSession("/test/project")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- m.DeleteSession(session.ID) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("DeleteSession failed: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("DeleteSession deadlocked calling the status listener")
+	}
+
+	if len(seen) != 1 || seen[0] != SessionStatusStopped {
+		t.Errorf("Expected listener to see [stopped], got %v", seen)
+	}
+}
+
+func TestManagerPersistsSessions(t *testing.T) {
+	useTestStore(t)
+
+	m := NewManager()
+	session, err := m.CreateSession("/test/project")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	if _, err := LoadSession(session.ID); err != nil {
+		t.Fatalf("Expected the new session to be saved: %v", err)
+	}
+
+	session.mu.Lock()
+	session.conversationID = "conv-1"
+	session.mu.Unlock()
+	session.addAssistantMessage("Hello")
+	m.FlushSaves()
+
+	// A fresh manager picks up where the old one left off
+	restored := NewManager()
+	count, err := restored.RestoreSessions()
+	if err != nil {
+		t.Fatalf("RestoreSessions failed: %v", err)
+	}
+	if count != 1 {
+		t.Fatalf("Expected 1 restored session, got %d", count)
+	}
+	got, err := restored.GetSession(session.ID)
+	if err != nil {
+		t.Fatalf("Expected the session to be restored: %v", err)
+	}
+	if got.conversationID != "conv-1" {
+		t.Errorf("Expected conversation ID conv-1, got %q", got.conversationID)
+	}
+	if messages := got.GetMessages(); len(messages) != 1 || messages[0].Content != "Hello" {
+		t.Errorf("Expected the message to be restored, got %v", messages)
+	}
+
+	if err := restored.DeleteSession(session.ID); err != nil {
+		t.Fatalf("DeleteSession failed: %v", err)
+	}
+	restored.FlushSaves()
+	if _, err := LoadSession(session.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Expected the deleted session to be removed from the store, got %v", err)
+	}
+}
+
 // TestCreateSession tests creating new sessions
 func TestCreateSession(t *testing.T) {
 	tests := []struct {
@@ -473,9 +735,9 @@ func TestApproveAction(t *testing.T) {
 		}
 
 		err = m.ApproveAction(session.ID, "action-123")
-		// Expected to fail as approval is not supported in current implementation
+		// Expected to fail as nothing is waiting for approval
 		if err == nil {
-			t.Error("expected error as approval is not supported")
+			t.Error("expected error for an action that isn't pending")
 		}
 	})
 }
@@ -499,9 +761,9 @@ func TestRejectAction(t *testing.T) {
 		}
 
 		err = m.RejectAction(session.ID, "action-123")
-		// Expected to fail as rejection is not supported in current implementation
+		// Expected to fail as nothing is waiting for approval
 		if err == nil {
-			t.Error("expected error as rejection is not supported")
+			t.Error("expected error for an action that isn't pending")
 		}
 	})
 }
@@ -1180,3 +1442,26 @@ func TestThreadSafety(t *testing.T) {
 		t.Errorf("expected 0 sessions after deletion, got %d", len(sessions))
 	}
 }
+
+// TestInvestigateAlert_BusySession tests that an alert for a project whose
+// firefighter session is mid-run is only added to its incident timeline
+func TestInvestigateAlert_BusySession(t *testing.T) {
+	m := NewManager()
+	other, _ := m.CreateFirefighterSession("/work/other", "")
+	session, _ := m.CreateFirefighterSession("/work/payments/", "")
+	session.Status = SessionStatusRunning
+
+	got, investigating, err := m.InvestigateAlert("/work/payments", Alert{Source: "datadog", Title: "Checkout 5xx spike"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != session || investigating {
+		t.Errorf("expected the busy payments session without an investigation, got %v, %v", got.ProjectPath, investigating)
+	}
+	if incident := session.GetIncident(); incident == nil || incident.Title != "Checkout 5xx spike" {
+		t.Errorf("expected the alert on the incident timeline, got %+v", incident)
+	}
+	if other.GetIncident() != nil || len(m.ListSessions()) != 2 {
+		t.Error("expected no other session to be touched or created")
+	}
+}
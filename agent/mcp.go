@@ -0,0 +1,36 @@
+package agent
+
+import "time"
+
+// SetEnabledMCPServers sets the MCP servers attached to the session's next
+// runs. nil goes back to the project's selection; an empty list attaches none.
+func (s *Session) SetEnabledMCPServers(servers []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if servers != nil {
+		servers = append([]string{}, servers...)
+	}
+	s.EnabledMCPServers = servers
+	s.UpdatedAt = time.Now()
+}
+
+// GetEnabledMCPServers returns the MCP servers chosen for the session, or nil
+// when it uses the project's selection
+func (s *Session) GetEnabledMCPServers() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.EnabledMCPServers == nil {
+		return nil
+	}
+	return append([]string{}, s.EnabledMCPServers...)
+}
+
+// SetSessionMCPServers sets the MCP servers attached to a session's runs (nil uses the project's)
+func (m *Manager) SetSessionMCPServers(sessionID string, servers []string) error {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	session.SetEnabledMCPServers(servers)
+	return SaveSession(session)
+}
@@ -0,0 +1,326 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// archivePageSize is how many archived messages each page file holds, so
+// archiving more messages only rewrites the last page
+const archivePageSize = 200
+
+// archivePagesDir is where a session's archived messages are kept as page
+// files, next to the archive file holding its metadata
+func archivePagesDir(archivesDir, sessionID string) string {
+	return filepath.Join(archivesDir, sessionID)
+}
+
+// archivePagePath returns the file holding a page of a session's archive
+func archivePagePath(archivesDir, sessionID string, page int) string {
+	return filepath.Join(archivePagesDir(archivesDir, sessionID), fmt.Sprintf("%06d.json", page))
+}
+
+// readArchiveHead reads the file recording a session's archive metadata.
+// Archives written before paging keep their messages in it too. A session
+// without an archive yields an empty one.
+func readArchiveHead(archivesDir, sessionID string) (*ArchiveData, error) {
+	data, err := os.ReadFile(filepath.Join(archivesDir, sessionID+".json"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &ArchiveData{SessionID: sessionID}, nil
+		}
+		return nil, fmt.Errorf("failed to read archive file: %w", err)
+	}
+	archive, err := parseArchive(sessionID, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal archive: %w", err)
+	}
+	return archive, nil
+}
+
+// readArchivePage reads one page of a session's archived messages
+func readArchivePage(archivesDir, sessionID string, page int) ([]Message, error) {
+	data, err := os.ReadFile(archivePagePath(archivesDir, sessionID, page))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive page: %w", err)
+	}
+	var messages []Message
+	if err := unmarshalSealed(data, &messages); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal archive page: %w", err)
+	}
+	return messages, nil
+}
+
+// appendArchivePages adds messages to a session's paged archive in timestamp
+// order, usually rewriting only its last page. An archive written before
+// paging has its messages moved into pages first.
+func appendArchivePages(archivesDir string, meta ArchiveData, messages []Message) error {
+	head, err := readArchiveHead(archivesDir, meta.SessionID)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal existing archive: %w", err)
+	}
+
+	// Keep the latest known metadata
+	if meta.ProjectPath != "" {
+		head.ProjectPath = meta.ProjectPath
+	}
+	if meta.Tags != nil {
+		head.Tags = meta.Tags
+	}
+
+	start := head.Count
+	existing := head.Messages
+	if len(existing) == 0 && start > 0 && len(messages) > 0 {
+		// Archived messages newer than those added, like a branch rewound
+		// before the messages leading up to it were trimmed, go after them,
+		// so read back to the page where they start
+		count := start
+		for page := (count - 1) / archivePageSize; page >= 0; page-- {
+			pageMessages, err := readArchivePage(archivesDir, meta.SessionID, page)
+			if err != nil {
+				return err
+			}
+			// Messages past the count are from a write that didn't finish
+			pageMessages = pageMessages[:min(len(pageMessages), count-page*archivePageSize)]
+			existing = append(pageMessages, existing...)
+			start = page * archivePageSize
+			if len(pageMessages) == 0 || !pageMessages[0].Timestamp.After(messages[0].Timestamp) {
+				break
+			}
+		}
+
+		// Whole pages older than everything added stay as they are
+		kept := 0
+		for kept < len(existing) && !existing[kept].Timestamp.After(messages[0].Timestamp) {
+			kept++
+		}
+		skip := kept - kept%archivePageSize
+		existing = existing[skip:]
+		start += skip
+	}
+	pending := mergeByTime(existing, messages)
+
+	if err := os.MkdirAll(archivePagesDir(archivesDir, meta.SessionID), 0755); err != nil {
+		return fmt.Errorf("failed to create archive pages directory: %w", err)
+	}
+	for i := 0; i < len(pending); i += archivePageSize {
+		page := pending[i:min(i+archivePageSize, len(pending))]
+		jsonData, err := marshalSealed(page, false)
+		if err != nil {
+			return fmt.Errorf("failed to marshal archive page: %w", err)
+		}
+		if err := os.WriteFile(archivePagePath(archivesDir, meta.SessionID, (start+i)/archivePageSize), jsonData, 0644); err != nil {
+			return fmt.Errorf("failed to write archive page: %w", err)
+		}
+	}
+
+	// The pages are written before the count that covers them
+	head.Count = start + len(pending)
+	head.Messages = nil
+	jsonData, err := marshalSealed(head, true)
+	if err != nil {
+		return fmt.Errorf("failed to marshal archive: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(archivesDir, meta.SessionID+".json"), jsonData, 0644); err != nil {
+		return fmt.Errorf("failed to write archive file: %w", err)
+	}
+	return nil
+}
+
+// mergeByTime merges two runs of messages each in timestamp order, putting
+// a's first where timestamps tie
+func mergeByTime(a, b []Message) []Message {
+	merged := make([]Message, 0, len(a)+len(b))
+	for len(a) > 0 && len(b) > 0 {
+		if b[0].Timestamp.Before(a[0].Timestamp) {
+			merged = append(merged, b[0])
+			b = b[1:]
+		} else {
+			merged = append(merged, a[0])
+			a = a[1:]
+		}
+	}
+	merged = append(merged, a...)
+	return append(merged, b...)
+}
+
+// loadArchivePages reads a session's whole archive from its files
+func loadArchivePages(archivesDir, sessionID string) (*ArchiveData, error) {
+	archive, err := readArchiveHead(archivesDir, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if archive.Messages, _, err = readArchiveRange(archivesDir, archive, 0, -1); err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// loadArchivePageFiles returns up to limit archived messages from offset
+// and how many are archived in all
+func loadArchivePageFiles(archivesDir, sessionID string, offset, limit int) ([]Message, int, error) {
+	head, err := readArchiveHead(archivesDir, sessionID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return readArchiveRange(archivesDir, head, offset, limit)
+}
+
+// readArchiveRange returns up to limit of an archive's messages from offset,
+// reading only the pages they're on, and how many it holds in all. A
+// negative limit reads to the end.
+func readArchiveRange(archivesDir string, head *ArchiveData, offset, limit int) ([]Message, int, error) {
+	total := head.Count
+	if len(head.Messages) > 0 {
+		total = len(head.Messages)
+	}
+	end := total
+	if limit >= 0 {
+		end = min(offset+limit, total)
+	}
+	if offset >= end {
+		return []Message{}, total, nil
+	}
+	if len(head.Messages) > 0 {
+		return head.Messages[offset:end], total, nil
+	}
+
+	messages := make([]Message, 0, end-offset)
+	for page := offset / archivePageSize; page*archivePageSize < end; page++ {
+		pageMessages, err := readArchivePage(archivesDir, head.SessionID, page)
+		if err != nil {
+			return nil, 0, err
+		}
+		first := page * archivePageSize
+		from := max(offset-first, 0)
+		to := min(end-first, len(pageMessages))
+		if from < to {
+			messages = append(messages, pageMessages[from:to]...)
+		}
+	}
+	return messages, total, nil
+}
+
+// archivePageFiles lists the page files of a session's archive in order
+func archivePageFiles(archivesDir, sessionID string) []string {
+	dir := archivePagesDir(archivesDir, sessionID)
+	var files []string
+	for _, id := range jsonFileIDs(dir) {
+		files = append(files, filepath.Join(dir, id+".json"))
+	}
+	return files
+}
+
+// GetMessagePage returns up to limit messages of the session's whole
+// history, skipping the offset newest, oldest first, and how many there are
+// in all. Offset 0 is the live tail. Messages trimmed or rewound out of the
+// session are read from the pages of its archive, which is merged with the
+// window by timestamp; only the messages asked for are copied.
+func (s *Session) GetMessagePage(offset, limit int) ([]Message, int, error) {
+	offset, limit = max(offset, 0), max(limit, 0)
+
+	// The archive is read without holding the lock, so start over if
+	// messages left the window meanwhile
+	for attempt := 1; ; attempt++ {
+		tail, windowLen, version := s.historyTail(offset + limit)
+		messages, total, err := pageHistory(s.ID, tail, windowLen, offset, limit)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to load archived messages: %w", err)
+		}
+
+		s.mu.RLock()
+		changed := s.historyVersion != version
+		s.mu.RUnlock()
+		if !changed || attempt == 3 {
+			return messages, total, nil
+		}
+	}
+}
+
+// historyTail copies up to the n newest messages in the session's window,
+// and the window's first message if it's not among them, returning them
+// with the window's length and history version
+func (s *Session) historyTail(n int) ([]Message, int, int) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n = min(n, len(s.Messages))
+	tail := make([]Message, 0, n+1)
+	if n < len(s.Messages) {
+		tail = append(tail, s.Messages[0])
+	}
+	tail = append(tail, s.Messages[len(s.Messages)-n:]...)
+	return tail, len(s.Messages), s.historyVersion
+}
+
+// pageHistory returns a page of a session's history from its archive and
+// tail, the newest messages of its window as returned by historyTail
+func pageHistory(sessionID string, tail []Message, windowLen, offset, limit int) ([]Message, int, error) {
+	_, archived, err := LoadArchivePage(sessionID, 0, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	total := archived + windowLen
+	end := total - offset
+	start := max(end-limit, 0)
+	if end <= 0 || start >= end {
+		return []Message{}, total, nil
+	}
+
+	// Archived messages newer than the window's first, like a rewound
+	// branch, are interleaved with the window; those before it come first
+	plain := archived
+	if windowLen > 0 {
+		first := tail[0].Timestamp
+		// Usually none are, which the last archived message shows
+		for size := 1; plain > 0; size = archivePageSize {
+			chunk, _, err := LoadArchivePage(sessionID, max(plain-size, 0), min(plain, size))
+			if err != nil {
+				return nil, 0, err
+			}
+			newer := 0
+			for newer < len(chunk) && chunk[len(chunk)-1-newer].Timestamp.After(first) {
+				newer++
+			}
+			plain -= newer
+			if newer < len(chunk) {
+				break
+			}
+		}
+	}
+
+	messages := []Message{}
+	if start < plain {
+		messages, _, err = LoadArchivePage(sessionID, start, min(end, plain)-start)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	if end <= plain {
+		return messages, total, nil
+	}
+
+	// The last n messages of the history are the last n of the newer
+	// archived messages and the window merged
+	from := max(start, plain)
+	n := total - from
+	newer, _, err := LoadArchivePage(sessionID, max(archived-n, plain), n)
+	if err != nil {
+		return nil, 0, err
+	}
+	window := tail[max(len(tail)-min(n, windowLen), 0):]
+	merged := mergeByTime(newer, window)
+	merged = merged[len(merged)-n:]
+	return append(messages, merged[:end-from]...), total, nil
+}
+
+// GetSessionMessagePage returns a page of a session's history, archived
+// messages included
+func (m *Manager) GetSessionMessagePage(sessionID string, offset, limit int) ([]Message, int, error) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return session.GetMessagePage(offset, limit)
+}
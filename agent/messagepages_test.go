@@ -0,0 +1,265 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// numberedMessages returns count messages whose content is their position from start
+func numberedMessages(start, count int) []Message {
+	messages := make([]Message, count)
+	for i := range messages {
+		messages[i] = Message{ID: fmt.Sprintf("msg-%d", start+i), Role: "user", Content: fmt.Sprint(start + i)}
+	}
+	return messages
+}
+
+// timedMessages returns numbered messages sent a second apart in order
+func timedMessages(start, count int) []Message {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	messages := numberedMessages(start, count)
+	for i := range messages {
+		messages[i].Timestamp = base.Add(time.Duration(start+i) * time.Second)
+	}
+	return messages
+}
+
+// checkNumbered fails unless messages are numbered from start in order
+func checkNumbered(t *testing.T, messages []Message, start, count int) {
+	t.Helper()
+	if len(messages) != count {
+		t.Fatalf("Expected %d messages, got %d", count, len(messages))
+	}
+	for i, msg := range messages {
+		if want := fmt.Sprint(start + i); msg.Content != want {
+			t.Fatalf("Expected message %d to be %s, got %s", i, want, msg.Content)
+		}
+	}
+}
+
+func TestArchivePages(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	meta := ArchiveData{SessionID: "paged", ProjectPath: "/tmp/project"}
+	archived := 0
+	for _, count := range []int{1, 150, 120, 179} {
+		if err := ArchiveSessionMessages(meta, numberedMessages(archived, count)); err != nil {
+			t.Fatalf("Failed to archive messages: %v", err)
+		}
+		archived += count
+	}
+
+	archivesDir, err := GetArchivesDir()
+	if err != nil {
+		t.Fatalf("Failed to get archives dir: %v", err)
+	}
+	if pages := archivePageFiles(archivesDir, "paged"); len(pages) != 3 {
+		t.Errorf("Expected 450 messages in 3 pages, got %d", len(pages))
+	}
+
+	archive, err := LoadArchive("paged")
+	if err != nil {
+		t.Fatalf("Failed to load archive: %v", err)
+	}
+	checkNumbered(t, archive.Messages, 0, archived)
+	if archive.ProjectPath != "/tmp/project" {
+		t.Errorf("Expected metadata to be kept, got %q", archive.ProjectPath)
+	}
+
+	// A page spanning two page files
+	messages, total, err := LoadArchivePage("paged", 190, 20)
+	if err != nil {
+		t.Fatalf("Failed to load archive page: %v", err)
+	}
+	if total != archived {
+		t.Errorf("Expected total %d, got %d", archived, total)
+	}
+	checkNumbered(t, messages, 190, 20)
+
+	// The last page is cut short, and past the end is empty
+	messages, _, err = LoadArchivePage("paged", 440, 50)
+	if err != nil {
+		t.Fatalf("Failed to load archive page: %v", err)
+	}
+	checkNumbered(t, messages, 440, 10)
+	if messages, _, _ = LoadArchivePage("paged", 500, 50); len(messages) != 0 {
+		t.Errorf("Expected no messages past the end, got %d", len(messages))
+	}
+
+	if count, err := GetArchivedMessageCount("paged"); err != nil || count != archived {
+		t.Errorf("Expected %d archived messages, got %d (%v)", archived, count, err)
+	}
+
+	if err := DeleteArchiveFile("paged"); err != nil {
+		t.Fatalf("Failed to delete archive: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(archivesDir, "paged")); !os.IsNotExist(err) {
+		t.Errorf("Expected archive pages to be deleted, got %v", err)
+	}
+}
+
+func TestArchivePagesLegacy(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	archivesDir, err := GetArchivesDir()
+	if err != nil {
+		t.Fatalf("Failed to get archives dir: %v", err)
+	}
+	legacy := `{"sessionId": "legacy", "messages": [{"id": "msg-0", "role": "user", "content": "0"}, {"id": "msg-1", "role": "user", "content": "1"}]}`
+	if err := os.WriteFile(filepath.Join(archivesDir, "legacy.json"), []byte(legacy), 0644); err != nil {
+		t.Fatalf("Failed to write legacy archive: %v", err)
+	}
+
+	messages, total, err := LoadArchivePage("legacy", 1, 5)
+	if err != nil {
+		t.Fatalf("Failed to load legacy archive page: %v", err)
+	}
+	if total != 2 {
+		t.Errorf("Expected total 2, got %d", total)
+	}
+	checkNumbered(t, messages, 1, 1)
+
+	// Appending moves the legacy messages into pages
+	if err := ArchiveMessages("legacy", numberedMessages(2, 1)); err != nil {
+		t.Fatalf("Failed to append to legacy archive: %v", err)
+	}
+	head, err := readArchiveHead(archivesDir, "legacy")
+	if err != nil {
+		t.Fatalf("Failed to read archive file: %v", err)
+	}
+	if len(head.Messages) != 0 || head.Count != 3 {
+		t.Errorf("Expected the archive file to count 3 paged messages, got %d inline and count %d", len(head.Messages), head.Count)
+	}
+	archive, err := LoadArchive("legacy")
+	if err != nil {
+		t.Fatalf("Failed to load archive: %v", err)
+	}
+	checkNumbered(t, archive.Messages, 0, 3)
+}
+
+func TestSessionGetMessagePage(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	session := NewSession("paged-session", "/tmp/project")
+	session.Messages = numberedMessages(0, 30)
+	if err := session.TrimMessagesIfNeeded(10, true); err != nil {
+		t.Fatalf("Failed to trim messages: %v", err)
+	}
+	checkNumbered(t, session.Messages, 20, 10)
+
+	tests := []struct {
+		name          string
+		offset, limit int
+		start, count  int
+	}{
+		{"live tail", 0, 5, 25, 5},
+		{"window only", 0, 10, 20, 10},
+		{"across the archive and window", 5, 10, 15, 10},
+		{"archived only", 15, 10, 5, 10},
+		{"oldest, cut short", 25, 10, 0, 5},
+		{"past the end", 40, 10, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, total, err := session.GetMessagePage(tt.offset, tt.limit)
+			if err != nil {
+				t.Fatalf("Failed to get message page: %v", err)
+			}
+			if total != 30 {
+				t.Errorf("Expected total 30, got %d", total)
+			}
+			checkNumbered(t, messages, tt.start, tt.count)
+		})
+	}
+}
+
+func TestSessionGetMessagePageRewound(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	// Messages 5-9 were rewound into the archive, and 10-11 sent since
+	session := NewSession("rewound-session", "/tmp/project")
+	session.Messages = append(timedMessages(0, 5), timedMessages(10, 2)...)
+	if err := ArchiveSessionMessages(session.archiveMetadata(), timedMessages(5, 5)); err != nil {
+		t.Fatalf("Failed to archive messages: %v", err)
+	}
+
+	messages, total, err := session.GetMessagePage(0, 100)
+	if err != nil {
+		t.Fatalf("Failed to get message page: %v", err)
+	}
+	if total != 12 {
+		t.Errorf("Expected total 12, got %d", total)
+	}
+	checkNumbered(t, messages, 0, 12)
+	if messages, _, _ = session.GetMessagePage(0, 3); len(messages) == 3 {
+		checkNumbered(t, messages, 9, 3)
+	} else {
+		t.Errorf("Expected the live tail to hold 3 messages, got %d", len(messages))
+	}
+
+	// Trimming archives 0-3 ahead of the rewound branch
+	if err := session.TrimMessagesIfNeeded(3, true); err != nil {
+		t.Fatalf("Failed to trim messages: %v", err)
+	}
+	archived, _, err := LoadArchivePage(session.ID, 0, 100)
+	if err != nil {
+		t.Fatalf("Failed to load archive page: %v", err)
+	}
+	checkNumbered(t, archived[:4], 0, 4)
+	checkNumbered(t, archived[4:], 5, 5)
+
+	messages, _, err = session.GetMessagePage(4, 4)
+	if err != nil {
+		t.Fatalf("Failed to get message page: %v", err)
+	}
+	checkNumbered(t, messages, 4, 4)
+	messages, _, _ = session.GetMessagePage(0, 100)
+	checkNumbered(t, messages, 0, 12)
+}
+
+func TestBoltStoreArchiveOrder(t *testing.T) {
+	useTestStore(t)
+
+	if err := ArchiveMessages("bolt-ordered", timedMessages(5, 5)); err != nil {
+		t.Fatalf("Failed to archive messages: %v", err)
+	}
+	if err := ArchiveMessages("bolt-ordered", timedMessages(0, 5)); err != nil {
+		t.Fatalf("Failed to archive messages: %v", err)
+	}
+
+	messages, total, err := LoadArchivePage("bolt-ordered", 0, 20)
+	if err != nil {
+		t.Fatalf("Failed to load archive page: %v", err)
+	}
+	if total != 10 {
+		t.Errorf("Expected total 10, got %d", total)
+	}
+	checkNumbered(t, messages, 0, 10)
+}
+
+func TestBoltStoreArchivePage(t *testing.T) {
+	useTestStore(t)
+
+	if err := ArchiveMessages("bolt-paged", numberedMessages(0, 25)); err != nil {
+		t.Fatalf("Failed to archive messages: %v", err)
+	}
+	if err := ArchiveMessages("bolt-paged", numberedMessages(25, 5)); err != nil {
+		t.Fatalf("Failed to archive messages: %v", err)
+	}
+
+	messages, total, err := LoadArchivePage("bolt-paged", 20, 8)
+	if err != nil {
+		t.Fatalf("Failed to load archive page: %v", err)
+	}
+	if total != 30 {
+		t.Errorf("Expected total 30, got %d", total)
+	}
+	checkNumbered(t, messages, 20, 8)
+
+	if messages, total, _ = LoadArchivePage("missing", 0, 10); len(messages) != 0 || total != 0 {
+		t.Errorf("Expected an empty page for a session without an archive, got %d of %d", len(messages), total)
+	}
+}
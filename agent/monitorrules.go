@@ -0,0 +1,153 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// defaultRuleCooldown is how long a rule stays quiet after firing when it
+// doesn't set its own cooldown
+const defaultRuleCooldown = 15 * time.Minute
+
+// MonitorRule picks the alerts a firefighter monitor prompts the agent about
+type MonitorRule struct {
+	Name string `json:"name"`
+	// Source is the service the rule watches, "datadog" or "bugsnag"; empty
+	// matches alerts from any service
+	Source string `json:"source,omitempty"`
+	// Tags must all be on an alert for it to match, e.g. "service:checkout"
+	Tags []string `json:"tags,omitempty"`
+	// MinSeverity is the lowest severity that matches, e.g. "error" or "P2";
+	// empty matches any severity
+	MinSeverity string `json:"minSeverity,omitempty"`
+	// Cooldown keeps the rule from firing again this soon after it fired, so
+	// an alert storm becomes one prompt. Zero uses the default.
+	Cooldown time.Duration `json:"cooldown,omitempty"`
+}
+
+// MonitorRules configures rule-based firefighter monitoring
+type MonitorRules struct {
+	Interval time.Duration // How often to poll; zero keeps the monitor's interval
+	Rules    []MonitorRule
+}
+
+// AlertFetcher returns the alerts firing now in the monitoring services. It
+// may return alerts along with an error when only some services failed.
+type AlertFetcher func(ctx context.Context) ([]Alert, error)
+
+// ruleMatch is a rule that fired and the new alerts it matched
+type ruleMatch struct {
+	Rule   MonitorRule
+	Alerts []Alert
+}
+
+// severityRanks orders the severity names used by Bugsnag, Datadog and
+// PagerDuty; higher is more severe
+var severityRanks = map[string]int{
+	"info": 1, "low": 1, "p5": 1,
+	"warning": 2, "warn": 2, "medium": 2, "p4": 2, "p3": 2,
+	"error": 3, "high": 3, "alert": 3, "p2": 3,
+	"critical": 4, "urgent": 4, "p1": 4,
+}
+
+// severityRank ranks a severity name, or returns 0 if it isn't known
+func severityRank(severity string) int {
+	return severityRanks[strings.ToLower(strings.TrimSpace(severity))]
+}
+
+// ValidateMonitorRule checks a rule's source and severity are ones the
+// monitor understands
+func ValidateMonitorRule(rule MonitorRule) error {
+	switch rule.Source {
+	case "", "datadog", "bugsnag":
+	default:
+		return fmt.Errorf("rule %q: unknown source %q", rule.Name, rule.Source)
+	}
+	if rule.MinSeverity != "" && severityRank(rule.MinSeverity) == 0 {
+		return fmt.Errorf("rule %q: unknown severity %q", rule.Name, rule.MinSeverity)
+	}
+	if rule.Cooldown < 0 {
+		return fmt.Errorf("rule %q: cooldown can't be negative", rule.Name)
+	}
+	return nil
+}
+
+// matches reports whether an alert satisfies the rule
+func (r MonitorRule) matches(alert Alert) bool {
+	if r.Source != "" && !strings.EqualFold(r.Source, alert.Source) {
+		return false
+	}
+	if r.MinSeverity != "" && severityRank(alert.Severity) < severityRank(r.MinSeverity) {
+		return false
+	}
+	for _, want := range r.Tags {
+		found := false
+		for _, tag := range alert.Tags {
+			if strings.EqualFold(tag, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// key identifies the rule for cooldown tracking
+func (r MonitorRule) key(index int) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return fmt.Sprintf("rule-%d", index)
+}
+
+// cooldown returns the rule's cooldown, or the default
+func (r MonitorRule) cooldown() time.Duration {
+	if r.Cooldown > 0 {
+		return r.Cooldown
+	}
+	return defaultRuleCooldown
+}
+
+// alertKey identifies an alert across checks
+func alertKey(alert Alert) string {
+	return alert.Source + "/" + alert.ID
+}
+
+// evaluateRules matches the firing alerts not yet prompted about against the
+// rules, in order; each alert goes to the first rule it matches. Rules in
+// their cooldown don't fire, and their alerts stay unprompted so they are
+// reported once the cooldown ends if still firing. lastFired is updated for
+// the rules that fire.
+func evaluateRules(rules []MonitorRule, alerts []Alert, prompted map[string]bool, lastFired map[string]time.Time, now time.Time) []ruleMatch {
+	matched := make([][]Alert, len(rules))
+	for _, alert := range alerts {
+		if prompted[alertKey(alert)] {
+			continue
+		}
+		for i, rule := range rules {
+			if rule.matches(alert) {
+				matched[i] = append(matched[i], alert)
+				break
+			}
+		}
+	}
+
+	var fired []ruleMatch
+	for i, rule := range rules {
+		if len(matched[i]) == 0 {
+			continue
+		}
+		key := rule.key(i)
+		if last, ok := lastFired[key]; ok && now.Sub(last) < rule.cooldown() {
+			continue
+		}
+		lastFired[key] = now
+		fired = append(fired, ruleMatch{Rule: rule, Alerts: matched[i]})
+	}
+	return fired
+}
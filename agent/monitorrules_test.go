@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvaluateRules(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	checkout := Alert{Source: "datadog", ID: "1", Severity: "P1", Tags: []string{"env:prod", "service:checkout"}}
+	search := Alert{Source: "datadog", ID: "2", Severity: "warning", Tags: []string{"env:prod", "service:search"}}
+	bugsnagError := Alert{Source: "bugsnag", ID: "e1", Severity: "error"}
+	info := Alert{Source: "bugsnag", ID: "e2", Severity: "info"}
+
+	tests := []struct {
+		name      string
+		rules     []MonitorRule
+		alerts    []Alert
+		prompted  map[string]bool
+		lastFired map[string]time.Time
+		expected  map[string][]string // Rule name to the IDs of its alerts
+	}{
+		{
+			name:     "tag filter",
+			rules:    []MonitorRule{{Name: "checkout", Source: "datadog", Tags: []string{"service:checkout"}}},
+			alerts:   []Alert{checkout, search},
+			expected: map[string][]string{"checkout": {"1"}},
+		},
+		{
+			name:     "severity threshold",
+			rules:    []MonitorRule{{Name: "errors", Source: "bugsnag", MinSeverity: "error"}},
+			alerts:   []Alert{bugsnagError, info},
+			expected: map[string][]string{"errors": {"e1"}},
+		},
+		{
+			name:     "priority threshold",
+			rules:    []MonitorRule{{Name: "urgent", MinSeverity: "P2"}},
+			alerts:   []Alert{checkout, search},
+			expected: map[string][]string{"urgent": {"1"}},
+		},
+		{
+			name: "first matching rule wins",
+			rules: []MonitorRule{
+				{Name: "checkout", Tags: []string{"service:checkout"}},
+				{Name: "prod", Tags: []string{"env:prod"}},
+			},
+			alerts:   []Alert{checkout, search},
+			expected: map[string][]string{"checkout": {"1"}, "prod": {"2"}},
+		},
+		{
+			name:     "already prompted",
+			rules:    []MonitorRule{{Name: "prod", Tags: []string{"env:prod"}}},
+			alerts:   []Alert{checkout, search},
+			prompted: map[string]bool{"datadog/1": true},
+			expected: map[string][]string{"prod": {"2"}},
+		},
+		{
+			name:      "in cooldown",
+			rules:     []MonitorRule{{Name: "prod", Tags: []string{"env:prod"}}},
+			alerts:    []Alert{checkout},
+			lastFired: map[string]time.Time{"prod": now.Add(-time.Minute)},
+			expected:  map[string][]string{},
+		},
+		{
+			name:      "cooldown over",
+			rules:     []MonitorRule{{Name: "prod", Tags: []string{"env:prod"}, Cooldown: time.Minute}},
+			alerts:    []Alert{checkout},
+			lastFired: map[string]time.Time{"prod": now.Add(-time.Minute)},
+			expected:  map[string][]string{"prod": {"1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompted := tt.prompted
+			if prompted == nil {
+				prompted = map[string]bool{}
+			}
+			lastFired := tt.lastFired
+			if lastFired == nil {
+				lastFired = map[string]time.Time{}
+			}
+
+			matches := evaluateRules(tt.rules, tt.alerts, prompted, lastFired, now)
+			if len(matches) != len(tt.expected) {
+				t.Fatalf("Expected %d rules to fire, got %d: %+v", len(tt.expected), len(matches), matches)
+			}
+			for _, match := range matches {
+				ids := tt.expected[match.Rule.Name]
+				if len(match.Alerts) != len(ids) {
+					t.Fatalf("Expected rule %q to match %v, got %+v", match.Rule.Name, ids, match.Alerts)
+				}
+				for i, alert := range match.Alerts {
+					if alert.ID != ids[i] {
+						t.Errorf("Expected rule %q to match %v, got %+v", match.Rule.Name, ids, match.Alerts)
+					}
+				}
+				if !lastFired[match.Rule.Name].Equal(now) {
+					t.Errorf("Expected rule %q's last fire to be recorded", match.Rule.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateMonitorRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    MonitorRule
+		wantErr bool
+	}{
+		{name: "valid", rule: MonitorRule{Name: "a", Source: "datadog", MinSeverity: "P2"}},
+		{name: "any source", rule: MonitorRule{Name: "a"}},
+		{name: "unknown source", rule: MonitorRule{Name: "a", Source: "sentry"}, wantErr: true},
+		{name: "unknown severity", rule: MonitorRule{Name: "a", MinSeverity: "scary"}, wantErr: true},
+		{name: "negative cooldown", rule: MonitorRule{Name: "a", Cooldown: -time.Second}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMonitorRule(tt.rule)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error=%v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
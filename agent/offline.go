@@ -0,0 +1,200 @@
+package agent
+
+import (
+	"context"
+	"net"
+	"strings"
+	"time"
+)
+
+// Reconnect backoff while prompts are queued offline
+var (
+	reconnectInitialDelay = 2 * time.Second
+	reconnectMaxDelay     = 30 * time.Second
+)
+
+// networkErrorMarkers identify CLI failures caused by lost connectivity rather than the request
+var networkErrorMarkers = []string{
+	"connection error",
+	"enotfound",
+	"econnrefused",
+	"econnreset",
+	"etimedout",
+	"eai_again",
+	"getaddrinfo",
+	"network is unreachable",
+	"fetch failed",
+	"socket hang up",
+	"unable to connect",
+}
+
+// isNetworkError reports whether CLI output describes a network-level failure
+func isNetworkError(text string) bool {
+	lower := strings.ToLower(text)
+	for _, marker := range networkErrorMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// queuedPrompt is a prompt held back until the network is reachable again
+type queuedPrompt struct {
+	messageID  string
+	prompt     string
+	authConfig AuthConfig
+	images     []promptImage
+}
+
+// defaultConnectivityCheck dials the API endpoint used by the configured auth method
+func defaultConnectivityCheck(authConfig AuthConfig) error {
+	host := "api.anthropic.com:443"
+	if authConfig.Method == "google-cloud" {
+		host = "aiplatform.googleapis.com:443"
+		if authConfig.GCPRegion != "" && authConfig.GCPRegion != "global" {
+			host = authConfig.GCPRegion + "-aiplatform.googleapis.com:443"
+		}
+	}
+
+	conn, err := net.DialTimeout("tcp", host, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// sendPrompt runs a prompt, queueing it instead if the network is down
+func (s *Session) sendPrompt(messageID, prompt string, authConfig AuthConfig, images ...promptImage) {
+	item := queuedPrompt{messageID: messageID, prompt: prompt, authConfig: authConfig, images: images}
+
+	// Keep prompts in order behind anything already waiting
+	s.mu.RLock()
+	offline := len(s.offlineQueue) > 0
+	s.mu.RUnlock()
+	if offline {
+		s.queueOffline(item)
+		return
+	}
+
+	if s.runWithRateLimitRetry(prompt, authConfig, images...) == runNetworkFailed {
+		s.queueOffline(item)
+	}
+}
+
+// queueOffline holds a prompt until connectivity returns, marking its message
+// as queued and starting the reconnect loop if it isn't already running
+func (s *Session) queueOffline(item queuedPrompt) {
+	s.mu.Lock()
+	s.offlineQueue = append(s.offlineQueue, item)
+	msg, found := s.setQueuedLocked(item.messageID, true)
+	start := !s.reconnecting
+	s.reconnecting = true
+	s.setStatus(SessionStatusOffline)
+	handler := s.onMessage
+	ctx := s.ctx
+	s.mu.Unlock()
+
+	if found && handler != nil {
+		handler(msg)
+	}
+	if start {
+		go s.retryWhenOnline(ctx)
+	}
+}
+
+// setQueuedLocked updates a message's queued flag. Caller must hold s.mu.
+func (s *Session) setQueuedLocked(messageID string, queued bool) (Message, bool) {
+	if messageID == "" {
+		return Message{}, false
+	}
+	for i := range s.Messages {
+		if s.Messages[i].ID != messageID {
+			continue
+		}
+		if s.Messages[i].Metadata == nil {
+			if !queued {
+				return s.Messages[i], true
+			}
+			s.Messages[i].Metadata = &MessageMetadata{}
+		}
+		s.Messages[i].Metadata.Queued = queued
+		return s.Messages[i], true
+	}
+	return Message{}, false
+}
+
+// retryWhenOnline waits for connectivity with backoff, then delivers queued
+// prompts in order. A prompt that fails on the network again goes back to the
+// front of the queue.
+func (s *Session) retryWhenOnline(ctx context.Context) {
+	delay := reconnectInitialDelay
+	for {
+		select {
+		case <-ctx.Done():
+			s.mu.Lock()
+			s.reconnecting = false
+			s.mu.Unlock()
+			return
+		case <-time.After(delay):
+		}
+
+		s.mu.Lock()
+		if len(s.offlineQueue) == 0 {
+			s.reconnecting = false
+			if s.Status == SessionStatusOffline {
+				s.setStatus(SessionStatusIdle)
+			}
+			s.mu.Unlock()
+			return
+		}
+		item := s.offlineQueue[0]
+		check := s.connectivityCheck
+		s.mu.Unlock()
+
+		if check == nil {
+			check = defaultConnectivityCheck
+		}
+		if err := check(item.authConfig); err != nil {
+			delay *= 2
+			if delay > reconnectMaxDelay {
+				delay = reconnectMaxDelay
+			}
+			continue
+		}
+
+		// Back online: deliver the next prompt
+		s.mu.Lock()
+		s.offlineQueue = s.offlineQueue[1:]
+		msg, found := s.setQueuedLocked(item.messageID, false)
+		s.setStatus(SessionStatusRunning)
+		handler := s.onMessage
+		s.mu.Unlock()
+
+		if found && handler != nil {
+			handler(msg)
+		}
+
+		if s.runWithRateLimitRetry(item.prompt, item.authConfig, item.images...) == runNetworkFailed {
+			s.mu.Lock()
+			s.offlineQueue = append([]queuedPrompt{item}, s.offlineQueue...)
+			msg, found = s.setQueuedLocked(item.messageID, true)
+			s.setStatus(SessionStatusOffline)
+			s.mu.Unlock()
+
+			if found && handler != nil {
+				handler(msg)
+			}
+			delay = reconnectInitialDelay
+			continue
+		}
+		delay = 0
+	}
+}
+
+// QueuedMessageCount returns how many prompts are waiting for connectivity
+func (s *Session) QueuedMessageCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.offlineQueue)
+}
@@ -0,0 +1,132 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsNetworkError(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected bool
+	}{
+		{"API Error: Connection error.", true},
+		{"getaddrinfo ENOTFOUND api.anthropic.com", true},
+		{"connect ECONNREFUSED 127.0.0.1:443", true},
+		{"TypeError: fetch failed", true},
+		{"API Error: 401 invalid x-api-key", false},
+		{"Prompt is too long", false},
+	}
+
+	for _, tt := range tests {
+		if got := isNetworkError(tt.text); got != tt.expected {
+			t.Errorf("isNetworkError(%q): expected %v, got %v", tt.text, tt.expected, got)
+		}
+	}
+}
+
+func TestParseStreamLineNetworkFailure(t *testing.T) {
+	session := NewSession("test-offline-parse", "/tmp/test")
+
+	var responseBuilder strings.Builder
+	var currentMessageID string
+	session.parseStreamLine(`{"type":"result","is_error":true,"result":"API Error: Connection error."}`, &responseBuilder, &currentMessageID)
+
+	if !session.networkFailure {
+		t.Error("Expected network failure to be recorded")
+	}
+	if len(session.GetMessages()) != 0 {
+		t.Errorf("Expected the connection error not to be shown as a response, got %d messages", len(session.GetMessages()))
+	}
+
+	// Once the run has produced output, errors are shown as usual
+	session = NewSession("test-offline-partial", "/tmp/test")
+	session.runHasOutput = true
+	session.parseStreamLine(`{"type":"result","is_error":true,"result":"API Error: Connection error."}`, &responseBuilder, &currentMessageID)
+
+	if session.networkFailure {
+		t.Error("Expected no retry after partial output")
+	}
+	if len(session.GetMessages()) != 1 {
+		t.Errorf("Expected the error to be shown, got %d messages", len(session.GetMessages()))
+	}
+}
+
+func TestQueueOffline(t *testing.T) {
+	oldDelay := reconnectInitialDelay
+	reconnectInitialDelay = 10 * time.Millisecond
+	defer func() { reconnectInitialDelay = oldDelay }()
+
+	session := NewSession("test-offline-queue", "/tmp/test")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	session.ctx = ctx
+
+	checks := make(chan struct{}, 10)
+	session.connectivityCheck = func(AuthConfig) error {
+		select {
+		case checks <- struct{}{}:
+		default:
+		}
+		return errors.New("network is unreachable")
+	}
+
+	session.Messages = append(session.Messages, Message{ID: "msg-1", Role: "user", Content: "hello"})
+
+	var emitted []Message
+	session.SetMessageHandler(func(msg Message) {
+		emitted = append(emitted, msg)
+	})
+
+	session.queueOffline(queuedPrompt{messageID: "msg-1", prompt: "hello"})
+
+	if session.Status != SessionStatusOffline {
+		t.Errorf("Expected status offline, got %s", session.Status)
+	}
+	if session.QueuedMessageCount() != 1 {
+		t.Errorf("Expected 1 queued prompt, got %d", session.QueuedMessageCount())
+	}
+	messages := session.GetMessages()
+	if messages[0].Metadata == nil || !messages[0].Metadata.Queued {
+		t.Error("Expected message to be marked queued")
+	}
+	if len(emitted) != 1 || emitted[0].Metadata == nil || !emitted[0].Metadata.Queued {
+		t.Errorf("Expected the queued message to be emitted, got %+v", emitted)
+	}
+
+	// Later prompts wait behind the queue without running
+	session.Messages = append(session.Messages, Message{ID: "msg-2", Role: "user", Content: "again"})
+	session.sendPrompt("msg-2", "again", AuthConfig{})
+	if session.QueuedMessageCount() != 2 {
+		t.Errorf("Expected 2 queued prompts, got %d", session.QueuedMessageCount())
+	}
+
+	// The reconnect loop keeps checking while offline
+	select {
+	case <-checks:
+	case <-time.After(time.Second):
+		t.Fatal("Expected connectivity to be checked")
+	}
+
+	// Stopping the session ends the reconnect loop
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for {
+		session.mu.RLock()
+		reconnecting := session.reconnecting
+		session.mu.RUnlock()
+		if !reconnecting {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected reconnect loop to stop")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if session.QueuedMessageCount() != 2 {
+		t.Errorf("Expected queued prompts to be kept, got %d", session.QueuedMessageCount())
+	}
+}
@@ -0,0 +1,101 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"boatman/stream"
+)
+
+// promptTimeout bounds a prompt run outside a session
+const promptTimeout = 2 * time.Minute
+
+// RunPrompt runs a single prompt in dir outside any session and returns
+// claude's reply. The run gets one turn and no tools, so it only answers
+// from the prompt.
+func (m *Manager) RunPrompt(ctx context.Context, dir, prompt string) (string, error) {
+	m.mu.RLock()
+	getter := m.authConfigGetter
+	model := m.defaultModel
+	runner := m.runner
+	m.mu.RUnlock()
+
+	var authConfig AuthConfig
+	if getter != nil {
+		authConfig = getter()
+	}
+	if runner == nil {
+		runner = ExecRunner{}
+	}
+
+	cliPath, err := ResolveClaudeCLI(authConfig.ClaudeCLIPath)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, promptTimeout)
+	defer cancel()
+
+	args := []string{"-p", "--output-format", "json", "--max-turns", "1"}
+	if model != "" {
+		args = append(args, "--model", model)
+	}
+	proc, err := runner.Start(ctx, ProcessSpec{
+		Path: cliPath,
+		Args: args,
+		Dir:  dir,
+		Env:  claudeEnv(authConfig),
+	})
+	if err != nil {
+		return "", classifyStartError(err)
+	}
+
+	stdin := proc.Stdin()
+	_, writeErr := io.WriteString(stdin, prompt)
+	stdin.Close()
+
+	// Drain stderr alongside stdout so a chatty process can't block on a full pipe
+	stderrDone := make(chan string, 1)
+	go func() {
+		output, _ := io.ReadAll(proc.Stderr())
+		stderrDone <- string(output)
+	}()
+	output, _ := io.ReadAll(proc.Stdout())
+	stderr := <-stderrDone
+	waitErr := proc.Wait()
+
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("claude did not answer within %v", promptTimeout)
+	}
+	return promptReply(output, stderr, waitErr, writeErr)
+}
+
+// promptReply extracts the reply from the JSON result of a prompt run, or
+// describes why the run failed
+func promptReply(output []byte, stderr string, waitErr, writeErr error) (string, error) {
+	var result stream.Result
+	parseErr := json.Unmarshal(output, &result)
+
+	exitCode, failed := exitStatus(waitErr)
+	if parseErr == nil && result.IsError {
+		return "", classifyRunFailure(result.Result.Text, exitCode)
+	}
+	if failed {
+		detail := strings.TrimSpace(stderr)
+		if detail == "" {
+			detail = strings.TrimSpace(string(output))
+		}
+		return "", classifyRunFailure(detail, exitCode)
+	}
+	if writeErr != nil {
+		return "", fmt.Errorf("failed to send prompt: %w", writeErr)
+	}
+	if parseErr != nil {
+		return "", fmt.Errorf("failed to parse claude output: %w", parseErr)
+	}
+	return strings.TrimSpace(result.Result.Text), nil
+}
@@ -1,12 +1,15 @@
 package agent
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"time"
+
+	"boatman/git"
 )
 
 // SessionData represents the persistable data of a session
@@ -22,8 +25,43 @@ type SessionData struct {
 	ConversationID string                `json:"conversationId"`
 	CurrentAgentID string                `json:"currentAgentId"`
 	Agents         map[string]*AgentInfo `json:"agents"`
+	AgentMessages  map[string][]Message  `json:"agentMessages,omitempty"`
 	Tags           []string              `json:"tags,omitempty"`
 	IsFavorite     bool                  `json:"isFavorite,omitempty"`
+
+	// Mode and its settings, e.g. a firefighter session's scope
+	Mode       string                 `json:"mode,omitempty"`
+	ModeConfig map[string]interface{} `json:"modeConfig,omitempty"`
+
+	// Workspace snapshotting before full-auto runs
+	SnapshotBeforeRun bool          `json:"snapshotBeforeRun,omitempty"`
+	Snapshot          *git.Snapshot `json:"snapshot,omitempty"`
+
+	// MCP servers chosen for the session; nil, not empty, uses the project's
+	EnabledMCPServers []string `json:"enabledMcpServers"`
+
+	// Plan mode, and the turn whose plan can be applied
+	PlanMode      bool   `json:"planMode,omitempty"`
+	PlanMessageID string `json:"planMessageId,omitempty"`
+
+	// Tools allowed or denied on top of the project's policy
+	ToolPolicy ToolPolicy `json:"toolPolicy"`
+
+	// Directories the session spans besides its project
+	AdditionalPaths []string `json:"additionalPaths,omitempty"`
+
+	// System prompt addition used instead of the user's and project's
+	SystemPromptOverride string `json:"systemPromptOverride,omitempty"`
+
+	// Model and approval mode used instead of the project's and user's
+	ModelOverride        string `json:"modelOverride,omitempty"`
+	ApprovalModeOverride string `json:"approvalModeOverride,omitempty"`
+
+	// Files changed by each turn, so they can be reviewed or reverted
+	ChangeSets []ChangeSet `json:"changeSets,omitempty"`
+
+	// Timeline of a firefighter session's incident
+	Incident *Incident `json:"incident,omitempty"`
 }
 
 // SessionsDirGetter is a function type for getting sessions directory (for testing)
@@ -53,50 +91,100 @@ func getSessionsDir() (string, error) {
 	return sessionsDir, nil
 }
 
-// SaveSession persists a session to disk
+// SaveSession persists a session to the active store, or to disk without one
 func SaveSession(session *Session) error {
+	data := newSessionData(session)
+	if err := saveSessionData(data); err != nil {
+		return err
+	}
+
+	// Keep content search current with the saved messages
+	indexSessionMessages(data.ID, data.Messages, false)
+	return nil
+}
+
+// saveSessionData writes a session's persistable form
+func saveSessionData(data SessionData) error {
+	if store := ActiveStore(); store != nil {
+		return store.SaveSession(data)
+	}
+
 	sessionsDir, err := GetSessionsDir()
 	if err != nil {
 		return fmt.Errorf("failed to get sessions directory: %w", err)
 	}
 
+	// Marshal to JSON, sealed when sessions are encrypted
+	jsonData, err := marshalSealed(data, true)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	// Write to file
+	filename := filepath.Join(sessionsDir, data.ID+".json")
+	if err := os.WriteFile(filename, jsonData, 0644); err != nil {
+		return fmt.Errorf("failed to write session file: %w", err)
+	}
+
+	return nil
+}
+
+// newSessionData copies a session into its persistable format
+func newSessionData(session *Session) SessionData {
 	session.mu.RLock()
 	defer session.mu.RUnlock()
 
-	// Convert to persistable format
-	data := SessionData{
+	return SessionData{
 		ID:             session.ID,
 		ProjectPath:    session.ProjectPath,
 		Status:         session.Status,
-		Messages:       session.Messages,
-		Tasks:          session.Tasks,
+		Messages:       append([]Message{}, session.Messages...),
+		Tasks:          append([]Task{}, session.Tasks...),
 		CreatedAt:      session.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		UpdatedAt:      session.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		Model:          session.Model,
 		ConversationID: session.conversationID,
 		CurrentAgentID: session.currentAgentID,
 		Agents:         session.agents,
+		AgentMessages:  copyAgentMessages(session.agentMessages),
 		Tags:           session.Tags,
 		IsFavorite:     session.IsFavorite,
-	}
+		Mode:           session.Mode,
+		ModeConfig:     session.ModeConfig,
 
-	// Marshal to JSON
-	jsonData, err := json.MarshalIndent(data, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal session: %w", err)
-	}
+		SnapshotBeforeRun: session.SnapshotBeforeRun,
+		Snapshot:          session.snapshot,
 
-	// Write to file
-	filename := filepath.Join(sessionsDir, session.ID+".json")
-	if err := os.WriteFile(filename, jsonData, 0644); err != nil {
-		return fmt.Errorf("failed to write session file: %w", err)
-	}
+		EnabledMCPServers: session.EnabledMCPServers,
 
-	return nil
+		PlanMode:      session.PlanMode,
+		PlanMessageID: session.planMessageID,
+
+		ToolPolicy: session.ToolPolicy,
+
+		AdditionalPaths: session.AdditionalPaths,
+
+		SystemPromptOverride: session.SystemPromptOverride,
+
+		ModelOverride:        session.ModelOverride,
+		ApprovalModeOverride: session.ApprovalModeOverride,
+
+		ChangeSets: append([]ChangeSet(nil), session.changeSets...),
+
+		Incident: session.incident.clone(),
+	}
 }
 
-// LoadSession loads a session from disk
+// LoadSession loads a session from the active store, or from disk without one
 func LoadSession(sessionID string) (*Session, error) {
+	if store := ActiveStore(); store != nil {
+		data, err := store.LoadSession(sessionID)
+		if err != nil {
+			return nil, err
+		}
+		return sessionFromData(*data), nil
+	}
+
 	sessionsDir, err := GetSessionsDir()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get sessions directory: %w", err)
@@ -109,11 +197,15 @@ func LoadSession(sessionID string) (*Session, error) {
 	}
 
 	var data SessionData
-	if err := json.Unmarshal(jsonData, &data); err != nil {
+	if err := unmarshalSealed(jsonData, &data); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal session: %w", err)
 	}
 
-	// Create session from persisted data
+	return sessionFromData(data), nil
+}
+
+// sessionFromData rebuilds a usable session from persisted data
+func sessionFromData(data SessionData) *Session {
 	session := &Session{
 		ID:             data.ID,
 		ProjectPath:    data.ProjectPath,
@@ -124,10 +216,36 @@ func LoadSession(sessionID string) (*Session, error) {
 		conversationID: data.ConversationID,
 		currentAgentID: data.CurrentAgentID,
 		agents:         data.Agents,
+		agentMessages:  data.AgentMessages,
 		Tags:           data.Tags,
 		IsFavorite:     data.IsFavorite,
+		Mode:           data.Mode,
+		ModeConfig:     data.ModeConfig,
+
+		SnapshotBeforeRun: data.SnapshotBeforeRun,
+		snapshot:          data.Snapshot,
+
+		EnabledMCPServers: data.EnabledMCPServers,
+
+		PlanMode:      data.PlanMode,
+		planMessageID: data.PlanMessageID,
+
+		ToolPolicy: data.ToolPolicy,
+
+		AdditionalPaths: data.AdditionalPaths,
+
+		SystemPromptOverride: data.SystemPromptOverride,
+
+		ModelOverride:        data.ModelOverride,
+		ApprovalModeOverride: data.ApprovalModeOverride,
+
+		changeSets: data.ChangeSets,
+
+		incident: data.Incident,
 	}
 
+	session.contextTracker = contextTrackerFromMessages(session.Messages, session.Model)
+
 	// Initialize tags if nil
 	if session.Tags == nil {
 		session.Tags = []string{}
@@ -165,11 +283,29 @@ func LoadSession(sessionID string) (*Session, error) {
 		session.Status = SessionStatusIdle
 	}
 
-	return session, nil
+	// A session saved mid-run has no process behind it anymore
+	switch session.Status {
+	case SessionStatusRunning, SessionStatusWaiting, SessionStatusOffline, SessionStatusRateLimited, SessionStatusQueued:
+		session.Status = SessionStatusIdle
+	}
+
+	return session
 }
 
 // LoadAllSessions loads all persisted sessions
 func LoadAllSessions() ([]*Session, error) {
+	if store := ActiveStore(); store != nil {
+		stored, err := store.LoadSessions()
+		if err != nil {
+			return nil, err
+		}
+		sessions := make([]*Session, 0, len(stored))
+		for _, data := range stored {
+			sessions = append(sessions, sessionFromData(data))
+		}
+		return sessions, nil
+	}
+
 	sessionsDir, err := GetSessionsDir()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get sessions directory: %w", err)
@@ -193,7 +329,7 @@ func LoadAllSessions() ([]*Session, error) {
 		sessionID := entry.Name()[:len(entry.Name())-5] // Remove .json extension
 		session, err := LoadSession(sessionID)
 		if err != nil {
-			fmt.Printf("Warning: failed to load session %s: %v\n", sessionID, err)
+			logger.Warn("Failed to load session", "session", sessionID, "error", err)
 			continue
 		}
 
@@ -203,8 +339,13 @@ func LoadAllSessions() ([]*Session, error) {
 	return sessions, nil
 }
 
-// DeleteSessionFile removes a session file from disk
+// DeleteSessionFile removes a persisted session
 func DeleteSessionFile(sessionID string) error {
+	unindexSession(sessionID)
+	if store := ActiveStore(); store != nil {
+		return store.DeleteSession(sessionID)
+	}
+
 	sessionsDir, err := GetSessionsDir()
 	if err != nil {
 		return fmt.Errorf("failed to get sessions directory: %w", err)
@@ -239,69 +380,128 @@ func GetArchivesDir() (string, error) {
 	return archivesDir, nil
 }
 
+// ArchiveData is the on-disk format of a session's archived messages. It
+// records the session metadata at archive time so archived usage can still be
+// attributed (e.g. to tags) later.
+type ArchiveData struct {
+	SessionID   string    `json:"sessionId"`
+	ProjectPath string    `json:"projectPath,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	Messages    []Message `json:"messages"`
+	// Count is how many messages the archive's page files hold; archives
+	// written before they were paged keep their messages in the file itself
+	Count int `json:"count,omitempty"`
+}
+
 // ArchiveMessages appends messages to an archive file for a session
 func ArchiveMessages(sessionID string, messages []Message) error {
+	return ArchiveSessionMessages(ArchiveData{SessionID: sessionID}, messages)
+}
+
+// ArchiveSessionMessages appends messages to a session's archive file and
+// records the given session metadata alongside them
+func ArchiveSessionMessages(meta ArchiveData, messages []Message) error {
 	if len(messages) == 0 {
 		return nil
 	}
+	if err := appendArchive(meta, messages); err != nil {
+		return err
+	}
+
+	// Archived messages stay searchable
+	indexSessionMessages(meta.SessionID, messages, true)
+	return nil
+}
+
+// appendArchive writes messages to the end of a session's archive
+func appendArchive(meta ArchiveData, messages []Message) error {
+	if store := ActiveStore(); store != nil {
+		return store.ArchiveMessages(meta, messages)
+	}
 
 	archivesDir, err := GetArchivesDir()
 	if err != nil {
 		return fmt.Errorf("failed to get archives directory: %w", err)
 	}
+	return appendArchivePages(archivesDir, meta, messages)
+}
 
-	filename := filepath.Join(archivesDir, sessionID+".json")
-
-	// Load existing archive if it exists
-	var existingMessages []Message
-	if data, err := os.ReadFile(filename); err == nil {
-		if err := json.Unmarshal(data, &existingMessages); err != nil {
-			return fmt.Errorf("failed to unmarshal existing archive: %w", err)
+// parseArchive decodes an archive file, which may be sealed, accepting the
+// older format that was a bare array of messages
+func parseArchive(sessionID string, data []byte) (*ArchiveData, error) {
+	data, err := openData(data)
+	if err != nil {
+		return nil, err
+	}
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var messages []Message
+		if err := json.Unmarshal(trimmed, &messages); err != nil {
+			return nil, err
 		}
+		return &ArchiveData{SessionID: sessionID, Messages: messages}, nil
 	}
 
-	// Append new messages
-	allMessages := append(existingMessages, messages...)
+	var archive ArchiveData
+	if err := json.Unmarshal(trimmed, &archive); err != nil {
+		return nil, err
+	}
+	if archive.SessionID == "" {
+		archive.SessionID = sessionID
+	}
+	return &archive, nil
+}
 
-	// Write back to file
-	jsonData, err := json.MarshalIndent(allMessages, "", "  ")
-	if err != nil {
-		return fmt.Errorf("failed to marshal archive: %w", err)
+// LoadArchive returns a session's archive. A session without an archive
+// yields an empty one.
+func LoadArchive(sessionID string) (*ArchiveData, error) {
+	if store := ActiveStore(); store != nil {
+		return store.LoadArchive(sessionID)
 	}
 
-	if err := os.WriteFile(filename, jsonData, 0644); err != nil {
-		return fmt.Errorf("failed to write archive file: %w", err)
+	archivesDir, err := GetArchivesDir()
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return loadArchivePages(archivesDir, sessionID)
 }
 
-// GetArchivedMessageCount returns the number of archived messages for a session
-func GetArchivedMessageCount(sessionID string) (int, error) {
-	archivesDir, err := GetArchivesDir()
-	if err != nil {
-		return 0, err
+// LoadArchivePage returns up to limit of a session's archived messages from
+// offset, oldest first, and how many it has archived in all
+func LoadArchivePage(sessionID string, offset, limit int) ([]Message, int, error) {
+	if store := ActiveStore(); store != nil {
+		return store.LoadArchivePage(sessionID, offset, limit)
 	}
 
-	filename := filepath.Join(archivesDir, sessionID+".json")
-	data, err := os.ReadFile(filename)
+	archivesDir, err := GetArchivesDir()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return 0, nil
-		}
-		return 0, fmt.Errorf("failed to read archive file: %w", err)
+		return nil, 0, err
 	}
+	return loadArchivePageFiles(archivesDir, sessionID, offset, limit)
+}
 
-	var messages []Message
-	if err := json.Unmarshal(data, &messages); err != nil {
-		return 0, fmt.Errorf("failed to unmarshal archive: %w", err)
-	}
+// GetArchivedMessageCount returns the number of archived messages for a session
+func GetArchivedMessageCount(sessionID string) (int, error) {
+	_, total, err := LoadArchivePage(sessionID, 0, 0)
+	return total, err
+}
 
-	return len(messages), nil
+// LoadArchivedMessages returns the archived messages for a session
+func LoadArchivedMessages(sessionID string) ([]Message, error) {
+	archive, err := LoadArchive(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return archive.Messages, nil
 }
 
-// DeleteArchiveFile removes an archive file from disk
+// DeleteArchiveFile removes a session's archived messages
 func DeleteArchiveFile(sessionID string) error {
+	if store := ActiveStore(); store != nil {
+		return store.DeleteArchive(sessionID)
+	}
+
 	archivesDir, err := GetArchivesDir()
 	if err != nil {
 		return fmt.Errorf("failed to get archives directory: %w", err)
@@ -311,6 +511,9 @@ func DeleteArchiveFile(sessionID string) error {
 	if err := os.Remove(filename); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("failed to delete archive file: %w", err)
 	}
+	if err := os.RemoveAll(archivePagesDir(archivesDir, sessionID)); err != nil {
+		return fmt.Errorf("failed to delete archive pages: %w", err)
+	}
 
 	return nil
 }
@@ -418,7 +621,7 @@ func CleanupOldSessions(maxAgeDays, maxTotal int) (int, error) {
 	deletedCount := 0
 	for _, sessionID := range toDelete {
 		if err := DeleteSessionFile(sessionID); err != nil {
-			fmt.Printf("Warning: failed to delete session %s: %v\n", sessionID, err)
+			logger.Warn("Failed to delete session", "session", sessionID, "error", err)
 			continue
 		}
 		// Also delete archive if it exists
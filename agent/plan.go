@@ -0,0 +1,221 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"boatman/diff"
+)
+
+// planBlockedTools can change the project, so plan runs can't use them
+var planBlockedTools = []string{
+	"Edit",
+	"MultiEdit",
+	"Write",
+	"NotebookEdit",
+	"Bash",
+}
+
+// planSystemPrompt tells the agent how to answer in plan mode
+const planSystemPrompt = `You are in plan mode. Do not change any files: investigate with read-only tools only.
+
+Answer with a plan: a short numbered list of the steps you would take and why. Then give every change you propose as a unified diff in a fenced ` + "```diff" + ` block, with "--- a/<path>" and "+++ b/<path>" headers relative to the project root ("/dev/null" for new or deleted files) and correct hunk headers. The user will review the diffs and ask you to apply them.`
+
+// applyPlanPrompt asks the agent to carry out the plan it proposed
+const applyPlanPrompt = `Apply the plan you proposed above. Make the changes in your proposed diffs, adapting them only if the files have changed since, then summarize what you changed.`
+
+// planModeArgs restricts a plan run to read-only tools. Nothing else is
+// allowed or offered for approval, so the run can't change the project.
+// denied are the tools the session's policy denies on top.
+func planModeArgs(denied []string) []string {
+	return []string{
+		"--permission-mode", "default",
+		"--allowedTools", strings.Join(withoutTools(readOnlyTools, denied), ","),
+		"--disallowedTools", strings.Join(appendTools(append([]string(nil), planBlockedTools...), denied), ","),
+	}
+}
+
+// SetPlanMode sets whether the session's runs only plan and propose changes
+// instead of making them
+func (s *Session) SetPlanMode(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.PlanMode = enabled
+	s.UpdatedAt = time.Now()
+}
+
+// IsPlanMode returns whether the session's runs only plan
+func (s *Session) IsPlanMode() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.PlanMode
+}
+
+// notePlanTurn remembers the turn a plan run answers, so its proposed
+// changes can be found
+func (s *Session) notePlanTurn(authConfig AuthConfig) {
+	if !authConfig.PlanOnly {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.planMessageID = s.turnMessageIDLocked("")
+}
+
+// planReplyLocked returns the text of the assistant's answer to the latest
+// plan turn, or false if there is no plan to apply.
+// Note: This method expects the caller to hold s.mu lock
+func (s *Session) planReplyLocked() (string, bool) {
+	if s.planMessageID == "" {
+		return "", false
+	}
+
+	start := -1
+	for i, msg := range s.Messages {
+		if msg.ID == s.planMessageID {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return "", false
+	}
+
+	var reply strings.Builder
+	for _, msg := range s.Messages[start+1:] {
+		if msg.Role == "user" {
+			break
+		}
+		if msg.Role != "assistant" || (msg.Metadata != nil && (msg.Metadata.Superseded || msg.Metadata.ToolUse != nil)) {
+			continue
+		}
+		reply.WriteString(msg.Content + "\n")
+	}
+	return reply.String(), true
+}
+
+// ProposedChanges parses the diffs proposed in the latest plan
+func (s *Session) ProposedChanges() ([]diff.FileDiff, error) {
+	s.mu.RLock()
+	reply, ok := s.planReplyLocked()
+	s.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no plan to review")
+	}
+
+	diffs, err := diff.ParseUnifiedDiff(extractDiffBlocks(reply))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse proposed changes: %w", err)
+	}
+	return diffs, nil
+}
+
+// ApplyPlan has the agent make the changes from its latest plan, with the
+// permissions in authConfig rather than plan mode's
+func (s *Session) ApplyPlan(authConfig AuthConfig) error {
+	s.mu.RLock()
+	_, ok := s.planReplyLocked()
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("no plan to apply")
+	}
+
+	authConfig.PlanOnly = false
+	if err := s.SendMessage(applyPlanPrompt, authConfig); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.planMessageID = ""
+	s.mu.Unlock()
+	return nil
+}
+
+// extractDiffBlocks joins the contents of the ```diff and ```patch fenced
+// blocks in text, adding the git header the diff parser expects to files
+// that don't have one
+func extractDiffBlocks(text string) string {
+	var blocks []string
+	var current []string
+	inBlock := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !inBlock {
+			lang, ok := strings.CutPrefix(trimmed, "```")
+			if ok && (lang == "diff" || lang == "patch") {
+				inBlock = true
+				current = nil
+			}
+			continue
+		}
+		if trimmed == "```" {
+			blocks = append(blocks, strings.Join(current, "\n"))
+			inBlock = false
+			continue
+		}
+		current = append(current, strings.TrimRight(line, "\r"))
+	}
+	return addGitHeaders(strings.Join(blocks, "\n"))
+}
+
+// addGitHeaders puts a "diff --git" line before each file's "---"/"+++"
+// headers that lack one
+func addGitHeaders(diffText string) string {
+	lines := strings.Split(diffText, "\n")
+	out := make([]string, 0, len(lines))
+	hasHeader := false
+	for i, line := range lines {
+		if strings.HasPrefix(line, "diff --git ") {
+			hasHeader = true
+		} else if strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") {
+			if !hasHeader {
+				oldPath := strings.TrimPrefix(strings.TrimPrefix(line, "--- "), "a/")
+				newPath := strings.TrimPrefix(strings.TrimPrefix(lines[i+1], "+++ "), "b/")
+				out = append(out, fmt.Sprintf("diff --git a/%s b/%s", oldPath, newPath))
+			}
+			hasHeader = false
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n")
+}
+
+// SetPlanMode sets whether a session's runs only plan and propose changes
+func (m *Manager) SetPlanMode(sessionID string, enabled bool) error {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	session.SetPlanMode(enabled)
+	return SaveSession(session)
+}
+
+// GetProposedChanges returns the diffs a session proposed in its latest plan
+func (m *Manager) GetProposedChanges(sessionID string) ([]diff.FileDiff, error) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return session.ProposedChanges()
+}
+
+// ApplyPlan re-runs a session with write permissions to make the changes it
+// proposed in plan mode
+func (m *Manager) ApplyPlan(sessionID string) error {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if err := m.costs.Check(sessionID); err != nil {
+		return err
+	}
+
+	authConfig, err := m.authConfigFor(session)
+	if err != nil {
+		return err
+	}
+
+	return session.ApplyPlan(authConfig)
+}
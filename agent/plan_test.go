@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+const planReply = "1. Guard against a nil order\n\n" +
+	"```diff\n" +
+	"--- a/orders.go\n" +
+	"+++ b/orders.go\n" +
+	"@@ -1,2 +1,3 @@\n" +
+	" func total(o *Order) int {\n" +
+	"+\tif o == nil { return 0 }\n" +
+	" \treturn o.Total\n" +
+	"```\n\n" +
+	"```go\nfmt.Println(\"not a diff\")\n```\n"
+
+func TestExtractDiffBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{name: "no blocks", text: "Nothing to change.", expected: ""},
+		{name: "other languages ignored", text: "```go\nx := 1\n```", expected: ""},
+		{name: "diff and patch blocks", text: "```diff\n--- a/a\n+++ b/a\n```\ntext\n```patch\n--- a/b\n+++ b/b\n```", expected: "diff --git a/a b/a\n--- a/a\n+++ b/a\ndiff --git a/b b/b\n--- a/b\n+++ b/b"},
+		{name: "git headers kept", text: "```diff\ndiff --git a/a b/a\nindex 1..2 100644\n--- a/a\n+++ b/a\n```", expected: "diff --git a/a b/a\nindex 1..2 100644\n--- a/a\n+++ b/a"},
+		{name: "unterminated block dropped", text: "```diff\n--- a/a", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractDiffBlocks(tt.text); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestProposedChanges(t *testing.T) {
+	session := NewSession("test-plan", "/tmp/project")
+	if _, err := session.ProposedChanges(); err == nil {
+		t.Error("Expected an error without a plan")
+	}
+	if err := session.ApplyPlan(AuthConfig{}); err == nil {
+		t.Error("Expected an error applying without a plan")
+	}
+
+	session.Messages = append(session.Messages, Message{ID: "user-1", Role: "user", Content: "fix the crash", Timestamp: time.Now()})
+	session.notePlanTurn(AuthConfig{PlanOnly: true})
+	session.Messages = append(session.Messages,
+		Message{ID: "tool-1", Role: "assistant", Metadata: &MessageMetadata{ToolUse: &ToolUse{ToolName: "Read"}}},
+		Message{ID: "reply-1", Role: "assistant", Content: planReply},
+	)
+
+	diffs, err := session.ProposedChanges()
+	if err != nil {
+		t.Fatalf("ProposedChanges failed: %v", err)
+	}
+	if len(diffs) != 1 || diffs[0].NewPath != "orders.go" {
+		t.Fatalf("Expected one diff of orders.go, got %+v", diffs)
+	}
+	if hunks := diffs[0].Hunks; len(hunks) != 1 || len(hunks[0].Lines) != 3 {
+		t.Errorf("Expected one hunk of three lines, got %+v", hunks)
+	}
+}
+
+func TestPlanRun(t *testing.T) {
+	session := NewSession("test-plan-run", "/tmp/project")
+	runner := &fakeRunner{stdout: `{"type":"result","subtype":"success","result":"Done"}` + "\n"}
+	session.runner = runner
+	session.Messages = append(session.Messages, Message{ID: "user-1", Role: "user", Content: "fix the crash"})
+
+	session.runWithRateLimitRetry("fix the crash", AuthConfig{ClaudeCLIPath: fakeClaudeBinary(t), ApprovalMode: "full-auto", PlanOnly: true})
+
+	args := strings.Join(runner.spec.Args, " ")
+	if strings.Contains(args, "bypassPermissions") {
+		t.Error("Expected plan runs not to bypass permissions")
+	}
+	if !strings.Contains(args, "--disallowedTools Edit,MultiEdit,Write,NotebookEdit,Bash") {
+		t.Errorf("Expected editing tools disallowed, got %q", args)
+	}
+	if !strings.Contains(args, "You are in plan mode") {
+		t.Errorf("Expected the plan instructions in the system prompt, got %q", args)
+	}
+	if session.planMessageID != "user-1" {
+		t.Errorf("Expected the plan turn recorded, got %q", session.planMessageID)
+	}
+}
+
+func TestBuildClaudeArgsPlanOnly(t *testing.T) {
+	args := buildClaudeArgs("", "", AuthConfig{ApprovalMode: "full-auto", AppendSystemPrompt: "Answer in French.", PlanOnly: true}, "")
+
+	expected := []string{
+		"-p",
+		"--input-format", "stream-json",
+		"--output-format", "stream-json",
+		"--verbose",
+		"--append-system-prompt", "Answer in French.\n\n" + planSystemPrompt,
+		"--permission-mode", "default",
+		"--allowedTools", "Read,Glob,Grep,LS,NotebookRead,WebFetch,WebSearch,TodoWrite,Task",
+		"--disallowedTools", "Edit,MultiEdit,Write,NotebookEdit,Bash",
+	}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("Expected args %q, got %q", expected, args)
+	}
+}
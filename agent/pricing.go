@@ -0,0 +1,119 @@
+package agent
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"boatman/stream"
+)
+
+// ModelPricing is what a model costs in dollars per million tokens
+type ModelPricing struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheWrite float64 `json:"cacheWrite"` // Writing prompt cache entries
+	CacheRead  float64 `json:"cacheRead"`  // Input served from the prompt cache
+}
+
+// Cache rates relative to the input rate, used when an override leaves them out
+const (
+	cacheWriteMultiplier = 1.25
+	cacheReadMultiplier  = 0.1
+)
+
+// defaultPricing lists public list prices. Entries are matched in order
+// against the lowercased model name, so older models that share a family name
+// with the current one come first. Aliases like "opus" price as the current
+// generation.
+var defaultPricing = []struct {
+	match   string
+	pricing ModelPricing
+}{
+	{"claude-3-haiku", ModelPricing{Input: 0.25, Output: 1.25, CacheWrite: 0.30, CacheRead: 0.03}},
+	{"3-5-haiku", ModelPricing{Input: 0.80, Output: 4, CacheWrite: 1, CacheRead: 0.08}},
+	{"claude-3-opus", ModelPricing{Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.50}},
+	{"opus-4-2025", ModelPricing{Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.50}},
+	{"opus-4-1", ModelPricing{Input: 15, Output: 75, CacheWrite: 18.75, CacheRead: 1.50}},
+	{"opus", ModelPricing{Input: 5, Output: 25, CacheWrite: 6.25, CacheRead: 0.50}},
+	{"sonnet", ModelPricing{Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30}},
+	{"haiku", ModelPricing{Input: 1, Output: 5, CacheWrite: 1.25, CacheRead: 0.10}},
+}
+
+// fallbackPricing prices models the table doesn't know (Sonnet rates)
+var fallbackPricing = ModelPricing{Input: 3, Output: 15, CacheWrite: 3.75, CacheRead: 0.30}
+
+var (
+	pricingMu        sync.RWMutex
+	pricingOverrides map[string]ModelPricing
+)
+
+// SetPricingOverrides replaces the user's custom prices, e.g. negotiated
+// enterprise rates. Keys are model names or parts of them ("opus",
+// "claude-sonnet-4-5") and the longest matching key wins. Missing cache rates
+// are derived from the input rate.
+func SetPricingOverrides(overrides map[string]ModelPricing) {
+	normalized := make(map[string]ModelPricing, len(overrides))
+	for model, pricing := range overrides {
+		model = strings.ToLower(strings.TrimSpace(model))
+		if model == "" {
+			continue
+		}
+		if pricing.CacheWrite == 0 && pricing.CacheRead == 0 {
+			pricing.CacheWrite = pricing.Input * cacheWriteMultiplier
+			pricing.CacheRead = pricing.Input * cacheReadMultiplier
+		}
+		normalized[model] = pricing
+	}
+
+	pricingMu.Lock()
+	defer pricingMu.Unlock()
+	pricingOverrides = normalized
+}
+
+// PricingFor returns the prices used for a model
+func PricingFor(model string) ModelPricing {
+	model = strings.ToLower(model)
+
+	pricingMu.RLock()
+	defer pricingMu.RUnlock()
+
+	if pricing, ok := pricingOverrides[model]; ok {
+		return pricing
+	}
+	keys := make([]string, 0, len(pricingOverrides))
+	for key := range pricingOverrides {
+		if strings.Contains(model, key) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) > 0 {
+		sort.Slice(keys, func(i, j int) bool {
+			if len(keys[i]) != len(keys[j]) {
+				return len(keys[i]) > len(keys[j])
+			}
+			return keys[i] < keys[j]
+		})
+		return pricingOverrides[keys[0]]
+	}
+
+	for _, entry := range defaultPricing {
+		if strings.Contains(model, entry.match) {
+			return entry.pricing
+		}
+	}
+	return fallbackPricing
+}
+
+// Cost returns the dollar cost of the given usage
+func (p ModelPricing) Cost(usage stream.Usage) float64 {
+	return (float64(usage.InputTokens)*p.Input +
+		float64(usage.OutputTokens)*p.Output +
+		float64(usage.CacheCreationInputTokens)*p.CacheWrite +
+		float64(usage.CacheReadInputTokens)*p.CacheRead) / 1_000_000
+}
+
+// calculateCost returns the dollar cost of usage on a model
+func calculateCost(model string, usage stream.Usage) float64 {
+	return PricingFor(model).Cost(usage)
+}
@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"math"
+	"testing"
+
+	"boatman/stream"
+)
+
+// TestPricingFor tests picking prices by model name
+func TestPricingFor(t *testing.T) {
+	tests := []struct {
+		model string
+		input float64
+	}{
+		{"sonnet", 3},
+		{"claude-sonnet-4-5-20250929", 3},
+		{"opus", 5},
+		{"claude-opus-4-1-20250805", 15},
+		{"claude-opus-4-20250514", 15},
+		{"claude-3-opus-20240229", 15},
+		{"haiku", 1},
+		{"claude-3-5-haiku-20241022", 0.80},
+		{"claude-3-haiku-20240307", 0.25},
+		{"Claude-Haiku-4-5", 1},
+		{"", 3},
+		{"some-future-model", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			if got := PricingFor(tt.model).Input; got != tt.input {
+				t.Errorf("Expected input price %.2f, got %.2f", tt.input, got)
+			}
+		})
+	}
+}
+
+// TestCalculateCost tests cost calculation including cache tokens
+func TestCalculateCost(t *testing.T) {
+	usage := stream.Usage{
+		InputTokens:              1_000_000,
+		OutputTokens:             1_000_000,
+		CacheCreationInputTokens: 1_000_000,
+		CacheReadInputTokens:     1_000_000,
+	}
+
+	tests := []struct {
+		model    string
+		expected float64
+	}{
+		{"sonnet", 3 + 15 + 3.75 + 0.30},
+		{"opus", 5 + 25 + 6.25 + 0.50},
+		{"haiku", 1 + 5 + 1.25 + 0.10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			if got := calculateCost(tt.model, usage); math.Abs(got-tt.expected) > 1e-9 {
+				t.Errorf("Expected cost %.4f, got %.4f", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestPricingOverrides tests user-provided prices
+func TestPricingOverrides(t *testing.T) {
+	SetPricingOverrides(map[string]ModelPricing{
+		"Opus":              {Input: 10, Output: 50},
+		"claude-opus-4-1":   {Input: 12, Output: 60, CacheWrite: 14, CacheRead: 1},
+		"  ":                {Input: 99},
+		"claude-sonnet-4-5": {Input: 2, Output: 10, CacheWrite: 2.5, CacheRead: 0.2},
+	})
+	t.Cleanup(func() { SetPricingOverrides(nil) })
+
+	opus := PricingFor("claude-opus-4-6")
+	if opus.Input != 10 || opus.Output != 50 {
+		t.Errorf("Expected the family override, got %+v", opus)
+	}
+	if math.Abs(opus.CacheWrite-12.5) > 1e-9 || math.Abs(opus.CacheRead-1) > 1e-9 {
+		t.Errorf("Expected derived cache prices, got %+v", opus)
+	}
+
+	if got := PricingFor("claude-opus-4-1-20250805"); got.Input != 12 || got.CacheRead != 1 {
+		t.Errorf("Expected the longest matching override, got %+v", got)
+	}
+	if got := PricingFor("claude-sonnet-4-5"); got.Input != 2 {
+		t.Errorf("Expected the exact override, got %+v", got)
+	}
+	if got := PricingFor("haiku"); got.Input != 1 {
+		t.Errorf("Expected default haiku pricing, got %+v", got)
+	}
+}
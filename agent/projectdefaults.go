@@ -0,0 +1,116 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ProjectDefaults are the model and approval mode a project's sessions use
+// unless they choose their own. Empty fields fall back to the user's.
+type ProjectDefaults struct {
+	Model        string `json:"model,omitempty"`
+	ApprovalMode string `json:"approvalMode,omitempty"` // "suggest", "auto-edit", "full-auto"
+}
+
+// ValidateApprovalMode checks that mode is empty or one claude can run in
+func ValidateApprovalMode(mode string) error {
+	switch mode {
+	case "", "suggest", "auto-edit", "full-auto":
+		return nil
+	}
+	return fmt.Errorf("unknown approval mode %q", mode)
+}
+
+// projectModelLocked returns the model for a project's sessions: the
+// project's default, or the user's.
+// Note: This method expects the caller to hold m.mu lock
+func (m *Manager) projectModelLocked(projectPath string) string {
+	if m.projectDefaultsResolver != nil {
+		if model := m.projectDefaultsResolver(projectPath).Model; model != "" {
+			return model
+		}
+	}
+	return m.defaultModel
+}
+
+// SetModelOverride sets the model used instead of the project's and user's
+// default; empty goes back to theirs
+func (s *Session) SetModelOverride(model string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ModelOverride = strings.TrimSpace(model)
+	s.UpdatedAt = time.Now()
+}
+
+// GetModelOverride returns the session's own model choice
+func (s *Session) GetModelOverride() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ModelOverride
+}
+
+// SetApprovalModeOverride sets the approval mode used instead of the
+// project's and user's; empty goes back to theirs
+func (s *Session) SetApprovalModeOverride(mode string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ApprovalModeOverride = mode
+	s.UpdatedAt = time.Now()
+}
+
+// GetApprovalModeOverride returns the session's own approval mode
+func (s *Session) GetApprovalModeOverride() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.ApprovalModeOverride
+}
+
+// GetModel returns the model the session's runs use
+func (s *Session) GetModel() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Model
+}
+
+// useModel switches the model the session's next run uses
+func (s *Session) useModel(model string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.Model = model
+}
+
+// SetSessionModel sets the model a session runs instead of its project's
+// and the user's default, taking effect from the next message. Empty goes
+// back to the default.
+func (m *Manager) SetSessionModel(sessionID, model string) error {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	session.SetModelOverride(model)
+
+	m.mu.RLock()
+	effective := m.projectModelLocked(session.ProjectPath)
+	m.mu.RUnlock()
+	if override := session.GetModelOverride(); override != "" {
+		effective = override
+	}
+	session.useModel(effective)
+	return SaveSession(session)
+}
+
+// SetSessionApprovalMode sets the approval mode a session runs in instead of
+// its project's and the user's, taking effect from the next message. Empty
+// goes back to theirs.
+func (m *Manager) SetSessionApprovalMode(sessionID, mode string) error {
+	if err := ValidateApprovalMode(mode); err != nil {
+		return err
+	}
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	session.SetApprovalModeOverride(mode)
+	return SaveSession(session)
+}
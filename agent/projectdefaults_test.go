@@ -0,0 +1,77 @@
+package agent
+
+import "testing"
+
+func TestProjectDefaults(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	m := NewManager()
+	m.SetDefaultModel("")
+	m.SetAuthConfigGetter(func() AuthConfig {
+		return AuthConfig{ApprovalMode: "suggest"}
+	})
+	m.SetProjectDefaultsResolver(func(projectPath string) ProjectDefaults {
+		if projectPath == "/work/payments" {
+			return ProjectDefaults{Model: "opus", ApprovalMode: "auto-edit"}
+		}
+		return ProjectDefaults{}
+	})
+
+	payments, err := m.CreateSession("/work/payments")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	other, err := m.CreateSession("/work/docs")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	if err := m.StartSession(payments.ID); err != nil {
+		t.Fatalf("StartSession failed: %v", err)
+	}
+	if payments.GetModel() != "opus" || other.GetModel() != fallbackModel {
+		t.Errorf("Expected opus for payments and %s elsewhere, got %q and %q", fallbackModel, payments.GetModel(), other.GetModel())
+	}
+
+	config, err := m.authConfigFor(payments)
+	if err != nil {
+		t.Fatalf("authConfigFor failed: %v", err)
+	}
+	if config.ApprovalMode != "auto-edit" {
+		t.Errorf("Expected the project's approval mode, got %q", config.ApprovalMode)
+	}
+	if config, _ := m.authConfigFor(other); config.ApprovalMode != "suggest" {
+		t.Errorf("Expected the user's approval mode without a project default, got %q", config.ApprovalMode)
+	}
+
+	// The session's own choices win, and survive a restart
+	if err := m.SetSessionModel(payments.ID, "haiku"); err != nil {
+		t.Fatalf("SetSessionModel failed: %v", err)
+	}
+	if err := m.SetSessionApprovalMode(payments.ID, "full-auto"); err != nil {
+		t.Fatalf("SetSessionApprovalMode failed: %v", err)
+	}
+	if err := m.StartSession(payments.ID); err != nil {
+		t.Fatalf("StartSession failed: %v", err)
+	}
+	if config, _ := m.authConfigFor(payments); config.ApprovalMode != "full-auto" || payments.GetModel() != "haiku" {
+		t.Errorf("Expected the session's model and approval mode, got %q and %q", payments.GetModel(), config.ApprovalMode)
+	}
+	loaded, err := LoadSession(payments.ID)
+	if err != nil {
+		t.Fatalf("LoadSession failed: %v", err)
+	}
+	if loaded.ModelOverride != "haiku" || loaded.ApprovalModeOverride != "full-auto" {
+		t.Errorf("Expected the overrides to be saved, got %q and %q", loaded.ModelOverride, loaded.ApprovalModeOverride)
+	}
+
+	// Clearing the override goes back to the project's model
+	if err := m.SetSessionModel(payments.ID, ""); err != nil {
+		t.Fatalf("SetSessionModel failed: %v", err)
+	}
+	if payments.GetModel() != "opus" {
+		t.Errorf("Expected the project's model back, got %q", payments.GetModel())
+	}
+
+	if err := m.SetSessionApprovalMode(payments.ID, "yolo"); err == nil {
+		t.Error("Expected an unknown approval mode to be rejected")
+	}
+}
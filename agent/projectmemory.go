@@ -0,0 +1,18 @@
+package agent
+
+// ProjectMemory is what a session gets from its project's shared notes
+type ProjectMemory struct {
+	// Prompt holds the notes, added to the system prompt of every run
+	Prompt string
+	// MCPConfig is inline MCP config for the server whose tool appends notes
+	MCPConfig string
+	// Tool is that tool's name; it runs without approval
+	Tool string
+}
+
+// SetProjectMemoryResolver sets the function that returns a project's shared notes
+func (m *Manager) SetProjectMemoryResolver(resolver func(projectPath string) ProjectMemory) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.projectMemoryResolver = resolver
+}
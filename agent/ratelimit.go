@@ -0,0 +1,244 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Waits used when the API doesn't say how long to back off
+var (
+	rateLimitDefaultWait = 30 * time.Second
+	rateLimitMaxWait     = 5 * time.Minute
+	rateLimitTick        = time.Second
+)
+
+// rateLimitMaxRetries is how many times a turn is resumed before the error is shown
+const rateLimitMaxRetries = 5
+
+// rateLimitContinuePrompt resumes a turn that was cut off after producing output
+const rateLimitContinuePrompt = "Continue from where you left off."
+
+// rateLimitMarkers identify responses rejected for rate limiting or overload.
+// Status codes are matched by rateLimitStatus so stray digits don't count.
+var rateLimitMarkers = []string{
+	"rate limit",
+	"rate_limit",
+	"ratelimit",
+	"too many requests",
+	"overloaded",
+}
+
+// rateLimitStatus matches a 429 or 529 status code reported as one
+var rateLimitStatus = statusCodePattern("429", "529")
+
+// statusCodePattern matches any of codes where the text reports it as an HTTP
+// or API status, e.g. "API Error: 429", "HTTP/1.1 503" or `"status":401`
+func statusCodePattern(codes ...string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)(?:\bapi error|\bhttp(?:/\d(?:\.\d)?)?|\bstatus(?:[ _]?code)?|\berror)"?\s*[:=]?\s*"?(?:` +
+		strings.Join(codes, "|") + `)\b`)
+}
+
+// retryAfterPatterns extract a wait hint, in seconds unless a unit follows
+var retryAfterPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)retry[-_ ]after"?\s*[:=]?\s*"?(\d+(?:\.\d+)?)\s*(ms|milliseconds?|s|sec|seconds?|m|min|minutes?)?\b`),
+	regexp.MustCompile(`(?i)(?:try again|retry) in (\d+(?:\.\d+)?)\s*(ms|milliseconds?|s|sec|seconds?|m|min|minutes?)\b`),
+}
+
+// runResult describes how a claude run ended
+type runResult int
+
+const (
+	runCompleted     runResult = iota
+	runNetworkFailed           // Failed on the network before producing output
+	runRateLimited             // Rejected for rate limiting or overload
+	runRetryable               // Failed in a way that may not happen again; see lastError
+)
+
+// RateLimitInfo is a countdown update while a session waits out a rate limit
+type RateLimitInfo struct {
+	RetryAt          time.Time `json:"retryAt"`
+	RemainingSeconds int       `json:"remainingSeconds"`
+	Attempt          int       `json:"attempt"`
+	Resumed          bool      `json:"resumed"` // The wait is over and the turn is running again
+}
+
+// isRateLimitError reports whether CLI output describes a rate limit or overload
+func isRateLimitError(text string) bool {
+	return containsAny(text, rateLimitMarkers) || rateLimitStatus.MatchString(text)
+}
+
+// parseRetryAfter extracts the wait hint from a rate limit message
+func parseRetryAfter(text string) (time.Duration, bool) {
+	for _, re := range retryAfterPatterns {
+		match := re.FindStringSubmatch(text)
+		if match == nil {
+			continue
+		}
+		value, err := strconv.ParseFloat(match[1], 64)
+		if err != nil {
+			continue
+		}
+
+		unit := time.Second
+		switch strings.ToLower(match[2]) {
+		case "ms", "millisecond", "milliseconds":
+			unit = time.Millisecond
+		case "m", "min", "minute", "minutes":
+			unit = time.Minute
+		}
+		return time.Duration(value * float64(unit)), true
+	}
+	return 0, false
+}
+
+// noteRateLimit records a rate limit seen in the current run's output
+func (s *Session) noteRateLimit(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rateLimited = true
+	if wait, ok := parseRetryAfter(text); ok {
+		s.retryAfter = wait
+	}
+}
+
+// SetRateLimitHandler sets the callback for rate limit countdown updates
+func (s *Session) SetRateLimitHandler(handler func(RateLimitInfo)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onRateLimit = handler
+}
+
+// runWithRateLimitRetry runs a prompt, waiting out rate limits and resuming the
+// turn until it completes, fails on the network, or runs out of retries.
+// Transient failures are retried with backoff following authConfig.RetryPolicy.
+func (s *Session) runWithRateLimitRetry(prompt string, authConfig AuthConfig, images ...promptImage) runResult {
+	ctx, done := s.beginTurn()
+	defer done()
+
+	release, err := s.waitForRunSlot(ctx)
+	if err != nil {
+		return runCompleted // Canceled while queued
+	}
+	defer release()
+
+	s.snapshotWorkspace(authConfig)
+	s.notePlanTurn(authConfig)
+	s.startChangeSet(authConfig)
+	defer s.finishChangeSet()
+
+	prompt = s.withKilledCommands(prompt)
+	policy := authConfig.RetryPolicy.withDefaults()
+	rateLimits, retries := 0, 0
+	for {
+		result := s.runClaudeCommand(ctx, prompt, authConfig, images...)
+
+		s.mu.Lock()
+		wait := s.retryAfter
+		partial := s.runHasOutput
+		runErr := s.lastError
+		s.mu.Unlock()
+
+		switch result {
+		case runRateLimited:
+			rateLimits++
+			if rateLimits > rateLimitMaxRetries {
+				s.mu.Lock()
+				s.lastError = &RunError{Kind: ErrRateLimited}
+				s.setStatus(SessionStatusError)
+				s.mu.Unlock()
+				s.addSystemMessage("Rate limit persisted after several retries. Send the message again to retry.")
+				return runCompleted
+			}
+
+			if wait <= 0 {
+				// No hint: back off exponentially
+				wait = rateLimitDefaultWait << (rateLimits - 1)
+			}
+			if wait > rateLimitMaxWait {
+				wait = rateLimitMaxWait
+			}
+
+			if !s.waitForRateLimit(ctx, wait, rateLimits) {
+				return runCompleted
+			}
+
+		case runRetryable:
+			retries++
+			if policy.MaxRetries < 0 || retries > policy.MaxRetries {
+				s.failRun(runErr)
+				return runCompleted
+			}
+
+			wait = policy.delay(retries)
+			s.addSystemMessage(fmt.Sprintf("🔁 %v. Retrying in %s (%d of %d)", runErr, wait, retries, policy.MaxRetries))
+			if !s.waitForRetry(ctx, wait) {
+				return runCompleted
+			}
+
+		default:
+			return result
+		}
+
+		// Output already shown stays; ask claude to pick up where it stopped
+		if partial {
+			prompt = rateLimitContinuePrompt
+		}
+	}
+}
+
+// waitForRateLimit sets the rate-limited status and counts down until the wait
+// passes. It returns false if ctx is canceled first, i.e. the session is
+// stopped or the run canceled.
+func (s *Session) waitForRateLimit(ctx context.Context, wait time.Duration, attempt int) bool {
+	retryAt := time.Now().Add(wait)
+
+	s.mu.Lock()
+	s.setStatus(SessionStatusRateLimited)
+	s.mu.Unlock()
+
+	ticker := time.NewTicker(rateLimitTick)
+	defer ticker.Stop()
+	for {
+		remaining := time.Until(retryAt)
+		if remaining <= 0 {
+			break
+		}
+		s.emitRateLimit(RateLimitInfo{
+			RetryAt:          retryAt,
+			RemainingSeconds: int(remaining.Round(time.Second).Seconds()),
+			Attempt:          attempt,
+		})
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+	}
+
+	s.mu.Lock()
+	if s.Status != SessionStatusRateLimited {
+		// Stopped while waiting
+		s.mu.Unlock()
+		return false
+	}
+	s.setStatus(SessionStatusRunning)
+	s.mu.Unlock()
+
+	s.emitRateLimit(RateLimitInfo{RetryAt: retryAt, Attempt: attempt, Resumed: true})
+	return true
+}
+
+// emitRateLimit sends a countdown update to the rate limit handler
+func (s *Session) emitRateLimit(info RateLimitInfo) {
+	s.mu.RLock()
+	handler := s.onRateLimit
+	s.mu.RUnlock()
+	if handler != nil {
+		handler(info)
+	}
+}
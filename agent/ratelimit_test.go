@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIsRateLimitError(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected bool
+	}{
+		{`API Error: 429 {"type":"error","error":{"type":"rate_limit_error"}}`, true},
+		{`API Error: 529 {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, true},
+		{"Too Many Requests", true},
+		{"API Error: 401 invalid x-api-key", false},
+		{"Prompt is too long", false},
+		{"HTTP/1.1 429 Too Many Requests", true},
+		{`{"status": 529}`, true},
+		{"processed 1429 files", false},
+		{"wrote 529 bytes to /tmp/out-429.log", false},
+		{"commit 4295a1c", false},
+	}
+
+	for _, tt := range tests {
+		if got := isRateLimitError(tt.text); got != tt.expected {
+			t.Errorf("isRateLimitError(%q): expected %v, got %v", tt.text, tt.expected, got)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected time.Duration
+		found    bool
+	}{
+		{"429 Too Many Requests retry-after: 30", 30 * time.Second, true},
+		{`{"error":"rate_limit_error","retry_after": 12}`, 12 * time.Second, true},
+		{"Rate limited. Please try again in 2 minutes.", 2 * time.Minute, true},
+		{"Retry after 1500ms", 1500 * time.Millisecond, true},
+		{"API Error: 529 Overloaded", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.text)
+		if ok != tt.found || got != tt.expected {
+			t.Errorf("parseRetryAfter(%q): expected %v (%v), got %v (%v)", tt.text, tt.expected, tt.found, got, ok)
+		}
+	}
+}
+
+func TestParseStreamLineRateLimit(t *testing.T) {
+	session := NewSession("test-ratelimit-parse", "/tmp/test")
+
+	var responseBuilder strings.Builder
+	var currentMessageID string
+	session.parseStreamLine(`{"type":"result","is_error":true,"result":"API Error: 429 rate_limit_error, retry-after: 20"}`, &responseBuilder, &currentMessageID)
+
+	if !session.rateLimited {
+		t.Error("Expected rate limit to be recorded")
+	}
+	if session.retryAfter != 20*time.Second {
+		t.Errorf("Expected retry after 20s, got %v", session.retryAfter)
+	}
+	if len(session.GetMessages()) != 0 {
+		t.Errorf("Expected the rate limit error not to be shown, got %d messages", len(session.GetMessages()))
+	}
+}
+
+func TestWaitForRateLimit(t *testing.T) {
+	oldTick := rateLimitTick
+	rateLimitTick = 10 * time.Millisecond
+	defer func() { rateLimitTick = oldTick }()
+
+	session := NewSession("test-ratelimit-wait", "/tmp/test")
+	session.ctx = context.Background()
+
+	var statuses []SessionStatus
+	session.SetStatusHandler(func(status SessionStatus) {
+		statuses = append(statuses, status)
+	})
+	var updates []RateLimitInfo
+	session.SetRateLimitHandler(func(info RateLimitInfo) {
+		updates = append(updates, info)
+	})
+
+	if !session.waitForRateLimit(session.ctx, 50*time.Millisecond, 2) {
+		t.Fatal("Expected the wait to complete")
+	}
+
+	if len(statuses) != 2 || statuses[0] != SessionStatusRateLimited || statuses[1] != SessionStatusRunning {
+		t.Errorf("Expected rate-limited then running, got %v", statuses)
+	}
+	if len(updates) < 2 {
+		t.Fatalf("Expected countdown updates, got %d", len(updates))
+	}
+	if updates[0].Attempt != 2 || updates[0].Resumed {
+		t.Errorf("Unexpected first update: %+v", updates[0])
+	}
+	if last := updates[len(updates)-1]; !last.Resumed {
+		t.Errorf("Expected final update to mark the turn resumed, got %+v", last)
+	}
+}
+
+func TestWaitForRateLimitStopped(t *testing.T) {
+	session := NewSession("test-ratelimit-stop", "/tmp/test")
+	ctx, cancel := context.WithCancel(context.Background())
+	session.ctx = ctx
+
+	done := make(chan bool)
+	go func() {
+		done <- session.waitForRateLimit(ctx, time.Minute, 1)
+	}()
+	cancel()
+
+	select {
+	case resumed := <-done:
+		if resumed {
+			t.Error("Expected a stopped session not to resume")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the wait to end when the session stops")
+	}
+}
@@ -0,0 +1,107 @@
+package agent
+
+import (
+	"encoding/json"
+	"regexp"
+)
+
+// redactedPlaceholder replaces secrets in message content and logs
+const redactedPlaceholder = "[REDACTED]"
+
+// secretPatterns match API keys, tokens, and other credentials. Patterns with a
+// "secret" group only mask that group so the surrounding context stays readable.
+var secretPatterns = []*regexp.Regexp{
+	// Private key blocks
+	regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`),
+	// Anthropic and OpenAI keys
+	regexp.MustCompile(`sk-ant-[A-Za-z0-9_-]{20,}`),
+	regexp.MustCompile(`sk-(?:proj-)?[A-Za-z0-9_-]{20,}`),
+	// GitHub tokens
+	regexp.MustCompile(`gh[pousr]_[A-Za-z0-9]{36,}`),
+	regexp.MustCompile(`github_pat_[A-Za-z0-9_]{22,}`),
+	// AWS access key IDs
+	regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	// Slack tokens
+	regexp.MustCompile(`xox[abprs]-[A-Za-z0-9-]{10,}`),
+	// Google API keys
+	regexp.MustCompile(`AIza[0-9A-Za-z_-]{35}`),
+	// Stripe keys
+	regexp.MustCompile(`\b[rs]k_(?:live|test)_[0-9A-Za-z]{16,}`),
+	// Linear API keys
+	regexp.MustCompile(`lin_api_[A-Za-z0-9]{32,}`),
+	// JSON web tokens
+	regexp.MustCompile(`eyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`),
+	// Bearer authorization headers
+	regexp.MustCompile(`(?i)\bbearer\s+(?P<secret>[A-Za-z0-9._~+/=-]{20,})`),
+	// Credentials embedded in URLs
+	regexp.MustCompile(`://[^/\s:@"]+:(?P<secret>[^/\s@"\\]+)@`),
+	// Assignments like API_KEY=..., "password": "...", client_secret: ...
+	regexp.MustCompile(`(?i)(?:api[_-]?key|app[_-]?key|secret|token|password|passwd|access[_-]?key|client[_-]?secret)["']?\s*[:=]\s*["']?(?P<secret>[^\s"'\\,;\[\]]{8,})`),
+}
+
+// RedactSecrets masks credentials in text and returns the redacted text along
+// with the number of secrets that were masked
+func RedactSecrets(text string) (string, int) {
+	count := 0
+	for _, pattern := range secretPatterns {
+		group := pattern.SubexpIndex("secret")
+		text = pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if group < 0 {
+				count++
+				return redactedPlaceholder
+			}
+			loc := pattern.FindStringSubmatchIndex(match)
+			start, end := loc[2*group], loc[2*group+1]
+			if start < 0 {
+				return match
+			}
+			count++
+			return match[:start] + redactedPlaceholder + match[end:]
+		})
+	}
+	return text, count
+}
+
+// redactString masks credentials in text, for log lines that don't need a count
+func redactString(text string) string {
+	redacted, _ := RedactSecrets(text)
+	return redacted
+}
+
+// redactMessage masks credentials in a message's content, tool input, and tool
+// result, and records how many were found in the message metadata
+func redactMessage(msg *Message) {
+	content, count := RedactSecrets(msg.Content)
+	msg.Content = content
+
+	if msg.Metadata != nil {
+		if use := msg.Metadata.ToolUse; use != nil && len(use.Input) > 0 {
+			input, n := RedactSecrets(string(use.Input))
+			if n > 0 {
+				if !json.Valid([]byte(input)) {
+					// Fall back to a JSON string so the stored input stays parseable
+					data, _ := json.Marshal(input)
+					input = string(data)
+				}
+				use.Input = json.RawMessage(input)
+				count += n
+			}
+		}
+		if result := msg.Metadata.ToolResult; result != nil {
+			var n int
+			result.Content, n = RedactSecrets(result.Content)
+			count += n
+		}
+	}
+
+	if count == 0 {
+		if msg.Metadata != nil {
+			msg.Metadata.Redactions = 0
+		}
+		return
+	}
+	if msg.Metadata == nil {
+		msg.Metadata = &MessageMetadata{}
+	}
+	msg.Metadata.Redactions = count
+}
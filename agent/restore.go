@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// RestorableSession summarizes a saved session so the user can pick it up again
+type RestorableSession struct {
+	ID           string    `json:"id"`
+	ProjectPath  string    `json:"projectPath"`
+	Preview      string    `json:"preview"` // The first user message, shortened
+	MessageCount int       `json:"messageCount"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+	Tags         []string  `json:"tags,omitempty"`
+	IsFavorite   bool      `json:"isFavorite,omitempty"`
+	// CanResume is set when claude has a conversation to continue with -r
+	CanResume bool `json:"canResume"`
+	// Loaded is set when the session is already open in the manager
+	Loaded bool `json:"loaded"`
+}
+
+// RestoreSessions loads every saved session so it can be used again, including
+// the conversation ID that lets claude resume it. Sessions already loaded are
+// left as they are.
+func (m *Manager) RestoreSessions() (int, error) {
+	sessions, err := LoadAllSessions()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load saved sessions: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	restored := 0
+	for _, session := range sessions {
+		if _, ok := m.sessions[session.ID]; ok {
+			continue
+		}
+		m.adoptSession(session)
+		restored++
+	}
+	return restored, nil
+}
+
+// RestoreSession loads one saved session, returning it as is if already loaded
+func (m *Manager) RestoreSession(sessionID string) (*Session, error) {
+	if session, err := m.GetSession(sessionID); err == nil {
+		return session, nil
+	}
+
+	session, err := LoadSession(sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore session: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Another caller may have restored it in the meantime
+	if existing, ok := m.sessions[sessionID]; ok {
+		return existing, nil
+	}
+	m.adoptSession(session)
+	return session, nil
+}
+
+// RestorableSessions lists the saved sessions, most recently active first
+func (m *Manager) RestorableSessions() ([]RestorableSession, error) {
+	sessions, err := LoadAllSessions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load saved sessions: %w", err)
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	restorable := make([]RestorableSession, 0, len(sessions))
+	for _, session := range sessions {
+		_, loaded := m.sessions[session.ID]
+		restorable = append(restorable, RestorableSession{
+			ID:           session.ID,
+			ProjectPath:  session.ProjectPath,
+			Preview:      sessionPreview(session.Messages),
+			MessageCount: len(session.Messages),
+			UpdatedAt:    session.UpdatedAt,
+			Tags:         session.Tags,
+			IsFavorite:   session.IsFavorite,
+			CanResume:    session.conversationID != "",
+			Loaded:       loaded,
+		})
+	}
+
+	sort.Slice(restorable, func(i, j int) bool {
+		return restorable[i].UpdatedAt.After(restorable[j].UpdatedAt)
+	})
+	return restorable, nil
+}
+
+// adoptSession wires a loaded session into the manager.
+// Note: This method expects the caller to hold m.mu lock
+func (m *Manager) adoptSession(session *Session) {
+	m.setupSessionHandlers(session, session.ID)
+
+	if m.configGetter != nil {
+		session.SetTrimSettings(m.configGetter.GetMaxMessagesPerSession(), m.configGetter.GetArchiveOldMessages())
+		session.SetAgentCleanupSettings(m.configGetter.GetMaxAgentsPerSession(), m.configGetter.GetKeepCompletedAgents())
+		session.SetCommandTimeout(time.Duration(m.configGetter.GetMaxCommandRuntimeSeconds()) * time.Second)
+	}
+
+	m.costs.Seed(session.ID, session.Messages)
+	m.sessions[session.ID] = session
+}
+
+// sessionPreview returns the start of the first user message
+func sessionPreview(messages []Message) string {
+	for _, msg := range messages {
+		if msg.Role == "user" {
+			return truncateString(msg.Content, 80)
+		}
+	}
+	return ""
+}
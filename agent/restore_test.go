@@ -0,0 +1,86 @@
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+func saveTestSession(t *testing.T, id, conversationID, prompt string, updatedAt time.Time) {
+	t.Helper()
+	session := NewSession(id, "/tmp/"+id)
+	session.conversationID = conversationID
+	if prompt != "" {
+		session.Messages = append(session.Messages, Message{ID: id + "-msg", Role: "user", Content: prompt, Timestamp: updatedAt})
+	}
+	session.UpdatedAt = updatedAt
+	if err := SaveSession(session); err != nil {
+		t.Fatalf("Failed to save session: %v", err)
+	}
+}
+
+func TestRestorableSessions(t *testing.T) {
+	useTestStore(t)
+
+	now := time.Now()
+	saveTestSession(t, "older", "conv-older", "Fix the login bug", now.Add(-time.Hour))
+	saveTestSession(t, "newer", "", "", now)
+
+	m := NewManager()
+	if _, err := m.RestoreSession("older"); err != nil {
+		t.Fatalf("RestoreSession failed: %v", err)
+	}
+
+	restorable, err := m.RestorableSessions()
+	if err != nil {
+		t.Fatalf("RestorableSessions failed: %v", err)
+	}
+	if len(restorable) != 2 {
+		t.Fatalf("Expected 2 restorable sessions, got %d", len(restorable))
+	}
+	if restorable[0].ID != "newer" || restorable[1].ID != "older" {
+		t.Errorf("Expected newest first, got %s, %s", restorable[0].ID, restorable[1].ID)
+	}
+
+	newer, older := restorable[0], restorable[1]
+	if newer.CanResume || newer.Loaded {
+		t.Errorf("Expected newer to be neither resumable nor loaded, got %+v", newer)
+	}
+	if !older.CanResume || !older.Loaded {
+		t.Errorf("Expected older to be resumable and loaded, got %+v", older)
+	}
+	if older.Preview != "Fix the login bug" || older.MessageCount != 1 {
+		t.Errorf("Expected preview of the first prompt, got %q (%d messages)", older.Preview, older.MessageCount)
+	}
+}
+
+func TestRestoreSession(t *testing.T) {
+	useTestStore(t)
+	saveTestSession(t, "saved", "conv-1", "Hello", time.Now())
+
+	m := NewManager()
+	session, err := m.RestoreSession("saved")
+	if err != nil {
+		t.Fatalf("RestoreSession failed: %v", err)
+	}
+	if session.conversationID != "conv-1" {
+		t.Errorf("Expected conversation ID conv-1, got %q", session.conversationID)
+	}
+	if session.onMessage == nil {
+		t.Error("Expected the restored session to have event handlers")
+	}
+
+	again, err := m.RestoreSession("saved")
+	if err != nil || again != session {
+		t.Errorf("Expected the loaded session to be returned, got %p, %v", again, err)
+	}
+
+	if _, err := m.RestoreSession("missing"); err == nil {
+		t.Error("Expected an error for an unknown session")
+	}
+
+	// Sessions already loaded are skipped
+	count, err := m.RestoreSessions()
+	if err != nil || count != 0 {
+		t.Errorf("Expected nothing new to restore, got %d, %v", count, err)
+	}
+}
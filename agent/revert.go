@@ -0,0 +1,164 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"boatman/git"
+)
+
+// RevertTurn puts back the files changed by the turn messageID belongs to,
+// from the content kept in its change set or, for large files, in git. It
+// changes nothing if any of the files has changed again since the turn.
+func (s *Session) RevertTurn(messageID string) error {
+	s.mu.RLock()
+	turnID := s.turnMessageIDLocked(messageID)
+	set, running := s.changeSetLocked(turnID)
+	busy := s.turnCancel != nil
+	s.mu.RUnlock()
+
+	if set == nil {
+		return fmt.Errorf("no changes recorded for message %s", messageID)
+	}
+	if running || busy {
+		return fmt.Errorf("can't revert a turn while a run is in progress")
+	}
+
+	// Check everything before touching anything
+	var conflicts []string
+	for _, file := range set.Files {
+		if !file.keepsContent() && file.Existed && file.BeforeBlob == "" {
+			if file.Withheld {
+				return fmt.Errorf("%s is protected and its content wasn't kept, so it can't be reverted", file.Path)
+			}
+			return fmt.Errorf("%s is too large to revert", file.Path)
+		}
+		if s.changedSinceTurn(file) {
+			conflicts = append(conflicts, file.Path)
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("files changed since the turn can't be reverted: %s", strings.Join(conflicts, ", "))
+	}
+
+	var restored, removed, recreated []string
+	for _, file := range set.Files {
+		path := s.absChangePath(file.Path)
+		if !file.Existed {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %w", file.Path, err)
+			}
+			removed = append(removed, file.Path)
+			continue
+		}
+
+		content := []byte(file.Before)
+		if !file.keepsContent() {
+			blob, err := git.NewRepository(s.ProjectPath).ReadBlob(file.BeforeBlob)
+			if err != nil {
+				return fmt.Errorf("failed to read the old content of %s: %w", file.Path, err)
+			}
+			content = blob
+		}
+		if err := writeFileKeepingMode(path, content); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", file.Path, err)
+		}
+		if file.Exists {
+			restored = append(restored, file.Path)
+		} else {
+			recreated = append(recreated, file.Path)
+		}
+	}
+
+	s.mu.Lock()
+	for i := range s.changeSets {
+		if s.changeSets[i].MessageID == turnID {
+			s.changeSets = append(s.changeSets[:i], s.changeSets[i+1:]...)
+			break
+		}
+	}
+	s.UpdatedAt = time.Now()
+	s.mu.Unlock()
+
+	s.addSystemMessage(revertSummary(restored, removed, recreated))
+	return nil
+}
+
+// changedSinceTurn reports whether a file no longer looks as the turn left it
+func (s *Session) changedSinceTurn(file FileChange) bool {
+	path := s.absChangePath(file.Path)
+	if file.keepsContent() {
+		current, exists, _ := readChangeFile(path)
+		return exists != file.Exists || current != file.After
+	}
+
+	_, err := os.Stat(path)
+	if exists := err == nil; exists != file.Exists {
+		return true
+	}
+	if !file.Exists {
+		return false
+	}
+	hash, err := hashFile(path)
+	return err != nil || hash != file.AfterHash
+}
+
+// revertSummary describes a reverted turn for the transcript
+func revertSummary(restored, removed, recreated []string) string {
+	var parts []string
+	if len(restored) > 0 {
+		parts = append(parts, "restored "+strings.Join(restored, ", "))
+	}
+	if len(removed) > 0 {
+		parts = append(parts, "removed "+strings.Join(removed, ", "))
+	}
+	if len(recreated) > 0 {
+		parts = append(parts, "recreated "+strings.Join(recreated, ", "))
+	}
+	return "↩️  Reverted the turn's changes: " + strings.Join(parts, "; ")
+}
+
+// writeFileKeepingMode replaces a file's content without changing its
+// permissions, creating it and its directory if needed
+func writeFileKeepingMode(path string, content []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	} else if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, mode)
+}
+
+// hashFile returns the SHA-256 of a file's content
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// RevertTurn puts back the files changed by the turn a message belongs to
+func (m *Manager) RevertTurn(sessionID, messageID string) error {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if err := session.RevertTurn(messageID); err != nil {
+		return err
+	}
+	return SaveSession(session)
+}
@@ -0,0 +1,148 @@
+package agent
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"boatman/stream"
+)
+
+func TestRevertTurn(t *testing.T) {
+	dir := t.TempDir()
+	writeProjectFile(t, dir, "kept.txt", "before\n")
+	writeProjectFile(t, dir, "removed.txt", "doomed\n")
+
+	session := NewSession("test-revert", dir)
+	turnID := startTestTurn(session, "change things")
+	for _, name := range []string{"kept.txt", "removed.txt", "added.txt"} {
+		session.handleToolUse(stream.ToolUse{ID: "tool-" + name, Name: "Write", Input: map[string]any{"file_path": name}})
+	}
+	writeProjectFile(t, dir, "kept.txt", "after\n")
+	writeProjectFile(t, dir, "added.txt", "new\n")
+	os.Remove(filepath.Join(dir, "removed.txt"))
+	session.finishChangeSet()
+
+	// A file changed again since the turn blocks the revert
+	writeProjectFile(t, dir, "kept.txt", "edited by hand\n")
+	if err := session.RevertTurn(turnID); err == nil || !strings.Contains(err.Error(), "kept.txt") {
+		t.Fatalf("Expected a conflict on kept.txt, got %v", err)
+	}
+	writeProjectFile(t, dir, "kept.txt", "after\n")
+
+	if err := session.RevertTurn(turnID); err != nil {
+		t.Fatalf("RevertTurn failed: %v", err)
+	}
+	for name, want := range map[string]string{"kept.txt": "before\n", "removed.txt": "doomed\n"} {
+		if data, err := os.ReadFile(filepath.Join(dir, name)); err != nil || string(data) != want {
+			t.Errorf("Expected %s restored to %q, got %q (%v)", name, want, data, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "added.txt")); !os.IsNotExist(err) {
+		t.Errorf("Expected added.txt removed, got %v", err)
+	}
+
+	summary := session.Messages[len(session.Messages)-1].Content
+	if want := "restored kept.txt; removed added.txt; recreated removed.txt"; !strings.HasSuffix(summary, want) {
+		t.Errorf("Expected the summary to end with %q, got %q", want, summary)
+	}
+
+	if err := session.RevertTurn(turnID); err == nil {
+		t.Error("Expected a second revert to fail")
+	}
+}
+
+func TestRevertTurnLargeFile(t *testing.T) {
+	dir := initGitProject(t)
+	before := strings.Repeat("a", maxChangeFileSize+1)
+	writeProjectFile(t, dir, "big.txt", before)
+
+	session := NewSession("test-revert-large", dir)
+	turnID := startTestTurn(session, "rewrite the big file")
+	session.handleToolUse(stream.ToolUse{ID: "tool-1", Name: "Write", Input: map[string]any{"file_path": "big.txt"}})
+	writeProjectFile(t, dir, "big.txt", strings.Repeat("b", maxChangeFileSize+1))
+	session.finishChangeSet()
+
+	diffs := session.GetTurnChanges(turnID)
+	if len(diffs) != 1 || !diffs[0].IsBinary {
+		t.Fatalf("Expected the large file listed without content, got %+v", diffs)
+	}
+
+	if err := session.RevertTurn(turnID); err != nil {
+		t.Fatalf("RevertTurn failed: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "big.txt")); string(data) != before {
+		t.Error("Expected the large file restored from git")
+	}
+
+	summary := session.Messages[len(session.Messages)-1].Content
+	if !strings.Contains(summary, "restored big.txt") {
+		t.Errorf("Expected a summary of the revert, got %q", summary)
+	}
+}
+
+func TestRevertTurnProtectedFiles(t *testing.T) {
+	dir := initGitProject(t)
+	writeProjectFile(t, dir, ".env", "SECRET=old\n")
+
+	session := NewSession("test-revert-protected", dir)
+	session.Messages = append(session.Messages, Message{ID: "msg-secrets", Role: "user", Content: "rotate the secret"})
+	session.startChangeSet(AuthConfig{ProtectedPaths: []string{"file.txt"}})
+	session.handleToolUse(stream.ToolUse{ID: "tool-1", Name: "Write", Input: map[string]any{"file_path": ".env"}})
+	session.handleToolUse(stream.ToolUse{ID: "tool-2", Name: "Edit", Input: map[string]any{"file_path": "file.txt"}})
+	writeProjectFile(t, dir, ".env", "SECRET=new\n")
+	writeProjectFile(t, dir, "file.txt", "protected edit")
+	session.finishChangeSet()
+
+	// Neither file's content is kept or saved with the session
+	for _, file := range session.changeSets[0].Files {
+		if !file.Withheld || file.Before != "" || file.After != "" || file.BeforeBlob == "" {
+			t.Errorf("Expected %s withheld and kept in git, got %+v", file.Path, file)
+		}
+	}
+	data, err := json.Marshal(newSessionData(session))
+	if err != nil {
+		t.Fatalf("Failed to marshal session: %v", err)
+	}
+	for _, secret := range []string{"SECRET", "original", "protected edit"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("Expected %q left out of the saved session", secret)
+		}
+	}
+	diffs := session.GetTurnChanges("msg-secrets")
+	if len(diffs) != 2 || !diffs[0].IsBinary || !diffs[1].IsBinary {
+		t.Errorf("Expected the files listed without content, got %+v", diffs)
+	}
+
+	if err := session.RevertTurn("msg-secrets"); err != nil {
+		t.Fatalf("RevertTurn failed: %v", err)
+	}
+	for name, want := range map[string]string{".env": "SECRET=old\n", "file.txt": "original"} {
+		if data, _ := os.ReadFile(filepath.Join(dir, name)); string(data) != want {
+			t.Errorf("Expected %s restored from git, got %q", name, data)
+		}
+	}
+}
+
+func TestRevertTurnProtectedFileOutsideGit(t *testing.T) {
+	dir := t.TempDir()
+	writeProjectFile(t, dir, ".env", "SECRET=old\n")
+
+	session := NewSession("test-revert-protected-plain", dir)
+	turnID := startTestTurn(session, "rotate the secret")
+	session.handleToolUse(stream.ToolUse{ID: "tool-1", Name: "Write", Input: map[string]any{"file_path": ".env"}})
+	writeProjectFile(t, dir, ".env", "SECRET=new\n")
+	session.finishChangeSet()
+
+	if file := session.changeSets[0].Files[0]; !file.Withheld || file.Before != "" || file.After != "" {
+		t.Errorf("Expected .env's content withheld, got %+v", file)
+	}
+	if err := session.RevertTurn(turnID); err == nil || !strings.Contains(err.Error(), "can't be reverted") {
+		t.Errorf("Expected .env reported as not revertable, got %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, ".env")); string(data) != "SECRET=new\n" {
+		t.Errorf("Expected .env left alone, got %q", data)
+	}
+}
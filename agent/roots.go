@@ -0,0 +1,69 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// NormalizeAdditionalPaths checks that paths are directories a session can
+// span besides its project, cleaning them and dropping repeats
+func NormalizeAdditionalPaths(projectPath string, paths []string) ([]string, error) {
+	var normalized []string
+	seen := map[string]bool{filepath.Clean(projectPath): true}
+	for _, path := range paths {
+		if !filepath.IsAbs(path) {
+			return nil, fmt.Errorf("additional directory must be an absolute path: %s", path)
+		}
+		path = filepath.Clean(path)
+		if seen[path] {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || !info.IsDir() {
+			return nil, fmt.Errorf("additional directory not found: %s", path)
+		}
+		seen[path] = true
+		normalized = append(normalized, path)
+	}
+	return normalized, nil
+}
+
+// SetAdditionalPaths sets the directories the session spans besides its project
+func (s *Session) SetAdditionalPaths(paths []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.AdditionalPaths = paths
+	s.UpdatedAt = time.Now()
+}
+
+// GetAdditionalPaths returns the directories the session spans besides its project
+func (s *Session) GetAdditionalPaths() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string(nil), s.AdditionalPaths...)
+}
+
+// Roots returns every directory the session spans, its project first
+func (s *Session) Roots() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]string{s.ProjectPath}, s.AdditionalPaths...)
+}
+
+// SetSessionAdditionalPaths sets the directories a session spans besides its
+// project, e.g. a backend repo next to the frontend one. They take effect
+// from the next message.
+func (m *Manager) SetSessionAdditionalPaths(sessionID string, paths []string) error {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	paths, err = NormalizeAdditionalPaths(session.ProjectPath, paths)
+	if err != nil {
+		return err
+	}
+	session.SetAdditionalPaths(paths)
+	return SaveSession(session)
+}
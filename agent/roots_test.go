@@ -0,0 +1,84 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeAdditionalPaths(t *testing.T) {
+	project := t.TempDir()
+	backend := t.TempDir()
+	file := filepath.Join(backend, "go.mod")
+	if err := os.WriteFile(file, []byte("module api"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		paths    []string
+		expected []string
+		wantErr  bool
+	}{
+		{"none", nil, nil, false},
+		{"cleaned and deduplicated", []string{backend + "/", backend}, []string{backend}, false},
+		{"project left out", []string{project, backend}, []string{backend}, false},
+		{"relative", []string{"backend"}, nil, true},
+		{"missing", []string{filepath.Join(backend, "missing")}, nil, true},
+		{"file", []string{file}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths, err := NormalizeAdditionalPaths(project, tt.paths)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeAdditionalPaths() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(paths, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, paths)
+			}
+		})
+	}
+}
+
+func TestAuthConfigForAdditionalPaths(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	backend := t.TempDir()
+
+	m := NewManager()
+	m.SetAuthConfigGetter(func() AuthConfig {
+		return AuthConfig{FilesystemGuardrails: true, GuardrailAllowedPaths: []string{"/tmp/cache"}}
+	})
+	session, err := m.CreateSession(t.TempDir())
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+	if err := m.SetSessionAdditionalPaths(session.ID, []string{backend}); err != nil {
+		t.Fatalf("SetSessionAdditionalPaths failed: %v", err)
+	}
+	if roots := session.Roots(); len(roots) != 2 || roots[1] != backend {
+		t.Errorf("Expected the backend as the second root, got %v", roots)
+	}
+
+	config, err := m.authConfigFor(session)
+	if err != nil {
+		t.Fatalf("authConfigFor failed: %v", err)
+	}
+	if !reflect.DeepEqual(config.AdditionalDirs, []string{backend}) {
+		t.Errorf("Expected the backend passed to claude, got %v", config.AdditionalDirs)
+	}
+	// The guardrails let the session write to every root
+	if !reflect.DeepEqual(config.GuardrailAllowedPaths, []string{"/tmp/cache", backend}) {
+		t.Errorf("Expected the backend allowed by the guardrails, got %v", config.GuardrailAllowedPaths)
+	}
+
+	// The paths are saved with the session
+	loaded, err := LoadSession(session.ID)
+	if err != nil {
+		t.Fatalf("LoadSession failed: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.GetAdditionalPaths(), []string{backend}) {
+		t.Errorf("Expected the additional paths restored, got %v", loaded.GetAdditionalPaths())
+	}
+}
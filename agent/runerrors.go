@@ -0,0 +1,275 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// Kinds of claude run failure. Errors from a run wrap one of these when the
+// cause is recognized, so callers can check them with errors.Is.
+var (
+	ErrCLIMissing     = errors.New("claude CLI not found; install it or set its path in settings")
+	ErrAuth           = errors.New("claude authentication failed")
+	ErrRateLimited    = errors.New("rate limited by the API")
+	ErrContextTooLong = errors.New("conversation is too long for the model's context window")
+	ErrProcessKilled  = errors.New("claude process was killed")
+)
+
+// stderrTailLines is how much of claude's stderr describes a failed run
+const stderrTailLines = 5
+
+// runErrorHints tell the user what to do about each kind of failure
+var runErrorHints = map[error]string{
+	ErrAuth:           "Check the API key or sign in again in Settings.",
+	ErrRateLimited:    "Wait a few minutes and send the message again.",
+	ErrContextTooLong: "Start a new session, or edit an earlier message to shorten the conversation.",
+}
+
+// Output that identifies each kind of failure, matched case-insensitively.
+// Rate limits use rateLimitMarkers. Status codes are only matched where
+// they're reported as one, so line numbers, sizes and ports don't count.
+var (
+	cliMissingMarkers = []string{
+		"command not found",
+		"is not recognized as an internal or external command",
+	}
+	authMarkers = []string{
+		"invalid api key",
+		"invalid x-api-key",
+		"authentication_error",
+		"authentication failed",
+		"oauth token has expired",
+		"please run /login",
+		"unauthorized",
+	}
+	authStatus            = statusCodePattern("401")
+	contextTooLongMarkers = []string{
+		"prompt is too long",
+		"context length",
+		"context window",
+		"context_length_exceeded",
+		"maximum context",
+		"too many tokens",
+	}
+	serverErrorMarkers = []string{
+		"api_error",
+		"internal server error",
+		"bad gateway",
+		"service unavailable",
+		"gateway timeout",
+	}
+	serverErrorStatus = statusCodePattern("500", "502", "503", "504")
+)
+
+// RunError is a failed claude run. Kind is one of the Err values above, or nil
+// if the cause wasn't recognized.
+type RunError struct {
+	Kind      error
+	Detail    string // What claude reported, if anything
+	ExitCode  int    // -1 if the process was ended by a signal
+	Transient bool   // Running the prompt again may succeed
+}
+
+func (e *RunError) Error() string {
+	switch {
+	case e.Kind != nil && e.Detail != "":
+		return e.Kind.Error() + ": " + e.Detail
+	case e.Kind != nil:
+		return e.Kind.Error()
+	case e.Detail != "":
+		return e.Detail
+	}
+	return fmt.Sprintf("claude exited with status %d", e.ExitCode)
+}
+
+func (e *RunError) Unwrap() error {
+	return e.Kind
+}
+
+// ErrorKind names the kind of a run failure for the UI: "cli_missing",
+// "auth", "rate_limited", "context_too_long", "process_killed", or "" when
+// the cause isn't recognized
+func ErrorKind(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrCLIMissing):
+		return "cli_missing"
+	case errors.Is(err, ErrAuth):
+		return "auth"
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrContextTooLong):
+		return "context_too_long"
+	case errors.Is(err, ErrProcessKilled):
+		return "process_killed"
+	}
+	return ""
+}
+
+// classifyRunFailure describes a run that reported an error or exited
+// unsuccessfully, from what claude reported and its exit code
+func classifyRunFailure(detail string, exitCode int) *RunError {
+	runErr := &RunError{Detail: strings.TrimSpace(detail), ExitCode: exitCode}
+
+	switch {
+	case exitCode == 127 || containsAny(detail, cliMissingMarkers):
+		runErr.Kind = ErrCLIMissing
+	case containsAny(detail, contextTooLongMarkers):
+		runErr.Kind = ErrContextTooLong
+	case containsAny(detail, authMarkers) || authStatus.MatchString(detail):
+		runErr.Kind = ErrAuth
+	case isRateLimitError(detail):
+		runErr.Kind = ErrRateLimited
+		runErr.Transient = true
+	case exitCode == -1 || exitCode == 128+9 || exitCode == 128+15:
+		// Ended by a signal, e.g. the OOM killer; runs we cancel aren't classified
+		runErr.Kind = ErrProcessKilled
+		runErr.Transient = true
+	case containsAny(detail, serverErrorMarkers) || serverErrorStatus.MatchString(detail) || isNetworkError(detail):
+		runErr.Transient = true
+	}
+	return runErr
+}
+
+// runErrorHint returns what the user can do about err, if anything
+func runErrorHint(err error) string {
+	for kind, hint := range runErrorHints {
+		if errors.Is(err, kind) {
+			return hint
+		}
+	}
+	return ""
+}
+
+// exitStatus returns the exit code of a finished process from its Wait error,
+// and whether the process failed
+func exitStatus(err error) (int, bool) {
+	if err == nil || errors.Is(err, exec.ErrWaitDelay) {
+		// A process that exited cleanly but left its output pipes open still succeeded
+		return 0, false
+	}
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), true
+	}
+	return -1, true
+}
+
+// classifyStartError marks a failure to start a binary that isn't there as ErrCLIMissing
+func classifyStartError(err error) error {
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %v", ErrCLIMissing, err)
+	}
+	return fmt.Errorf("failed to start claude: %w", err)
+}
+
+func containsAny(text string, markers []string) bool {
+	lower := strings.ToLower(text)
+	for _, marker := range markers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryPolicy controls how runs that fail transiently, e.g. on API server
+// errors or a crashed process, are retried before the session shows the error
+type RetryPolicy struct {
+	MaxRetries   int           // Zero uses the default and a negative value disables retrying
+	InitialDelay time.Duration // Doubles after each retry, up to MaxDelay
+	MaxDelay     time.Duration
+}
+
+// DefaultRetryPolicy is used for the fields of a RetryPolicy left at zero
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:   3,
+	InitialDelay: 2 * time.Second,
+	MaxDelay:     time.Minute,
+}
+
+// withDefaults fills the fields left at zero from DefaultRetryPolicy
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxRetries == 0 {
+		p.MaxRetries = DefaultRetryPolicy.MaxRetries
+	}
+	if p.InitialDelay <= 0 {
+		p.InitialDelay = DefaultRetryPolicy.InitialDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = DefaultRetryPolicy.MaxDelay
+	}
+	return p
+}
+
+// delay returns how long to wait before the given retry, counting from 1
+func (p RetryPolicy) delay(retry int) time.Duration {
+	wait := p.InitialDelay
+	for i := 1; i < retry && wait < p.MaxDelay; i++ {
+		wait *= 2
+	}
+	if wait > p.MaxDelay {
+		wait = p.MaxDelay
+	}
+	return wait
+}
+
+// LastError returns why the session's latest run failed, or nil if it didn't
+func (s *Session) LastError() error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lastError
+}
+
+// noteRunFailure records an error claude reported during the current run
+func (s *Session) noteRunFailure(text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.runFailure == "" {
+		s.runFailure = text
+	}
+}
+
+// failRun marks the session failed with a run's error. An error event that
+// already failed the session was shown when it arrived, so only the hint is added.
+func (s *Session) failRun(err error) {
+	s.mu.Lock()
+	shown := s.Status == SessionStatusError
+	if shown {
+		s.lastError = err
+	}
+	s.mu.Unlock()
+
+	if !shown {
+		s.handleError(err)
+	} else if hint := runErrorHint(err); hint != "" {
+		s.addSystemMessage(hint)
+	}
+}
+
+// waitForRetry waits out the delay before retrying a failed run. It returns
+// false if ctx is canceled first, i.e. the session is stopped or the run
+// canceled.
+func (s *Session) waitForRetry(ctx context.Context, wait time.Duration) bool {
+	// An error event may have marked the session failed
+	s.mu.Lock()
+	if s.Status == SessionStatusError {
+		s.setStatus(SessionStatusRunning)
+	}
+	s.mu.Unlock()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,251 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// exitError stands in for *exec.ExitError
+type exitError int
+
+func (e exitError) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+func (e exitError) ExitCode() int { return int(e) }
+
+// sequenceRunner plays one scripted run per start, repeating the last
+type sequenceRunner struct {
+	runs   []*fakeRunner
+	starts int
+}
+
+func (r *sequenceRunner) Start(ctx context.Context, spec ProcessSpec) (Process, error) {
+	run := r.runs[min(r.starts, len(r.runs)-1)]
+	r.starts++
+	return run.Start(ctx, spec)
+}
+
+func TestClassifyRunFailure(t *testing.T) {
+	tests := []struct {
+		name      string
+		detail    string
+		exitCode  int
+		kind      error
+		transient bool
+	}{
+		{"missing binary", "", 127, ErrCLIMissing, false},
+		{"windows missing binary", "'claude' is not recognized as an internal or external command", 1, ErrCLIMissing, false},
+		{"invalid key", "Invalid API key · Please run /login", 1, ErrAuth, false},
+		{"auth error", `API Error: 401 {"type":"error","error":{"type":"authentication_error"}}`, 1, ErrAuth, false},
+		{"context", "Prompt is too long", 1, ErrContextTooLong, false},
+		{"rate limit", "API Error: 429 rate_limit_error", 1, ErrRateLimited, true},
+		{"killed by signal", "", -1, ErrProcessKilled, true},
+		{"sigkill exit", "", 137, ErrProcessKilled, true},
+		{"server error", `API Error: 500 {"type":"error","error":{"type":"api_error"}}`, 1, nil, true},
+		{"connection lost", "API Error: Connection error.", 1, nil, true},
+		{"unknown", "something broke", 1, nil, false},
+		{"gateway status", "HTTP 503 Service Unavailable", 1, nil, true},
+		{"line number", "main.go:401: undefined: foo", 1, nil, false},
+		{"byte count", "read 5020 bytes then failed", 1, nil, false},
+		{"port", "dial tcp 127.0.0.1:5001: bind failed", 1, nil, false},
+		{"plain number", "500 tests failed", 1, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runErr := classifyRunFailure(tt.detail, tt.exitCode)
+			if runErr.Kind != tt.kind {
+				t.Errorf("Expected kind %v, got %v", tt.kind, runErr.Kind)
+			}
+			if runErr.Transient != tt.transient {
+				t.Errorf("Expected transient %v, got %v", tt.transient, runErr.Transient)
+			}
+			if tt.kind != nil && !errors.Is(runErr, tt.kind) {
+				t.Errorf("Expected errors.Is to match %v", tt.kind)
+			}
+		})
+	}
+}
+
+func TestRunErrorMessage(t *testing.T) {
+	tests := []struct {
+		err      *RunError
+		expected string
+	}{
+		{&RunError{Kind: ErrAuth, Detail: "Invalid API key"}, "claude authentication failed: Invalid API key"},
+		{&RunError{Kind: ErrProcessKilled, ExitCode: -1}, "claude process was killed"},
+		{&RunError{Detail: "something broke", ExitCode: 1}, "something broke"},
+		{&RunError{ExitCode: 2}, "claude exited with status 2"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.expected {
+			t.Errorf("Expected %q, got %q", tt.expected, got)
+		}
+	}
+}
+
+func TestErrorKind(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{nil, ""},
+		{fmt.Errorf("%w at /opt/claude", ErrCLIMissing), "cli_missing"},
+		{&RunError{Kind: ErrAuth}, "auth"},
+		{&RunError{Kind: ErrRateLimited}, "rate_limited"},
+		{&RunError{Kind: ErrContextTooLong}, "context_too_long"},
+		{&RunError{Kind: ErrProcessKilled}, "process_killed"},
+		{errors.New("other"), ""},
+	}
+
+	for _, tt := range tests {
+		if got := ErrorKind(tt.err); got != tt.expected {
+			t.Errorf("ErrorKind(%v): expected %q, got %q", tt.err, tt.expected, got)
+		}
+	}
+}
+
+func TestExitStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		code     int
+		expected bool
+	}{
+		{"success", nil, 0, false},
+		{"pipes left open", exec.ErrWaitDelay, 0, false},
+		{"failed exit", exitError(1), 1, true},
+		{"wrapped exit", fmt.Errorf("wait: %w", exitError(127)), 127, true},
+		{"other error", errors.New("broken pipe"), -1, true},
+	}
+
+	for _, tt := range tests {
+		code, failed := exitStatus(tt.err)
+		if code != tt.code || failed != tt.expected {
+			t.Errorf("%s: expected %d, %v, got %d, %v", tt.name, tt.code, tt.expected, code, failed)
+		}
+	}
+}
+
+func TestClassifyStartError(t *testing.T) {
+	if err := classifyStartError(exec.ErrNotFound); !errors.Is(err, ErrCLIMissing) {
+		t.Errorf("Expected ErrCLIMissing, got %v", err)
+	}
+	if err := classifyStartError(errors.New("permission denied")); errors.Is(err, ErrCLIMissing) {
+		t.Errorf("Expected a plain start failure, got %v", err)
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 5, InitialDelay: time.Second, MaxDelay: 5 * time.Second}
+	expected := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, want := range expected {
+		if got := policy.delay(i + 1); got != want {
+			t.Errorf("Retry %d: expected %v, got %v", i+1, want, got)
+		}
+	}
+
+	defaults := RetryPolicy{}.withDefaults()
+	if defaults != DefaultRetryPolicy {
+		t.Errorf("Expected the default policy, got %+v", defaults)
+	}
+	if disabled := (RetryPolicy{MaxRetries: -1}).withDefaults(); disabled.MaxRetries != -1 {
+		t.Errorf("Expected retrying to stay disabled, got %d", disabled.MaxRetries)
+	}
+}
+
+func TestRunClaudeCommandFailure(t *testing.T) {
+	runner := &fakeRunner{
+		stderr:  "Invalid API key · Please run /login\n",
+		waitErr: exitError(1),
+	}
+	session := NewSession("test-run-failure", "/tmp/project")
+	session.runner = runner
+
+	result := session.runClaudeCommand(context.Background(), "hi", AuthConfig{ClaudeCLIPath: fakeClaudeBinary(t)})
+	if result != runCompleted {
+		t.Errorf("Expected an auth failure not to be retried, got %v", result)
+	}
+	if session.Status != SessionStatusError {
+		t.Errorf("Expected status error, got %s", session.Status)
+	}
+	if err := session.LastError(); !errors.Is(err, ErrAuth) {
+		t.Errorf("Expected ErrAuth, got %v", err)
+	}
+
+	found := false
+	for _, msg := range session.GetMessages() {
+		found = found || strings.Contains(msg.Content, runErrorHints[ErrAuth])
+	}
+	if !found {
+		t.Error("Expected the auth hint to be shown")
+	}
+}
+
+func TestRunWithRetry(t *testing.T) {
+	binary := fakeClaudeBinary(t)
+	serverError := &fakeRunner{
+		stdout:  `{"type":"result","is_error":true,"result":"API Error: 500 {\"type\":\"error\",\"error\":{\"type\":\"api_error\"}}"}` + "\n",
+		waitErr: exitError(1),
+	}
+	success := &fakeRunner{
+		stdout: `{"type":"result","subtype":"success","result":"Done"}` + "\n",
+	}
+	policy := RetryPolicy{MaxRetries: 2, InitialDelay: time.Millisecond}
+
+	t.Run("transient failure is retried", func(t *testing.T) {
+		runner := &sequenceRunner{runs: []*fakeRunner{serverError, success}}
+		session := NewSession("test-retry", "/tmp/project")
+		session.runner = runner
+
+		session.runWithRateLimitRetry("hi", AuthConfig{ClaudeCLIPath: binary, RetryPolicy: policy})
+
+		if runner.starts != 2 {
+			t.Errorf("Expected 2 runs, got %d", runner.starts)
+		}
+		if session.Status != SessionStatusIdle {
+			t.Errorf("Expected status idle, got %s", session.Status)
+		}
+		if err := session.LastError(); err != nil {
+			t.Errorf("Expected no error after the retry succeeded, got %v", err)
+		}
+	})
+
+	t.Run("retries run out", func(t *testing.T) {
+		runner := &sequenceRunner{runs: []*fakeRunner{serverError}}
+		session := NewSession("test-retry-exhausted", "/tmp/project")
+		session.runner = runner
+
+		session.runWithRateLimitRetry("hi", AuthConfig{ClaudeCLIPath: binary, RetryPolicy: policy})
+
+		if runner.starts != 3 {
+			t.Errorf("Expected 3 runs, got %d", runner.starts)
+		}
+		if session.Status != SessionStatusError {
+			t.Errorf("Expected status error, got %s", session.Status)
+		}
+		var runErr *RunError
+		if !errors.As(session.LastError(), &runErr) || !runErr.Transient {
+			t.Errorf("Expected the transient run error, got %v", session.LastError())
+		}
+	})
+
+	t.Run("retrying disabled", func(t *testing.T) {
+		runner := &sequenceRunner{runs: []*fakeRunner{serverError, success}}
+		session := NewSession("test-retry-disabled", "/tmp/project")
+		session.runner = runner
+
+		session.runWithRateLimitRetry("hi", AuthConfig{ClaudeCLIPath: binary, RetryPolicy: RetryPolicy{MaxRetries: -1}})
+
+		if runner.starts != 1 {
+			t.Errorf("Expected 1 run, got %d", runner.starts)
+		}
+		if session.Status != SessionStatusError {
+			t.Errorf("Expected status error, got %s", session.Status)
+		}
+	})
+}
@@ -0,0 +1,207 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// processWaitDelay bounds how long Wait blocks on output pipes after a killed
+// process exits, e.g. when a grandchild still holds them open
+const processWaitDelay = 5 * time.Second
+
+// ProcessSpec describes a process to start
+type ProcessSpec struct {
+	Path string // Resolved path of the binary
+	Args []string
+	Dir  string
+	Env  []string // Full environment; nil inherits the app's
+}
+
+// Process is a started process and its standard streams
+type Process interface {
+	Stdin() io.WriteCloser
+	Stdout() io.Reader
+	Stderr() io.Reader
+	// Wait waits for the process to exit. Stdout and Stderr must be read to
+	// the end first.
+	Wait() error
+}
+
+// ProcessRunner starts processes for a session. Cancelling the context kills
+// the process along with anything it spawned.
+type ProcessRunner interface {
+	Start(ctx context.Context, spec ProcessSpec) (Process, error)
+}
+
+// ExecRunner runs processes on the local machine
+type ExecRunner struct{}
+
+// Start implements ProcessRunner
+func (ExecRunner) Start(ctx context.Context, spec ProcessSpec) (Process, error) {
+	name, args, err := commandLine(runtime.GOOS, spec.Path, spec.Args)
+	if err != nil {
+		return nil, err
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = spec.Dir
+	cmd.Env = spec.Env
+	cmd.WaitDelay = processWaitDelay
+
+	// Run in a process group of its own so cancelling also stops the tools claude started
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error { return killProcessGroup(cmd) }
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdout pipe: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stderr pipe: %w", err)
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create stdin pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &execProcess{cmd: cmd, stdin: stdin, stdout: stdout, stderr: stderr}, nil
+}
+
+type execProcess struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.Reader
+	stderr io.Reader
+}
+
+func (p *execProcess) Stdin() io.WriteCloser { return p.stdin }
+func (p *execProcess) Stdout() io.Reader     { return p.stdout }
+func (p *execProcess) Stderr() io.Reader     { return p.stderr }
+func (p *execProcess) Wait() error           { return p.cmd.Wait() }
+
+// commandLine returns the program and arguments that run path on goos. On
+// Windows, npm installs claude as a .cmd shim (or a PowerShell script) that
+// runs node on the package's cli.js. Starting the shim would have cmd.exe
+// parse the prompt and settings JSON as part of a command line, so the node
+// and script it runs are started directly instead.
+func commandLine(goos, path string, args []string) (string, []string, error) {
+	if goos != "windows" {
+		return path, args, nil
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".cmd", ".bat", ".ps1":
+		node, script, err := resolveNodeShim(path)
+		if err != nil {
+			return "", nil, err
+		}
+		return node, append([]string{script}, args...), nil
+	}
+	return path, args, nil
+}
+
+// resolveNodeShim returns the node executable and script an npm shim for
+// claude runs
+func resolveNodeShim(path string) (string, string, error) {
+	dir := filepath.Dir(path)
+	script := filepath.Join(dir, "node_modules", "@anthropic-ai", "claude-code", "cli.js")
+	if _, err := os.Stat(script); err != nil {
+		return "", "", fmt.Errorf("can't find the script %s runs; set the Claude CLI path to claude.exe instead", path)
+	}
+
+	// Like the shim, prefer a node installed next to it
+	node := filepath.Join(dir, "node.exe")
+	if _, err := os.Stat(node); err != nil {
+		if node, err = exec.LookPath("node"); err != nil {
+			return "", "", fmt.Errorf("can't find node to run %s: %w", script, err)
+		}
+	}
+	return node, script, nil
+}
+
+// ResolveClaudeCLI returns the claude binary to run. A configured path must
+// exist; otherwise claude is looked up on PATH and then in the usual install
+// locations, since apps started from the desktop don't get the shell's PATH.
+func ResolveClaudeCLI(configured string) (string, error) {
+	home, _ := os.UserHomeDir()
+
+	if configured = strings.TrimSpace(configured); configured != "" {
+		if strings.HasPrefix(configured, "~") && home != "" {
+			configured = filepath.Join(home, configured[1:])
+		}
+		info, err := os.Stat(configured)
+		if err != nil || info.IsDir() {
+			return "", fmt.Errorf("%w at %s", ErrCLIMissing, configured)
+		}
+		return configured, nil
+	}
+
+	if path, err := exec.LookPath("claude"); err == nil {
+		return path, nil
+	}
+	for _, candidate := range claudeCLICandidates(runtime.GOOS, home, os.Getenv) {
+		if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+	}
+	return "", ErrCLIMissing
+}
+
+// claudeEnv returns the environment for running claude with the configured auth method
+func claudeEnv(authConfig AuthConfig) []string {
+	env := os.Environ()
+	if authConfig.Method == "google-cloud" {
+		if authConfig.GCPProjectID != "" {
+			env = append(env, "CLOUD_ML_PROJECT_ID="+authConfig.GCPProjectID)
+		}
+		if authConfig.GCPRegion != "" {
+			env = append(env, "CLOUD_ML_REGION="+authConfig.GCPRegion)
+		}
+	} else {
+		// Use Anthropic API key authentication
+		if authConfig.APIKey != "" {
+			env = append(env, "ANTHROPIC_API_KEY="+authConfig.APIKey)
+		}
+	}
+	return env
+}
+
+// claudeCLICandidates lists where the claude installers put the binary
+func claudeCLICandidates(goos, home string, getenv func(string) string) []string {
+	var candidates []string
+	if goos == "windows" {
+		if appData := getenv("APPDATA"); appData != "" {
+			candidates = append(candidates,
+				filepath.Join(appData, "npm", "claude.cmd"),
+				filepath.Join(appData, "npm", "claude.ps1"))
+		}
+		if home != "" {
+			candidates = append(candidates,
+				filepath.Join(home, ".local", "bin", "claude.exe"),
+				filepath.Join(home, ".claude", "local", "claude.cmd"))
+		}
+		return candidates
+	}
+
+	if home != "" {
+		candidates = append(candidates,
+			filepath.Join(home, ".claude", "local", "claude"),
+			filepath.Join(home, ".local", "bin", "claude"),
+			filepath.Join(home, ".npm-global", "bin", "claude"))
+	}
+	candidates = append(candidates, "/usr/local/bin/claude")
+	if goos == "darwin" {
+		candidates = append(candidates, "/opt/homebrew/bin/claude")
+	}
+	return candidates
+}
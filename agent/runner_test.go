@@ -0,0 +1,209 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeRunner records what it was asked to start and replays scripted output
+type fakeRunner struct {
+	spec    ProcessSpec
+	stdin   bytes.Buffer
+	stdout  string
+	stderr  string
+	waitErr error // Returned by Wait, e.g. for a failed exit
+	starts  int
+}
+
+func (r *fakeRunner) Start(ctx context.Context, spec ProcessSpec) (Process, error) {
+	r.spec = spec
+	r.starts++
+	return &fakeProcess{runner: r}, nil
+}
+
+type fakeProcess struct {
+	runner *fakeRunner
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func (p *fakeProcess) Stdin() io.WriteCloser { return nopWriteCloser{&p.runner.stdin} }
+func (p *fakeProcess) Stdout() io.Reader     { return strings.NewReader(p.runner.stdout) }
+func (p *fakeProcess) Stderr() io.Reader     { return strings.NewReader(p.runner.stderr) }
+func (p *fakeProcess) Wait() error           { return p.runner.waitErr }
+
+// fakeClaudeBinary creates an empty file to stand in for the claude binary
+func fakeClaudeBinary(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "claude")
+	if err := os.WriteFile(path, nil, 0755); err != nil {
+		t.Fatalf("Failed to create fake binary: %v", err)
+	}
+	return path
+}
+
+func TestCommandLine(t *testing.T) {
+	// An npm install: the shims, node, and the package they run
+	npm := t.TempDir()
+	script := filepath.Join(npm, "node_modules", "@anthropic-ai", "claude-code", "cli.js")
+	if err := os.MkdirAll(filepath.Dir(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{script, filepath.Join(npm, "node.exe"), filepath.Join(npm, "claude.cmd")} {
+		if err := os.WriteFile(name, nil, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	node := filepath.Join(npm, "node.exe")
+
+	tests := []struct {
+		name         string
+		goos         string
+		path         string
+		expectedName string
+		expectedArgs []string
+	}{
+		{"unix binary", "linux", "/usr/local/bin/claude", "/usr/local/bin/claude", []string{"-p"}},
+		{"windows exe", "windows", `C:\bin\claude.exe`, `C:\bin\claude.exe`, []string{"-p"}},
+		{"windows cmd shim", "windows", filepath.Join(npm, "claude.cmd"), node, []string{script, "-p"}},
+		{"windows batch file", "windows", filepath.Join(npm, "CLAUDE.BAT"), node, []string{script, "-p"}},
+		{"windows powershell script", "windows", filepath.Join(npm, "claude.ps1"), node, []string{script, "-p"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, args, err := commandLine(tt.goos, tt.path, []string{"-p"})
+			if err != nil {
+				t.Fatalf("commandLine failed: %v", err)
+			}
+			if name != tt.expectedName {
+				t.Errorf("Expected program %q, got %q", tt.expectedName, name)
+			}
+			if !reflect.DeepEqual(args, tt.expectedArgs) {
+				t.Errorf("Expected args %v, got %v", tt.expectedArgs, args)
+			}
+		})
+	}
+
+	// A shim that doesn't run the package isn't started through cmd.exe
+	other := filepath.Join(t.TempDir(), "claude.cmd")
+	if name, _, err := commandLine("windows", other, []string{"-p"}); err == nil {
+		t.Errorf("Expected an error for an unknown shim, got %q", name)
+	}
+}
+
+func TestClaudeCLICandidates(t *testing.T) {
+	getenv := func(key string) string {
+		if key == "APPDATA" {
+			return "appdata"
+		}
+		return ""
+	}
+
+	windows := claudeCLICandidates("windows", "home", getenv)
+	if len(windows) == 0 || windows[0] != filepath.Join("appdata", "npm", "claude.cmd") {
+		t.Errorf("Expected the npm shim first on Windows, got %v", windows)
+	}
+
+	darwin := claudeCLICandidates("darwin", "home", getenv)
+	if darwin[0] != filepath.Join("home", ".claude", "local", "claude") {
+		t.Errorf("Expected the local install first, got %v", darwin)
+	}
+	if darwin[len(darwin)-1] != "/opt/homebrew/bin/claude" {
+		t.Errorf("Expected Homebrew's prefix on macOS, got %v", darwin)
+	}
+	for _, path := range claudeCLICandidates("linux", "home", getenv) {
+		if strings.Contains(path, "homebrew") {
+			t.Errorf("Expected no Homebrew path on Linux, got %s", path)
+		}
+	}
+}
+
+func TestResolveClaudeCLIConfigured(t *testing.T) {
+	binary := fakeClaudeBinary(t)
+
+	path, err := ResolveClaudeCLI("  " + binary + " ")
+	if err != nil {
+		t.Fatalf("ResolveClaudeCLI failed: %v", err)
+	}
+	if path != binary {
+		t.Errorf("Expected %s, got %s", binary, path)
+	}
+
+	for _, missing := range []string{filepath.Join(t.TempDir(), "nope"), t.TempDir()} {
+		if _, err := ResolveClaudeCLI(missing); !errors.Is(err, ErrCLIMissing) {
+			t.Errorf("Expected ErrCLIMissing for %s, got %v", missing, err)
+		}
+	}
+}
+
+func TestRunClaudeCommandUsesRunner(t *testing.T) {
+	binary := fakeClaudeBinary(t)
+	runner := &fakeRunner{
+		stdout: strings.Join([]string{
+			`{"type":"system","subtype":"init","session_id":"conv-42"}`,
+			`{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Hello from the runner"}]}}`,
+			`{"type":"result","subtype":"success","result":"Hello from the runner","session_id":"conv-42"}`,
+		}, "\n") + "\n",
+	}
+
+	session := NewSession("test-runner", "/tmp/project")
+	session.runner = runner
+
+	result := session.runClaudeCommand(context.Background(), "Say hello", AuthConfig{
+		APIKey:        "sk-test",
+		ClaudeCLIPath: binary,
+	})
+	if result != runCompleted {
+		t.Errorf("Expected the run to complete, got %v", result)
+	}
+
+	if runner.spec.Path != binary || runner.spec.Dir != "/tmp/project" {
+		t.Errorf("Expected claude to start in the project, got %+v", runner.spec)
+	}
+	hasKey := false
+	for _, kv := range runner.spec.Env {
+		hasKey = hasKey || kv == "ANTHROPIC_API_KEY=sk-test"
+	}
+	if !hasKey {
+		t.Error("Expected the API key in the environment")
+	}
+	if !strings.Contains(runner.stdin.String(), "Say hello") {
+		t.Errorf("Expected the prompt on stdin, got %q", runner.stdin.String())
+	}
+
+	if session.conversationID != "conv-42" {
+		t.Errorf("Expected conversation ID conv-42, got %q", session.conversationID)
+	}
+	found := false
+	for _, msg := range session.GetMessages() {
+		found = found || (msg.Role == "assistant" && strings.Contains(msg.Content, "Hello from the runner"))
+	}
+	if !found {
+		t.Errorf("Expected the assistant reply, got %v", session.GetMessages())
+	}
+}
+
+func TestRunClaudeCommandMissingCLI(t *testing.T) {
+	runner := &fakeRunner{}
+	session := NewSession("test-missing-cli", "/tmp/project")
+	session.runner = runner
+
+	session.runClaudeCommand(context.Background(), "hi", AuthConfig{ClaudeCLIPath: filepath.Join(t.TempDir(), "claude")})
+
+	if runner.spec.Path != "" {
+		t.Error("Expected nothing to be started")
+	}
+	if session.Status != SessionStatusError {
+		t.Errorf("Expected status error, got %s", session.Status)
+	}
+}
@@ -0,0 +1,32 @@
+//go:build !windows
+
+package agent
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestExecRunnerKillsProcessGroup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// The background sleep inherits stdout, so the pipe only closes once the
+	// whole group is killed
+	proc, err := ExecRunner{}.Start(ctx, ProcessSpec{Path: "/bin/sh", Args: []string{"-c", "sleep 30 & sleep 30"}})
+	if err != nil {
+		t.Fatalf("Start failed: %v", err)
+	}
+
+	start := time.Now()
+	cancel()
+	io.ReadAll(proc.Stdout())
+	io.ReadAll(proc.Stderr())
+	proc.Wait()
+
+	if elapsed := time.Since(start); elapsed > processWaitDelay/2 {
+		t.Errorf("Expected the process group to be killed promptly, took %v", elapsed)
+	}
+}
@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// protects reports whether path matches one of the guard's protected globs
+func (g PathGuard) protects(target string) bool {
+	if len(g.Protected) == 0 {
+		return false
+	}
+
+	resolved := g.resolve(target)
+	rel := resolved
+	if root := g.resolve(g.Root); isWithin(root, resolved) {
+		rel, _ = filepath.Rel(root, resolved)
+	}
+	rel = filepath.ToSlash(rel)
+
+	for _, pattern := range g.Protected {
+		if matchProtected(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchProtected reports whether a path, relative to the project root when
+// inside it, matches a protected glob. A glob without a slash matches any
+// file of that name, or with a trailing slash any directory, e.g. ".env" or
+// "secrets/". A glob with a slash matches from the project root and covers
+// everything under what it names, e.g. "config/*.key" or "deploy/prod/".
+func matchProtected(pattern, rel string) bool {
+	pattern = filepath.ToSlash(strings.TrimSpace(pattern))
+	if pattern == "" {
+		return false
+	}
+	dir := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	parts := strings.Split(rel, "/")
+
+	if !strings.Contains(pattern, "/") {
+		names := parts
+		if dir {
+			names = parts[:len(parts)-1]
+		}
+		for _, name := range names {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+		return false
+	}
+
+	n := strings.Count(pattern, "/") + 1
+	if len(parts) < n || (dir && len(parts) == n) {
+		return false
+	}
+	ok, _ := path.Match(pattern, strings.Join(parts[:n], "/"))
+	return ok
+}
+
+// ValidateProtectedPath checks a protected path glob is well formed
+func ValidateProtectedPath(pattern string) error {
+	if strings.TrimSpace(pattern) == "" {
+		return fmt.Errorf("protected path can't be empty")
+	}
+	if _, err := path.Match(filepath.ToSlash(strings.TrimSuffix(pattern, "/")), ""); err != nil {
+		return fmt.Errorf("invalid protected path %q: %w", pattern, err)
+	}
+	return nil
+}
+
+// warnProtectedWrite adds a warning when a tool call waiting for approval
+// would modify a protected path, which is why it is waiting even in full-auto
+func (s *Session) warnProtectedWrite(toolName string, input map[string]any) {
+	s.mu.RLock()
+	guard := PathGuard{Root: s.ProjectPath, Protected: s.protectedPaths, Unconfined: true}
+	s.mu.RUnlock()
+	if len(guard.Protected) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(input)
+	if err != nil {
+		return
+	}
+	if decision := guard.CheckToolUse(toolName, data); decision.Ask {
+		s.addSystemMessage(fmt.Sprintf("🛡️  %s wants to modify %s, a protected path. It won't run unless you approve it.", toolName, decision.Path))
+	}
+}
@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestMatchProtected(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{".env", ".env", true},
+		{".env", "services/api/.env", true},
+		{".env", ".envrc", false},
+		{".env.*", "config/.env.production", true},
+		{"*.pem", "certs/server.pem", true},
+		{"secrets/", "secrets/db.yaml", true},
+		{"secrets/", "deploy/secrets/token", true},
+		{"secrets/", "secrets", false},
+		{"config/*.key", "config/signing.key", true},
+		{"config/*.key", "other/config/signing.key", false},
+		{"deploy/prod/", "deploy/prod/values.yaml", true},
+		{"deploy/prod/", "deploy/staging/values.yaml", false},
+		{"/etc/", "/etc/hosts", true},
+		{"", "main.go", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			if got := matchProtected(tt.pattern, tt.path); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestPathGuardProtected(t *testing.T) {
+	root := t.TempDir()
+	guard := PathGuard{Root: root, Protected: []string{".env", "secrets/"}, Unconfined: true}
+
+	tests := []struct {
+		name  string
+		tool  string
+		input map[string]interface{}
+		ask   bool
+	}{
+		{"edit protected file", "Edit", map[string]interface{}{"file_path": root + "/.env"}, true},
+		{"write relative protected dir", "Write", map[string]interface{}{"file_path": "secrets/token"}, true},
+		{"bash redirect into protected", "Bash", map[string]interface{}{"command": "echo KEY=1 >> .env"}, true},
+		{"ordinary write", "Write", map[string]interface{}{"file_path": "main.go"}, false},
+		{"unconfined write outside", "Write", map[string]interface{}{"file_path": "/tmp/elsewhere/x.go"}, false},
+		{"read is not guarded", "Read", map[string]interface{}{"file_path": ".env"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input, _ := json.Marshal(tt.input)
+			decision := guard.CheckToolUse(tt.tool, input)
+			if decision.Ask != tt.ask || decision.Allowed == tt.ask {
+				t.Errorf("Expected ask=%v, got %+v", tt.ask, decision)
+			}
+		})
+	}
+
+	// Confinement still blocks outright when it's on
+	guard.Unconfined = false
+	input, _ := json.Marshal(map[string]string{"file_path": "/tmp/elsewhere/.env"})
+	if decision := guard.CheckToolUse("Write", input); decision.Allowed || decision.Ask {
+		t.Errorf("Expected a write outside the root to be denied, got %+v", decision)
+	}
+}
+
+func TestRunGuardHookAsksForProtectedPaths(t *testing.T) {
+	guard := PathGuard{Root: t.TempDir(), Protected: []string{".env"}, Unconfined: true}
+
+	var out bytes.Buffer
+	in := `{"tool_name":"Write","tool_input":{"file_path":".env"}}`
+	if err := RunGuardHook(guard, strings.NewReader(in), &out); err != nil {
+		t.Fatalf("RunGuardHook failed: %v", err)
+	}
+	if !strings.Contains(out.String(), `"permissionDecision":"ask"`) {
+		t.Errorf("Expected an ask decision, got %s", out.String())
+	}
+}
+
+func TestProtectedWriteWarning(t *testing.T) {
+	s, _, _ := newApprovalSession(t)
+	s.protectedPaths = []string{".env"}
+
+	parseLine(s, permissionRequest("req-1", "Write", map[string]any{"file_path": "/project/main.go"}))
+	parseLine(s, permissionRequest("req-2", "Edit", map[string]any{"file_path": "/project/.env"}))
+
+	var warnings []string
+	for _, msg := range s.GetMessages() {
+		if msg.Role == "system" && strings.Contains(msg.Content, "protected path") {
+			warnings = append(warnings, msg.Content)
+		}
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "/project/.env") {
+		t.Errorf("Expected one warning about .env, got %q", warnings)
+	}
+	if len(s.PendingActions()) != 2 {
+		t.Errorf("Expected both calls to wait for approval, got %d", len(s.PendingActions()))
+	}
+}
+
+func TestBuildClaudeArgsProtectedPaths(t *testing.T) {
+	args := buildClaudeArgs("", "", AuthConfig{ApprovalMode: "full-auto", ProtectedPaths: []string{".env"}}, "")
+	expected := []string{"--permission-mode", "bypassPermissions", "--permission-prompt-tool", "stdio"}
+	if got := args[len(args)-len(expected):]; strings.Join(got, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected args ending %q, got %q", expected, args)
+	}
+}
+
+func TestGuardHookSettingsProtected(t *testing.T) {
+	settings, err := guardHookSettings("/bin/boatman", PathGuard{Root: "/work", Protected: []string{"secrets/"}, Unconfined: true})
+	if err != nil {
+		t.Fatalf("guardHookSettings failed: %v", err)
+	}
+	if !strings.Contains(settings, `--root '/work' --protect 'secrets/' --unconfined`) {
+		t.Errorf("Expected the protect and unconfined flags, got %s", settings)
+	}
+}
@@ -0,0 +1,268 @@
+package agent
+
+import (
+	"context"
+	"sync"
+)
+
+// DefaultMaxConcurrentRuns is how many sessions may run claude at once unless configured
+const DefaultMaxConcurrentRuns = 4
+
+// RunScheduler limits how many sessions run claude at once. Runs over the
+// limit wait for a slot in the order they asked for one.
+type RunScheduler struct {
+	mu      sync.Mutex
+	limit   int // Negative means no limit
+	running int
+	waiting []*runWaiter
+}
+
+// runWaiter is a run queued for a slot
+type runWaiter struct {
+	sessionID string
+	ready     chan struct{} // Closed once the run holds a slot
+	granted   bool
+	// onPosition is told the waiter's place in the queue, starting at 1
+	onPosition func(position int)
+}
+
+// NewRunScheduler creates a scheduler allowing limit runs at once. Zero uses
+// the default and a negative limit runs everything immediately.
+func NewRunScheduler(limit int) *RunScheduler {
+	r := &RunScheduler{}
+	r.SetLimit(limit)
+	return r
+}
+
+// SetLimit changes how many runs may run at once; see NewRunScheduler.
+// Raising it starts queued runs straight away.
+func (r *RunScheduler) SetLimit(limit int) {
+	if limit == 0 {
+		limit = DefaultMaxConcurrentRuns
+	}
+
+	r.mu.Lock()
+	r.limit = limit
+	notify := r.admitLocked()
+	r.mu.Unlock()
+
+	notifyPositions(notify)
+}
+
+// Limit returns how many runs may run at once, or a negative number for no limit
+func (r *RunScheduler) Limit() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.limit
+}
+
+// Acquire waits for a slot for a session's run. onPosition, which may be nil,
+// is called with the run's place in the queue, starting at 1, when it queues
+// and as the queue moves; it isn't called if a slot is free straight away.
+// Updates can race with Acquire returning, so callers should ignore them once
+// it has. The returned function gives the slot back. Acquire fails only if
+// ctx is done first.
+func (r *RunScheduler) Acquire(ctx context.Context, sessionID string, onPosition func(position int)) (func(), error) {
+	r.mu.Lock()
+	if r.hasSlotLocked() && len(r.waiting) == 0 {
+		r.running++
+		r.mu.Unlock()
+		return r.releaseFunc(), nil
+	}
+
+	waiter := &runWaiter{
+		sessionID:  sessionID,
+		ready:      make(chan struct{}),
+		onPosition: onPosition,
+	}
+	r.waiting = append(r.waiting, waiter)
+	position := len(r.waiting)
+	r.mu.Unlock()
+
+	if onPosition != nil {
+		onPosition(position)
+	}
+
+	select {
+	case <-waiter.ready:
+		return r.releaseFunc(), nil
+	case <-ctx.Done():
+	}
+
+	r.mu.Lock()
+	if waiter.granted {
+		// The slot arrived as the wait was abandoned; pass it on
+		r.running--
+	} else {
+		r.removeWaiterLocked(waiter)
+	}
+	notify := r.admitLocked()
+	r.mu.Unlock()
+
+	notifyPositions(notify)
+	return nil, ctx.Err()
+}
+
+// Position returns a session's place in the queue, starting at 1, or 0 if it isn't waiting
+func (r *RunScheduler) Position(sessionID string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, waiter := range r.waiting {
+		if waiter.sessionID == sessionID {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// Stats returns how many runs hold a slot and how many are waiting for one
+func (r *RunScheduler) Stats() (running, queued int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.running, len(r.waiting)
+}
+
+// releaseFunc returns the function that gives back a slot; calling it again does nothing
+func (r *RunScheduler) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r.mu.Lock()
+			r.running--
+			notify := r.admitLocked()
+			r.mu.Unlock()
+
+			notifyPositions(notify)
+		})
+	}
+}
+
+// hasSlotLocked reports whether another run may start.
+// Note: This method expects the caller to hold r.mu lock
+func (r *RunScheduler) hasSlotLocked() bool {
+	return r.limit < 0 || r.running < r.limit
+}
+
+// removeWaiterLocked drops a waiter from the queue.
+// Note: This method expects the caller to hold r.mu lock
+func (r *RunScheduler) removeWaiterLocked(waiter *runWaiter) {
+	for i, w := range r.waiting {
+		if w == waiter {
+			r.waiting = append(r.waiting[:i], r.waiting[i+1:]...)
+			return
+		}
+	}
+}
+
+// admitLocked hands free slots to the front of the queue. It returns the
+// positions to report to the runs still waiting once the lock is released.
+// Note: This method expects the caller to hold r.mu lock
+func (r *RunScheduler) admitLocked() []positionUpdate {
+	admitted := 0
+	for admitted < len(r.waiting) && r.hasSlotLocked() {
+		waiter := r.waiting[admitted]
+		waiter.granted = true
+		close(waiter.ready)
+		r.running++
+		admitted++
+	}
+
+	r.waiting = r.waiting[admitted:]
+	var updates []positionUpdate
+	for i, waiter := range r.waiting {
+		updates = append(updates, positionUpdate{waiter.onPosition, i + 1})
+	}
+	return updates
+}
+
+// positionUpdate is a queue position to report to a waiter
+type positionUpdate struct {
+	onPosition func(int)
+	position   int
+}
+
+// notifyPositions reports queue positions, outside the scheduler's lock
+func notifyPositions(updates []positionUpdate) {
+	for _, update := range updates {
+		if update.onPosition != nil {
+			update.onPosition(update.position)
+		}
+	}
+}
+
+// SetRunScheduler sets the limit on runs shared with other sessions; nil starts runs immediately
+func (s *Session) SetRunScheduler(scheduler *RunScheduler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.scheduler = scheduler
+}
+
+// SetQueueHandler sets the callback for the session's place in the run queue,
+// which is 0 once its run starts
+func (s *Session) SetQueueHandler(handler func(position int)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onQueue = handler
+}
+
+// QueuePosition returns the session's place in the run queue, or 0 if it isn't waiting for a slot
+func (s *Session) QueuePosition() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.queuePosition
+}
+
+// waitForRunSlot waits until the scheduler lets the session run claude,
+// showing the queued status meanwhile. The returned function frees the slot.
+func (s *Session) waitForRunSlot(ctx context.Context) (func(), error) {
+	s.mu.RLock()
+	scheduler := s.scheduler
+	s.mu.RUnlock()
+	if scheduler == nil {
+		return func() {}, nil
+	}
+
+	var mu sync.Mutex
+	waited := false // Acquire returned; later position updates are stale
+	release, err := scheduler.Acquire(ctx, s.ID, func(position int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !waited {
+			s.setQueuePosition(position, SessionStatusQueued)
+		}
+	})
+	mu.Lock()
+	waited = true
+	mu.Unlock()
+
+	if err != nil {
+		// Leave the status for the turn to settle
+		s.setQueuePosition(0, "")
+		return nil, err
+	}
+	s.setQueuePosition(0, SessionStatusRunning)
+	return release, nil
+}
+
+// setQueuePosition records the session's place in the run queue. A non-empty
+// status replaces the queued status, or sets it when the session starts waiting.
+func (s *Session) setQueuePosition(position int, status SessionStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if position == s.queuePosition {
+		return
+	}
+	s.queuePosition = position
+	if status == SessionStatusQueued || (status != "" && s.Status == SessionStatusQueued) {
+		s.setStatus(status)
+	}
+	if s.onQueue != nil {
+		s.onQueue(position)
+	}
+}
+
+// SetMaxConcurrentRuns sets how many sessions may run claude at once. Zero
+// uses the default and a negative value removes the limit.
+func (m *Manager) SetMaxConcurrentRuns(limit int) {
+	m.scheduler.SetLimit(limit)
+}
@@ -0,0 +1,198 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// acquireAsync queues a run for a slot, returning a channel that receives its release
+func acquireAsync(t *testing.T, r *RunScheduler, ctx context.Context, sessionID string) <-chan func() {
+	t.Helper()
+	acquired := make(chan func(), 1)
+	go func() {
+		release, err := r.Acquire(ctx, sessionID, nil)
+		if err == nil {
+			acquired <- release
+		}
+	}()
+	waitFor(t, func() bool { return r.Position(sessionID) > 0 || len(acquired) > 0 })
+	return acquired
+}
+
+// waitFor polls until cond holds
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestRunSchedulerLimit(t *testing.T) {
+	r := NewRunScheduler(2)
+
+	first, err := r.Acquire(context.Background(), "a", nil)
+	if err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+	if _, err := r.Acquire(context.Background(), "b", nil); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	third := acquireAsync(t, r, context.Background(), "c")
+	fourth := acquireAsync(t, r, context.Background(), "d")
+	if r.Position("c") != 1 || r.Position("d") != 2 {
+		t.Fatalf("Expected c and d queued in order, got %d and %d", r.Position("c"), r.Position("d"))
+	}
+	if running, queued := r.Stats(); running != 2 || queued != 2 {
+		t.Errorf("Expected 2 running and 2 queued, got %d and %d", running, queued)
+	}
+
+	// Releasing twice frees one slot
+	first()
+	first()
+	select {
+	case <-third:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected c to get the freed slot")
+	}
+	if r.Position("d") != 1 {
+		t.Errorf("Expected d to move up, got position %d", r.Position("d"))
+	}
+	select {
+	case <-fourth:
+		t.Fatal("Expected d to keep waiting")
+	default:
+	}
+}
+
+func TestRunSchedulerCancel(t *testing.T) {
+	r := NewRunScheduler(1)
+	release, _ := r.Acquire(context.Background(), "a", nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	acquireAsync(t, r, ctx, "b")
+	later := acquireAsync(t, r, context.Background(), "c")
+
+	cancel()
+	waitFor(t, func() bool { return r.Position("b") == 0 })
+	if r.Position("c") != 1 {
+		t.Errorf("Expected c first in line after b gave up, got %d", r.Position("c"))
+	}
+
+	release()
+	select {
+	case <-later:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected c to get the slot")
+	}
+
+	if _, err := r.Acquire(ctx, "d", nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected a canceled wait to fail, got %v", err)
+	}
+}
+
+func TestRunSchedulerSetLimit(t *testing.T) {
+	if limit := NewRunScheduler(0).Limit(); limit != DefaultMaxConcurrentRuns {
+		t.Errorf("Expected the default limit, got %d", limit)
+	}
+
+	r := NewRunScheduler(1)
+	r.Acquire(context.Background(), "a", nil)
+	queued := acquireAsync(t, r, context.Background(), "b")
+
+	// Raising the limit starts queued runs
+	r.SetLimit(-1)
+	select {
+	case <-queued:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected b to start when the limit was removed")
+	}
+	if _, err := r.Acquire(context.Background(), "c", nil); err != nil {
+		t.Errorf("Expected no limit, got %v", err)
+	}
+}
+
+func TestSessionWaitsForRunSlot(t *testing.T) {
+	scheduler := NewRunScheduler(1)
+	release, _ := scheduler.Acquire(context.Background(), "other", nil)
+
+	session := NewSession("test-queued", "/tmp/project")
+	session.SetRunScheduler(scheduler)
+	session.Status = SessionStatusRunning
+
+	var mu sync.Mutex
+	var positions []int
+	session.SetQueueHandler(func(position int) {
+		mu.Lock()
+		defer mu.Unlock()
+		positions = append(positions, position)
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		slot, err := session.waitForRunSlot(context.Background())
+		if err == nil {
+			slot()
+		}
+		done <- err
+	}()
+
+	waitFor(t, func() bool { return session.QueuePosition() == 1 })
+	session.mu.RLock()
+	status := session.Status
+	session.mu.RUnlock()
+	if status != SessionStatusQueued {
+		t.Errorf("Expected status queued, got %s", status)
+	}
+
+	release()
+	if err := <-done; err != nil {
+		t.Fatalf("waitForRunSlot failed: %v", err)
+	}
+	if session.Status != SessionStatusRunning || session.QueuePosition() != 0 {
+		t.Errorf("Expected the session running, got %s at position %d", session.Status, session.QueuePosition())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(positions) != 2 || positions[0] != 1 || positions[1] != 0 {
+		t.Errorf("Expected positions [1 0], got %v", positions)
+	}
+}
+
+func TestCancelWhileQueued(t *testing.T) {
+	scheduler := NewRunScheduler(1)
+	scheduler.Acquire(context.Background(), "other", nil)
+
+	session := NewSession("test-cancel-queued", "/tmp/project")
+	session.SetRunScheduler(scheduler)
+	session.runner = &fakeRunner{}
+	session.Status = SessionStatusRunning
+
+	authConfig := AuthConfig{ClaudeCLIPath: fakeClaudeBinary(t)}
+	done := make(chan struct{})
+	go func() {
+		session.runWithRateLimitRetry("hi", authConfig)
+		close(done)
+	}()
+
+	waitFor(t, func() bool { return session.QueuePosition() == 1 })
+	if err := session.CancelCurrentRun(); err != nil {
+		t.Fatalf("CancelCurrentRun failed: %v", err)
+	}
+	<-done
+
+	if session.Status != SessionStatusIdle {
+		t.Errorf("Expected status idle, got %s", session.Status)
+	}
+	if _, queued := scheduler.Stats(); queued != 0 {
+		t.Errorf("Expected the queue to be empty, got %d", queued)
+	}
+}
@@ -14,6 +14,10 @@ type SearchFilter struct {
 	IsFavorite  *bool     // Filter favorites (nil = no filter)
 	FromDate    time.Time // Filter by date range (start)
 	ToDate      time.Time // Filter by date range (end)
+
+	Content      string   // Words in message bodies, tool calls or tool results, archived ones included
+	Roles        []string // Only match Content in messages with these roles
+	HasToolError bool     // Only sessions where a tool call failed
 }
 
 // SearchResult represents a search result
@@ -41,11 +45,33 @@ func searchSessionsWithLoader(filter SearchFilter, loader SessionLoader) ([]*Sea
 		return nil, err
 	}
 
+	// Content criteria are answered by the search index when there is one
+	var contentMatches, toolErrors map[string]*ContentMatch
+	if strings.TrimSpace(filter.Content) != "" {
+		contentMatches, err = searchContent(ContentQuery{Text: filter.Content, Roles: filter.Roles}, sessions)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if filter.HasToolError {
+		toolErrors, err = searchContent(ContentQuery{ToolErrors: true}, sessions)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var results []*SearchResult
 
 	for _, session := range sessions {
+		if contentMatches != nil && contentMatches[session.ID] == nil {
+			continue
+		}
+		if toolErrors != nil && toolErrors[session.ID] == nil {
+			continue
+		}
 		if matchesFilter(session, filter) {
 			score, reasons := scoreSession(session, filter)
+			score, reasons = scoreContent(score, reasons, contentMatches[session.ID], toolErrors[session.ID])
 			results = append(results, &SearchResult{
 				Session:     session,
 				Score:       score,
@@ -216,6 +242,97 @@ func scoreSession(session *Session, filter SearchFilter) (int, []string) {
 	return score, reasons
 }
 
+// searchContent finds the messages matching q with the active search index,
+// or by scanning the messages of sessions without one
+func searchContent(q ContentQuery, sessions []*Session) (map[string]*ContentMatch, error) {
+	if index := ActiveSearchIndex(); index != nil {
+		return index.Search(q)
+	}
+
+	words := strings.Fields(strings.ToLower(q.Text))
+	matches := make(map[string]*ContentMatch)
+	for _, session := range sessions {
+		session.mu.RLock()
+		for _, msg := range session.Messages {
+			if messageMatches(newMessageDocument(session.ID, msg, false), words, q) {
+				if matches[session.ID] == nil {
+					matches[session.ID] = &ContentMatch{}
+				}
+				matches[session.ID].Messages++
+				matches[session.ID].Score++
+			}
+		}
+		session.mu.RUnlock()
+	}
+	return matches, nil
+}
+
+// messageMatches reports whether a message has every word in one of its
+// text fields and meets the rest of q
+func messageMatches(doc messageDocument, words []string, q ContentQuery) bool {
+	if q.ToolErrors && !doc.ToolError {
+		return false
+	}
+	if len(q.Roles) > 0 {
+		found := false
+		for _, role := range q.Roles {
+			if strings.EqualFold(role, doc.Role) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if len(words) == 0 {
+		return true
+	}
+
+	for _, text := range []string{doc.Content, doc.ToolName, doc.ToolInput, doc.ToolOutput} {
+		text = strings.ToLower(text)
+		all := true
+		for _, word := range words {
+			if !strings.Contains(text, word) {
+				all = false
+				break
+			}
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+// scoreContent adds a session's content matches to its score and reasons
+func scoreContent(score int, reasons []string, content, toolErrors *ContentMatch) (int, []string) {
+	if content != nil {
+		score += content.Messages * 5
+		reason := fmt.Sprintf("%d content matches", content.Messages)
+		if content.Messages == 1 {
+			reason = "1 content match"
+		}
+		if content.Archived > 0 {
+			reason += fmt.Sprintf(" (%d archived)", content.Archived)
+		}
+		reasons = append(reasons, reason)
+	}
+	if toolErrors != nil {
+		if toolErrors.Messages == 1 {
+			reasons = append(reasons, "1 failed tool call")
+		} else {
+			reasons = append(reasons, fmt.Sprintf("%d failed tool calls", toolErrors.Messages))
+		}
+	}
+
+	// Content matches are more specific than the recency fallback
+	if len(reasons) > 1 && reasons[0] == "Matches all filters" {
+		reasons = reasons[1:]
+	}
+	return score, reasons
+}
+
 // GetAllTags returns all unique tags across all sessions
 func GetAllTags() ([]string, error) {
 	return getAllTagsWithLoader(defaultSessionLoader)
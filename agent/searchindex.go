@@ -0,0 +1,368 @@
+package agent
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/standard"
+	"github.com/blevesearch/bleve/v2/mapping"
+	"github.com/blevesearch/bleve/v2/search/query"
+)
+
+// maxContentHits caps how many matching messages one content search reads
+const maxContentHits = 5000
+
+// messageDocument is a message as stored in the search index
+type messageDocument struct {
+	SessionID  string    `json:"sessionId"`
+	Role       string    `json:"role"`
+	Content    string    `json:"content"`
+	ToolName   string    `json:"toolName"`
+	ToolInput  string    `json:"toolInput"`
+	ToolOutput string    `json:"toolOutput"`
+	ToolError  bool      `json:"toolError"`
+	Archived   bool      `json:"archived"`
+	Timestamp  time.Time `json:"timestamp"`
+}
+
+// textFields are the document fields searched for content
+var textFields = []string{"content", "toolName", "toolInput", "toolOutput"}
+
+// ContentQuery selects messages by what they say
+type ContentQuery struct {
+	Text       string   // Words every matching message contains
+	Roles      []string // Only messages with these roles (empty = any)
+	ToolErrors bool     // Only failed tool results
+}
+
+// ContentMatch summarizes the messages of one session matching a ContentQuery
+type ContentMatch struct {
+	Messages int     // Matching messages, archived ones included
+	Archived int     // Matching messages that were archived
+	Score    float64 // Combined relevance of the matching messages
+}
+
+// SearchIndex is a full-text index of message bodies, tool calls and tool
+// results, updated as sessions are saved and archived
+type SearchIndex struct {
+	index bleve.Index
+
+	mu      sync.Mutex
+	indexed map[string]map[string]uint64 // Session ID -> message ID -> hash of what was indexed
+}
+
+// DefaultSearchIndexPath returns where the search index lives
+func DefaultSearchIndexPath() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".boatman", "search.bleve"), nil
+}
+
+// OpenSearchIndex opens or creates the search index at path. An empty path
+// keeps the index in memory, e.g. so encrypted sessions aren't written to
+// disk in plaintext. Like the session database, it fails rather than
+// waiting if another process has the index open.
+func OpenSearchIndex(path string) (*SearchIndex, error) {
+	var index bleve.Index
+	var err error
+	if path == "" {
+		index, err = bleve.NewMemOnly(newSearchMapping())
+	} else if index, err = bleve.OpenUsing(path, map[string]interface{}{"bolt_timeout": "1s"}); err == bleve.ErrorIndexPathDoesNotExist {
+		if err = os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+			index, err = bleve.New(path, newSearchMapping())
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open search index: %w", err)
+	}
+	return &SearchIndex{index: index, indexed: make(map[string]map[string]uint64)}, nil
+}
+
+// newSearchMapping analyzes message text as words and matches IDs and roles exactly
+func newSearchMapping() mapping.IndexMapping {
+	text := bleve.NewTextFieldMapping()
+	text.Analyzer = standard.Name
+	text.Store = false
+	text.IncludeTermVectors = false
+
+	exact := bleve.NewTextFieldMapping()
+	exact.Analyzer = keyword.Name
+	exact.Store = false
+	exact.IncludeTermVectors = false
+
+	flag := bleve.NewBooleanFieldMapping()
+	flag.Store = false
+
+	// Stored so search results can tell archived messages apart
+	archived := bleve.NewBooleanFieldMapping()
+
+	when := bleve.NewDateTimeFieldMapping()
+	when.Store = false
+
+	doc := bleve.NewDocumentStaticMapping()
+	doc.AddFieldMappingsAt("sessionId", exact)
+	doc.AddFieldMappingsAt("role", exact)
+	for _, field := range textFields {
+		doc.AddFieldMappingsAt(field, text)
+	}
+	doc.AddFieldMappingsAt("toolError", flag)
+	doc.AddFieldMappingsAt("archived", archived)
+	doc.AddFieldMappingsAt("timestamp", when)
+
+	indexMapping := bleve.NewIndexMapping()
+	indexMapping.DefaultMapping = doc
+	return indexMapping
+}
+
+// newMessageDocument returns the indexed form of a message
+func newMessageDocument(sessionID string, msg Message, archived bool) messageDocument {
+	doc := messageDocument{
+		SessionID: sessionID,
+		Role:      msg.Role,
+		Content:   msg.Content,
+		Archived:  archived,
+		Timestamp: msg.Timestamp,
+	}
+	if meta := msg.Metadata; meta != nil {
+		if meta.ToolUse != nil {
+			doc.ToolName = meta.ToolUse.ToolName
+			doc.ToolInput = string(meta.ToolUse.Input)
+		}
+		if meta.ToolResult != nil {
+			doc.ToolOutput = meta.ToolResult.Content
+			doc.ToolError = meta.ToolResult.IsError
+		}
+	}
+	return doc
+}
+
+// hash identifies what a document says, so unchanged messages aren't reindexed
+func (d messageDocument) hash() uint64 {
+	h := fnv.New64a()
+	for _, part := range []string{d.Role, d.Content, d.ToolName, d.ToolInput, d.ToolOutput, fmt.Sprint(d.ToolError, d.Archived)} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
+
+// messageDocID is a message's ID in the index
+func messageDocID(sessionID, messageID string) string {
+	return sessionID + "/" + messageID
+}
+
+// IndexMessages adds or updates a session's messages, skipping ones already
+// indexed as they are. Superseded responses are left out.
+func (x *SearchIndex) IndexMessages(sessionID string, messages []Message, archived bool) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	seen := x.indexed[sessionID]
+	if seen == nil {
+		seen = make(map[string]uint64)
+		x.indexed[sessionID] = seen
+	}
+
+	batch := x.index.NewBatch()
+	updated := make(map[string]uint64)
+	for _, msg := range messages {
+		if msg.Metadata != nil && msg.Metadata.Superseded {
+			continue
+		}
+		doc := newMessageDocument(sessionID, msg, archived)
+		hash := doc.hash()
+		if seen[msg.ID] == hash {
+			continue
+		}
+		if err := batch.Index(messageDocID(sessionID, msg.ID), doc); err != nil {
+			return fmt.Errorf("failed to index message %s: %w", msg.ID, err)
+		}
+		updated[msg.ID] = hash
+	}
+	if batch.Size() == 0 {
+		return nil
+	}
+	if err := x.index.Batch(batch); err != nil {
+		return fmt.Errorf("failed to update search index: %w", err)
+	}
+	for id, hash := range updated {
+		seen[id] = hash
+	}
+	return nil
+}
+
+// DeleteSession removes a session's messages from the index
+func (x *SearchIndex) DeleteSession(sessionID string) error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	delete(x.indexed, sessionID)
+
+	q := bleve.NewTermQuery(sessionID)
+	q.SetField("sessionId")
+	for {
+		req := bleve.NewSearchRequestOptions(q, maxContentHits, 0, false)
+		result, err := x.index.Search(req)
+		if err != nil {
+			return fmt.Errorf("failed to search index: %w", err)
+		}
+		if len(result.Hits) == 0 {
+			return nil
+		}
+		batch := x.index.NewBatch()
+		for _, hit := range result.Hits {
+			batch.Delete(hit.ID)
+		}
+		if err := x.index.Batch(batch); err != nil {
+			return fmt.Errorf("failed to update search index: %w", err)
+		}
+	}
+}
+
+// Search returns the sessions with messages matching q, by session ID
+func (x *SearchIndex) Search(q ContentQuery) (map[string]*ContentMatch, error) {
+	var conjuncts []query.Query
+	if text := strings.TrimSpace(q.Text); text != "" {
+		var fields []query.Query
+		for _, field := range textFields {
+			match := bleve.NewMatchQuery(text)
+			match.SetField(field)
+			match.SetOperator(query.MatchQueryOperatorAnd)
+			fields = append(fields, match)
+		}
+		conjuncts = append(conjuncts, bleve.NewDisjunctionQuery(fields...))
+	}
+	if len(q.Roles) > 0 {
+		var roles []query.Query
+		for _, role := range q.Roles {
+			term := bleve.NewTermQuery(role)
+			term.SetField("role")
+			roles = append(roles, term)
+		}
+		conjuncts = append(conjuncts, bleve.NewDisjunctionQuery(roles...))
+	}
+	if q.ToolErrors {
+		failed := bleve.NewBoolFieldQuery(true)
+		failed.SetField("toolError")
+		conjuncts = append(conjuncts, failed)
+	}
+	if len(conjuncts) == 0 {
+		return map[string]*ContentMatch{}, nil
+	}
+
+	req := bleve.NewSearchRequestOptions(bleve.NewConjunctionQuery(conjuncts...), maxContentHits, 0, false)
+	req.Fields = []string{"archived"}
+	result, err := x.index.Search(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+
+	matches := make(map[string]*ContentMatch)
+	for _, hit := range result.Hits {
+		sessionID, _, ok := strings.Cut(hit.ID, "/")
+		if !ok {
+			continue
+		}
+		match := matches[sessionID]
+		if match == nil {
+			match = &ContentMatch{}
+			matches[sessionID] = match
+		}
+		match.Messages++
+		match.Score += hit.Score
+		if archived, _ := hit.Fields["archived"].(bool); archived {
+			match.Archived++
+		}
+	}
+	return matches, nil
+}
+
+// Empty reports whether nothing has been indexed yet
+func (x *SearchIndex) Empty() bool {
+	count, err := x.index.DocCount()
+	return err == nil && count == 0
+}
+
+// Close closes the index
+func (x *SearchIndex) Close() error {
+	return x.index.Close()
+}
+
+var (
+	searchIndexMu     sync.RWMutex
+	activeSearchIndex *SearchIndex
+)
+
+// UseSearchIndex makes index the one kept current as sessions are saved and
+// used for content searches. With no index, content searches scan the
+// messages of loaded sessions.
+func UseSearchIndex(index *SearchIndex) {
+	searchIndexMu.Lock()
+	defer searchIndexMu.Unlock()
+	activeSearchIndex = index
+}
+
+// ActiveSearchIndex returns the index content searches use, or nil
+func ActiveSearchIndex() *SearchIndex {
+	searchIndexMu.RLock()
+	defer searchIndexMu.RUnlock()
+	return activeSearchIndex
+}
+
+// indexSessionMessages brings the active index up to date with a session's
+// messages. Failures are logged rather than failing the save.
+func indexSessionMessages(sessionID string, messages []Message, archived bool) {
+	index := ActiveSearchIndex()
+	if index == nil {
+		return
+	}
+	if err := index.IndexMessages(sessionID, messages, archived); err != nil {
+		logger.Warn("Failed to index session messages", "session", sessionID, "error", err)
+	}
+}
+
+// unindexSession removes a deleted session from the active index
+func unindexSession(sessionID string) {
+	index := ActiveSearchIndex()
+	if index == nil {
+		return
+	}
+	if err := index.DeleteSession(sessionID); err != nil {
+		logger.Warn("Failed to remove session from the search index", "session", sessionID, "error", err)
+	}
+}
+
+// BuildSearchIndex indexes every saved session and its archive, returning
+// how many sessions were indexed
+func BuildSearchIndex(index *SearchIndex) (int, error) {
+	sessions, err := LoadAllSessions()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load saved sessions: %w", err)
+	}
+
+	for _, session := range sessions {
+		archive, err := LoadArchive(session.ID)
+		if err != nil {
+			return 0, fmt.Errorf("failed to load archive for %s: %w", session.ID, err)
+		}
+		if err := index.IndexMessages(session.ID, archive.Messages, true); err != nil {
+			return 0, err
+		}
+
+		session.mu.RLock()
+		messages := append([]Message(nil), session.Messages...)
+		session.mu.RUnlock()
+		if err := index.IndexMessages(session.ID, messages, false); err != nil {
+			return 0, err
+		}
+	}
+	return len(sessions), nil
+}
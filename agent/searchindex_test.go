@@ -0,0 +1,214 @@
+package agent
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+// useTestSearchIndex makes an in-memory index active for the test
+func useTestSearchIndex(t *testing.T) *SearchIndex {
+	t.Helper()
+	index, err := OpenSearchIndex("")
+	if err != nil {
+		t.Fatalf("Failed to open search index: %v", err)
+	}
+	UseSearchIndex(index)
+	t.Cleanup(func() {
+		UseSearchIndex(nil)
+		index.Close()
+	})
+	return index
+}
+
+// toolMessages returns an edit of path, its failed result, and a reply
+func toolMessages(path string) []Message {
+	input, _ := json.Marshal(map[string]string{"file_path": path})
+	return []Message{
+		{
+			ID: "tool-use", Role: "assistant", Content: "Editing the config", Timestamp: time.Now(),
+			Metadata: &MessageMetadata{ToolUse: &ToolUse{ToolName: "Edit", ToolID: "t1", Input: input}},
+		},
+		{
+			ID: "tool-result", Role: "system", Content: "Edit failed", Timestamp: time.Now(),
+			Metadata: &MessageMetadata{ToolResult: &ToolResult{ToolID: "t1", Content: "permission denied", IsError: true}},
+		},
+		{ID: "reply", Role: "assistant", Content: "The reload worked", Timestamp: time.Now()},
+	}
+}
+
+func TestSearchIndexSearch(t *testing.T) {
+	index := useTestSearchIndex(t)
+
+	if err := index.IndexMessages("web", toolMessages("/etc/nginx/nginx.conf"), false); err != nil {
+		t.Fatalf("IndexMessages() error = %v", err)
+	}
+	archived := []Message{{ID: "old", Role: "user", Content: "Tune the nginx workers", Timestamp: time.Now()}}
+	if err := index.IndexMessages("web", archived, true); err != nil {
+		t.Fatalf("IndexMessages() error = %v", err)
+	}
+	other := []Message{{ID: "m1", Role: "user", Content: "Write the release notes", Timestamp: time.Now()}}
+	if err := index.IndexMessages("docs", other, false); err != nil {
+		t.Fatalf("IndexMessages() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		query    ContentQuery
+		sessions map[string]int // Session ID -> matching messages
+		archived int
+	}{
+		{"tool input", ContentQuery{Text: "nginx.conf"}, map[string]int{"web": 1}, 0},
+		{"tool output", ContentQuery{Text: "permission denied"}, map[string]int{"web": 1}, 0},
+		{"archived message", ContentQuery{Text: "workers"}, map[string]int{"web": 1}, 1},
+		{"case insensitive", ContentQuery{Text: "RELEASE"}, map[string]int{"docs": 1}, 0},
+		{"role filter", ContentQuery{Text: "reload", Roles: []string{"user"}}, map[string]int{}, 0},
+		{"role filter matches", ContentQuery{Text: "reload", Roles: []string{"assistant"}}, map[string]int{"web": 1}, 0},
+		{"tool errors", ContentQuery{ToolErrors: true}, map[string]int{"web": 1}, 0},
+		{"every word", ContentQuery{Text: "release workers"}, map[string]int{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := index.Search(tt.query)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			if len(matches) != len(tt.sessions) {
+				t.Fatalf("Expected %d sessions, got %d", len(tt.sessions), len(matches))
+			}
+			for id, count := range tt.sessions {
+				match := matches[id]
+				if match == nil || match.Messages != count || match.Archived != tt.archived {
+					t.Errorf("Expected %s with %d matches (%d archived), got %+v", id, count, tt.archived, match)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchIndexUpdates(t *testing.T) {
+	index := useTestSearchIndex(t)
+
+	messages := []Message{{ID: "m1", Role: "assistant", Content: "Working", Timestamp: time.Now()}}
+	if err := index.IndexMessages("s1", messages, false); err != nil {
+		t.Fatalf("IndexMessages() error = %v", err)
+	}
+
+	// A streamed message is reindexed as it grows
+	messages[0].Content = "Working on the migration"
+	if err := index.IndexMessages("s1", messages, false); err != nil {
+		t.Fatalf("IndexMessages() error = %v", err)
+	}
+	if matches, _ := index.Search(ContentQuery{Text: "migration"}); matches["s1"] == nil {
+		t.Error("Expected the updated content to be searchable")
+	}
+
+	// Superseded responses are left out
+	superseded := []Message{{ID: "m2", Role: "assistant", Content: "Discarded draft", Metadata: &MessageMetadata{Superseded: true}}}
+	if err := index.IndexMessages("s1", superseded, false); err != nil {
+		t.Fatalf("IndexMessages() error = %v", err)
+	}
+	if matches, _ := index.Search(ContentQuery{Text: "draft"}); len(matches) != 0 {
+		t.Errorf("Expected superseded messages left out, got %v", matches)
+	}
+
+	if err := index.DeleteSession("s1"); err != nil {
+		t.Fatalf("DeleteSession() error = %v", err)
+	}
+	if !index.Empty() {
+		t.Error("Expected the index empty after deleting its only session")
+	}
+}
+
+func TestSearchIndexFollowsPersistence(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	index := useTestSearchIndex(t)
+
+	session := NewSession("persisted", "/tmp/project")
+	session.Messages = toolMessages("/etc/nginx/nginx.conf")
+	if err := SaveSession(session); err != nil {
+		t.Fatalf("SaveSession() error = %v", err)
+	}
+	if err := ArchiveSessionMessages(ArchiveData{SessionID: session.ID}, session.Messages[:1]); err != nil {
+		t.Fatalf("ArchiveSessionMessages() error = %v", err)
+	}
+
+	matches, err := index.Search(ContentQuery{Text: "nginx.conf"})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+	if match := matches[session.ID]; match == nil || match.Archived != 1 {
+		t.Errorf("Expected the archived edit to be found, got %+v", match)
+	}
+
+	// A fresh index is rebuilt from what was saved
+	rebuilt, err := OpenSearchIndex("")
+	if err != nil {
+		t.Fatalf("OpenSearchIndex() error = %v", err)
+	}
+	defer rebuilt.Close()
+	if count, err := BuildSearchIndex(rebuilt); err != nil || count != 1 {
+		t.Fatalf("Expected 1 session indexed, got %d, %v", count, err)
+	}
+	if matches, _ := rebuilt.Search(ContentQuery{ToolErrors: true}); matches[session.ID] == nil {
+		t.Error("Expected the rebuilt index to find the failed tool call")
+	}
+
+	if err := DeleteSessionFile(session.ID); err != nil {
+		t.Fatalf("DeleteSessionFile() error = %v", err)
+	}
+	if !index.Empty() {
+		t.Error("Expected a deleted session removed from the index")
+	}
+}
+
+func TestSearchSessions_ContentFilters(t *testing.T) {
+	web := NewSession("web", "/project/web")
+	web.Messages = toolMessages("/etc/nginx/nginx.conf")
+	docs := createTestSession("docs", "/project/docs", nil, false, []string{"Write the release notes"})
+	loader := func() ([]*Session, error) {
+		return []*Session{web, docs}, nil
+	}
+
+	tests := []struct {
+		name     string
+		filter   SearchFilter
+		expected []string
+	}{
+		{"content", SearchFilter{Content: "nginx.conf"}, []string{"web"}},
+		{"content and role", SearchFilter{Content: "notes", Roles: []string{"assistant"}}, nil},
+		{"tool error", SearchFilter{HasToolError: true}, []string{"web"}},
+		{"tool error and content", SearchFilter{HasToolError: true, Content: "release"}, nil},
+		{"content and project", SearchFilter{Content: "release", ProjectPath: "/project/docs"}, []string{"docs"}},
+	}
+
+	// The same filters work with and without a search index
+	for _, indexed := range []bool{false, true} {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if indexed {
+					index := useTestSearchIndex(t)
+					for _, session := range []*Session{web, docs} {
+						if err := index.IndexMessages(session.ID, session.Messages, false); err != nil {
+							t.Fatalf("IndexMessages() error = %v", err)
+						}
+					}
+				}
+
+				results, err := searchSessionsWithLoader(tt.filter, loader)
+				if err != nil {
+					t.Fatalf("Search failed: %v", err)
+				}
+				if len(results) != len(tt.expected) {
+					t.Fatalf("Expected %v, got %d results", tt.expected, len(results))
+				}
+				for i, id := range tt.expected {
+					if results[i].Session.ID != id {
+						t.Errorf("Expected %s, got %s", id, results[i].Session.ID)
+					}
+				}
+			})
+		}
+	}
+}
@@ -4,22 +4,35 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os/exec"
+	"io"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
+
+	"boatman/git"
+	"boatman/logging"
+	"boatman/stream"
 )
 
+// logger logs the agent package's diagnostics
+var logger = logging.For("agent")
+
 // SessionStatus represents the current state of an agent session
 type SessionStatus string
 
 const (
-	SessionStatusIdle    SessionStatus = "idle"
-	SessionStatusRunning SessionStatus = "running"
-	SessionStatusWaiting SessionStatus = "waiting"
-	SessionStatusError   SessionStatus = "error"
-	SessionStatusStopped SessionStatus = "stopped"
+	SessionStatusIdle        SessionStatus = "idle"
+	SessionStatusRunning     SessionStatus = "running"
+	SessionStatusWaiting     SessionStatus = "waiting"
+	SessionStatusError       SessionStatus = "error"
+	SessionStatusStopped     SessionStatus = "stopped"
+	SessionStatusOffline     SessionStatus = "offline"      // Prompts queued until the network returns
+	SessionStatusRateLimited SessionStatus = "rate-limited" // Waiting out a rate limit before resuming
+	SessionStatusQueued      SessionStatus = "queued"       // Waiting for a slot under the concurrent run limit
 )
 
 // Message represents a chat message
@@ -38,7 +51,9 @@ type AgentInfo struct {
 	ParentAgentID string    `json:"parentAgentId,omitempty"`
 	Description   string    `json:"description,omitempty"`
 	Status        string    `json:"status,omitempty"`        // "active" or "completed"
+	StartedAt     time.Time `json:"startedAt,omitempty"`
 	CompletedAt   time.Time `json:"completedAt,omitempty"`
+	ToolUseID     string    `json:"toolUseId,omitempty"` // Task tool call that spawned the agent
 }
 
 // MessageMetadata contains additional message information
@@ -47,6 +62,13 @@ type MessageMetadata struct {
 	ToolResult *ToolResult `json:"toolResult,omitempty"`
 	CostInfo   *CostInfo   `json:"costInfo,omitempty"`
 	Agent      *AgentInfo  `json:"agent,omitempty"`
+	Superseded bool        `json:"superseded,omitempty"` // Replaced by a regenerated response
+	Redactions int         `json:"redactions,omitempty"` // Secrets masked before storage
+	Queued     bool        `json:"queued,omitempty"`     // Waiting to be sent until the network returns
+	// Attachments are files the user attached to the message, as workspace paths
+	Attachments []string `json:"attachments,omitempty"`
+	// Images are previews of the images sent with the message
+	Images []ImageThumbnail `json:"images,omitempty"`
 }
 
 // ToolUse represents a tool invocation by the agent
@@ -65,9 +87,11 @@ type ToolResult struct {
 
 // CostInfo tracks token usage and cost
 type CostInfo struct {
-	InputTokens  int     `json:"inputTokens"`
-	OutputTokens int     `json:"outputTokens"`
-	TotalCost    float64 `json:"totalCost"`
+	InputTokens      int     `json:"inputTokens"`
+	OutputTokens     int     `json:"outputTokens"`
+	CacheWriteTokens int     `json:"cacheWriteTokens,omitempty"`
+	CacheReadTokens  int     `json:"cacheReadTokens,omitempty"`
+	TotalCost        float64 `json:"totalCost"`
 }
 
 // Task represents a task being tracked by the agent
@@ -94,26 +118,125 @@ type Session struct {
 	Mode        string                 `json:"mode"` // "standard", "firefighter", "boatmanmode"
 	ModeConfig  map[string]interface{} `json:"modeConfig,omitempty"`
 
+	// SnapshotBeforeRun snapshots the project before full-auto runs so they can be undone
+	SnapshotBeforeRun bool `json:"snapshotBeforeRun,omitempty"`
+
+	// EnabledMCPServers are the MCP servers attached to this session's runs;
+	// nil uses the project's selection, while empty attaches none
+	EnabledMCPServers []string `json:"enabledMcpServers"`
+
+	// PlanMode has runs propose a plan and diffs without changing files
+	PlanMode bool `json:"planMode,omitempty"`
+
+	// ToolPolicy allows or denies tools on top of the project's policy
+	ToolPolicy ToolPolicy `json:"toolPolicy"`
+
+	// AdditionalPaths are directories besides ProjectPath the session spans,
+	// e.g. a backend repo next to the frontend one
+	AdditionalPaths []string `json:"additionalPaths,omitempty"`
+
+	// SystemPromptOverride, when set, replaces the user's and project's
+	// system prompt additions for this session
+	SystemPromptOverride string `json:"systemPromptOverride,omitempty"`
+
+	// ModelOverride and ApprovalModeOverride, when set, replace the
+	// project's and user's defaults for this session
+	ModelOverride        string `json:"modelOverride,omitempty"`
+	ApprovalModeOverride string `json:"approvalModeOverride,omitempty"`
+
 	mu             sync.RWMutex
 	ctx            context.Context
 	cancel         context.CancelFunc
 	onMessage      func(Message)
+	onMessageDelta func(messageID, delta string)
 	onTask         func(Task)
 	onStatus       func(SessionStatus)
 	conversationID string
 	currentAgentID string // Tracks which agent is currently active
 	agents         map[string]*AgentInfo // All known agents in this session
+	agentMessages  map[string][]Message  // Each sub-agent's own transcript
+	onAgentMessage func(agentID string, msg Message)
+
+	// Streaming message updates, batched into deltas
+	streamBatchInterval time.Duration
+	stream              *streamState
 
 	// Message trimming settings
 	maxMessages int
 	archive     bool
 
+	// historyVersion changes whenever messages leave the window, so
+	// readers of the archive can tell it moved under them
+	historyVersion int
+
 	// Agent cleanup settings
 	maxAgents     int
 	keepCompleted bool
 
-	// Firefighter monitoring
+	// Firefighter monitoring, and the timeline of the incident being handled
 	firefighterMonitor *FirefighterMonitor
+	incident           *Incident
+
+	// Long-running Bash command tracking
+	onCommand       func(RunningCommand)
+	commandTimeout  time.Duration
+	runningCommands map[string]*RunningCommand
+	killedCommands  []RunningCommand
+	unreportedKills []RunningCommand // Killed commands claude hasn't been told about
+	runCancel       context.CancelFunc
+	runModel        string // Overrides Model for the current run
+
+	// Offline queue for prompts that failed on the network
+	offlineQueue      []queuedPrompt
+	reconnecting      bool
+	connectivityCheck func(AuthConfig) error
+	runHasOutput      bool
+	networkFailure    bool
+
+	// Starts the claude process; nil uses ExecRunner
+	runner ProcessRunner
+
+	// Cancellation of the prompt being processed, including rate limit retries
+	turnCancel   context.CancelFunc
+	turnCanceled bool
+
+	// Interactive tool approval for the current run
+	onApproval     func([]PendingAction)
+	pendingActions []PendingAction
+	runInput       io.WriteCloser
+	runInputMu     sync.Mutex // Serializes writes to runInput
+	protectedPaths []string   // Globs whose writes wait for approval
+
+	// Rate limit handling for the current run
+	onRateLimit func(RateLimitInfo)
+	rateLimited bool
+	retryAfter  time.Duration
+
+	// Failures of the current and latest run
+	runFailure string // Error claude reported during the current run
+	lastError  error  // Why the latest run failed
+
+	// User-defined tool output formatters
+	formatters *ToolFormatterRegistry
+
+	// Token usage of the conversation against the model's context window
+	contextTracker ContextTracker
+
+	// Limit on runs across sessions; nil starts runs immediately
+	scheduler     *RunScheduler
+	queuePosition int // Place in the scheduler's queue, or 0 if not waiting
+	onQueue       func(position int)
+
+	// Project state from before the agent's full-auto runs, until restored or discarded
+	snapshot   *git.Snapshot
+	onSnapshot func(*git.Snapshot)
+
+	// Files changed by each turn, and by the turn in progress
+	changeSets     []ChangeSet
+	currentChanges *turnChanges
+
+	// User message of the latest plan turn, until its plan is applied
+	planMessageID string
 }
 
 // NewSession creates a new agent session
@@ -204,24 +327,84 @@ func (s *Session) Stop() error {
 	return nil
 }
 
-// SendMessage sends a user message to the agent
-func (s *Session) SendMessage(content string, authConfig AuthConfig) error {
+// CancelCurrentRun aborts the prompt being processed without stopping the
+// session. The claude process is killed, output received so far is kept, and
+// the session returns to idle.
+func (s *Session) CancelCurrentRun() error {
 	s.mu.Lock()
-	if s.Status == SessionStatusStopped || s.Status == SessionStatusError {
+	cancel := s.turnCancel
+	if cancel == nil {
 		s.mu.Unlock()
-		return fmt.Errorf("session not available")
+		return fmt.Errorf("no run in progress")
 	}
+	s.turnCanceled = true
+	s.mu.Unlock()
 
-	// Boatmanmode sessions should not use SendMessage - they use StreamExecution instead
-	if s.Mode == "boatmanmode" {
+	cancel()
+	return nil
+}
+
+// beginTurn starts tracking a prompt so CancelCurrentRun can abort it. The
+// returned function ends the turn, settling the session if it was canceled.
+func (s *Session) beginTurn() (context.Context, func()) {
+	s.mu.Lock()
+	parent := s.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+	s.turnCancel = cancel
+	s.turnCanceled = false
+	s.mu.Unlock()
+
+	return ctx, func() {
+		cancel()
+
+		s.mu.Lock()
+		canceled := s.turnCanceled
+		s.turnCancel = nil
+		s.turnCanceled = false
+		if canceled {
+			switch s.Status {
+			case SessionStatusRunning, SessionStatusWaiting, SessionStatusRateLimited, SessionStatusQueued:
+				s.setStatus(SessionStatusIdle)
+			}
+		}
 		s.mu.Unlock()
-		return fmt.Errorf("boatmanmode sessions do not support SendMessage - use StreamBoatmanModeExecution")
+
+		if canceled {
+			s.addSystemMessage("⏹️  Run cancelled")
+		}
 	}
+}
 
-	// Check if context is initialized
-	if s.ctx == nil {
+// SendMessage sends a user message to the agent
+func (s *Session) SendMessage(content string, authConfig AuthConfig) error {
+	return s.SendMessageWithAttachments(content, nil, nil, authConfig)
+}
+
+// SendMessageWithAttachments sends a user message along with workspace files,
+// which are inlined in the prompt or referenced by path, and images such as
+// screenshots, which claude sees alongside the text
+func (s *Session) SendMessageWithAttachments(content string, attachments []string, images []ImageAttachment, authConfig AuthConfig) error {
+	prompt := content
+	var attached []string
+	if len(attachments) > 0 {
+		var err error
+		prompt, attached, err = buildAttachmentPrompt(s.Roots(), content, attachments)
+		if err != nil {
+			return err
+		}
+	}
+	promptImages, thumbnails, err := loadImages(images)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if err := s.checkSendable(); err != nil {
 		s.mu.Unlock()
-		return fmt.Errorf("session context not initialized")
+		return err
 	}
 
 	// Add user message to history
@@ -231,6 +414,10 @@ func (s *Session) SendMessage(content string, authConfig AuthConfig) error {
 		Content:   content,
 		Timestamp: time.Now(),
 	}
+	if len(attached) > 0 || len(thumbnails) > 0 {
+		msg.Metadata = &MessageMetadata{Attachments: attached, Images: thumbnails}
+	}
+	redactMessage(&msg)
 
 	s.Messages = append(s.Messages, msg)
 	s.UpdatedAt = time.Now()
@@ -263,100 +450,352 @@ func (s *Session) SendMessage(content string, authConfig AuthConfig) error {
 	}
 
 	// Run Claude CLI in a goroutine
-	go s.runClaudeCommand(content, authConfig)
+	go s.sendPrompt(msg.ID, prompt, authConfig, promptImages...)
 
 	return nil
 }
 
-// runClaudeCommand executes the Claude CLI with the given prompt
-func (s *Session) runClaudeCommand(prompt string, authConfig AuthConfig) {
-	// Inject system prompt for firefighter mode
-	actualPrompt := prompt
-	s.mu.RLock()
-	if s.Mode == "firefighter" && len(s.Messages) <= 1 {
-		scope, _ := s.ModeConfig["scope"].(string)
-		systemPrompt := GetFirefighterPrompt(scope)
-		actualPrompt = systemPrompt + "\n\n" + prompt
+// checkSendable verifies the session can accept a new prompt
+// Note: This method expects the caller to hold s.mu lock
+func (s *Session) checkSendable() error {
+	if s.Status == SessionStatusStopped || s.Status == SessionStatusError {
+		return fmt.Errorf("session not available")
 	}
-	s.mu.RUnlock()
+	return s.checkRunnable()
+}
 
-	// Build command arguments
-	args := []string{
-		"-p", actualPrompt,
-		"--output-format", "stream-json",
-		"--verbose",
+// checkRewindable returns an error unless the session is between turns, so
+// rewriting its history can't race a run, a queued prompt, or a retry.
+// A session whose last turn failed can be rewound to try it again.
+// Note: This method expects the caller to hold s.mu lock
+func (s *Session) checkRewindable() error {
+	switch s.Status {
+	case SessionStatusIdle, SessionStatusError:
+		return s.checkRunnable()
+	case SessionStatusStopped:
+		return fmt.Errorf("session not available")
+	default:
+		return fmt.Errorf("session is busy (%s)", s.Status)
 	}
+}
 
-	// Add conversation resume if we have one
-	if s.conversationID != "" {
-		args = append(args, "-r", s.conversationID)
+// checkRunnable returns an error if the session can't run prompts at all
+// Note: This method expects the caller to hold s.mu lock
+func (s *Session) checkRunnable() error {
+	// Boatmanmode sessions should not use SendMessage - they use StreamExecution instead
+	if s.Mode == "boatmanmode" {
+		return fmt.Errorf("boatmanmode sessions do not support SendMessage - use StreamBoatmanModeExecution")
 	}
 
-	if s.Model != "" {
-		args = append(args, "--model", s.Model)
+	// Check if context is initialized
+	if s.ctx == nil {
+		return fmt.Errorf("session context not initialized")
 	}
 
-	// MCP servers are automatically loaded from ~/.claude/claude_mcp_config.json
-	// No need to pass them as command-line arguments
+	return nil
+}
 
-	// Add approval mode flags
-	switch authConfig.ApprovalMode {
-	case "auto-edit":
-		// Allow Edit and Write tools without approval
-		args = append(args, "--dangerously-skip-permissions", "Edit,Write")
-	case "full-auto":
-		// Allow all tools without approval
-		args = append(args, "--dangerously-skip-permissions")
-	case "suggest":
-		// This is the default - require approval for everything
-		// We need to run without stream-json for this to work properly
-		// For now, we'll just use dangerously-skip-permissions to avoid hanging
-		args = append(args, "--dangerously-skip-permissions")
-	}
+// RegenerateOptions configures how the last assistant response is regenerated
+type RegenerateOptions struct {
+	Model   string `json:"model,omitempty"`   // Model to use for the new response (empty keeps the current model)
+	Note    string `json:"note,omitempty"`    // Extra guidance appended to the prompt, e.g. "be more concise"
+	Replace bool   `json:"replace,omitempty"` // Remove the old response instead of keeping it marked as superseded
+}
 
-	cmd := exec.CommandContext(s.ctx, "claude", args...)
-	cmd.Dir = s.ProjectPath
+// RegenerateLast re-sends the most recent user turn and produces a new assistant response.
+// Messages that followed that turn are marked superseded, or removed when opts.Replace is set.
+func (s *Session) RegenerateLast(authConfig AuthConfig, opts RegenerateOptions) error {
+	s.mu.Lock()
+	if err := s.checkRewindable(); err != nil {
+		s.mu.Unlock()
+		return err
+	}
 
-	// Set environment variables based on auth method
-	if authConfig.Method == "google-cloud" {
-		if authConfig.GCPProjectID != "" {
-			cmd.Env = append(cmd.Environ(), "CLOUD_ML_PROJECT_ID="+authConfig.GCPProjectID)
+	// Find the last user turn
+	userIndex := -1
+	for i := len(s.Messages) - 1; i >= 0; i-- {
+		if s.Messages[i].Role == "user" {
+			userIndex = i
+			break
 		}
-		if authConfig.GCPRegion != "" {
-			cmd.Env = append(cmd.Environ(), "CLOUD_ML_REGION="+authConfig.GCPRegion)
+	}
+	if userIndex < 0 {
+		s.mu.Unlock()
+		return fmt.Errorf("no user message to regenerate")
+	}
+	userContent := s.Messages[userIndex].Content
+	userMessageID := s.Messages[userIndex].ID
+
+	// Supersede or drop everything the previous run produced
+	var superseded []Message
+	if opts.Replace {
+		// Archive what's dropped so its usage and content aren't lost
+		if err := ArchiveSessionMessages(s.archiveMetadata(), s.Messages[userIndex+1:]); err != nil {
+			logger.Warn("Failed to archive replaced messages", "session", s.ID, "error", err)
 		}
+		s.Messages = s.Messages[:userIndex+1]
+		s.historyVersion++
 	} else {
-		// Use Anthropic API key authentication
-		if authConfig.APIKey != "" {
-			cmd.Env = append(cmd.Environ(), "ANTHROPIC_API_KEY="+authConfig.APIKey)
+		for i := userIndex + 1; i < len(s.Messages); i++ {
+			if s.Messages[i].Metadata == nil {
+				s.Messages[i].Metadata = &MessageMetadata{}
+			}
+			s.Messages[i].Metadata.Superseded = true
+			superseded = append(superseded, s.Messages[i])
+		}
+	}
+
+	// The model only applies to the regenerated response
+	authConfig.Model = opts.Model
+
+	// Store handler references to call after releasing lock
+	messageHandler := s.onMessage
+	statusHandler := s.onStatus
+
+	s.Status = SessionStatusRunning
+	s.UpdatedAt = time.Now()
+
+	s.mu.Unlock()
+
+	if messageHandler != nil {
+		for _, msg := range superseded {
+			messageHandler(msg)
+		}
+	}
+
+	if statusHandler != nil {
+		statusHandler(SessionStatusRunning)
+	}
+
+	// The CLI conversation already contains the previous answer, so ask for a fresh one explicitly
+	prompt := "Please regenerate your previous response to this request:\n\n" + userContent
+	if opts.Note != "" {
+		prompt += "\n\n" + opts.Note
+	}
+
+	go s.sendPrompt(userMessageID, prompt, authConfig)
+
+	return nil
+}
+
+// EditAndResend rewinds the conversation to an earlier user message, replaces its content,
+// and sends it again. The discarded branch is archived and a fresh CLI conversation is
+// started, seeded with the history that precedes the edited message.
+func (s *Session) EditAndResend(messageID, content string, authConfig AuthConfig) error {
+	s.mu.Lock()
+	if err := s.checkRewindable(); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+
+	index := -1
+	for i, msg := range s.Messages {
+		if msg.ID == messageID {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		s.mu.Unlock()
+		return fmt.Errorf("message not found: %s", messageID)
+	}
+	if s.Messages[index].Role != "user" {
+		s.mu.Unlock()
+		return fmt.Errorf("only user messages can be edited")
+	}
+
+	// Archive the discarded branch before truncating
+	discarded := make([]Message, len(s.Messages)-index)
+	copy(discarded, s.Messages[index:])
+	if err := ArchiveSessionMessages(s.archiveMetadata(), discarded); err != nil {
+		logger.Warn("Failed to archive rewound messages", "session", s.ID, "error", err)
+	}
+
+	s.Messages = s.Messages[:index]
+	s.historyVersion++
+	prompt := buildRewindPrompt(s.Messages, content)
+
+	// The CLI conversation still contains the discarded turns, so start a new one
+	s.conversationID = ""
+	s.contextTracker.Reset()
+
+	msg := Message{
+		ID:        fmt.Sprintf("msg-%d", time.Now().UnixNano()),
+		Role:      "user",
+		Content:   content,
+		Timestamp: time.Now(),
+	}
+	redactMessage(&msg)
+	s.Messages = append(s.Messages, msg)
+
+	// Store handler references to call after releasing lock
+	messageHandler := s.onMessage
+	statusHandler := s.onStatus
+
+	s.Status = SessionStatusRunning
+	s.UpdatedAt = time.Now()
+
+	s.mu.Unlock()
+
+	if messageHandler != nil {
+		messageHandler(msg)
+	}
+
+	if statusHandler != nil {
+		statusHandler(SessionStatusRunning)
+	}
+
+	go s.sendPrompt(msg.ID, prompt, authConfig)
+
+	return nil
+}
+
+// buildRewindPrompt seeds a fresh conversation with the transcript that precedes a rewound message
+func buildRewindPrompt(history []Message, content string) string {
+	var transcript strings.Builder
+	for _, msg := range history {
+		if msg.Metadata != nil && (msg.Metadata.Superseded || msg.Metadata.ToolUse != nil) {
+			continue
+		}
+		switch msg.Role {
+		case "user":
+			transcript.WriteString("User: " + msg.Content + "\n\n")
+		case "assistant":
+			transcript.WriteString("Assistant: " + msg.Content + "\n\n")
+		}
+	}
+
+	if transcript.Len() == 0 {
+		return content
+	}
+
+	return "Here is our conversation so far:\n\n" + transcript.String() +
+		"Continue from that point. My next message is:\n\n" + content
+}
+
+// processRunner returns the runner that starts claude
+func (s *Session) processRunner() ProcessRunner {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.runner != nil {
+		return s.runner
+	}
+	return ExecRunner{}
+}
+
+// runClaudeCommand runs a prompt through the claude CLI. The result reports
+// whether the run failed on the network before producing any output, so the
+// prompt can be queued, or was rate limited, so the turn can be resumed.
+func (s *Session) runClaudeCommand(ctx context.Context, prompt string, authConfig AuthConfig, images ...promptImage) runResult {
+	// Inject system prompt for firefighter mode
+	actualPrompt := prompt
+	s.mu.RLock()
+	if s.Mode == "firefighter" && len(s.Messages) <= 1 {
+		scope, _ := s.ModeConfig["scope"].(string)
+		systemPrompt := GetFirefighterPrompt(scope)
+		actualPrompt = systemPrompt + "\n\n" + prompt
+	}
+	s.mu.RUnlock()
+
+	// Keep file writes inside the project and hold writes to protected paths
+	// for approval, independent of the approval mode
+	var guardSettings string
+	if authConfig.FilesystemGuardrails || len(authConfig.ProtectedPaths) > 0 {
+		executable, err := os.Executable()
+		if err != nil {
+			s.handleError(fmt.Errorf("failed to locate guardrail hook: %w", err))
+			return runCompleted
+		}
+		guardSettings, err = guardHookSettings(executable, PathGuard{
+			Root:       s.ProjectPath,
+			Allowed:    authConfig.GuardrailAllowedPaths,
+			Protected:  authConfig.ProtectedPaths,
+			Unconfined: !authConfig.FilesystemGuardrails,
+		})
+		if err != nil {
+			s.handleError(fmt.Errorf("failed to configure guardrails: %w", err))
+			return runCompleted
 		}
 	}
 
-	stdout, err := cmd.StdoutPipe()
+	s.mu.Lock()
+	s.runModel = authConfig.Model
+	args := buildClaudeArgs(s.conversationID, s.currentModelLocked(), authConfig, guardSettings)
+	s.mu.Unlock()
+
+	// Each run gets its own context so a stuck command can be killed without stopping the session
+	runCtx, runCancel := context.WithCancel(ctx)
+	defer runCancel()
+	s.mu.Lock()
+	s.runCancel = runCancel
+	s.runHasOutput = false
+	s.networkFailure = false
+	s.rateLimited = false
+	s.retryAfter = 0
+	s.runFailure = ""
+	s.lastError = nil
+	s.protectedPaths = authConfig.ProtectedPaths
+	s.mu.Unlock()
+	defer s.finishCommandTracking()
+
+	cliPath, err := ResolveClaudeCLI(authConfig.ClaudeCLIPath)
 	if err != nil {
-		s.handleError(fmt.Errorf("failed to create stdout pipe: %w", err))
-		return
+		s.handleError(err)
+		return runCompleted
 	}
 
-	stderr, err := cmd.StderrPipe()
+	env := claudeEnv(authConfig)
+
+	// The prompt and approval decisions are sent on stdin
+	proc, err := s.processRunner().Start(runCtx, ProcessSpec{
+		Path: cliPath,
+		Args: args,
+		Dir:  s.ProjectPath,
+		Env:  env,
+	})
 	if err != nil {
-		s.handleError(fmt.Errorf("failed to create stderr pipe: %w", err))
-		return
+		s.handleError(classifyStartError(err))
+		return runCompleted
 	}
+	stdin, stdout, stderr := proc.Stdin(), proc.Stdout(), proc.Stderr()
 
-	if err := cmd.Start(); err != nil {
-		s.handleError(fmt.Errorf("failed to start claude: %w", err))
-		return
+	s.setRunInput(stdin)
+	if err := s.sendUserPrompt(actualPrompt, images...); err != nil {
+		s.closeRunInput()
+		runCancel()
+		proc.Wait()
+		s.handleError(err)
+		return runCompleted
 	}
 
-	// Read stderr in background and show as system messages
+	// Read stderr in background and show as system messages. The last lines
+	// describe the failure if claude exits unsuccessfully.
+	var stderrTail []string
+	stderrDone := make(chan struct{})
 	go func() {
+		defer close(stderrDone)
 		scanner := bufio.NewScanner(stderr)
 		for scanner.Scan() {
 			line := scanner.Text()
 			// Only show non-empty stderr lines
 			if strings.TrimSpace(line) != "" {
-				fmt.Printf("[claude stderr] %s\n", line)
+				stderrTail = append(stderrTail, strings.TrimSpace(line))
+				if len(stderrTail) > stderrTailLines {
+					stderrTail = stderrTail[1:]
+				}
+				logger.Debug("claude stderr", "session", s.ID, "line", redactString(line))
+				// Connectivity failures are retried rather than shown
+				if isNetworkError(line) {
+					s.mu.Lock()
+					s.networkFailure = true
+					s.mu.Unlock()
+					continue
+				}
+				// Rate limits are waited out and the turn resumed
+				if isRateLimitError(line) {
+					s.noteRateLimit(line)
+					continue
+				}
 				// Add as system message if it contains useful info
 				if strings.Contains(line, "error") || strings.Contains(line, "warning") ||
 				   strings.Contains(line, "token") || strings.Contains(line, "cost") {
@@ -366,6 +805,11 @@ func (s *Session) runClaudeCommand(prompt string, authConfig AuthConfig) {
 		}
 	}()
 
+	// Watch for Bash commands that run too long
+	watchDone := make(chan struct{})
+	defer close(watchDone)
+	go s.watchCommands(watchDone)
+
 	// Read and parse stdout
 	var responseBuilder strings.Builder
 	var currentMessageID string // Track the current streaming message
@@ -379,19 +823,51 @@ func (s *Session) runClaudeCommand(prompt string, authConfig AuthConfig) {
 	}
 
 	// Wait for command to finish
-	cmd.Wait()
+	s.closeRunInput()
+	waitErr := proc.Wait()
+	<-stderrDone
+	s.clearPendingActions()
 
 	// Flush any remaining response
 	if responseBuilder.Len() > 0 {
 		s.finalizeMessage(currentMessageID, responseBuilder.String())
 	}
 
-	// Set status back to idle
+	// A network failure before any output leaves the prompt to be retried,
+	// unless the run was killed on purpose. Other failures are retried if
+	// they may be transient.
 	s.mu.Lock()
-	if s.Status == SessionStatusRunning {
+	result := runCompleted
+	var failure *RunError
+	if runCtx.Err() == nil {
+		if s.networkFailure && !s.runHasOutput {
+			result = runNetworkFailed
+		} else if s.rateLimited {
+			result = runRateLimited
+		} else if exitCode, failed := exitStatus(waitErr); failed || s.runFailure != "" {
+			detail := s.runFailure
+			if detail == "" {
+				detail = strings.Join(stderrTail, "\n")
+			}
+			failure = classifyRunFailure(detail, exitCode)
+			if failure.Transient {
+				s.lastError = failure
+				result = runRetryable
+			}
+		}
+	}
+	s.networkFailure = false
+
+	// Set status back to idle, including when the run ended mid-approval
+	if (s.Status == SessionStatusRunning || s.Status == SessionStatusWaiting) && result == runCompleted && failure == nil {
 		s.setStatus(SessionStatusIdle)
 	}
 	s.mu.Unlock()
+
+	if failure != nil && result == runCompleted {
+		s.failRun(failure)
+	}
+	return result
 }
 
 // parseStreamLine parses a single line of stream-json output
@@ -400,10 +876,10 @@ func (s *Session) parseStreamLine(line string, responseBuilder *strings.Builder,
 		return
 	}
 
-	var event map[string]any
-	if err := json.Unmarshal([]byte(line), &event); err != nil {
+	event, err := stream.Decode([]byte(line))
+	if errors.Is(err, stream.ErrNotJSON) {
 		// Not JSON, might be plain text or verbose output
-		fmt.Printf("[claude stdout] %s\n", line)
+		logger.Debug("claude stdout", "session", s.ID, "line", redactString(line))
 		// Show informative non-JSON lines to user
 		trimmed := strings.TrimSpace(line)
 		if len(trimmed) > 0 && !strings.HasPrefix(trimmed, "[") {
@@ -411,81 +887,82 @@ func (s *Session) parseStreamLine(line string, responseBuilder *strings.Builder,
 		}
 		return
 	}
+	if err != nil {
+		logger.Debug("Unparseable claude event", "session", s.ID, "error", err, "line", redactString(line))
+		return
+	}
 
-	eventType, _ := event["type"].(string)
+	eventType := event.EventType()
 
 	// Log all events for debugging
-	eventJSON, _ := json.MarshalIndent(event, "", "  ")
-	fmt.Printf("[claude event] type=%s\n%s\n", eventType, string(eventJSON))
+	logger.Debug("claude event", "session", s.ID, "type", eventType, "line", redactString(line))
 
 	// Check for usage in ANY event type (it can appear anywhere)
-	if usage, ok := event["usage"].(map[string]any); ok {
-		fmt.Println("[parseStreamLine] Found usage at top level in event type:", eventType)
+	if usage := event.EventUsage(); usage != nil {
+		logger.Debug("Found usage at top level", "session", s.ID, "type", eventType)
 		// Process usage from any event type except streaming deltas and events that handle usage in their case statement
-		isStreamingDelta := eventType == "content_block_delta" || eventType == "message_delta"
-		hasOwnUsageHandling := eventType == "message_start" || eventType == "message_stop" || eventType == "result"
+		isStreamingDelta := eventType == stream.TypeContentBlockDelta || eventType == stream.TypeMessageDelta
+		hasOwnUsageHandling := eventType == stream.TypeMessageStart || eventType == stream.TypeMessageStop || eventType == stream.TypeResult
 		if !isStreamingDelta && !hasOwnUsageHandling {
-			s.handleUsageInfo(usage, true)
+			s.handleUsageInfo(*usage, true)
 		}
 	}
 
-	switch eventType {
-	case "system":
+	switch event := event.(type) {
+	case *stream.System:
 		// System message - extract conversation ID if present
-		if convID, ok := event["conversation_id"].(string); ok {
-			s.mu.Lock()
-			s.conversationID = convID
-			s.mu.Unlock()
+		s.mu.Lock()
+		if event.ConversationID != "" {
+			s.conversationID = event.ConversationID
 		}
-		// Also check in subtype or session_id
-		if sessionID, ok := event["session_id"].(string); ok && s.conversationID == "" {
-			s.mu.Lock()
-			s.conversationID = sessionID
-			s.mu.Unlock()
+		// Also check session_id
+		if event.SessionID != "" && s.conversationID == "" {
+			s.conversationID = event.SessionID
 		}
+		s.mu.Unlock()
 
-	case "user":
-		// User message event - just log for now
-		fmt.Println("[user event] Received user message event")
+	case *stream.User:
+		// Sub-agents' tool results go to the agent's own transcript
+		if event.ParentToolUseID != "" && s.handleSubAgentMessage(event.ParentToolUseID, event.Message) {
+			break
+		}
+		// The main agent's tool results include the Task calls of finished sub-agents
+		s.completeTaskAgents(event.Message)
 
-	case "assistant":
+	case *stream.Assistant:
+		// Sub-agent messages go to the agent's own transcript
+		if event.ParentToolUseID != "" && s.handleSubAgentMessage(event.ParentToolUseID, event.Message) {
+			break
+		}
 		// Full assistant message
-		if message, ok := event["message"].(map[string]any); ok {
-			if content, ok := message["content"].([]any); ok {
-				for _, block := range content {
-					if textBlock, ok := block.(map[string]any); ok {
-						if textBlock["type"] == "text" {
-							if text, ok := textBlock["text"].(string); ok {
-								responseBuilder.WriteString(text)
-							}
-						}
-					}
+		if event.Message != nil {
+			for _, block := range event.Message.Content {
+				if block.Type == "text" {
+					responseBuilder.WriteString(block.Text)
 				}
 			}
 		}
 
-	case "content_block_start":
+	case *stream.ContentBlockStart:
 		// New content block starting - create a new message for streaming
 		if *currentMessageID == "" {
 			*currentMessageID = s.createStreamingMessage()
 		}
 
-	case "content_block_delta":
+	case *stream.ContentBlockDelta:
 		// Streaming delta - update the message in real-time
-		if delta, ok := event["delta"].(map[string]any); ok {
-			if text, ok := delta["text"].(string); ok {
-				fmt.Printf("[content_block_delta] Received text chunk (len=%d): %s...\n", len(text), truncateString(text, 50))
-				responseBuilder.WriteString(text)
-				// Stream this update to the frontend
-				if *currentMessageID != "" {
-					s.updateStreamingMessage(*currentMessageID, responseBuilder.String())
-				} else {
-					fmt.Println("[content_block_delta] WARNING: No currentMessageID set!")
-				}
+		if text := event.Delta.Text; text != "" {
+			logger.Debug("Received text chunk", "session", s.ID, "len", len(text), "text", truncateString(redactString(text), 50))
+			responseBuilder.WriteString(text)
+			// Stream this update to the frontend
+			if *currentMessageID != "" {
+				s.updateStreamingMessage(*currentMessageID, responseBuilder.String())
+			} else {
+				logger.Warn("Text chunk without a streaming message", "session", s.ID)
 			}
 		}
 
-	case "content_block_stop":
+	case *stream.ContentBlockStop:
 		// Block finished - finalize the message
 		if responseBuilder.Len() > 0 && *currentMessageID != "" {
 			s.finalizeMessage(*currentMessageID, responseBuilder.String())
@@ -493,127 +970,133 @@ func (s *Session) parseStreamLine(line string, responseBuilder *strings.Builder,
 			*currentMessageID = ""
 		}
 
-	case "message_start":
+	case *stream.MessageStart:
 		// Extract usage info from message start (process only once)
-		var startUsage map[string]any
-
-		// Priority: top level > message
-		if usage, ok := event["usage"].(map[string]any); ok {
-			fmt.Println("[message_start] Found usage at top level")
-			startUsage = usage
-		} else if message, ok := event["message"].(map[string]any); ok {
-			if usage, ok := message["usage"].(map[string]any); ok {
-				fmt.Println("[message_start] Found usage in message")
-				startUsage = usage
-			}
+		if usage := messageUsage(event.Usage, event.Message); usage != nil {
+			s.handleUsageInfo(*usage, false)
 		}
 
-		if startUsage != nil {
-			s.handleUsageInfo(startUsage, false)
+	case *stream.MessageDelta:
+		// Handle streaming usage updates once the message is ending
+		if event.Delta.StopReason != "" && event.Usage != nil {
+			logger.Debug("Message ending with usage", "session", s.ID)
+			s.handleUsageInfo(*event.Usage, true)
 		}
 
-	case "message_delta":
-		// Handle streaming usage updates
-		if delta, ok := event["delta"].(map[string]any); ok {
-			if stopReason, ok := delta["stop_reason"].(string); ok && stopReason != "" {
-				fmt.Println("[message_delta] Message ending, checking for usage")
-				// Message is ending - check usage (process only once)
-				if usage, ok := event["usage"].(map[string]any); ok {
-					fmt.Println("[message_delta] Found usage in event")
-					s.handleUsageInfo(usage, true)
-				}
-			}
+	case *stream.MessageStop:
+		logger.Debug("Processing end of message", "session", s.ID)
+
+		// Finalize any remaining streamed content
+		if responseBuilder.Len() > 0 && *currentMessageID != "" {
+			s.finalizeMessage(*currentMessageID, responseBuilder.String())
+			responseBuilder.Reset()
+			*currentMessageID = ""
 		}
 
-	case "message_stop", "result":
-		fmt.Println("[message_stop/result] Processing end of message")
+		// Extract final usage (process only once)
+		if usage := messageUsage(event.Usage, event.Message); usage != nil {
+			s.handleUsageInfo(*usage, true)
+		}
 
-		// For "result" type events, extract the text from the result field
-		if eventType == "result" {
-			if resultText, ok := event["result"].(string); ok && resultText != "" {
-				// Create or update message with the result text
-				if *currentMessageID == "" {
-					*currentMessageID = s.createStreamingMessage()
-				}
-				s.finalizeMessage(*currentMessageID, resultText)
-				*currentMessageID = ""
-			}
-		} else {
-			// For message_stop, finalize any remaining streamed content
-			if responseBuilder.Len() > 0 && *currentMessageID != "" {
-				s.finalizeMessage(*currentMessageID, responseBuilder.String())
-				responseBuilder.Reset()
-				*currentMessageID = ""
-			}
+	case *stream.Result:
+		// A sub-agent finishing doesn't end the turn
+		if event.AgentID != "" || event.ParentToolUseID != "" {
+			s.completeSubAgent(event.AgentID, event.ParentToolUseID)
+			break
 		}
 
-		// Extract conversation ID from result if present
-		if result, ok := event["result"].(map[string]any); ok {
-			if convID, ok := result["session_id"].(string); ok {
-				s.mu.Lock()
-				s.conversationID = convID
-				s.mu.Unlock()
+		logger.Debug("Processing end of turn", "session", s.ID)
+
+		// The turn is over; closing its input lets claude exit
+		s.closeRunInput()
+
+		// Sub-agents don't outlive the turn that spawned them
+		s.completeActiveSubAgents()
+
+		resultText := event.Result.Text
+		if event.IsError {
+			// A connectivity failure before any output is retried instead of shown
+			s.mu.Lock()
+			offline := isNetworkError(resultText) && !s.runHasOutput
+			if offline {
+				s.networkFailure = true
 			}
-		}
-		// Extract final usage - check multiple locations (process only once)
-		var finalUsage map[string]any
-
-		// Priority: top level > message > result
-		if usage, ok := event["usage"].(map[string]any); ok {
-			fmt.Println("[message_stop/result] Found usage at top level")
-			finalUsage = usage
-		} else if message, ok := event["message"].(map[string]any); ok {
-			if usage, ok := message["usage"].(map[string]any); ok {
-				fmt.Println("[message_stop/result] Found usage in message")
-				finalUsage = usage
+			s.mu.Unlock()
+			if offline {
+				return
 			}
-		} else if result, ok := event["result"].(map[string]any); ok {
-			if usage, ok := result["usage"].(map[string]any); ok {
-				fmt.Println("[message_stop/result] Found usage in result")
-				finalUsage = usage
+			// Rate limits are retried once the window passes
+			if isRateLimitError(resultText) {
+				s.noteRateLimit(resultText)
+				return
+			}
+			s.noteRunFailure(resultText)
+		}
+		if resultText != "" {
+			// Create or update message with the result text
+			if *currentMessageID == "" {
+				*currentMessageID = s.createStreamingMessage()
 			}
+			s.finalizeMessage(*currentMessageID, resultText)
+			*currentMessageID = ""
+		}
+
+		// Extract conversation ID from the result if present
+		if event.Result.SessionID != "" {
+			s.mu.Lock()
+			s.conversationID = event.Result.SessionID
+			s.mu.Unlock()
 		}
 
-		if finalUsage != nil {
-			s.handleUsageInfo(finalUsage, true)
+		// Extract final usage - top level first, then the result (process only once)
+		usage := event.Usage
+		if usage == nil {
+			usage = event.Result.Usage
+		}
+		if usage != nil {
+			s.handleUsageInfo(*usage, true)
 		}
 
-	case "tool_use":
+	case *stream.ToolUse:
 		// Flush any pending text before tool use
 		if responseBuilder.Len() > 0 && *currentMessageID != "" {
 			s.finalizeMessage(*currentMessageID, responseBuilder.String())
 			responseBuilder.Reset()
 			*currentMessageID = ""
 		}
-		s.handleToolUse(event)
+		s.handleToolUse(*event)
+
+	case *stream.ToolResult:
+		s.handleToolResult(*event)
 
-	case "tool_result":
-		s.handleToolResult(event)
+	case *stream.ControlRequest:
+		// Claude is asking permission to run a tool
+		s.handleControlRequest(*event)
 
-	case "input_request":
+	case *stream.InputRequest:
 		// Claude is asking for approval
 		s.mu.Lock()
 		s.setStatus(SessionStatusWaiting)
 		s.mu.Unlock()
 
-	case "task_create", "task_update":
+	case *stream.TaskEvent:
 		// Handle task events from team agents
-		s.handleTaskEvent(event)
+		s.handleTaskEvent(*event)
 
-	case "agent_output":
-		// Handle sub-agent output from team agents
-		if text, ok := event["text"].(string); ok {
-			responseBuilder.WriteString(text)
+	case *stream.AgentOutput:
+		// Handle sub-agent output from team agents, falling back to the main
+		// response when it can't be attributed to a sub-agent
+		if event.Text != "" && !s.handleAgentOutput(*event) {
+			responseBuilder.WriteString(event.Text)
 			if *currentMessageID != "" {
 				s.updateStreamingMessage(*currentMessageID, responseBuilder.String())
 			}
 		}
 
-	case "error":
-		if errorMsg, ok := event["error"].(map[string]any); ok {
-			if message, ok := errorMsg["message"].(string); ok {
-				s.addSystemMessage("Error: " + message)
-			}
+	case *stream.Error:
+		if event.Error.Message != "" {
+			s.addSystemMessage("Error: " + event.Error.Message)
+			s.noteRunFailure(event.Error.Message)
 		}
 		s.mu.Lock()
 		s.setStatus(SessionStatusError)
@@ -621,25 +1104,29 @@ func (s *Session) parseStreamLine(line string, responseBuilder *strings.Builder,
 	}
 }
 
+// messageUsage returns an event's usage, preferring the top level over its message
+func messageUsage(usage *stream.Usage, message *stream.Message) *stream.Usage {
+	if usage != nil {
+		return usage
+	}
+	if message != nil {
+		return message.Usage
+	}
+	return nil
+}
+
 func (s *Session) handleError(err error) {
-	s.addSystemMessage("Error: " + err.Error())
+	message := "Error: " + err.Error()
+	if hint := runErrorHint(err); hint != "" {
+		message += "\n" + hint
+	}
+	s.addSystemMessage(message)
 	s.mu.Lock()
+	s.lastError = err
 	s.setStatus(SessionStatusError)
 	s.mu.Unlock()
 }
 
-// Approve approves a pending action
-func (s *Session) Approve(actionID string) error {
-	// For now, we don't support approval in print mode
-	// This would require a different approach with interactive mode
-	return fmt.Errorf("approval not supported in current mode")
-}
-
-// Reject rejects a pending action
-func (s *Session) Reject(actionID string) error {
-	return fmt.Errorf("rejection not supported in current mode")
-}
-
 // GetMessages returns a copy of all messages
 func (s *Session) GetMessages() []Message {
 	s.mu.RLock()
@@ -746,6 +1233,7 @@ func (s *Session) addAssistantMessage(content string) {
 			Agent: &agentCopy,
 		},
 	}
+	redactMessage(&msg)
 
 	s.Messages = append(s.Messages, msg)
 	s.UpdatedAt = time.Now()
@@ -761,7 +1249,12 @@ func (s *Session) addAssistantMessage(content string) {
 func (s *Session) addSystemMessage(content string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.addSystemMessageLocked(content)
+}
 
+// addSystemMessageLocked adds a system message to the transcript
+// Note: This method expects the caller to hold s.mu lock
+func (s *Session) addSystemMessageLocked(content string) {
 	// Get current agent info
 	agentInfo := s.agents[s.currentAgentID]
 	agentCopy := *agentInfo
@@ -775,6 +1268,7 @@ func (s *Session) addSystemMessage(content string) {
 			Agent: &agentCopy,
 		},
 	}
+	redactMessage(&msg)
 
 	s.Messages = append(s.Messages, msg)
 	s.UpdatedAt = time.Now()
@@ -804,6 +1298,8 @@ func (s *Session) createStreamingMessage() string {
 	agentInfo := s.agents[s.currentAgentID]
 	agentCopy := *agentInfo
 
+	s.runHasOutput = true
+
 	msgID := fmt.Sprintf("msg-%d", time.Now().UnixNano())
 	msg := Message{
 		ID:        msgID,
@@ -818,7 +1314,7 @@ func (s *Session) createStreamingMessage() string {
 	s.Messages = append(s.Messages, msg)
 	s.UpdatedAt = time.Now()
 
-	fmt.Printf("[createStreamingMessage] Created and emitting message ID=%s with empty content (will update as content streams)\n", msgID)
+	logger.Debug("Created streaming message", "session", s.ID, "message", msgID)
 
 	// Emit the message immediately so frontend knows about it
 	// Content will be added via updateStreamingMessage calls
@@ -838,20 +1334,19 @@ func (s *Session) updateStreamingMessage(messageID string, content string) {
 	for i := range s.Messages {
 		if s.Messages[i].ID == messageID {
 			s.Messages[i].Content = content
+			redactMessage(&s.Messages[i])
 			s.Messages[i].Timestamp = time.Now()
 			s.UpdatedAt = time.Now()
 
-			fmt.Printf("[updateStreamingMessage] Updated message ID=%s with content (len=%d): %s...\n",
-				messageID, len(content), truncateString(content, 100))
+			logger.Debug("Updated streaming message", "session", s.ID, "message", messageID,
+				"len", len(content), "content", truncateString(redactString(s.Messages[i].Content), 100))
 
 			// Always emit updates so frontend can see streaming content
-			if s.onMessage != nil {
-				s.onMessage(s.Messages[i])
-			}
+			s.emitStreamingUpdateLocked(s.Messages[i])
 			return
 		}
 	}
-	fmt.Printf("[updateStreamingMessage] WARNING: Message ID=%s not found!\n", messageID)
+	logger.Warn("Streaming message not found", "session", s.ID, "message", messageID)
 }
 
 // finalizeMessage marks a streaming message as complete
@@ -859,12 +1354,17 @@ func (s *Session) finalizeMessage(messageID string, content string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	// The finalized message is sent whole, so nothing more is streamed
+	if s.stream != nil && s.stream.messageID == messageID {
+		s.stopStreamLocked()
+	}
+
 	// Find the message and finalize it
 	for i := range s.Messages {
 		if s.Messages[i].ID == messageID {
 			// Skip finalizing if content is empty
 			if strings.TrimSpace(content) == "" {
-				fmt.Printf("[finalizeMessage] WARNING: Message ID=%s has empty content, removing it\n", messageID)
+				logger.Debug("Removing empty streamed message", "session", s.ID, "message", messageID)
 				// Remove the message from the list
 				s.Messages = append(s.Messages[:i], s.Messages[i+1:]...)
 				s.UpdatedAt = time.Now()
@@ -872,11 +1372,12 @@ func (s *Session) finalizeMessage(messageID string, content string) {
 			}
 
 			s.Messages[i].Content = content
+			redactMessage(&s.Messages[i])
 			s.Messages[i].Timestamp = time.Now()
 			s.UpdatedAt = time.Now()
 
-			fmt.Printf("[finalizeMessage] Finalized message ID=%s with content (len=%d): %s...\n",
-				messageID, len(content), truncateString(content, 100))
+			logger.Debug("Finalized streamed message", "session", s.ID, "message", messageID,
+				"len", len(content), "content", truncateString(redactString(s.Messages[i].Content), 100))
 
 			// Trim messages if needed
 			_ = s.TrimMessagesIfNeeded(s.maxMessages, s.archive)
@@ -887,23 +1388,28 @@ func (s *Session) finalizeMessage(messageID string, content string) {
 			return
 		}
 	}
-	fmt.Printf("[finalizeMessage] WARNING: Message ID=%s not found!\n", messageID)
+	logger.Warn("Streamed message not found", "session", s.ID, "message", messageID)
 }
 
-func (s *Session) handleToolUse(event map[string]any) {
+func (s *Session) handleToolUse(event stream.ToolUse) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	toolName, _ := event["name"].(string)
-	if toolName == "" {
-		toolName, _ = event["tool_name"].(string)
+	toolName := event.Name
+	toolID := event.ID
+	var inputRaw any
+	if event.Input != nil {
+		inputRaw = event.Input
 	}
-	toolID, _ := event["id"].(string)
-	if toolID == "" {
-		toolID, _ = event["tool_id"].(string)
-	}
-	inputRaw := event["input"]
 	input, _ := json.Marshal(inputRaw)
+	s.runHasOutput = true
+
+	// Track shell commands so long-running ones can be surfaced and killed
+	if toolName == "Bash" && event.Input != nil {
+		command, _ := event.Input["command"].(string)
+		s.trackCommandStart(toolID, command)
+	}
+	s.recordToolChangeLocked(toolName, toolID, event.Input)
 
 	// Create a human-readable description of what's happening
 	content := s.formatToolUseDescription(toolName, inputRaw)
@@ -914,7 +1420,7 @@ func (s *Session) handleToolUse(event map[string]any) {
 
 	// Check if this is a Task tool spawning a new agent
 	if toolName == "Task" {
-		s.handleTaskSpawn(inputRaw)
+		s.handleTaskSpawn(toolID, inputRaw)
 	}
 
 	msg := Message{
@@ -931,10 +1437,16 @@ func (s *Session) handleToolUse(event map[string]any) {
 			Agent: &agentCopy,
 		},
 	}
+	redactMessage(&msg)
 
 	s.Messages = append(s.Messages, msg)
 	s.UpdatedAt = time.Now()
 
+	// Firefighter sessions keep what the agent did for the postmortem
+	if s.Mode == "firefighter" && !incidentReadOnlyTools[toolName] {
+		s.recordIncidentActionLocked(msg.Content, "")
+	}
+
 	// Trim messages if needed
 	_ = s.TrimMessagesIfNeeded(s.maxMessages, s.archive)
 
@@ -944,6 +1456,11 @@ func (s *Session) handleToolUse(event map[string]any) {
 }
 
 func (s *Session) formatToolUseDescription(toolName string, input any) string {
+	// User-defined formatters take precedence over the built-in descriptions
+	if text, ok := s.formatters.FormatToolUse(toolName, input); ok {
+		return text
+	}
+
 	inputMap, ok := input.(map[string]any)
 	if !ok {
 		return fmt.Sprintf("🔧 Using tool: %s", toolName)
@@ -964,7 +1481,8 @@ func (s *Session) formatToolUseDescription(toolName string, input any) string {
 		}
 	case "Bash":
 		if command, ok := inputMap["command"].(string); ok {
-			// Truncate long commands
+			// Truncate long commands, masking secrets first so truncation can't split them
+			command = redactString(command)
 			if len(command) > 60 {
 				command = command[:60] + "..."
 			}
@@ -992,48 +1510,48 @@ func (s *Session) formatToolUseDescription(toolName string, input any) string {
 		}
 	}
 
+	if server, tool := splitMCPToolName(toolName); server != "" {
+		return fmt.Sprintf("🔌 %s: %s", server, tool)
+	}
+
 	return fmt.Sprintf("🔧 Using tool: %s", toolName)
 }
 
-func (s *Session) handleToolResult(event map[string]any) {
+// SetToolFormatters sets the user-defined formatters for tool calls and results
+func (s *Session) SetToolFormatters(formatters *ToolFormatterRegistry) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.formatters = formatters
+}
 
-	toolID, _ := event["tool_use_id"].(string)
-	if toolID == "" {
-		toolID, _ = event["tool_id"].(string)
-	}
-
-	// Handle different content formats
-	var content string
-	if contentStr, ok := event["content"].(string); ok {
-		content = contentStr
-	} else if contentArr, ok := event["content"].([]any); ok {
-		// Content might be an array of blocks
-		for _, block := range contentArr {
-			if blockMap, ok := block.(map[string]any); ok {
-				if text, ok := blockMap["text"].(string); ok {
-					content += text
-				}
-			}
+// toolNameFor returns the name of the tool invoked with toolID.
+// Note: This method expects the caller to hold s.mu lock
+func (s *Session) toolNameFor(toolID string) string {
+	for i := len(s.Messages) - 1; i >= 0; i-- {
+		if meta := s.Messages[i].Metadata; meta != nil && meta.ToolUse != nil && meta.ToolUse.ToolID == toolID {
+			return meta.ToolUse.ToolName
 		}
 	}
+	return ""
+}
 
-	isError, _ := event["is_error"].(bool)
+func (s *Session) handleToolResult(event stream.ToolResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	// Format the content for display
-	displayContent := content
-	if len(displayContent) > 500 {
-		// Truncate very long results
-		displayContent = displayContent[:500] + "... (truncated)"
-	}
+	toolID := event.ToolUseID
 
-	// Add emoji based on error status
-	prefix := "✅"
-	if isError {
-		prefix = "❌"
+	if cmd := s.trackCommandFinish(toolID); cmd != nil && s.onCommand != nil {
+		s.onCommand(*cmd)
 	}
 
+	// A Task call's result means its sub-agent is done
+	s.completeAgentLocked(s.subAgentForLocked("", toolID))
+
+	// String and block-array content both arrive joined into text
+	content := string(event.Content)
+	isError := event.IsError
+
 	// Get current agent info
 	agentInfo := s.agents[s.currentAgentID]
 	agentCopy := *agentInfo
@@ -1041,7 +1559,7 @@ func (s *Session) handleToolResult(event map[string]any) {
 	msg := Message{
 		ID:        fmt.Sprintf("msg-%d", time.Now().UnixNano()),
 		Role:      "system",
-		Content:   fmt.Sprintf("%s Tool result: %s", prefix, displayContent),
+		Content:   s.formatToolResultDescription(toolID, content, isError),
 		Timestamp: time.Now(),
 		Metadata: &MessageMetadata{
 			ToolResult: &ToolResult{
@@ -1052,6 +1570,7 @@ func (s *Session) handleToolResult(event map[string]any) {
 			Agent: &agentCopy,
 		},
 	}
+	redactMessage(&msg)
 
 	s.Messages = append(s.Messages, msg)
 	s.UpdatedAt = time.Now()
@@ -1064,7 +1583,28 @@ func (s *Session) handleToolResult(event map[string]any) {
 	}
 }
 
-func (s *Session) handleTaskSpawn(input any) {
+// formatToolResultDescription returns the transcript line for a tool result.
+// Note: This method expects the caller to hold s.mu lock
+func (s *Session) formatToolResultDescription(toolID, content string, isError bool) string {
+	// Format the content for display, masking secrets before truncation can split them
+	displayContent := redactString(content)
+	if formatted, ok := s.formatters.FormatToolResult(s.toolNameFor(toolID), displayContent); ok {
+		displayContent = formatted
+	}
+	if len(displayContent) > 500 {
+		// Truncate very long results
+		displayContent = displayContent[:500] + "... (truncated)"
+	}
+
+	// Add emoji based on error status
+	prefix := "✅"
+	if isError {
+		prefix = "❌"
+	}
+	return fmt.Sprintf("%s Tool result: %s", prefix, displayContent)
+}
+
+func (s *Session) handleTaskSpawn(toolID string, input any) {
 	inputMap, ok := input.(map[string]any)
 	if !ok {
 		return
@@ -1083,27 +1623,29 @@ func (s *Session) handleTaskSpawn(input any) {
 		ParentAgentID: s.currentAgentID,
 		Description:   description,
 		Status:        "active",
+		StartedAt:     time.Now(),
+		ToolUseID:     toolID,
 	}
 }
 
-func (s *Session) handleTaskEvent(event map[string]any) {
+func (s *Session) handleTaskEvent(event stream.TaskEvent) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	// Extract task information
-	taskData, ok := event["task"].(map[string]any)
-	if !ok {
+	taskData := event.Task
+	if taskData == nil {
 		return
 	}
 
-	taskID, _ := taskData["id"].(string)
+	taskID := taskData.ID
 	if taskID == "" {
 		taskID = fmt.Sprintf("task-%d", time.Now().UnixNano())
 	}
 
-	subject, _ := taskData["subject"].(string)
-	description, _ := taskData["description"].(string)
-	status, _ := taskData["status"].(string)
+	subject := taskData.Subject
+	description := taskData.Description
+	status := taskData.Status
 	if status == "" {
 		status = "pending"
 	}
@@ -1138,39 +1680,30 @@ func (s *Session) handleTaskEvent(event map[string]any) {
 	}
 }
 
-func (s *Session) handleUsageInfo(usage map[string]any, isFinal bool) {
+func (s *Session) handleUsageInfo(usage stream.Usage, isFinal bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	fmt.Printf("[handleUsageInfo] Received usage data (isFinal=%v): %+v\n", isFinal, usage)
+	logger.Debug("Received usage", "session", s.ID, "final", isFinal, "usage", fmt.Sprintf("%+v", usage))
 
-	inputTokens := int(0)
-	outputTokens := int(0)
+	inputTokens := usage.InputTokens
+	outputTokens := usage.OutputTokens
 
-	if val, ok := usage["input_tokens"].(float64); ok {
-		inputTokens = int(val)
-	}
-	if val, ok := usage["output_tokens"].(float64); ok {
-		outputTokens = int(val)
-	}
+	logger.Debug("Parsed usage", "session", s.ID, "input", inputTokens, "output", outputTokens)
 
-	fmt.Printf("[handleUsageInfo] Parsed tokens: input=%d, output=%d\n", inputTokens, outputTokens)
-
-	// Calculate approximate cost (based on Sonnet 4 pricing as example)
-	// $3 per million input tokens, $15 per million output tokens
-	inputCost := float64(inputTokens) * 3.0 / 1_000_000
-	outputCost := float64(outputTokens) * 15.0 / 1_000_000
-	totalCost := inputCost + outputCost
+	// Price the usage for the model the run used
+	totalCost := calculateCost(s.currentModelLocked(), usage)
 
 	costInfo := &CostInfo{
-		InputTokens:  inputTokens,
-		OutputTokens: outputTokens,
-		TotalCost:    totalCost,
+		InputTokens:      inputTokens,
+		OutputTokens:     outputTokens,
+		CacheWriteTokens: usage.CacheCreationInputTokens,
+		CacheReadTokens:  usage.CacheReadInputTokens,
+		TotalCost:        totalCost,
 	}
 
 	// Add or update a system message with token usage
-	msgContent := fmt.Sprintf("📊 Token usage: %d input, %d output (≈$%.4f)",
-		inputTokens, outputTokens, totalCost)
+	msgContent := "📊 Token usage: " + usageSummary(*costInfo)
 
 	// Get current agent info
 	agentInfo := s.agents[s.currentAgentID]
@@ -1189,7 +1722,7 @@ func (s *Session) handleUsageInfo(usage map[string]any, isFinal bool) {
 
 	// Only add message if it's a final update (to avoid spam)
 	if isFinal && (inputTokens > 0 || outputTokens > 0) {
-		fmt.Printf("[handleUsageInfo] Adding usage message to session\n")
+		logger.Debug("Adding usage message", "session", s.ID)
 		s.Messages = append(s.Messages, msg)
 		s.UpdatedAt = time.Now()
 
@@ -1199,7 +1732,20 @@ func (s *Session) handleUsageInfo(usage map[string]any, isFinal bool) {
 		if s.onMessage != nil {
 			s.onMessage(msg)
 		}
+
+		// Warn once the conversation nears the end of the context window
+		if s.contextTracker.Record(usage, s.currentModelLocked()) {
+			s.addSystemMessageLocked(contextWarning(s.contextTracker.Usage(s.currentModelLocked())))
+		}
+	}
+}
+
+// currentModelLocked returns the model the current run uses. Caller must hold s.mu.
+func (s *Session) currentModelLocked() string {
+	if s.runModel != "" {
+		return s.runModel
 	}
+	return s.Model
 }
 
 func (s *Session) setStatus(status SessionStatus) {
@@ -1210,6 +1756,18 @@ func (s *Session) setStatus(status SessionStatus) {
 	}
 }
 
+// archiveMetadata returns the session metadata recorded alongside archived messages
+// Note: This method expects the caller to hold s.mu lock
+func (s *Session) archiveMetadata() ArchiveData {
+	tags := make([]string, len(s.Tags))
+	copy(tags, s.Tags)
+	return ArchiveData{
+		SessionID:   s.ID,
+		ProjectPath: s.ProjectPath,
+		Tags:        tags,
+	}
+}
+
 // TrimMessagesIfNeeded limits message history and optionally archives overflow
 // Note: This method expects the caller to hold s.mu lock
 func (s *Session) TrimMessagesIfNeeded(maxMessages int, archive bool) error {
@@ -1220,16 +1778,21 @@ func (s *Session) TrimMessagesIfNeeded(maxMessages int, archive bool) error {
 	// Calculate how many messages to remove
 	overflow := len(s.Messages) - maxMessages
 	messagesToArchive := s.Messages[:overflow]
-	s.Messages = s.Messages[overflow:]
 
 	// Archive if enabled
 	if archive && len(messagesToArchive) > 0 {
-		if err := ArchiveMessages(s.ID, messagesToArchive); err != nil {
+		if err := ArchiveSessionMessages(s.archiveMetadata(), messagesToArchive); err != nil {
 			// Log error but don't fail - we still trimmed the messages
-			fmt.Printf("Warning: failed to archive messages: %v\n", err)
+			logger.Warn("Failed to archive messages", "session", s.ID, "error", err)
 		}
 	}
 
+	// Drop the trimmed messages from the backing array so they can be freed
+	// before append next reallocates it
+	clear(messagesToArchive)
+	s.Messages = s.Messages[overflow:]
+	s.historyVersion++
+
 	s.UpdatedAt = time.Now()
 	return nil
 }
@@ -1291,6 +1854,7 @@ func (s *Session) CleanupCompletedAgents(maxAgents int, keepCompleted bool) {
 	agentsToDelete := len(s.agents) - maxAgents
 	for i := 0; i < len(completed) && agentsToDelete > 0; i++ {
 		delete(s.agents, completed[i].id)
+		delete(s.agentMessages, completed[i].id)
 		agentsToDelete--
 	}
 }
@@ -1399,42 +1963,63 @@ func (s *Session) GetFirefighterMonitorStatus() map[string]interface{} {
 	return s.firefighterMonitor.GetStatus()
 }
 
-// InvestigateLinearTicket triggers investigation for a specific Linear ticket
-func (s *Session) InvestigateLinearTicket(linearIssueID string) error {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// firefighterMonitorFor returns the session's firefighter monitor, creating it
+// if needed, so it can be used without holding the lock
+func (s *Session) firefighterMonitorFor() (*FirefighterMonitor, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
 	if s.Mode != "firefighter" {
-		return fmt.Errorf("not a firefighter session")
+		return nil, fmt.Errorf("not a firefighter session")
 	}
 
 	if s.firefighterMonitor == nil {
-		s.mu.RUnlock()
-		s.mu.Lock()
 		s.firefighterMonitor = NewFirefighterMonitor(s)
-		s.mu.Unlock()
-		s.mu.RLock()
 	}
+	return s.firefighterMonitor, nil
+}
 
-	return s.firefighterMonitor.InvestigateLinearTicket(linearIssueID)
+// InvestigateLinearTicket triggers investigation for a specific Linear ticket
+func (s *Session) InvestigateLinearTicket(linearIssueID string) error {
+	monitor, err := s.firefighterMonitorFor()
+	if err != nil {
+		return err
+	}
+	return monitor.InvestigateLinearTicket(linearIssueID)
 }
 
 // InvestigateSlackAlert triggers investigation for a Slack alert
 func (s *Session) InvestigateSlackAlert(slackThreadID, alertMessage string) error {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	if s.Mode != "firefighter" {
-		return fmt.Errorf("not a firefighter session")
+	monitor, err := s.firefighterMonitorFor()
+	if err != nil {
+		return err
 	}
+	return monitor.InvestigateSlackAlert(slackThreadID, alertMessage)
+}
 
-	if s.firefighterMonitor == nil {
-		s.mu.RUnlock()
-		s.mu.Lock()
-		s.firefighterMonitor = NewFirefighterMonitor(s)
-		s.mu.Unlock()
-		s.mu.RLock()
+// InvestigateAlert records an alert received by webhook and sends the session
+// to investigate it
+func (s *Session) InvestigateAlert(alert Alert, authConfig AuthConfig) error {
+	monitor, err := s.firefighterMonitorFor()
+	if err != nil {
+		return err
 	}
+	return monitor.InvestigateAlert(alert, authConfig)
+}
 
-	return s.firefighterMonitor.InvestigateSlackAlert(slackThreadID, alertMessage)
+// alertTarget reports whether the session is a usable firefighter session for
+// projectPath, whether a run is in progress, and when it last changed
+func (s *Session) alertTarget(projectPath string) (ok, busy bool, updatedAt time.Time) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	switch s.Status {
+	case SessionStatusStopped, SessionStatusError:
+		return false, false, s.UpdatedAt
+	case SessionStatusIdle:
+	default:
+		busy = true
+	}
+	ok = s.Mode == "firefighter" && filepath.Clean(s.ProjectPath) == filepath.Clean(projectPath)
+	return ok, busy, s.UpdatedAt
 }
@@ -270,3 +270,258 @@ func TestSessionHandlers_CanSafelyAccessSession(t *testing.T) {
 		t.Fatal("Deadlock detected - handler cannot access session")
 	}
 }
+
+func newRegenerateTestSession() *Session {
+	session := NewSession("test-session", "/tmp/test")
+	session.ctx, session.cancel = context.WithCancel(context.Background())
+	session.Messages = []Message{
+		{ID: "u1", Role: "user", Content: "first question"},
+		{ID: "a1", Role: "assistant", Content: "first answer"},
+		{ID: "u2", Role: "user", Content: "second question"},
+		{ID: "a2", Role: "assistant", Content: "second answer"},
+		{ID: "s2", Role: "system", Content: "📊 Token usage", Metadata: &MessageMetadata{CostInfo: &CostInfo{}}},
+	}
+	return session
+}
+
+func TestRegenerateLast_MarksSuperseded(t *testing.T) {
+	session := newRegenerateTestSession()
+	defer session.cancel()
+
+	var mu sync.Mutex
+	emitted := map[string]bool{}
+	session.SetMessageHandler(func(msg Message) {
+		mu.Lock()
+		defer mu.Unlock()
+		if msg.Metadata != nil && msg.Metadata.Superseded {
+			emitted[msg.ID] = true
+		}
+	})
+
+	if err := session.RegenerateLast(AuthConfig{}, RegenerateOptions{Model: "opus"}); err != nil {
+		t.Fatalf("RegenerateLast failed: %v", err)
+	}
+
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+
+	for _, msg := range session.Messages {
+		superseded := msg.Metadata != nil && msg.Metadata.Superseded
+		switch msg.ID {
+		case "u1", "a1", "u2":
+			if superseded {
+				t.Errorf("Message %s should not be superseded", msg.ID)
+			}
+		case "a2", "s2":
+			if !superseded {
+				t.Errorf("Message %s should be superseded", msg.ID)
+			}
+		}
+	}
+	mu.Lock()
+	if !emitted["a2"] || !emitted["s2"] {
+		t.Errorf("Expected superseded messages to be emitted, got %v", emitted)
+	}
+	mu.Unlock()
+}
+
+func TestRegenerateLast_ModelForOneRun(t *testing.T) {
+	session := newRegenerateTestSession()
+	defer session.cancel()
+	session.Model = "sonnet"
+	runner := &fakeRunner{}
+	session.runner = runner
+	done := make(chan struct{}, 1)
+	session.SetStatusHandler(func(status SessionStatus) {
+		if status != SessionStatusRunning {
+			done <- struct{}{}
+		}
+	})
+
+	authConfig := AuthConfig{ClaudeCLIPath: fakeClaudeBinary(t)}
+	if err := session.RegenerateLast(authConfig, RegenerateOptions{Model: "opus"}); err != nil {
+		t.Fatalf("RegenerateLast failed: %v", err)
+	}
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Timed out waiting for the run")
+	}
+
+	if !strings.Contains(strings.Join(runner.spec.Args, " "), "--model opus") {
+		t.Errorf("Expected the regenerated run to use opus, got %v", runner.spec.Args)
+	}
+	session.mu.RLock()
+	model := session.Model
+	session.mu.RUnlock()
+	if model != "sonnet" {
+		t.Errorf("Expected the session to keep its model, got %s", model)
+	}
+}
+
+func TestRegenerateLast_Replace(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	session := newRegenerateTestSession()
+	defer session.cancel()
+
+	if err := session.RegenerateLast(AuthConfig{}, RegenerateOptions{Replace: true}); err != nil {
+		t.Fatalf("RegenerateLast failed: %v", err)
+	}
+
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+
+	for _, msg := range session.Messages {
+		if msg.ID == "a2" || msg.ID == "s2" {
+			t.Errorf("Message %s should have been removed", msg.ID)
+		}
+	}
+	if len(session.Messages) < 3 || session.Messages[2].ID != "u2" {
+		t.Error("Expected history up to the last user message to be kept")
+	}
+
+	archived, err := LoadArchivedMessages(session.ID)
+	if err != nil {
+		t.Fatalf("LoadArchivedMessages failed: %v", err)
+	}
+	if len(archived) != 2 || archived[0].ID != "a2" || archived[1].ID != "s2" {
+		t.Fatalf("Expected the replaced response and its usage to be archived, got %+v", archived)
+	}
+	if archived[1].Metadata == nil || archived[1].Metadata.CostInfo == nil {
+		t.Error("Expected the archived usage message to keep its cost info")
+	}
+}
+
+func TestRegenerateLast_Errors(t *testing.T) {
+	t.Run("no user message", func(t *testing.T) {
+		session := NewSession("test-session", "/tmp/test")
+		session.ctx, session.cancel = context.WithCancel(context.Background())
+		defer session.cancel()
+
+		err := session.RegenerateLast(AuthConfig{}, RegenerateOptions{})
+		if err == nil || !strings.Contains(err.Error(), "no user message") {
+			t.Errorf("Expected no user message error, got %v", err)
+		}
+	})
+
+	// Only a session between turns can be regenerated
+	for _, status := range []SessionStatus{SessionStatusRunning, SessionStatusWaiting, SessionStatusQueued, SessionStatusRateLimited, SessionStatusOffline} {
+		t.Run("busy session "+string(status), func(t *testing.T) {
+			session := newRegenerateTestSession()
+			defer session.cancel()
+			session.Status = status
+
+			err := session.RegenerateLast(AuthConfig{}, RegenerateOptions{})
+			if err == nil || !strings.Contains(err.Error(), "busy") {
+				t.Errorf("Expected busy error, got %v", err)
+			}
+			if len(session.Messages) != 5 {
+				t.Errorf("Expected the history untouched, got %d messages", len(session.Messages))
+			}
+		})
+	}
+
+	t.Run("stopped session", func(t *testing.T) {
+		session := newRegenerateTestSession()
+		defer session.cancel()
+		session.Status = SessionStatusStopped
+
+		if err := session.RegenerateLast(AuthConfig{}, RegenerateOptions{}); err == nil {
+			t.Error("Expected error for stopped session")
+		}
+	})
+}
+
+func TestRegenerateLast_AfterError(t *testing.T) {
+	session := newRegenerateTestSession()
+	defer session.cancel()
+	session.Status = SessionStatusError
+
+	if err := session.RegenerateLast(AuthConfig{}, RegenerateOptions{}); err != nil {
+		t.Errorf("Expected a failed turn to be regenerated, got %v", err)
+	}
+}
+
+func TestEditAndResend_RewindsHistory(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	session := newRegenerateTestSession()
+	defer session.cancel()
+	session.conversationID = "conv-old"
+
+	if err := session.EditAndResend("u2", "edited question", AuthConfig{}); err != nil {
+		t.Fatalf("EditAndResend failed: %v", err)
+	}
+
+	session.mu.RLock()
+	if len(session.Messages) < 3 {
+		session.mu.RUnlock()
+		t.Fatalf("Expected at least 3 messages, got %d", len(session.Messages))
+	}
+	if session.Messages[0].ID != "u1" || session.Messages[1].ID != "a1" {
+		t.Error("Expected history before the edited message to be kept")
+	}
+	edited := session.Messages[2]
+	if edited.Role != "user" || edited.Content != "edited question" {
+		t.Errorf("Expected edited user message, got %+v", edited)
+	}
+	for _, msg := range session.Messages {
+		if msg.ID == "u2" || msg.ID == "a2" || msg.ID == "s2" {
+			t.Errorf("Message %s should have been discarded", msg.ID)
+		}
+	}
+	session.mu.RUnlock()
+
+	count, err := GetArchivedMessageCount(session.ID)
+	if err != nil {
+		t.Fatalf("GetArchivedMessageCount failed: %v", err)
+	}
+	if count != 3 {
+		t.Errorf("Expected 3 archived messages, got %d", count)
+	}
+}
+
+func TestEditAndResend_Errors(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	session := newRegenerateTestSession()
+	defer session.cancel()
+
+	if err := session.EditAndResend("missing", "x", AuthConfig{}); err == nil {
+		t.Error("Expected error for unknown message")
+	}
+	if err := session.EditAndResend("a1", "x", AuthConfig{}); err == nil {
+		t.Error("Expected error when editing an assistant message")
+	}
+
+	session.Status = SessionStatusQueued
+	if err := session.EditAndResend("u1", "x", AuthConfig{}); err == nil || !strings.Contains(err.Error(), "busy") {
+		t.Errorf("Expected busy error for a queued session, got %v", err)
+	}
+}
+
+func TestBuildRewindPrompt(t *testing.T) {
+	if got := buildRewindPrompt(nil, "hello"); got != "hello" {
+		t.Errorf("Expected plain content for empty history, got %q", got)
+	}
+
+	history := []Message{
+		{Role: "user", Content: "q1"},
+		{Role: "assistant", Content: "📖 Reading file: a.go", Metadata: &MessageMetadata{ToolUse: &ToolUse{ToolName: "Read"}}},
+		{Role: "system", Content: "✅ Tool result: ok"},
+		{Role: "assistant", Content: "a1"},
+		{Role: "assistant", Content: "old", Metadata: &MessageMetadata{Superseded: true}},
+	}
+	got := buildRewindPrompt(history, "q2")
+
+	if !strings.Contains(got, "User: q1") || !strings.Contains(got, "Assistant: a1") {
+		t.Errorf("Expected transcript in prompt, got %q", got)
+	}
+	if strings.Contains(got, "Reading file") || strings.Contains(got, "Tool result") || strings.Contains(got, "old") {
+		t.Errorf("Expected tool and superseded messages to be skipped, got %q", got)
+	}
+	if !strings.HasSuffix(got, "q2") {
+		t.Errorf("Expected prompt to end with new content, got %q", got)
+	}
+}
@@ -4,12 +4,30 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"boatman/stream"
 )
 
+// decodeEvent converts a raw stream-json event into its typed form the way
+// the stream decoder would
+func decodeEvent[T any](t *testing.T, raw map[string]any) T {
+	t.Helper()
+	var event T
+	data, err := json.Marshal(raw)
+	if err != nil {
+		t.Fatalf("Failed to marshal event: %v", err)
+	}
+	if err := json.Unmarshal(data, &event); err != nil {
+		t.Fatalf("Failed to decode event: %v", err)
+	}
+	return event
+}
+
 // TestNewSession tests session initialization
 func TestNewSession(t *testing.T) {
 	tests := []struct {
@@ -251,7 +269,7 @@ func TestSetHandlers(t *testing.T) {
 				"status":      "pending",
 			},
 		}
-		session.handleTaskEvent(event)
+		session.handleTaskEvent(decodeEvent[stream.TaskEvent](t, event))
 
 		if !called {
 			t.Error("Task handler was not called")
@@ -706,7 +724,7 @@ func TestHandleToolUse(t *testing.T) {
 			},
 		}
 
-		session.handleToolUse(event)
+		session.handleToolUse(decodeEvent[stream.ToolUse](t, event))
 
 		if receivedMsg.Role != "assistant" {
 			t.Errorf("Expected role 'assistant', got %s", receivedMsg.Role)
@@ -739,7 +757,7 @@ func TestHandleToolUse(t *testing.T) {
 		}
 
 		initialAgentCount := len(session.agents)
-		session.handleToolUse(event)
+		session.handleToolUse(decodeEvent[stream.ToolUse](t, event))
 		newAgentCount := len(session.agents)
 
 		if newAgentCount != initialAgentCount+1 {
@@ -844,7 +862,7 @@ func TestHandleToolResult(t *testing.T) {
 				receivedMsg = msg
 			})
 
-			session.handleToolResult(tt.event)
+			session.handleToolResult(decodeEvent[stream.ToolResult](t, tt.event))
 
 			if tt.checkFunc != nil {
 				tt.checkFunc(t, receivedMsg)
@@ -875,7 +893,7 @@ func TestHandleTaskEvent(t *testing.T) {
 			},
 		}
 
-		session.handleTaskEvent(event)
+		session.handleTaskEvent(decodeEvent[stream.TaskEvent](t, event))
 
 		if !taskCalled {
 			t.Error("Task handler was not called")
@@ -912,7 +930,7 @@ func TestHandleTaskEvent(t *testing.T) {
 				"status":      "pending",
 			},
 		}
-		session.handleTaskEvent(event1)
+		session.handleTaskEvent(decodeEvent[stream.TaskEvent](t, event1))
 
 		// Update task
 		event2 := map[string]any{
@@ -924,7 +942,7 @@ func TestHandleTaskEvent(t *testing.T) {
 				"status":      "in_progress",
 			},
 		}
-		session.handleTaskEvent(event2)
+		session.handleTaskEvent(decodeEvent[stream.TaskEvent](t, event2))
 
 		tasks := session.GetTasks()
 		if len(tasks) != 1 {
@@ -947,7 +965,7 @@ func TestHandleTaskEvent(t *testing.T) {
 			},
 		}
 
-		session.handleTaskEvent(event)
+		session.handleTaskEvent(decodeEvent[stream.TaskEvent](t, event))
 
 		tasks := session.GetTasks()
 		if len(tasks) != 1 {
@@ -975,7 +993,7 @@ func TestHandleTaskEvent(t *testing.T) {
 			},
 		}
 
-		session.handleTaskEvent(event)
+		session.handleTaskEvent(decodeEvent[stream.TaskEvent](t, event))
 
 		tasks := session.GetTasks()
 		if len(tasks) != 1 {
@@ -998,7 +1016,7 @@ func TestHandleUsageInfo(t *testing.T) {
 			"output_tokens": float64(500),
 		}
 
-		session.handleUsageInfo(usage, false)
+		session.handleUsageInfo(decodeEvent[stream.Usage](t, usage), false)
 
 		// Non-final usage should not create a message
 		messages := session.GetMessages()
@@ -1015,7 +1033,7 @@ func TestHandleUsageInfo(t *testing.T) {
 			"output_tokens": float64(500),
 		}
 
-		session.handleUsageInfo(usage, true)
+		session.handleUsageInfo(decodeEvent[stream.Usage](t, usage), true)
 
 		messages := session.GetMessages()
 		if len(messages) != 1 {
@@ -1059,6 +1077,39 @@ func TestHandleUsageInfo(t *testing.T) {
 		}
 	})
 
+	t.Run("prices usage for the session model", func(t *testing.T) {
+		session := NewSession("test-session", "/path/to/project")
+		session.Model = "opus"
+
+		usage := map[string]any{
+			"input_tokens":                float64(1000),
+			"output_tokens":               float64(500),
+			"cache_creation_input_tokens": float64(2000),
+			"cache_read_input_tokens":     float64(10000),
+		}
+
+		session.handleUsageInfo(decodeEvent[stream.Usage](t, usage), true)
+
+		messages := session.GetMessages()
+		if len(messages) != 1 {
+			t.Fatalf("Expected 1 message, got %d", len(messages))
+		}
+
+		costInfo := messages[0].Metadata.CostInfo
+		if costInfo.CacheWriteTokens != 2000 || costInfo.CacheReadTokens != 10000 {
+			t.Errorf("Expected cache tokens 2000/10000, got %d/%d", costInfo.CacheWriteTokens, costInfo.CacheReadTokens)
+		}
+
+		expectedCost := (1000*5.0 + 500*25.0 + 2000*6.25 + 10000*0.50) / 1_000_000
+		if math.Abs(costInfo.TotalCost-expectedCost) > 1e-12 {
+			t.Errorf("Expected cost %.6f, got %.6f", expectedCost, costInfo.TotalCost)
+		}
+
+		if !strings.Contains(messages[0].Content, "10000 cache read") {
+			t.Errorf("Expected message to mention cache reads, got %q", messages[0].Content)
+		}
+	})
+
 	t.Run("handle zero usage", func(t *testing.T) {
 		session := NewSession("test-session", "/path/to/project")
 
@@ -1067,7 +1118,7 @@ func TestHandleUsageInfo(t *testing.T) {
 			"output_tokens": float64(0),
 		}
 
-		session.handleUsageInfo(usage, true)
+		session.handleUsageInfo(decodeEvent[stream.Usage](t, usage), true)
 
 		messages := session.GetMessages()
 		if len(messages) != 1 {
@@ -1128,8 +1179,8 @@ func TestGetMessagesAndTasks(t *testing.T) {
 			},
 		}
 
-		session.handleTaskEvent(event1)
-		session.handleTaskEvent(event2)
+		session.handleTaskEvent(decodeEvent[stream.TaskEvent](t, event1))
+		session.handleTaskEvent(decodeEvent[stream.TaskEvent](t, event2))
 
 		tasks1 := session.GetTasks()
 		tasks2 := session.GetTasks()
@@ -1283,7 +1334,7 @@ func TestConcurrency(t *testing.T) {
 						"status":  "pending",
 					},
 				}
-				session.handleTaskEvent(event)
+				session.handleTaskEvent(decodeEvent[stream.TaskEvent](t, event))
 			}(i)
 		}
 
@@ -1369,7 +1420,7 @@ func TestEdgeCases(t *testing.T) {
 		}
 
 		// Should not crash
-		session.handleTaskEvent(event)
+		session.handleTaskEvent(decodeEvent[stream.TaskEvent](t, event))
 
 		tasks := session.GetTasks()
 		if len(tasks) != 0 {
@@ -1386,7 +1437,7 @@ func TestEdgeCases(t *testing.T) {
 		}
 
 		// Should not crash
-		session.handleToolUse(event)
+		session.handleToolUse(decodeEvent[stream.ToolUse](t, event))
 
 		messages := session.GetMessages()
 		if len(messages) != 1 {
@@ -1411,7 +1462,7 @@ func TestEdgeCases(t *testing.T) {
 		}
 
 		// Should not crash
-		session.handleToolResult(event)
+		session.handleToolResult(decodeEvent[stream.ToolResult](t, event))
 
 		messages := session.GetMessages()
 		if len(messages) != 1 {
@@ -1428,7 +1479,7 @@ func TestEdgeCases(t *testing.T) {
 		}
 
 		// Should not crash, should default to 0
-		session.handleUsageInfo(usage, true)
+		session.handleUsageInfo(decodeEvent[stream.Usage](t, usage), true)
 
 		messages := session.GetMessages()
 		if len(messages) != 1 {
@@ -1444,7 +1495,7 @@ func TestEdgeCases(t *testing.T) {
 		}
 	})
 
-	t.Run("approve and reject not supported", func(t *testing.T) {
+	t.Run("approve and reject without pending actions", func(t *testing.T) {
 		session := NewSession("test-session", "/path/to/project")
 
 		err := session.Approve("action-1")
@@ -1614,7 +1665,7 @@ func TestAgentTracking(t *testing.T) {
 		}
 
 		initialCount := len(session.agents)
-		session.handleTaskSpawn(input)
+		session.handleTaskSpawn("toolu-1", input)
 		newCount := len(session.agents)
 
 		if newCount != initialCount+1 {
@@ -1651,7 +1702,7 @@ func TestAgentTracking(t *testing.T) {
 		session := NewSession("test-session", "/path/to/project")
 
 		// Invalid input (not a map)
-		session.handleTaskSpawn("not a map")
+		session.handleTaskSpawn("toolu-2", "not a map")
 
 		// Should not crash, should not add agent
 		if len(session.agents) != 1 {
@@ -1762,3 +1813,81 @@ func TestContextCancellation(t *testing.T) {
 		}
 	})
 }
+
+func TestCancelCurrentRun(t *testing.T) {
+	session := NewSession("test-cancel", "/tmp/test")
+	session.ctx = context.Background()
+
+	if err := session.CancelCurrentRun(); err == nil {
+		t.Error("Expected an error with no run in progress")
+	}
+
+	ctx, done := session.beginTurn()
+	session.mu.Lock()
+	session.setStatus(SessionStatusRunning)
+	session.mu.Unlock()
+
+	if err := session.CancelCurrentRun(); err != nil {
+		t.Fatalf("CancelCurrentRun failed: %v", err)
+	}
+	select {
+	case <-ctx.Done():
+	default:
+		t.Fatal("Expected the run context to be canceled")
+	}
+	if session.ctx.Err() != nil {
+		t.Error("Expected the session context to stay usable")
+	}
+
+	done()
+	if session.Status != SessionStatusIdle {
+		t.Errorf("Expected status idle, got %s", session.Status)
+	}
+	messages := session.GetMessages()
+	if len(messages) != 1 || !strings.Contains(messages[0].Content, "cancelled") {
+		t.Errorf("Expected a cancellation message, got %v", messages)
+	}
+	if err := session.CancelCurrentRun(); err == nil {
+		t.Error("Expected an error once the run has ended")
+	}
+}
+
+func TestCancelCurrentRunDuringRateLimit(t *testing.T) {
+	session := NewSession("test-cancel-ratelimit", "/tmp/test")
+	session.ctx = context.Background()
+
+	ctx, done := session.beginTurn()
+	waited := make(chan bool)
+	go func() {
+		waited <- session.waitForRateLimit(ctx, time.Minute, 1)
+	}()
+
+	// Wait for the countdown to start
+	deadline := time.Now().Add(time.Second)
+	for {
+		session.mu.RLock()
+		status := session.Status
+		session.mu.RUnlock()
+		if status == SessionStatusRateLimited || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if err := session.CancelCurrentRun(); err != nil {
+		t.Fatalf("CancelCurrentRun failed: %v", err)
+	}
+	select {
+	case resumed := <-waited:
+		if resumed {
+			t.Error("Expected a canceled run not to resume")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the wait to end when the run is canceled")
+	}
+
+	done()
+	if session.Status != SessionStatusIdle {
+		t.Errorf("Expected status idle, got %s", session.Status)
+	}
+}
@@ -0,0 +1,149 @@
+package agent
+
+import (
+	"fmt"
+	"time"
+
+	"boatman/git"
+)
+
+// SetSnapshotBeforeRun sets whether the project is snapshotted before
+// full-auto runs, so their changes can be undone
+func (s *Session) SetSnapshotBeforeRun(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.SnapshotBeforeRun = enabled
+	s.UpdatedAt = time.Now()
+}
+
+// SetSnapshotHandler sets the callback for when a snapshot is taken, or
+// cleared with nil after it is restored or discarded
+func (s *Session) SetSnapshotHandler(handler func(*git.Snapshot)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onSnapshot = handler
+}
+
+// GetSnapshot returns the snapshot that can be restored, or nil
+func (s *Session) GetSnapshot() *git.Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.snapshot == nil {
+		return nil
+	}
+	snapshot := *s.snapshot
+	return &snapshot
+}
+
+// snapshotWorkspace snapshots the project before a full-auto run when the
+// session asks for it. A snapshot that hasn't been restored or discarded yet
+// is kept, so restoring undoes every run since it was taken.
+func (s *Session) snapshotWorkspace(authConfig AuthConfig) {
+	s.mu.RLock()
+	wanted := s.SnapshotBeforeRun && s.snapshot == nil
+	s.mu.RUnlock()
+	if !wanted || authConfig.ApprovalMode != "full-auto" || authConfig.PlanOnly {
+		return
+	}
+
+	repo := git.NewRepository(s.ProjectPath)
+	if !repo.IsGitRepo() {
+		return
+	}
+	snapshot, err := repo.CreateSnapshot("boatman: before run in session " + s.ID)
+	if err != nil {
+		s.addSystemMessage(fmt.Sprintf("⚠️  Couldn't snapshot the workspace before this run: %v", err))
+		return
+	}
+
+	s.setSnapshot(snapshot)
+	s.addSystemMessage("📸 Saved a snapshot of the workspace. Restore it to undo the agent's changes.")
+}
+
+// RestoreSnapshot puts the project back the way it was when the snapshot was
+// taken, discarding the agent's changes and commits since
+func (s *Session) RestoreSnapshot() error {
+	s.mu.RLock()
+	snapshot := s.snapshot
+	running := s.turnCancel != nil
+	s.mu.RUnlock()
+	if snapshot == nil {
+		return fmt.Errorf("no snapshot to restore")
+	}
+	if running {
+		return fmt.Errorf("can't restore the snapshot while a run is in progress")
+	}
+
+	repo := git.NewRepository(s.ProjectPath)
+	if err := repo.RestoreSnapshot(*snapshot); err != nil {
+		return fmt.Errorf("failed to restore snapshot: %w", err)
+	}
+	if err := repo.DropSnapshot(*snapshot); err != nil {
+		return fmt.Errorf("restored the snapshot but failed to remove it: %w", err)
+	}
+
+	s.setSnapshot(nil)
+	s.addSystemMessage(fmt.Sprintf("⏪ Restored the workspace to the snapshot from %s", snapshot.CreatedAt.Format("15:04:05")))
+	return nil
+}
+
+// DiscardSnapshot keeps the agent's changes and forgets the snapshot
+func (s *Session) DiscardSnapshot() error {
+	s.mu.RLock()
+	snapshot := s.snapshot
+	s.mu.RUnlock()
+	if snapshot == nil {
+		return nil
+	}
+
+	if err := git.NewRepository(s.ProjectPath).DropSnapshot(*snapshot); err != nil {
+		return fmt.Errorf("failed to discard snapshot: %w", err)
+	}
+	s.setSnapshot(nil)
+	return nil
+}
+
+// setSnapshot records the snapshot that can be restored and reports it
+func (s *Session) setSnapshot(snapshot *git.Snapshot) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.snapshot = snapshot
+	s.UpdatedAt = time.Now()
+	if s.onSnapshot != nil {
+		s.onSnapshot(snapshot)
+	}
+}
+
+// SetSnapshotBeforeRun sets whether a session snapshots its project before full-auto runs
+func (m *Manager) SetSnapshotBeforeRun(sessionID string, enabled bool) error {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	session.SetSnapshotBeforeRun(enabled)
+	return SaveSession(session)
+}
+
+// RestoreSnapshot undoes a session's full-auto runs since its snapshot
+func (m *Manager) RestoreSnapshot(sessionID string) error {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if err := session.RestoreSnapshot(); err != nil {
+		return err
+	}
+	return SaveSession(session)
+}
+
+// DiscardSnapshot accepts a session's changes since its snapshot
+func (m *Manager) DiscardSnapshot(sessionID string) error {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	if err := session.DiscardSnapshot(); err != nil {
+		return err
+	}
+	return SaveSession(session)
+}
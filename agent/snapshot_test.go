@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// initGitProject creates a git repository with one commit of file.txt
+func initGitProject(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "file.txt"), []byte("original"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+	for _, args := range [][]string{
+		{"init"},
+		{"add", "."},
+		{"-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-m", "Initial commit"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, output)
+		}
+	}
+	return dir
+}
+
+func TestSnapshotBeforeRun(t *testing.T) {
+	binary := fakeClaudeBinary(t)
+	success := `{"type":"result","subtype":"success","result":"Done"}` + "\n"
+
+	t.Run("full-auto runs are snapshotted and restorable", func(t *testing.T) {
+		dir := initGitProject(t)
+		session := NewSession("test-snapshot", dir)
+		session.runner = &fakeRunner{stdout: success}
+		session.SetSnapshotBeforeRun(true)
+		authConfig := AuthConfig{ClaudeCLIPath: binary, ApprovalMode: "full-auto"}
+
+		session.runWithRateLimitRetry("edit the file", authConfig)
+		first := session.GetSnapshot()
+		if first == nil {
+			t.Fatal("Expected a snapshot")
+		}
+
+		// The agent's changes
+		os.WriteFile(filepath.Join(dir, "file.txt"), []byte("agent edit"), 0644)
+		os.WriteFile(filepath.Join(dir, "new.txt"), []byte("generated"), 0644)
+
+		// Later runs keep the first snapshot
+		session.runWithRateLimitRetry("more", authConfig)
+		if snapshot := session.GetSnapshot(); snapshot == nil || *snapshot != *first {
+			t.Errorf("Expected the first snapshot kept, got %+v", snapshot)
+		}
+
+		if err := session.RestoreSnapshot(); err != nil {
+			t.Fatalf("RestoreSnapshot failed: %v", err)
+		}
+		content, _ := os.ReadFile(filepath.Join(dir, "file.txt"))
+		if string(content) != "original" {
+			t.Errorf("Expected the file restored, got %q", content)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "new.txt")); !os.IsNotExist(err) {
+			t.Error("Expected the agent's new file removed")
+		}
+		if session.GetSnapshot() != nil {
+			t.Error("Expected the snapshot cleared")
+		}
+		if err := session.RestoreSnapshot(); err == nil {
+			t.Error("Expected an error without a snapshot")
+		}
+	})
+
+	t.Run("other approval modes aren't snapshotted", func(t *testing.T) {
+		dir := initGitProject(t)
+		session := NewSession("test-snapshot-suggest", dir)
+		session.runner = &fakeRunner{stdout: success}
+		session.SetSnapshotBeforeRun(true)
+
+		session.runWithRateLimitRetry("hi", AuthConfig{ClaudeCLIPath: binary, ApprovalMode: "suggest"})
+		if session.GetSnapshot() != nil {
+			t.Error("Expected no snapshot outside full-auto")
+		}
+	})
+
+	t.Run("discarding keeps the changes", func(t *testing.T) {
+		dir := initGitProject(t)
+		session := NewSession("test-snapshot-discard", dir)
+		session.runner = &fakeRunner{stdout: success}
+		session.SetSnapshotBeforeRun(true)
+
+		session.runWithRateLimitRetry("hi", AuthConfig{ClaudeCLIPath: binary, ApprovalMode: "full-auto"})
+		os.WriteFile(filepath.Join(dir, "file.txt"), []byte("agent edit"), 0644)
+
+		if err := session.DiscardSnapshot(); err != nil {
+			t.Fatalf("DiscardSnapshot failed: %v", err)
+		}
+		content, _ := os.ReadFile(filepath.Join(dir, "file.txt"))
+		if string(content) != "agent edit" || session.GetSnapshot() != nil {
+			t.Errorf("Expected the changes kept and the snapshot cleared, got %q", content)
+		}
+
+		// The snapshot survives a restart
+		session.SetSnapshotBeforeRun(true)
+		session.runWithRateLimitRetry("hi", AuthConfig{ClaudeCLIPath: binary, ApprovalMode: "full-auto"})
+		restored := sessionFromData(newSessionData(session))
+		if !restored.SnapshotBeforeRun || restored.GetSnapshot() == nil {
+			t.Error("Expected the restored session's snapshot")
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrSessionNotFound is returned by a Store for a session it doesn't hold
+var ErrSessionNotFound = errors.New("session not found")
+
+// Store durably persists sessions and their archived messages
+type Store interface {
+	SaveSession(data SessionData) error
+	// LoadSession returns ErrSessionNotFound for an unknown session
+	LoadSession(sessionID string) (*SessionData, error)
+	LoadSessions() ([]SessionData, error)
+	DeleteSession(sessionID string) error
+
+	// ArchiveMessages appends messages trimmed from a session and records its metadata
+	ArchiveMessages(meta ArchiveData, messages []Message) error
+	// LoadArchive returns an empty archive for a session without one
+	LoadArchive(sessionID string) (*ArchiveData, error)
+	// LoadArchivePage returns up to limit archived messages from offset and
+	// how many the session has archived in all
+	LoadArchivePage(sessionID string, offset, limit int) ([]Message, int, error)
+	DeleteArchive(sessionID string) error
+
+	// Reseal rewrites everything stored for the current encryption setting
+	Reseal() (int, error)
+
+	Close() error
+}
+
+var (
+	storeMu     sync.RWMutex
+	activeStore Store
+)
+
+// UseStore makes store the backend for session persistence. With no store,
+// sessions are saved as JSON files in the sessions directory.
+func UseStore(store Store) {
+	storeMu.Lock()
+	defer storeMu.Unlock()
+	activeStore = store
+}
+
+// ActiveStore returns the store sessions persist to, or nil when they use JSON files
+func ActiveStore() Store {
+	storeMu.RLock()
+	defer storeMu.RUnlock()
+	return activeStore
+}
@@ -0,0 +1,131 @@
+package agent
+
+import (
+	"strings"
+	"time"
+)
+
+// DefaultStreamBatchInterval is how long streamed text is gathered before
+// it's sent to the frontend, unless configured otherwise
+const DefaultStreamBatchInterval = 50 * time.Millisecond
+
+// streamFullSyncInterval is how often a streaming message is re-sent whole,
+// so a frontend that missed a delta catches up
+const streamFullSyncInterval = 2 * time.Second
+
+// streamState tracks what the frontend has of the message being streamed
+type streamState struct {
+	messageID string
+	sent      string // Content the frontend has
+	lastSync  time.Time
+	timer     *time.Timer // Pending flush, nil when nothing is waiting
+}
+
+// SetMessageDeltaHandler sets the callback for text appended to a streaming
+// message. With one set, streaming updates are sent as deltas, batched by
+// the stream batch interval, with the whole message re-sent now and then.
+func (s *Session) SetMessageDeltaHandler(handler func(messageID, delta string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onMessageDelta = handler
+}
+
+// SetStreamBatchInterval sets how long streamed text is gathered before it's
+// sent; zero sends every delta as it arrives
+func (s *Session) SetStreamBatchInterval(interval time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.streamBatchInterval = interval
+}
+
+// emitStreamingUpdateLocked sends a streaming message's new content, as a
+// delta once batched when a delta handler is set
+// Note: This method expects the caller to hold s.mu lock
+func (s *Session) emitStreamingUpdateLocked(msg Message) {
+	if s.onMessageDelta == nil {
+		if s.onMessage != nil {
+			s.onMessage(msg)
+		}
+		return
+	}
+
+	if s.stream == nil || s.stream.messageID != msg.ID {
+		s.stopStreamLocked()
+		s.stream = &streamState{messageID: msg.ID, lastSync: time.Now()}
+	}
+	if s.streamBatchInterval <= 0 {
+		s.flushStreamLocked(msg)
+		return
+	}
+	if s.stream.timer == nil {
+		messageID := msg.ID
+		s.stream.timer = time.AfterFunc(s.streamBatchInterval, func() { s.flushStream(messageID) })
+	}
+}
+
+// flushStream sends the text gathered for a streaming message since the last flush
+func (s *Session) flushStream(messageID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stream == nil || s.stream.messageID != messageID {
+		return // Finalized in the meantime
+	}
+	s.stream.timer = nil
+	for _, msg := range s.Messages {
+		if msg.ID == messageID {
+			s.flushStreamLocked(msg)
+			return
+		}
+	}
+}
+
+// flushStreamLocked sends what the frontend doesn't have of msg: the appended
+// text, or the whole message when it's time for a full sync or redaction
+// changed text already sent
+// Note: This method expects the caller to hold s.mu lock
+func (s *Session) flushStreamLocked(msg Message) {
+	state := s.stream
+	if strings.HasPrefix(msg.Content, state.sent) && time.Since(state.lastSync) < streamFullSyncInterval {
+		if delta := msg.Content[len(state.sent):]; delta != "" {
+			s.onMessageDelta(msg.ID, delta)
+		}
+	} else {
+		if s.onMessage != nil {
+			s.onMessage(msg)
+		}
+		state.lastSync = time.Now()
+	}
+	state.sent = msg.Content
+}
+
+// stopStreamLocked drops the streaming state, e.g. once the message is
+// finalized and sent whole
+// Note: This method expects the caller to hold s.mu lock
+func (s *Session) stopStreamLocked() {
+	if s.stream == nil {
+		return
+	}
+	if s.stream.timer != nil {
+		s.stream.timer.Stop()
+	}
+	s.stream = nil
+}
+
+// SetStreamBatchInterval sets how long every session gathers streamed text
+// before sending it to the frontend. Zero uses DefaultStreamBatchInterval
+// and a negative interval sends every delta as it arrives.
+func (m *Manager) SetStreamBatchInterval(interval time.Duration) {
+	if interval == 0 {
+		interval = DefaultStreamBatchInterval
+	} else if interval < 0 {
+		interval = 0
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.streamBatchInterval = interval
+	for _, session := range m.sessions {
+		session.SetStreamBatchInterval(interval)
+	}
+}
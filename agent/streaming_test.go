@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// streamRecorder collects what a session sends while streaming
+type streamRecorder struct {
+	mu       sync.Mutex
+	deltas   []string
+	messages []string
+}
+
+func (r *streamRecorder) attach(s *Session, deltas bool) {
+	s.SetMessageHandler(func(msg Message) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.messages = append(r.messages, msg.Content)
+	})
+	if deltas {
+		s.SetMessageDeltaHandler(func(messageID, delta string) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.deltas = append(r.deltas, delta)
+		})
+	}
+}
+
+func (r *streamRecorder) snapshot() ([]string, []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.deltas...), append([]string(nil), r.messages...)
+}
+
+func TestStreamingDeltas(t *testing.T) {
+	tests := []struct {
+		name     string
+		deltas   bool
+		updates  []string
+		expected []string // Deltas sent
+		messages []string // Whole messages sent, after the empty one that starts the stream
+	}{
+		{"without a delta handler", false, []string{"Hel", "Hello"}, nil, []string{"Hel", "Hello"}},
+		{"appended text", true, []string{"Hel", "Hello", "Hello!"}, []string{"Hel", "lo", "!"}, nil},
+		{"rewritten text", true, []string{"Hello", "Help"}, []string{"Hello"}, []string{"Help"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := NewSession("stream", "/tmp/project")
+			session.SetStreamBatchInterval(0)
+			var recorder streamRecorder
+			recorder.attach(session, tt.deltas)
+
+			id := session.createStreamingMessage()
+			for _, content := range tt.updates {
+				session.updateStreamingMessage(id, content)
+			}
+
+			deltas, messages := recorder.snapshot()
+			if !reflect.DeepEqual(deltas, tt.expected) {
+				t.Errorf("Expected deltas %q, got %q", tt.expected, deltas)
+			}
+			if !reflect.DeepEqual(messages[1:], append([]string{}, tt.messages...)) {
+				t.Errorf("Expected messages %q, got %q", tt.messages, messages[1:])
+			}
+		})
+	}
+}
+
+func TestStreamingBatches(t *testing.T) {
+	session := NewSession("stream", "/tmp/project")
+	session.SetStreamBatchInterval(20 * time.Millisecond)
+	var recorder streamRecorder
+	recorder.attach(session, true)
+
+	id := session.createStreamingMessage()
+	for _, content := range []string{"a", "ab", "abc"} {
+		session.updateStreamingMessage(id, content)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if deltas, _ := recorder.snapshot(); len(deltas) > 0 {
+			if !reflect.DeepEqual(deltas, []string{"abc"}) {
+				t.Errorf("Expected one batched delta, got %q", deltas)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the batched delta")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Finalizing sends the whole message and drops the pending batch
+	session.updateStreamingMessage(id, "abcd")
+	session.finalizeMessage(id, "abcde")
+	time.Sleep(50 * time.Millisecond)
+	deltas, messages := recorder.snapshot()
+	if len(deltas) != 1 {
+		t.Errorf("Expected no delta after finalizing, got %q", deltas)
+	}
+	if last := messages[len(messages)-1]; last != "abcde" {
+		t.Errorf("Expected the finalized message sent whole, got %q", last)
+	}
+}
+
+func TestManagerStreamBatchInterval(t *testing.T) {
+	m := NewManager()
+	session, err := m.CreateSession("/test/project")
+	if err != nil {
+		t.Fatalf("CreateSession failed: %v", err)
+	}
+
+	tests := []struct {
+		interval time.Duration
+		expected time.Duration
+	}{
+		{0, DefaultStreamBatchInterval},
+		{-1, 0},
+		{200 * time.Millisecond, 200 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		m.SetStreamBatchInterval(tt.interval)
+		session.mu.RLock()
+		got := session.streamBatchInterval
+		session.mu.RUnlock()
+		if got != tt.expected {
+			t.Errorf("SetStreamBatchInterval(%v): expected %v, got %v", tt.interval, tt.expected, got)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"boatman/stream"
+)
+
+// SetAgentMessageHandler sets the callback for messages added to a sub-agent's transcript
+func (s *Session) SetAgentMessageHandler(handler func(agentID string, msg Message)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onAgentMessage = handler
+}
+
+// GetAgents returns the session's agents, the main agent first and then
+// sub-agents in the order they started
+func (s *Session) GetAgents() []AgentInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	agents := make([]AgentInfo, 0, len(s.agents))
+	for _, agent := range s.agents {
+		agents = append(agents, *agent)
+	}
+	sort.Slice(agents, func(i, j int) bool {
+		if (agents[i].AgentID == "main") != (agents[j].AgentID == "main") {
+			return agents[i].AgentID == "main"
+		}
+		if !agents[i].StartedAt.Equal(agents[j].StartedAt) {
+			return agents[i].StartedAt.Before(agents[j].StartedAt)
+		}
+		return agents[i].AgentID < agents[j].AgentID
+	})
+	return agents
+}
+
+// GetAgentMessages returns a sub-agent's own transcript. The main agent's
+// messages are the session's messages.
+func (s *Session) GetAgentMessages(agentID string) ([]Message, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.agents[agentID]; !ok {
+		return nil, fmt.Errorf("agent %s not found", agentID)
+	}
+	if agentID == "main" {
+		return append([]Message{}, s.Messages...), nil
+	}
+	return append([]Message{}, s.agentMessages[agentID]...), nil
+}
+
+// handleAgentOutput adds streamed sub-agent text to that agent's transcript.
+// Output without an agent ID goes to the most recently started active
+// sub-agent. It returns false if there's no sub-agent to attribute it to.
+func (s *Session) handleAgentOutput(event stream.AgentOutput) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	agentID := s.subAgentForLocked(event.AgentID, event.ParentToolUseID)
+	if agentID == "" && event.AgentID != "" {
+		// An agent the CLI started without a Task call we saw
+		agentID = event.AgentID
+		s.agents[agentID] = &AgentInfo{
+			AgentID:       agentID,
+			AgentType:     "task",
+			ParentAgentID: s.currentAgentID,
+			Status:        "active",
+			StartedAt:     time.Now(),
+		}
+	}
+	if agentID == "" && event.ParentToolUseID == "" {
+		agentID = s.latestActiveSubAgentLocked()
+	}
+	if agentID == "" {
+		return false
+	}
+
+	// Streamed chunks extend the agent's last reply rather than each becoming a message
+	messages := s.agentMessages[agentID]
+	if n := len(messages); n > 0 && isPlainAgentReply(messages[n-1]) {
+		messages[n-1].Content += event.Text
+		redactMessage(&messages[n-1])
+		s.notifyAgentMessageLocked(agentID, messages[n-1])
+		return true
+	}
+	s.appendAgentMessageLocked(agentID, Message{
+		ID:        fmt.Sprintf("msg-%d", time.Now().UnixNano()),
+		Role:      "assistant",
+		Content:   event.Text,
+		Timestamp: time.Now(),
+	})
+	return true
+}
+
+// handleSubAgentMessage adds a sub-agent's message, identified by the Task
+// call that spawned the agent, to the agent's transcript. It returns false
+// for an unknown Task call.
+func (s *Session) handleSubAgentMessage(parentToolUseID string, message *stream.Message) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	agentID := s.subAgentForLocked("", parentToolUseID)
+	if agentID == "" {
+		return false
+	}
+	if message == nil {
+		return true
+	}
+
+	for _, block := range message.Content {
+		msg := Message{
+			ID:        fmt.Sprintf("msg-%d", time.Now().UnixNano()),
+			Role:      "assistant",
+			Timestamp: time.Now(),
+		}
+		switch block.Type {
+		case "text":
+			if block.Text == "" {
+				continue
+			}
+			msg.Content = block.Text
+		case "tool_use":
+			var input any
+			if block.Input != nil {
+				input = block.Input
+			}
+			raw, _ := json.Marshal(input)
+			msg.Content = s.formatToolUseDescription(block.Name, input)
+			msg.Metadata = &MessageMetadata{ToolUse: &ToolUse{ToolName: block.Name, ToolID: block.ID, Input: raw}}
+		case "tool_result":
+			content := string(block.Content)
+			msg.Role = "system"
+			msg.Content = s.formatToolResultDescription(block.ToolUseID, content, block.IsError)
+			msg.Metadata = &MessageMetadata{ToolResult: &ToolResult{ToolID: block.ToolUseID, Content: content, IsError: block.IsError}}
+		default:
+			continue
+		}
+		s.appendAgentMessageLocked(agentID, msg)
+	}
+	return true
+}
+
+// completeSubAgent marks the sub-agent with the given ID, or spawned by the
+// given Task call, completed
+func (s *Session) completeSubAgent(agentID, toolUseID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.completeAgentLocked(s.subAgentForLocked(agentID, toolUseID))
+}
+
+// completeTaskAgents marks the sub-agents whose Task calls have results in message completed
+func (s *Session) completeTaskAgents(message *stream.Message) {
+	if message == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, block := range message.Content {
+		if block.Type == "tool_result" {
+			s.completeAgentLocked(s.subAgentForLocked("", block.ToolUseID))
+		}
+	}
+}
+
+// completeActiveSubAgents marks every sub-agent still running completed
+func (s *Session) completeActiveSubAgents() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id := range s.agents {
+		s.completeAgentLocked(id)
+	}
+}
+
+// completeAgentLocked marks an active sub-agent completed. The main agent and
+// agents already completed are left alone.
+// Note: This method expects the caller to hold s.mu lock
+func (s *Session) completeAgentLocked(agentID string) {
+	agent, ok := s.agents[agentID]
+	if !ok || agentID == "main" || agent.Status == "completed" {
+		return
+	}
+	agent.Status = "completed"
+	agent.CompletedAt = time.Now()
+	s.UpdatedAt = time.Now()
+}
+
+// subAgentForLocked finds a known sub-agent by ID or by the Task call that spawned it.
+// Note: This method expects the caller to hold s.mu lock
+func (s *Session) subAgentForLocked(agentID, toolUseID string) string {
+	if agentID != "" && agentID != "main" {
+		if _, ok := s.agents[agentID]; ok {
+			return agentID
+		}
+	}
+	if toolUseID != "" {
+		for id, agent := range s.agents {
+			if agent.ToolUseID == toolUseID {
+				return id
+			}
+		}
+	}
+	return ""
+}
+
+// latestActiveSubAgentLocked returns the most recently started sub-agent still running.
+// Note: This method expects the caller to hold s.mu lock
+func (s *Session) latestActiveSubAgentLocked() string {
+	var latest *AgentInfo
+	for id, agent := range s.agents {
+		if id == "main" || agent.Status != "active" {
+			continue
+		}
+		if latest == nil || agent.StartedAt.After(latest.StartedAt) {
+			latest = agent
+		}
+	}
+	if latest == nil {
+		return ""
+	}
+	return latest.AgentID
+}
+
+// appendAgentMessageLocked adds a message to a sub-agent's transcript, keeping
+// it within the session's message limit.
+// Note: This method expects the caller to hold s.mu lock
+func (s *Session) appendAgentMessageLocked(agentID string, msg Message) {
+	if msg.Metadata == nil {
+		msg.Metadata = &MessageMetadata{}
+	}
+	agentCopy := *s.agents[agentID]
+	msg.Metadata.Agent = &agentCopy
+	redactMessage(&msg)
+
+	if s.agentMessages == nil {
+		s.agentMessages = make(map[string][]Message)
+	}
+	messages := append(s.agentMessages[agentID], msg)
+	if s.maxMessages > 0 && len(messages) > s.maxMessages {
+		messages = append([]Message(nil), messages[len(messages)-s.maxMessages:]...)
+	}
+	s.agentMessages[agentID] = messages
+	s.UpdatedAt = time.Now()
+
+	s.notifyAgentMessageLocked(agentID, msg)
+}
+
+// notifyAgentMessageLocked reports a new or updated sub-agent message.
+// Note: This method expects the caller to hold s.mu lock
+func (s *Session) notifyAgentMessageLocked(agentID string, msg Message) {
+	if s.onAgentMessage != nil {
+		s.onAgentMessage(agentID, msg)
+	}
+}
+
+// isPlainAgentReply reports whether msg is sub-agent text that streamed output can extend
+func isPlainAgentReply(msg Message) bool {
+	return msg.Role == "assistant" && (msg.Metadata == nil || (msg.Metadata.ToolUse == nil && msg.Metadata.ToolResult == nil))
+}
+
+// copyAgentMessages copies sub-agent transcripts for saving
+func copyAgentMessages(agentMessages map[string][]Message) map[string][]Message {
+	if len(agentMessages) == 0 {
+		return nil
+	}
+	copied := make(map[string][]Message, len(agentMessages))
+	for agentID, messages := range agentMessages {
+		copied[agentID] = append([]Message{}, messages...)
+	}
+	return copied
+}
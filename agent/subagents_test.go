@@ -0,0 +1,296 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+
+	"boatman/stream"
+)
+
+// spawnTestAgent starts a sub-agent through a Task tool call and returns its ID
+func spawnTestAgent(t *testing.T, session *Session, toolID, description string) string {
+	t.Helper()
+	session.handleTaskSpawn(toolID, map[string]any{"description": description, "subagent_type": "explore"})
+	for id, agent := range session.agents {
+		if agent.ToolUseID == toolID {
+			return id
+		}
+	}
+	t.Fatalf("Expected an agent for %s", toolID)
+	return ""
+}
+
+func TestSubAgentTranscripts(t *testing.T) {
+	parse := func(session *Session, lines ...string) {
+		var responseBuilder strings.Builder
+		var currentMessageID string
+		for _, line := range lines {
+			session.parseStreamLine(line, &responseBuilder, &currentMessageID)
+		}
+	}
+
+	t.Run("agent output by parent tool use", func(t *testing.T) {
+		session := NewSession("test-session", "/path/to/project")
+		agentID := spawnTestAgent(t, session, "toolu-1", "Find the handler")
+
+		parse(session,
+			`{"type":"agent_output","text":"Searching ","parent_tool_use_id":"toolu-1"}`,
+			`{"type":"agent_output","text":"the repo","parent_tool_use_id":"toolu-1"}`,
+		)
+
+		messages, err := session.GetAgentMessages(agentID)
+		if err != nil {
+			t.Fatalf("GetAgentMessages failed: %v", err)
+		}
+		if len(messages) != 1 || messages[0].Content != "Searching the repo" {
+			t.Fatalf("Expected one merged message, got %+v", messages)
+		}
+		if agent := messages[0].Metadata.Agent; agent == nil || agent.AgentID != agentID {
+			t.Errorf("Expected the message attributed to %s, got %+v", agentID, agent)
+		}
+		if len(session.GetMessages()) != 0 {
+			t.Errorf("Expected the main transcript to stay empty, got %d messages", len(session.GetMessages()))
+		}
+	})
+
+	t.Run("agent output by agent id registers the agent", func(t *testing.T) {
+		session := NewSession("test-session", "/path/to/project")
+
+		parse(session, `{"type":"agent_output","text":"hello","agent_id":"cli-agent-7"}`)
+
+		messages, err := session.GetAgentMessages("cli-agent-7")
+		if err != nil {
+			t.Fatalf("GetAgentMessages failed: %v", err)
+		}
+		if len(messages) != 1 || messages[0].Content != "hello" {
+			t.Errorf("Expected the agent's output, got %+v", messages)
+		}
+	})
+
+	t.Run("unattributed output goes to the latest active agent", func(t *testing.T) {
+		session := NewSession("test-session", "/path/to/project")
+		first := spawnTestAgent(t, session, "toolu-1", "first")
+		second := spawnTestAgent(t, session, "toolu-2", "second")
+		session.agents[second].StartedAt = session.agents[first].StartedAt.Add(1)
+
+		parse(session, `{"type":"agent_output","text":"working"}`)
+
+		if messages, _ := session.GetAgentMessages(second); len(messages) != 1 {
+			t.Errorf("Expected the latest agent to get the output, got %d messages", len(messages))
+		}
+		if messages, _ := session.GetAgentMessages(first); len(messages) != 0 {
+			t.Errorf("Expected the earlier agent to get nothing, got %d messages", len(messages))
+		}
+	})
+
+	t.Run("output without sub-agents stays in the main transcript", func(t *testing.T) {
+		session := NewSession("test-session", "/path/to/project")
+
+		var responseBuilder strings.Builder
+		var currentMessageID string
+		session.parseStreamLine(`{"type":"agent_output","text":"main output"}`, &responseBuilder, &currentMessageID)
+
+		if responseBuilder.String() != "main output" {
+			t.Errorf("Expected the output in the main response, got %q", responseBuilder.String())
+		}
+		if len(session.agentMessages) != 0 {
+			t.Errorf("Expected no agent transcripts, got %d", len(session.agentMessages))
+		}
+	})
+
+	t.Run("sub-agent messages", func(t *testing.T) {
+		session := NewSession("test-session", "/path/to/project")
+		agentID := spawnTestAgent(t, session, "toolu-1", "Find the handler")
+
+		var notified []string
+		session.SetAgentMessageHandler(func(id string, msg Message) {
+			notified = append(notified, id)
+		})
+
+		parse(session,
+			`{"type":"assistant","parent_tool_use_id":"toolu-1","message":{"content":[{"type":"text","text":"Let me look."},{"type":"tool_use","id":"toolu-9","name":"Grep","input":{"pattern":"handler"}}]}}`,
+			`{"type":"user","parent_tool_use_id":"toolu-1","message":{"content":[{"type":"tool_result","tool_use_id":"toolu-9","content":"main.go:12"}]}}`,
+			`{"type":"assistant","parent_tool_use_id":"toolu-unknown","message":{"content":[{"type":"text","text":"stray"}]}}`,
+		)
+
+		messages, _ := session.GetAgentMessages(agentID)
+		if len(messages) != 3 {
+			t.Fatalf("Expected 3 agent messages, got %d", len(messages))
+		}
+		if messages[1].Metadata.ToolUse == nil || messages[1].Metadata.ToolUse.ToolName != "Grep" {
+			t.Errorf("Expected Grep tool use, got %+v", messages[1].Metadata.ToolUse)
+		}
+		if messages[2].Metadata.ToolResult == nil || messages[2].Metadata.ToolResult.Content != "main.go:12" {
+			t.Errorf("Expected the tool result, got %+v", messages[2].Metadata.ToolResult)
+		}
+		if len(notified) != 3 || notified[0] != agentID {
+			t.Errorf("Expected 3 notifications for %s, got %v", agentID, notified)
+		}
+
+		for _, msg := range session.GetMessages() {
+			if strings.Contains(msg.Content, "Let me look.") {
+				t.Error("Expected sub-agent text to stay out of the main transcript")
+			}
+		}
+	})
+
+	t.Run("unknown agent", func(t *testing.T) {
+		session := NewSession("test-session", "/path/to/project")
+		if _, err := session.GetAgentMessages("agent-missing"); err == nil {
+			t.Error("Expected an error for an unknown agent")
+		}
+	})
+}
+
+func TestGetAgents(t *testing.T) {
+	session := NewSession("test-session", "/path/to/project")
+	first := spawnTestAgent(t, session, "toolu-1", "first")
+	second := spawnTestAgent(t, session, "toolu-2", "second")
+	session.agents[first].StartedAt = session.agents[second].StartedAt.Add(1)
+
+	agents := session.GetAgents()
+	if len(agents) != 3 {
+		t.Fatalf("Expected 3 agents, got %d", len(agents))
+	}
+	if agents[0].AgentID != "main" || agents[1].AgentID != second || agents[2].AgentID != first {
+		t.Errorf("Expected main, %s, %s, got %s, %s, %s", second, first, agents[0].AgentID, agents[1].AgentID, agents[2].AgentID)
+	}
+}
+
+func TestSubAgentTranscriptPersistence(t *testing.T) {
+	useTestStore(t)
+
+	session := NewSession("test-subagents", "/path/to/project")
+	agentID := spawnTestAgent(t, session, "toolu-1", "Find the handler")
+	session.handleAgentOutput(stream.AgentOutput{Text: "found it", ParentToolUseID: "toolu-1"})
+
+	if err := SaveSession(session); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+	loaded, err := LoadSession(session.ID)
+	if err != nil {
+		t.Fatalf("LoadSession failed: %v", err)
+	}
+
+	messages, err := loaded.GetAgentMessages(agentID)
+	if err != nil {
+		t.Fatalf("GetAgentMessages failed: %v", err)
+	}
+	if len(messages) != 1 || messages[0].Content != "found it" {
+		t.Errorf("Expected the saved agent transcript, got %+v", messages)
+	}
+}
+
+func TestCleanupCompletedAgentsDropsTranscripts(t *testing.T) {
+	session := NewSession("test-session", "/path/to/project")
+	agentID := spawnTestAgent(t, session, "toolu-1", "done")
+	session.handleAgentOutput(stream.AgentOutput{Text: "finished", ParentToolUseID: "toolu-1"})
+	spawnTestAgent(t, session, "toolu-2", "running")
+	session.MarkAgentCompleted(agentID)
+
+	session.CleanupCompletedAgents(2, false)
+
+	if _, ok := session.agentMessages[agentID]; ok {
+		t.Error("Expected the removed agent's transcript to be dropped")
+	}
+}
+
+// closeRecorder is a run input that records being closed
+type closeRecorder struct {
+	strings.Builder
+	closed bool
+}
+
+func (c *closeRecorder) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestAgentCompletion(t *testing.T) {
+	parse := func(session *Session, line string) {
+		var responseBuilder strings.Builder
+		var currentMessageID string
+		session.parseStreamLine(line, &responseBuilder, &currentMessageID)
+	}
+
+	tests := []struct {
+		name string
+		line string
+	}{
+		{"tool result event", `{"type":"tool_result","tool_use_id":"toolu-1","content":"done"}`},
+		{"user tool result", `{"type":"user","message":{"content":[{"type":"tool_result","tool_use_id":"toolu-1","content":"done"}]}}`},
+		{"sub-agent result by parent tool use", `{"type":"result","result":"done","parent_tool_use_id":"toolu-1"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := NewSession("test-session", "/path/to/project")
+			finished := spawnTestAgent(t, session, "toolu-1", "finished")
+			running := spawnTestAgent(t, session, "toolu-2", "running")
+
+			parse(session, tt.line)
+
+			if agent := session.agents[finished]; agent.Status != "completed" || agent.CompletedAt.IsZero() {
+				t.Errorf("Expected %s completed, got status %q at %v", finished, agent.Status, agent.CompletedAt)
+			}
+			if status := session.agents[running].Status; status != "active" {
+				t.Errorf("Expected %s still active, got %q", running, status)
+			}
+			if status := session.agents["main"].Status; status == "completed" {
+				t.Error("Expected the main agent not to be completed")
+			}
+		})
+	}
+
+	t.Run("sub-agent result by agent id keeps the turn going", func(t *testing.T) {
+		session := NewSession("test-session", "/path/to/project")
+		agentID := spawnTestAgent(t, session, "toolu-1", "finished")
+		input := &closeRecorder{}
+		session.runInput = input
+
+		parse(session, `{"type":"result","result":"sub-agent summary","agent_id":"`+agentID+`"}`)
+
+		if session.agents[agentID].Status != "completed" {
+			t.Errorf("Expected %s completed, got %q", agentID, session.agents[agentID].Status)
+		}
+		if input.closed {
+			t.Error("Expected a sub-agent result not to end the turn")
+		}
+		if len(session.GetMessages()) != 0 {
+			t.Errorf("Expected no main transcript messages, got %d", len(session.GetMessages()))
+		}
+	})
+
+	t.Run("end of turn completes running sub-agents", func(t *testing.T) {
+		session := NewSession("test-session", "/path/to/project")
+		first := spawnTestAgent(t, session, "toolu-1", "first")
+		second := spawnTestAgent(t, session, "toolu-2", "second")
+
+		parse(session, `{"type":"result","result":"all done"}`)
+
+		for _, id := range []string{first, second} {
+			if session.agents[id].Status != "completed" {
+				t.Errorf("Expected %s completed, got %q", id, session.agents[id].Status)
+			}
+		}
+		if session.agents["main"].Status == "completed" {
+			t.Error("Expected the main agent not to be completed")
+		}
+	})
+
+	t.Run("completed agents can be cleaned up", func(t *testing.T) {
+		session := NewSession("test-session", "/path/to/project")
+		finished := spawnTestAgent(t, session, "toolu-1", "finished")
+		spawnTestAgent(t, session, "toolu-2", "running")
+
+		parse(session, `{"type":"tool_result","tool_use_id":"toolu-1","content":"done"}`)
+		session.CleanupCompletedAgents(2, false)
+
+		if _, ok := session.agents[finished]; ok {
+			t.Errorf("Expected %s to be cleaned up", finished)
+		}
+		if len(session.agents) != 2 {
+			t.Errorf("Expected 2 agents left, got %d", len(session.agents))
+		}
+	})
+}
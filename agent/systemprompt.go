@@ -0,0 +1,33 @@
+package agent
+
+import (
+	"strings"
+	"time"
+)
+
+// SetSystemPromptOverride sets the system prompt addition used instead of
+// the user's and project's; empty goes back to theirs
+func (s *Session) SetSystemPromptOverride(prompt string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.SystemPromptOverride = strings.TrimSpace(prompt)
+	s.UpdatedAt = time.Now()
+}
+
+// GetSystemPromptOverride returns the session's own system prompt addition
+func (s *Session) GetSystemPromptOverride() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.SystemPromptOverride
+}
+
+// SetSessionSystemPrompt sets the system prompt addition a session's runs use
+// instead of the user's and project's, taking effect from the next message
+func (m *Manager) SetSessionSystemPrompt(sessionID, prompt string) error {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	session.SetSystemPromptOverride(prompt)
+	return SaveSession(session)
+}
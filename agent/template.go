@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// templateVarPattern matches {{name}} placeholders, allowing surrounding whitespace
+var templateVarPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// MissingVariablesError is returned when a template references variables that have no value
+type MissingVariablesError struct {
+	Names []string
+}
+
+func (e *MissingVariablesError) Error() string {
+	return fmt.Sprintf("missing template variables: %s", strings.Join(e.Names, ", "))
+}
+
+// TemplateVariables returns the unique variable names referenced by a template, in order of appearance
+func TemplateVariables(template string) []string {
+	names := []string{}
+	seen := make(map[string]bool)
+
+	for _, match := range templateVarPattern.FindAllStringSubmatch(template, -1) {
+		name := match[1]
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// RenderTemplate substitutes {{name}} placeholders with values from vars.
+// All referenced variables must have a non-empty value; otherwise a
+// *MissingVariablesError listing every missing name is returned.
+func RenderTemplate(template string, vars map[string]string) (string, error) {
+	var missing []string
+	for _, name := range TemplateVariables(template) {
+		if strings.TrimSpace(vars[name]) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", &MissingVariablesError{Names: missing}
+	}
+
+	return templateVarPattern.ReplaceAllStringFunc(template, func(placeholder string) string {
+		name := templateVarPattern.FindStringSubmatch(placeholder)[1]
+		return vars[name]
+	}), nil
+}
+
+// TemplateContext returns the template variables that can be derived from the session itself
+func (s *Session) TemplateContext() map[string]string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	vars := map[string]string{
+		"session_id":   s.ID,
+		"project_path": s.ProjectPath,
+		"model":        s.Model,
+	}
+	if s.ProjectPath != "" {
+		vars["project"] = filepath.Base(s.ProjectPath)
+	}
+
+	// Expose string mode settings (e.g. firefighter scope, boatmanmode input)
+	for k, v := range s.ModeConfig {
+		if str, ok := v.(string); ok {
+			vars[k] = str
+		}
+	}
+
+	return vars
+}
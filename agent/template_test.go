@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestTemplateVariables tests placeholder extraction
+func TestTemplateVariables(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		expected []string
+	}{
+		{
+			name:     "no placeholders",
+			template: "fix the bug",
+			expected: []string{},
+		},
+		{
+			name:     "single placeholder",
+			template: "look at {{file}}",
+			expected: []string{"file"},
+		},
+		{
+			name:     "duplicates and whitespace",
+			template: "{{ branch }} vs {{branch}} in {{file}}",
+			expected: []string{"branch", "file"},
+		},
+		{
+			name:     "invalid names ignored",
+			template: "{{1abc}} {{error_id}}",
+			expected: []string{"error_id"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := TemplateVariables(tt.template)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestRenderTemplate tests placeholder substitution
+func TestRenderTemplate(t *testing.T) {
+	vars := map[string]string{
+		"file":   "main.go",
+		"branch": "feature/x",
+	}
+
+	got, err := RenderTemplate("Review {{file}} on {{ branch }}", vars)
+	if err != nil {
+		t.Fatalf("RenderTemplate() error = %v", err)
+	}
+	if got != "Review main.go on feature/x" {
+		t.Errorf("Unexpected render result: %q", got)
+	}
+}
+
+// TestRenderTemplate_MissingVariables tests validation of unresolved placeholders
+func TestRenderTemplate_MissingVariables(t *testing.T) {
+	_, err := RenderTemplate("{{file}} {{selection}} {{error_id}}", map[string]string{
+		"file":      "main.go",
+		"selection": "  ",
+	})
+	if err == nil {
+		t.Fatal("Expected error for missing variables")
+	}
+
+	var missingErr *MissingVariablesError
+	if !errors.As(err, &missingErr) {
+		t.Fatalf("Expected MissingVariablesError, got %T", err)
+	}
+	expected := []string{"selection", "error_id"}
+	if !reflect.DeepEqual(missingErr.Names, expected) {
+		t.Errorf("Expected missing %v, got %v", expected, missingErr.Names)
+	}
+}
+
+// TestSessionTemplateContext tests session-derived variables
+func TestSessionTemplateContext(t *testing.T) {
+	session := NewSession("test-session", "/path/to/project")
+	session.Model = "opus"
+	session.ModeConfig = map[string]interface{}{
+		"scope": "payments",
+		"count": 3,
+	}
+
+	vars := session.TemplateContext()
+
+	expected := map[string]string{
+		"session_id":   "test-session",
+		"project_path": "/path/to/project",
+		"project":      "project",
+		"model":        "opus",
+		"scope":        "payments",
+	}
+	if !reflect.DeepEqual(vars, expected) {
+		t.Errorf("Expected %v, got %v", expected, vars)
+	}
+}
@@ -0,0 +1,138 @@
+package agent
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"boatman/stream"
+)
+
+// charsPerToken is the average number of characters per token used for local estimates
+const charsPerToken = 4
+
+// usageSummary describes token usage and its cost, e.g. "100 input, 50 output (≈$0.0010)"
+func usageSummary(usage CostInfo) string {
+	summary := fmt.Sprintf("%d input, %d output", usage.InputTokens, usage.OutputTokens)
+	if usage.CacheWriteTokens > 0 || usage.CacheReadTokens > 0 {
+		summary += fmt.Sprintf(", %d cache write, %d cache read", usage.CacheWriteTokens, usage.CacheReadTokens)
+	}
+	return summary + fmt.Sprintf(" (≈$%.4f)", usage.TotalCost)
+}
+
+// EstimateTokens approximates the number of tokens in text without calling a tokenizer
+func EstimateTokens(text string) int {
+	chars := utf8.RuneCountInString(text)
+	if chars == 0 {
+		return 0
+	}
+	return (chars + charsPerToken - 1) / charsPerToken
+}
+
+// Image token costs. Claude charges about width*height/750 tokens per image,
+// after shrinking large images to roughly maxImageTokens worth of pixels.
+const (
+	imagePixelsPerToken = 750
+	maxImageTokens      = 1600
+)
+
+// MessageEstimate is a pre-send estimate of what a prompt will cost
+type MessageEstimate struct {
+	PromptTokens  int     `json:"promptTokens"`  // Tokens in the prompt with its attached files and the appended system prompts
+	ImageTokens   int     `json:"imageTokens"`   // Tokens of the images sent with the prompt
+	ContextTokens int     `json:"contextTokens"` // Tokens of conversation history resent with the prompt
+	InputTokens   int     `json:"inputTokens"`   // PromptTokens + ImageTokens + ContextTokens
+	EstimatedCost float64 `json:"estimatedCost"` // Projected input cost in dollars
+	Model         string  `json:"model"`
+}
+
+// EstimateMessage estimates the input tokens and cost of sending content to the session
+func (s *Session) EstimateMessage(content string) MessageEstimate {
+	estimate, _ := s.EstimateMessageWithAttachments(content, nil, nil, AuthConfig{})
+	return estimate
+}
+
+// EstimateMessageWithAttachments estimates the input tokens and cost of
+// sending content with workspace files and images, as SendMessageWithAttachments
+// would send it under authConfig
+func (s *Session) EstimateMessageWithAttachments(content string, attachments []string, images []ImageAttachment, authConfig AuthConfig) (MessageEstimate, error) {
+	prompt := content
+	if len(attachments) > 0 {
+		var err error
+		prompt, _, err = buildAttachmentPrompt(s.Roots(), content, attachments)
+		if err != nil {
+			return MessageEstimate{}, err
+		}
+	}
+	_, thumbnails, err := loadImages(images)
+	if err != nil {
+		return MessageEstimate{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.Mode == "firefighter" && len(s.Messages) == 0 {
+		scope, _ := s.ModeConfig["scope"].(string)
+		prompt = GetFirefighterPrompt(scope) + "\n\n" + prompt
+	}
+	systemPrompt := authConfig.AppendSystemPrompt
+	if authConfig.PlanOnly {
+		systemPrompt = joinSystemPrompts(systemPrompt, planSystemPrompt)
+	}
+
+	contextTokens := 0
+	for _, msg := range s.Messages {
+		contextTokens += contextMessageTokens(msg)
+	}
+
+	promptTokens := EstimateTokens(prompt) + EstimateTokens(systemPrompt)
+	imageTokens := 0
+	for _, thumbnail := range thumbnails {
+		imageTokens += estimateImageTokens(thumbnail)
+	}
+	inputTokens := promptTokens + imageTokens + contextTokens
+
+	return MessageEstimate{
+		PromptTokens:  promptTokens,
+		ImageTokens:   imageTokens,
+		ContextTokens: contextTokens,
+		InputTokens:   inputTokens,
+		EstimatedCost: calculateCost(s.Model, stream.Usage{InputTokens: inputTokens}),
+		Model:         s.Model,
+	}, nil
+}
+
+// contextMessageTokens estimates what a transcript message adds to the
+// conversation claude resends. Tool calls and results count as what the model
+// saw rather than their descriptions; other system messages, such as usage
+// and notices, are only shown in the app.
+func contextMessageTokens(msg Message) int {
+	meta := msg.Metadata
+	switch {
+	case meta != nil && meta.Superseded:
+		return 0
+	case meta != nil && meta.ToolUse != nil:
+		return EstimateTokens(meta.ToolUse.ToolName) + EstimateTokens(string(meta.ToolUse.Input))
+	case meta != nil && meta.ToolResult != nil:
+		return EstimateTokens(meta.ToolResult.Content)
+	case msg.Role == "system":
+		return 0
+	}
+
+	tokens := EstimateTokens(msg.Content)
+	if meta != nil {
+		for _, image := range meta.Images {
+			tokens += estimateImageTokens(image)
+		}
+	}
+	return tokens
+}
+
+// estimateImageTokens approximates the tokens of an image, assuming the
+// largest size when its dimensions are unknown
+func estimateImageTokens(image ImageThumbnail) int {
+	if image.Width <= 0 || image.Height <= 0 {
+		return maxImageTokens
+	}
+	return min((image.Width*image.Height+imagePixelsPerToken-1)/imagePixelsPerToken, maxImageTokens)
+}
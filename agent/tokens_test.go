@@ -0,0 +1,165 @@
+package agent
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestEstimateTokens tests the local token approximation
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected int
+	}{
+		{name: "empty", text: "", expected: 0},
+		{name: "short", text: "hi", expected: 1},
+		{name: "exact multiple", text: "abcdefgh", expected: 2},
+		{name: "rounds up", text: "abcdefghi", expected: 3},
+		{name: "counts runes not bytes", text: "日本語の", expected: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateTokens(tt.text); got != tt.expected {
+				t.Errorf("Expected %d tokens, got %d", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestSessionEstimateMessage tests pre-send estimation including history
+func TestSessionEstimateMessage(t *testing.T) {
+	session := NewSession("test-session", "/tmp/test")
+	session.Model = "sonnet"
+	session.Messages = []Message{
+		{Role: "user", Content: "12345678"},
+		{Role: "assistant", Content: "1234"},
+		{Role: "assistant", Content: "ignored ignored", Metadata: &MessageMetadata{Superseded: true}},
+	}
+
+	estimate := session.EstimateMessage("abcdefgh")
+
+	if estimate.PromptTokens != 2 {
+		t.Errorf("Expected 2 prompt tokens, got %d", estimate.PromptTokens)
+	}
+	if estimate.ContextTokens != 3 {
+		t.Errorf("Expected 3 context tokens, got %d", estimate.ContextTokens)
+	}
+	if estimate.InputTokens != 5 {
+		t.Errorf("Expected 5 input tokens, got %d", estimate.InputTokens)
+	}
+	if estimate.EstimatedCost <= 0 {
+		t.Error("Expected a positive estimated cost")
+	}
+	if estimate.Model != "sonnet" {
+		t.Errorf("Expected model sonnet, got %s", estimate.Model)
+	}
+}
+
+// TestSessionEstimateMessage_Firefighter tests that the injected system prompt is counted
+func TestSessionEstimateMessage_Firefighter(t *testing.T) {
+	session := NewSession("test-session", "/tmp/test")
+	session.Mode = "firefighter"
+
+	estimate := session.EstimateMessage("check errors")
+
+	if estimate.PromptTokens <= EstimateTokens("check errors") {
+		t.Error("Expected firefighter system prompt to be included in the estimate")
+	}
+}
+
+// TestSessionEstimateMessage_OnlyCountsSentHistory tests that display-only
+// messages are left out and tool calls count as what the model saw
+func TestSessionEstimateMessage_OnlyCountsSentHistory(t *testing.T) {
+	session := NewSession("test-session", "/tmp/test")
+	session.Messages = []Message{
+		{Role: "user", Content: "12345678"},
+		{Role: "assistant", Content: "🔧 Using tool: a long description of the call", Metadata: &MessageMetadata{
+			ToolUse: &ToolUse{ToolName: "Read", Input: json.RawMessage(`{}`)},
+		}},
+		{Role: "system", Content: "✅ a long description of the result", Metadata: &MessageMetadata{
+			ToolResult: &ToolResult{Content: "abcd"},
+		}},
+		{Role: "system", Content: "📊 Usage: 100 input, 50 output", Metadata: &MessageMetadata{
+			CostInfo: &CostInfo{InputTokens: 100, OutputTokens: 50},
+		}},
+		{Role: "system", Content: "⏹️  Run cancelled"},
+	}
+
+	estimate := session.EstimateMessage("")
+
+	// 2 for the user message, 1 each for the tool name, input and result
+	if estimate.ContextTokens != 5 {
+		t.Errorf("Expected 5 context tokens, got %d", estimate.ContextTokens)
+	}
+}
+
+// TestSessionEstimateMessageWithAttachments tests that attached files, images
+// and appended system prompts are counted
+func TestSessionEstimateMessageWithAttachments(t *testing.T) {
+	root := t.TempDir()
+	file := strings.Repeat("x", 400)
+	if err := os.WriteFile(filepath.Join(root, "notes.txt"), []byte(file), 0644); err != nil {
+		t.Fatal(err)
+	}
+	session := NewSession("test-session", root)
+	session.Model = "sonnet"
+
+	plain := session.EstimateMessage("Summarize this")
+
+	withFile, err := session.EstimateMessageWithAttachments("Summarize this", []string{"notes.txt"}, nil, AuthConfig{})
+	if err != nil {
+		t.Fatalf("EstimateMessageWithAttachments failed: %v", err)
+	}
+	if withFile.PromptTokens < plain.PromptTokens+EstimateTokens(file) {
+		t.Errorf("Expected the attached file in the prompt tokens, got %d (without it %d)", withFile.PromptTokens, plain.PromptTokens)
+	}
+
+	image := ImageAttachment{Data: base64.StdEncoding.EncodeToString(testPNG(t, 150, 100)), Name: "shot.png"}
+	withImage, err := session.EstimateMessageWithAttachments("Summarize this", nil, []ImageAttachment{image}, AuthConfig{})
+	if err != nil {
+		t.Fatalf("EstimateMessageWithAttachments failed: %v", err)
+	}
+	if withImage.ImageTokens != 20 {
+		t.Errorf("Expected 20 image tokens for a 150x100 image, got %d", withImage.ImageTokens)
+	}
+	if withImage.InputTokens != plain.InputTokens+20 {
+		t.Errorf("Expected the image added to the input tokens, got %d", withImage.InputTokens)
+	}
+
+	withPrompt, err := session.EstimateMessageWithAttachments("Summarize this", nil, nil, AuthConfig{AppendSystemPrompt: "abcdefgh"})
+	if err != nil {
+		t.Fatalf("EstimateMessageWithAttachments failed: %v", err)
+	}
+	if withPrompt.PromptTokens != plain.PromptTokens+2 {
+		t.Errorf("Expected the appended system prompt counted, got %d", withPrompt.PromptTokens)
+	}
+
+	if _, err := session.EstimateMessageWithAttachments("Summarize this", []string{"missing.txt"}, nil, AuthConfig{}); err == nil {
+		t.Error("Expected a missing attachment to fail the estimate")
+	}
+}
+
+// TestEstimateImageTokens tests the per-image cost
+func TestEstimateImageTokens(t *testing.T) {
+	tests := []struct {
+		image    ImageThumbnail
+		expected int
+	}{
+		{ImageThumbnail{Width: 750, Height: 1}, 1},
+		{ImageThumbnail{Width: 1000, Height: 1000}, 1334},
+		{ImageThumbnail{Width: 4000, Height: 3000}, maxImageTokens},
+		{ImageThumbnail{MediaType: "image/webp"}, maxImageTokens},
+	}
+
+	for _, tt := range tests {
+		if got := estimateImageTokens(tt.image); got != tt.expected {
+			t.Errorf("estimateImageTokens(%dx%d): expected %d, got %d", tt.image.Width, tt.image.Height, tt.expected, got)
+		}
+	}
+}
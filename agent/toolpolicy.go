@@ -0,0 +1,126 @@
+package agent
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ToolPolicy narrows the tools a session's runs may use beyond what the
+// approval mode allows. Entries are claude tool names or rules such as
+// "Bash", "Bash(git log:*)" or "mcp__github".
+type ToolPolicy struct {
+	// Allowed tools run without asking for approval
+	Allowed []string `json:"allowed,omitempty"`
+	// Denied tools can't be used at all, whatever the approval mode
+	Denied []string `json:"denied,omitempty"`
+}
+
+// NormalizeToolPolicy trims and dedupes a policy's entries, rejecting ones
+// that can't be passed to claude
+func NormalizeToolPolicy(policy ToolPolicy) (ToolPolicy, error) {
+	allowed, err := normalizeTools(policy.Allowed)
+	if err != nil {
+		return ToolPolicy{}, err
+	}
+	denied, err := normalizeTools(policy.Denied)
+	if err != nil {
+		return ToolPolicy{}, err
+	}
+	return ToolPolicy{Allowed: allowed, Denied: denied}, nil
+}
+
+// normalizeTools trims and dedupes a list of tools
+func normalizeTools(tools []string) ([]string, error) {
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, tool := range tools {
+		tool = strings.TrimSpace(tool)
+		if tool == "" || seen[tool] {
+			continue
+		}
+		// The CLI takes the list comma separated
+		if strings.Contains(tool, ",") {
+			return nil, fmt.Errorf("invalid tool %q: tool names can't contain commas", tool)
+		}
+		seen[tool] = true
+		normalized = append(normalized, tool)
+	}
+	return normalized, nil
+}
+
+// mergeToolPolicies combines the project's policy with a session's, keeping
+// the first occurrence of each tool
+func mergeToolPolicies(policies ...ToolPolicy) ToolPolicy {
+	var merged ToolPolicy
+	for _, policy := range policies {
+		merged.Allowed = appendTools(merged.Allowed, policy.Allowed)
+		merged.Denied = appendTools(merged.Denied, policy.Denied)
+	}
+	return merged
+}
+
+// appendTools adds the tools not already in list
+func appendTools(list, tools []string) []string {
+	for _, tool := range tools {
+		if !containsTool(list, tool) {
+			list = append(list, tool)
+		}
+	}
+	return list
+}
+
+// containsTool reports whether list has tool
+func containsTool(list []string, tool string) bool {
+	for _, t := range list {
+		if t == tool {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutTools returns list without the tools in remove
+func withoutTools(list, remove []string) []string {
+	var kept []string
+	for _, tool := range list {
+		if !containsTool(remove, tool) {
+			kept = append(kept, tool)
+		}
+	}
+	return kept
+}
+
+// SetToolPolicy sets the tools the session's runs may use on top of the
+// project's policy
+func (s *Session) SetToolPolicy(policy ToolPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ToolPolicy = policy
+	s.UpdatedAt = time.Now()
+}
+
+// GetToolPolicy returns the session's own tool policy
+func (s *Session) GetToolPolicy() ToolPolicy {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return ToolPolicy{
+		Allowed: append([]string(nil), s.ToolPolicy.Allowed...),
+		Denied:  append([]string(nil), s.ToolPolicy.Denied...),
+	}
+}
+
+// SetSessionToolPolicy sets the tools a session's runs may use on top of its
+// project's policy
+func (m *Manager) SetSessionToolPolicy(sessionID string, policy ToolPolicy) error {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+	policy, err = NormalizeToolPolicy(policy)
+	if err != nil {
+		return err
+	}
+	session.SetToolPolicy(policy)
+	return SaveSession(session)
+}
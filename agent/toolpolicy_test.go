@@ -0,0 +1,113 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeToolPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   ToolPolicy
+		expected ToolPolicy
+		wantErr  bool
+	}{
+		{name: "empty", policy: ToolPolicy{}, expected: ToolPolicy{}},
+		{
+			name:     "trims and dedupes",
+			policy:   ToolPolicy{Allowed: []string{" Bash(git log:*) ", "Bash(git log:*)", ""}, Denied: []string{"WebFetch", " WebFetch"}},
+			expected: ToolPolicy{Allowed: []string{"Bash(git log:*)"}, Denied: []string{"WebFetch"}},
+		},
+		{name: "comma", policy: ToolPolicy{Denied: []string{"Bash,Write"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := NormalizeToolPolicy(tt.policy)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %+v", policy)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(policy, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, policy)
+			}
+		})
+	}
+}
+
+func TestBuildClaudeArgsToolPolicy(t *testing.T) {
+	readOnly := "Read,Glob,Grep,LS,NotebookRead,WebFetch,WebSearch,TodoWrite,Task"
+	policy := ToolPolicy{Allowed: []string{"Bash(go test:*)"}, Denied: []string{"Bash", "WebFetch"}}
+
+	tests := []struct {
+		name       string
+		authConfig AuthConfig
+		expected   []string
+	}{
+		{
+			name:       "suggest",
+			authConfig: AuthConfig{ApprovalMode: "suggest", ToolPolicy: policy},
+			expected: []string{"--permission-mode", "default", "--allowedTools", "Read,Glob,Grep,LS,NotebookRead,WebSearch,TodoWrite,Task,Bash(go test:*)",
+				"--permission-prompt-tool", "stdio", "--disallowedTools", "Bash,WebFetch"},
+		},
+		{
+			name:       "full-auto still denies",
+			authConfig: AuthConfig{ApprovalMode: "full-auto", ToolPolicy: policy},
+			expected:   []string{"--permission-mode", "bypassPermissions", "--disallowedTools", "Bash,WebFetch"},
+		},
+		{
+			name:       "no policy",
+			authConfig: AuthConfig{ApprovalMode: "suggest"},
+			expected:   []string{"--permission-mode", "default", "--allowedTools", readOnly, "--permission-prompt-tool", "stdio"},
+		},
+		{
+			name:       "plan mode",
+			authConfig: AuthConfig{PlanOnly: true, ToolPolicy: policy},
+			expected: []string{"--permission-mode", "default", "--allowedTools", "Read,Glob,Grep,LS,NotebookRead,WebSearch,TodoWrite,Task",
+				"--disallowedTools", "Edit,MultiEdit,Write,NotebookEdit,Bash,WebFetch"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := buildClaudeArgs("", "", tt.authConfig, "")
+			// Skip the fixed flags and, for plan mode, the system prompt
+			args = args[len(args)-len(tt.expected):]
+			if !reflect.DeepEqual(args, tt.expected) {
+				t.Errorf("Expected args %q, got %q", tt.expected, args)
+			}
+		})
+	}
+}
+
+func TestAuthConfigForToolPolicy(t *testing.T) {
+	m := NewManager()
+	session := NewSession("s1", "/project")
+
+	config, err := m.authConfigFor(session)
+	if err != nil {
+		t.Fatalf("authConfigFor failed: %v", err)
+	}
+	if len(config.ToolPolicy.Allowed) != 0 || len(config.ToolPolicy.Denied) != 0 {
+		t.Errorf("Expected no tool policy, got %+v", config.ToolPolicy)
+	}
+
+	m.SetToolPolicyResolver(func(projectPath string) ToolPolicy {
+		return ToolPolicy{Allowed: []string{"Bash(go test:*)"}, Denied: []string{"WebFetch"}}
+	})
+	session.SetToolPolicy(ToolPolicy{Denied: []string{"Bash", "WebFetch"}})
+
+	config, err = m.authConfigFor(session)
+	if err != nil {
+		t.Fatalf("authConfigFor failed: %v", err)
+	}
+	expected := ToolPolicy{Allowed: []string{"Bash(go test:*)"}, Denied: []string{"WebFetch", "Bash"}}
+	if !reflect.DeepEqual(config.ToolPolicy, expected) {
+		t.Errorf("Expected the project's and session's policies merged, got %+v", config.ToolPolicy)
+	}
+}
@@ -0,0 +1,77 @@
+package agent
+
+import "time"
+
+// Turn groups the messages of one exchange: a user message and everything
+// that answered it, i.e. assistant text, tool uses and results, and usage notes
+type Turn struct {
+	ID string `json:"id"` // The message that started the turn, normally the user's
+	// UserMessage is nil for messages before the first user message, or when
+	// the turn's start was trimmed from the transcript
+	UserMessage *Message  `json:"userMessage,omitempty"`
+	Messages    []Message `json:"messages"`
+	StartedAt   time.Time `json:"startedAt"`
+	CompletedAt time.Time `json:"completedAt,omitempty"` // Zero while the turn is running
+	DurationMs  int64     `json:"durationMs"`
+	// Cost sums the turn's usage, leaving out superseded responses
+	Cost    CostInfo `json:"cost"`
+	Running bool     `json:"running,omitempty"`
+}
+
+// GetTurns groups the session's transcript into turns, oldest first
+func (s *Session) GetTurns() []Turn {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	turns := groupTurns(s.Messages)
+	if len(turns) > 0 && s.Status == SessionStatusRunning {
+		last := &turns[len(turns)-1]
+		last.Running = true
+		last.CompletedAt = time.Time{}
+		last.DurationMs = time.Since(last.StartedAt).Milliseconds()
+	}
+	return turns
+}
+
+// groupTurns splits messages into turns at each user message, timing each
+// turn from its first message to its last
+func groupTurns(messages []Message) []Turn {
+	turns := []Turn{}
+	for _, msg := range messages {
+		if msg.Role == "user" || len(turns) == 0 {
+			turn := Turn{ID: msg.ID, StartedAt: msg.Timestamp, Messages: []Message{}}
+			if msg.Role == "user" {
+				userMessage := msg
+				turn.UserMessage = &userMessage
+			}
+			turns = append(turns, turn)
+		}
+
+		turn := &turns[len(turns)-1]
+		if msg.Role != "user" {
+			turn.Messages = append(turn.Messages, msg)
+		}
+		turn.CompletedAt = msg.Timestamp
+		if meta := msg.Metadata; meta != nil && meta.CostInfo != nil && !meta.Superseded {
+			turn.Cost.InputTokens += meta.CostInfo.InputTokens
+			turn.Cost.OutputTokens += meta.CostInfo.OutputTokens
+			turn.Cost.CacheWriteTokens += meta.CostInfo.CacheWriteTokens
+			turn.Cost.CacheReadTokens += meta.CostInfo.CacheReadTokens
+			turn.Cost.TotalCost += meta.CostInfo.TotalCost
+		}
+	}
+
+	for i := range turns {
+		turns[i].DurationMs = turns[i].CompletedAt.Sub(turns[i].StartedAt).Milliseconds()
+	}
+	return turns
+}
+
+// GetSessionTurns returns a session's transcript grouped into turns
+func (m *Manager) GetSessionTurns(sessionID string) ([]Turn, error) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return session.GetTurns(), nil
+}
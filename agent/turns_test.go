@@ -0,0 +1,80 @@
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGroupTurns(t *testing.T) {
+	start := time.Date(2026, 1, 2, 9, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time { return start.Add(time.Duration(seconds) * time.Second) }
+	usage := func(cost float64, superseded bool) *MessageMetadata {
+		return &MessageMetadata{CostInfo: &CostInfo{InputTokens: 100, OutputTokens: 10, TotalCost: cost}, Superseded: superseded}
+	}
+
+	messages := []Message{
+		{ID: "intro", Role: "system", Content: "Investigating", Timestamp: at(0)},
+		{ID: "u1", Role: "user", Content: "Fix the build", Timestamp: at(1)},
+		{ID: "a1", Role: "assistant", Content: "Looking", Timestamp: at(2)},
+		{ID: "t1", Role: "assistant", Timestamp: at(3), Metadata: &MessageMetadata{ToolUse: &ToolUse{ToolName: "Bash", ToolID: "tool-1"}}},
+		{ID: "r1", Role: "assistant", Timestamp: at(5), Metadata: &MessageMetadata{ToolResult: &ToolResult{ToolID: "tool-1"}}},
+		{ID: "c1", Role: "system", Timestamp: at(6), Metadata: usage(0.5, false)},
+		{ID: "u2", Role: "user", Content: "Thanks", Timestamp: at(10)},
+		{ID: "c2", Role: "system", Timestamp: at(12), Metadata: usage(0.25, true)},
+		{ID: "c3", Role: "system", Timestamp: at(15), Metadata: usage(0.1, false)},
+	}
+
+	turns := groupTurns(messages)
+	if len(turns) != 3 {
+		t.Fatalf("Expected 3 turns, got %d", len(turns))
+	}
+
+	if turns[0].ID != "intro" || turns[0].UserMessage != nil || len(turns[0].Messages) != 1 {
+		t.Errorf("Expected the messages before the first user message in their own turn, got %+v", turns[0])
+	}
+
+	first := turns[1]
+	if first.ID != "u1" || first.UserMessage == nil || first.UserMessage.Content != "Fix the build" {
+		t.Errorf("Expected the turn to start with u1, got %+v", first.UserMessage)
+	}
+	if len(first.Messages) != 4 {
+		t.Errorf("Expected 4 replies in the first turn, got %d", len(first.Messages))
+	}
+	if first.DurationMs != 5000 || !first.CompletedAt.Equal(at(6)) {
+		t.Errorf("Expected the first turn to take 5s, got %dms ending %v", first.DurationMs, first.CompletedAt)
+	}
+	if first.Cost.TotalCost != 0.5 || first.Cost.InputTokens != 100 {
+		t.Errorf("Expected the first turn's usage summed, got %+v", first.Cost)
+	}
+
+	// Superseded responses are left out of the cost
+	if second := turns[2]; second.Cost.TotalCost != 0.1 || second.DurationMs != 5000 {
+		t.Errorf("Expected the second turn to cost 0.1 over 5s, got %+v", second)
+	}
+}
+
+func TestSessionGetTurnsRunning(t *testing.T) {
+	session := NewSession("turns", "/tmp/project")
+	session.Messages = []Message{
+		{ID: "u1", Role: "user", Content: "Hello", Timestamp: time.Now().Add(-time.Minute)},
+		{ID: "a1", Role: "assistant", Content: "Hi", Timestamp: time.Now().Add(-50 * time.Second)},
+	}
+
+	turns := session.GetTurns()
+	if len(turns) != 1 || turns[0].Running || turns[0].CompletedAt.IsZero() {
+		t.Fatalf("Expected one completed turn, got %+v", turns)
+	}
+
+	session.Status = SessionStatusRunning
+	turns = session.GetTurns()
+	if !turns[0].Running || !turns[0].CompletedAt.IsZero() {
+		t.Errorf("Expected the last turn running, got %+v", turns[0])
+	}
+	if turns[0].DurationMs < time.Minute.Milliseconds() {
+		t.Errorf("Expected a running turn to be timed until now, got %dms", turns[0].DurationMs)
+	}
+
+	if turns := NewSession("empty", "/tmp/project").GetTurns(); len(turns) != 0 {
+		t.Errorf("Expected no turns for an empty session, got %d", len(turns))
+	}
+}
@@ -0,0 +1,324 @@
+package agent
+
+import (
+	"sort"
+	"time"
+)
+
+// UsageRange limits usage analytics to messages within [From, To].
+// Zero values leave that end of the range open.
+type UsageRange struct {
+	From time.Time
+	To   time.Time
+}
+
+// contains reports whether t falls within the range
+func (r UsageRange) contains(t time.Time) bool {
+	if !r.From.IsZero() && t.Before(r.From) {
+		return false
+	}
+	if !r.To.IsZero() && t.After(r.To) {
+		return false
+	}
+	return true
+}
+
+// DailyUsage is the token usage and cost for a single day
+type DailyUsage struct {
+	Date         string  `json:"date"` // YYYY-MM-DD in local time
+	InputTokens  int     `json:"inputTokens"`
+	OutputTokens int     `json:"outputTokens"`
+	Cost         float64 `json:"cost"`
+	Requests     int     `json:"requests"`
+}
+
+// ProjectUsage is the token usage and cost for a single project
+type ProjectUsage struct {
+	ProjectPath  string  `json:"projectPath"`
+	InputTokens  int     `json:"inputTokens"`
+	OutputTokens int     `json:"outputTokens"`
+	Cost         float64 `json:"cost"`
+	Sessions     int     `json:"sessions"`
+}
+
+// TagUsage is the token usage and cost attributed to a session tag
+type TagUsage struct {
+	Tag          string  `json:"tag"` // "" for untagged sessions
+	InputTokens  int     `json:"inputTokens"`
+	OutputTokens int     `json:"outputTokens"`
+	Cost         float64 `json:"cost"`
+	Sessions     int     `json:"sessions"`
+}
+
+// ToolUsage counts how often a tool was invoked
+type ToolUsage struct {
+	ToolName string `json:"toolName"`
+	Count    int    `json:"count"`
+	Errors   int    `json:"errors"`
+}
+
+// ArchiveLoader is a function type for loading session archives (for testing)
+type ArchiveLoader func(sessionID string) (*ArchiveData, error)
+
+// defaultArchiveLoader is the default implementation
+var defaultArchiveLoader ArchiveLoader = LoadArchive
+
+// usageSession pairs a session with all of its messages, including archived ones
+type usageSession struct {
+	session  *Session
+	messages []Message
+	// archived is the number of leading messages that came from the archive
+	archived int
+	// archiveTags are the session's tags when those messages were archived
+	archiveTags []string
+}
+
+// tagsFor returns the tags to attribute the i-th message to
+func (u usageSession) tagsFor(i int) []string {
+	if i < u.archived && u.archiveTags != nil {
+		return u.archiveTags
+	}
+	return u.session.GetTags()
+}
+
+// loadUsageSessions loads every stored session along with its archived messages,
+// so usage from trimmed or rewound history is still counted
+func loadUsageSessions(loader SessionLoader, archives ArchiveLoader) ([]usageSession, error) {
+	sessions, err := loader()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]usageSession, 0, len(sessions))
+	for _, session := range sessions {
+		archive, err := archives(session.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		messages := make([]Message, 0, len(archive.Messages)+len(session.Messages))
+		messages = append(messages, archive.Messages...)
+		messages = append(messages, session.GetMessages()...)
+
+		result = append(result, usageSession{
+			session:     session,
+			messages:    messages,
+			archived:    len(archive.Messages),
+			archiveTags: archive.Tags,
+		})
+	}
+	return result, nil
+}
+
+// GetUsageByDay returns token usage and cost per day within the range
+func GetUsageByDay(r UsageRange) ([]DailyUsage, error) {
+	sessions, err := loadUsageSessions(defaultSessionLoader, defaultArchiveLoader)
+	if err != nil {
+		return nil, err
+	}
+	return usageByDay(sessions, r), nil
+}
+
+func usageByDay(sessions []usageSession, r UsageRange) []DailyUsage {
+	byDate := make(map[string]*DailyUsage)
+	for _, s := range sessions {
+		for _, msg := range s.messages {
+			if msg.Metadata == nil || msg.Metadata.CostInfo == nil || !r.contains(msg.Timestamp) {
+				continue
+			}
+			date := msg.Timestamp.Local().Format("2006-01-02")
+			day, ok := byDate[date]
+			if !ok {
+				day = &DailyUsage{Date: date}
+				byDate[date] = day
+			}
+			day.InputTokens += msg.Metadata.CostInfo.InputTokens
+			day.OutputTokens += msg.Metadata.CostInfo.OutputTokens
+			day.Cost += msg.Metadata.CostInfo.TotalCost
+			day.Requests++
+		}
+	}
+
+	if len(byDate) == 0 {
+		return []DailyUsage{}
+	}
+
+	// Fill in days without usage so charts have a continuous axis
+	var first, last time.Time
+	for date := range byDate {
+		t, _ := time.ParseInLocation("2006-01-02", date, time.Local)
+		if first.IsZero() || t.Before(first) {
+			first = t
+		}
+		if t.After(last) {
+			last = t
+		}
+	}
+
+	days := []DailyUsage{}
+	for t := first; !t.After(last); t = t.AddDate(0, 0, 1) {
+		date := t.Format("2006-01-02")
+		if day, ok := byDate[date]; ok {
+			days = append(days, *day)
+		} else {
+			days = append(days, DailyUsage{Date: date})
+		}
+	}
+	return days
+}
+
+// GetUsageByProject returns token usage and cost per project within the range, most expensive first
+func GetUsageByProject(r UsageRange) ([]ProjectUsage, error) {
+	sessions, err := loadUsageSessions(defaultSessionLoader, defaultArchiveLoader)
+	if err != nil {
+		return nil, err
+	}
+	return usageByProject(sessions, r), nil
+}
+
+func usageByProject(sessions []usageSession, r UsageRange) []ProjectUsage {
+	byProject := make(map[string]*ProjectUsage)
+	for _, s := range sessions {
+		counted := false
+		for _, msg := range s.messages {
+			if msg.Metadata == nil || msg.Metadata.CostInfo == nil || !r.contains(msg.Timestamp) {
+				continue
+			}
+			project, ok := byProject[s.session.ProjectPath]
+			if !ok {
+				project = &ProjectUsage{ProjectPath: s.session.ProjectPath}
+				byProject[s.session.ProjectPath] = project
+			}
+			if !counted {
+				project.Sessions++
+				counted = true
+			}
+			project.InputTokens += msg.Metadata.CostInfo.InputTokens
+			project.OutputTokens += msg.Metadata.CostInfo.OutputTokens
+			project.Cost += msg.Metadata.CostInfo.TotalCost
+		}
+	}
+
+	projects := make([]ProjectUsage, 0, len(byProject))
+	for _, project := range byProject {
+		projects = append(projects, *project)
+	}
+	sort.Slice(projects, func(i, j int) bool {
+		if projects[i].Cost != projects[j].Cost {
+			return projects[i].Cost > projects[j].Cost
+		}
+		return projects[i].ProjectPath < projects[j].ProjectPath
+	})
+	return projects
+}
+
+// GetTopTools returns tool invocation counts within the range, most used first
+func GetTopTools(r UsageRange) ([]ToolUsage, error) {
+	sessions, err := loadUsageSessions(defaultSessionLoader, defaultArchiveLoader)
+	if err != nil {
+		return nil, err
+	}
+	return topTools(sessions, r), nil
+}
+
+func topTools(sessions []usageSession, r UsageRange) []ToolUsage {
+	byTool := make(map[string]*ToolUsage)
+	for _, s := range sessions {
+		// Map tool IDs to names so errored results can be attributed
+		toolNames := make(map[string]string)
+		for _, msg := range s.messages {
+			if msg.Metadata == nil || !r.contains(msg.Timestamp) {
+				continue
+			}
+			if use := msg.Metadata.ToolUse; use != nil {
+				toolNames[use.ToolID] = use.ToolName
+				tool, ok := byTool[use.ToolName]
+				if !ok {
+					tool = &ToolUsage{ToolName: use.ToolName}
+					byTool[use.ToolName] = tool
+				}
+				tool.Count++
+			}
+			if result := msg.Metadata.ToolResult; result != nil && result.IsError {
+				if tool, ok := byTool[toolNames[result.ToolID]]; ok {
+					tool.Errors++
+				}
+			}
+		}
+	}
+
+	tools := make([]ToolUsage, 0, len(byTool))
+	for _, tool := range byTool {
+		tools = append(tools, *tool)
+	}
+	sort.Slice(tools, func(i, j int) bool {
+		if tools[i].Count != tools[j].Count {
+			return tools[i].Count > tools[j].Count
+		}
+		return tools[i].ToolName < tools[j].ToolName
+	})
+	return tools
+}
+
+// GetCostByTag returns token usage and cost per session tag within the range,
+// most expensive first. Usage of a session with several tags counts toward each
+// of them, and untagged usage is reported under the empty tag. If tags is
+// non-empty, only those tags are reported.
+func GetCostByTag(r UsageRange, tags []string) ([]TagUsage, error) {
+	sessions, err := loadUsageSessions(defaultSessionLoader, defaultArchiveLoader)
+	if err != nil {
+		return nil, err
+	}
+	return costByTag(sessions, r, tags), nil
+}
+
+func costByTag(sessions []usageSession, r UsageRange, only []string) []TagUsage {
+	wanted := make(map[string]bool)
+	for _, tag := range only {
+		wanted[tag] = true
+	}
+
+	byTag := make(map[string]*TagUsage)
+	for _, s := range sessions {
+		counted := make(map[string]bool)
+		for i, msg := range s.messages {
+			if msg.Metadata == nil || msg.Metadata.CostInfo == nil || !r.contains(msg.Timestamp) {
+				continue
+			}
+
+			tags := s.tagsFor(i)
+			if len(tags) == 0 {
+				tags = []string{""}
+			}
+			for _, tag := range tags {
+				if len(wanted) > 0 && !wanted[tag] {
+					continue
+				}
+				usage, ok := byTag[tag]
+				if !ok {
+					usage = &TagUsage{Tag: tag}
+					byTag[tag] = usage
+				}
+				if !counted[tag] {
+					usage.Sessions++
+					counted[tag] = true
+				}
+				usage.InputTokens += msg.Metadata.CostInfo.InputTokens
+				usage.OutputTokens += msg.Metadata.CostInfo.OutputTokens
+				usage.Cost += msg.Metadata.CostInfo.TotalCost
+			}
+		}
+	}
+
+	result := make([]TagUsage, 0, len(byTag))
+	for _, usage := range byTag {
+		result = append(result, *usage)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Cost != result[j].Cost {
+			return result[i].Cost > result[j].Cost
+		}
+		return result[i].Tag < result[j].Tag
+	})
+	return result
+}
@@ -0,0 +1,182 @@
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+func costMessage(id string, ts time.Time, in, out int, cost float64) Message {
+	return Message{
+		ID:        id,
+		Role:      "system",
+		Timestamp: ts,
+		Metadata: &MessageMetadata{
+			CostInfo: &CostInfo{InputTokens: in, OutputTokens: out, TotalCost: cost},
+		},
+	}
+}
+
+func toolMessage(id, toolID, toolName string, ts time.Time) Message {
+	return Message{
+		ID:        id,
+		Role:      "assistant",
+		Timestamp: ts,
+		Metadata: &MessageMetadata{
+			ToolUse: &ToolUse{ToolName: toolName, ToolID: toolID},
+		},
+	}
+}
+
+func toolErrorMessage(id, toolID string, ts time.Time) Message {
+	return Message{
+		ID:        id,
+		Role:      "system",
+		Timestamp: ts,
+		Metadata: &MessageMetadata{
+			ToolResult: &ToolResult{ToolID: toolID, IsError: true},
+		},
+	}
+}
+
+// newUsageTestSessions builds two projects' worth of usage across three days
+func newUsageTestSessions(t *testing.T) ([]usageSession, time.Time) {
+	t.Helper()
+
+	day1 := time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)
+	day3 := day1.AddDate(0, 0, 2)
+
+	s1 := NewSession("s1", "/project/a")
+	s1.Tags = []string{"firefighter", "client-x"}
+	s1.Messages = []Message{
+		costMessage("c1", day1, 100, 50, 1.0),
+		toolMessage("t1", "tool-1", "Read", day1),
+		toolMessage("t2", "tool-2", "Bash", day1),
+		toolErrorMessage("r2", "tool-2", day1),
+	}
+
+	s2 := NewSession("s2", "/project/b")
+	s2.Messages = []Message{
+		costMessage("c2", day3, 200, 100, 3.0),
+		toolMessage("t3", "tool-3", "Read", day3),
+	}
+
+	archives := map[string]*ArchiveData{
+		"s1": {
+			SessionID: "s1",
+			Tags:      []string{"legacy"},
+			Messages:  []Message{costMessage("c0", day1, 10, 5, 0.5)},
+		},
+	}
+
+	sessions, err := loadUsageSessions(
+		func() ([]*Session, error) { return []*Session{s1, s2}, nil },
+		func(id string) (*ArchiveData, error) {
+			if archive, ok := archives[id]; ok {
+				return archive, nil
+			}
+			return &ArchiveData{SessionID: id}, nil
+		},
+	)
+	if err != nil {
+		t.Fatalf("loadUsageSessions failed: %v", err)
+	}
+	return sessions, day1
+}
+
+func TestUsageByDay(t *testing.T) {
+	sessions, _ := newUsageTestSessions(t)
+
+	days := usageByDay(sessions, UsageRange{})
+	if len(days) != 3 {
+		t.Fatalf("Expected 3 days (including the empty one), got %d: %+v", len(days), days)
+	}
+
+	if days[0].Date != "2024-03-01" || days[0].InputTokens != 110 || days[0].Requests != 2 {
+		t.Errorf("Unexpected first day: %+v", days[0])
+	}
+	if days[0].Cost != 1.5 {
+		t.Errorf("Expected archived cost to be included, got %v", days[0].Cost)
+	}
+	if days[1].Date != "2024-03-02" || days[1].Requests != 0 {
+		t.Errorf("Expected an empty gap day, got %+v", days[1])
+	}
+	if days[2].OutputTokens != 100 {
+		t.Errorf("Expected 100 output tokens on the last day, got %d", days[2].OutputTokens)
+	}
+}
+
+func TestUsageByDay_Range(t *testing.T) {
+	sessions, day1 := newUsageTestSessions(t)
+
+	days := usageByDay(sessions, UsageRange{From: day1.AddDate(0, 0, 1)})
+	if len(days) != 1 || days[0].Date != "2024-03-03" {
+		t.Errorf("Expected only 2024-03-03, got %+v", days)
+	}
+
+	if days := usageByDay(sessions, UsageRange{From: day1.AddDate(1, 0, 0)}); len(days) != 0 {
+		t.Errorf("Expected no usage, got %+v", days)
+	}
+}
+
+func TestUsageByProject(t *testing.T) {
+	sessions, day1 := newUsageTestSessions(t)
+
+	projects := usageByProject(sessions, UsageRange{})
+	if len(projects) != 2 {
+		t.Fatalf("Expected 2 projects, got %d", len(projects))
+	}
+	if projects[0].ProjectPath != "/project/b" || projects[0].Cost != 3.0 {
+		t.Errorf("Expected most expensive project first, got %+v", projects[0])
+	}
+	if projects[1].InputTokens != 110 || projects[1].Sessions != 1 {
+		t.Errorf("Unexpected usage for /project/a: %+v", projects[1])
+	}
+
+	projects = usageByProject(sessions, UsageRange{To: day1.Add(time.Hour)})
+	if len(projects) != 1 || projects[0].ProjectPath != "/project/a" {
+		t.Errorf("Expected only /project/a in range, got %+v", projects)
+	}
+}
+
+func TestTopTools(t *testing.T) {
+	sessions, _ := newUsageTestSessions(t)
+
+	tools := topTools(sessions, UsageRange{})
+	if len(tools) != 2 {
+		t.Fatalf("Expected 2 tools, got %d", len(tools))
+	}
+	if tools[0].ToolName != "Read" || tools[0].Count != 2 {
+		t.Errorf("Expected Read used twice first, got %+v", tools[0])
+	}
+	if tools[1].ToolName != "Bash" || tools[1].Errors != 1 {
+		t.Errorf("Expected Bash with one error, got %+v", tools[1])
+	}
+}
+
+func TestCostByTag(t *testing.T) {
+	sessions, day1 := newUsageTestSessions(t)
+
+	tags := costByTag(sessions, UsageRange{}, nil)
+	byTag := make(map[string]TagUsage)
+	for _, usage := range tags {
+		byTag[usage.Tag] = usage
+	}
+
+	if len(tags) != 4 {
+		t.Fatalf("Expected 4 tags (including untagged), got %+v", tags)
+	}
+	if tags[0].Tag != "" || tags[0].Cost != 3.0 {
+		t.Errorf("Expected untagged usage first, got %+v", tags[0])
+	}
+	if byTag["firefighter"].Cost != 1.0 || byTag["client-x"].Cost != 1.0 {
+		t.Errorf("Expected live usage attributed to both current tags, got %+v", byTag)
+	}
+	if byTag["legacy"].Cost != 0.5 {
+		t.Errorf("Expected archived usage attributed to archive tags, got %+v", byTag["legacy"])
+	}
+
+	filtered := costByTag(sessions, UsageRange{To: day1.Add(time.Hour)}, []string{"firefighter"})
+	if len(filtered) != 1 || filtered[0].Tag != "firefighter" || filtered[0].Sessions != 1 {
+		t.Errorf("Expected only firefighter usage, got %+v", filtered)
+	}
+}
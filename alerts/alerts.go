@@ -0,0 +1,236 @@
+// Package alerts receives alert webhooks from monitoring services so
+// firefighter sessions can start investigating without being asked.
+package alerts
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Source is a service that sends alert webhooks
+type Source string
+
+const (
+	SourceDatadog   Source = "datadog"
+	SourceBugsnag   Source = "bugsnag"
+	SourcePagerDuty Source = "pagerduty"
+)
+
+// Sources are the services webhooks are accepted from
+var Sources = []Source{SourceDatadog, SourceBugsnag, SourcePagerDuty}
+
+// DisplayName returns the service's name as its users write it
+func (s Source) DisplayName() string {
+	switch s {
+	case SourceDatadog:
+		return "Datadog"
+	case SourceBugsnag:
+		return "Bugsnag"
+	case SourcePagerDuty:
+		return "PagerDuty"
+	}
+	return string(s)
+}
+
+// Alert is an alert parsed from a webhook
+type Alert struct {
+	Source      Source `json:"source"`
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Severity    string `json:"severity,omitempty"`
+	URL         string `json:"url,omitempty"`
+	// Project names what the alert is about, used to pick the project: a
+	// Datadog service tag, a Bugsnag project, or a PagerDuty service
+	Project string   `json:"project,omitempty"`
+	Tags    []string `json:"tags,omitempty"`
+}
+
+// Parse reads a webhook payload from source. It returns false, without an
+// error, for notifications that don't need investigating, such as
+// recoveries and resolved incidents.
+func Parse(source Source, payload []byte) (Alert, bool, error) {
+	switch source {
+	case SourceDatadog:
+		return parseDatadog(payload)
+	case SourceBugsnag:
+		return parseBugsnag(payload)
+	case SourcePagerDuty:
+		return parsePagerDuty(payload)
+	}
+	return Alert{}, false, fmt.Errorf("unknown alert source %q", source)
+}
+
+// datadogPayload holds the fields of a Datadog webhook. Datadog payloads are
+// templates the user writes, so the common variable names are accepted.
+type datadogPayload struct {
+	ID         string          `json:"id"`
+	AlertID    string          `json:"alert_id"`
+	Title      string          `json:"title"`
+	Body       string          `json:"body"`
+	Link       string          `json:"link"`
+	URL        string          `json:"url"`
+	Priority   string          `json:"priority"`
+	AlertPrio  string          `json:"alert_priority"`
+	Transition string          `json:"alert_transition"`
+	Tags       json.RawMessage `json:"tags"`
+}
+
+func parseDatadog(payload []byte) (Alert, bool, error) {
+	var p datadogPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return Alert{}, false, fmt.Errorf("invalid Datadog payload: %w", err)
+	}
+	if strings.EqualFold(p.Transition, "Recovered") {
+		return Alert{}, false, nil
+	}
+
+	alert := Alert{
+		Source:      SourceDatadog,
+		ID:          firstNonEmpty(p.AlertID, p.ID),
+		Title:       p.Title,
+		Description: p.Body,
+		Severity:    firstNonEmpty(p.AlertPrio, p.Priority, p.Transition),
+		URL:         firstNonEmpty(p.Link, p.URL),
+		Tags:        datadogTags(p.Tags),
+	}
+	for _, tag := range alert.Tags {
+		if service, ok := strings.CutPrefix(tag, "service:"); ok {
+			alert.Project = service
+			break
+		}
+	}
+	if alert.Title == "" {
+		return Alert{}, false, fmt.Errorf("Datadog payload has no title")
+	}
+	return alert, true, nil
+}
+
+// datadogTags accepts tags as a list or as the comma-separated string
+// Datadog's $TAGS variable expands to
+func datadogTags(raw json.RawMessage) []string {
+	var tags []string
+	if json.Unmarshal(raw, &tags) == nil {
+		return tags
+	}
+	var joined string
+	if json.Unmarshal(raw, &joined) != nil {
+		return nil
+	}
+	for _, tag := range strings.Split(joined, ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// bugsnagPayload holds the fields of a Bugsnag webhook
+type bugsnagPayload struct {
+	Project struct {
+		Name string `json:"name"`
+	} `json:"project"`
+	Trigger struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"trigger"`
+	Error struct {
+		ID             string `json:"id"`
+		ErrorID        string `json:"errorId"`
+		ExceptionClass string `json:"exceptionClass"`
+		Message        string `json:"message"`
+		Context        string `json:"context"`
+		Severity       string `json:"severity"`
+		ReleaseStage   string `json:"releaseStage"`
+		URL            string `json:"url"`
+	} `json:"error"`
+}
+
+func parseBugsnag(payload []byte) (Alert, bool, error) {
+	var p bugsnagPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return Alert{}, false, fmt.Errorf("invalid Bugsnag payload: %w", err)
+	}
+	if p.Error.ExceptionClass == "" && p.Error.Message == "" {
+		// Triggers like project spikes carry no error to investigate
+		return Alert{}, false, nil
+	}
+
+	title := p.Error.ExceptionClass
+	if p.Error.Message != "" {
+		title = strings.TrimPrefix(title+": "+p.Error.Message, ": ")
+	}
+	var details []string
+	for _, detail := range []string{p.Trigger.Message, p.Error.Context, p.Error.ReleaseStage} {
+		if detail != "" {
+			details = append(details, detail)
+		}
+	}
+
+	return Alert{
+		Source:      SourceBugsnag,
+		ID:          firstNonEmpty(p.Error.ErrorID, p.Error.ID),
+		Title:       title,
+		Description: strings.Join(details, "\n"),
+		Severity:    p.Error.Severity,
+		URL:         p.Error.URL,
+		Project:     p.Project.Name,
+	}, true, nil
+}
+
+// pagerDutyPayload holds the fields of a PagerDuty V3 webhook
+type pagerDutyPayload struct {
+	Event struct {
+		EventType string `json:"event_type"`
+		Data      struct {
+			ID      string `json:"id"`
+			Title   string `json:"title"`
+			HTMLURL string `json:"html_url"`
+			Urgency string `json:"urgency"`
+			Service struct {
+				Summary string `json:"summary"`
+			} `json:"service"`
+			Priority *struct {
+				Summary string `json:"summary"`
+			} `json:"priority"`
+		} `json:"data"`
+	} `json:"event"`
+}
+
+func parsePagerDuty(payload []byte) (Alert, bool, error) {
+	var p pagerDutyPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return Alert{}, false, fmt.Errorf("invalid PagerDuty payload: %w", err)
+	}
+	// Acknowledgements, resolutions and the like need no investigation
+	switch p.Event.EventType {
+	case "incident.triggered", "incident.escalated", "incident.reopened":
+	default:
+		return Alert{}, false, nil
+	}
+
+	data := p.Event.Data
+	severity := data.Urgency
+	if data.Priority != nil && data.Priority.Summary != "" {
+		severity = data.Priority.Summary
+	}
+	return Alert{
+		Source:   SourcePagerDuty,
+		ID:       data.ID,
+		Title:    data.Title,
+		Severity: severity,
+		URL:      data.HTMLURL,
+		Project:  data.Service.Summary,
+	}, true, nil
+}
+
+// firstNonEmpty returns the first value that isn't empty
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
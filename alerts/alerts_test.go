@@ -0,0 +1,123 @@
+package alerts
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   Source
+		payload  string
+		expected Alert
+		ignored  bool
+		wantErr  bool
+	}{
+		{
+			name:   "datadog alert",
+			source: SourceDatadog,
+			payload: `{"id":"123","alert_id":"456","title":"[Triggered] Checkout 5xx","body":"Error rate above 5%",
+				"link":"https://app.datadoghq.com/monitors/456","alert_priority":"P1","alert_transition":"Triggered",
+				"tags":"env:prod, service:checkout"}`,
+			expected: Alert{
+				Source:      SourceDatadog,
+				ID:          "456",
+				Title:       "[Triggered] Checkout 5xx",
+				Description: "Error rate above 5%",
+				Severity:    "P1",
+				URL:         "https://app.datadoghq.com/monitors/456",
+				Project:     "checkout",
+				Tags:        []string{"env:prod", "service:checkout"},
+			},
+		},
+		{
+			name:    "datadog recovery",
+			source:  SourceDatadog,
+			payload: `{"id":"123","title":"[Recovered] Checkout 5xx","alert_transition":"Recovered"}`,
+			ignored: true,
+		},
+		{
+			name:    "datadog without a title",
+			source:  SourceDatadog,
+			payload: `{"id":"123"}`,
+			wantErr: true,
+		},
+		{
+			name:   "bugsnag error",
+			source: SourceBugsnag,
+			payload: `{"project":{"name":"payments"},"trigger":{"type":"firstException","message":"New error"},
+				"error":{"errorId":"e1","exceptionClass":"NoMethodError","message":"undefined method 'id'",
+				"context":"OrdersController#create","severity":"error","url":"https://app.bugsnag.com/e1"}}`,
+			expected: Alert{
+				Source:      SourceBugsnag,
+				ID:          "e1",
+				Title:       "NoMethodError: undefined method 'id'",
+				Description: "New error\nOrdersController#create",
+				Severity:    "error",
+				URL:         "https://app.bugsnag.com/e1",
+				Project:     "payments",
+			},
+		},
+		{
+			name:    "bugsnag trigger without an error",
+			source:  SourceBugsnag,
+			payload: `{"project":{"name":"payments"},"trigger":{"type":"projectSpiking"}}`,
+			ignored: true,
+		},
+		{
+			name:   "pagerduty incident",
+			source: SourcePagerDuty,
+			payload: `{"event":{"event_type":"incident.triggered","data":{"id":"PD1","title":"Database CPU high",
+				"html_url":"https://acme.pagerduty.com/incidents/PD1","urgency":"high",
+				"service":{"summary":"orders-db"},"priority":{"summary":"P2"}}}}`,
+			expected: Alert{
+				Source:   SourcePagerDuty,
+				ID:       "PD1",
+				Title:    "Database CPU high",
+				Severity: "P2",
+				URL:      "https://acme.pagerduty.com/incidents/PD1",
+				Project:  "orders-db",
+			},
+		},
+		{
+			name:    "pagerduty resolution",
+			source:  SourcePagerDuty,
+			payload: `{"event":{"event_type":"incident.resolved","data":{"id":"PD1"}}}`,
+			ignored: true,
+		},
+		{
+			name:    "invalid json",
+			source:  SourcePagerDuty,
+			payload: `{`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown source",
+			source:  "sentry",
+			payload: `{}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert, ok, err := Parse(tt.source, []byte(tt.payload))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error, got %+v", alert)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if ok == tt.ignored {
+				t.Fatalf("Expected ignored=%v, got ok=%v", tt.ignored, ok)
+			}
+			if ok && !reflect.DeepEqual(alert, tt.expected) {
+				t.Errorf("Expected %+v, got %+v", tt.expected, alert)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrNoCredentials is returned when a service's API keys aren't configured
+var ErrNoCredentials = errors.New("no API credentials configured")
+
+// pollTimeout bounds a call to a monitoring service's API
+const pollTimeout = 30 * time.Second
+
+// Poller fetches the alerts firing now in a monitoring service
+type Poller interface {
+	// Source identifies the service
+	Source() Source
+	Fetch(ctx context.Context) ([]Alert, error)
+}
+
+// Datadog polls Datadog for monitors in the alert or warn state
+type Datadog struct {
+	apiBase string
+	appBase string
+	apiKey  string
+	appKey  string
+	client  *http.Client
+}
+
+// NewDatadog creates a Datadog poller for a site like "datadoghq.com"
+func NewDatadog(site, apiKey, appKey string) (*Datadog, error) {
+	if apiKey == "" || appKey == "" {
+		return nil, fmt.Errorf("%w: add Datadog API and application keys in settings", ErrNoCredentials)
+	}
+	if site == "" {
+		site = "datadoghq.com"
+	}
+	// US1 and EU1 serve the app from app.<site>; the other sites serve it
+	// from the site itself
+	appBase := "https://" + site
+	if site == "datadoghq.com" || site == "datadoghq.eu" {
+		appBase = "https://app." + site
+	}
+	return &Datadog{
+		apiBase: "https://api." + site,
+		appBase: appBase,
+		apiKey:  apiKey,
+		appKey:  appKey,
+		client:  &http.Client{Timeout: pollTimeout},
+	}, nil
+}
+
+// Source returns SourceDatadog
+func (d *Datadog) Source() Source { return SourceDatadog }
+
+// Fetch returns a Datadog monitor's alert for each monitor that is alerting
+// or warning
+func (d *Datadog) Fetch(ctx context.Context) ([]Alert, error) {
+	var monitors []struct {
+		ID           int64    `json:"id"`
+		Name         string   `json:"name"`
+		Message      string   `json:"message"`
+		OverallState string   `json:"overall_state"`
+		Tags         []string `json:"tags"`
+		Priority     *int     `json:"priority"`
+	}
+	query := url.Values{"group_states": {"alert,warn"}, "page_size": {"1000"}}
+	headers := map[string]string{"DD-API-KEY": d.apiKey, "DD-APPLICATION-KEY": d.appKey}
+	if err := getJSON(ctx, d.client, d.apiBase+"/api/v1/monitor?"+query.Encode(), headers, &monitors); err != nil {
+		return nil, fmt.Errorf("failed to list Datadog monitors: %w", err)
+	}
+
+	var alerts []Alert
+	for _, monitor := range monitors {
+		var severity string
+		switch monitor.OverallState {
+		case "Alert":
+			severity = "critical"
+		case "Warn":
+			severity = "warning"
+		default:
+			continue
+		}
+		if monitor.Priority != nil {
+			severity = fmt.Sprintf("P%d", *monitor.Priority)
+		}
+
+		alert := Alert{
+			Source:      SourceDatadog,
+			ID:          fmt.Sprint(monitor.ID),
+			Title:       monitor.Name,
+			Description: monitor.Message,
+			Severity:    severity,
+			URL:         fmt.Sprintf("%s/monitors/%d", d.appBase, monitor.ID),
+			Tags:        monitor.Tags,
+		}
+		for _, tag := range monitor.Tags {
+			if service, ok := strings.CutPrefix(tag, "service:"); ok {
+				alert.Project = service
+				break
+			}
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+// Bugsnag polls Bugsnag projects for open errors seen recently
+type Bugsnag struct {
+	apiBase    string
+	apiKey     string
+	projectIDs []string
+	// window is how far back an error must have been seen
+	window time.Duration
+	client *http.Client
+}
+
+// NewBugsnag creates a Bugsnag poller for the projects, reporting errors
+// seen within window
+func NewBugsnag(apiKey string, projectIDs []string, window time.Duration) (*Bugsnag, error) {
+	if apiKey == "" {
+		return nil, fmt.Errorf("%w: add a Bugsnag API key in settings", ErrNoCredentials)
+	}
+	return &Bugsnag{
+		apiBase:    "https://api.bugsnag.com",
+		apiKey:     apiKey,
+		projectIDs: projectIDs,
+		window:     window,
+		client:     &http.Client{Timeout: pollTimeout},
+	}, nil
+}
+
+// Source returns SourceBugsnag
+func (b *Bugsnag) Source() Source { return SourceBugsnag }
+
+// Fetch returns an alert for each open error seen within the window in any
+// of the projects
+func (b *Bugsnag) Fetch(ctx context.Context) ([]Alert, error) {
+	headers := map[string]string{"Authorization": "token " + b.apiKey, "X-Version": "2"}
+	since := time.Now().Add(-b.window).UTC().Format(time.RFC3339)
+
+	var alerts []Alert
+	for _, projectID := range b.projectIDs {
+		query := url.Values{
+			"sort":                           {"last_seen"},
+			"direction":                      {"desc"},
+			"per_page":                       {"50"},
+			"filters[error.status][][type]":  {"eq"},
+			"filters[error.status][][value]": {"open"},
+			"filters[event.since][][type]":   {"eq"},
+			"filters[event.since][][value]":  {since},
+		}
+		var errs []struct {
+			ID         string   `json:"id"`
+			ErrorClass string   `json:"error_class"`
+			Message    string   `json:"message"`
+			Context    string   `json:"context"`
+			Severity   string   `json:"severity"`
+			Events     int      `json:"events"`
+			Stages     []string `json:"release_stages"`
+		}
+		endpoint := fmt.Sprintf("%s/projects/%s/errors?%s", b.apiBase, url.PathEscape(projectID), query.Encode())
+		if err := getJSON(ctx, b.client, endpoint, headers, &errs); err != nil {
+			return alerts, fmt.Errorf("failed to list Bugsnag errors for project %s: %w", projectID, err)
+		}
+
+		for _, e := range errs {
+			title := e.ErrorClass
+			if e.Message != "" {
+				title = strings.TrimPrefix(title+": "+e.Message, ": ")
+			}
+			var tags []string
+			for _, stage := range e.Stages {
+				tags = append(tags, "release_stage:"+stage)
+			}
+			alerts = append(alerts, Alert{
+				Source:      SourceBugsnag,
+				ID:          e.ID,
+				Title:       title,
+				Description: fmt.Sprintf("%s (%d events)", e.Context, e.Events),
+				Severity:    e.Severity,
+				Project:     projectID,
+				Tags:        tags,
+			})
+		}
+	}
+	return alerts, nil
+}
+
+// getJSON sends a GET request and decodes a successful response into out
+func getJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
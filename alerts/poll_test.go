@@ -0,0 +1,97 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDatadog_Fetch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("DD-API-KEY") != "api" || r.Header.Get("DD-APPLICATION-KEY") != "app" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`[
+			{"id":1,"name":"Checkout 5xx","overall_state":"Alert","tags":["service:checkout"],"priority":1},
+			{"id":2,"name":"Search latency","overall_state":"Warn","tags":["env:prod"]},
+			{"id":3,"name":"Healthy","overall_state":"OK"}
+		]`))
+	}))
+	defer ts.Close()
+
+	d, err := NewDatadog("", "api", "app")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	d.apiBase = ts.URL
+
+	alerts, err := d.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []Alert{
+		{Source: SourceDatadog, ID: "1", Title: "Checkout 5xx", Severity: "P1", URL: "https://app.datadoghq.com/monitors/1", Project: "checkout", Tags: []string{"service:checkout"}},
+		{Source: SourceDatadog, ID: "2", Title: "Search latency", Severity: "warning", URL: "https://app.datadoghq.com/monitors/2", Tags: []string{"env:prod"}},
+	}
+	if !reflect.DeepEqual(alerts, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, alerts)
+	}
+}
+
+func TestBugsnag_Fetch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/projects/p1/errors" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`[{"id":"e1","error_class":"NoMethodError","message":"undefined method 'id'",
+			"context":"OrdersController#create","severity":"error","events":12,"release_stages":["production"]}]`))
+	}))
+	defer ts.Close()
+
+	b, err := NewBugsnag("key", []string{"p1"}, time.Hour)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	b.apiBase = ts.URL
+
+	alerts, err := b.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []Alert{{
+		Source:      SourceBugsnag,
+		ID:          "e1",
+		Title:       "NoMethodError: undefined method 'id'",
+		Description: "OrdersController#create (12 events)",
+		Severity:    "error",
+		Project:     "p1",
+		Tags:        []string{"release_stage:production"},
+	}}
+	if !reflect.DeepEqual(alerts, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, alerts)
+	}
+
+	b.apiKey = "wrong"
+	if _, err := b.Fetch(context.Background()); err == nil {
+		t.Error("Expected an error for a rejected API key")
+	}
+}
+
+func TestNewPollers_NoCredentials(t *testing.T) {
+	if _, err := NewDatadog("", "api", ""); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Expected ErrNoCredentials, got %v", err)
+	}
+	if _, err := NewBugsnag("", []string{"p1"}, time.Hour); !errors.Is(err, ErrNoCredentials) {
+		t.Errorf("Expected ErrNoCredentials, got %v", err)
+	}
+}
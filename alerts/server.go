@@ -0,0 +1,207 @@
+package alerts
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"boatman/logging"
+)
+
+// logger logs webhook server failures
+var logger = logging.For("alerts")
+
+const (
+	// maxPayloadSize caps a webhook body
+	maxPayloadSize = 1 << 20
+	// repeatWindow is how long a repeat of an alert is ignored; monitors
+	// re-notify while an alert stays triggered
+	repeatWindow = 10 * time.Minute
+	// shutdownTimeout bounds waiting for in-flight webhooks on Close
+	shutdownTimeout = 5 * time.Second
+)
+
+// Handler is called with each alert that needs investigating
+type Handler func(Alert)
+
+// Server accepts alert webhooks on a local port at /webhooks/<source>. Every
+// request must carry the token, as a bearer token, an X-Boatman-Token header,
+// or a token query parameter for services that can't set headers.
+type Server struct {
+	port    int
+	token   string
+	handler Handler
+
+	mu       sync.Mutex
+	seen     map[string]time.Time // When each alert was last accepted
+	now      func() time.Time
+	listener net.Listener
+	server   *http.Server
+}
+
+// NewServer creates a server for port that passes alerts to handler
+func NewServer(port int, token string, handler Handler) (*Server, error) {
+	if port < 1 || port > 65535 {
+		return nil, fmt.Errorf("invalid webhook port %d", port)
+	}
+	if strings.TrimSpace(token) == "" {
+		return nil, fmt.Errorf("a webhook token is required")
+	}
+	return &Server{
+		port:    port,
+		token:   token,
+		handler: handler,
+		seen:    make(map[string]time.Time),
+		now:     time.Now,
+	}, nil
+}
+
+// Start listens on localhost and serves webhooks in the background
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", s.port))
+	if err != nil {
+		return fmt.Errorf("failed to listen for alert webhooks: %w", err)
+	}
+
+	s.mu.Lock()
+	s.listener = listener
+	s.server = &http.Server{Handler: s.routes(), ReadHeaderTimeout: 10 * time.Second}
+	server := s.server
+	s.mu.Unlock()
+
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Webhook server stopped", "error", err)
+		}
+	}()
+	return nil
+}
+
+// Addr returns the address the server listens on, or "" before Start
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Close stops the server, waiting briefly for webhooks being handled
+func (s *Server) Close() error {
+	s.mu.Lock()
+	server := s.server
+	s.server, s.listener = nil, nil
+	s.mu.Unlock()
+	if server == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return server.Shutdown(ctx)
+}
+
+// routes returns the handler serving the webhook endpoints
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /webhooks/{source}", s.handleWebhook)
+	return mux
+}
+
+func (s *Server) handleWebhook(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		writeStatus(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+
+	source := Source(r.PathValue("source"))
+	if !knownSource(source) {
+		writeStatus(w, http.StatusNotFound, "unknown source")
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPayloadSize))
+	if err != nil {
+		writeStatus(w, http.StatusRequestEntityTooLarge, "payload too large")
+		return
+	}
+	alert, ok, err := Parse(source, payload)
+	if err != nil {
+		writeStatus(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if !ok {
+		writeStatus(w, http.StatusOK, "ignored")
+		return
+	}
+	if s.isRepeat(alert) {
+		writeStatus(w, http.StatusOK, "duplicate")
+		return
+	}
+
+	s.handler(alert)
+	writeStatus(w, http.StatusAccepted, "accepted")
+}
+
+// authorized checks the request carries the server's token
+func (s *Server) authorized(r *http.Request) bool {
+	token := r.Header.Get("X-Boatman-Token")
+	if bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		token = bearer
+	}
+	if token == "" {
+		token = r.URL.Query().Get("token")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) == 1
+}
+
+// isRepeat reports whether the alert was accepted within repeatWindow,
+// recording it otherwise. Alerts without an ID are never repeats.
+func (s *Server) isRepeat(alert Alert) bool {
+	if alert.ID == "" {
+		return false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for key, at := range s.seen {
+		if now.Sub(at) >= repeatWindow {
+			delete(s.seen, key)
+		}
+	}
+
+	key := string(alert.Source) + "/" + alert.ID
+	if _, ok := s.seen[key]; ok {
+		return true
+	}
+	s.seen[key] = now
+	return false
+}
+
+// knownSource reports whether webhooks are accepted from source
+func knownSource(source Source) bool {
+	for _, known := range Sources {
+		if source == known {
+			return true
+		}
+	}
+	return false
+}
+
+// writeStatus writes a JSON body reporting what happened to a webhook
+func writeStatus(w http.ResponseWriter, code int, status string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(map[string]string{"status": status})
+}
@@ -0,0 +1,100 @@
+package alerts
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testPayload = `{"alert_id":"456","title":"Checkout 5xx","tags":["service:checkout"]}`
+
+func newTestServer(t *testing.T) (*Server, *httptest.Server, *[]Alert) {
+	t.Helper()
+	var received []Alert
+	s, err := NewServer(7421, "secret", func(alert Alert) {
+		received = append(received, alert)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ts := httptest.NewServer(s.routes())
+	t.Cleanup(ts.Close)
+	return s, ts, &received
+}
+
+func TestServer_Webhooks(t *testing.T) {
+	tests := []struct {
+		name     string
+		path     string
+		header   string
+		payload  string
+		expected int
+		alerts   int
+	}{
+		{name: "bearer token", path: "/webhooks/datadog", header: "Bearer secret", payload: testPayload, expected: http.StatusAccepted, alerts: 1},
+		{name: "query token", path: "/webhooks/datadog?token=secret", payload: testPayload, expected: http.StatusAccepted, alerts: 1},
+		{name: "missing token", path: "/webhooks/datadog", payload: testPayload, expected: http.StatusUnauthorized},
+		{name: "wrong token", path: "/webhooks/datadog", header: "Bearer nope", payload: testPayload, expected: http.StatusUnauthorized},
+		{name: "unknown source", path: "/webhooks/sentry?token=secret", payload: testPayload, expected: http.StatusNotFound},
+		{name: "bad payload", path: "/webhooks/datadog?token=secret", payload: `{`, expected: http.StatusBadRequest},
+		{name: "recovery", path: "/webhooks/datadog?token=secret", payload: `{"title":"ok","alert_transition":"Recovered"}`, expected: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, ts, received := newTestServer(t)
+			req, _ := http.NewRequest(http.MethodPost, ts.URL+tt.path, strings.NewReader(tt.payload))
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, resp.StatusCode)
+			}
+			if len(*received) != tt.alerts {
+				t.Errorf("Expected %d alerts handled, got %d", tt.alerts, len(*received))
+			}
+		})
+	}
+}
+
+func TestServer_IgnoresRepeats(t *testing.T) {
+	s, ts, received := newTestServer(t)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	post := func() int {
+		resp, err := http.Post(ts.URL+"/webhooks/datadog?token=secret", "application/json", strings.NewReader(testPayload))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	post()
+	if status := post(); status != http.StatusOK || len(*received) != 1 {
+		t.Errorf("Expected the repeat to be ignored, got status %d and %d alerts", status, len(*received))
+	}
+
+	now = now.Add(repeatWindow)
+	if status := post(); status != http.StatusAccepted || len(*received) != 2 {
+		t.Errorf("Expected the alert to be handled again after the window, got status %d and %d alerts", status, len(*received))
+	}
+}
+
+func TestNewServer_Validation(t *testing.T) {
+	if _, err := NewServer(7421, " ", nil); err == nil {
+		t.Error("Expected an error without a token")
+	}
+	if _, err := NewServer(70000, "secret", nil); err == nil {
+		t.Error("Expected an error for an invalid port")
+	}
+}
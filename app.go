@@ -2,21 +2,45 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"boatman/agent"
+	"boatman/alerts"
 	"boatman/auth"
 	bmintegration "boatman/boatmanmode"
+	"boatman/commands"
 	"boatman/config"
+	"boatman/diagnostics"
 	"boatman/diff"
 	gitpkg "boatman/git"
+	"boatman/logging"
 	"boatman/mcp"
+	"boatman/memory"
+	"boatman/notify"
 	"boatman/project"
+	"boatman/updater"
+	"boatman/vcs"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
+// logger logs the app's own diagnostics
+var logger = logging.For("app")
+
 // App struct holds application state and dependencies
 type App struct {
 	ctx            context.Context
@@ -24,6 +48,16 @@ type App struct {
 	agentManager   *agent.Manager
 	projectManager *project.ProjectManager
 	mcpManager     *mcp.Manager
+	updater        *updater.Updater
+	commands       *commands.Registry
+	notifier       *notify.Dispatcher
+	memory         *memory.Store
+	// windowFocused is reported by the frontend; Wails has no focus API
+	windowFocused atomic.Bool
+
+	// Listener for alert webhooks, nil while turned off
+	alertServer *alerts.Server
+	alertMu     sync.Mutex
 }
 
 // NewApp creates a new App application struct
@@ -43,17 +77,41 @@ func NewApp() *App {
 		panic(err)
 	}
 
-	return &App{
+	upd, err := updater.New(version)
+	if err != nil {
+		panic(err)
+	}
+
+	memoryDir, err := memory.DefaultDir()
+	if err != nil {
+		panic(err)
+	}
+
+	app := &App{
 		config:         cfg,
 		agentManager:   agent.NewManager(),
 		projectManager: pm,
 		mcpManager:     mcpMgr,
+		updater:        upd,
+		commands:       commands.NewRegistry(),
+		memory:         memory.NewStore(memoryDir),
+	}
+	app.notifier = notify.NewDispatcher(app.notificationSettings, app.windowFocused.Load, app.sendNotification)
+	if err := app.registerCommands(); err != nil {
+		panic(err)
 	}
+	return app
 }
 
 // startup is called when the app starts
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+
+	// Keep diagnostics in ~/.boatman/logs for bug reports
+	if err := a.startLogging(); err != nil {
+		runtime.LogWarningf(ctx, "Failed to start log file: %v", err)
+	}
+
 	a.agentManager.SetContext(ctx)
 	a.agentManager.SetAuthConfigGetter(func() agent.AuthConfig {
 		prefs := a.config.GetPreferences()
@@ -64,23 +122,232 @@ func (a *App) startup(ctx context.Context) {
 			GCPProjectID: gcpProjectID,
 			GCPRegion:    gcpRegion,
 			ApprovalMode: string(prefs.ApprovalMode),
+
+			FilesystemGuardrails:  prefs.FilesystemGuardrails,
+			GuardrailAllowedPaths: prefs.GuardrailAllowedPaths,
+			ProtectedPaths:        a.config.GetProtectedPaths(),
+			AppendSystemPrompt:    prefs.SystemPromptAppend,
+			ClaudeCLIPath:         prefs.ClaudeCLIPath,
+			RetryPolicy: agent.RetryPolicy{
+				MaxRetries:   prefs.RunRetries,
+				InitialDelay: time.Duration(prefs.RunRetryDelaySeconds) * time.Second,
+			},
 		}
 	})
 
+	// Attach only the MCP servers enabled for each project
+	a.agentManager.SetMCPConfigResolver(a.resolveMCPConfig)
+
+	// Append each project's system prompt addition after the user-level one
+	a.agentManager.SetSystemPromptResolver(a.config.GetProjectSystemPrompt)
+
+	// Give each project's sessions its model and approval mode, and the user's default model otherwise
+	a.agentManager.SetDefaultModel(a.config.GetPreferences().DefaultModel)
+	a.agentManager.SetProjectDefaultsResolver(a.GetProjectDefaults)
+
+	// Apply each project's tool allowlist and denylist
+	a.agentManager.SetToolPolicyResolver(a.GetProjectToolPolicy)
+
+	// Share each project's notes with its sessions, and let them add to it
+	a.agentManager.SetProjectMemoryResolver(a.resolveProjectMemory)
+
 	// Set config getter for memory management
 	a.agentManager.SetConfigGetter(a)
 
-	// Run session cleanup asynchronously on startup
-	go func() {
+	// Enforce the user's cost budgets
+	a.agentManager.SetBudgetGetter(func() agent.Budget {
+		prefs := a.config.GetPreferences()
+		return agent.Budget{
+			MaxPerSession: prefs.MaxCostPerSession,
+			MaxPerDay:     prefs.MaxCostPerDay,
+		}
+	})
+
+	// Price usage with the user's rates where they override list prices
+	applyModelPricing(a.config.GetPreferences().ModelPricing)
+
+	// Limit how many sessions run claude at once
+	a.agentManager.SetMaxConcurrentRuns(a.config.GetPreferences().MaxConcurrentRuns)
+	a.agentManager.SetStreamBatchInterval(time.Duration(a.config.GetPreferences().StreamBatchIntervalMs) * time.Millisecond)
+
+	// Notify about sessions that need attention while the user is elsewhere
+	a.windowFocused.Store(true)
+	a.agentManager.SetStatusListener(func(session *agent.Session, status agent.SessionStatus) {
+		a.notifier.StatusChanged(session.ID, filepath.Base(session.ProjectPath), string(status))
+	})
+	a.agentManager.SetCostListener(func(session *agent.Session, cost float64) {
+		a.notifier.CostChanged(session.ID, filepath.Base(session.ProjectPath), cost)
+	})
+	a.agentManager.Attention().SetListener(a.attentionChanged)
+
+	// Report supervised MCP servers starting, crashing and restarting
+	a.mcpManager.SetStatusHandler(func(status mcp.ServerStatus) {
+		runtime.EventsEmit(ctx, "mcp:status", status)
+	})
+
+	// Load user-defined tool formatters
+	if _, err := a.ReloadToolFormatters(); err != nil {
+		runtime.LogWarningf(ctx, "Failed to load tool formatters: %v", err)
+	}
+
+	// Persist sessions in the session database, falling back to JSON files
+	// when it can't be opened (e.g. another instance holds it)
+	if err := a.openSessionStore(); err != nil {
+		runtime.LogWarningf(ctx, "Session database unavailable, saving sessions as files: %v", err)
+	}
+
+	// Sessions encrypted with a passphrase stay locked until UnlockSessions
+	a.applySessionEncryption()
+
+	// Index message content for search, including archived messages
+	if err := a.openSearchIndex(); err != nil {
+		runtime.LogWarningf(ctx, "Search index unavailable, searching loaded sessions only: %v", err)
+	}
+	if agent.EncryptionLocked() {
+		runtime.EventsEmit(ctx, "sessions:locked")
+	} else {
+		// Clean up old sessions before restoring the rest
 		if count, err := a.agentManager.CleanupSessions(); err == nil && count > 0 {
 			runtime.LogInfof(ctx, "Cleaned up %d old sessions", count)
 		}
+		if count, err := a.agentManager.RestoreSessions(); err != nil {
+			runtime.LogWarningf(ctx, "Failed to restore sessions: %v", err)
+		} else if count > 0 {
+			runtime.LogInfof(ctx, "Restored %d sessions", count)
+		}
+	}
+
+	// Hand incoming alerts to firefighter sessions
+	if err := a.restartAlertWebhooks(); err != nil {
+		runtime.LogWarningf(ctx, "Alert webhooks unavailable: %v", err)
+	}
+
+	// Check for updates in the background and let the frontend know
+	go func() {
+		info, err := a.CheckForUpdates()
+		if err == nil && info.UpdateAvailable {
+			runtime.EventsEmit(ctx, "update:available", info)
+		}
 	}()
 }
 
 // shutdown is called when the app is closing
 func (a *App) shutdown(ctx context.Context) {
+	a.stopAlertWebhooks()
 	a.agentManager.StopAllSessions()
+	a.mcpManager.StopServers()
+	a.mcpManager.CloseClients()
+
+	// Write pending session changes before the database closes
+	a.agentManager.FlushSaves()
+	if store := agent.ActiveStore(); store != nil {
+		agent.UseStore(nil)
+		if err := store.Close(); err != nil {
+			logger.Warn("Failed to close session database", "error", err)
+		}
+	}
+
+	a.closeSearchIndex()
+
+	// Install a downloaded update so the next launch runs it
+	if pending, err := a.updater.Install(); err != nil {
+		logger.Warn("Failed to install update", "error", err)
+	} else if pending != nil {
+		logger.Info("Installed update", "version", pending.Version)
+	}
+
+	if err := logging.Close(); err != nil {
+		logger.Warn("Failed to close log file", "error", err)
+	}
+}
+
+// openSessionStore opens the session database and makes it the session backend
+func (a *App) openSessionStore() error {
+	path, err := agent.DefaultStorePath()
+	if err != nil {
+		return err
+	}
+	store, err := agent.OpenBoltStore(path)
+	if err != nil {
+		return err
+	}
+
+	// Import sessions saved as files by earlier versions
+	sessionsDir, err := agent.GetSessionsDir()
+	if err != nil {
+		store.Close()
+		return err
+	}
+	archivesDir, err := agent.GetArchivesDir()
+	if err != nil {
+		store.Close()
+		return err
+	}
+	count, err := store.ImportFiles(sessionsDir, archivesDir)
+	if err != nil {
+		store.Close()
+		return fmt.Errorf("failed to import saved sessions: %w", err)
+	}
+	if count > 0 {
+		runtime.LogInfof(a.ctx, "Imported %d saved sessions into the session database", count)
+	}
+
+	agent.UseStore(store)
+	return nil
+}
+
+// openSearchIndex opens the message search index, filling it from saved
+// sessions when it's new. While sessions are encrypted the index is kept in
+// memory so their content isn't written to disk in plaintext.
+func (a *App) openSearchIndex() error {
+	path, err := agent.DefaultSearchIndexPath()
+	if err != nil {
+		return err
+	}
+	a.closeSearchIndex()
+	if a.config.GetPreferences().SessionEncryption != "" {
+		// An index written before encryption was turned on holds plaintext
+		if err := os.RemoveAll(path); err != nil {
+			logger.Warn("Failed to remove unencrypted search index", "error", err)
+		}
+		path = ""
+	}
+
+	index, err := agent.OpenSearchIndex(path)
+	if err != nil {
+		return err
+	}
+	agent.UseSearchIndex(index)
+	a.fillSearchIndex(index)
+	return nil
+}
+
+// fillSearchIndex indexes every saved session in the background if index is
+// empty and sessions can be read
+func (a *App) fillSearchIndex(index *agent.SearchIndex) {
+	if !index.Empty() || agent.EncryptionLocked() {
+		return
+	}
+	go func() {
+		count, err := agent.BuildSearchIndex(index)
+		if err != nil {
+			logger.Warn("Failed to build search index", "error", err)
+			return
+		}
+		logger.Info("Built search index", "sessions", count)
+	}()
+}
+
+// closeSearchIndex stops using the search index and closes it
+func (a *App) closeSearchIndex() {
+	index := agent.ActiveSearchIndex()
+	if index == nil {
+		return
+	}
+	agent.UseSearchIndex(nil)
+	if err := index.Close(); err != nil {
+		logger.Warn("Failed to close search index", "error", err)
+	}
 }
 
 // =============================================================================
@@ -92,9 +359,110 @@ func (a *App) GetPreferences() config.UserPreferences {
 	return a.config.GetPreferences()
 }
 
+// maxStreamBatchIntervalMs keeps streamed responses from looking stalled
+const maxStreamBatchIntervalMs = 1000
+
 // SetPreferences updates user preferences
 func (a *App) SetPreferences(prefs config.UserPreferences) error {
-	return a.config.SetPreferences(prefs)
+	if n := prefs.Notifications; n != nil && n.QuietHoursEnabled {
+		if err := notify.ValidateClock(n.QuietHoursStart); err != nil {
+			return fmt.Errorf("quiet hours start: %w", err)
+		}
+		if err := notify.ValidateClock(n.QuietHoursEnd); err != nil {
+			return fmt.Errorf("quiet hours end: %w", err)
+		}
+	}
+	if n := prefs.Notifications; n != nil && n.CostThreshold < 0 {
+		return fmt.Errorf("cost notification threshold can't be negative")
+	}
+	if err := agent.ValidateApprovalMode(string(prefs.ApprovalMode)); err != nil {
+		return err
+	}
+	if prefs.RunRetryDelaySeconds < 0 {
+		return fmt.Errorf("retry delay can't be negative")
+	}
+	if prefs.StreamBatchIntervalMs > maxStreamBatchIntervalMs {
+		return fmt.Errorf("stream batch interval can't be over %d ms", maxStreamBatchIntervalMs)
+	}
+	if prefs.MaxCostPerSession < 0 || prefs.MaxCostPerDay < 0 {
+		return fmt.Errorf("cost budgets can't be negative")
+	}
+	for model, price := range prefs.ModelPricing {
+		if price.Input < 0 || price.Output < 0 || price.CacheWrite < 0 || price.CacheRead < 0 {
+			return fmt.Errorf("pricing for %s can't be negative", model)
+		}
+	}
+	if w := prefs.AlertWebhooks; w != nil && w.Enabled {
+		if w.Port < 0 || w.Port > 65535 {
+			return fmt.Errorf("alert webhook port must be between 1 and 65535")
+		}
+		if strings.TrimSpace(w.Token) == "" {
+			return fmt.Errorf("alert webhooks need a token")
+		}
+	}
+	for _, pattern := range prefs.ProtectedPaths {
+		if err := agent.ValidateProtectedPath(pattern); err != nil {
+			return err
+		}
+	}
+	if prefs.LogLevel != "" {
+		if _, err := logging.ParseLevel(prefs.LogLevel); err != nil {
+			return err
+		}
+	}
+	// Session encryption changes go through SetSessionEncryption, which
+	// re-encrypts what's saved, and saved searches have their own methods
+	current := a.config.GetPreferences()
+	prefs.SessionEncryption = current.SessionEncryption
+	prefs.SessionKeySalt = current.SessionKeySalt
+	prefs.SessionKeyCheck = current.SessionKeyCheck
+	prefs.SavedSearches = current.SavedSearches
+
+	webhooks := a.config.GetAlertWebhookSettings()
+	if err := a.config.SetPreferences(prefs); err != nil {
+		return err
+	}
+	applyModelPricing(prefs.ModelPricing)
+	a.agentManager.SetMaxConcurrentRuns(prefs.MaxConcurrentRuns)
+	a.agentManager.SetStreamBatchInterval(time.Duration(prefs.StreamBatchIntervalMs) * time.Millisecond)
+	a.agentManager.SetDefaultModel(prefs.DefaultModel)
+	logging.SetLevel(a.config.GetLogLevel())
+	if !reflect.DeepEqual(webhooks, a.config.GetAlertWebhookSettings()) {
+		return a.restartAlertWebhooks()
+	}
+	return nil
+}
+
+// applyModelPricing makes the configured prices override the agent's list prices
+func applyModelPricing(prices map[string]config.ModelPrice) {
+	overrides := make(map[string]agent.ModelPricing, len(prices))
+	for model, price := range prices {
+		overrides[model] = agent.ModelPricing{
+			Input:      price.Input,
+			Output:     price.Output,
+			CacheWrite: price.CacheWrite,
+			CacheRead:  price.CacheRead,
+		}
+	}
+	agent.SetPricingOverrides(overrides)
+}
+
+// notificationSettings converts the saved notification preferences for the dispatcher
+func (a *App) notificationSettings() notify.Settings {
+	n := a.config.GetNotificationSettings()
+	return notify.Settings{
+		Enabled:           a.config.GetPreferences().NotificationsEnabled,
+		OnWaiting:         n.OnWaiting,
+		OnCompleted:       n.OnCompleted,
+		OnError:           n.OnError,
+		OnAlert:           n.OnAlert,
+		CostThreshold:     n.CostThreshold,
+		MinTurnDuration:   time.Duration(n.MinTurnSeconds) * time.Second,
+		OnlyWhenUnfocused: n.OnlyWhenUnfocused,
+		QuietHoursEnabled: n.QuietHoursEnabled,
+		QuietHoursStart:   n.QuietHoursStart,
+		QuietHoursEnd:     n.QuietHoursEnd,
+	}
 }
 
 // IsOnboardingCompleted checks if onboarding is done
@@ -119,6 +487,25 @@ type AgentSessionInfo struct {
 	CreatedAt   string              `json:"createdAt"`
 	Tags        []string            `json:"tags,omitempty"`
 	IsFavorite  bool                `json:"isFavorite,omitempty"`
+	// ErrorKind says why a session in the error status failed, e.g. "auth"
+	ErrorKind string `json:"errorKind,omitempty"`
+	// QueuePosition is a queued session's place in line for a run slot
+	QueuePosition int `json:"queuePosition,omitempty"`
+	// Snapshot, when set, can be restored to undo the agent's full-auto runs
+	SnapshotBeforeRun bool             `json:"snapshotBeforeRun,omitempty"`
+	Snapshot          *gitpkg.Snapshot `json:"snapshot,omitempty"`
+	// PlanMode sessions propose diffs for review instead of changing files
+	PlanMode bool `json:"planMode,omitempty"`
+	// ToolPolicy is the session's own tool policy, on top of its project's
+	ToolPolicy agent.ToolPolicy `json:"toolPolicy"`
+	// SystemPromptOverride replaces the user's and project's system prompt additions
+	SystemPromptOverride string `json:"systemPromptOverride,omitempty"`
+	// Model is what the session runs; the overrides replace its project's defaults
+	Model                string `json:"model,omitempty"`
+	ModelOverride        string `json:"modelOverride,omitempty"`
+	ApprovalModeOverride string `json:"approvalModeOverride,omitempty"`
+	// AdditionalPaths are the directories the session spans besides ProjectPath
+	AdditionalPaths []string `json:"additionalPaths,omitempty"`
 }
 
 // CreateAgentSession creates a new agent session
@@ -136,6 +523,47 @@ func (a *App) CreateAgentSession(projectPath string) (*AgentSessionInfo, error)
 	}, nil
 }
 
+// CreateMultiRootSession creates an agent session spanning several
+// directories, e.g. frontend and backend repos. It runs in projectPath and
+// can work in additionalPaths too.
+func (a *App) CreateMultiRootSession(projectPath string, additionalPaths []string) (*AgentSessionInfo, error) {
+	additionalPaths, err := agent.NormalizeAdditionalPaths(projectPath, additionalPaths)
+	if err != nil {
+		return nil, err
+	}
+	session, err := a.agentManager.CreateSession(projectPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.agentManager.SetSessionAdditionalPaths(session.ID, additionalPaths); err != nil {
+		return nil, err
+	}
+
+	return &AgentSessionInfo{
+		ID:              session.ID,
+		ProjectPath:     session.ProjectPath,
+		Status:          session.Status,
+		CreatedAt:       session.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		AdditionalPaths: session.GetAdditionalPaths(),
+	}, nil
+}
+
+// SetSessionAdditionalPaths sets the directories a session spans besides its
+// project, from its next message
+func (a *App) SetSessionAdditionalPaths(sessionID string, paths []string) error {
+	return a.agentManager.SetSessionAdditionalPaths(sessionID, paths)
+}
+
+// GetSessionWorkspaceInfo returns information about each directory a session
+// spans, its project first
+func (a *App) GetSessionWorkspaceInfo(sessionID string) ([]*project.WorkspaceInfo, error) {
+	session, err := a.agentManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return project.NewWorkspace(session.ProjectPath, session.GetAdditionalPaths()...).GetRootsInfo()
+}
+
 // CreateFirefighterSession creates a new firefighter agent session
 func (a *App) CreateFirefighterSession(projectPath string, scope string) (*AgentSessionInfo, error) {
 	session, err := a.agentManager.CreateFirefighterSession(projectPath, scope)
@@ -179,6 +607,11 @@ func (a *App) StopAgentSession(sessionID string) error {
 	return a.agentManager.StopSession(sessionID)
 }
 
+// CancelAgentRun aborts the message a session is working on without stopping the session
+func (a *App) CancelAgentRun(sessionID string) error {
+	return a.agentManager.CancelRun(sessionID)
+}
+
 // DeleteAgentSession deletes an agent session
 func (a *App) DeleteAgentSession(sessionID string) error {
 	return a.agentManager.DeleteSession(sessionID)
@@ -189,11 +622,109 @@ func (a *App) SendAgentMessage(sessionID, content string) error {
 	return a.agentManager.SendMessage(sessionID, content)
 }
 
+// SendAgentMessageWithAttachments sends a message with workspace files the
+// user attached, e.g. by @-mentioning them, and images such as pasted
+// screenshots. Small text files are inlined in the prompt; others are
+// referenced by path.
+func (a *App) SendAgentMessageWithAttachments(sessionID, content string, attachments []string, images []agent.ImageAttachment) error {
+	return a.agentManager.SendMessageWithAttachments(sessionID, content, attachments, images)
+}
+
+// SendTemplatedAgentMessage resolves {{variable}} placeholders (file, branch, error_id, selection, ...)
+// from project, git, and session context plus the supplied vars, then sends the prompt
+func (a *App) SendTemplatedAgentMessage(sessionID, template string, vars map[string]string) error {
+	session, err := a.agentManager.GetSession(sessionID)
+	if err != nil {
+		return err
+	}
+
+	resolved := make(map[string]string)
+	repo := gitpkg.NewRepository(session.ProjectPath)
+	if repo.IsGitRepo() {
+		if branch, err := repo.GetCurrentBranch(); err == nil {
+			resolved["branch"] = branch
+		}
+	}
+	for k, v := range vars {
+		resolved[k] = v
+	}
+
+	return a.agentManager.SendTemplatedMessage(sessionID, template, resolved)
+}
+
+// GetTemplateVariables returns the placeholder names referenced by a prompt template
+func (a *App) GetTemplateVariables(template string) []string {
+	return agent.TemplateVariables(template)
+}
+
+// RegenerateAgentResponse regenerates the last assistant response of a session
+func (a *App) RegenerateAgentResponse(sessionID string, opts agent.RegenerateOptions) error {
+	return a.agentManager.RegenerateLastResponse(sessionID, opts)
+}
+
+// EditAndResendAgentMessage edits an earlier user message and resends the conversation from there
+func (a *App) EditAndResendAgentMessage(sessionID, messageID, content string) error {
+	return a.agentManager.EditAndResendMessage(sessionID, messageID, content)
+}
+
+// EstimateMessage returns a pre-send token and cost estimate for a prompt
+func (a *App) EstimateMessage(sessionID, content string) (*agent.MessageEstimate, error) {
+	return a.agentManager.EstimateMessage(sessionID, content)
+}
+
+// EstimateMessageWithAttachments returns a pre-send token and cost estimate for a prompt with files and images attached
+func (a *App) EstimateMessageWithAttachments(sessionID, content string, attachments []string, images []agent.ImageAttachment) (*agent.MessageEstimate, error) {
+	return a.agentManager.EstimateMessageWithAttachments(sessionID, content, attachments, images)
+}
+
+// GetContextUsage reports how much of a session's context window its conversation fills
+func (a *App) GetContextUsage(sessionID string) (*agent.ContextUsage, error) {
+	return a.agentManager.GetContextUsage(sessionID)
+}
+
+// ExportAgentSession writes a session's transcript to path as "markdown",
+// "json", or "html". With no path the user picks one in a save dialog. It
+// returns the path written, or "" if the dialog was cancelled.
+func (a *App) ExportAgentSession(sessionID, format, path string) (string, error) {
+	exportFormat, err := agent.ParseExportFormat(format)
+	if err != nil {
+		return "", err
+	}
+	session, err := a.agentManager.GetSession(sessionID)
+	if err != nil {
+		return "", err
+	}
+
+	if path == "" {
+		path, err = runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+			Title:           "Export Session",
+			DefaultFilename: fmt.Sprintf("%s-%s%s", filepath.Base(session.ProjectPath), time.Now().Format("2006-01-02"), exportFormat.Extension()),
+		})
+		if err != nil || path == "" {
+			return "", err
+		}
+	}
+
+	data, err := session.Export(exportFormat)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write export: %w", err)
+	}
+	return path, nil
+}
+
 // ApproveAgentAction approves a pending action
 func (a *App) ApproveAgentAction(sessionID, actionID string) error {
 	return a.agentManager.ApproveAction(sessionID, actionID)
 }
 
+// GetPendingAgentActions returns the tool calls in a session waiting for approval
+func (a *App) GetPendingAgentActions(sessionID string) ([]agent.PendingAction, error) {
+	return a.agentManager.GetPendingActions(sessionID)
+}
+
 // RejectAgentAction rejects a pending action
 func (a *App) RejectAgentAction(sessionID, actionID string) error {
 	return a.agentManager.RejectAction(sessionID, actionID)
@@ -213,15 +744,10 @@ type MessagePage struct {
 	HasMore  bool            `json:"hasMore"`
 }
 
-// GetAgentMessagesPaginated returns a paginated list of messages for a session
+// GetAgentMessagesPaginated returns a page of a session's whole history.
+// Page 0 is the newest messages and later pages go back in time, each
+// oldest first. Archived messages are read from their pages on disk.
 func (a *App) GetAgentMessagesPaginated(sessionID string, page, pageSize int) (*MessagePage, error) {
-	allMessages, err := a.agentManager.GetSessionMessages(sessionID)
-	if err != nil {
-		return nil, err
-	}
-
-	total := len(allMessages)
-
 	// Default page size
 	if pageSize <= 0 {
 		pageSize = 50
@@ -232,41 +758,52 @@ func (a *App) GetAgentMessagesPaginated(sessionID string, page, pageSize int) (*
 		page = 0
 	}
 
-	// Calculate start and end indices
-	start := page * pageSize
-	if start >= total {
-		// Page is beyond available messages
-		return &MessagePage{
-			Messages: []agent.Message{},
-			Total:    total,
-			Page:     page,
-			PageSize: pageSize,
-			HasMore:  false,
-		}, nil
-	}
-
-	end := start + pageSize
-	if end > total {
-		end = total
+	messages, total, err := a.agentManager.GetSessionMessagePage(sessionID, page*pageSize, pageSize)
+	if err != nil {
+		return nil, err
 	}
 
-	messages := allMessages[start:end]
-	hasMore := end < total
-
 	return &MessagePage{
 		Messages: messages,
 		Total:    total,
 		Page:     page,
 		PageSize: pageSize,
-		HasMore:  hasMore,
+		HasMore:  (page+1)*pageSize < total,
 	}, nil
 }
 
+// GetSessionTurns returns a session's transcript grouped into user→assistant
+// exchanges, each with its duration and cost
+func (a *App) GetSessionTurns(sessionID string) ([]agent.Turn, error) {
+	return a.agentManager.GetSessionTurns(sessionID)
+}
+
 // GetAgentTasks returns tasks for a session
 func (a *App) GetAgentTasks(sessionID string) ([]agent.Task, error) {
 	return a.agentManager.GetSessionTasks(sessionID)
 }
 
+// GetSubAgents returns a session's main agent and the sub-agents it spawned
+func (a *App) GetSubAgents(sessionID string) ([]agent.AgentInfo, error) {
+	return a.agentManager.GetSessionAgents(sessionID)
+}
+
+// GetSubAgentMessages returns a sub-agent's own transcript
+func (a *App) GetSubAgentMessages(sessionID, agentID string) ([]agent.Message, error) {
+	return a.agentManager.GetAgentMessages(sessionID, agentID)
+}
+
+// GetRunningCommands returns Bash commands still running in a session
+func (a *App) GetRunningCommands(sessionID string) ([]agent.RunningCommand, error) {
+	return a.agentManager.GetRunningCommands(sessionID)
+}
+
+// KillRunningCommands kills a session's long-running commands and reports them,
+// to the agent with the next message
+func (a *App) KillRunningCommands(sessionID string) error {
+	return a.agentManager.KillRunningCommands(sessionID)
+}
+
 // ListAgentSessions returns all agent sessions
 func (a *App) ListAgentSessions() []AgentSessionInfo {
 	sessions := a.agentManager.ListSessions()
@@ -279,11 +816,57 @@ func (a *App) ListAgentSessions() []AgentSessionInfo {
 			CreatedAt:   s.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 			Tags:        s.Tags,
 			IsFavorite:  s.IsFavorite,
+
+			SnapshotBeforeRun: s.SnapshotBeforeRun,
+			Snapshot:          s.GetSnapshot(),
+			PlanMode:          s.IsPlanMode(),
+			ToolPolicy:        s.GetToolPolicy(),
+
+			SystemPromptOverride: s.GetSystemPromptOverride(),
+			Model:                s.GetModel(),
+			ModelOverride:        s.GetModelOverride(),
+			ApprovalModeOverride: s.GetApprovalModeOverride(),
+			AdditionalPaths:      s.GetAdditionalPaths(),
+		}
+		switch s.Status {
+		case agent.SessionStatusError:
+			infos[i].ErrorKind = agent.ErrorKind(s.LastError())
+		case agent.SessionStatusQueued:
+			infos[i].QueuePosition = s.QueuePosition()
 		}
 	}
 	return infos
 }
 
+// ListRestorableSessions returns saved sessions, most recently active first,
+// so the user can pick up a conversation where they left off
+func (a *App) ListRestorableSessions() ([]agent.RestorableSession, error) {
+	return a.agentManager.RestorableSessions()
+}
+
+// RestoreAgentSession loads a saved session; its next message resumes the conversation
+func (a *App) RestoreAgentSession(sessionID string) (*AgentSessionInfo, error) {
+	session, err := a.agentManager.RestoreSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &AgentSessionInfo{
+		ID:          session.ID,
+		ProjectPath: session.ProjectPath,
+		Status:      session.Status,
+		CreatedAt:   session.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		Tags:        session.Tags,
+		IsFavorite:  session.IsFavorite,
+	}, nil
+}
+
+// ImportClaudeCodeHistory imports the transcripts the claude CLI keeps in
+// ~/.claude/projects as sessions, returning how many were added
+func (a *App) ImportClaudeCodeHistory() (int, error) {
+	return a.agentManager.ImportClaudeHistory("")
+}
+
 // =============================================================================
 // Project Methods
 // =============================================================================
@@ -303,6 +886,11 @@ func (a *App) GetProject(id string) (*project.Project, error) {
 	return a.projectManager.GetProject(id)
 }
 
+// RefreshProjectGitMetadata re-reads a project's git remote and default branch
+func (a *App) RefreshProjectGitMetadata(id string) (*project.Project, error) {
+	return a.projectManager.RefreshGitMetadata(id)
+}
+
 // ListProjects returns all projects
 func (a *App) ListProjects() []project.Project {
 	return a.projectManager.ListProjects()
@@ -326,46 +914,174 @@ func (a *App) GetWorkspaceInfo(path string) (*project.WorkspaceInfo, error) {
 	return ws.GetInfo()
 }
 
+// ReadWorkspaceFile previews up to maxBytes of a project file (a default
+// amount when zero), flagging binary files and truncated content
+func (a *App) ReadWorkspaceFile(projectPath, relPath string, maxBytes int) (*project.FilePreview, error) {
+	return project.NewWorkspace(projectPath).PreviewFile(relPath, maxBytes)
+}
+
+// GetProjectClaudeMD returns a project's CLAUDE.md, the instructions claude
+// reads in every session
+func (a *App) GetProjectClaudeMD(projectPath string) (*project.ClaudeMD, error) {
+	return project.NewWorkspace(projectPath).ReadClaudeMD()
+}
+
+// SetProjectClaudeMD replaces a project's CLAUDE.md, creating it if needed
+func (a *App) SetProjectClaudeMD(projectPath, content string) error {
+	return project.NewWorkspace(projectPath).WriteClaudeMD(content)
+}
+
 // =============================================================================
-// Git Methods
+// Project Memory Methods
 // =============================================================================
 
-// GitStatus represents git status for a project
-type GitStatus struct {
-	IsRepo    bool            `json:"isRepo"`
-	Branch    string          `json:"branch"`
-	Modified  []string        `json:"modified"`
-	Added     []string        `json:"added"`
-	Deleted   []string        `json:"deleted"`
-	Untracked []string        `json:"untracked"`
+// GetProjectMemory returns the notes shared by every session of a project
+func (a *App) GetProjectMemory(projectPath string) (string, error) {
+	return a.memory.Load(projectPath)
 }
 
-// GetGitStatus returns git status for a project
-func (a *App) GetGitStatus(projectPath string) (*GitStatus, error) {
-	repo := gitpkg.NewRepository(projectPath)
+// SetProjectMemory replaces a project's notes; empty notes clear them
+func (a *App) SetProjectMemory(projectPath, notes string) error {
+	return a.memory.Save(projectPath, notes)
+}
 
-	if !repo.IsGitRepo() {
-		return &GitStatus{IsRepo: false}, nil
-	}
+// AppendProjectMemory adds a note to the end of a project's notes
+func (a *App) AppendProjectMemory(projectPath, note string) error {
+	return a.memory.Append(projectPath, note)
+}
 
-	branch, err := repo.GetCurrentBranch()
+// resolveProjectMemory returns a project's notes for the system prompt and
+// the MCP server whose tool lets the model add to them
+func (a *App) resolveProjectMemory(projectPath string) agent.ProjectMemory {
+	notes, err := a.memory.Load(projectPath)
 	if err != nil {
-		branch = "unknown"
+		logger.Warn("Failed to load project memory", "project", projectPath, "error", err)
 	}
+	resolved := agent.ProjectMemory{Prompt: memory.Prompt(notes)}
 
-	status, err := repo.GetStatus()
+	// Sessions run this binary as the memory server
+	executable, err := os.Executable()
 	if err != nil {
-		return nil, err
+		logger.Warn("Project memory tool unavailable", "error", err)
+		return resolved
 	}
+	mcpConfig, err := memory.MCPConfig(executable, a.memory.Dir(), projectPath)
+	if err != nil {
+		logger.Warn("Project memory tool unavailable", "error", err)
+		return resolved
+	}
+	resolved.MCPConfig = mcpConfig
+	resolved.Tool = memory.QualifiedToolName
+	return resolved
+}
 
-	return &GitStatus{
-		IsRepo:    true,
+// =============================================================================
+// Git Methods
+// =============================================================================
+
+// GitStatus represents git status for a project
+type GitStatus struct {
+	IsRepo    bool     `json:"isRepo"`
+	Branch    string   `json:"branch"`
+	Modified  []string `json:"modified"`
+	Added     []string `json:"added"`
+	Deleted   []string `json:"deleted"`
+	Untracked []string `json:"untracked"`
+	// Commits not yet pushed or pulled, as of the last fetch
+	Upstream string `json:"upstream,omitempty"`
+	Ahead    int    `json:"ahead"`
+	Behind   int    `json:"behind"`
+	// Path is the root this status is for, in a multi-root session's Roots
+	Path string `json:"path,omitempty"`
+	// Roots has the status of each root of a multi-root session, whose
+	// changes the lists above combine
+	Roots []*GitStatus `json:"roots,omitempty"`
+}
+
+// GetGitStatus returns git status for a project
+func (a *App) GetGitStatus(projectPath string) (*GitStatus, error) {
+	return gitStatusFor(projectPath)
+}
+
+// GetSessionGitStatus returns git status across every root a session spans.
+// The branch and tracking information are the project's; the changed files
+// of the other roots are listed by absolute path.
+func (a *App) GetSessionGitStatus(sessionID string) (*GitStatus, error) {
+	session, err := a.agentManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	roots := session.Roots()
+	combined, err := gitStatusFor(roots[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(roots) == 1 {
+		return combined, nil
+	}
+
+	primary := *combined
+	primary.Path = roots[0]
+	combined.Roots = []*GitStatus{&primary}
+	for _, root := range roots[1:] {
+		status, err := gitStatusFor(root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get git status for %s: %w", root, err)
+		}
+		status.Path = root
+		combined.Roots = append(combined.Roots, status)
+
+		combined.IsRepo = combined.IsRepo || status.IsRepo
+		combined.Modified = appendRootFiles(combined.Modified, root, status.Modified)
+		combined.Added = appendRootFiles(combined.Added, root, status.Added)
+		combined.Deleted = appendRootFiles(combined.Deleted, root, status.Deleted)
+		combined.Untracked = appendRootFiles(combined.Untracked, root, status.Untracked)
+	}
+	return combined, nil
+}
+
+// appendRootFiles adds a root's changed files to list by absolute path
+func appendRootFiles(list []string, root string, files []string) []string {
+	// Copied so the project's own status keeps its lists
+	list = append([]string(nil), list...)
+	for _, file := range files {
+		list = append(list, filepath.Join(root, file))
+	}
+	return list
+}
+
+// gitStatusFor returns the git status of one directory
+func gitStatusFor(projectPath string) (*GitStatus, error) {
+	repo := gitpkg.NewRepository(projectPath)
+
+	if !repo.IsGitRepo() {
+		return &GitStatus{IsRepo: false}, nil
+	}
+
+	branch, err := repo.GetCurrentBranch()
+	if err != nil {
+		branch = "unknown"
+	}
+
+	status, err := repo.GetStatus()
+	if err != nil {
+		return nil, err
+	}
+
+	gitStatus := &GitStatus{
+		IsRepo:    true,
 		Branch:    branch,
 		Modified:  status.Modified,
 		Added:     status.Added,
 		Deleted:   status.Deleted,
 		Untracked: status.Untracked,
-	}, nil
+	}
+	if tracking, err := repo.GetAheadBehind(""); err == nil {
+		gitStatus.Upstream = tracking.Upstream
+		gitStatus.Ahead = tracking.Ahead
+		gitStatus.Behind = tracking.Behind
+	}
+	return gitStatus, nil
 }
 
 // GetGitDiff returns diff for a file
@@ -374,6 +1090,226 @@ func (a *App) GetGitDiff(projectPath, filePath string) (string, error) {
 	return repo.GetDiff(filePath)
 }
 
+// StageGitFiles stages files in a project; no paths stages every change
+func (a *App) StageGitFiles(projectPath string, paths []string) error {
+	repo := gitpkg.NewRepository(projectPath)
+	return repo.Stage(paths...)
+}
+
+// UnstageGitFiles unstages files in a project; no paths unstages everything
+func (a *App) UnstageGitFiles(projectPath string, paths []string) error {
+	repo := gitpkg.NewRepository(projectPath)
+	return repo.Unstage(paths...)
+}
+
+// CommitGitChanges commits the staged changes of a project and returns the new commit
+func (a *App) CommitGitChanges(projectPath, message string) (*gitpkg.Commit, error) {
+	repo := gitpkg.NewRepository(projectPath)
+	if err := repo.Commit(message); err != nil {
+		return nil, err
+	}
+
+	commits, err := repo.Log(1)
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("commit not found after committing")
+	}
+	return &commits[0], nil
+}
+
+// GenerateCommitMessage drafts a conventional commit message for a project's
+// staged changes, for the user to accept or edit before committing
+func (a *App) GenerateCommitMessage(projectPath string) (string, error) {
+	repo := gitpkg.NewRepository(projectPath)
+	stagedDiff, err := repo.GetStagedDiff()
+	if err != nil {
+		return "", fmt.Errorf("failed to read staged changes: %w", err)
+	}
+	return a.agentManager.GenerateCommitMessage(a.ctx, projectPath, stagedDiff)
+}
+
+// CreateGitBranch creates a branch at startPoint (HEAD if empty), switching to it if checkout is set
+func (a *App) CreateGitBranch(projectPath, name, startPoint string, checkout bool) error {
+	repo := gitpkg.NewRepository(projectPath)
+	if err := repo.CreateBranch(name, startPoint); err != nil {
+		return err
+	}
+	if checkout {
+		return repo.Checkout(name)
+	}
+	return nil
+}
+
+// CheckoutGitRef switches a project to a branch or commit
+func (a *App) CheckoutGitRef(projectPath, ref string) error {
+	repo := gitpkg.NewRepository(projectPath)
+	return repo.Checkout(ref)
+}
+
+// GetGitLog returns a project's latest commits, newest first
+func (a *App) GetGitLog(projectPath string, limit int) ([]gitpkg.Commit, error) {
+	repo := gitpkg.NewRepository(projectPath)
+	return repo.Log(limit)
+}
+
+// FetchGitRemote fetches a project's remote; empty uses the default remote
+func (a *App) FetchGitRemote(projectPath, remote string) error {
+	repo := gitpkg.NewRepository(projectPath)
+	return repo.Fetch(remote)
+}
+
+// PullGitBranch fast-forwards a project's current branch; empty arguments pull its upstream
+func (a *App) PullGitBranch(projectPath, remote, branch string) error {
+	repo := gitpkg.NewRepository(projectPath)
+	return repo.Pull(remote, branch)
+}
+
+// PushGitBranch pushes a branch, the current one if empty, to a remote, origin if empty
+func (a *App) PushGitBranch(projectPath, remote, branch string) error {
+	repo := gitpkg.NewRepository(projectPath)
+	return repo.Push(remote, branch)
+}
+
+// GetGitAheadBehind compares a project's current branch with a remote; empty uses its upstream
+func (a *App) GetGitAheadBehind(projectPath, remote string) (*gitpkg.AheadBehind, error) {
+	repo := gitpkg.NewRepository(projectPath)
+	return repo.GetAheadBehind(remote)
+}
+
+// CreatePullRequest pushes a project's current branch to origin and opens a
+// pull request (a merge request on GitLab) into baseBranch, the repository's
+// default branch if empty
+func (a *App) CreatePullRequest(projectPath, title, body, baseBranch string) (*vcs.PullRequest, error) {
+	if strings.TrimSpace(title) == "" {
+		return nil, fmt.Errorf("a title is required")
+	}
+
+	repo := gitpkg.NewRepository(projectPath)
+	branch, err := repo.GetCurrentBranch()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read current branch: %w", err)
+	}
+	if baseBranch == "" {
+		if baseBranch, err = repo.GetDefaultBranch(); err != nil {
+			return nil, fmt.Errorf("failed to find the default branch: %w", err)
+		}
+	}
+	if branch == baseBranch {
+		return nil, fmt.Errorf("create a branch for the changes first; %s is the base branch", branch)
+	}
+
+	remoteURL, err := repo.GetRemoteURL("origin")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the origin remote: %w", err)
+	}
+	remote, err := gitpkg.ParseRemoteURL(remoteURL)
+	if err != nil {
+		return nil, err
+	}
+	prefs := a.config.GetPreferences()
+	provider, err := vcs.NewProvider(remote, vcs.Tokens{
+		GitHub: prefs.GitHubToken,
+		GitLab: prefs.GitLabToken,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repo.Push("origin", branch); err != nil {
+		return nil, err
+	}
+	return provider.CreatePullRequest(a.ctx, vcs.PullRequestOptions{
+		Title: title,
+		Body:  body,
+		Head:  branch,
+		Base:  baseBranch,
+	})
+}
+
+// GetGitRefDiff returns the parsed changes on head since it branched from base.
+// An empty head compares base against the working tree.
+func (a *App) GetGitRefDiff(projectPath, base, head string, paths []string) ([]diff.FileDiff, error) {
+	repo := gitpkg.NewRepository(projectPath)
+	return repo.DiffRefs(base, head, paths...)
+}
+
+// CherryPickCommit applies a commit onto the current branch of a project
+func (a *App) CherryPickCommit(projectPath, hash string) (*gitpkg.ApplyResult, error) {
+	repo := gitpkg.NewRepository(projectPath)
+	return repo.CherryPick(hash)
+}
+
+// RevertGitCommit creates a commit undoing the given commit
+func (a *App) RevertGitCommit(projectPath, hash string) (*gitpkg.ApplyResult, error) {
+	repo := gitpkg.NewRepository(projectPath)
+	return repo.RevertCommit(hash)
+}
+
+// GetGitConflictVersions returns base/ours/theirs contents of a conflicted file
+func (a *App) GetGitConflictVersions(projectPath, filePath string) (*gitpkg.ConflictVersions, error) {
+	repo := gitpkg.NewRepository(projectPath)
+	return repo.GetConflictVersions(filePath)
+}
+
+// AbortGitApply aborts an in-progress cherry-pick or revert
+func (a *App) AbortGitApply(projectPath, operation string) error {
+	repo := gitpkg.NewRepository(projectPath)
+	if operation == "revert" {
+		return repo.AbortRevert()
+	}
+	return repo.AbortCherryPick()
+}
+
+// ContinueGitApply completes a cherry-pick or revert after conflicts are resolved
+func (a *App) ContinueGitApply(projectPath, operation string) error {
+	repo := gitpkg.NewRepository(projectPath)
+	if operation == "revert" {
+		return repo.ContinueRevert()
+	}
+	return repo.ContinueCherryPick()
+}
+
+// StartGitBisect begins a bisect between a bad and a good commit
+func (a *App) StartGitBisect(projectPath, bad, good string) (*gitpkg.BisectStatus, error) {
+	repo := gitpkg.NewRepository(projectPath)
+	return repo.BisectStart(bad, good)
+}
+
+// MarkGitBisect marks the current bisect commit as "good", "bad", or "skip"
+func (a *App) MarkGitBisect(projectPath, verdict string) (*gitpkg.BisectStatus, error) {
+	repo := gitpkg.NewRepository(projectPath)
+	switch verdict {
+	case "good":
+		return repo.BisectMarkGood()
+	case "bad":
+		return repo.BisectMarkBad()
+	case "skip":
+		return repo.BisectSkip()
+	default:
+		return nil, fmt.Errorf("invalid bisect verdict: %s", verdict)
+	}
+}
+
+// RunGitBisect drives the bisect automatically using a project test command
+func (a *App) RunGitBisect(projectPath, command string) (*gitpkg.BisectStatus, error) {
+	repo := gitpkg.NewRepository(projectPath)
+	return repo.BisectRun(command)
+}
+
+// GetGitBisectStatus returns the state of the current bisect
+func (a *App) GetGitBisectStatus(projectPath string) (*gitpkg.BisectStatus, error) {
+	repo := gitpkg.NewRepository(projectPath)
+	return repo.GetBisectStatus()
+}
+
+// ResetGitBisect ends the current bisect
+func (a *App) ResetGitBisect(projectPath string) error {
+	repo := gitpkg.NewRepository(projectPath)
+	return repo.BisectReset()
+}
+
 // =============================================================================
 // Diff Methods
 // =============================================================================
@@ -383,11 +1319,46 @@ func (a *App) ParseDiff(diffText string) ([]diff.FileDiff, error) {
 	return diff.ParseUnifiedDiff(diffText)
 }
 
+// ParseDiffWithOptions parses a unified diff string, ignoring or marking whitespace-only changes
+func (a *App) ParseDiffWithOptions(diffText string, opts diff.Options) ([]diff.FileDiff, error) {
+	return diff.ParseUnifiedDiffWithOptions(diffText, opts)
+}
+
+// GenerateDiff diffs two versions of a file without git, such as the before and after of an agent edit
+func (a *App) GenerateDiff(oldContent, newContent, path string) diff.FileDiff {
+	return diff.Generate(oldContent, newContent, path)
+}
+
+// GenerateDiffWithOptions diffs two versions of a file, ignoring or marking whitespace-only changes
+func (a *App) GenerateDiffWithOptions(oldContent, newContent, path string, opts diff.Options) diff.FileDiff {
+	return diff.GenerateWithOptions(oldContent, newContent, path, opts)
+}
+
+// GenerateUnifiedDiff diffs two versions of a file and returns the unified diff text
+func (a *App) GenerateUnifiedDiff(oldContent, newContent, path string) string {
+	return diff.GenerateUnified(oldContent, newContent, path)
+}
+
 // GetSideBySideDiff generates side-by-side diff
 func (a *App) GetSideBySideDiff(fileDiff diff.FileDiff) []diff.SideBySideLine {
 	return diff.GenerateSideBySide(fileDiff)
 }
 
+// GetSideBySideDiffWithOptions generates side-by-side diff, ignoring or marking whitespace-only changes
+func (a *App) GetSideBySideDiffWithOptions(fileDiff diff.FileDiff, opts diff.Options) []diff.SideBySideLine {
+	return diff.GenerateSideBySideWithOptions(fileDiff, opts)
+}
+
+// RenderDiffHTML renders diffs as a self-contained HTML fragment for sharing and reports
+func (a *App) RenderDiffHTML(diffs []diff.FileDiff, opts diff.HTMLOptions) string {
+	return diff.RenderHTML(diffs, opts)
+}
+
+// ExportDiffHunks serializes the selected hunks of a file diff as a patch
+func (a *App) ExportDiffHunks(fileDiff diff.FileDiff, hunkIndices []int) (string, error) {
+	return diff.ExportHunks(fileDiff, hunkIndices)
+}
+
 // =============================================================================
 // MCP Methods
 // =============================================================================
@@ -412,11 +1383,259 @@ func (a *App) UpdateMCPServer(server mcp.Server) error {
 	return a.mcpManager.UpdateServer(server)
 }
 
+// FindDuplicateMCPServer returns the name of an existing server that runs the same
+// command and args as server, or "" if there is none
+func (a *App) FindDuplicateMCPServer(server mcp.Server) (string, error) {
+	name, _, err := a.mcpManager.FindDuplicate(server)
+	return name, err
+}
+
+// MergeMCPServer merges server into an existing duplicate entry
+func (a *App) MergeMCPServer(existing string, server mcp.Server) error {
+	return a.mcpManager.MergeServer(existing, server)
+}
+
+// RenameMCPServer renames an MCP server
+func (a *App) RenameMCPServer(oldName, newName string) error {
+	return a.mcpManager.RenameServer(oldName, newName)
+}
+
+// FindDuplicateMCPServers returns groups of servers that share the same command and args
+func (a *App) FindDuplicateMCPServers() ([]mcp.DuplicateGroup, error) {
+	return a.mcpManager.FindDuplicates()
+}
+
+// DedupeMCPServers collapses duplicate MCP servers and returns the merged groups
+func (a *App) DedupeMCPServers() ([]mcp.DuplicateGroup, error) {
+	return a.mcpManager.Dedupe()
+}
+
+// ImportMCPFromClaudeDesktop adds the MCP servers configured in Claude Desktop
+// that aren't configured here yet
+func (a *App) ImportMCPFromClaudeDesktop() (*mcp.SyncResult, error) {
+	return a.mcpManager.ImportFromClaudeDesktop()
+}
+
+// ExportMCPToClaudeDesktop adds the MCP servers configured here to Claude Desktop
+func (a *App) ExportMCPToClaudeDesktop() (*mcp.SyncResult, error) {
+	return a.mcpManager.ExportToClaudeDesktop()
+}
+
 // GetMCPPresets returns preset MCP servers
 func (a *App) GetMCPPresets() []mcp.Server {
 	return mcp.GetPresetServers()
 }
 
+// InstantiateMCPPreset validates the env values for a preset and adds it to the MCP config
+func (a *App) InstantiateMCPPreset(name string, envValues map[string]string) (mcp.Server, error) {
+	return a.mcpManager.InstantiatePreset(name, envValues)
+}
+
+// CallMCPTool runs a tool on a configured MCP server without an agent turn, e.g.
+// to check a server's credentials. argsJSON is a JSON object of tool arguments.
+func (a *App) CallMCPTool(serverName, toolName, argsJSON string) (*mcp.ToolResult, error) {
+	var args map[string]interface{}
+	if strings.TrimSpace(argsJSON) != "" {
+		if err := json.Unmarshal([]byte(argsJSON), &args); err != nil {
+			return nil, fmt.Errorf("tool arguments must be a JSON object: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(a.ctx, mcp.DefaultCallTimeout)
+	defer cancel()
+	return a.mcpManager.CallTool(ctx, serverName, toolName, args)
+}
+
+// ListMCPServerTools returns the tools an MCP server exposes, launching it if
+// needed, so they can be inspected before the server is enabled for sessions
+func (a *App) ListMCPServerTools(serverName string) ([]mcp.Tool, error) {
+	ctx, cancel := context.WithTimeout(a.ctx, mcp.DefaultCallTimeout)
+	defer cancel()
+	return a.mcpManager.ListServerTools(ctx, serverName)
+}
+
+// StartMCPServer launches a configured MCP server and keeps it running,
+// restarting it if it crashes or stops answering
+func (a *App) StartMCPServer(name string) error {
+	return a.mcpManager.StartServer(name)
+}
+
+// StopMCPServer stops an MCP server started with StartMCPServer
+func (a *App) StopMCPServer(name string) error {
+	return a.mcpManager.StopServer(name)
+}
+
+// GetMCPServerStatus returns whether an MCP server is running, restarting or has failed
+func (a *App) GetMCPServerStatus(name string) mcp.ServerStatus {
+	return a.mcpManager.GetServerStatus(name)
+}
+
+// GetProjectMCPServers returns the MCP servers enabled for a project (nil when all are enabled)
+func (a *App) GetProjectMCPServers(projectPath string) []string {
+	servers, _ := a.config.GetProjectMCPServers(projectPath)
+	return servers
+}
+
+// SetProjectMCPServers sets the MCP servers enabled for a project (nil enables all)
+func (a *App) SetProjectMCPServers(projectPath string, servers []string) error {
+	return a.config.SetProjectMCPServers(projectPath, servers)
+}
+
+// GetSessionMCPServers returns the MCP servers chosen for a session (nil when it uses the project's)
+func (a *App) GetSessionMCPServers(sessionID string) ([]string, error) {
+	session, err := a.agentManager.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return session.GetEnabledMCPServers(), nil
+}
+
+// SetSessionMCPServers sets the MCP servers for a session's runs (nil uses the project's)
+func (a *App) SetSessionMCPServers(sessionID string, servers []string) error {
+	return a.agentManager.SetSessionMCPServers(sessionID, servers)
+}
+
+// GetProjectSystemPrompt returns the system prompt addition for a project
+func (a *App) GetProjectSystemPrompt(projectPath string) string {
+	return a.config.GetProjectSystemPrompt(projectPath)
+}
+
+// SetProjectSystemPrompt sets the system prompt addition for a project
+func (a *App) SetProjectSystemPrompt(projectPath, prompt string) error {
+	return a.config.SetProjectSystemPrompt(projectPath, prompt)
+}
+
+// SetSessionSystemPrompt sets the system prompt addition a session uses
+// instead of the user's and project's. Empty goes back to theirs.
+func (a *App) SetSessionSystemPrompt(sessionID, prompt string) error {
+	return a.agentManager.SetSessionSystemPrompt(sessionID, prompt)
+}
+
+// GetProjectDefaults returns the model and approval mode a project's sessions use
+func (a *App) GetProjectDefaults(projectPath string) agent.ProjectDefaults {
+	model, mode := a.config.GetProjectDefaults(projectPath)
+	return agent.ProjectDefaults{Model: model, ApprovalMode: string(mode)}
+}
+
+// SetProjectDefaults sets the model and approval mode a project's sessions
+// use unless they choose their own. Empty fields use the user's.
+func (a *App) SetProjectDefaults(projectPath string, defaults agent.ProjectDefaults) error {
+	if err := agent.ValidateApprovalMode(defaults.ApprovalMode); err != nil {
+		return err
+	}
+	return a.config.SetProjectDefaults(projectPath, strings.TrimSpace(defaults.Model), config.ApprovalMode(defaults.ApprovalMode))
+}
+
+// SetSessionModel sets the model a session runs instead of its project's.
+// Empty goes back to the project's.
+func (a *App) SetSessionModel(sessionID, model string) error {
+	return a.agentManager.SetSessionModel(sessionID, model)
+}
+
+// SetSessionApprovalMode sets the approval mode a session runs in instead of
+// its project's. Empty goes back to the project's.
+func (a *App) SetSessionApprovalMode(sessionID, mode string) error {
+	return a.agentManager.SetSessionApprovalMode(sessionID, mode)
+}
+
+// GetProjectToolPolicy returns the tools allowed and denied in a project's sessions
+func (a *App) GetProjectToolPolicy(projectPath string) agent.ToolPolicy {
+	allowed, denied := a.config.GetProjectToolPolicy(projectPath)
+	return agent.ToolPolicy{Allowed: allowed, Denied: denied}
+}
+
+// SetProjectToolPolicy sets the tools allowed and denied in a project's
+// sessions, e.g. denying "Bash"
+func (a *App) SetProjectToolPolicy(projectPath string, policy agent.ToolPolicy) error {
+	policy, err := agent.NormalizeToolPolicy(policy)
+	if err != nil {
+		return err
+	}
+	return a.config.SetProjectToolPolicy(projectPath, policy.Allowed, policy.Denied)
+}
+
+// SetSessionToolPolicy sets the tools allowed and denied in a session on top
+// of its project's, e.g. turning off Bash for one session
+func (a *App) SetSessionToolPolicy(sessionID string, policy agent.ToolPolicy) error {
+	return a.agentManager.SetSessionToolPolicy(sessionID, policy)
+}
+
+// GetProjectMonitoringRules returns the firefighter monitoring rules for a
+// project, or nil if it has none
+func (a *App) GetProjectMonitoringRules(projectPath string) *config.MonitoringRules {
+	return a.config.GetProjectMonitoringRules(projectPath)
+}
+
+// SetProjectMonitoringRules sets the firefighter monitoring rules for a
+// project. They apply the next time monitoring starts.
+func (a *App) SetProjectMonitoringRules(projectPath string, rules *config.MonitoringRules) error {
+	if rules != nil {
+		if rules.PollIntervalSeconds < 0 {
+			return fmt.Errorf("poll interval can't be negative")
+		}
+		for _, rule := range rules.Rules {
+			if rule.CooldownSeconds < 0 {
+				return fmt.Errorf("rule %q: cooldown can't be negative", rule.Name)
+			}
+			if err := agent.ValidateMonitorRule(monitorRule(rule)); err != nil {
+				return err
+			}
+		}
+	}
+	return a.config.SetProjectMonitoringRules(projectPath, rules)
+}
+
+// resolveMCPConfig writes a filtered MCP config for sessions or projects that
+// restrict their servers and returns its path. A session's own selection wins
+// over its project's. It returns "" when every server is used.
+func (a *App) resolveMCPConfig(projectPath string, sessionServers []string) (string, error) {
+	servers, restricted := sessionServers, sessionServers != nil
+	if !restricted {
+		servers, restricted = a.config.GetProjectMCPServers(projectPath)
+	}
+	if !restricted {
+		return "", nil
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	// One file per set of servers, so sessions of a project can differ
+	sorted := append([]string(nil), servers...)
+	sort.Strings(sorted)
+	sum := sha256.Sum256([]byte(strings.Join(sorted, "\x00")))
+	path := filepath.Join(homeDir, ".boatman", "mcp", hex.EncodeToString(sum[:8])+".json")
+	if err := a.mcpManager.WriteConfigSubset(servers, path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// toolFormattersDir is where user-defined tool formatter files live
+func toolFormattersDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".boatman", "tool-formatters"), nil
+}
+
+// GetToolFormatters returns the loaded tool formatters
+func (a *App) GetToolFormatters() []agent.ToolFormatter {
+	return a.agentManager.ToolFormatters().List()
+}
+
+// ReloadToolFormatters reloads tool formatters from ~/.boatman/tool-formatters
+func (a *App) ReloadToolFormatters() (int, error) {
+	dir, err := toolFormattersDir()
+	if err != nil {
+		return 0, err
+	}
+	return a.agentManager.ToolFormatters().LoadDir(dir)
+}
+
 // =============================================================================
 // Config Getter Implementation (for agent.ConfigGetter interface)
 // =============================================================================
@@ -472,29 +1691,181 @@ func (a *App) GetKeepCompletedAgents() bool {
 	return a.config.GetPreferences().KeepCompletedAgents
 }
 
+// GetMaxCommandRuntimeSeconds returns the max runtime for a single Bash command
+func (a *App) GetMaxCommandRuntimeSeconds() int {
+	prefs := a.config.GetPreferences()
+	if prefs.MaxCommandRuntimeSeconds < 0 {
+		return 0 // Disabled
+	}
+	if prefs.MaxCommandRuntimeSeconds == 0 {
+		return 600 // Default
+	}
+	return prefs.MaxCommandRuntimeSeconds
+}
+
 // =============================================================================
 // Session Cleanup Methods
 // =============================================================================
 
-// CleanupOldSessions manually triggers session cleanup
-func (a *App) CleanupOldSessions() (int, error) {
-	maxAgeDays := a.GetMaxSessionAgeDays()
-	maxTotal := a.GetMaxTotalSessions()
-	return agent.CleanupOldSessions(maxAgeDays, maxTotal)
+// CleanupOldSessions manually triggers session cleanup
+func (a *App) CleanupOldSessions() (int, error) {
+	maxAgeDays := a.GetMaxSessionAgeDays()
+	maxTotal := a.GetMaxTotalSessions()
+	return agent.CleanupOldSessions(maxAgeDays, maxTotal)
+}
+
+// GetSessionStats returns statistics about all sessions
+func (a *App) GetSessionStats() (map[string]interface{}, error) {
+	stats, err := agent.GetSessionStats()
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"total":      stats.Total,
+		"oldestDate": stats.OldestDate.Format("2006-01-02T15:04:05Z07:00"),
+		"newestDate": stats.NewestDate.Format("2006-01-02T15:04:05Z07:00"),
+	}, nil
+}
+
+// =============================================================================
+// Session Encryption Methods
+// =============================================================================
+
+// Session encryption modes
+const (
+	sessionEncryptionKeychain   = "keychain"
+	sessionEncryptionPassphrase = "passphrase"
+)
+
+// minPassphraseLength is the shortest passphrase sessions can be encrypted with
+const minPassphraseLength = 8
+
+// SessionEncryptionStatus reports how saved sessions are encrypted
+type SessionEncryptionStatus struct {
+	Mode   string `json:"mode"`   // "keychain", "passphrase", or "" when off
+	Locked bool   `json:"locked"` // Encrypted with a passphrase not entered yet
+}
+
+// applySessionEncryption sets up the session key for the saved encryption
+// mode. The keychain key is always loaded when there is one, so sessions
+// sealed with it still open after switching to another mode.
+func (a *App) applySessionEncryption() {
+	mode := a.config.GetPreferences().SessionEncryption
+
+	key, err := a.config.KeychainSessionKey(mode == sessionEncryptionKeychain)
+	if err != nil {
+		logger.Warn("Failed to read the session key from the keychain", "error", err)
+	}
+	if key != nil {
+		if err := agent.SetEncryption(key, mode == sessionEncryptionKeychain); err != nil {
+			logger.Warn("Invalid session key in the keychain", "error", err)
+		}
+	}
+
+	switch mode {
+	case sessionEncryptionKeychain:
+		if key == nil {
+			// Lock saving rather than write sessions in plaintext
+			agent.SetEncryption(nil, true)
+		}
+	case sessionEncryptionPassphrase:
+		agent.SetEncryption(nil, true)
+	}
+}
+
+// GetSessionEncryption returns how saved sessions are encrypted
+func (a *App) GetSessionEncryption() SessionEncryptionStatus {
+	return SessionEncryptionStatus{
+		Mode:   a.config.GetPreferences().SessionEncryption,
+		Locked: agent.EncryptionLocked(),
+	}
+}
+
+// SetSessionEncryption changes how saved sessions and archives are encrypted
+// and re-encrypts everything already saved. mode is "keychain" for a key kept
+// in the OS keychain, "passphrase" for a key derived from passphrase, or ""
+// to turn encryption off.
+func (a *App) SetSessionEncryption(mode, passphrase string) error {
+	if agent.EncryptionLocked() {
+		return fmt.Errorf("unlock sessions before changing their encryption")
+	}
+
+	prefs := a.config.GetPreferences()
+	prefs.SessionKeySalt = ""
+	prefs.SessionKeyCheck = ""
+
+	var key []byte
+	var err error
+	switch mode {
+	case "":
+	case sessionEncryptionKeychain:
+		if key, err = a.config.KeychainSessionKey(true); err != nil {
+			return fmt.Errorf("failed to get the session key: %w", err)
+		}
+	case sessionEncryptionPassphrase:
+		if len(passphrase) < minPassphraseLength {
+			return fmt.Errorf("passphrase must be at least %d characters", minPassphraseLength)
+		}
+		salt := make([]byte, 16)
+		if _, err := rand.Read(salt); err != nil {
+			return fmt.Errorf("failed to generate salt: %w", err)
+		}
+		if key, err = agent.KeyFromPassphrase(passphrase, salt); err != nil {
+			return err
+		}
+		check, err := agent.NewKeyCheck(key)
+		if err != nil {
+			return err
+		}
+		prefs.SessionKeySalt = base64.StdEncoding.EncodeToString(salt)
+		prefs.SessionKeyCheck = check
+	default:
+		return fmt.Errorf("unknown session encryption %q", mode)
+	}
+
+	// Write pending changes under the old setting before resealing them all.
+	// The new setting is only saved once everything is resealed, and a
+	// failure either way puts the old one back.
+	a.agentManager.FlushSaves()
+	prefs.SessionEncryption = mode
+	count, err := agent.ChangeEncryption(key, mode != "", func() error {
+		return a.config.SetPreferences(prefs)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to change session encryption: %w", err)
+	}
+	logger.Info("Re-encrypted saved sessions", "mode", mode, "count", count)
+
+	// Move the search index to or from memory to match
+	return a.openSearchIndex()
 }
 
-// GetSessionStats returns statistics about all sessions
-func (a *App) GetSessionStats() (map[string]interface{}, error) {
-	stats, err := agent.GetSessionStats()
+// UnlockSessions enters the passphrase sessions are encrypted with, then
+// restores them
+func (a *App) UnlockSessions(passphrase string) (int, error) {
+	prefs := a.config.GetPreferences()
+	if prefs.SessionEncryption != sessionEncryptionPassphrase {
+		return 0, fmt.Errorf("sessions aren't encrypted with a passphrase")
+	}
+	salt, err := base64.StdEncoding.DecodeString(prefs.SessionKeySalt)
 	if err != nil {
-		return nil, err
+		return 0, fmt.Errorf("invalid session key salt: %w", err)
 	}
-
-	return map[string]interface{}{
-		"total":      stats.Total,
-		"oldestDate": stats.OldestDate.Format("2006-01-02T15:04:05Z07:00"),
-		"newestDate": stats.NewestDate.Format("2006-01-02T15:04:05Z07:00"),
-	}, nil
+	key, err := agent.KeyFromPassphrase(passphrase, salt)
+	if err != nil {
+		return 0, err
+	}
+	if !agent.VerifyKeyCheck(key, prefs.SessionKeyCheck) {
+		return 0, fmt.Errorf("wrong passphrase")
+	}
+	if err := agent.SetEncryption(key, true); err != nil {
+		return 0, err
+	}
+	if index := agent.ActiveSearchIndex(); index != nil {
+		a.fillSearchIndex(index)
+	}
+	return a.agentManager.RestoreSessions()
 }
 
 // =============================================================================
@@ -509,6 +1880,15 @@ type SearchSessionsRequest struct {
 	IsFavorite  *bool    `json:"isFavorite"`
 	FromDate    string   `json:"fromDate"`
 	ToDate      string   `json:"toDate"`
+
+	// Content searches message bodies, tool calls and tool results,
+	// including archived messages
+	Content      string   `json:"content,omitempty"`
+	Roles        []string `json:"roles,omitempty"`
+	HasToolError bool     `json:"hasToolError,omitempty"`
+
+	// UpdatedWithinDays limits results to sessions updated in the last days
+	UpdatedWithinDays int `json:"updatedWithinDays,omitempty"`
 }
 
 // SearchSessionsResponse represents a search response
@@ -545,6 +1925,13 @@ func (a *App) SearchSessions(req SearchSessionsRequest) ([]SearchSessionsRespons
 		}
 	}
 
+	if req.UpdatedWithinDays > 0 {
+		since := time.Now().AddDate(0, 0, -req.UpdatedWithinDays)
+		if since.After(fromDate) {
+			fromDate = since
+		}
+	}
+
 	// Create filter
 	filter := agent.SearchFilter{
 		Query:       req.Query,
@@ -553,6 +1940,10 @@ func (a *App) SearchSessions(req SearchSessionsRequest) ([]SearchSessionsRespons
 		IsFavorite:  req.IsFavorite,
 		FromDate:    fromDate,
 		ToDate:      toDate,
+
+		Content:      req.Content,
+		Roles:        req.Roles,
+		HasToolError: req.HasToolError,
 	}
 
 	// Perform search
@@ -581,6 +1972,66 @@ func (a *App) SearchSessions(req SearchSessionsRequest) ([]SearchSessionsRespons
 	return response, nil
 }
 
+// GetSavedSearches returns the saved session searches
+func (a *App) GetSavedSearches() []config.SavedSearch {
+	return a.config.GetSavedSearches()
+}
+
+// GetSessionViews returns the saved searches pinned to the sidebar
+func (a *App) GetSessionViews() []config.SavedSearch {
+	var views []config.SavedSearch
+	for _, search := range a.config.GetSavedSearches() {
+		if search.Pinned {
+			views = append(views, search)
+		}
+	}
+	return views
+}
+
+// SaveSearch saves a named search, adding it when it has no ID
+func (a *App) SaveSearch(search config.SavedSearch) (config.SavedSearch, error) {
+	for _, date := range []string{search.Filter.FromDate, search.Filter.ToDate} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return config.SavedSearch{}, fmt.Errorf("invalid date %q: %w", date, err)
+		}
+	}
+	return a.config.SaveSearch(search)
+}
+
+// DeleteSavedSearch removes a saved search
+func (a *App) DeleteSavedSearch(id string) error {
+	return a.config.DeleteSavedSearch(id)
+}
+
+// SetSavedSearchPinned pins a saved search to the sidebar as a view, or unpins it
+func (a *App) SetSavedSearchPinned(id string, pinned bool) error {
+	return a.config.SetSavedSearchPinned(id, pinned)
+}
+
+// RunSavedSearch returns the sessions a saved search currently matches
+func (a *App) RunSavedSearch(id string) ([]SearchSessionsResponse, error) {
+	search, err := a.config.GetSavedSearch(id)
+	if err != nil {
+		return nil, err
+	}
+	filter := search.Filter
+	return a.SearchSessions(SearchSessionsRequest{
+		Query:             filter.Query,
+		Tags:              filter.Tags,
+		ProjectPath:       filter.ProjectPath,
+		IsFavorite:        filter.IsFavorite,
+		FromDate:          filter.FromDate,
+		ToDate:            filter.ToDate,
+		Content:           filter.Content,
+		Roles:             filter.Roles,
+		HasToolError:      filter.HasToolError,
+		UpdatedWithinDays: filter.UpdatedWithinDays,
+	})
+}
+
 // AddSessionTag adds a tag to a session
 func (a *App) AddSessionTag(sessionID, tag string) error {
 	return a.agentManager.AddTag(sessionID, tag)
@@ -596,22 +2047,235 @@ func (a *App) SetSessionFavorite(sessionID string, favorite bool) error {
 	return a.agentManager.SetFavorite(sessionID, favorite)
 }
 
+// SetSessionSnapshotBeforeRun sets whether a session snapshots its project before full-auto runs
+func (a *App) SetSessionSnapshotBeforeRun(sessionID string, enabled bool) error {
+	return a.agentManager.SetSnapshotBeforeRun(sessionID, enabled)
+}
+
+// RestoreSessionSnapshot rejects the agent's changes, putting the project back as it was before its runs
+func (a *App) RestoreSessionSnapshot(sessionID string) error {
+	return a.agentManager.RestoreSnapshot(sessionID)
+}
+
+// DiscardSessionSnapshot accepts the agent's changes and drops the session's snapshot
+func (a *App) DiscardSessionSnapshot(sessionID string) error {
+	return a.agentManager.DiscardSnapshot(sessionID)
+}
+
+// SetSessionPlanMode sets whether a session only plans and proposes diffs
+// instead of changing files
+func (a *App) SetSessionPlanMode(sessionID string, enabled bool) error {
+	return a.agentManager.SetPlanMode(sessionID, enabled)
+}
+
+// GetProposedChanges returns the diffs proposed in a session's latest plan, for review
+func (a *App) GetProposedChanges(sessionID string) ([]diff.FileDiff, error) {
+	return a.agentManager.GetProposedChanges(sessionID)
+}
+
+// ApplyPlan has a session make the changes from its latest plan with write permissions
+func (a *App) ApplyPlan(sessionID string) error {
+	return a.agentManager.ApplyPlan(sessionID)
+}
+
+// GetTurnChanges returns diffs of the files changed by the turn a message belongs to,
+// found from its Edit, Write and Bash tool calls
+func (a *App) GetTurnChanges(sessionID, messageID string) ([]diff.FileDiff, error) {
+	return a.agentManager.GetTurnChanges(sessionID, messageID)
+}
+
+// RevertTurn puts back the files changed by the turn a message belongs to
+func (a *App) RevertTurn(sessionID, messageID string) error {
+	return a.agentManager.RevertTurn(sessionID, messageID)
+}
+
 // GetAllTags returns all unique tags across all sessions
 func (a *App) GetAllTags() ([]string, error) {
 	return agent.GetAllTags()
 }
 
+// =============================================================================
+// Usage Analytics Methods
+// =============================================================================
+
+// UsageRangeRequest is an inclusive date range (YYYY-MM-DD); empty dates are unbounded
+type UsageRangeRequest struct {
+	FromDate string `json:"fromDate"`
+	ToDate   string `json:"toDate"`
+}
+
+// toUsageRange converts a request into an agent.UsageRange covering whole local days
+func (r UsageRangeRequest) toUsageRange() (agent.UsageRange, error) {
+	var usageRange agent.UsageRange
+
+	if r.FromDate != "" {
+		from, err := time.ParseInLocation("2006-01-02", r.FromDate, time.Local)
+		if err != nil {
+			return usageRange, fmt.Errorf("invalid from date: %w", err)
+		}
+		usageRange.From = from
+	}
+
+	if r.ToDate != "" {
+		to, err := time.ParseInLocation("2006-01-02", r.ToDate, time.Local)
+		if err != nil {
+			return usageRange, fmt.Errorf("invalid to date: %w", err)
+		}
+		usageRange.To = to.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	}
+
+	return usageRange, nil
+}
+
+// GetUsageByDay returns token usage and cost per day
+func (a *App) GetUsageByDay(req UsageRangeRequest) ([]agent.DailyUsage, error) {
+	usageRange, err := req.toUsageRange()
+	if err != nil {
+		return nil, err
+	}
+	return agent.GetUsageByDay(usageRange)
+}
+
+// GetUsageByProject returns token usage and cost per project
+func (a *App) GetUsageByProject(req UsageRangeRequest) ([]agent.ProjectUsage, error) {
+	usageRange, err := req.toUsageRange()
+	if err != nil {
+		return nil, err
+	}
+	return agent.GetUsageByProject(usageRange)
+}
+
+// CostByTagRequest selects a date range and, optionally, the tags to report on
+type CostByTagRequest struct {
+	FromDate string   `json:"fromDate"`
+	ToDate   string   `json:"toDate"`
+	Tags     []string `json:"tags"`
+}
+
+// GetCostByTag returns token usage and cost grouped by session tag
+func (a *App) GetCostByTag(req CostByTagRequest) ([]agent.TagUsage, error) {
+	usageRange, err := UsageRangeRequest{FromDate: req.FromDate, ToDate: req.ToDate}.toUsageRange()
+	if err != nil {
+		return nil, err
+	}
+	return agent.GetCostByTag(usageRange, req.Tags)
+}
+
+// GetTopTools returns tool invocation counts, most used first
+func (a *App) GetTopTools(req UsageRangeRequest) ([]agent.ToolUsage, error) {
+	usageRange, err := req.toUsageRange()
+	if err != nil {
+		return nil, err
+	}
+	return agent.GetTopTools(usageRange)
+}
+
 // =============================================================================
 // Firefighter Monitoring Methods
 // =============================================================================
 
 // StartFirefighterMonitoring enables active monitoring for a firefighter session
+// If the session's project has monitoring rules, the monitor polls Datadog
+// and Bugsnag itself and only prompts the agent about alerts a rule matches.
 func (a *App) StartFirefighterMonitoring(sessionID string) error {
 	session, err := a.agentManager.GetSession(sessionID)
 	if err != nil {
 		return err
 	}
-	return session.StartFirefighterMonitoring()
+
+	settings := a.config.GetProjectMonitoringRules(session.ProjectPath)
+	if settings == nil || len(settings.Rules) == 0 {
+		return a.agentManager.StartFirefighterMonitoring(sessionID, agent.MonitorRules{}, nil)
+	}
+
+	rules := agent.MonitorRules{Interval: time.Duration(settings.PollIntervalSeconds) * time.Second}
+	for _, rule := range settings.Rules {
+		rules.Rules = append(rules.Rules, monitorRule(rule))
+	}
+	fetch, err := a.alertFetcher(settings)
+	if err != nil {
+		return err
+	}
+	return a.agentManager.StartFirefighterMonitoring(sessionID, rules, fetch)
+}
+
+// monitorRule converts a configured monitoring rule for the agent package
+func monitorRule(rule config.MonitoringRule) agent.MonitorRule {
+	return agent.MonitorRule{
+		Name:        rule.Name,
+		Source:      rule.Source,
+		Tags:        rule.Tags,
+		MinSeverity: rule.MinSeverity,
+		Cooldown:    time.Duration(rule.CooldownSeconds) * time.Second,
+	}
+}
+
+// bugsnagMonitoringWindow is how recently a Bugsnag error must have been seen
+// for monitoring to report it
+const bugsnagMonitoringWindow = time.Hour
+
+// alertFetcher polls the monitoring services the rules need. Datadog is
+// polled when a rule can match its alerts, and Bugsnag when there are also
+// projects to poll.
+func (a *App) alertFetcher(settings *config.MonitoringRules) (agent.AlertFetcher, error) {
+	prefs := a.config.GetPreferences()
+
+	var pollers []alerts.Poller
+	for _, source := range []alerts.Source{alerts.SourceDatadog, alerts.SourceBugsnag} {
+		needed := false
+		for _, rule := range settings.Rules {
+			if rule.Source == "" || rule.Source == string(source) {
+				needed = true
+				break
+			}
+		}
+		if !needed {
+			continue
+		}
+
+		var poller alerts.Poller
+		var err error
+		switch source {
+		case alerts.SourceDatadog:
+			poller, err = alerts.NewDatadog(prefs.DatadogSite, prefs.DatadogAPIKey, prefs.DatadogAppKey)
+		case alerts.SourceBugsnag:
+			if len(settings.BugsnagProjectIDs) == 0 {
+				continue
+			}
+			poller, err = alerts.NewBugsnag(prefs.BugsnagAPIKey, settings.BugsnagProjectIDs, bugsnagMonitoringWindow)
+		}
+		if err != nil {
+			return nil, err
+		}
+		pollers = append(pollers, poller)
+	}
+	if len(pollers) == 0 {
+		return nil, fmt.Errorf("monitoring rules need Datadog, or Bugsnag with project IDs, to poll")
+	}
+
+	return func(ctx context.Context) ([]agent.Alert, error) {
+		var found []agent.Alert
+		var errs []error
+		for _, poller := range pollers {
+			fetched, err := poller.Fetch(ctx)
+			if err != nil {
+				errs = append(errs, err)
+			}
+			for _, alert := range fetched {
+				found = append(found, agent.Alert{
+					ID:          alert.ID,
+					Source:      string(alert.Source),
+					Severity:    alert.Severity,
+					Title:       alert.Title,
+					Description: alert.Description,
+					FirstSeen:   time.Now(),
+					URL:         alert.URL,
+					Tags:        alert.Tags,
+				})
+			}
+		}
+		return found, errors.Join(errs...)
+	}, nil
 }
 
 // StopFirefighterMonitoring disables active monitoring
@@ -660,6 +2324,124 @@ func (a *App) GetFirefighterMonitorStatus(sessionID string) (map[string]interfac
 	return session.GetFirefighterMonitorStatus(), nil
 }
 
+// RecordIncidentResolutionStep adds a resolution step to a firefighter session's incident
+func (a *App) RecordIncidentResolutionStep(sessionID, step string) error {
+	return a.agentManager.RecordResolutionStep(sessionID, step)
+}
+
+// ResolveIncident marks a firefighter session's incident resolved
+func (a *App) ResolveIncident(sessionID, summary string) error {
+	return a.agentManager.ResolveIncident(sessionID, summary)
+}
+
+// GeneratePostmortem returns a Markdown postmortem of a firefighter session's incident
+func (a *App) GeneratePostmortem(sessionID string) (string, error) {
+	session, err := a.agentManager.GetSession(sessionID)
+	if err != nil {
+		return "", err
+	}
+	return session.GeneratePostmortem()
+}
+
+// restartAlertWebhooks starts the alert webhook listener with the saved
+// settings, replacing one already running
+func (a *App) restartAlertWebhooks() error {
+	a.stopAlertWebhooks()
+
+	settings := a.config.GetAlertWebhookSettings()
+	if !settings.Enabled {
+		return nil
+	}
+	server, err := alerts.NewServer(settings.Port, settings.Token, a.handleAlert)
+	if err != nil {
+		return err
+	}
+	if err := server.Start(); err != nil {
+		return err
+	}
+
+	a.alertMu.Lock()
+	a.alertServer = server
+	a.alertMu.Unlock()
+	return nil
+}
+
+// stopAlertWebhooks stops the alert webhook listener if it is running
+func (a *App) stopAlertWebhooks() {
+	a.alertMu.Lock()
+	server := a.alertServer
+	a.alertServer = nil
+	a.alertMu.Unlock()
+
+	if server != nil {
+		if err := server.Close(); err != nil {
+			logger.Warn("Failed to stop alert webhooks", "error", err)
+		}
+	}
+}
+
+// handleAlert hands an alert received by webhook to a firefighter session for
+// its project and tells the user about it
+func (a *App) handleAlert(alert alerts.Alert) {
+	title := "🔥 " + alert.Source.DisplayName() + " alert"
+
+	projectPath := a.alertProjectPath(alert)
+	if projectPath == "" {
+		runtime.LogWarningf(a.ctx, "No project matches %s alert %q (project %q)", alert.Source, alert.Title, alert.Project)
+		a.notifier.Alert(title, alert.Title+" (no matching project)")
+		return
+	}
+
+	session, investigating, err := a.agentManager.InvestigateAlert(projectPath, agent.Alert{
+		ID:          alert.ID,
+		Source:      string(alert.Source),
+		Severity:    alert.Severity,
+		Title:       alert.Title,
+		Description: alert.Description,
+		FirstSeen:   time.Now(),
+		URL:         alert.URL,
+		Tags:        alert.Tags,
+	})
+	if err != nil {
+		runtime.LogWarningf(a.ctx, "Failed to investigate %s alert %q: %v", alert.Source, alert.Title, err)
+	}
+
+	body := fmt.Sprintf("%s in %s", alert.Title, filepath.Base(projectPath))
+	if !investigating {
+		body += " (added to the incident timeline)"
+	}
+	a.notifier.Alert(title, body)
+
+	if session != nil {
+		runtime.EventsEmit(a.ctx, "firefighter:alert", map[string]interface{}{
+			"sessionId":     session.ID,
+			"alert":         alert,
+			"investigating": investigating,
+		})
+	}
+}
+
+// alertProjectPath picks the project an alert is about: the one mapped to
+// the alert's service or project in the settings, then an open project of
+// that name, then the configured default
+func (a *App) alertProjectPath(alert alerts.Alert) string {
+	settings := a.config.GetAlertWebhookSettings()
+	if alert.Project != "" {
+		for name, path := range settings.Projects {
+			if strings.EqualFold(name, alert.Project) {
+				return path
+			}
+		}
+		for _, p := range a.projectManager.ListProjects() {
+			repo := p.DisplayName[strings.LastIndex(p.DisplayName, "/")+1:]
+			if strings.EqualFold(p.Name, alert.Project) || strings.EqualFold(repo, alert.Project) {
+				return p.Path
+			}
+		}
+	}
+	return settings.DefaultProjectPath
+}
+
 // =============================================================================
 // Google Cloud OAuth Authentication Methods
 // =============================================================================
@@ -806,12 +2588,12 @@ func (a *App) StreamBoatmanModeExecution(sessionID, input, mode, linearAPIKey, p
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
-				fmt.Printf("[boatmanmode] Recovered from panic in execution: %v\n", r)
+				logger.Error("Recovered from panic in boatmanmode execution", "session", sessionID, "panic", r)
 				// Try to emit error event to frontend (with panic recovery)
 				func() {
 					defer func() {
 						if r2 := recover(); r2 != nil {
-							fmt.Printf("[boatmanmode] Failed to emit panic error: %v\n", r2)
+							logger.Error("Failed to emit boatmanmode panic error", "session", sessionID, "panic", r2)
 						}
 					}()
 					runtime.EventsEmit(a.ctx, "boatmanmode:error", map[string]interface{}{
@@ -829,7 +2611,7 @@ func (a *App) StreamBoatmanModeExecution(sessionID, input, mode, linearAPIKey, p
 		go func() {
 			defer func() {
 				if r := recover(); r != nil {
-					fmt.Printf("[boatmanmode] Recovered from panic in output emitter: %v\n", r)
+					logger.Error("Recovered from panic in boatmanmode output emitter", "session", sessionID, "panic", r)
 				}
 			}()
 
@@ -837,7 +2619,7 @@ func (a *App) StreamBoatmanModeExecution(sessionID, input, mode, linearAPIKey, p
 				func() {
 					defer func() {
 						if r := recover(); r != nil {
-							fmt.Printf("[boatmanmode] Failed to emit output: %v\n", r)
+							logger.Error("Failed to emit boatmanmode output", "session", sessionID, "panic", r)
 						}
 					}()
 					runtime.EventsEmit(a.ctx, "boatmanmode:output", map[string]interface{}{
@@ -855,7 +2637,7 @@ func (a *App) StreamBoatmanModeExecution(sessionID, input, mode, linearAPIKey, p
 		close(outputChan)
 
 		if err != nil {
-			fmt.Printf("[boatmanmode] Execution error: %v\n", err)
+			logger.Warn("boatmanmode execution failed", "session", sessionID, "error", err)
 			// Emit error event to frontend
 			runtime.EventsEmit(a.ctx, "boatmanmode:error", map[string]interface{}{
 				"sessionId": sessionID,
@@ -976,6 +2758,287 @@ func (a *App) FetchLinearTicketsForBoatmanMode(linearAPIKey, projectPath string)
 	return tickets, nil
 }
 
+// =============================================================================
+// Command Palette Methods
+// =============================================================================
+
+// ListCommands returns every command available to the command palette
+func (a *App) ListCommands() []commands.Command {
+	return a.commands.List()
+}
+
+// SearchCommands fuzzy-searches commands by title, ID, category, and keywords
+func (a *App) SearchCommands(query string, limit int) []commands.Match {
+	return a.commands.Search(query, limit)
+}
+
+// ExecuteCommand runs a command palette command with the given arguments
+func (a *App) ExecuteCommand(id string, args map[string]interface{}) (interface{}, error) {
+	return a.commands.Execute(id, args)
+}
+
+// registerCommands adds the app's actions to the command registry
+func (a *App) registerCommands() error {
+	sessionArg := commands.Arg{Name: "sessionId", Title: "Session", Type: commands.ArgString, Required: true, Source: "activeSession"}
+	projectArg := commands.Arg{Name: "projectPath", Title: "Project", Type: commands.ArgString, Required: true, Source: "activeProject"}
+
+	registrations := []struct {
+		command commands.Command
+		handler commands.Handler
+	}{
+		{
+			commands.Command{ID: "session.new", Title: "New Session", Category: "Session", Keywords: []string{"chat", "start", "agent"}, Args: []commands.Arg{projectArg}},
+			func(args commands.Args) (any, error) {
+				return a.CreateAgentSession(args.String("projectPath"))
+			},
+		},
+		{
+			commands.Command{ID: "session.newFirefighter", Title: "New Firefighter Session", Category: "Session", Keywords: []string{"incident", "investigate"}, Args: []commands.Arg{
+				projectArg,
+				{Name: "scope", Title: "Scope", Type: commands.ArgString, Description: "What to investigate"},
+			}},
+			func(args commands.Args) (any, error) {
+				return a.CreateFirefighterSession(args.String("projectPath"), args.String("scope"))
+			},
+		},
+		{
+			commands.Command{ID: "session.stop", Title: "Stop Session", Category: "Session", Args: []commands.Arg{sessionArg}},
+			func(args commands.Args) (any, error) {
+				return nil, a.StopAgentSession(args.String("sessionId"))
+			},
+		},
+		{
+			commands.Command{ID: "session.send", Title: "Send Message", Category: "Session", Keywords: []string{"prompt", "ask"}, Args: []commands.Arg{
+				sessionArg,
+				{Name: "message", Title: "Message", Type: commands.ArgString, Required: true},
+			}},
+			func(args commands.Args) (any, error) {
+				return nil, a.SendAgentMessage(args.String("sessionId"), args.String("message"))
+			},
+		},
+		{
+			commands.Command{ID: "prompt.runTemplate", Title: "Run Prompt Template", Category: "Session", Keywords: []string{"saved prompt", "template"}, Args: []commands.Arg{
+				sessionArg,
+				{Name: "template", Title: "Template", Type: commands.ArgString, Required: true, Description: "Prompt with {{variable}} placeholders"},
+				{Name: "vars", Title: "Variables", Type: commands.ArgObject},
+			}},
+			func(args commands.Args) (any, error) {
+				return nil, a.SendTemplatedAgentMessage(args.String("sessionId"), args.String("template"), args.StringMap("vars"))
+			},
+		},
+		{
+			commands.Command{ID: "session.killCommands", Title: "Kill Running Commands", Category: "Session", Keywords: []string{"bash", "stuck", "cancel"}, Args: []commands.Arg{sessionArg}},
+			func(args commands.Args) (any, error) {
+				return nil, a.KillRunningCommands(args.String("sessionId"))
+			},
+		},
+		{
+			commands.Command{ID: "project.open", Title: "Open Project", Category: "Project", Keywords: []string{"folder", "workspace"}, Args: []commands.Arg{
+				{Name: "path", Title: "Path", Type: commands.ArgString, Description: "Leave empty to choose a folder"},
+			}},
+			func(args commands.Args) (any, error) {
+				path := args.String("path")
+				if path == "" {
+					selected, err := a.SelectFolder()
+					if err != nil || selected == "" {
+						return nil, err
+					}
+					path = selected
+				}
+				return a.OpenProject(path)
+			},
+		},
+		{
+			commands.Command{ID: "mcp.callTool", Title: "Call MCP Tool", Category: "MCP", Keywords: []string{"server", "test"}, Args: []commands.Arg{
+				{Name: "server", Title: "Server", Type: commands.ArgString, Required: true},
+				{Name: "tool", Title: "Tool", Type: commands.ArgString, Required: true},
+				{Name: "arguments", Title: "Arguments", Type: commands.ArgString, Description: "JSON object of tool arguments"},
+			}},
+			func(args commands.Args) (any, error) {
+				return a.CallMCPTool(args.String("server"), args.String("tool"), args.String("arguments"))
+			},
+		},
+		{
+			commands.Command{ID: "firefighter.toggleMonitoring", Title: "Toggle Monitoring", Category: "Firefighter", Keywords: []string{"alerts", "watch"}, Args: []commands.Arg{sessionArg}},
+			func(args commands.Args) (any, error) {
+				sessionID := args.String("sessionId")
+				active, err := a.IsFirefighterMonitoringActive(sessionID)
+				if err != nil {
+					return nil, err
+				}
+				if active {
+					return false, a.StopFirefighterMonitoring(sessionID)
+				}
+				return true, a.StartFirefighterMonitoring(sessionID)
+			},
+		},
+		{
+			commands.Command{ID: "sessions.cleanup", Title: "Clean Up Old Sessions", Category: "App", Keywords: []string{"delete", "prune"}},
+			func(args commands.Args) (any, error) {
+				return a.CleanupOldSessions()
+			},
+		},
+		{
+			commands.Command{ID: "toolFormatters.reload", Title: "Reload Tool Formatters", Category: "App"},
+			func(args commands.Args) (any, error) {
+				return a.ReloadToolFormatters()
+			},
+		},
+		{
+			commands.Command{ID: "updates.check", Title: "Check for Updates", Category: "App", Keywords: []string{"version", "upgrade"}},
+			func(args commands.Args) (any, error) {
+				return a.CheckForUpdates()
+			},
+		},
+	}
+
+	for _, r := range registrations {
+		if err := a.commands.Register(r.command, r.handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// =============================================================================
+// Update Methods
+// =============================================================================
+
+// GetAppVersion returns the running app version
+func (a *App) GetAppVersion() string {
+	return a.updater.CurrentVersion()
+}
+
+// CheckForUpdates checks GitHub releases on the configured update channel
+func (a *App) CheckForUpdates() (*updater.UpdateInfo, error) {
+	channel := updater.Channel(a.config.GetPreferences().UpdateChannel)
+	return a.updater.Check(a.ctx, channel)
+}
+
+// DownloadUpdate downloads and verifies the latest update so it installs on restart
+func (a *App) DownloadUpdate() (*updater.PendingUpdate, error) {
+	info, err := a.CheckForUpdates()
+	if err != nil {
+		return nil, err
+	}
+	return a.updater.Download(a.ctx, info)
+}
+
+// GetPendingUpdate returns the downloaded update waiting to be installed, if any
+func (a *App) GetPendingUpdate() (*updater.PendingUpdate, error) {
+	return a.updater.Pending()
+}
+
+// InstallUpdateAndRestart installs the downloaded update and relaunches the app
+func (a *App) InstallUpdateAndRestart() error {
+	pending, err := a.updater.Install()
+	if err != nil {
+		return err
+	}
+	if pending == nil {
+		return fmt.Errorf("no update has been downloaded")
+	}
+	if err := a.updater.Restart(); err != nil {
+		return fmt.Errorf("update installed but failed to relaunch: %w", err)
+	}
+	runtime.Quit(a.ctx)
+	return nil
+}
+
+// =============================================================================
+// Logging and Diagnostics Methods
+// =============================================================================
+
+const (
+	logMaxSize   = 10 << 20 // Bytes before the log file rotates
+	logFilesKept = 5        // Rotated log files kept besides the current one
+)
+
+// startLogging sets the saved log level and starts writing the log file
+func (a *App) startLogging() error {
+	if err := logging.SetLevel(a.config.GetLogLevel()); err != nil {
+		logger.Warn("Ignoring saved log level", "error", err)
+	}
+	dir, err := logging.DefaultDir()
+	if err != nil {
+		return err
+	}
+	return logging.Init(dir, logMaxSize, logFilesKept)
+}
+
+// GetRecentLogs returns up to limit of the latest log entries at or above
+// level ("debug", "info", "warn" or "error"; empty for all), oldest first
+func (a *App) GetRecentLogs(limit int, level string) ([]logging.Entry, error) {
+	return logging.Recent(limit, level)
+}
+
+// SetLogLevel changes the lowest level logged and saves it
+func (a *App) SetLogLevel(level string) error {
+	if err := logging.SetLevel(level); err != nil {
+		return err
+	}
+	return a.config.SetLogLevel(logging.Level())
+}
+
+// GetLogDirectory returns the directory the log files are in
+func (a *App) GetLogDirectory() (string, error) {
+	return logging.DefaultDir()
+}
+
+// ExportDiagnostics writes a zip for support requests to path holding the
+// parts options includes: redacted logs, config and MCP config, the Claude CLI
+// version, and session statistics. With no path the user picks one in a save
+// dialog. It returns the path written, or "" if the dialog was cancelled.
+func (a *App) ExportDiagnostics(path string, options diagnostics.Options) (string, error) {
+	if path == "" {
+		var err error
+		path, err = runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+			Title:           "Export Diagnostics",
+			DefaultFilename: fmt.Sprintf("boatman-diagnostics-%s.zip", time.Now().Format("2006-01-02")),
+		})
+		if err != nil || path == "" {
+			return "", err
+		}
+	}
+
+	logDir, _ := logging.DefaultDir()
+	_, err := diagnostics.Export(path, options, diagnostics.Sources{
+		AppVersion:    a.updater.CurrentVersion(),
+		LogDir:        logDir,
+		ConfigFile:    a.config.GetConfigPath(),
+		MCPConfigFile: a.mcpManager.GetConfigPath(),
+		CLIVersion:    agent.NewClaudeCLI().GetVersion,
+		SessionStats:  a.diagnosticSessionStats,
+		Redact: func(text string) string {
+			redacted, _ := agent.RedactSecrets(text)
+			return redacted
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// diagnosticSessionStats summarizes sessions for a diagnostics bundle,
+// leaving out their conversations
+func (a *App) diagnosticSessionStats() (any, error) {
+	stored, err := agent.GetSessionStats()
+	if err != nil {
+		return nil, err
+	}
+	byStatus := make(map[string]int)
+	infos := a.ListAgentSessions()
+	for _, info := range infos {
+		byStatus[string(info.Status)]++
+	}
+	return map[string]any{
+		"stored":   stored,
+		"loaded":   len(infos),
+		"byStatus": byStatus,
+	}, nil
+}
+
 // =============================================================================
 // Utility Methods
 // =============================================================================
@@ -992,11 +3055,50 @@ func (a *App) GetClaudeCLIVersion() (string, error) {
 	return cli.GetVersion()
 }
 
-// SendNotification sends a desktop notification
+// SendNotification sends a desktop notification unless notifications are off
+// or it's quiet hours. It returns without waiting for the notification.
 func (a *App) SendNotification(title, message string) {
-	runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
-		Type:    runtime.InfoDialog,
-		Title:   title,
-		Message: message,
+	a.notifier.Notify(title, message)
+}
+
+// sendNotification shows a notification through the OS notification center,
+// asking the frontend to show it in the app where that isn't available
+func (a *App) sendNotification(title, body string) error {
+	err := notify.Native(title, body)
+	if err != nil {
+		runtime.EventsEmit(a.ctx, "app:notification", map[string]string{
+			"title": title,
+			"body":  body,
+		})
+	}
+	return err
+}
+
+// GetSessionsNeedingAttention returns the sessions waiting for approval or
+// stopped by an error, longest waiting first
+func (a *App) GetSessionsNeedingAttention() []agent.SessionAttention {
+	return a.agentManager.GetSessionsNeedingAttention()
+}
+
+// attentionChanged badges the window title with how many sessions are waiting
+// on the user and tells the frontend, which plays a sound when one newly
+// needs attention and the user asked for it
+func (a *App) attentionChanged(sessions []agent.SessionAttention, raised bool) {
+	title := "Boatman"
+	if len(sessions) > 0 {
+		title = fmt.Sprintf("Boatman (%d)", len(sessions))
+	}
+	runtime.WindowSetTitle(a.ctx, title)
+
+	runtime.EventsEmit(a.ctx, "app:attention", map[string]interface{}{
+		"count":    len(sessions),
+		"sessions": sessions,
+		"sound":    raised && a.config.GetNotificationSettings().AttentionSound,
 	})
 }
+
+// SetWindowFocused records whether the app window has focus so notifications
+// can be held back while the user is looking at the app
+func (a *App) SetWindowFocused(focused bool) {
+	a.windowFocused.Store(focused)
+}
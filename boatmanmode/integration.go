@@ -11,8 +11,13 @@ import (
 	"path/filepath"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"boatman/logging"
 )
 
+// logger logs the boatmanmode process
+var logger = logging.For("boatmanmode")
+
 // Integration provides boatmanmode functionality via subprocess calls
 type Integration struct {
 	boatmanmodePath string
@@ -149,19 +154,19 @@ func (i *Integration) StreamExecution(ctx context.Context, sessionID string, inp
 	var stderrBuf bytes.Buffer
 	cmd.Stderr = &stderrBuf
 
-	fmt.Printf("[boatmanmode] Starting command: %s %v in directory: %s\n", i.boatmanmodePath, cmd.Args[1:], i.repoPath)
+	logger.Info("Starting command", "path", i.boatmanmodePath, "args", cmd.Args[1:], "dir", i.repoPath)
 
 	if err := cmd.Start(); err != nil {
 		return nil, fmt.Errorf("failed to start boatmanmode at %s: %w", i.boatmanmodePath, err)
 	}
 
-	fmt.Printf("[boatmanmode] Command started successfully, PID: %d\n", cmd.Process.Pid)
+	logger.Info("Command started", "pid", cmd.Process.Pid)
 
 	// Stream stdout and parse JSON events
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
-				fmt.Printf("[boatmanmode] Recovered from panic in stdout reader: %v\n", r)
+				logger.Error("Recovered from panic in stdout reader", "panic", r)
 			}
 		}()
 
@@ -176,7 +181,7 @@ func (i *Integration) StreamExecution(ctx context.Context, sessionID string, inp
 				func() {
 					defer func() {
 						if r := recover(); r != nil {
-							fmt.Printf("[boatmanmode] Failed to emit event: %v\n", r)
+							logger.Error("Failed to emit event", "panic", r)
 						}
 					}()
 					runtime.EventsEmit(ctx, "boatmanmode:event", map[string]interface{}{
@@ -207,7 +212,7 @@ func (i *Integration) StreamExecution(ctx context.Context, sessionID string, inp
 		}
 
 		if err := scanner.Err(); err != nil {
-			fmt.Printf("[boatmanmode] Scanner error: %v\n", err)
+			logger.Warn("Scanner error", "error", err)
 		}
 	}()
 
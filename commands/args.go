@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Args holds a command's validated arguments
+type Args map[string]any
+
+// String returns a string argument, or "" when it wasn't given
+func (a Args) String(name string) string {
+	s, _ := a[name].(string)
+	return s
+}
+
+// Int returns a number argument as an int, or 0 when it wasn't given
+func (a Args) Int(name string) int {
+	f, _ := a[name].(float64)
+	return int(f)
+}
+
+// Bool returns a boolean argument, or false when it wasn't given
+func (a Args) Bool(name string) bool {
+	b, _ := a[name].(bool)
+	return b
+}
+
+// StringMap returns an object argument, or nil when it wasn't given
+func (a Args) StringMap(name string) map[string]string {
+	m, _ := a[name].(map[string]string)
+	return m
+}
+
+// validateArgs checks args against cmd's schema and normalizes their values.
+// Numbers and booleans may arrive as strings from a text prompt.
+func validateArgs(cmd Command, args map[string]any) (Args, error) {
+	validated := make(Args, len(cmd.Args))
+	known := make(map[string]bool, len(cmd.Args))
+
+	for _, spec := range cmd.Args {
+		known[spec.Name] = true
+		raw, present := args[spec.Name]
+		if !present || raw == nil || raw == "" {
+			if spec.Required {
+				return nil, fmt.Errorf("%s: missing required argument %s", cmd.ID, spec.Name)
+			}
+			continue
+		}
+
+		value, err := convertArg(spec, raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: argument %s: %w", cmd.ID, spec.Name, err)
+		}
+		validated[spec.Name] = value
+	}
+
+	for name := range args {
+		if !known[name] {
+			return nil, fmt.Errorf("%s: unknown argument %s", cmd.ID, name)
+		}
+	}
+
+	return validated, nil
+}
+
+// convertArg converts a raw value to the type the argument expects
+func convertArg(spec Arg, raw any) (any, error) {
+	switch spec.Type {
+	case ArgString:
+		s, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a string")
+		}
+		if len(spec.Enum) > 0 {
+			for _, allowed := range spec.Enum {
+				if s == allowed {
+					return s, nil
+				}
+			}
+			return nil, fmt.Errorf("must be one of %v", spec.Enum)
+		}
+		return s, nil
+
+	case ArgNumber:
+		switch v := raw.(type) {
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+				return nil, fmt.Errorf("expected a number")
+			}
+			return f, nil
+		}
+		return nil, fmt.Errorf("expected a number")
+
+	case ArgBoolean:
+		switch v := raw.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("expected true or false")
+			}
+			return b, nil
+		}
+		return nil, fmt.Errorf("expected true or false")
+
+	case ArgObject:
+		switch v := raw.(type) {
+		case map[string]string:
+			return v, nil
+		case map[string]any:
+			m := make(map[string]string, len(v))
+			for key, item := range v {
+				s, ok := item.(string)
+				if !ok {
+					return nil, fmt.Errorf("value for %s must be a string", key)
+				}
+				m[key] = s
+			}
+			return m, nil
+		}
+		return nil, fmt.Errorf("expected an object")
+	}
+
+	return nil, fmt.Errorf("unknown type %q", spec.Type)
+}
@@ -0,0 +1,77 @@
+package commands
+
+import (
+	"unicode"
+)
+
+// Scoring weights for fuzzy matches
+const (
+	matchScore        = 16
+	consecutiveBonus  = 24
+	wordStartBonus    = 20
+	prefixBonus       = 32
+	gapPenalty        = 2
+	otherFieldPenalty = 20
+)
+
+// fuzzyMatch reports whether every rune of query appears in text in order,
+// ignoring case. Matches score higher when they are consecutive, start words,
+// or start the text. It returns the matched rune positions in text.
+func fuzzyMatch(query, text string) (int, []int, bool) {
+	q := []rune(query)
+	t := []rune(text)
+
+	// Spaces in the query only separate words
+	needle := make([]rune, 0, len(q))
+	for _, r := range q {
+		if !unicode.IsSpace(r) {
+			needle = append(needle, unicode.ToLower(r))
+		}
+	}
+	if len(needle) == 0 {
+		return 0, nil, true
+	}
+
+	score := 0
+	positions := make([]int, 0, len(needle))
+	last := -1
+	n := 0
+	for i := 0; i < len(t) && n < len(needle); i++ {
+		if unicode.ToLower(t[i]) != needle[n] {
+			continue
+		}
+
+		score += matchScore
+		switch {
+		case i == 0:
+			score += prefixBonus
+		case last == i-1:
+			score += consecutiveBonus
+		case isWordStart(t, i):
+			score += wordStartBonus
+		}
+		if last >= 0 {
+			score -= (i - last - 1) * gapPenalty
+		}
+
+		positions = append(positions, i)
+		last = i
+		n++
+	}
+
+	if n < len(needle) {
+		return 0, nil, false
+	}
+	// Prefer shorter texts when matches are otherwise equal
+	score -= len(t) - len(needle)
+	return score, positions, true
+}
+
+// isWordStart reports whether t[i] begins a word: after a separator or at a lower-to-upper case change
+func isWordStart(t []rune, i int) bool {
+	prev := t[i-1]
+	if !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+		return true
+	}
+	return unicode.IsLower(prev) && unicode.IsUpper(t[i])
+}
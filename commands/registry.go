@@ -0,0 +1,169 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ArgType is the kind of value a command argument accepts
+type ArgType string
+
+const (
+	ArgString  ArgType = "string"
+	ArgNumber  ArgType = "number"
+	ArgBoolean ArgType = "boolean"
+	ArgObject  ArgType = "object" // String keys mapped to string values
+)
+
+// Arg describes one argument of a command so the palette can prompt for it
+type Arg struct {
+	Name        string   `json:"name"`
+	Title       string   `json:"title"`
+	Type        ArgType  `json:"type"`
+	Required    bool     `json:"required,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Enum        []string `json:"enum,omitempty"` // Allowed values for string arguments
+	// Source hints where the palette can fill the value from, e.g. "activeSession" or "activeProject"
+	Source string `json:"source,omitempty"`
+}
+
+// Command is an action that can be run from the command palette
+type Command struct {
+	ID          string   `json:"id"` // e.g. "session.new"
+	Title       string   `json:"title"`
+	Description string   `json:"description,omitempty"`
+	Category    string   `json:"category,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"` // Extra search terms
+	Args        []Arg    `json:"args,omitempty"`
+}
+
+// Handler runs a command with validated arguments
+type Handler func(args Args) (any, error)
+
+// entry pairs a command with its handler
+type entry struct {
+	command Command
+	handler Handler
+}
+
+// Registry holds the app's commands in registration order
+type Registry struct {
+	mu       sync.RWMutex
+	commands map[string]entry
+	order    []string
+}
+
+// NewRegistry creates an empty command registry
+func NewRegistry() *Registry {
+	return &Registry{commands: make(map[string]entry)}
+}
+
+// Register adds a command. IDs must be unique.
+func (r *Registry) Register(cmd Command, handler Handler) error {
+	if cmd.ID == "" {
+		return fmt.Errorf("command is missing an ID")
+	}
+	if cmd.Title == "" {
+		return fmt.Errorf("command %s is missing a title", cmd.ID)
+	}
+	if handler == nil {
+		return fmt.Errorf("command %s has no handler", cmd.ID)
+	}
+	for _, arg := range cmd.Args {
+		switch arg.Type {
+		case ArgString, ArgNumber, ArgBoolean, ArgObject:
+		default:
+			return fmt.Errorf("command %s: argument %s has unknown type %q", cmd.ID, arg.Name, arg.Type)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.commands[cmd.ID]; exists {
+		return fmt.Errorf("command already registered: %s", cmd.ID)
+	}
+	r.commands[cmd.ID] = entry{command: cmd, handler: handler}
+	r.order = append(r.order, cmd.ID)
+	return nil
+}
+
+// List returns every command in registration order
+func (r *Registry) List() []Command {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	commands := make([]Command, 0, len(r.order))
+	for _, id := range r.order {
+		commands = append(commands, r.commands[id].command)
+	}
+	return commands
+}
+
+// Get returns a command by ID
+func (r *Registry) Get(id string) (Command, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	e, ok := r.commands[id]
+	return e.command, ok
+}
+
+// Execute validates args against the command's schema and runs it
+func (r *Registry) Execute(id string, args map[string]any) (any, error) {
+	r.mu.RLock()
+	e, ok := r.commands[id]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown command: %s", id)
+	}
+
+	validated, err := validateArgs(e.command, args)
+	if err != nil {
+		return nil, err
+	}
+	return e.handler(validated)
+}
+
+// Match is a command that matched a search query
+type Match struct {
+	Command Command `json:"command"`
+	Score   int     `json:"score"`
+	// Positions are the matched rune indexes in the title, for highlighting
+	Positions []int `json:"positions,omitempty"`
+}
+
+// Search fuzzy-matches query against command titles, IDs, categories, and
+// keywords. Results are best first; an empty query returns every command.
+func (r *Registry) Search(query string, limit int) []Match {
+	commands := r.List()
+
+	matches := make([]Match, 0, len(commands))
+	for i, cmd := range commands {
+		if query == "" {
+			matches = append(matches, Match{Command: cmd, Score: -i})
+			continue
+		}
+
+		score, positions, found := fuzzyMatch(query, cmd.Title)
+		match := Match{Command: cmd, Score: score, Positions: positions}
+
+		others := append([]string{cmd.ID, cmd.Category}, cmd.Keywords...)
+		for _, text := range others {
+			// Matches outside the title count for a little less
+			if s, _, ok := fuzzyMatch(query, text); ok && (!found || s-otherFieldPenalty > match.Score) {
+				match.Score = s - otherFieldPenalty
+				found = true
+			}
+		}
+		if found {
+			matches = append(matches, match)
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score > matches[j].Score
+	})
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
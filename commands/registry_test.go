@@ -0,0 +1,163 @@
+package commands
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func newTestRegistry(t *testing.T) *Registry {
+	t.Helper()
+	r := NewRegistry()
+	noop := func(args Args) (any, error) { return nil, nil }
+
+	cmds := []Command{
+		{ID: "session.new", Title: "New Session", Category: "Session", Keywords: []string{"chat", "start"}},
+		{ID: "project.open", Title: "Open Project", Category: "Project"},
+		{ID: "firefighter.toggleMonitoring", Title: "Toggle Monitoring", Category: "Firefighter"},
+		{ID: "updates.check", Title: "Check for Updates", Category: "App"},
+	}
+	for _, cmd := range cmds {
+		if err := r.Register(cmd, noop); err != nil {
+			t.Fatalf("Register(%s) failed: %v", cmd.ID, err)
+		}
+	}
+	return r
+}
+
+func TestRegistryRegisterValidation(t *testing.T) {
+	r := newTestRegistry(t)
+	noop := func(args Args) (any, error) { return nil, nil }
+
+	tests := []struct {
+		name    string
+		cmd     Command
+		handler Handler
+	}{
+		{"missing ID", Command{Title: "x"}, noop},
+		{"missing title", Command{ID: "x"}, noop},
+		{"nil handler", Command{ID: "x", Title: "x"}, nil},
+		{"duplicate ID", Command{ID: "session.new", Title: "Again"}, noop},
+		{"bad arg type", Command{ID: "x", Title: "x", Args: []Arg{{Name: "a", Type: "date"}}}, noop},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := r.Register(tt.cmd, tt.handler); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+
+	if len(r.List()) != 4 {
+		t.Errorf("Expected 4 commands, got %d", len(r.List()))
+	}
+	if r.List()[0].ID != "session.new" {
+		t.Errorf("Expected registration order, got %s first", r.List()[0].ID)
+	}
+}
+
+func TestRegistryExecute(t *testing.T) {
+	r := NewRegistry()
+	var got Args
+	r.Register(Command{
+		ID:    "prompt.run",
+		Title: "Run Prompt",
+		Args: []Arg{
+			{Name: "sessionId", Type: ArgString, Required: true},
+			{Name: "model", Type: ArgString, Enum: []string{"sonnet", "opus"}},
+			{Name: "count", Type: ArgNumber},
+			{Name: "dryRun", Type: ArgBoolean},
+			{Name: "vars", Type: ArgObject},
+		},
+	}, func(args Args) (any, error) {
+		got = args
+		return "ok", nil
+	})
+	r.Register(Command{ID: "fail", Title: "Fail"}, func(args Args) (any, error) {
+		return nil, errors.New("boom")
+	})
+
+	result, err := r.Execute("prompt.run", map[string]any{
+		"sessionId": "s1",
+		"model":     "opus",
+		"count":     "3",
+		"dryRun":    "true",
+		"vars":      map[string]any{"file": "main.go"},
+	})
+	if err != nil || result != "ok" {
+		t.Fatalf("Execute() = %v, %v", result, err)
+	}
+	if got.String("sessionId") != "s1" || got.String("model") != "opus" || got.Int("count") != 3 || !got.Bool("dryRun") {
+		t.Errorf("Unexpected args: %+v", got)
+	}
+	if got.StringMap("vars")["file"] != "main.go" {
+		t.Errorf("Expected vars to be converted, got %+v", got["vars"])
+	}
+
+	errorCases := []struct {
+		name string
+		id   string
+		args map[string]any
+		want string
+	}{
+		{"unknown command", "nope", nil, "unknown command"},
+		{"missing required", "prompt.run", map[string]any{}, "missing required argument sessionId"},
+		{"enum", "prompt.run", map[string]any{"sessionId": "s1", "model": "gpt"}, "must be one of"},
+		{"number", "prompt.run", map[string]any{"sessionId": "s1", "count": "many"}, "expected a number"},
+		{"unknown arg", "prompt.run", map[string]any{"sessionId": "s1", "extra": 1}, "unknown argument extra"},
+		{"handler error", "fail", nil, "boom"},
+	}
+	for _, tt := range errorCases {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Execute(tt.id, tt.args)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestRegistrySearch(t *testing.T) {
+	r := newTestRegistry(t)
+
+	tests := []struct {
+		query string
+		first string
+	}{
+		{"new", "session.new"},
+		{"ns", "session.new"},
+		{"open proj", "project.open"},
+		{"monitor", "firefighter.toggleMonitoring"},
+		{"upd", "updates.check"},
+		{"chat", "session.new"},                         // keyword
+		{"firefighter", "firefighter.toggleMonitoring"}, // category
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			matches := r.Search(tt.query, 0)
+			if len(matches) == 0 {
+				t.Fatal("Expected matches")
+			}
+			if matches[0].Command.ID != tt.first {
+				t.Errorf("Expected %s first, got %s", tt.first, matches[0].Command.ID)
+			}
+		})
+	}
+
+	if matches := r.Search("zzz", 0); len(matches) != 0 {
+		t.Errorf("Expected no matches, got %d", len(matches))
+	}
+	if matches := r.Search("", 2); len(matches) != 2 || matches[0].Command.ID != "session.new" {
+		t.Errorf("Expected first 2 commands in order, got %+v", matches)
+	}
+
+	matches := r.Search("op", 1)
+	if len(matches) != 1 || matches[0].Command.ID != "project.open" {
+		t.Fatalf("Expected Open Project, got %+v", matches)
+	}
+	if len(matches[0].Positions) != 2 || matches[0].Positions[0] != 0 || matches[0].Positions[1] != 1 {
+		t.Errorf("Expected title positions [0 1], got %v", matches[0].Positions)
+	}
+}
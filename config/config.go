@@ -2,9 +2,14 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"sync"
+
+	"boatman/persist"
+	"boatman/secrets"
 )
 
 // ApprovalMode defines how the agent handles changes
@@ -63,12 +68,48 @@ type UserPreferences struct {
 	MaxAgentsPerSession   int  `json:"maxAgentsPerSession"`
 	KeepCompletedAgents   bool `json:"keepCompletedAgents"`
 
+	// ClaudeCLIPath points at the claude binary when it isn't on PATH
+	ClaudeCLIPath string `json:"claudeCLIPath,omitempty"`
+
+	// MaxCommandRuntimeSeconds limits a single Bash command before it is flagged
+	// for killing. Zero uses the default and a negative value disables the limit.
+	MaxCommandRuntimeSeconds int `json:"maxCommandRuntimeSeconds"`
+
+	// RunRetries is how many times a run that fails transiently (API server
+	// errors, a crashed claude process) is retried before the session shows the
+	// error. Zero uses the default and a negative value disables retrying.
+	RunRetries int `json:"runRetries,omitempty"`
+	// RunRetryDelaySeconds is the wait before the first retry, doubling after each
+	RunRetryDelaySeconds int `json:"runRetryDelaySeconds,omitempty"`
+
+	// MaxConcurrentRuns is how many sessions may run claude at once; others wait
+	// for a slot. Zero uses the default and a negative value removes the limit.
+	MaxConcurrentRuns int `json:"maxConcurrentRuns,omitempty"`
+
+	// StreamBatchIntervalMs is how long streamed response text is gathered
+	// before it's shown. Zero uses the default and a negative value shows
+	// every chunk as it arrives.
+	StreamBatchIntervalMs int `json:"streamBatchIntervalMs,omitempty"`
+
+	// Cost budgets in USD. Sending is blocked once a session or the day reaches
+	// its limit; zero leaves the limit off.
+	MaxCostPerSession float64 `json:"maxCostPerSession,omitempty"`
+	MaxCostPerDay     float64 `json:"maxCostPerDay,omitempty"`
+
+	// ModelPricing overrides list prices, e.g. for enterprise rates. Keys are
+	// model names or parts of them ("opus", "claude-sonnet-4-5").
+	ModelPricing map[string]ModelPrice `json:"modelPricing,omitempty"`
+
 	// Firefighter/Observability settings
 	DatadogAPIKey string `json:"datadogAPIKey,omitempty"`
 	DatadogAppKey string `json:"datadogAppKey,omitempty"`
 	DatadogSite   string `json:"datadogSite,omitempty"`
 	BugsnagAPIKey string `json:"bugsnagAPIKey,omitempty"`
 
+	// AlertWebhooks receives Datadog, Bugsnag and PagerDuty alerts and hands
+	// them to firefighter sessions; nil leaves the listener off
+	AlertWebhooks *AlertWebhookSettings `json:"alertWebhooks,omitempty"`
+
 	// Okta OAuth settings
 	OktaDomain       string `json:"oktaDomain,omitempty"`
 	OktaClientID     string `json:"oktaClientID,omitempty"`
@@ -76,6 +117,116 @@ type UserPreferences struct {
 
 	// Linear settings
 	LinearAPIKey string `json:"linearAPIKey,omitempty"`
+
+	// API tokens for opening pull requests on GitHub and merge requests on GitLab
+	GitHubToken string `json:"githubToken,omitempty"`
+	GitLabToken string `json:"gitlabToken,omitempty"`
+
+	// Notifications picks which session events notify; nil uses the defaults
+	Notifications *NotificationSettings `json:"notifications,omitempty"`
+
+	// SystemPromptAppend is added to the system prompt of every session
+	// (coding style, language, safety rules)
+	SystemPromptAppend string `json:"systemPromptAppend,omitempty"`
+
+	// Update settings
+	UpdateChannel string `json:"updateChannel,omitempty"` // "stable" or "beta"
+
+	// Filesystem guardrail settings: with guardrails on, writes must stay in
+	// the project or the allowed paths
+	FilesystemGuardrails  bool     `json:"filesystemGuardrails"`
+	GuardrailAllowedPaths []string `json:"guardrailAllowedPaths,omitempty"`
+	// ProtectedPaths are globs whose writes wait for approval in every
+	// approval mode; nil uses DefaultProtectedPaths and empty protects nothing
+	ProtectedPaths []string `json:"protectedPaths"`
+
+	// LogLevel is the lowest level written to ~/.boatman/logs: "debug",
+	// "info", "warn" or "error"; empty means "info"
+	LogLevel string `json:"logLevel,omitempty"`
+
+	// SessionEncryption seals saved sessions and archives: "keychain" with a
+	// random key kept in the keychain, "passphrase" with a key derived from a
+	// passphrase entered each launch; empty saves them as plain JSON
+	SessionEncryption string `json:"sessionEncryption,omitempty"`
+	// SessionKeySalt and SessionKeyCheck verify the passphrase (base64)
+	SessionKeySalt  string `json:"sessionKeySalt,omitempty"`
+	SessionKeyCheck string `json:"sessionKeyCheck,omitempty"`
+
+	// SavedSearches are named session searches; pinned ones are sidebar views
+	SavedSearches []SavedSearch `json:"savedSearches,omitempty"`
+}
+
+// DefaultProtectedPaths are the paths agents can't write to without approval
+// unless the user picks others
+var DefaultProtectedPaths = []string{".env", ".env.*", "secrets/", "*.pem", "*.key"}
+
+// ModelPrice is a model's cost in dollars per million tokens. Cache prices
+// left at zero are derived from the input price.
+type ModelPrice struct {
+	Input      float64 `json:"input"`
+	Output     float64 `json:"output"`
+	CacheWrite float64 `json:"cacheWrite,omitempty"`
+	CacheRead  float64 `json:"cacheRead,omitempty"`
+}
+
+// NotificationSettings controls desktop notifications for session events
+type NotificationSettings struct {
+	OnWaiting         bool `json:"onWaiting"`   // Session needs approval
+	OnCompleted       bool `json:"onCompleted"` // A long turn finished
+	OnError           bool `json:"onError"`
+	OnAlert           bool `json:"onAlert"`        // A firefighter alert came in
+	MinTurnSeconds    int  `json:"minTurnSeconds"` // Shorter turns finish without a notification
+	OnlyWhenUnfocused bool `json:"onlyWhenUnfocused"`
+	// CostThreshold notifies once a session has spent this much in USD; zero is off
+	CostThreshold float64 `json:"costThreshold,omitempty"`
+	// AttentionSound plays a sound when a session starts waiting for approval or errors
+	AttentionSound bool `json:"attentionSound,omitempty"`
+	// Quiet hours as "HH:MM" local time; the window may wrap past midnight
+	QuietHoursEnabled bool   `json:"quietHoursEnabled"`
+	QuietHoursStart   string `json:"quietHoursStart"`
+	QuietHoursEnd     string `json:"quietHoursEnd"`
+}
+
+// DefaultNotificationSettings returns the notification settings used until the user changes them
+func DefaultNotificationSettings() NotificationSettings {
+	return NotificationSettings{
+		OnWaiting:         true,
+		OnCompleted:       true,
+		OnError:           true,
+		OnAlert:           true,
+		MinTurnSeconds:    30,
+		OnlyWhenUnfocused: true,
+		QuietHoursStart:   "22:00",
+		QuietHoursEnd:     "08:00",
+	}
+}
+
+// UnmarshalJSON starts from the defaults, so settings saved before a rule
+// existed get its default rather than turning it off
+func (n *NotificationSettings) UnmarshalJSON(data []byte) error {
+	type plain NotificationSettings
+	settings := plain(DefaultNotificationSettings())
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return err
+	}
+	*n = NotificationSettings(settings)
+	return nil
+}
+
+// DefaultAlertWebhookPort is the port the alert webhook listener uses unless
+// the user picks another
+const DefaultAlertWebhookPort = 7421
+
+// AlertWebhookSettings configures the local listener for alert webhooks
+type AlertWebhookSettings struct {
+	Enabled bool   `json:"enabled"`
+	Port    int    `json:"port"`
+	Token   string `json:"token"` // Webhooks must present this token
+	// Projects maps what an alert is about (a Datadog service, a Bugsnag
+	// project, a PagerDuty service) to a project path
+	Projects map[string]string `json:"projects,omitempty"`
+	// DefaultProjectPath handles alerts no project matches; empty drops them
+	DefaultProjectPath string `json:"defaultProjectPath,omitempty"`
 }
 
 // ProjectPreferences stores project-specific overrides
@@ -83,6 +234,38 @@ type ProjectPreferences struct {
 	ProjectPath  string       `json:"projectPath"`
 	ApprovalMode ApprovalMode `json:"approvalMode,omitempty"`
 	Model        string       `json:"model,omitempty"`
+	// EnabledMCPServers restricts which MCP servers are attached to this project's
+	// sessions. Nil means every configured server is attached.
+	EnabledMCPServers []string `json:"enabledMcpServers"`
+	// SystemPromptAppend is added after the user-level system prompt snippet
+	SystemPromptAppend string `json:"systemPromptAppend,omitempty"`
+	// MonitoringRules decides which alerts prompt this project's firefighter
+	// sessions while monitoring
+	MonitoringRules *MonitoringRules `json:"monitoringRules,omitempty"`
+	// AllowedTools run without approval and DeniedTools can't be used at all
+	// in this project's sessions, on top of the approval mode
+	AllowedTools []string `json:"allowedTools,omitempty"`
+	DeniedTools  []string `json:"deniedTools,omitempty"`
+}
+
+// MonitoringRules configures rule-based firefighter monitoring for a project
+type MonitoringRules struct {
+	// PollIntervalSeconds is how often Datadog and Bugsnag are polled; zero
+	// uses the monitor's default
+	PollIntervalSeconds int `json:"pollIntervalSeconds,omitempty"`
+	// BugsnagProjectIDs are the Bugsnag projects polled for open errors
+	BugsnagProjectIDs []string         `json:"bugsnagProjectIds,omitempty"`
+	Rules             []MonitoringRule `json:"rules"`
+}
+
+// MonitoringRule matches the alerts that should prompt the agent
+type MonitoringRule struct {
+	Name        string   `json:"name"`
+	Source      string   `json:"source,omitempty"`      // "datadog", "bugsnag", or empty for both
+	Tags        []string `json:"tags,omitempty"`        // Datadog monitor tags, all required
+	MinSeverity string   `json:"minSeverity,omitempty"` // e.g. "warning", "error", "P2"
+	// CooldownSeconds keeps the rule quiet after it fires; zero uses the default
+	CooldownSeconds int `json:"cooldownSeconds,omitempty"`
 }
 
 // Config manages application configuration
@@ -91,6 +274,19 @@ type Config struct {
 	configPath  string
 	preferences UserPreferences
 	projects    map[string]ProjectPreferences
+	// migrated is set when load upgraded an older config.json, which
+	// NewConfig then saves
+	migrated bool
+	// newer is set when config.json is from a newer build, which Save
+	// then refuses to overwrite
+	newer error
+
+	// secrets keeps API keys and tokens out of config.json; nil stores them
+	// there when the system has no keychain
+	secrets    secrets.Store
+	secretsMu  sync.Mutex
+	stored     map[string]string // Secrets known to be in the keychain
+	unresolved map[string]string // References whose secrets couldn't be read
 }
 
 // NewConfig creates a new Config instance
@@ -106,37 +302,64 @@ func NewConfig() (*Config, error) {
 	}
 
 	c := &Config{
-		configPath: filepath.Join(configDir, "config.json"),
-		preferences: UserPreferences{
-			AuthMethod:           AuthMethodAnthropicAPI,
-			GCPRegion:            "us-east5",
-			ApprovalMode:         ApprovalModeSuggest,
-			DefaultModel:         "sonnet",
-			Theme:                ThemeDark,
-			NotificationsEnabled: true,
-			MCPServers:           []MCPServer{},
-			OnboardingCompleted:  false,
-
-			// Memory management defaults
-			MaxMessagesPerSession: 1000,
-			ArchiveOldMessages:    true,
-			MaxSessionAgeDays:     30,
-			MaxTotalSessions:      100,
-			AutoCleanupSessions:   true,
-			MaxAgentsPerSession:   20,
-			KeepCompletedAgents:   false,
-		},
-		projects: make(map[string]ProjectPreferences),
+		configPath:  filepath.Join(configDir, "config.json"),
+		preferences: DefaultPreferences(),
+		projects:    make(map[string]ProjectPreferences),
+		secrets:     secrets.Keychain(keychainService),
 	}
 
-	// Load existing config if it exists
-	if err := c.load(); err != nil && !os.IsNotExist(err) {
+	// Load existing config if it exists. One from a newer build is used as
+	// far as it's understood, and left as it is.
+	if err := c.load(); errors.Is(err, ErrNewerSchema) {
+		logger.Error("Config won't be saved", "path", c.configPath, "error", err)
+	} else if err != nil && !os.IsNotExist(err) {
 		return nil, err
 	}
 
+	// Write back a config just migrated to the current schema
+	if c.migrated {
+		if err := c.Save(); err != nil {
+			logger.Warn("Failed to save migrated config", "error", err)
+		}
+	}
+
+	// Move secrets saved before the keychain was used out of config.json
+	if c.hasPlaintextSecrets() {
+		if err := c.Save(); err != nil {
+			logger.Warn("Failed to move secrets to the keychain", "error", err)
+		}
+	}
+
 	return c, nil
 }
 
+// DefaultPreferences returns the preferences used until the user changes them
+func DefaultPreferences() UserPreferences {
+	return UserPreferences{
+		AuthMethod:           AuthMethodAnthropicAPI,
+		GCPRegion:            "us-east5",
+		ApprovalMode:         ApprovalModeSuggest,
+		DefaultModel:         "sonnet",
+		Theme:                ThemeDark,
+		NotificationsEnabled: true,
+		MCPServers:           []MCPServer{},
+		OnboardingCompleted:  false,
+
+		// Memory management defaults
+		MaxMessagesPerSession: 1000,
+		ArchiveOldMessages:    true,
+		MaxSessionAgeDays:     30,
+		MaxTotalSessions:      100,
+		AutoCleanupSessions:   true,
+		MaxAgentsPerSession:   20,
+		KeepCompletedAgents:   false,
+
+		MaxCommandRuntimeSeconds: 600,
+
+		UpdateChannel: "stable",
+	}
+}
+
 // load reads configuration from disk
 func (c *Config) load() error {
 	c.mu.Lock()
@@ -147,6 +370,13 @@ func (c *Config) load() error {
 		return err
 	}
 
+	data, c.migrated, err = migrateConfig(c.configPath, data)
+	if errors.Is(err, ErrNewerSchema) {
+		c.newer = err
+	} else if err != nil {
+		return err
+	}
+
 	var saved struct {
 		Preferences UserPreferences              `json:"preferences"`
 		Projects    map[string]ProjectPreferences `json:"projects"`
@@ -157,12 +387,13 @@ func (c *Config) load() error {
 	}
 
 	c.preferences = saved.Preferences
+	c.resolveSecrets()
 	c.projects = saved.Projects
 	if c.projects == nil {
 		c.projects = make(map[string]ProjectPreferences)
 	}
 
-	return nil
+	return c.newer
 }
 
 // Save writes configuration to disk
@@ -170,18 +401,41 @@ func (c *Config) Save() error {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
-	data, err := json.MarshalIndent(struct {
-		Preferences UserPreferences               `json:"preferences"`
-		Projects    map[string]ProjectPreferences `json:"projects"`
-	}{
-		Preferences: c.preferences,
-		Projects:    c.projects,
-	}, "", "  ")
-	if err != nil {
-		return err
+	if c.newer != nil {
+		return fmt.Errorf("not overwriting %s: %w", c.configPath, c.newer)
 	}
 
-	return os.WriteFile(c.configPath, data, 0644)
+	prefs := c.preferences
+	c.storeSecrets(&prefs)
+
+	// The file is read again under its lock so that a newer schema is never
+	// overwritten and top-level fields this build doesn't know survive.
+	// Preferences and projects are written from this process's copy, so the
+	// last save wins over changes another window made since it was loaded.
+	// The file can hold secrets when there's no keychain, so only the user
+	// can read it.
+	return persist.Update(c.configPath, 0600, func(old []byte) ([]byte, error) {
+		doc := configDocument{}
+		if old != nil {
+			// A file that doesn't parse is replaced
+			if err := json.Unmarshal(old, &doc); err != nil || doc == nil {
+				doc = configDocument{}
+			}
+			if v, ok := doc["schemaVersion"].(float64); ok && int(v) > CurrentSchemaVersion {
+				return nil, fmt.Errorf("not overwriting %s: %w", c.configPath, ErrNewerSchema)
+			}
+		}
+
+		doc["schemaVersion"] = CurrentSchemaVersion
+		doc["preferences"] = prefs
+		doc["projects"] = c.projects
+		return json.MarshalIndent(doc, "", "  ")
+	})
+}
+
+// GetConfigPath returns the path of the config file
+func (c *Config) GetConfigPath() string {
+	return c.configPath
 }
 
 // GetPreferences returns a copy of user preferences
@@ -266,3 +520,165 @@ func (c *Config) SetMCPServers(servers []MCPServer) error {
 	c.mu.Unlock()
 	return c.Save()
 }
+
+// GetProjectMCPServers returns the MCP servers enabled for a project.
+// The second return value is false when the project has no restriction.
+func (c *Config) GetProjectMCPServers(projectPath string) ([]string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	prefs, ok := c.projects[projectPath]
+	if !ok || prefs.EnabledMCPServers == nil {
+		return nil, false
+	}
+
+	servers := make([]string, len(prefs.EnabledMCPServers))
+	copy(servers, prefs.EnabledMCPServers)
+	return servers, true
+}
+
+// SetProjectMCPServers sets the MCP servers enabled for a project.
+// Passing nil removes the restriction so every server is attached.
+func (c *Config) SetProjectMCPServers(projectPath string, servers []string) error {
+	c.mu.Lock()
+	prefs := c.projects[projectPath]
+	prefs.ProjectPath = projectPath
+	prefs.EnabledMCPServers = servers
+	c.projects[projectPath] = prefs
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// GetProjectSystemPrompt returns the system prompt addition for a project
+func (c *Config) GetProjectSystemPrompt(projectPath string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.projects[projectPath].SystemPromptAppend
+}
+
+// SetProjectSystemPrompt sets the system prompt addition for a project
+func (c *Config) SetProjectSystemPrompt(projectPath, prompt string) error {
+	c.mu.Lock()
+	prefs := c.projects[projectPath]
+	prefs.ProjectPath = projectPath
+	prefs.SystemPromptAppend = prompt
+	c.projects[projectPath] = prefs
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// GetProjectToolPolicy returns the tools allowed and denied for a project
+func (c *Config) GetProjectToolPolicy(projectPath string) (allowed, denied []string) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	prefs := c.projects[projectPath]
+	return append([]string(nil), prefs.AllowedTools...), append([]string(nil), prefs.DeniedTools...)
+}
+
+// GetProjectDefaults returns the model and approval mode for a project's
+// sessions, empty where it uses the user's
+func (c *Config) GetProjectDefaults(projectPath string) (model string, mode ApprovalMode) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	prefs := c.projects[projectPath]
+	return prefs.Model, prefs.ApprovalMode
+}
+
+// SetProjectDefaults sets the model and approval mode for a project's sessions
+func (c *Config) SetProjectDefaults(projectPath, model string, mode ApprovalMode) error {
+	c.mu.Lock()
+	prefs := c.projects[projectPath]
+	prefs.ProjectPath = projectPath
+	prefs.Model = model
+	prefs.ApprovalMode = mode
+	c.projects[projectPath] = prefs
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// SetProjectToolPolicy sets the tools allowed and denied for a project
+func (c *Config) SetProjectToolPolicy(projectPath string, allowed, denied []string) error {
+	c.mu.Lock()
+	prefs := c.projects[projectPath]
+	prefs.ProjectPath = projectPath
+	prefs.AllowedTools = allowed
+	prefs.DeniedTools = denied
+	c.projects[projectPath] = prefs
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// GetProjectMonitoringRules returns a project's monitoring rules, or nil if
+// it has none
+func (c *Config) GetProjectMonitoringRules(projectPath string) *MonitoringRules {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.projects[projectPath].MonitoringRules
+}
+
+// SetProjectMonitoringRules sets a project's monitoring rules. Passing nil
+// removes them.
+func (c *Config) SetProjectMonitoringRules(projectPath string, rules *MonitoringRules) error {
+	c.mu.Lock()
+	prefs := c.projects[projectPath]
+	prefs.ProjectPath = projectPath
+	prefs.MonitoringRules = rules
+	c.projects[projectPath] = prefs
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// GetAlertWebhookSettings returns the alert webhook settings, with the port
+// defaulted; the listener is off if none are saved
+func (c *Config) GetAlertWebhookSettings() AlertWebhookSettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var settings AlertWebhookSettings
+	if c.preferences.AlertWebhooks != nil {
+		settings = *c.preferences.AlertWebhooks
+	}
+	if settings.Port == 0 {
+		settings.Port = DefaultAlertWebhookPort
+	}
+	return settings
+}
+
+// GetProtectedPaths returns the globs whose writes need approval, or the
+// defaults if none are saved
+func (c *Config) GetProtectedPaths() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.preferences.ProtectedPaths == nil {
+		return append([]string(nil), DefaultProtectedPaths...)
+	}
+	return append([]string(nil), c.preferences.ProtectedPaths...)
+}
+
+// GetLogLevel returns the lowest level logged, "info" if none is saved
+func (c *Config) GetLogLevel() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.preferences.LogLevel == "" {
+		return "info"
+	}
+	return c.preferences.LogLevel
+}
+
+// SetLogLevel saves the lowest level logged
+func (c *Config) SetLogLevel(level string) error {
+	c.mu.Lock()
+	c.preferences.LogLevel = level
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// GetNotificationSettings returns the notification settings, or the defaults if none are saved
+func (c *Config) GetNotificationSettings() NotificationSettings {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.preferences.Notifications == nil {
+		return DefaultNotificationSettings()
+	}
+	return *c.preferences.Notifications
+}
@@ -593,6 +593,141 @@ func TestSetProjectPreferences(t *testing.T) {
 	}
 }
 
+func TestProjectMCPServers(t *testing.T) {
+	cfg, tempDir := setupTestConfig(t)
+	defer os.RemoveAll(tempDir)
+
+	// Projects without a mapping have every server enabled
+	if servers, ok := cfg.GetProjectMCPServers("/test/path"); ok || servers != nil {
+		t.Errorf("Expected no restriction, got %v (restricted=%v)", servers, ok)
+	}
+
+	if err := cfg.SetProjectPreferences(ProjectPreferences{ProjectPath: "/test/path", Model: "haiku"}); err != nil {
+		t.Fatalf("SetProjectPreferences() error = %v", err)
+	}
+	if err := cfg.SetProjectMCPServers("/test/path", []string{"github", "datadog"}); err != nil {
+		t.Fatalf("SetProjectMCPServers() error = %v", err)
+	}
+
+	servers, ok := cfg.GetProjectMCPServers("/test/path")
+	if !ok {
+		t.Fatal("Expected project to be restricted")
+	}
+	if len(servers) != 2 || servers[0] != "github" || servers[1] != "datadog" {
+		t.Errorf("Expected [github datadog], got %v", servers)
+	}
+
+	// Other project preferences are preserved
+	if cfg.GetProjectPreferences("/test/path").Model != "haiku" {
+		t.Error("Expected Model to be preserved")
+	}
+
+	// The returned slice is a copy
+	servers[0] = "changed"
+	if again, _ := cfg.GetProjectMCPServers("/test/path"); again[0] != "github" {
+		t.Errorf("Expected stored servers to be unchanged, got %v", again)
+	}
+}
+
+func TestProjectMCPServers_Persistence(t *testing.T) {
+	cfg, tempDir := setupTestConfig(t)
+	defer os.RemoveAll(tempDir)
+
+	// An empty list disables every server and must survive a reload
+	if err := cfg.SetProjectMCPServers("/none", []string{}); err != nil {
+		t.Fatalf("SetProjectMCPServers() error = %v", err)
+	}
+	if err := cfg.SetProjectMCPServers("/some", []string{"github"}); err != nil {
+		t.Fatalf("SetProjectMCPServers() error = %v", err)
+	}
+	if err := cfg.SetProjectMCPServers("/all", nil); err != nil {
+		t.Fatalf("SetProjectMCPServers() error = %v", err)
+	}
+
+	loaded := &Config{configPath: cfg.configPath}
+	if err := loaded.load(); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+
+	if servers, ok := loaded.GetProjectMCPServers("/none"); !ok || len(servers) != 0 {
+		t.Errorf("Expected empty restriction for /none, got %v (restricted=%v)", servers, ok)
+	}
+	if servers, ok := loaded.GetProjectMCPServers("/some"); !ok || len(servers) != 1 || servers[0] != "github" {
+		t.Errorf("Expected [github] for /some, got %v (restricted=%v)", servers, ok)
+	}
+	if _, ok := loaded.GetProjectMCPServers("/all"); ok {
+		t.Error("Expected /all to have no restriction")
+	}
+}
+
+func TestProjectSystemPrompt(t *testing.T) {
+	cfg, tempDir := setupTestConfig(t)
+	defer os.RemoveAll(tempDir)
+
+	if prompt := cfg.GetProjectSystemPrompt("/test/path"); prompt != "" {
+		t.Errorf("Expected no project system prompt, got %q", prompt)
+	}
+
+	if err := cfg.SetProjectMCPServers("/test/path", []string{"github"}); err != nil {
+		t.Fatalf("SetProjectMCPServers() error = %v", err)
+	}
+	if err := cfg.SetProjectSystemPrompt("/test/path", "Prefer table-driven tests."); err != nil {
+		t.Fatalf("SetProjectSystemPrompt() error = %v", err)
+	}
+
+	loaded := &Config{configPath: cfg.configPath}
+	if err := loaded.load(); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if prompt := loaded.GetProjectSystemPrompt("/test/path"); prompt != "Prefer table-driven tests." {
+		t.Errorf("Expected project system prompt to persist, got %q", prompt)
+	}
+	if servers, ok := loaded.GetProjectMCPServers("/test/path"); !ok || len(servers) != 1 {
+		t.Errorf("Expected MCP servers to be preserved, got %v", servers)
+	}
+}
+
+func TestGetNotificationSettings(t *testing.T) {
+	cfg, tempDir := setupTestConfig(t)
+	defer os.RemoveAll(tempDir)
+
+	if got := cfg.GetNotificationSettings(); got != DefaultNotificationSettings() {
+		t.Errorf("Expected default notification settings, got %+v", got)
+	}
+
+	prefs := cfg.GetPreferences()
+	prefs.Notifications = &NotificationSettings{OnError: true, QuietHoursEnabled: true, QuietHoursStart: "23:00", QuietHoursEnd: "07:00"}
+	if err := cfg.SetPreferences(prefs); err != nil {
+		t.Fatalf("SetPreferences() error = %v", err)
+	}
+
+	loaded := &Config{configPath: cfg.configPath}
+	if err := loaded.load(); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	got := loaded.GetNotificationSettings()
+	if got.OnWaiting || got.OnCompleted || !got.OnError {
+		t.Errorf("Expected only error notifications, got %+v", got)
+	}
+	if !got.QuietHoursEnabled || got.QuietHoursStart != "23:00" || got.QuietHoursEnd != "07:00" {
+		t.Errorf("Expected quiet hours 23:00-07:00 to persist, got %+v", got)
+	}
+}
+
+func TestNotificationSettingsDefaultNewRules(t *testing.T) {
+	// Saved before alert notifications could be turned off
+	var n NotificationSettings
+	if err := json.Unmarshal([]byte(`{"onWaiting": false, "onCompleted": true, "onError": true, "minTurnSeconds": 10}`), &n); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !n.OnAlert {
+		t.Errorf("Expected alert notifications on by default")
+	}
+	if n.OnWaiting || n.MinTurnSeconds != 10 {
+		t.Errorf("Expected saved settings to be kept, got %+v", n)
+	}
+}
+
 func TestConfigFilePath(t *testing.T) {
 	// Save original home dir
 	originalHome := os.Getenv("HOME")
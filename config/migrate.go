@@ -0,0 +1,102 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"boatman/persist"
+)
+
+// CurrentSchemaVersion is the layout of config.json this build writes.
+// Files saved before versioning have no schemaVersion and are version 0.
+const CurrentSchemaVersion = 1
+
+// ErrNewerSchema is returned for a config.json saved by a newer build.
+// It's loaded as far as this build understands it, but not saved, since
+// that would drop the settings the newer build added.
+var ErrNewerSchema = errors.New("config was saved by a newer version")
+
+// configDocument is config.json decoded without the current types, so a
+// migration can restructure what they would no longer read
+type configDocument map[string]interface{}
+
+// migrations[i] upgrades a version i document to version i+1. Add one,
+// and bump CurrentSchemaVersion, whenever a change to the preferences
+// needs more than a new field's zero value.
+var migrations = []func(doc configDocument) error{
+	fillDefaultPreferences,
+}
+
+// migrateConfig upgrades config.json's contents to CurrentSchemaVersion,
+// backing up the old file next to it first. It returns data unchanged,
+// and false, when the file is already current, and ErrNewerSchema along
+// with them when it's newer.
+func migrateConfig(configPath string, data []byte) ([]byte, bool, error) {
+	var doc configDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, false, err
+	}
+	if doc == nil {
+		doc = configDocument{}
+	}
+
+	version := 0
+	if v, ok := doc["schemaVersion"].(float64); ok {
+		version = int(v)
+	}
+	if version > CurrentSchemaVersion {
+		return data, false, fmt.Errorf("%w: schema version %d, this build writes %d", ErrNewerSchema, version, CurrentSchemaVersion)
+	}
+	if version == CurrentSchemaVersion {
+		return data, false, nil
+	}
+
+	// Keep the old file in case the upgrade loses something. It may hold
+	// secrets saved before the keychain, so only the user can read it.
+	backupPath := fmt.Sprintf("%s.v%d.bak", configPath, version)
+	if err := persist.WriteFile(backupPath, data, 0600); err != nil {
+		return nil, false, fmt.Errorf("failed to back up config before migrating: %w", err)
+	}
+
+	for ; version < CurrentSchemaVersion; version++ {
+		if err := migrations[version](doc); err != nil {
+			return nil, false, fmt.Errorf("failed to migrate config from version %d: %w", version, err)
+		}
+	}
+	doc["schemaVersion"] = CurrentSchemaVersion
+
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return nil, false, err
+	}
+	logger.Info("Migrated config", "schemaVersion", CurrentSchemaVersion, "backup", backupPath)
+	return migrated, true, nil
+}
+
+// fillDefaultPreferences gives preferences missing from an unversioned
+// config their defaults. They were added after the file was saved, and
+// loading them as zero values turned on-by-default settings off.
+func fillDefaultPreferences(doc configDocument) error {
+	prefs, _ := doc["preferences"].(map[string]interface{})
+	if prefs == nil {
+		prefs = make(map[string]interface{})
+	}
+
+	data, err := json.Marshal(DefaultPreferences())
+	if err != nil {
+		return err
+	}
+	var defaults map[string]interface{}
+	if err := json.Unmarshal(data, &defaults); err != nil {
+		return err
+	}
+	for key, value := range defaults {
+		if _, ok := prefs[key]; !ok {
+			prefs[key] = value
+		}
+	}
+
+	doc["preferences"] = prefs
+	return nil
+}
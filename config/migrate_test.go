@@ -0,0 +1,120 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMigrateUnversionedConfig(t *testing.T) {
+	cfg, tempDir := setupTestConfig(t)
+	defer os.RemoveAll(tempDir)
+
+	// Saved before the memory management settings existed
+	original := `{"preferences": {"apiKey": "", "defaultModel": "opus", "notificationsEnabled": false}, "projects": {}}`
+	if err := os.WriteFile(cfg.configPath, []byte(original), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	if err := cfg.load(); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if !cfg.migrated {
+		t.Fatal("Expected an unversioned config to be migrated")
+	}
+
+	prefs := cfg.GetPreferences()
+	if prefs.DefaultModel != "opus" || prefs.NotificationsEnabled {
+		t.Errorf("Expected saved preferences kept, got model %q and notifications %v", prefs.DefaultModel, prefs.NotificationsEnabled)
+	}
+	if prefs.MaxMessagesPerSession != 1000 || !prefs.ArchiveOldMessages || !prefs.AutoCleanupSessions {
+		t.Errorf("Expected missing preferences to get their defaults, got %+v", prefs)
+	}
+
+	backup, err := os.ReadFile(cfg.configPath + ".v0.bak")
+	if err != nil {
+		t.Fatalf("Expected a backup of the old config: %v", err)
+	}
+	if string(backup) != original {
+		t.Errorf("Expected the backup to hold the old config, got %s", backup)
+	}
+	if info, _ := os.Stat(cfg.configPath + ".v0.bak"); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the backup readable only by the user, got %v", info.Mode().Perm())
+	}
+
+	// Once saved, the config is current
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if !strings.Contains(readConfigFile(t, cfg), `"schemaVersion": 1`) {
+		t.Error("Expected the saved config to record its schema version")
+	}
+	if info, _ := os.Stat(cfg.configPath); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the config readable only by the user, got %v", info.Mode().Perm())
+	}
+	reloaded := &Config{configPath: cfg.configPath}
+	if err := reloaded.load(); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if reloaded.migrated || reloaded.GetPreferences().DefaultModel != "opus" {
+		t.Errorf("Expected a current config to load without migrating, got migrated %v", reloaded.migrated)
+	}
+}
+
+func TestMigrateNewerConfig(t *testing.T) {
+	cfg, tempDir := setupTestConfig(t)
+	defer os.RemoveAll(tempDir)
+
+	newer := `{"schemaVersion": 99, "preferences": {"defaultModel": "haiku"}}`
+	if err := os.WriteFile(cfg.configPath, []byte(newer), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	if err := cfg.load(); !errors.Is(err, ErrNewerSchema) {
+		t.Fatalf("Expected ErrNewerSchema, got %v", err)
+	}
+	if cfg.migrated || cfg.GetPreferences().DefaultModel != "haiku" {
+		t.Errorf("Expected a newer config loaded as is, got migrated %v", cfg.migrated)
+	}
+	if _, err := os.Stat(cfg.configPath + ".v99.bak"); !os.IsNotExist(err) {
+		t.Errorf("Expected no backup without a migration, got %v", err)
+	}
+
+	// Saving would downgrade it and drop what the newer build added
+	if err := cfg.Save(); !errors.Is(err, ErrNewerSchema) {
+		t.Errorf("Expected Save to refuse a newer config, got %v", err)
+	}
+	if got := readConfigFile(t, cfg); got != newer {
+		t.Errorf("Expected the newer config left as it was, got %s", got)
+	}
+}
+
+func TestSaveKeepsUnknownFields(t *testing.T) {
+	cfg, tempDir := setupTestConfig(t)
+	defer os.RemoveAll(tempDir)
+
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Another build adds a setting while this one is running
+	added := strings.Replace(readConfigFile(t, cfg), "{", `{"workspaces": ["a"],`, 1)
+	if err := os.WriteFile(cfg.configPath, []byte(added), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if !strings.Contains(readConfigFile(t, cfg), `"workspaces"`) {
+		t.Error("Expected a setting this build doesn't know to be kept")
+	}
+
+	// Or a newer build saves its schema
+	if err := os.WriteFile(cfg.configPath, []byte(`{"schemaVersion": 99}`), 0600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	if err := cfg.Save(); !errors.Is(err, ErrNewerSchema) {
+		t.Errorf("Expected Save to refuse a config a newer build saved since, got %v", err)
+	}
+}
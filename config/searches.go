@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// SavedSearch is a named session search that can be pinned to the sidebar
+// as a view
+type SavedSearch struct {
+	ID     string       `json:"id"`
+	Name   string       `json:"name"`
+	Filter SearchFilter `json:"filter"`
+	Pinned bool         `json:"pinned,omitempty"`
+}
+
+// SearchFilter is what a saved search matches
+type SearchFilter struct {
+	Query       string   `json:"query,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	ProjectPath string   `json:"projectPath,omitempty"`
+	IsFavorite  *bool    `json:"isFavorite,omitempty"`
+	FromDate    string   `json:"fromDate,omitempty"` // YYYY-MM-DD
+	ToDate      string   `json:"toDate,omitempty"`   // YYYY-MM-DD
+	// UpdatedWithinDays keeps views like "this week" current; zero doesn't limit
+	UpdatedWithinDays int `json:"updatedWithinDays,omitempty"`
+
+	Content      string   `json:"content,omitempty"`
+	Roles        []string `json:"roles,omitempty"`
+	HasToolError bool     `json:"hasToolError,omitempty"`
+}
+
+// GetSavedSearches returns the saved searches in the order they were added
+func (c *Config) GetSavedSearches() []SavedSearch {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]SavedSearch{}, c.preferences.SavedSearches...)
+}
+
+// GetSavedSearch returns the saved search with the given ID
+func (c *Config) GetSavedSearch(id string) (SavedSearch, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, search := range c.preferences.SavedSearches {
+		if search.ID == id {
+			return search, nil
+		}
+	}
+	return SavedSearch{}, fmt.Errorf("saved search not found: %s", id)
+}
+
+// SaveSearch adds a saved search, or replaces the one with the same ID. A
+// search without an ID is given one. It returns the search as saved.
+func (c *Config) SaveSearch(search SavedSearch) (SavedSearch, error) {
+	search.Name = strings.TrimSpace(search.Name)
+	if search.Name == "" {
+		return SavedSearch{}, fmt.Errorf("saved searches need a name")
+	}
+	if search.Filter.UpdatedWithinDays < 0 {
+		return SavedSearch{}, fmt.Errorf("updated within days can't be negative")
+	}
+
+	c.mu.Lock()
+	if search.ID == "" {
+		search.ID = uuid.New().String()
+		c.preferences.SavedSearches = append(c.copySavedSearchesLocked(), search)
+	} else if i := c.savedSearchIndexLocked(search.ID); i >= 0 {
+		c.preferences.SavedSearches = c.copySavedSearchesLocked()
+		c.preferences.SavedSearches[i] = search
+	} else {
+		c.mu.Unlock()
+		return SavedSearch{}, fmt.Errorf("saved search not found: %s", search.ID)
+	}
+	c.mu.Unlock()
+	return search, c.Save()
+}
+
+// DeleteSavedSearch removes a saved search
+func (c *Config) DeleteSavedSearch(id string) error {
+	c.mu.Lock()
+	i := c.savedSearchIndexLocked(id)
+	if i < 0 {
+		c.mu.Unlock()
+		return fmt.Errorf("saved search not found: %s", id)
+	}
+	searches := c.preferences.SavedSearches
+	c.preferences.SavedSearches = append(searches[:i:i], searches[i+1:]...)
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// SetSavedSearchPinned pins a saved search to the sidebar as a view, or unpins it
+func (c *Config) SetSavedSearchPinned(id string, pinned bool) error {
+	c.mu.Lock()
+	i := c.savedSearchIndexLocked(id)
+	if i < 0 {
+		c.mu.Unlock()
+		return fmt.Errorf("saved search not found: %s", id)
+	}
+	c.preferences.SavedSearches = c.copySavedSearchesLocked()
+	c.preferences.SavedSearches[i].Pinned = pinned
+	c.mu.Unlock()
+	return c.Save()
+}
+
+// savedSearchIndexLocked returns the position of a saved search, or -1
+// Note: This method expects the caller to hold c.mu lock
+func (c *Config) savedSearchIndexLocked(id string) int {
+	for i, search := range c.preferences.SavedSearches {
+		if search.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// copySavedSearchesLocked copies the saved searches so changing them doesn't
+// change copies of the preferences already handed out
+// Note: This method expects the caller to hold c.mu lock
+func (c *Config) copySavedSearchesLocked() []SavedSearch {
+	return append([]SavedSearch(nil), c.preferences.SavedSearches...)
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestSavedSearches(t *testing.T) {
+	cfg, tempDir := setupTestConfig(t)
+	defer os.RemoveAll(tempDir)
+
+	favorite := true
+	saved, err := cfg.SaveSearch(SavedSearch{
+		Name:   "  Favorites with errors this week ",
+		Filter: SearchFilter{IsFavorite: &favorite, HasToolError: true, UpdatedWithinDays: 7},
+	})
+	if err != nil {
+		t.Fatalf("SaveSearch() error = %v", err)
+	}
+	if saved.ID == "" || saved.Name != "Favorites with errors this week" {
+		t.Errorf("Expected an ID and a trimmed name, got %+v", saved)
+	}
+	if _, err := cfg.SaveSearch(SavedSearch{Name: "nginx", Filter: SearchFilter{Content: "nginx.conf"}}); err != nil {
+		t.Fatalf("SaveSearch() error = %v", err)
+	}
+
+	// Searches are kept in order and survive a reload
+	if err := cfg.SetSavedSearchPinned(saved.ID, true); err != nil {
+		t.Fatalf("SetSavedSearchPinned() error = %v", err)
+	}
+	loaded := &Config{configPath: cfg.configPath}
+	if err := loaded.load(); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	searches := loaded.GetSavedSearches()
+	if len(searches) != 2 || searches[0].ID != saved.ID || searches[1].Name != "nginx" {
+		t.Fatalf("Expected both searches in order, got %+v", searches)
+	}
+	if !searches[0].Pinned || searches[0].Filter.UpdatedWithinDays != 7 || !*searches[0].Filter.IsFavorite {
+		t.Errorf("Expected the search saved as it was, got %+v", searches[0])
+	}
+
+	// Updating replaces the search with the same ID
+	saved.Name = "Recent favorites"
+	if _, err := cfg.SaveSearch(saved); err != nil {
+		t.Fatalf("SaveSearch() error = %v", err)
+	}
+	if search, _ := cfg.GetSavedSearch(saved.ID); search.Name != "Recent favorites" {
+		t.Errorf("Expected the renamed search, got %+v", search)
+	}
+
+	if err := cfg.DeleteSavedSearch(saved.ID); err != nil {
+		t.Fatalf("DeleteSavedSearch() error = %v", err)
+	}
+	if searches := cfg.GetSavedSearches(); len(searches) != 1 || searches[0].Name != "nginx" {
+		t.Errorf("Expected only nginx left, got %+v", searches)
+	}
+}
+
+func TestSavedSearchErrors(t *testing.T) {
+	cfg, tempDir := setupTestConfig(t)
+	defer os.RemoveAll(tempDir)
+
+	tests := []struct {
+		name string
+		run  func() error
+	}{
+		{"missing name", func() error { _, err := cfg.SaveSearch(SavedSearch{Name: " "}); return err }},
+		{"negative days", func() error {
+			_, err := cfg.SaveSearch(SavedSearch{Name: "x", Filter: SearchFilter{UpdatedWithinDays: -1}})
+			return err
+		}},
+		{"unknown update", func() error { _, err := cfg.SaveSearch(SavedSearch{ID: "missing", Name: "x"}); return err }},
+		{"unknown delete", func() error { return cfg.DeleteSavedSearch("missing") }},
+		{"unknown pin", func() error { return cfg.SetSavedSearchPinned("missing", true) }},
+		{"unknown get", func() error { _, err := cfg.GetSavedSearch("missing"); return err }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"boatman/logging"
+	"boatman/secrets"
+)
+
+// keychainService is the service Boatman's secrets are stored under
+const keychainService = "boatman"
+
+// sessionKeyName is the keychain entry holding the session encryption key
+const sessionKeyName = "sessionKey"
+
+// logger logs config problems that don't stop the app, like an unreadable keychain
+var logger = logging.For("config")
+
+// secretFields returns the preferences kept in the keychain, by entry name
+func secretFields(prefs *UserPreferences) map[string]*string {
+	return map[string]*string{
+		"apiKey":           &prefs.APIKey,
+		"datadogAPIKey":    &prefs.DatadogAPIKey,
+		"datadogAppKey":    &prefs.DatadogAppKey,
+		"bugsnagAPIKey":    &prefs.BugsnagAPIKey,
+		"oktaClientSecret": &prefs.OktaClientSecret,
+		"linearAPIKey":     &prefs.LinearAPIKey,
+		"githubToken":      &prefs.GitHubToken,
+		"gitlabToken":      &prefs.GitLabToken,
+	}
+}
+
+// resolveSecrets replaces the keychain references in loaded preferences with
+// the secrets they refer to. References that can't be read are kept so the
+// next save doesn't drop them.
+// Note: This method expects the caller to hold c.mu lock
+func (c *Config) resolveSecrets() {
+	c.secretsMu.Lock()
+	defer c.secretsMu.Unlock()
+	c.initSecretsLocked()
+
+	for name, field := range secretFields(&c.preferences) {
+		entry, ok := secrets.ParseRef(*field)
+		if !ok {
+			continue
+		}
+		*field = ""
+		if c.secrets == nil {
+			logger.Warn("No keychain to read a saved secret from", "secret", name)
+			c.unresolved[name] = secrets.Ref(entry)
+			continue
+		}
+		value, err := c.secrets.Get(entry)
+		if err != nil {
+			logger.Warn("Failed to read secret from the keychain", "secret", name, "error", err)
+			c.unresolved[name] = secrets.Ref(entry)
+			continue
+		}
+		*field = value
+		if entry == name {
+			c.stored[name] = value
+		}
+	}
+}
+
+// storeSecrets moves the secrets in prefs into the keychain, leaving
+// references in their place. Secrets the keychain won't take stay in prefs
+// so they aren't lost.
+func (c *Config) storeSecrets(prefs *UserPreferences) {
+	c.secretsMu.Lock()
+	defer c.secretsMu.Unlock()
+	c.initSecretsLocked()
+
+	for name, field := range secretFields(prefs) {
+		value := *field
+		if value == "" {
+			if ref, ok := c.unresolved[name]; ok {
+				*field = ref
+				continue
+			}
+			if _, ok := c.stored[name]; ok {
+				if err := c.secrets.Delete(name); err != nil {
+					logger.Warn("Failed to remove secret from the keychain", "secret", name, "error", err)
+				}
+				delete(c.stored, name)
+			}
+			continue
+		}
+		if c.secrets == nil {
+			continue
+		}
+
+		if c.stored[name] != value {
+			if err := c.secrets.Set(name, value); err != nil {
+				logger.Warn("Failed to save secret to the keychain, keeping it in config.json", "secret", name, "error", err)
+				continue
+			}
+			c.stored[name] = value
+		}
+		delete(c.unresolved, name)
+		*field = secrets.Ref(name)
+	}
+}
+
+// initSecretsLocked makes the maps tracking keychain entries
+// Note: This method expects the caller to hold c.secretsMu lock
+func (c *Config) initSecretsLocked() {
+	if c.stored == nil {
+		c.stored = make(map[string]string)
+		c.unresolved = make(map[string]string)
+	}
+}
+
+// hasPlaintextSecrets reports whether loaded preferences hold secrets that
+// belong in the keychain, e.g. ones saved before it was used
+func (c *Config) hasPlaintextSecrets() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	c.secretsMu.Lock()
+	defer c.secretsMu.Unlock()
+
+	if c.secrets == nil {
+		return false
+	}
+	for name, field := range secretFields(&c.preferences) {
+		if *field != "" && c.stored[name] != *field {
+			return true
+		}
+	}
+	return false
+}
+
+// KeychainSessionKey returns the session encryption key kept in the keychain.
+// With create set, a new key is stored if there's none; otherwise nil is
+// returned.
+func (c *Config) KeychainSessionKey(create bool) ([]byte, error) {
+	if c.secrets == nil {
+		return nil, fmt.Errorf("no keychain available to hold the session key")
+	}
+
+	encoded, err := c.secrets.Get(sessionKeyName)
+	if err == nil {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid session key in the keychain: %w", err)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, secrets.ErrNotFound) {
+		return nil, err
+	}
+	if !create {
+		return nil, nil
+	}
+
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate session key: %w", err)
+	}
+	if err := c.secrets.Set(sessionKeyName, base64.StdEncoding.EncodeToString(key)); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"boatman/secrets"
+)
+
+// memoryStore is a keychain held in memory
+type memoryStore struct {
+	entries map[string]string
+	failSet bool
+	failGet bool
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{entries: make(map[string]string)}
+}
+
+func (m *memoryStore) Get(name string) (string, error) {
+	if m.failGet {
+		return "", errors.New("keychain locked")
+	}
+	value, ok := m.entries[name]
+	if !ok {
+		return "", secrets.ErrNotFound
+	}
+	return value, nil
+}
+
+func (m *memoryStore) Set(name, value string) error {
+	if m.failSet {
+		return errors.New("keychain locked")
+	}
+	m.entries[name] = value
+	return nil
+}
+
+func (m *memoryStore) Delete(name string) error {
+	delete(m.entries, name)
+	return nil
+}
+
+// readConfigFile returns config.json as text
+func readConfigFile(t *testing.T, cfg *Config) string {
+	t.Helper()
+	data, err := os.ReadFile(cfg.configPath)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	return string(data)
+}
+
+func TestSecretsStoredInKeychain(t *testing.T) {
+	cfg, tempDir := setupTestConfig(t)
+	defer os.RemoveAll(tempDir)
+	store := newMemoryStore()
+	cfg.secrets = store
+
+	prefs := cfg.GetPreferences()
+	prefs.APIKey = "sk-ant-secret"
+	prefs.GitHubToken = "ghp_token"
+	if err := cfg.SetPreferences(prefs); err != nil {
+		t.Fatalf("SetPreferences() error = %v", err)
+	}
+
+	saved := readConfigFile(t, cfg)
+	if strings.Contains(saved, "sk-ant-secret") || strings.Contains(saved, "ghp_token") {
+		t.Errorf("Expected no secrets in config.json, got %s", saved)
+	}
+	if !strings.Contains(saved, `"apiKey": "keychain:apiKey"`) {
+		t.Errorf("Expected a keychain reference in config.json, got %s", saved)
+	}
+	if store.entries["apiKey"] != "sk-ant-secret" || store.entries["githubToken"] != "ghp_token" {
+		t.Errorf("Expected the secrets in the keychain, got %v", store.entries)
+	}
+	if cfg.GetAPIKey() != "sk-ant-secret" {
+		t.Errorf("Expected the API key still available, got %q", cfg.GetAPIKey())
+	}
+
+	loaded := &Config{configPath: cfg.configPath, secrets: store}
+	if err := loaded.load(); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if loaded.GetAPIKey() != "sk-ant-secret" || loaded.GetPreferences().GitHubToken != "ghp_token" {
+		t.Errorf("Expected the secrets read back from the keychain, got %+v", loaded.GetPreferences())
+	}
+
+	// Clearing a secret removes it from the keychain
+	prefs = loaded.GetPreferences()
+	prefs.GitHubToken = ""
+	if err := loaded.SetPreferences(prefs); err != nil {
+		t.Fatalf("SetPreferences() error = %v", err)
+	}
+	if _, ok := store.entries["githubToken"]; ok {
+		t.Error("Expected the cleared token removed from the keychain")
+	}
+}
+
+func TestPlaintextSecretsMigrate(t *testing.T) {
+	cfg, tempDir := setupTestConfig(t)
+	defer os.RemoveAll(tempDir)
+
+	// Saved before there was a keychain
+	prefs := cfg.GetPreferences()
+	prefs.APIKey = "sk-ant-secret"
+	if err := cfg.SetPreferences(prefs); err != nil {
+		t.Fatalf("SetPreferences() error = %v", err)
+	}
+	if !strings.Contains(readConfigFile(t, cfg), "sk-ant-secret") {
+		t.Fatal("Expected the key in config.json without a keychain")
+	}
+
+	store := newMemoryStore()
+	loaded := &Config{configPath: cfg.configPath, secrets: store}
+	if err := loaded.load(); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if !loaded.hasPlaintextSecrets() {
+		t.Fatal("Expected the plaintext key flagged for migration")
+	}
+	if err := loaded.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if strings.Contains(readConfigFile(t, loaded), "sk-ant-secret") || store.entries["apiKey"] != "sk-ant-secret" {
+		t.Errorf("Expected the key moved to the keychain, got %v", store.entries)
+	}
+	if loaded.hasPlaintextSecrets() {
+		t.Error("Expected nothing left to migrate")
+	}
+}
+
+func TestKeychainFailuresKeepSecrets(t *testing.T) {
+	cfg, tempDir := setupTestConfig(t)
+	defer os.RemoveAll(tempDir)
+	store := newMemoryStore()
+	store.failSet = true
+	cfg.secrets = store
+
+	// A keychain that won't take the key leaves it in config.json
+	prefs := cfg.GetPreferences()
+	prefs.APIKey = "sk-ant-secret"
+	if err := cfg.SetPreferences(prefs); err != nil {
+		t.Fatalf("SetPreferences() error = %v", err)
+	}
+	if !strings.Contains(readConfigFile(t, cfg), "sk-ant-secret") {
+		t.Error("Expected the key kept in config.json")
+	}
+
+	// A reference that can't be read survives the next save
+	store.failSet = false
+	if err := cfg.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	store.failGet = true
+	loaded := &Config{configPath: cfg.configPath, secrets: store}
+	if err := loaded.load(); err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+	if loaded.GetAPIKey() != "" {
+		t.Errorf("Expected no key while the keychain is locked, got %q", loaded.GetAPIKey())
+	}
+	if err := loaded.CompleteOnboarding(); err != nil {
+		t.Fatalf("CompleteOnboarding() error = %v", err)
+	}
+	if !strings.Contains(readConfigFile(t, loaded), `"apiKey": "keychain:apiKey"`) {
+		t.Error("Expected the unreadable reference kept in config.json")
+	}
+}
+
+func TestKeychainSessionKey(t *testing.T) {
+	cfg, tempDir := setupTestConfig(t)
+	defer os.RemoveAll(tempDir)
+	store := newMemoryStore()
+	cfg.secrets = store
+
+	key, err := cfg.KeychainSessionKey(false)
+	if err != nil || key != nil {
+		t.Fatalf("Expected no key before one is created, got %v, %v", key, err)
+	}
+
+	key, err = cfg.KeychainSessionKey(true)
+	if err != nil || len(key) != 32 {
+		t.Fatalf("Expected a new 32 byte key, got %d bytes, %v", len(key), err)
+	}
+	again, err := cfg.KeychainSessionKey(true)
+	if err != nil || string(again) != string(key) {
+		t.Errorf("Expected the same key read back, got %v", err)
+	}
+	if store.entries[sessionKeyName] == "" {
+		t.Error("Expected the key stored in the keychain")
+	}
+
+	cfg.secrets = nil
+	if _, err := cfg.KeychainSessionKey(true); err == nil {
+		t.Error("Expected an error without a keychain")
+	}
+}
@@ -0,0 +1,239 @@
+package diagnostics
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+)
+
+// redactedPlaceholder replaces secret values in the bundle
+const redactedPlaceholder = "[REDACTED]"
+
+// secretKey matches config keys whose values are credentials
+var secretKey = regexp.MustCompile(`(?i)(key|token|secret|password|credential)`)
+
+// Options picks what goes in a diagnostics bundle. A manifest describing the
+// bundle is always included.
+type Options struct {
+	Logs         bool `json:"logs"`
+	Config       bool `json:"config"`
+	CLIVersion   bool `json:"cliVersion"`
+	SessionStats bool `json:"sessionStats"`
+	MCPConfig    bool `json:"mcpConfig"`
+}
+
+// Sources are where a bundle's contents come from. Each is only read when
+// its part is included.
+type Sources struct {
+	AppVersion    string
+	LogDir        string
+	ConfigFile    string
+	MCPConfigFile string
+	CLIVersion    func() (string, error)
+	SessionStats  func() (any, error)
+	// Redact masks credentials in free text such as log lines
+	Redact func(string) string
+}
+
+// Manifest describes a bundle: what it holds and what couldn't be collected
+type Manifest struct {
+	AppVersion string            `json:"appVersion"`
+	CreatedAt  time.Time         `json:"createdAt"`
+	OS         string            `json:"os"`
+	Arch       string            `json:"arch"`
+	Files      []string          `json:"files"`
+	Errors     map[string]string `json:"errors,omitempty"`
+}
+
+// Export writes a zip of the chosen diagnostics to path. Parts that can't be
+// collected are noted in the manifest rather than failing the export.
+func Export(path string, options Options, sources Sources) (*Manifest, error) {
+	redact := sources.Redact
+	if redact == nil {
+		redact = func(text string) string { return text }
+	}
+
+	manifest := &Manifest{
+		AppVersion: sources.AppVersion,
+		CreatedAt:  time.Now(),
+		OS:         runtime.GOOS,
+		Arch:       runtime.GOARCH,
+	}
+	files := make(map[string][]byte)
+	fail := func(part string, err error) {
+		if manifest.Errors == nil {
+			manifest.Errors = make(map[string]string)
+		}
+		manifest.Errors[part] = err.Error()
+	}
+
+	if options.Logs {
+		if err := collectLogs(sources.LogDir, redact, files); err != nil {
+			fail("logs", err)
+		}
+	}
+	if options.Config {
+		if data, err := readRedactedJSON(sources.ConfigFile, redact); err != nil {
+			fail("config", err)
+		} else {
+			files["config.json"] = data
+		}
+	}
+	if options.MCPConfig {
+		if data, err := readRedactedJSON(sources.MCPConfigFile, redact); err != nil {
+			fail("mcpConfig", err)
+		} else {
+			files["mcp.json"] = data
+		}
+	}
+	if options.CLIVersion && sources.CLIVersion != nil {
+		if version, err := sources.CLIVersion(); err != nil {
+			fail("cliVersion", err)
+		} else {
+			files["claude-cli-version.txt"] = []byte(strings.TrimSpace(version) + "\n")
+		}
+	}
+	if options.SessionStats && sources.SessionStats != nil {
+		stats, err := sources.SessionStats()
+		if err == nil {
+			var data []byte
+			if data, err = json.MarshalIndent(stats, "", "  "); err == nil {
+				files["sessions.json"] = data
+			}
+		}
+		if err != nil {
+			fail("sessionStats", err)
+		}
+	}
+
+	for name := range files {
+		manifest.Files = append(manifest.Files, name)
+	}
+	sort.Strings(manifest.Files)
+
+	if err := writeZip(path, manifest, files); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// collectLogs adds the current and rotated log files, redacted
+func collectLogs(dir string, redact func(string) string, files map[string][]byte) error {
+	if dir == "" {
+		return fmt.Errorf("no log directory")
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.log*"))
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no log files in %s", dir)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+		}
+		files["logs/"+filepath.Base(path)] = []byte(redact(string(data)))
+	}
+	return nil
+}
+
+// readRedactedJSON reads a JSON config file with its secrets masked
+func readRedactedJSON(path string, redact func(string) string) ([]byte, error) {
+	if path == "" {
+		return nil, fmt.Errorf("no config file")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filepath.Base(path), err)
+	}
+	return RedactJSON(data, redact)
+}
+
+// RedactJSON masks the values of credential-like keys and of environment
+// maps, which hold tokens for MCP servers, and redacts the remaining strings
+func RedactJSON(data []byte, redact func(string) string) ([]byte, error) {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return json.MarshalIndent(redactValue(value, false, redact), "", "  ")
+}
+
+// redactValue walks a decoded JSON value. secret masks every non-empty
+// string beneath it.
+func redactValue(value any, secret bool, redact func(string) string) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, child := range v {
+			v[key] = redactValue(child, secret || secretKey.MatchString(key) || strings.EqualFold(key, "env"), redact)
+		}
+		return v
+	case []any:
+		for i, child := range v {
+			v[i] = redactValue(child, secret, redact)
+		}
+		return v
+	case string:
+		if secret && v != "" {
+			return redactedPlaceholder
+		}
+		return redact(v)
+	}
+	return value
+}
+
+// writeZip writes the manifest and files to a zip at path, removing it if
+// the write fails
+func writeZip(path string, manifest *Manifest, files map[string][]byte) (err error) {
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create bundle: %w", err)
+	}
+	defer func() {
+		if closeErr := out.Close(); err == nil && closeErr != nil {
+			err = fmt.Errorf("failed to write bundle: %w", closeErr)
+		}
+		if err != nil {
+			os.Remove(path)
+		}
+	}()
+
+	zw := zip.NewWriter(out)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := addFile(zw, "manifest.json", data); err != nil {
+		return err
+	}
+	for _, name := range manifest.Files {
+		if err := addFile(zw, name, files[name]); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("failed to write bundle: %w", err)
+	}
+	return nil
+}
+
+// addFile adds one file to the zip
+func addFile(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("failed to add %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package diagnostics
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRedactJSON(t *testing.T) {
+	input := `{
+		"preferences": {"apiKey": "sk-secret", "approvalMode": "suggest", "maxTokens": 100, "linearApiKey": ""},
+		"alertWebhooks": {"token": "hook-token", "port": 8487},
+		"mcpServers": {"github": {"command": "npx", "args": ["--key=abc"], "env": {"GITHUB_PERSONAL_ACCESS": "ghp_x"}}}
+	}`
+	redact := func(text string) string { return strings.ReplaceAll(text, "abc", "[REDACTED]") }
+
+	data, err := RedactJSON([]byte(input), redact)
+	if err != nil {
+		t.Fatalf("RedactJSON failed: %v", err)
+	}
+
+	var got struct {
+		Preferences   map[string]any `json:"preferences"`
+		AlertWebhooks map[string]any `json:"alertWebhooks"`
+		MCPServers    map[string]struct {
+			Command string            `json:"command"`
+			Args    []string          `json:"args"`
+			Env     map[string]string `json:"env"`
+		} `json:"mcpServers"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Failed to parse redacted JSON: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		got      any
+		expected any
+	}{
+		{"secret key", got.Preferences["apiKey"], redactedPlaceholder},
+		{"empty secret left empty", got.Preferences["linearApiKey"], ""},
+		{"ordinary value kept", got.Preferences["approvalMode"], "suggest"},
+		{"numbers kept", got.Preferences["maxTokens"], float64(100)},
+		{"nested token", got.AlertWebhooks["token"], redactedPlaceholder},
+		{"env values", got.MCPServers["github"].Env["GITHUB_PERSONAL_ACCESS"], redactedPlaceholder},
+		{"free text redacted", got.MCPServers["github"].Args[0], "--key=[REDACTED]"},
+		{"command kept", got.MCPServers["github"].Command, "npx"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, tt.got)
+			}
+		})
+	}
+
+	if _, err := RedactJSON([]byte("not json"), redact); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+}
+
+func TestExport(t *testing.T) {
+	dir := t.TempDir()
+	logDir := filepath.Join(dir, "logs")
+	os.MkdirAll(logDir, 0755)
+	os.WriteFile(filepath.Join(logDir, "boatman.log"), []byte("level=WARN msg=failed token=sk-live\n"), 0644)
+	os.WriteFile(filepath.Join(logDir, "boatman.log.1"), []byte("level=INFO msg=started\n"), 0644)
+	configFile := filepath.Join(dir, "config.json")
+	os.WriteFile(configFile, []byte(`{"preferences":{"apiKey":"sk-secret"}}`), 0644)
+
+	sources := Sources{
+		AppVersion:    "1.2.3",
+		LogDir:        logDir,
+		ConfigFile:    configFile,
+		MCPConfigFile: filepath.Join(dir, "missing.json"),
+		CLIVersion:    func() (string, error) { return "2.0.1 (Claude Code)\n", nil },
+		SessionStats:  func() (any, error) { return nil, errors.New("store closed") },
+		Redact:        func(text string) string { return strings.ReplaceAll(text, "sk-live", "[REDACTED]") },
+	}
+	path := filepath.Join(dir, "bundle.zip")
+	options := Options{Logs: true, Config: true, CLIVersion: true, SessionStats: true, MCPConfig: true}
+
+	manifest, err := Export(path, options, sources)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+
+	expectedFiles := []string{"claude-cli-version.txt", "config.json", "logs/boatman.log", "logs/boatman.log.1"}
+	if strings.Join(manifest.Files, ",") != strings.Join(expectedFiles, ",") {
+		t.Errorf("Expected files %v, got %v", expectedFiles, manifest.Files)
+	}
+	if manifest.Errors["sessionStats"] != "store closed" || manifest.Errors["mcpConfig"] == "" {
+		t.Errorf("Expected the failed parts noted, got %v", manifest.Errors)
+	}
+
+	files := readZip(t, path)
+	if _, ok := files["manifest.json"]; !ok {
+		t.Error("Expected a manifest in the bundle")
+	}
+	if log := files["logs/boatman.log"]; strings.Contains(log, "sk-live") {
+		t.Errorf("Expected the log redacted, got %q", log)
+	}
+	if config := files["config.json"]; strings.Contains(config, "sk-secret") {
+		t.Errorf("Expected the config redacted, got %q", config)
+	}
+	if version := files["claude-cli-version.txt"]; version != "2.0.1 (Claude Code)\n" {
+		t.Errorf("Expected the CLI version, got %q", version)
+	}
+}
+
+func TestExportOnlyIncludesChosenParts(t *testing.T) {
+	dir := t.TempDir()
+	called := false
+	sources := Sources{
+		LogDir:     dir,
+		CLIVersion: func() (string, error) { called = true; return "2.0.1", nil },
+	}
+	path := filepath.Join(dir, "bundle.zip")
+
+	manifest, err := Export(path, Options{}, sources)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if called {
+		t.Error("Expected excluded parts not to be collected")
+	}
+	if len(manifest.Files) != 0 || len(manifest.Errors) != 0 {
+		t.Errorf("Expected an empty bundle, got %+v", manifest)
+	}
+	if files := readZip(t, path); len(files) != 1 {
+		t.Errorf("Expected only the manifest, got %d files", len(files))
+	}
+}
+
+// readZip returns the bundle's files by name
+func readZip(t *testing.T, path string) map[string]string {
+	t.Helper()
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("Failed to open bundle: %v", err)
+	}
+	defer r.Close()
+
+	files := make(map[string]string)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("Failed to open %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+	return files
+}
@@ -42,10 +42,11 @@ type Hunk struct {
 
 // Line represents a single line in a diff
 type Line struct {
-	Type    LineType `json:"type"`
-	Content string   `json:"content"`
-	OldNum  int      `json:"oldNum,omitempty"`
-	NewNum  int      `json:"newNum,omitempty"`
+	Type           LineType `json:"type"`
+	Content        string   `json:"content"`
+	OldNum         int      `json:"oldNum,omitempty"`
+	NewNum         int      `json:"newNum,omitempty"`
+	WhitespaceOnly bool     `json:"whitespaceOnly,omitempty"` // Set by WhitespaceMark
 }
 
 // LineType represents the type of change for a line
@@ -305,12 +306,15 @@ func GenerateSideBySide(diff FileDiff) []SideBySideLine {
 			case LineTypeDeletion:
 				// Check for modification (deletion followed by addition)
 				if i+1 < len(hunk.Lines) && hunk.Lines[i+1].Type == LineTypeAddition {
+					left, right := IntralineHighlights(line.Content, hunk.Lines[i+1].Content)
 					lines = append(lines, SideBySideLine{
-						LeftNum:      line.OldNum,
-						LeftContent:  line.Content,
-						RightNum:     hunk.Lines[i+1].NewNum,
-						RightContent: hunk.Lines[i+1].Content,
-						Type:         "modified",
+						LeftNum:         line.OldNum,
+						LeftContent:     line.Content,
+						RightNum:        hunk.Lines[i+1].NewNum,
+						RightContent:    hunk.Lines[i+1].Content,
+						Type:            "modified",
+						LeftHighlights:  left,
+						RightHighlights: right,
 					})
 					i += 2
 				} else {
@@ -344,4 +348,9 @@ type SideBySideLine struct {
 	RightNum     int    `json:"rightNum,omitempty"`
 	RightContent string `json:"rightContent,omitempty"`
 	Type         string `json:"type"` // context, added, deleted, modified
+	// WhitespaceOnly is set by WhitespaceMark for changes that only touch whitespace
+	WhitespaceOnly bool `json:"whitespaceOnly,omitempty"`
+	// LeftHighlights and RightHighlights mark the words that changed on a modified line
+	LeftHighlights  []HighlightRange `json:"leftHighlights,omitempty"`
+	RightHighlights []HighlightRange `json:"rightHighlights,omitempty"`
 }
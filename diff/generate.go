@@ -0,0 +1,254 @@
+package diff
+
+import "strings"
+
+// contextLines is how many unchanged lines Generate keeps around each change
+const contextLines = 3
+
+// maxEditDistance bounds the work of the line diff. Past it, everything
+// between the common leading and trailing lines is shown as removed and re-added.
+const maxEditDistance = 2000
+
+// Generate diffs two versions of a file without git, for changes such as an
+// agent's Edit or Write on an untracked file. An empty old version is treated
+// as a new file. A missing newline at the end of either version is ignored.
+func Generate(oldContent, newContent, path string) FileDiff {
+	return GenerateWithOptions(oldContent, newContent, path, Options{})
+}
+
+// GenerateWithOptions diffs two versions of a file like Generate, applying opts
+// to whitespace-only changes. With WhitespaceIgnore, hunks that only changed
+// whitespace are left out.
+func GenerateWithOptions(oldContent, newContent, path string, opts Options) FileDiff {
+	fd := FileDiff{
+		OldPath: path,
+		NewPath: path,
+		Hunks:   []Hunk{},
+		IsNew:   oldContent == "" && newContent != "",
+	}
+	if strings.ContainsRune(oldContent, 0) || strings.ContainsRune(newContent, 0) {
+		fd.IsBinary = oldContent != newContent
+		return fd
+	}
+
+	lines := diffLines(contentLines(oldContent), contentLines(newContent))
+	if opts.Whitespace != WhitespaceShow {
+		lines = applyWhitespaceMode(lines, opts.Whitespace)
+	}
+	fd.Hunks = buildHunks(lines, path)
+	return fd
+}
+
+// GenerateUnified diffs two versions of a file and formats the result as a unified diff
+func GenerateUnified(oldContent, newContent, path string) string {
+	return FormatUnified(Generate(oldContent, newContent, path))
+}
+
+// contentLines splits file content into lines, without a final empty line for a trailing newline
+func contentLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// diffLines lines up a and b as context, deletions and additions, numbering
+// both sides from 1
+func diffLines(a, b []string) []Line {
+	// Common leading and trailing lines need no search
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+
+	lines := make([]Line, 0, len(a)+len(b)-prefix-suffix)
+	oldNum, newNum := 1, 1
+	context := func(content string) {
+		lines = append(lines, Line{Type: LineTypeContext, Content: content, OldNum: oldNum, NewNum: newNum})
+		oldNum++
+		newNum++
+	}
+
+	for _, line := range a[:prefix] {
+		context(line)
+	}
+	x, y := prefix, prefix
+	for _, op := range ops {
+		switch op {
+		case LineTypeDeletion:
+			lines = append(lines, Line{Type: LineTypeDeletion, Content: a[x], OldNum: oldNum})
+			oldNum++
+			x++
+		case LineTypeAddition:
+			lines = append(lines, Line{Type: LineTypeAddition, Content: b[y], NewNum: newNum})
+			newNum++
+			y++
+		default:
+			context(a[x])
+			x++
+			y++
+		}
+	}
+	for _, line := range a[len(a)-suffix:] {
+		context(line)
+	}
+
+	return lines
+}
+
+// myersDiff finds a shortest edit script turning a into b with Myers'
+// algorithm, returning one operation per line: context, deletion or addition.
+// Deletions come before additions wherever they touch.
+func myersDiff(a, b []string) []LineType {
+	n, m := len(a), len(b)
+	limit := min(n+m, maxEditDistance)
+
+	// v[offset+k] is the furthest x reached on diagonal k = x - y; trace keeps
+	// v's diagonals -d..d as they stood before each round d
+	offset := limit + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+	found := false
+
+	d := 0
+	for ; d <= limit && !found; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // Down: insert b[y]
+			} else {
+				x = v[offset+k-1] + 1 // Right: delete a[x]
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	if !found {
+		return replaceAll(a, b)
+	}
+
+	// Walk back from the end, recording operations in reverse
+	var ops []LineType
+	x, y := n, m
+	for d--; d > 0; d-- {
+		prev := trace[d] // Diagonals -d..d before round d
+		at := func(k int) int { return prev[k+d] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			ops = append(ops, LineTypeContext)
+			x--
+			y--
+		}
+		if x == prevX {
+			ops = append(ops, LineTypeAddition)
+			y--
+		} else {
+			ops = append(ops, LineTypeDeletion)
+			x--
+		}
+	}
+	for ; x > 0 && y > 0; x, y = x-1, y-1 {
+		ops = append(ops, LineTypeContext)
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// replaceAll removes every line of a and adds every line of b
+func replaceAll(a, b []string) []LineType {
+	ops := make([]LineType, 0, len(a)+len(b))
+	for range a {
+		ops = append(ops, LineTypeDeletion)
+	}
+	for range b {
+		ops = append(ops, LineTypeAddition)
+	}
+	return ops
+}
+
+// buildHunks groups changed lines into hunks with contextLines of context,
+// joining changes whose context would overlap
+func buildHunks(lines []Line, path string) []Hunk {
+	hunks := []Hunk{}
+
+	for i := 0; i < len(lines); {
+		if lines[i].Type == LineTypeContext {
+			i++
+			continue
+		}
+
+		start := max(i-contextLines, 0)
+		end := i
+		for end < len(lines) {
+			if lines[end].Type != LineTypeContext {
+				end++
+				continue
+			}
+			run := end
+			for run < len(lines) && lines[run].Type == LineTypeContext {
+				run++
+			}
+			if run == len(lines) || run-end > 2*contextLines {
+				end = min(end+contextLines, len(lines))
+				break
+			}
+			end = run
+		}
+
+		hunks = append(hunks, newHunk(lines[start:end], path))
+		i = end
+	}
+
+	return hunks
+}
+
+// newHunk builds a hunk from its lines. A hunk that starts with context, or at
+// the top of the file, tells us how many lines come before it on each side.
+func newHunk(lines []Line, path string) Hunk {
+	oldBefore, newBefore := 0, 0
+	if first := lines[0]; first.Type == LineTypeContext {
+		oldBefore, newBefore = first.OldNum-1, first.NewNum-1
+	}
+
+	hunk := Hunk{Lines: lines}
+	hunk.OldLines, hunk.NewLines = countHunkLines(hunk)
+	// Like git, an empty side is numbered by the line it follows
+	hunk.OldStart, hunk.NewStart = oldBefore, newBefore
+	if hunk.OldLines > 0 {
+		hunk.OldStart++
+	}
+	if hunk.NewLines > 0 {
+		hunk.NewStart++
+	}
+	hunk.ID = generateHunkID(path, hunk.OldStart, hunk.NewStart)
+	return hunk
+}
@@ -0,0 +1,224 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// applyFileDiff rebuilds the new version of a file from the old one and its hunks
+func applyFileDiff(t *testing.T, oldContent string, fd FileDiff) string {
+	t.Helper()
+	oldLines := contentLines(oldContent)
+	var out []string
+	next := 0 // Index of the next old line to copy
+	for _, hunk := range fd.Hunks {
+		start := hunk.OldStart - 1
+		if hunk.OldLines == 0 {
+			start = hunk.OldStart
+		}
+		out = append(out, oldLines[next:start]...)
+		next = start
+		for _, line := range hunk.Lines {
+			switch line.Type {
+			case LineTypeContext:
+				if oldLines[next] != line.Content {
+					t.Fatalf("Context %q doesn't match old line %d %q", line.Content, next+1, oldLines[next])
+				}
+				out = append(out, line.Content)
+				next++
+			case LineTypeDeletion:
+				next++
+			case LineTypeAddition:
+				out = append(out, line.Content)
+			}
+		}
+	}
+	out = append(out, oldLines[next:]...)
+	if len(out) == 0 {
+		return ""
+	}
+	return strings.Join(out, "\n") + "\n"
+}
+
+// numberedLines returns "line 1" through "line n"
+func numberedLines(n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+	return lines
+}
+
+func TestGenerate(t *testing.T) {
+	base := numberedLines(20)
+	join := func(lines []string) string { return strings.Join(lines, "\n") + "\n" }
+	replaced := func(i int, s string) []string {
+		lines := append([]string(nil), base...)
+		lines[i] = s
+		return lines
+	}
+
+	tests := []struct {
+		name       string
+		oldContent string
+		newContent string
+		wantHunks  int
+	}{
+		{name: "unchanged", oldContent: join(base), newContent: join(base), wantHunks: 0},
+		{name: "one line changed", oldContent: join(base), newContent: join(replaced(9, "changed")), wantHunks: 1},
+		{name: "new file", oldContent: "", newContent: "a\nb\n", wantHunks: 1},
+		{name: "emptied file", oldContent: "a\nb\n", newContent: "", wantHunks: 1},
+		{name: "inserted at top", oldContent: join(base), newContent: "first\n" + join(base), wantHunks: 1},
+		{name: "appended", oldContent: join(base), newContent: join(base) + "last\n", wantHunks: 1},
+		{name: "reordered", oldContent: "a\nb\nc\nd\n", newContent: "d\nc\nb\na\n", wantHunks: 1},
+		{name: "no trailing newline", oldContent: "a\nb", newContent: "a\nc\n", wantHunks: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fd := Generate(tt.oldContent, tt.newContent, "file.txt")
+			if len(fd.Hunks) != tt.wantHunks {
+				t.Fatalf("Expected %d hunks, got %d", tt.wantHunks, len(fd.Hunks))
+			}
+			want := tt.newContent
+			if want != "" && !strings.HasSuffix(want, "\n") {
+				want += "\n"
+			}
+			if got := applyFileDiff(t, tt.oldContent, fd); got != want {
+				t.Errorf("Applying the diff gave %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestGenerate_HunkLayout(t *testing.T) {
+	oldLines := numberedLines(30)
+	newLines := append([]string(nil), oldLines...)
+	newLines[4] = "changed 5"
+	newLines[9] = "changed 10"  // Close enough to share a hunk with line 5
+	newLines[24] = "changed 25" // Far enough away for its own hunk
+
+	fd := Generate(strings.Join(oldLines, "\n")+"\n", strings.Join(newLines, "\n")+"\n", "file.txt")
+	if len(fd.Hunks) != 2 {
+		t.Fatalf("Expected 2 hunks, got %d", len(fd.Hunks))
+	}
+
+	first, second := fd.Hunks[0], fd.Hunks[1]
+	if first.OldStart != 2 || first.OldLines != 12 || first.NewStart != 2 || first.NewLines != 12 {
+		t.Errorf("Unexpected first hunk range -%d,%d +%d,%d", first.OldStart, first.OldLines, first.NewStart, first.NewLines)
+	}
+	if second.OldStart != 22 || second.OldLines != 7 {
+		t.Errorf("Unexpected second hunk range -%d,%d", second.OldStart, second.OldLines)
+	}
+	if first.ID == "" || first.ID == second.ID {
+		t.Errorf("Expected distinct hunk IDs, got %q and %q", first.ID, second.ID)
+	}
+
+	// Deletions come before the additions that replace them
+	var types []LineType
+	for _, line := range first.Lines[3:5] {
+		types = append(types, line.Type)
+	}
+	if types[0] != LineTypeDeletion || types[1] != LineTypeAddition {
+		t.Errorf("Expected a deletion then an addition, got %v", types)
+	}
+	if first.Lines[3].OldNum != 5 || first.Lines[4].NewNum != 5 {
+		t.Errorf("Expected line 5 on both sides, got %d and %d", first.Lines[3].OldNum, first.Lines[4].NewNum)
+	}
+}
+
+func TestGenerate_NewFile(t *testing.T) {
+	fd := Generate("", "a\nb\n", "new.txt")
+	if !fd.IsNew || fd.IsBinary {
+		t.Errorf("Expected a new text file, got IsNew=%v IsBinary=%v", fd.IsNew, fd.IsBinary)
+	}
+	hunk := fd.Hunks[0]
+	if hunk.OldStart != 0 || hunk.OldLines != 0 || hunk.NewStart != 1 || hunk.NewLines != 2 {
+		t.Errorf("Unexpected range -%d,%d +%d,%d", hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines)
+	}
+}
+
+func TestGenerate_Binary(t *testing.T) {
+	fd := Generate("a\x00b", "a\x00c", "image.png")
+	if !fd.IsBinary || len(fd.Hunks) != 0 {
+		t.Errorf("Expected a binary diff without hunks, got IsBinary=%v with %d hunks", fd.IsBinary, len(fd.Hunks))
+	}
+	if !strings.Contains(FormatUnified(fd), "Binary files a/image.png and b/image.png differ") {
+		t.Errorf("Expected a binary notice, got %q", FormatUnified(fd))
+	}
+}
+
+func TestGenerateWithOptions(t *testing.T) {
+	oldContent := "func main() {\n  x := 1\n\trun(x)\n}\n"
+	newContent := "func main() {\n\tx := 1\n\trun(x)\n}\n"
+
+	if fd := GenerateWithOptions(oldContent, newContent, "main.go", Options{Whitespace: WhitespaceIgnore}); len(fd.Hunks) != 0 {
+		t.Errorf("Expected whitespace-only changes to be ignored, got %d hunks", len(fd.Hunks))
+	}
+
+	fd := GenerateWithOptions(oldContent, newContent, "main.go", Options{Whitespace: WhitespaceMark})
+	if len(fd.Hunks) != 1 {
+		t.Fatalf("Expected 1 hunk, got %d", len(fd.Hunks))
+	}
+	for _, line := range fd.Hunks[0].Lines {
+		if line.Type != LineTypeContext && !line.WhitespaceOnly {
+			t.Errorf("Expected %+v to be marked whitespace-only", line)
+		}
+	}
+
+	mixed := strings.Replace(newContent, "run(x)", "run(x, y)", 1)
+	fd = GenerateWithOptions(oldContent, mixed, "main.go", Options{Whitespace: WhitespaceIgnore})
+	if len(fd.Hunks) != 1 {
+		t.Fatalf("Expected 1 hunk, got %d", len(fd.Hunks))
+	}
+	var changed []string
+	for _, line := range fd.Hunks[0].Lines {
+		if line.Type != LineTypeContext {
+			changed = append(changed, line.Content)
+		}
+	}
+	if len(changed) != 2 || changed[0] != "\trun(x)" || changed[1] != "\trun(x, y)" {
+		t.Errorf("Expected only the real change, got %q", changed)
+	}
+}
+
+func TestGenerate_LargeEdit(t *testing.T) {
+	var oldLines, newLines []string
+	for i := 0; i < maxEditDistance; i++ {
+		oldLines = append(oldLines, fmt.Sprintf("old %d", i))
+		newLines = append(newLines, fmt.Sprintf("new %d", i))
+	}
+	oldContent := strings.Join(oldLines, "\n") + "\n"
+	newContent := strings.Join(newLines, "\n") + "\n"
+
+	fd := Generate(oldContent, newContent, "big.txt")
+	if got := applyFileDiff(t, oldContent, fd); got != newContent {
+		t.Error("Expected the diff to rebuild the new content past the edit limit")
+	}
+}
+
+func TestGenerateUnified_RoundTrip(t *testing.T) {
+	oldContent := "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"
+	newContent := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n"
+
+	text := GenerateUnified(oldContent, newContent, "main.go")
+	if !strings.HasPrefix(text, "diff --git a/main.go b/main.go\n--- a/main.go\n+++ b/main.go\n@@ -1,5 +1,7 @@\n") {
+		t.Errorf("Unexpected header:\n%s", text)
+	}
+
+	parsed, err := ParseUnifiedDiff(text)
+	if err != nil {
+		t.Fatalf("ParseUnifiedDiff failed: %v", err)
+	}
+	if len(parsed) != 1 {
+		t.Fatalf("Expected 1 file, got %d", len(parsed))
+	}
+	if got := applyFileDiff(t, oldContent, parsed[0]); got != newContent {
+		t.Errorf("Parsed diff gave %q, want %q", got, newContent)
+	}
+
+	if GenerateUnified(oldContent, oldContent, "main.go") != "" {
+		t.Error("Expected no output for identical content")
+	}
+}
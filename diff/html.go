@@ -0,0 +1,177 @@
+package diff
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// HTMLLayout selects how RenderHTML lays out changes
+type HTMLLayout string
+
+const (
+	HTMLLayoutUnified    HTMLLayout = "unified"
+	HTMLLayoutSideBySide HTMLLayout = "side-by-side"
+)
+
+// HTMLOptions configures HTML rendering of diffs
+type HTMLOptions struct {
+	Layout          HTMLLayout `json:"layout"`
+	HideLineNumbers bool       `json:"hideLineNumbers,omitempty"`
+}
+
+// Inline styles so the fragment renders correctly outside the app
+const (
+	htmlFileStyle     = "font-family:ui-monospace,SFMono-Regular,Menlo,monospace;font-size:12px;border:1px solid #d0d7de;border-radius:6px;margin:0 0 16px 0;overflow:hidden;"
+	htmlHeaderStyle   = "background:#f6f8fa;border-bottom:1px solid #d0d7de;padding:8px 12px;font-weight:600;color:#24292f;"
+	htmlTableStyle    = "border-collapse:collapse;width:100%;table-layout:fixed;"
+	htmlHunkStyle     = "background:#ddf4ff;color:#57606a;padding:4px 12px;"
+	htmlNumStyle      = "width:48px;padding:0 8px;text-align:right;color:#8c959f;user-select:none;vertical-align:top;"
+	htmlCodeStyle     = "padding:0 8px;white-space:pre-wrap;word-break:break-all;vertical-align:top;"
+	htmlNoticeStyle   = "padding:8px 12px;color:#57606a;font-style:italic;"
+	htmlAdditionColor = "#e6ffec"
+	htmlDeletionColor = "#ffebe9"
+)
+
+// RenderHTML renders diffs as a self-contained HTML fragment with inline styles
+func RenderHTML(diffs []FileDiff, opts HTMLOptions) string {
+	var b strings.Builder
+
+	b.WriteString(`<div class="boatman-diff">`)
+	for _, fd := range diffs {
+		renderFileHTML(&b, fd, opts)
+	}
+	b.WriteString(`</div>`)
+
+	return b.String()
+}
+
+// renderFileHTML writes a single file block
+func renderFileHTML(b *strings.Builder, fd FileDiff, opts HTMLOptions) {
+	fmt.Fprintf(b, `<div style="%s">`, htmlFileStyle)
+	fmt.Fprintf(b, `<div style="%s">%s</div>`, htmlHeaderStyle, html.EscapeString(fileDisplayName(fd)))
+
+	switch {
+	case fd.IsBinary:
+		fmt.Fprintf(b, `<div style="%s">Binary file not shown</div>`, htmlNoticeStyle)
+	case len(fd.Hunks) == 0:
+		fmt.Fprintf(b, `<div style="%s">No changes</div>`, htmlNoticeStyle)
+	default:
+		fmt.Fprintf(b, `<table style="%s">`, htmlTableStyle)
+		for _, hunk := range fd.Hunks {
+			if opts.Layout == HTMLLayoutSideBySide {
+				renderSideBySideHunkHTML(b, hunk, opts)
+			} else {
+				renderUnifiedHunkHTML(b, hunk, opts)
+			}
+		}
+		b.WriteString(`</table>`)
+	}
+
+	b.WriteString(`</div>`)
+}
+
+// fileDisplayName returns the header label for a file diff
+func fileDisplayName(fd FileDiff) string {
+	switch {
+	case fd.IsNew:
+		return fd.NewPath + " (new)"
+	case fd.IsDelete:
+		return fd.OldPath + " (deleted)"
+	case fd.OldPath != "" && fd.NewPath != "" && fd.OldPath != fd.NewPath:
+		return fd.OldPath + " → " + fd.NewPath
+	case fd.NewPath != "":
+		return fd.NewPath
+	default:
+		return fd.OldPath
+	}
+}
+
+// hunkHeader formats the @@ header for a hunk
+func hunkHeader(hunk Hunk) string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@", hunk.OldStart, hunk.OldLines, hunk.NewStart, hunk.NewLines)
+}
+
+// renderUnifiedHunkHTML writes a hunk as unified rows
+func renderUnifiedHunkHTML(b *strings.Builder, hunk Hunk, opts HTMLOptions) {
+	cols := 2
+	if !opts.HideLineNumbers {
+		cols = 4
+	}
+	fmt.Fprintf(b, `<tr><td colspan="%d" style="%s">%s</td></tr>`, cols, htmlHunkStyle, html.EscapeString(hunkHeader(hunk)))
+
+	for _, line := range hunk.Lines {
+		marker, bg := " ", ""
+		switch line.Type {
+		case LineTypeAddition:
+			marker, bg = "+", htmlAdditionColor
+		case LineTypeDeletion:
+			marker, bg = "-", htmlDeletionColor
+		}
+
+		rowStyle := ""
+		if bg != "" {
+			rowStyle = fmt.Sprintf(` style="background:%s;"`, bg)
+		}
+		fmt.Fprintf(b, `<tr%s>`, rowStyle)
+		if !opts.HideLineNumbers {
+			writeNumCell(b, line.OldNum)
+			writeNumCell(b, line.NewNum)
+		}
+		fmt.Fprintf(b, `<td style="%swidth:16px;">%s</td>`, htmlCodeStyle, marker)
+		fmt.Fprintf(b, `<td style="%s">%s</td>`, htmlCodeStyle, html.EscapeString(line.Content))
+		b.WriteString(`</tr>`)
+	}
+}
+
+// renderSideBySideHunkHTML writes a hunk as paired left/right rows
+func renderSideBySideHunkHTML(b *strings.Builder, hunk Hunk, opts HTMLOptions) {
+	cols := 2
+	if !opts.HideLineNumbers {
+		cols = 4
+	}
+	fmt.Fprintf(b, `<tr><td colspan="%d" style="%s">%s</td></tr>`, cols, htmlHunkStyle, html.EscapeString(hunkHeader(hunk)))
+
+	single := FileDiff{Hunks: []Hunk{hunk}}
+	for _, line := range GenerateSideBySide(single) {
+		leftBg, rightBg := "", ""
+		switch line.Type {
+		case "added":
+			rightBg = htmlAdditionColor
+		case "deleted":
+			leftBg = htmlDeletionColor
+		case "modified":
+			leftBg, rightBg = htmlDeletionColor, htmlAdditionColor
+		}
+
+		b.WriteString(`<tr>`)
+		writeSideHTML(b, line.LeftNum, line.LeftContent, leftBg, opts)
+		writeSideHTML(b, line.RightNum, line.RightContent, rightBg, opts)
+		b.WriteString(`</tr>`)
+	}
+}
+
+// writeSideHTML writes one half of a side-by-side row
+func writeSideHTML(b *strings.Builder, num int, content, bg string, opts HTMLOptions) {
+	bgStyle := ""
+	if bg != "" {
+		bgStyle = "background:" + bg + ";"
+	}
+	if !opts.HideLineNumbers {
+		fmt.Fprintf(b, `<td style="%s%s">`, htmlNumStyle, bgStyle)
+		if num > 0 {
+			fmt.Fprintf(b, "%d", num)
+		}
+		b.WriteString(`</td>`)
+	}
+	fmt.Fprintf(b, `<td style="%s%s">%s</td>`, htmlCodeStyle, bgStyle, html.EscapeString(content))
+}
+
+// writeNumCell writes a line number cell, leaving it blank for zero
+func writeNumCell(b *strings.Builder, num int) {
+	fmt.Fprintf(b, `<td style="%s">`, htmlNumStyle)
+	if num > 0 {
+		fmt.Fprintf(b, "%d", num)
+	}
+	b.WriteString(`</td>`)
+}
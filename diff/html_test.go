@@ -0,0 +1,87 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderHTML_Unified(t *testing.T) {
+	diffs, err := ParseUnifiedDiff(simpleDiff)
+	if err != nil {
+		t.Fatalf("ParseUnifiedDiff() error = %v", err)
+	}
+
+	out := RenderHTML(diffs, HTMLOptions{Layout: HTMLLayoutUnified})
+
+	if !strings.HasPrefix(out, `<div class="boatman-diff">`) || !strings.HasSuffix(out, `</div>`) {
+		t.Errorf("Expected wrapped fragment, got %q", out)
+	}
+	for _, want := range []string{"file.txt", "@@ -1,3 +1,3 @@", "modified line 2", htmlAdditionColor, htmlDeletionColor} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q", want)
+		}
+	}
+	if strings.Contains(out, "<style") || strings.Contains(out, "class=\"add") {
+		t.Error("Expected only inline styles")
+	}
+}
+
+func TestRenderHTML_SideBySide(t *testing.T) {
+	diffs, err := ParseUnifiedDiff(simpleDiff)
+	if err != nil {
+		t.Fatalf("ParseUnifiedDiff() error = %v", err)
+	}
+
+	out := RenderHTML(diffs, HTMLOptions{Layout: HTMLLayoutSideBySide})
+
+	// The modified pair should appear on the same row
+	idx := strings.Index(out, "line 2</td>")
+	if idx < 0 {
+		t.Fatal("Expected deleted line in output")
+	}
+	rowEnd := strings.Index(out[idx:], "</tr>")
+	if !strings.Contains(out[idx:idx+rowEnd], "modified line 2") {
+		t.Error("Expected modified line on the same row as the deleted line")
+	}
+}
+
+func TestRenderHTML_EscapesContent(t *testing.T) {
+	diffs := []FileDiff{{
+		NewPath: "<script>.html",
+		Hunks: []Hunk{{
+			OldStart: 1, OldLines: 1, NewStart: 1, NewLines: 1,
+			Lines: []Line{{Type: LineTypeAddition, Content: `<script>alert("x")</script>`, NewNum: 1}},
+		}},
+	}}
+
+	out := RenderHTML(diffs, HTMLOptions{})
+
+	if strings.Contains(out, "<script>") {
+		t.Error("Expected content to be HTML-escaped")
+	}
+	if !strings.Contains(out, "&lt;script&gt;") {
+		t.Error("Expected escaped script tag")
+	}
+}
+
+func TestRenderHTML_BinaryAndLineNumbers(t *testing.T) {
+	diffs, err := ParseUnifiedDiff(binaryFileDiff)
+	if err != nil {
+		t.Fatalf("ParseUnifiedDiff() error = %v", err)
+	}
+
+	out := RenderHTML(diffs, HTMLOptions{})
+	if !strings.Contains(out, "Binary file not shown") {
+		t.Error("Expected binary notice")
+	}
+
+	textDiffs, _ := ParseUnifiedDiff(simpleDiff)
+	withNums := RenderHTML(textDiffs, HTMLOptions{})
+	withoutNums := RenderHTML(textDiffs, HTMLOptions{HideLineNumbers: true})
+	if strings.Count(withNums, htmlNumStyle) == 0 {
+		t.Error("Expected line number cells")
+	}
+	if strings.Contains(withoutNums, htmlNumStyle) {
+		t.Error("Expected no line number cells when hidden")
+	}
+}
@@ -0,0 +1,156 @@
+package diff
+
+import "unicode"
+
+// maxIntralineTokens caps the tokens compared per line pair; the LCS table
+// grows with the product of both sides, so longer lines go unhighlighted
+const maxIntralineTokens = 500
+
+// HighlightRange marks changed characters within a line, as rune offsets
+// from the start of the line with End exclusive
+type HighlightRange struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// token is a word, a run of whitespace, or a single other character
+type token struct {
+	text  string
+	start int // Rune offset in the line
+	end   int
+	space bool
+}
+
+// IntralineHighlights compares a removed line with the line that replaced it
+// word by word and returns the ranges that changed on each side. Lines with
+// nothing but whitespace in common return no ranges, since highlighting the
+// whole line adds nothing to the line's own colouring.
+func IntralineHighlights(oldLine, newLine string) (left, right []HighlightRange) {
+	if oldLine == newLine {
+		return nil, nil
+	}
+
+	oldTokens := tokenize(oldLine)
+	newTokens := tokenize(newLine)
+	if len(oldTokens) > maxIntralineTokens || len(newTokens) > maxIntralineTokens {
+		return nil, nil
+	}
+
+	oldCommon, newCommon := commonTokens(oldTokens, newTokens)
+	if !sharesWord(oldTokens, oldCommon) {
+		return nil, nil
+	}
+
+	return changedRanges(oldTokens, oldCommon), changedRanges(newTokens, newCommon)
+}
+
+// tokenize splits a line into words, whitespace runs, and single punctuation characters
+func tokenize(line string) []token {
+	var tokens []token
+	runes := []rune(line)
+
+	for i := 0; i < len(runes); {
+		start := i
+		switch {
+		case isWordRune(runes[i]):
+			for i < len(runes) && isWordRune(runes[i]) {
+				i++
+			}
+		case unicode.IsSpace(runes[i]):
+			for i < len(runes) && unicode.IsSpace(runes[i]) {
+				i++
+			}
+		default:
+			i++
+		}
+		tokens = append(tokens, token{
+			text:  string(runes[start:i]),
+			start: start,
+			end:   i,
+			space: unicode.IsSpace(runes[start]),
+		})
+	}
+
+	return tokens
+}
+
+// isWordRune reports whether r belongs in an identifier-like word
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// commonTokens runs a longest common subsequence over two token lists and
+// reports which tokens on each side belong to it
+func commonTokens(a, b []token) (inA, inB []bool) {
+	// lengths[i][j] is the LCS length of a[i:] and b[j:]
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i].text == b[j].text {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+
+	inA = make([]bool, len(a))
+	inB = make([]bool, len(b))
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i].text == b[j].text:
+			inA[i], inB[j] = true, true
+			i++
+			j++
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return inA, inB
+}
+
+// sharesWord reports whether any token outside whitespace is common to both sides
+func sharesWord(tokens []token, common []bool) bool {
+	for i, tok := range tokens {
+		if common[i] && !tok.space {
+			return true
+		}
+	}
+	return false
+}
+
+// changedRanges merges runs of tokens outside the common subsequence into
+// ranges. Whitespace between two changes joins them into one range.
+func changedRanges(tokens []token, common []bool) []HighlightRange {
+	var ranges []HighlightRange
+
+	for i := 0; i < len(tokens); i++ {
+		if common[i] {
+			continue
+		}
+
+		if n := len(ranges); n > 0 && bridgedBySpace(tokens, common, ranges[n-1].End, tokens[i].start) {
+			ranges[n-1].End = tokens[i].end
+			continue
+		}
+		ranges = append(ranges, HighlightRange{Start: tokens[i].start, End: tokens[i].end})
+	}
+
+	return ranges
+}
+
+// bridgedBySpace reports whether only whitespace lies between the rune offsets from and to
+func bridgedBySpace(tokens []token, common []bool, from, to int) bool {
+	for i, tok := range tokens {
+		if tok.start >= from && tok.end <= to && common[i] && !tok.space {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,104 @@
+package diff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestIntralineHighlights(t *testing.T) {
+	tests := []struct {
+		name      string
+		oldLine   string
+		newLine   string
+		wantLeft  []HighlightRange
+		wantRight []HighlightRange
+	}{
+		{
+			name:    "identical",
+			oldLine: "return nil",
+			newLine: "return nil",
+		},
+		{
+			name:      "changed word",
+			oldLine:   "return nil, err",
+			newLine:   "return result, err",
+			wantLeft:  []HighlightRange{{Start: 7, End: 10}},
+			wantRight: []HighlightRange{{Start: 7, End: 13}},
+		},
+		{
+			name:      "inserted argument",
+			oldLine:   "foo(a)",
+			newLine:   "foo(a, b)",
+			wantRight: []HighlightRange{{Start: 5, End: 8}},
+		},
+		{
+			name:     "removed words join across spaces",
+			oldLine:  "if x and not y {",
+			newLine:  "if y {",
+			wantLeft: []HighlightRange{{Start: 3, End: 13}},
+		},
+		{
+			name:    "nothing in common",
+			oldLine: "alpha beta",
+			newLine: "gamma delta",
+		},
+		{
+			name:      "multibyte offsets count runes",
+			oldLine:   "café = 1",
+			newLine:   "café = 2",
+			wantLeft:  []HighlightRange{{Start: 7, End: 8}},
+			wantRight: []HighlightRange{{Start: 7, End: 8}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			left, right := IntralineHighlights(tt.oldLine, tt.newLine)
+			if !reflect.DeepEqual(left, tt.wantLeft) {
+				t.Errorf("Expected left %v, got %v", tt.wantLeft, left)
+			}
+			if !reflect.DeepEqual(right, tt.wantRight) {
+				t.Errorf("Expected right %v, got %v", tt.wantRight, right)
+			}
+		})
+	}
+}
+
+func TestIntralineHighlightsLongLine(t *testing.T) {
+	long := make([]byte, 0, 2*maxIntralineTokens+2)
+	for i := 0; i <= maxIntralineTokens; i++ {
+		long = append(long, 'a', ' ')
+	}
+
+	left, right := IntralineHighlights(string(long), string(long)+"b")
+	if left != nil || right != nil {
+		t.Errorf("Expected lines over the token limit to go unhighlighted, got %v and %v", left, right)
+	}
+}
+
+func TestGenerateSideBySide_Highlights(t *testing.T) {
+	diff := FileDiff{
+		Hunks: []Hunk{
+			{
+				Lines: []Line{
+					{Type: LineTypeDeletion, Content: "x := 1", OldNum: 1},
+					{Type: LineTypeAddition, Content: "x := 2", NewNum: 1},
+					{Type: LineTypeAddition, Content: "y := 3", NewNum: 2},
+				},
+			},
+		},
+	}
+
+	result := GenerateSideBySide(diff)
+	if len(result) != 2 {
+		t.Fatalf("Expected 2 lines, got %d", len(result))
+	}
+
+	want := []HighlightRange{{Start: 5, End: 6}}
+	if !reflect.DeepEqual(result[0].LeftHighlights, want) || !reflect.DeepEqual(result[0].RightHighlights, want) {
+		t.Errorf("Expected the digit highlighted on both sides, got %v and %v", result[0].LeftHighlights, result[0].RightHighlights)
+	}
+	if result[1].RightHighlights != nil {
+		t.Errorf("Expected no highlights on an unpaired addition, got %v", result[1].RightHighlights)
+	}
+}
@@ -0,0 +1,139 @@
+package diff
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ExportHunks serializes the selected hunks of a FileDiff as a unified diff patch.
+// Hunk start lines are adjusted for any unselected hunks so the patch applies cleanly
+// to the original file on its own.
+func ExportHunks(fd FileDiff, hunkIndices []int) (string, error) {
+	if fd.IsBinary {
+		return "", fmt.Errorf("cannot export hunks of a binary file")
+	}
+	if len(hunkIndices) == 0 {
+		return "", fmt.Errorf("no hunks selected")
+	}
+
+	selected := make(map[int]bool)
+	for _, idx := range hunkIndices {
+		if idx < 0 || idx >= len(fd.Hunks) {
+			return "", fmt.Errorf("hunk index out of range: %d", idx)
+		}
+		selected[idx] = true
+	}
+
+	indices := make([]int, 0, len(selected))
+	for idx := range selected {
+		indices = append(indices, idx)
+	}
+	sort.Ints(indices)
+
+	var b strings.Builder
+	writePatchHeader(&b, fd)
+
+	// Running total of line-count changes from hunks left out of the patch
+	skippedDelta := 0
+	next := 0
+	for i, hunk := range fd.Hunks {
+		oldCount, newCount := countHunkLines(hunk)
+		if next >= len(indices) || indices[next] != i {
+			skippedDelta += newCount - oldCount
+			continue
+		}
+		next++
+
+		writeHunk(&b, hunk, hunk.NewStart-skippedDelta, oldCount, newCount)
+	}
+
+	return b.String(), nil
+}
+
+// FormatUnified formats a whole FileDiff as a unified diff, or returns an
+// empty string when it has no changes
+func FormatUnified(fd FileDiff) string {
+	if !fd.IsBinary && len(fd.Hunks) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	writePatchHeader(&b, fd)
+	if fd.IsBinary {
+		fmt.Fprintf(&b, "Binary files a/%s and b/%s differ\n", fd.OldPath, fd.NewPath)
+		return b.String()
+	}
+	for _, hunk := range fd.Hunks {
+		oldCount, newCount := countHunkLines(hunk)
+		writeHunk(&b, hunk, hunk.NewStart, oldCount, newCount)
+	}
+
+	return b.String()
+}
+
+// writeHunk writes a hunk's header, starting the new side at newStart, and its lines
+func writeHunk(b *strings.Builder, hunk Hunk, newStart, oldCount, newCount int) {
+	fmt.Fprintf(b, "@@ -%s +%s @@\n",
+		formatRange(hunk.OldStart, oldCount),
+		formatRange(newStart, newCount))
+	for _, line := range hunk.Lines {
+		switch line.Type {
+		case LineTypeAddition:
+			b.WriteString("+")
+		case LineTypeDeletion:
+			b.WriteString("-")
+		default:
+			b.WriteString(" ")
+		}
+		b.WriteString(line.Content)
+		b.WriteString("\n")
+	}
+}
+
+// writePatchHeader writes the git-style file header for a patch
+func writePatchHeader(b *strings.Builder, fd FileDiff) {
+	oldPath, newPath := fd.OldPath, fd.NewPath
+	if fd.IsNew || oldPath == "" {
+		oldPath = newPath
+	}
+	if fd.IsDelete || newPath == "" {
+		newPath = oldPath
+	}
+
+	fmt.Fprintf(b, "diff --git a/%s b/%s\n", oldPath, newPath)
+	if fd.IsNew {
+		b.WriteString("--- /dev/null\n")
+	} else {
+		fmt.Fprintf(b, "--- a/%s\n", oldPath)
+	}
+	if fd.IsDelete {
+		b.WriteString("+++ /dev/null\n")
+	} else {
+		fmt.Fprintf(b, "+++ b/%s\n", newPath)
+	}
+}
+
+// countHunkLines counts the old and new side lines actually present in a hunk
+func countHunkLines(hunk Hunk) (oldCount, newCount int) {
+	for _, line := range hunk.Lines {
+		switch line.Type {
+		case LineTypeAddition:
+			newCount++
+		case LineTypeDeletion:
+			oldCount++
+		default:
+			oldCount++
+			newCount++
+		}
+	}
+	return oldCount, newCount
+}
+
+// formatRange formats a hunk range, omitting the count when it is 1
+func formatRange(start, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
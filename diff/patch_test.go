@@ -0,0 +1,108 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExportHunks_AllHunks(t *testing.T) {
+	diffs, err := ParseUnifiedDiff(simpleDiff)
+	if err != nil {
+		t.Fatalf("ParseUnifiedDiff() error = %v", err)
+	}
+
+	patch, err := ExportHunks(diffs[0], []int{0})
+	if err != nil {
+		t.Fatalf("ExportHunks() error = %v", err)
+	}
+
+	if patch != simpleDiff {
+		t.Errorf("Expected round-trip patch.\nGot:\n%s\nWant:\n%s", patch, simpleDiff)
+	}
+}
+
+func TestExportHunks_AdjustsStartForSkippedHunks(t *testing.T) {
+	diffText := `diff --git a/multi.txt b/multi.txt
+--- a/multi.txt
++++ b/multi.txt
+@@ -1,3 +1,5 @@
+ line 1
++added a
++added b
+ line 2
+ line 3
+@@ -10,3 +12,3 @@
+ line 10
+-line 11
++changed 11
+ line 12
+`
+	diffs, err := ParseUnifiedDiff(diffText)
+	if err != nil {
+		t.Fatalf("ParseUnifiedDiff() error = %v", err)
+	}
+
+	patch, err := ExportHunks(diffs[0], []int{1})
+	if err != nil {
+		t.Fatalf("ExportHunks() error = %v", err)
+	}
+
+	if !strings.Contains(patch, "@@ -10,3 +10,3 @@") {
+		t.Errorf("Expected second hunk to be rebased onto original file, got:\n%s", patch)
+	}
+	if strings.Contains(patch, "added a") {
+		t.Error("Expected unselected hunk to be excluded")
+	}
+
+	reparsed, err := ParseUnifiedDiff(patch)
+	if err != nil || len(reparsed) != 1 || len(reparsed[0].Hunks) != 1 {
+		t.Fatalf("Expected exported patch to parse as a single hunk, got %v (%v)", reparsed, err)
+	}
+}
+
+func TestExportHunks_NewAndDeletedFiles(t *testing.T) {
+	newDiffs, _ := ParseUnifiedDiff(newFileDiff)
+	patch, err := ExportHunks(newDiffs[0], []int{0})
+	if err != nil {
+		t.Fatalf("ExportHunks() error = %v", err)
+	}
+	if !strings.Contains(patch, "--- /dev/null\n+++ b/new.txt\n") {
+		t.Errorf("Expected new file header, got:\n%s", patch)
+	}
+	if !strings.Contains(patch, "@@ -0,0 +1,3 @@") {
+		t.Errorf("Expected new file hunk header, got:\n%s", patch)
+	}
+
+	delDiffs, _ := ParseUnifiedDiff(deletedFileDiff)
+	patch, err = ExportHunks(delDiffs[0], []int{0})
+	if err != nil {
+		t.Fatalf("ExportHunks() error = %v", err)
+	}
+	if !strings.Contains(patch, "--- a/deleted.txt\n+++ /dev/null\n") {
+		t.Errorf("Expected deleted file header, got:\n%s", patch)
+	}
+}
+
+func TestExportHunks_Errors(t *testing.T) {
+	diffs, _ := ParseUnifiedDiff(simpleDiff)
+	binary, _ := ParseUnifiedDiff(binaryFileDiff)
+
+	tests := []struct {
+		name    string
+		fd      FileDiff
+		indices []int
+	}{
+		{name: "no selection", fd: diffs[0], indices: nil},
+		{name: "out of range", fd: diffs[0], indices: []int{3}},
+		{name: "negative index", fd: diffs[0], indices: []int{-1}},
+		{name: "binary file", fd: binary[0], indices: []int{0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ExportHunks(tt.fd, tt.indices); err == nil {
+				t.Error("Expected error")
+			}
+		})
+	}
+}
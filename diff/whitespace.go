@@ -0,0 +1,208 @@
+package diff
+
+import (
+	"strings"
+	"unicode"
+)
+
+// WhitespaceMode selects how whitespace-only changes are treated
+type WhitespaceMode string
+
+const (
+	// WhitespaceShow keeps whitespace-only changes as ordinary changes
+	WhitespaceShow WhitespaceMode = ""
+	// WhitespaceIgnore turns whitespace-only changes into context and drops
+	// hunks and files left without real changes
+	WhitespaceIgnore WhitespaceMode = "ignore"
+	// WhitespaceMark keeps whitespace-only changes but flags them as WhitespaceOnly
+	WhitespaceMark WhitespaceMode = "mark"
+)
+
+// Options configures diff parsing and side-by-side generation. Diffs parsed with
+// WhitespaceIgnore are for review; export patches from an unfiltered parse.
+type Options struct {
+	Whitespace WhitespaceMode `json:"whitespace,omitempty"`
+}
+
+// ParseUnifiedDiffWithOptions parses a unified diff, applying opts to whitespace-only changes
+func ParseUnifiedDiffWithOptions(diffText string, opts Options) ([]FileDiff, error) {
+	diffs, err := ParseUnifiedDiff(diffText)
+	if err != nil || opts.Whitespace == WhitespaceShow {
+		return diffs, err
+	}
+
+	result := make([]FileDiff, 0, len(diffs))
+	for _, fd := range diffs {
+		hunks := make([]Hunk, 0, len(fd.Hunks))
+		for _, hunk := range fd.Hunks {
+			hunk.Lines = applyWhitespaceMode(hunk.Lines, opts.Whitespace)
+			if opts.Whitespace == WhitespaceIgnore && !hasChanges(hunk.Lines) {
+				continue
+			}
+			hunks = append(hunks, hunk)
+		}
+
+		// Files that only changed whitespace drop out, unless the file itself was added, removed, or renamed
+		if opts.Whitespace == WhitespaceIgnore && len(hunks) == 0 && len(fd.Hunks) > 0 &&
+			!fd.IsNew && !fd.IsDelete && fd.OldPath == fd.NewPath {
+			continue
+		}
+		fd.Hunks = hunks
+		result = append(result, fd)
+	}
+
+	return result, nil
+}
+
+// GenerateSideBySideWithOptions converts a FileDiff to side-by-side format,
+// applying opts to whitespace-only changes
+func GenerateSideBySideWithOptions(diff FileDiff, opts Options) []SideBySideLine {
+	lines := GenerateSideBySide(diff)
+	if opts.Whitespace == WhitespaceShow {
+		return lines
+	}
+
+	result := make([]SideBySideLine, 0, len(lines))
+	for _, line := range lines {
+		whitespaceOnly := false
+		switch line.Type {
+		case "modified":
+			whitespaceOnly = equalIgnoringWhitespace(line.LeftContent, line.RightContent)
+		case "added":
+			whitespaceOnly = isBlank(line.RightContent)
+		case "deleted":
+			whitespaceOnly = isBlank(line.LeftContent)
+		}
+
+		if whitespaceOnly && opts.Whitespace == WhitespaceIgnore {
+			if line.Type != "modified" {
+				continue
+			}
+			line.Type = "context"
+			line.LeftHighlights, line.RightHighlights = nil, nil
+		} else {
+			line.WhitespaceOnly = whitespaceOnly
+		}
+		result = append(result, line)
+	}
+
+	return result
+}
+
+// applyWhitespaceMode pairs each run of deletions with the additions that follow
+// it and handles pairs that differ only in whitespace, plus added or removed blank lines
+func applyWhitespaceMode(lines []Line, mode WhitespaceMode) []Line {
+	result := make([]Line, 0, len(lines))
+
+	for i := 0; i < len(lines); {
+		if lines[i].Type == LineTypeContext {
+			result = append(result, lines[i])
+			i++
+			continue
+		}
+
+		// Collect a block of deletions followed by additions
+		var dels, adds []Line
+		for i < len(lines) && lines[i].Type == LineTypeDeletion {
+			dels = append(dels, lines[i])
+			i++
+		}
+		for i < len(lines) && lines[i].Type == LineTypeAddition {
+			adds = append(adds, lines[i])
+			i++
+		}
+
+		result = append(result, whitespaceBlock(dels, adds, mode)...)
+	}
+
+	return result
+}
+
+// whitespaceBlock applies mode to one block of deletions and additions
+func whitespaceBlock(dels, adds []Line, mode WhitespaceMode) []Line {
+	paired := len(dels)
+	if len(adds) < paired {
+		paired = len(adds)
+	}
+
+	var out, pendingDels, pendingAdds []Line
+	flush := func() {
+		out = append(out, pendingDels...)
+		out = append(out, pendingAdds...)
+		pendingDels, pendingAdds = nil, nil
+	}
+	keep := func(line Line, pending *[]Line, content string) {
+		if isBlank(content) {
+			if mode == WhitespaceIgnore {
+				return
+			}
+			line.WhitespaceOnly = true
+		}
+		*pending = append(*pending, line)
+	}
+
+	for j := 0; j < paired; j++ {
+		del, add := dels[j], adds[j]
+		if !equalIgnoringWhitespace(del.Content, add.Content) {
+			pendingDels = append(pendingDels, del)
+			pendingAdds = append(pendingAdds, add)
+			continue
+		}
+
+		if mode == WhitespaceIgnore {
+			// Show the line once, as it reads now
+			flush()
+			out = append(out, Line{
+				Type:    LineTypeContext,
+				Content: add.Content,
+				OldNum:  del.OldNum,
+				NewNum:  add.NewNum,
+			})
+			continue
+		}
+		del.WhitespaceOnly = true
+		add.WhitespaceOnly = true
+		pendingDels = append(pendingDels, del)
+		pendingAdds = append(pendingAdds, add)
+	}
+
+	for _, del := range dels[paired:] {
+		keep(del, &pendingDels, del.Content)
+	}
+	for _, add := range adds[paired:] {
+		keep(add, &pendingAdds, add.Content)
+	}
+	flush()
+
+	return out
+}
+
+// hasChanges reports whether lines contain any addition or deletion
+func hasChanges(lines []Line) bool {
+	for _, line := range lines {
+		if line.Type != LineTypeContext {
+			return true
+		}
+	}
+	return false
+}
+
+// equalIgnoringWhitespace compares two lines with all whitespace removed
+func equalIgnoringWhitespace(a, b string) bool {
+	return stripWhitespace(a) == stripWhitespace(b)
+}
+
+// stripWhitespace removes every whitespace character from s
+func stripWhitespace(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// isBlank reports whether a line contains only whitespace
+func isBlank(s string) bool {
+	return strings.TrimSpace(s) == ""
+}
@@ -0,0 +1,149 @@
+package diff
+
+import (
+	"testing"
+)
+
+const whitespaceDiff = `diff --git a/main.go b/main.go
+--- a/main.go
++++ b/main.go
+@@ -1,5 +1,6 @@
+ func main() {
+-  x := 1
+-  y := 2
++	x := 1
++	y := 3
++
+ 	run(x, y)
+ }
+diff --git a/fmt.go b/fmt.go
+--- a/fmt.go
++++ b/fmt.go
+@@ -1,2 +1,2 @@
+-if(x){
++if (x) {
+ }
+`
+
+func TestParseUnifiedDiffWithOptionsShow(t *testing.T) {
+	diffs, err := ParseUnifiedDiffWithOptions(whitespaceDiff, Options{})
+	if err != nil {
+		t.Fatalf("ParseUnifiedDiffWithOptions() error = %v", err)
+	}
+	if len(diffs) != 2 {
+		t.Fatalf("Expected 2 files, got %d", len(diffs))
+	}
+	for _, line := range diffs[0].Hunks[0].Lines {
+		if line.WhitespaceOnly {
+			t.Errorf("Expected no whitespace marks, got %+v", line)
+		}
+	}
+}
+
+func TestParseUnifiedDiffWithOptionsIgnore(t *testing.T) {
+	diffs, err := ParseUnifiedDiffWithOptions(whitespaceDiff, Options{Whitespace: WhitespaceIgnore})
+	if err != nil {
+		t.Fatalf("ParseUnifiedDiffWithOptions() error = %v", err)
+	}
+
+	// fmt.go only changed whitespace
+	if len(diffs) != 1 || diffs[0].NewPath != "main.go" {
+		t.Fatalf("Expected only main.go to remain, got %+v", diffs)
+	}
+
+	expected := []Line{
+		{Type: LineTypeContext, Content: "func main() {", OldNum: 1, NewNum: 1},
+		{Type: LineTypeContext, Content: "\tx := 1", OldNum: 2, NewNum: 2},
+		{Type: LineTypeDeletion, Content: "  y := 2", OldNum: 3},
+		{Type: LineTypeAddition, Content: "\ty := 3", NewNum: 3},
+		{Type: LineTypeContext, Content: "\trun(x, y)", OldNum: 4, NewNum: 5},
+		{Type: LineTypeContext, Content: "}", OldNum: 5, NewNum: 6},
+	}
+	lines := diffs[0].Hunks[0].Lines
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got %d: %+v", len(expected), len(lines), lines)
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("Line %d: expected %+v, got %+v", i, expected[i], lines[i])
+		}
+	}
+}
+
+func TestParseUnifiedDiffWithOptionsMark(t *testing.T) {
+	diffs, err := ParseUnifiedDiffWithOptions(whitespaceDiff, Options{Whitespace: WhitespaceMark})
+	if err != nil {
+		t.Fatalf("ParseUnifiedDiffWithOptions() error = %v", err)
+	}
+	if len(diffs) != 2 {
+		t.Fatalf("Expected both files to remain, got %d", len(diffs))
+	}
+
+	tests := []struct {
+		content        string
+		lineType       LineType
+		whitespaceOnly bool
+	}{
+		{"func main() {", LineTypeContext, false},
+		{"  x := 1", LineTypeDeletion, true},
+		{"  y := 2", LineTypeDeletion, false},
+		{"\tx := 1", LineTypeAddition, true},
+		{"\ty := 3", LineTypeAddition, false},
+		{"", LineTypeAddition, true},
+		{"\trun(x, y)", LineTypeContext, false},
+		{"}", LineTypeContext, false},
+	}
+
+	lines := diffs[0].Hunks[0].Lines
+	if len(lines) != len(tests) {
+		t.Fatalf("Expected %d lines, got %d: %+v", len(tests), len(lines), lines)
+	}
+	for i, tt := range tests {
+		if lines[i].Content != tt.content || lines[i].Type != tt.lineType || lines[i].WhitespaceOnly != tt.whitespaceOnly {
+			t.Errorf("Line %d: expected %q %s (whitespaceOnly=%v), got %+v", i, tt.content, tt.lineType, tt.whitespaceOnly, lines[i])
+		}
+	}
+
+	for _, line := range diffs[1].Hunks[0].Lines {
+		if line.Type != LineTypeContext && !line.WhitespaceOnly {
+			t.Errorf("Expected fmt.go change to be whitespace-only, got %+v", line)
+		}
+	}
+}
+
+func TestGenerateSideBySideWithOptions(t *testing.T) {
+	fileDiff := FileDiff{
+		NewPath: "a.go",
+		Hunks: []Hunk{{
+			Lines: []Line{
+				{Type: LineTypeDeletion, Content: "  x := 1", OldNum: 1},
+				{Type: LineTypeAddition, Content: "\tx := 1", NewNum: 1},
+				{Type: LineTypeDeletion, Content: "y := 2", OldNum: 2},
+				{Type: LineTypeAddition, Content: "y := 3", NewNum: 2},
+				{Type: LineTypeAddition, Content: "   ", NewNum: 3},
+			},
+		}},
+	}
+
+	ignored := GenerateSideBySideWithOptions(fileDiff, Options{Whitespace: WhitespaceIgnore})
+	if len(ignored) != 2 {
+		t.Fatalf("Expected 2 lines, got %d: %+v", len(ignored), ignored)
+	}
+	if ignored[0].Type != "context" || ignored[1].Type != "modified" {
+		t.Errorf("Expected context then modified, got %s, %s", ignored[0].Type, ignored[1].Type)
+	}
+
+	marked := GenerateSideBySideWithOptions(fileDiff, Options{Whitespace: WhitespaceMark})
+	if len(marked) != 3 {
+		t.Fatalf("Expected 3 lines, got %d: %+v", len(marked), marked)
+	}
+	if !marked[0].WhitespaceOnly || marked[0].Type != "modified" {
+		t.Errorf("Expected whitespace-only modification, got %+v", marked[0])
+	}
+	if marked[1].WhitespaceOnly {
+		t.Errorf("Expected real modification, got %+v", marked[1])
+	}
+	if !marked[2].WhitespaceOnly || marked[2].Type != "added" {
+		t.Errorf("Expected whitespace-only addition, got %+v", marked[2])
+	}
+}
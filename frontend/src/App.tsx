@@ -20,17 +20,55 @@ import { useSearch } from './hooks/useSearch';
 import { useDiff } from './hooks/useDiff';
 import { useStore } from './store';
 import { ListTodo, MessageSquare, FileCode } from 'lucide-react';
-import { ListAgentSessions, SetSessionFavorite, AddSessionTag, RemoveSessionTag } from '../wailsjs/go/main/App';
+import { ListAgentSessions, SetSessionFavorite, AddSessionTag, RemoveSessionTag, SetWindowFocused } from '../wailsjs/go/main/App';
+import { EventsOn, EventsOff } from '../wailsjs/runtime/runtime';
 import type { Task } from './types';
 
 type TabView = 'chat' | 'tasks' | 'diff';
 
+// playAttentionSound plays a short two-note chime
+function playAttentionSound() {
+  try {
+    const audio = new AudioContext();
+    [880, 660].forEach((frequency, i) => {
+      const oscillator = audio.createOscillator();
+      const gain = audio.createGain();
+      const start = audio.currentTime + i * 0.15;
+      oscillator.frequency.value = frequency;
+      gain.gain.setValueAtTime(0.2, start);
+      gain.gain.exponentialRampToValueAtTime(0.001, start + 0.3);
+      oscillator.connect(gain).connect(audio.destination);
+      oscillator.start(start);
+      oscillator.stop(start + 0.3);
+    });
+    setTimeout(() => audio.close(), 1000);
+  } catch (err) {
+    console.error('Failed to play attention sound:', err);
+  }
+}
+
+// approvalActionType picks the approval bar icon for a tool
+function approvalActionType(toolName?: string): 'edit' | 'bash' | 'other' {
+  switch (toolName) {
+    case 'Edit':
+    case 'MultiEdit':
+    case 'Write':
+    case 'NotebookEdit':
+      return 'edit';
+    case 'Bash':
+      return 'bash';
+    default:
+      return 'other';
+  }
+}
+
 function App() {
   const [activeTab, setActiveTab] = useState<TabView>('chat');
   const [firefighterDialogOpen, setFirefighterDialogOpen] = useState(false);
   const [boatmanModeDialogOpen, setBoatmanModeDialogOpen] = useState(false);
   const [monitoringActive, setMonitoringActive] = useState(false);
   const [selectedTask, setSelectedTask] = useState<Task | null>(null);
+  const [notification, setNotification] = useState<{ title: string; body: string } | null>(null);
 
   const {
     sidebarOpen,
@@ -57,6 +95,9 @@ function App() {
     isMonitoringActive,
   } = useAgent();
 
+  // The oldest tool call waiting for approval in the active session
+  const pendingAction = activeSession?.pendingActions?.[0];
+
   const {
     projects,
     activeProject,
@@ -106,6 +147,44 @@ function App() {
     }
   }, [activeProject, activeTab, loadDiffs]);
 
+  // Report window focus so notifications can skip events the user is watching
+  useEffect(() => {
+    const handleFocus = () => SetWindowFocused(true);
+    const handleBlur = () => SetWindowFocused(false);
+    window.addEventListener('focus', handleFocus);
+    window.addEventListener('blur', handleBlur);
+    return () => {
+      window.removeEventListener('focus', handleFocus);
+      window.removeEventListener('blur', handleBlur);
+    };
+  }, []);
+
+  // Chime when a session starts waiting on the user, if they asked for it
+  useEffect(() => {
+    EventsOn('app:attention', (data: { sound?: boolean }) => {
+      if (data.sound) {
+        playAttentionSound();
+      }
+    });
+    return () => EventsOff('app:attention');
+  }, []);
+
+  // Show notifications the system couldn't display in the app instead
+  useEffect(() => {
+    EventsOn('app:notification', (data: { title: string; body: string }) => {
+      setNotification(data);
+    });
+    return () => EventsOff('app:notification');
+  }, []);
+
+  // Dismiss notification after 5 seconds
+  useEffect(() => {
+    if (notification) {
+      const timer = setTimeout(() => setNotification(null), 5000);
+      return () => clearTimeout(timer);
+    }
+  }, [notification]);
+
   // Dismiss error after 5 seconds
   useEffect(() => {
     if (error) {
@@ -183,14 +262,14 @@ function App() {
   // Handle approval
   const handleApprove = async () => {
     if (activeSession) {
-      await approveAction(activeSession.id, '');
+      await approveAction(activeSession.id, pendingAction?.id ?? '');
     }
   };
 
   // Handle rejection
   const handleReject = async () => {
     if (activeSession) {
-      await rejectAction(activeSession.id, '');
+      await rejectAction(activeSession.id, pendingAction?.id ?? '');
     }
   };
 
@@ -295,6 +374,7 @@ function App() {
           onClose={() => setSettingsOpen(false)}
           preferences={preferences}
           onSave={savePreferences}
+          projectPath={activeSession?.projectPath}
         />
       )}
 
@@ -308,6 +388,17 @@ function App() {
         availableProjects={availableProjects}
       />
 
+      {/* Notification Toast */}
+      {notification && (
+        <div
+          className="fixed bottom-4 right-4 z-50 max-w-sm bg-slate-800 border border-slate-700 text-slate-100 px-4 py-3 rounded-lg shadow-lg cursor-pointer"
+          onClick={() => setNotification(null)}
+        >
+          <div className="font-medium">{notification.title}</div>
+          {notification.body && <div className="text-sm text-slate-400 mt-1">{notification.body}</div>}
+        </div>
+      )}
+
       {/* Error Toast */}
       {error && (
         <div className="fixed top-4 right-4 z-50 bg-red-500 text-white px-4 py-2 rounded-lg shadow-lg">
@@ -470,6 +561,9 @@ function App() {
         visible={isWaitingForApproval}
         onApprove={handleApprove}
         onReject={handleReject}
+        actionType={approvalActionType(pendingAction?.toolName)}
+        actionDescription={pendingAction?.description}
+        filePath={pendingAction?.filePath}
       />
     </div>
   );
@@ -48,6 +48,12 @@ export function ChatView({
         return 'An error occurred';
       case 'stopped':
         return 'Session stopped';
+      case 'offline':
+        return 'Offline - queued messages will send when the connection returns';
+      case 'rate-limited':
+        return 'Rate limited - resuming automatically when the limit resets';
+      case 'queued':
+        return 'Waiting for a slot - other sessions are running';
       default:
         return null;
     }
@@ -80,6 +80,9 @@ export const MessageBubble = memo(function MessageBubble({ message }: MessageBub
           <span className="text-xs text-slate-500">
             {new Date(message.timestamp).toLocaleTimeString()}
           </span>
+          {message.metadata?.queued && (
+            <span className="text-xs text-amber-400">queued (offline)</span>
+          )}
         </div>
         <div
           className={`inline-block text-left rounded-lg border px-4 py-3 ${getBubbleStyles()}`}
@@ -1,4 +1,5 @@
-import type { LineType } from '../../types';
+import { ReactNode } from 'react';
+import type { HighlightRange, LineType } from '../../types';
 
 interface DiffLineProps {
   type: LineType;
@@ -77,6 +78,34 @@ interface SideBySideLineProps {
   rightNum?: number;
   rightContent?: string;
   type: 'context' | 'added' | 'deleted' | 'modified';
+  leftHighlights?: HighlightRange[];
+  rightHighlights?: HighlightRange[];
+}
+
+// Wraps the changed ranges of a line, given as code point offsets, in marks
+function renderHighlighted(content: string, ranges: HighlightRange[] | undefined, markClass: string) {
+  if (!ranges || ranges.length === 0) {
+    return content;
+  }
+
+  const chars = Array.from(content);
+  const parts: ReactNode[] = [];
+  let pos = 0;
+  ranges.forEach((range, index) => {
+    if (range.start > pos) {
+      parts.push(chars.slice(pos, range.start).join(''));
+    }
+    parts.push(
+      <mark key={index} className={`${markClass} text-inherit rounded-sm`}>
+        {chars.slice(range.start, range.end).join('')}
+      </mark>
+    );
+    pos = range.end;
+  });
+  if (pos < chars.length) {
+    parts.push(chars.slice(pos).join(''));
+  }
+  return parts;
 }
 
 export function SideBySideLine({
@@ -85,6 +114,8 @@ export function SideBySideLine({
   rightNum,
   rightContent,
   type,
+  leftHighlights,
+  rightHighlights,
 }: SideBySideLineProps) {
   const getLeftStyles = () => {
     switch (type) {
@@ -114,7 +145,7 @@ export function SideBySideLine({
           {leftNum || ''}
         </span>
         <pre className="flex-1 px-2 text-slate-200 overflow-x-auto whitespace-pre border-r border-slate-700">
-          {leftContent ?? ''}
+          {renderHighlighted(leftContent ?? '', leftHighlights, 'bg-red-500/30')}
         </pre>
       </div>
       {/* Right side */}
@@ -123,7 +154,7 @@ export function SideBySideLine({
           {rightNum || ''}
         </span>
         <pre className="flex-1 px-2 text-slate-200 overflow-x-auto whitespace-pre">
-          {rightContent ?? ''}
+          {renderHighlighted(rightContent ?? '', rightHighlights, 'bg-green-500/30')}
         </pre>
       </div>
     </div>
@@ -233,6 +233,8 @@ export function DiffView({
                         rightNum={line.rightNum}
                         rightContent={line.rightContent}
                         type={line.type as any}
+                        leftHighlights={line.leftHighlights}
+                        rightHighlights={line.rightHighlights}
                       />
                     ))}
                   </div>
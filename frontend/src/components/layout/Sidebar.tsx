@@ -41,6 +41,10 @@ function getStatusColor(status: SessionStatus): string {
       return 'text-red-500';
     case 'stopped':
       return 'text-slate-400';
+    case 'offline':
+    case 'rate-limited':
+    case 'queued':
+      return 'text-amber-500';
     default:
       return 'text-slate-500';
   }
@@ -111,7 +115,7 @@ export function Sidebar({
                   }`}
                 >
                   <Folder className="w-4 h-4 flex-shrink-0" />
-                  <span className="truncate">{project.name}</span>
+                  <span className="truncate" title={project.path}>{project.displayName || project.name}</span>
                 </button>
               ))
             )}
@@ -316,7 +320,7 @@ export function Sidebar({
             className="w-full flex items-center gap-2 px-2 py-1 text-xs text-slate-400 hover:text-slate-200 hover:bg-slate-800 rounded transition-colors"
           >
             <MessageSquare className="w-3 h-3" />
-            <span className="truncate">{project.name}</span>
+            <span className="truncate" title={project.path}>{project.displayName || project.name}</span>
           </button>
         ))}
       </div>
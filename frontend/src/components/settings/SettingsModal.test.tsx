@@ -459,7 +459,7 @@ describe('SettingsModal', () => {
         />
       );
 
-      const checkbox = screen.getByRole('checkbox');
+      const checkbox = screen.getByRole('checkbox', { name: /Desktop Notifications/ });
       expect(checkbox).toBeChecked();
     });
 
@@ -473,11 +473,40 @@ describe('SettingsModal', () => {
         />
       );
 
-      const checkbox = screen.getByRole('checkbox');
+      const checkbox = screen.getByRole('checkbox', { name: /Desktop Notifications/ });
       fireEvent.click(checkbox);
 
       expect(checkbox).not.toBeChecked();
     });
+
+    it('should save per-event notification settings', () => {
+      render(
+        <SettingsModal
+          isOpen={true}
+          onClose={mockOnClose}
+          preferences={mockPreferences}
+          onSave={mockOnSave}
+        />
+      );
+
+      fireEvent.click(screen.getByRole('checkbox', { name: 'Task finished' }));
+      fireEvent.click(screen.getByRole('checkbox', { name: 'Quiet hours' }));
+      fireEvent.change(screen.getByLabelText('Quiet hours start'), { target: { value: '21:30' } });
+
+      fireEvent.click(screen.getByRole('button', { name: 'Save Changes' }));
+
+      expect(mockOnSave).toHaveBeenCalledWith(
+        expect.objectContaining({
+          notifications: expect.objectContaining({
+            onWaiting: true,
+            onCompleted: false,
+            quietHoursEnabled: true,
+            quietHoursStart: '21:30',
+            quietHoursEnd: '08:00',
+          }),
+        })
+      );
+    });
   });
 
   describe('General Settings - Model Selection', () => {
@@ -546,6 +575,30 @@ describe('SettingsModal', () => {
     });
   });
 
+  describe('General Settings - System Prompt', () => {
+    it('should save the system prompt snippet', () => {
+      render(
+        <SettingsModal
+          isOpen={true}
+          onClose={mockOnClose}
+          preferences={mockPreferences}
+          onSave={mockOnSave}
+        />
+      );
+
+      expect(screen.getByText('System Prompt')).toBeInTheDocument();
+      expect(screen.queryByText('Project Addition')).not.toBeInTheDocument();
+
+      const textarea = screen.getByPlaceholderText(/Always write tests/);
+      fireEvent.change(textarea, { target: { value: 'Answer in French.' } });
+      fireEvent.click(screen.getByRole('button', { name: 'Save Changes' }));
+
+      expect(mockOnSave).toHaveBeenCalledWith(
+        expect.objectContaining({ systemPromptAppend: 'Answer in French.' })
+      );
+    });
+  });
+
   describe('Approval Settings', () => {
     beforeEach(() => {
       render(
@@ -795,7 +848,7 @@ describe('SettingsModal', () => {
       fireEvent.change(modelSelect, { target: { value: 'opus' } });
 
       // Toggle notifications
-      const checkbox = screen.getByRole('checkbox');
+      const checkbox = screen.getByRole('checkbox', { name: /Desktop Notifications/ });
       fireEvent.click(checkbox);
 
       const saveButton = screen.getByRole('button', { name: 'Save Changes' });
@@ -1,7 +1,7 @@
 import { useState, useEffect } from 'react';
 import { X, Moon, Sun, Bell, BellOff, Shield, Zap, Bot, Server, Key, Eye, EyeOff, Database, Trash2, Plus, Flame } from 'lucide-react';
-import type { UserPreferences, ApprovalMode, Theme, MCPServer } from '../../types';
-import { CleanupOldSessions, GetSessionStats, GetMCPServers, GetMCPPresets, AddMCPServer, RemoveMCPServer, UpdateMCPServer } from '../../../wailsjs/go/main/App';
+import type { UserPreferences, ApprovalMode, Theme, MCPServer, NotificationSettings } from '../../types';
+import { CleanupOldSessions, GetSessionStats, GetMCPServers, GetMCPPresets, AddMCPServer, RemoveMCPServer, UpdateMCPServer, GetProjectSystemPrompt, SetProjectSystemPrompt } from '../../../wailsjs/go/main/App';
 import { MCPServerDialog } from './MCPServerDialog';
 import { GCloudAuthSection } from './GCloudAuthSection';
 import { FirefighterSettings } from './FirefighterSettings';
@@ -11,18 +11,33 @@ interface SettingsModalProps {
   onClose: () => void;
   preferences: UserPreferences;
   onSave: (preferences: UserPreferences) => void;
+  projectPath?: string;
 }
 
 type SettingsTab = 'general' | 'approval' | 'memory' | 'mcp' | 'firefighter' | 'about';
 
-export function SettingsModal({ isOpen, onClose, preferences, onSave }: SettingsModalProps) {
+export function SettingsModal({ isOpen, onClose, preferences, onSave, projectPath }: SettingsModalProps) {
   const [activeTab, setActiveTab] = useState<SettingsTab>('general');
   const [localPrefs, setLocalPrefs] = useState<UserPreferences>(preferences);
+  const [projectPrompt, setProjectPrompt] = useState('');
+
+  useEffect(() => {
+    if (isOpen && projectPath) {
+      GetProjectSystemPrompt(projectPath)
+        .then((prompt) => setProjectPrompt(prompt || ''))
+        .catch((err) => console.error('Failed to load project system prompt:', err));
+    }
+  }, [isOpen, projectPath]);
 
   if (!isOpen) return null;
 
   const handleSave = () => {
     onSave(localPrefs);
+    if (projectPath) {
+      SetProjectSystemPrompt(projectPath, projectPrompt).catch((err) =>
+        console.error('Failed to save project system prompt:', err)
+      );
+    }
     onClose();
   };
 
@@ -78,6 +93,9 @@ export function SettingsModal({ isOpen, onClose, preferences, onSave }: Settings
               <GeneralSettings
                 preferences={localPrefs}
                 onChange={setLocalPrefs}
+                projectPath={projectPath}
+                projectPrompt={projectPrompt}
+                onProjectPromptChange={setProjectPrompt}
               />
             )}
             {activeTab === 'approval' && (
@@ -145,14 +163,36 @@ export function SettingsModal({ isOpen, onClose, preferences, onSave }: Settings
 }
 
 // General Settings Tab
+// Matches config.DefaultNotificationSettings for configs saved before these settings existed
+const DEFAULT_NOTIFICATION_SETTINGS: NotificationSettings = {
+  onWaiting: true,
+  onCompleted: true,
+  onError: true,
+  onAlert: true,
+  minTurnSeconds: 30,
+  onlyWhenUnfocused: true,
+  quietHoursEnabled: false,
+  quietHoursStart: '22:00',
+  quietHoursEnd: '08:00',
+};
+
 function GeneralSettings({
   preferences,
   onChange,
+  projectPath,
+  projectPrompt,
+  onProjectPromptChange,
 }: {
   preferences: UserPreferences;
   onChange: (prefs: UserPreferences) => void;
+  projectPath?: string;
+  projectPrompt: string;
+  onProjectPromptChange: (prompt: string) => void;
 }) {
   const [showApiKey, setShowApiKey] = useState(false);
+  const notifications = preferences.notifications ?? DEFAULT_NOTIFICATION_SETTINGS;
+  const updateNotifications = (changes: Partial<NotificationSettings>) =>
+    onChange({ ...preferences, notifications: { ...notifications, ...changes } });
 
   return (
     <div className="space-y-6">
@@ -279,6 +319,83 @@ function GeneralSettings({
             className="w-4 h-4 rounded"
           />
         </label>
+        {preferences.notificationsEnabled && (
+          <div className="mt-3 p-4 rounded-lg border border-slate-700 space-y-3">
+            {([
+              ['onWaiting', 'Session needs approval'],
+              ['onCompleted', 'Task finished'],
+              ['onError', 'Session errored'],
+              ['onAlert', 'Firefighter alert received'],
+              ['onlyWhenUnfocused', 'Only when Boatman is in the background'],
+              ['attentionSound', 'Play a sound when a session needs attention'],
+            ] as const).map(([key, label]) => (
+              <label key={key} className="flex items-center justify-between cursor-pointer">
+                <span className="text-sm text-slate-100">{label}</span>
+                <input
+                  type="checkbox"
+                  checked={notifications[key] ?? false}
+                  onChange={(e) => updateNotifications({ [key]: e.target.checked })}
+                  className="w-4 h-4 rounded"
+                />
+              </label>
+            ))}
+            <label className="flex items-center justify-between">
+              <span className="text-sm text-slate-100">Minimum task duration (seconds)</span>
+              <input
+                type="number"
+                min={0}
+                value={notifications.minTurnSeconds}
+                onChange={(e) =>
+                  updateNotifications({ minTurnSeconds: Math.max(0, parseInt(e.target.value) || 0) })
+                }
+                className="w-20 px-2 py-1 bg-slate-800 border border-slate-700 rounded text-sm text-slate-100 focus:outline-none focus:border-blue-500"
+              />
+            </label>
+            <label className="flex items-center justify-between">
+              <span className="text-sm text-slate-100">Session cost over ($, 0 = off)</span>
+              <input
+                type="number"
+                min={0}
+                step={0.5}
+                value={notifications.costThreshold ?? 0}
+                onChange={(e) =>
+                  updateNotifications({ costThreshold: Math.max(0, parseFloat(e.target.value) || 0) })
+                }
+                className="w-20 px-2 py-1 bg-slate-800 border border-slate-700 rounded text-sm text-slate-100 focus:outline-none focus:border-blue-500"
+              />
+            </label>
+            <div className="flex items-center justify-between">
+              <label className="flex items-center gap-2 cursor-pointer">
+                <input
+                  type="checkbox"
+                  checked={notifications.quietHoursEnabled}
+                  onChange={(e) => updateNotifications({ quietHoursEnabled: e.target.checked })}
+                  className="w-4 h-4 rounded"
+                />
+                <span className="text-sm text-slate-100">Quiet hours</span>
+              </label>
+              <div className="flex items-center gap-2">
+                <input
+                  type="time"
+                  aria-label="Quiet hours start"
+                  value={notifications.quietHoursStart}
+                  disabled={!notifications.quietHoursEnabled}
+                  onChange={(e) => updateNotifications({ quietHoursStart: e.target.value })}
+                  className="px-2 py-1 bg-slate-800 border border-slate-700 rounded text-sm text-slate-100 disabled:opacity-50"
+                />
+                <span className="text-xs text-slate-400">to</span>
+                <input
+                  type="time"
+                  aria-label="Quiet hours end"
+                  value={notifications.quietHoursEnd}
+                  disabled={!notifications.quietHoursEnabled}
+                  onChange={(e) => updateNotifications({ quietHoursEnd: e.target.value })}
+                  className="px-2 py-1 bg-slate-800 border border-slate-700 rounded text-sm text-slate-100 disabled:opacity-50"
+                />
+              </div>
+            </div>
+          </div>
+        )}
       </div>
 
       <div>
@@ -297,6 +414,158 @@ function GeneralSettings({
           <option value="claude-3-5-sonnet-20241022">Claude 3.5 Sonnet</option>
         </select>
       </div>
+
+      <div>
+        <h3 className="text-sm font-medium text-slate-100 mb-2">Claude CLI Path</h3>
+        <p className="text-xs text-slate-400 mb-3">
+          Leave empty to find <span className="font-mono">claude</span> on your PATH or in the usual install locations
+        </p>
+        <input
+          type="text"
+          value={preferences.claudeCLIPath || ''}
+          onChange={(e) => onChange({ ...preferences, claudeCLIPath: e.target.value })}
+          placeholder="/usr/local/bin/claude"
+          className="w-full px-4 py-2 bg-slate-800 border border-slate-700 rounded-lg text-sm font-mono text-slate-100 placeholder-slate-500 focus:outline-none focus:border-blue-500"
+        />
+      </div>
+
+      <div>
+        <h3 className="text-sm font-medium text-slate-100 mb-2">Retries</h3>
+        <p className="text-xs text-slate-400 mb-3">
+          Runs that fail on API server errors or a crashed claude process are retried, waiting twice as long each time. Set retries to -1 to turn this off.
+        </p>
+        <div className="grid grid-cols-2 gap-4">
+          <div>
+            <label htmlFor="run-retries" className="block text-sm text-slate-300 mb-2">
+              Retries
+            </label>
+            <input
+              id="run-retries"
+              type="number"
+              min="-1"
+              step="1"
+              placeholder="3"
+              value={preferences.runRetries || ''}
+              onChange={(e) =>
+                onChange({
+                  ...preferences,
+                  runRetries: parseInt(e.target.value) || 0,
+                })
+              }
+              className="w-full px-4 py-2 bg-slate-800 border border-slate-700 rounded-lg text-sm text-slate-100 focus:outline-none focus:border-blue-500"
+            />
+          </div>
+          <div>
+            <label htmlFor="run-retry-delay" className="block text-sm text-slate-300 mb-2">
+              First Retry After (seconds)
+            </label>
+            <input
+              id="run-retry-delay"
+              type="number"
+              min="0"
+              step="1"
+              placeholder="2"
+              value={preferences.runRetryDelaySeconds || ''}
+              onChange={(e) =>
+                onChange({
+                  ...preferences,
+                  runRetryDelaySeconds: parseInt(e.target.value) || 0,
+                })
+              }
+              className="w-full px-4 py-2 bg-slate-800 border border-slate-700 rounded-lg text-sm text-slate-100 focus:outline-none focus:border-blue-500"
+            />
+          </div>
+        </div>
+      </div>
+
+      <div>
+        <label htmlFor="max-concurrent-runs" className="block text-sm font-medium text-slate-100 mb-2">
+          Concurrent Runs
+        </label>
+        <p className="text-xs text-slate-400 mb-3">
+          How many sessions may run claude at once. Others wait for a slot. Set to -1 for no limit.
+        </p>
+        <input
+          id="max-concurrent-runs"
+          type="number"
+          min="-1"
+          step="1"
+          placeholder="4"
+          value={preferences.maxConcurrentRuns || ''}
+          onChange={(e) =>
+            onChange({
+              ...preferences,
+              maxConcurrentRuns: parseInt(e.target.value) || 0,
+            })
+          }
+          className="w-full px-4 py-2 bg-slate-800 border border-slate-700 rounded-lg text-sm text-slate-100 focus:outline-none focus:border-blue-500"
+        />
+      </div>
+
+      <div>
+        <h3 className="text-sm font-medium text-slate-100 mb-2">Pull Requests</h3>
+        <p className="text-xs text-slate-400 mb-3">
+          Tokens for opening pull requests on GitHub and merge requests on GitLab. GitHub tokens need pull request write access; GitLab tokens need the api scope.
+        </p>
+        <div className="grid grid-cols-2 gap-4">
+          <div>
+            <label htmlFor="github-token" className="block text-sm text-slate-300 mb-2">
+              GitHub Token
+            </label>
+            <input
+              id="github-token"
+              type="password"
+              value={preferences.githubToken || ''}
+              onChange={(e) => onChange({ ...preferences, githubToken: e.target.value })}
+              placeholder="ghp_..."
+              className="w-full px-4 py-2 bg-slate-800 border border-slate-700 rounded-lg text-sm text-slate-100 placeholder-slate-500 focus:outline-none focus:border-blue-500"
+            />
+          </div>
+          <div>
+            <label htmlFor="gitlab-token" className="block text-sm text-slate-300 mb-2">
+              GitLab Token
+            </label>
+            <input
+              id="gitlab-token"
+              type="password"
+              value={preferences.gitlabToken || ''}
+              onChange={(e) => onChange({ ...preferences, gitlabToken: e.target.value })}
+              placeholder="glpat-..."
+              className="w-full px-4 py-2 bg-slate-800 border border-slate-700 rounded-lg text-sm text-slate-100 placeholder-slate-500 focus:outline-none focus:border-blue-500"
+            />
+          </div>
+        </div>
+      </div>
+
+      <div>
+        <h3 className="text-sm font-medium text-slate-100 mb-2">System Prompt</h3>
+        <p className="text-xs text-slate-400 mb-3">
+          Instructions appended to every session, such as coding style, language, or safety rules
+        </p>
+        <textarea
+          value={preferences.systemPromptAppend || ''}
+          onChange={(e) => onChange({ ...preferences, systemPromptAppend: e.target.value })}
+          placeholder="Always write tests for new code. Prefer small, focused commits."
+          rows={4}
+          className="w-full px-4 py-2 bg-slate-800 border border-slate-700 rounded-lg text-sm text-slate-100 placeholder-slate-500 focus:outline-none focus:border-blue-500"
+        />
+        {projectPath && (
+          <div className="mt-4">
+            <h4 className="text-sm text-slate-100 mb-1">Project Addition</h4>
+            <p className="text-xs text-slate-400 mb-3">
+              Appended after the system prompt for sessions in{' '}
+              <span className="font-mono text-slate-300">{projectPath}</span>
+            </p>
+            <textarea
+              value={projectPrompt}
+              onChange={(e) => onProjectPromptChange(e.target.value)}
+              placeholder="This project uses Go 1.23 and table-driven tests."
+              rows={3}
+              className="w-full px-4 py-2 bg-slate-800 border border-slate-700 rounded-lg text-sm text-slate-100 placeholder-slate-500 focus:outline-none focus:border-blue-500"
+            />
+          </div>
+        )}
+      </div>
     </div>
   );
 }
@@ -591,6 +860,60 @@ function MemorySettings({
           </label>
         </div>
       </div>
+
+      <div className="pt-6 border-t border-slate-700">
+        <h3 className="text-sm font-medium text-slate-100 mb-2">Cost Budgets</h3>
+        <p className="text-xs text-slate-400 mb-4">
+          Warn at 80% of a budget and block new messages once it is reached. Leave empty for no limit.
+        </p>
+
+        <div className="space-y-4">
+          <div>
+            <label htmlFor="max-cost-per-session" className="block text-sm text-slate-300 mb-2">
+              Max Cost Per Session (USD)
+            </label>
+            <input
+              id="max-cost-per-session"
+              type="number"
+              min="0"
+              step="0.5"
+              placeholder="No limit"
+              value={preferences.maxCostPerSession || ''}
+              onChange={(e) =>
+                onChange({
+                  ...preferences,
+                  maxCostPerSession: parseFloat(e.target.value) || 0,
+                })
+              }
+              className="w-full px-4 py-2 bg-slate-800 border border-slate-700 rounded-lg text-sm text-slate-100 focus:outline-none focus:border-blue-500"
+            />
+          </div>
+
+          <div>
+            <label htmlFor="max-cost-per-day" className="block text-sm text-slate-300 mb-2">
+              Max Cost Per Day (USD)
+            </label>
+            <input
+              id="max-cost-per-day"
+              type="number"
+              min="0"
+              step="1"
+              placeholder="No limit"
+              value={preferences.maxCostPerDay || ''}
+              onChange={(e) =>
+                onChange({
+                  ...preferences,
+                  maxCostPerDay: parseFloat(e.target.value) || 0,
+                })
+              }
+              className="w-full px-4 py-2 bg-slate-800 border border-slate-700 rounded-lg text-sm text-slate-100 focus:outline-none focus:border-blue-500"
+            />
+            <p className="text-xs text-slate-500 mt-1">
+              Counts spending across all sessions since local midnight
+            </p>
+          </div>
+        </div>
+      </div>
     </div>
   );
 }
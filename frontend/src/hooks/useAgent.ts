@@ -1,6 +1,6 @@
 import { useEffect, useCallback } from 'react';
 import { useStore } from '../store';
-import type { AgentSession, Message, Task, SessionStatus, BoatmanModeEventPayload } from '../types';
+import type { AgentSession, Message, Task, SessionStatus, BoatmanModeEventPayload, PendingAction } from '../types';
 
 // Import Wails bindings (will be generated)
 import {
@@ -34,7 +34,9 @@ export function useAgent() {
     removeSession,
     setActiveSession,
     updateSessionStatus,
+    setPendingActions,
     addMessage,
+    appendMessageDelta,
     setMessages,
     appendMessages,
     setMessagePagination,
@@ -51,6 +53,11 @@ export function useAgent() {
       addMessage(data.sessionId, data.message);
     };
 
+    // Streamed text arrives as deltas between whole copies of the message
+    const messageDeltaHandler = (data: { sessionId: string; messageId: string; delta: string }) => {
+      appendMessageDelta(data.sessionId, data.messageId, data.delta);
+    };
+
     const taskHandler = (data: { sessionId: string; task: Task }) => {
       console.log('[FRONTEND] Received task event:', data);
       updateTask(data.sessionId, data.task);
@@ -61,6 +68,27 @@ export function useAgent() {
       updateSessionStatus(data.sessionId, data.status);
     };
 
+    const approvalHandler = (data: { sessionId: string; actions: PendingAction[] }) => {
+      setPendingActions(data.sessionId, data.actions);
+    };
+
+    // The history was rewritten (e.g. a message was edited and resent), so
+    // reload its newest page in place of the messages shown
+    const historyHandler = async (data: { sessionId: string; total: number }) => {
+      console.log('[FRONTEND] Received history event:', data);
+      try {
+        const result = await GetAgentMessagesPaginated(data.sessionId, 0, 50);
+        setMessages(data.sessionId, result.messages as unknown as Message[]);
+        setMessagePagination(data.sessionId, {
+          page: result.page,
+          hasMore: result.hasMore,
+          total: result.total,
+        });
+      } catch (err) {
+        console.error('Failed to reload message history:', err);
+      }
+    };
+
     const boatmanModeEventHandler = async (data: BoatmanModeEventPayload) => {
       console.log('[FRONTEND] Received boatmanmode event:', data);
       try {
@@ -73,18 +101,24 @@ export function useAgent() {
 
     console.log('[FRONTEND] Subscribing to agent events...');
     EventsOn('agent:message', messageHandler);
+    EventsOn('agent:message-delta', messageDeltaHandler);
     EventsOn('agent:task', taskHandler);
     EventsOn('agent:status', statusHandler);
+    EventsOn('agent:approval', approvalHandler);
+    EventsOn('agent:history', historyHandler);
     EventsOn('boatmanmode:event', boatmanModeEventHandler);
 
     return () => {
       console.log('[FRONTEND] Unsubscribing from agent events...');
       EventsOff('agent:message');
+      EventsOff('agent:message-delta');
       EventsOff('agent:task');
       EventsOff('agent:status');
+      EventsOff('agent:approval');
+      EventsOff('agent:history');
       EventsOff('boatmanmode:event');
     };
-  }, [addMessage, updateTask, updateSessionStatus]);
+  }, [addMessage, appendMessageDelta, updateTask, updateSessionStatus, setPendingActions, setMessages, setMessagePagination]);
 
   // Load existing sessions on mount
   useEffect(() => {
@@ -303,7 +337,8 @@ export function useAgent() {
         setLoading('messages', true);
         const result = await GetAgentMessagesPaginated(sessionId, page, pageSize);
 
-        // If it's the first page, replace messages; otherwise append
+        // Page 0 is the newest messages and replaces them; later pages are
+        // older and go before them
         if (page === 0) {
           setMessages(sessionId, result.messages as unknown as Message[]);
         } else {
@@ -336,11 +371,11 @@ export function useAgent() {
     }
   }, [setTasks]);
 
-  // Select a session
+  // Select a session, showing its newest page of messages
   const selectSession = useCallback(async (sessionId: string) => {
     setActiveSession(sessionId);
-    await Promise.all([loadMessages(sessionId), loadTasks(sessionId)]);
-  }, [setActiveSession, loadMessages, loadTasks]);
+    await Promise.all([loadMessagesPaginated(sessionId, 0), loadTasks(sessionId)]);
+  }, [setActiveSession, loadMessagesPaginated, loadTasks]);
 
   // Get active session
   const activeSession = sessions.find((s) => s.id === activeSessionId) ?? null;
@@ -7,6 +7,7 @@ import type {
   Project,
   UserPreferences,
   SessionStatus,
+  PendingAction,
 } from '../types';
 
 // =============================================================================
@@ -31,9 +32,11 @@ interface AgentActions {
   removeSession: (sessionId: string) => void;
   setActiveSession: (sessionId: string | null) => void;
   updateSessionStatus: (sessionId: string, status: SessionStatus) => void;
+  setPendingActions: (sessionId: string, pendingActions: PendingAction[]) => void;
 
   // Messages
   addMessage: (sessionId: string, message: Message) => void;
+  appendMessageDelta: (sessionId: string, messageId: string, delta: string) => void;
   setMessages: (sessionId: string, messages: Message[]) => void;
   appendMessages: (sessionId: string, messages: Message[]) => void;
 
@@ -176,6 +179,17 @@ export const useStore = create<Store>()(
             'updateSessionStatus'
           ),
 
+        setPendingActions: (sessionId, pendingActions) =>
+          set(
+            (state) => ({
+              sessions: state.sessions.map((s) =>
+                s.id === sessionId ? { ...s, pendingActions } : s
+              ),
+            }),
+            false,
+            'setPendingActions'
+          ),
+
         addMessage: (sessionId, message) => {
           console.log('[STORE] addMessage called:', { sessionId, message });
           set(
@@ -201,6 +215,28 @@ export const useStore = create<Store>()(
           );
         },
 
+        appendMessageDelta: (sessionId, messageId, delta) =>
+          set(
+            (state) => ({
+              sessions: state.sessions.map((s) => {
+                if (s.id !== sessionId) return s;
+
+                // A delta for a message not loaded is caught up by the next full sync
+                const index = s.messages.findIndex((m) => m.id === messageId);
+                if (index === -1) return s;
+
+                const newMessages = [...s.messages];
+                newMessages[index] = {
+                  ...newMessages[index],
+                  content: newMessages[index].content + delta,
+                };
+                return { ...s, messages: newMessages };
+              }),
+            }),
+            false,
+            'appendMessageDelta'
+          ),
+
         setMessages: (sessionId, messages) =>
           set(
             (state) => ({
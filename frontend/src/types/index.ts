@@ -2,7 +2,7 @@
 // Agent Types
 // =============================================================================
 
-export type SessionStatus = 'idle' | 'running' | 'waiting' | 'error' | 'stopped';
+export type SessionStatus = 'idle' | 'running' | 'waiting' | 'error' | 'stopped' | 'offline' | 'rate-limited' | 'queued';
 
 export interface Message {
   id: string;
@@ -17,6 +17,10 @@ export interface AgentInfo {
   agentType: string; // "main", "task", "explore", etc.
   parentAgentId?: string;
   description?: string;
+  status?: 'active' | 'completed';
+  startedAt?: string;
+  completedAt?: string;
+  toolUseId?: string;
 }
 
 export interface MessageMetadata {
@@ -24,6 +28,7 @@ export interface MessageMetadata {
   toolResult?: ToolResult;
   costInfo?: CostInfo;
   agent?: AgentInfo;
+  queued?: boolean; // Waiting to be sent until the network returns
 }
 
 export interface ToolUse {
@@ -41,9 +46,26 @@ export interface ToolResult {
 export interface CostInfo {
   inputTokens: number;
   outputTokens: number;
+  cacheWriteTokens?: number;
+  cacheReadTokens?: number;
   totalCost: number;
 }
 
+// How full a session's context window is, estimated from the latest API call
+export interface ContextUsage {
+  model: string;
+  contextWindow: number;
+  usedTokens: number;
+  remainingTokens: number;
+  percentUsed: number;
+  nearLimit: boolean;
+  turns: number;
+  totalInputTokens: number;
+  totalOutputTokens: number;
+  totalCacheWriteTokens: number;
+  totalCacheReadTokens: number;
+}
+
 export interface Task {
   id: string;
   subject: string;
@@ -61,8 +83,26 @@ export interface AgentSession {
   tasks: Task[];
   tags?: string[];
   isFavorite?: boolean;
+  // Why a session in the error status failed
+  errorKind?: 'cli_missing' | 'auth' | 'rate_limited' | 'context_too_long' | 'process_killed';
+  // Place in line for a run slot while queued
+  queuePosition?: number;
+  // Snapshot of the project before full-auto runs, restorable to undo them
+  snapshotBeforeRun?: boolean;
+  snapshot?: WorkspaceSnapshot;
   mode?: string;
   modeConfig?: Record<string, any>;
+  pendingActions?: PendingAction[];
+}
+
+// A tool call paused until the user approves or rejects it
+export interface PendingAction {
+  id: string;
+  toolName: string;
+  toolUseId?: string;
+  description: string;
+  filePath?: string;
+  requestedAt: string;
 }
 
 // =============================================================================
@@ -76,6 +116,18 @@ export interface Project {
   description?: string;
   lastOpened: string;
   createdAt: string;
+  displayName?: string; // owner/repo when the project has a git remote
+  git?: ProjectGitMetadata;
+}
+
+export interface ProjectGitMetadata {
+  remoteUrl?: string;
+  host?: string;
+  owner?: string;
+  repo?: string;
+  webUrl?: string;
+  defaultBranch?: string;
+  refreshedAt: string;
 }
 
 export interface WorkspaceInfo {
@@ -97,6 +149,32 @@ export interface GitStatus {
   added: string[];
   deleted: string[];
   untracked: string[];
+  // Commits not yet pushed or pulled, as of the last fetch
+  upstream?: string;
+  ahead: number;
+  behind: number;
+}
+
+export interface WorkspaceSnapshot {
+  head: string;
+  stash?: string;
+  createdAt: string;
+}
+
+export interface PullRequest {
+  number: number;
+  url: string;
+  title: string;
+  head: string;
+  base: string;
+}
+
+export interface GitCommit {
+  hash: string;
+  authorName: string;
+  authorEmail: string;
+  message: string; // Subject line
+  date: string;
 }
 
 // =============================================================================
@@ -110,6 +188,7 @@ export interface DiffLine {
   content: string;
   oldNum?: number;
   newNum?: number;
+  whitespaceOnly?: boolean;
 }
 
 export interface DiffHunk {
@@ -139,6 +218,21 @@ export interface SideBySideLine {
   rightNum?: number;
   rightContent?: string;
   type: 'context' | 'added' | 'deleted' | 'modified';
+  whitespaceOnly?: boolean;
+  leftHighlights?: HighlightRange[];
+  rightHighlights?: HighlightRange[];
+}
+
+// Changed characters within a line, as code point offsets with end exclusive
+export interface HighlightRange {
+  start: number;
+  end: number;
+}
+
+export type WhitespaceMode = '' | 'ignore' | 'mark';
+
+export interface DiffOptions {
+  whitespace?: WhitespaceMode;
 }
 
 // Diff comment types
@@ -188,6 +282,61 @@ export interface MCPServer {
   enabled: boolean;
 }
 
+// Result of calling an MCP tool directly (CallMCPTool)
+export interface MCPToolContent {
+  type: string;
+  text?: string;
+  data?: string;
+  mimeType?: string;
+  resource?: Record<string, unknown>;
+}
+
+export interface MCPToolResult {
+  content: MCPToolContent[];
+  structuredContent?: Record<string, unknown>;
+  isError?: boolean;
+}
+
+// Outcome of ImportMCPFromClaudeDesktop / ExportMCPToClaudeDesktop; servers
+// already in the target are never overwritten
+export interface MCPSyncResult {
+  added: string[];
+  unchanged: string[];
+  conflicts: string[]; // Same name, different definition; left as is
+  duplicates: string[]; // Same command under another name
+  unsupported: string[]; // Entries without a command, e.g. remote servers
+}
+
+// A tool an MCP server exposes (ListMCPServerTools)
+export interface MCPTool {
+  name: string;
+  title?: string;
+  description?: string;
+  inputSchema: Record<string, unknown>;
+  outputSchema?: Record<string, unknown>;
+  annotations?: Record<string, unknown>;
+}
+
+// State of an MCP server started with StartMCPServer, also sent as mcp:status events
+export type MCPServerState = 'stopped' | 'running' | 'restarting' | 'failed';
+
+export interface MCPServerStatus {
+  name: string;
+  state: MCPServerState;
+  pid?: number;
+  startedAt?: string;
+  lastHealthy?: string;
+  restarts: number;
+  lastError?: string;
+}
+
+export interface ModelPrice {
+  input: number;
+  output: number;
+  cacheWrite?: number;
+  cacheRead?: number;
+}
+
 export interface UserPreferences {
   apiKey: string;
   authMethod: AuthMethod;
@@ -197,8 +346,15 @@ export interface UserPreferences {
   defaultModel: string;
   theme: Theme;
   notificationsEnabled: boolean;
+  notifications?: NotificationSettings;
   mcpServers: MCPServer[];
   onboardingCompleted: boolean;
+  claudeCLIPath?: string;
+  // Retries for runs that fail transiently; 0 or unset uses the default, -1 disables
+  runRetries?: number;
+  runRetryDelaySeconds?: number;
+  // Sessions running claude at once; 0 or unset uses the default, -1 removes the limit
+  maxConcurrentRuns?: number;
 
   // Memory management settings
   maxMessagesPerSession?: number;
@@ -209,6 +365,16 @@ export interface UserPreferences {
   maxAgentsPerSession?: number;
   keepCompletedAgents?: boolean;
 
+  // Cost budgets in USD; 0 or unset means no limit
+  maxCostPerSession?: number;
+  maxCostPerDay?: number;
+
+  // Per-model price overrides in USD per million tokens
+  modelPricing?: Record<string, ModelPrice>;
+
+  // Appended to the system prompt of every session
+  systemPromptAppend?: string;
+
   // Firefighter/Observability settings
   datadogAPIKey?: string;
   datadogAppKey?: string;
@@ -222,12 +388,65 @@ export interface UserPreferences {
 
   // Linear settings
   linearAPIKey?: string;
+
+  // API tokens for opening pull requests on GitHub and merge requests on GitLab
+  githubToken?: string;
+  gitlabToken?: string;
+}
+
+export interface NotificationSettings {
+  onWaiting: boolean;
+  onCompleted: boolean;
+  onError: boolean;
+  onAlert: boolean;
+  minTurnSeconds: number;
+  onlyWhenUnfocused: boolean;
+  // Session cost in USD that notifies once reached; 0 is off
+  costThreshold?: number;
+  // Play a sound when a session starts waiting for approval or errors
+  attentionSound?: boolean;
+  // Quiet hours as "HH:MM" local time; may wrap past midnight
+  quietHoursEnabled: boolean;
+  quietHoursStart: string;
+  quietHoursEnd: string;
 }
 
 export interface ProjectPreferences {
   projectPath: string;
   approvalMode?: ApprovalMode;
   model?: string;
+  systemPromptAppend?: string;
+}
+
+// =============================================================================
+// Command Palette Types
+// =============================================================================
+
+export type CommandArgType = 'string' | 'number' | 'boolean' | 'object';
+
+export interface CommandArg {
+  name: string;
+  title: string;
+  type: CommandArgType;
+  required?: boolean;
+  description?: string;
+  enum?: string[];
+  source?: 'activeSession' | 'activeProject';
+}
+
+export interface AppCommand {
+  id: string;
+  title: string;
+  description?: string;
+  category?: string;
+  keywords?: string[];
+  args?: CommandArg[];
+}
+
+export interface CommandMatch {
+  command: AppCommand;
+  score: number;
+  positions?: number[]; // Matched character indexes in the title
 }
 
 // =============================================================================
@@ -6,11 +6,17 @@ import {agent} from '../models';
 import {config} from '../models';
 import {project} from '../models';
 import {diff} from '../models';
+import {logging} from '../models';
+import {diagnostics} from '../models';
 
 export function AddMCPServer(arg1:mcp.Server):Promise<void>;
 
 export function AddSessionTag(arg1:string,arg2:string):Promise<void>;
 
+export function AppendProjectMemory(arg1:string,arg2:string):Promise<void>;
+
+export function ApplyPlan(arg1:string):Promise<void>;
+
 export function ApproveAgentAction(arg1:string,arg2:string):Promise<void>;
 
 export function CheckClaudeCLI():Promise<boolean>;
@@ -25,10 +31,16 @@ export function CreateBoatmanModeSession(arg1:string,arg2:string,arg3:string):Pr
 
 export function CreateFirefighterSession(arg1:string,arg2:string):Promise<main.AgentSessionInfo>;
 
+export function CreateMultiRootSession(arg1:string,arg2:Array<string>):Promise<main.AgentSessionInfo>;
+
 export function DeleteAgentSession(arg1:string):Promise<void>;
 
+export function DeleteSavedSearch(arg1:string):Promise<void>;
+
 export function ExecuteLinearTicketWithBoatmanMode(arg1:string,arg2:string,arg3:string):Promise<void>;
 
+export function ExportDiagnostics(arg1:string,arg2:diagnostics.Options):Promise<string>;
+
 export function FetchLinearTicketsForBoatmanMode(arg1:string,arg2:string):Promise<Array<Record<string, any>>>;
 
 export function GCloudGetAvailableProjects():Promise<Array<string>>;
@@ -43,6 +55,8 @@ export function GCloudSetProject(arg1:string):Promise<void>;
 
 export function GCloudVerifyVertexAIAccess(arg1:string,arg2:string):Promise<void>;
 
+export function GeneratePostmortem(arg1:string):Promise<string>;
+
 export function GetAgentMessages(arg1:string):Promise<Array<agent.Message>>;
 
 export function GetAgentMessagesPaginated(arg1:string,arg2:number,arg3:number):Promise<main.MessagePage>;
@@ -67,6 +81,8 @@ export function GetGitStatus(arg1:string):Promise<main.GitStatus>;
 
 export function GetKeepCompletedAgents():Promise<boolean>;
 
+export function GetLogDirectory():Promise<string>;
+
 export function GetMCPPresets():Promise<Array<mcp.Server>>;
 
 export function GetMCPServers():Promise<Array<mcp.Server>>;
@@ -85,10 +101,40 @@ export function GetPreferences():Promise<config.UserPreferences>;
 
 export function GetProject(arg1:string):Promise<project.Project>;
 
+export function GetProjectClaudeMD(arg1:string):Promise<project.ClaudeMD>;
+
+export function GetProjectDefaults(arg1:string):Promise<agent.ProjectDefaults>;
+
+export function GetProjectMemory(arg1:string):Promise<string>;
+
+export function GetProjectMonitoringRules(arg1:string):Promise<config.MonitoringRules>;
+
+export function GetProjectSystemPrompt(arg1:string):Promise<string>;
+
+export function GetProjectToolPolicy(arg1:string):Promise<agent.ToolPolicy>;
+
+export function GetProposedChanges(arg1:string):Promise<Array<diff.FileDiff>>;
+
+export function GetRecentLogs(arg1:number,arg2:string):Promise<Array<logging.Entry>>;
+
 export function GetRecentProjects(arg1:number):Promise<Array<project.Project>>;
 
+export function GetSavedSearches():Promise<Array<config.SavedSearch>>;
+
+export function GetSessionEncryption():Promise<main.SessionEncryptionStatus>;
+
+export function GetSessionGitStatus(arg1:string):Promise<main.GitStatus>;
+
 export function GetSessionStats():Promise<Record<string, any>>;
 
+export function GetSessionTurns(arg1:string):Promise<Array<agent.Turn>>;
+
+export function GetSessionViews():Promise<Array<config.SavedSearch>>;
+
+export function GetSessionWorkspaceInfo(arg1:string):Promise<Array<project.WorkspaceInfo>>;
+
+export function GetSessionsNeedingAttention():Promise<Array<agent.SessionAttention>>;
+
 export function GetSideBySideDiff(arg1:diff.FileDiff):Promise<Array<diff.SideBySideLine>>;
 
 export function GetWorkspaceInfo(arg1:string):Promise<project.WorkspaceInfo>;
@@ -123,6 +169,10 @@ export function OpenProject(arg1:string):Promise<project.Project>;
 
 export function ParseDiff(arg1:string):Promise<Array<diff.FileDiff>>;
 
+export function ReadWorkspaceFile(arg1:string,arg2:string,arg3:number):Promise<project.FilePreview>;
+
+export function RecordIncidentResolutionStep(arg1:string,arg2:string):Promise<void>;
+
 export function RejectAgentAction(arg1:string,arg2:string):Promise<void>;
 
 export function RemoveMCPServer(arg1:string):Promise<void>;
@@ -131,18 +181,58 @@ export function RemoveProject(arg1:string):Promise<void>;
 
 export function RemoveSessionTag(arg1:string,arg2:string):Promise<void>;
 
+export function ResolveIncident(arg1:string,arg2:string):Promise<void>;
+
+export function RunSavedSearch(arg1:string):Promise<Array<main.SearchSessionsResponse>>;
+
+export function SaveSearch(arg1:config.SavedSearch):Promise<config.SavedSearch>;
+
 export function SearchSessions(arg1:main.SearchSessionsRequest):Promise<Array<main.SearchSessionsResponse>>;
 
 export function SelectFolder():Promise<string>;
 
 export function SendAgentMessage(arg1:string,arg2:string):Promise<void>;
 
+export function SendAgentMessageWithAttachments(arg1:string,arg2:string,arg3:Array<string>,arg4:Array<agent.ImageAttachment>):Promise<void>;
+
 export function SendNotification(arg1:string,arg2:string):Promise<void>;
 
+export function SetLogLevel(arg1:string):Promise<void>;
+
 export function SetPreferences(arg1:config.UserPreferences):Promise<void>;
 
+export function SetProjectClaudeMD(arg1:string,arg2:string):Promise<void>;
+
+export function SetProjectDefaults(arg1:string,arg2:agent.ProjectDefaults):Promise<void>;
+
+export function SetProjectMemory(arg1:string,arg2:string):Promise<void>;
+
+export function SetProjectMonitoringRules(arg1:string,arg2:config.MonitoringRules):Promise<void>;
+
+export function SetProjectSystemPrompt(arg1:string,arg2:string):Promise<void>;
+
+export function SetProjectToolPolicy(arg1:string,arg2:agent.ToolPolicy):Promise<void>;
+
+export function SetSavedSearchPinned(arg1:string,arg2:boolean):Promise<void>;
+
+export function SetSessionAdditionalPaths(arg1:string,arg2:Array<string>):Promise<void>;
+
+export function SetSessionApprovalMode(arg1:string,arg2:string):Promise<void>;
+
+export function SetSessionEncryption(arg1:string,arg2:string):Promise<void>;
+
 export function SetSessionFavorite(arg1:string,arg2:boolean):Promise<void>;
 
+export function SetSessionModel(arg1:string,arg2:string):Promise<void>;
+
+export function SetSessionPlanMode(arg1:string,arg2:boolean):Promise<void>;
+
+export function SetSessionSystemPrompt(arg1:string,arg2:string):Promise<void>;
+
+export function SetSessionToolPolicy(arg1:string,arg2:agent.ToolPolicy):Promise<void>;
+
+export function SetWindowFocused(arg1:boolean):Promise<void>;
+
 export function StartAgentSession(arg1:string):Promise<void>;
 
 export function StartFirefighterMonitoring(arg1:string):Promise<void>;
@@ -153,4 +243,6 @@ export function StopFirefighterMonitoring(arg1:string):Promise<void>;
 
 export function StreamBoatmanModeExecution(arg1:string,arg2:string,arg3:string,arg4:string,arg5:string):Promise<void>;
 
+export function UnlockSessions(arg1:string):Promise<number>;
+
 export function UpdateMCPServer(arg1:mcp.Server):Promise<void>;
@@ -10,6 +10,14 @@ export function AddSessionTag(arg1, arg2) {
   return window['go']['main']['App']['AddSessionTag'](arg1, arg2);
 }
 
+export function AppendProjectMemory(arg1, arg2) {
+  return window['go']['main']['App']['AppendProjectMemory'](arg1, arg2);
+}
+
+export function ApplyPlan(arg1) {
+  return window['go']['main']['App']['ApplyPlan'](arg1);
+}
+
 export function ApproveAgentAction(arg1, arg2) {
   return window['go']['main']['App']['ApproveAgentAction'](arg1, arg2);
 }
@@ -38,14 +46,26 @@ export function CreateFirefighterSession(arg1, arg2) {
   return window['go']['main']['App']['CreateFirefighterSession'](arg1, arg2);
 }
 
+export function CreateMultiRootSession(arg1, arg2) {
+  return window['go']['main']['App']['CreateMultiRootSession'](arg1, arg2);
+}
+
 export function DeleteAgentSession(arg1) {
   return window['go']['main']['App']['DeleteAgentSession'](arg1);
 }
 
+export function DeleteSavedSearch(arg1) {
+  return window['go']['main']['App']['DeleteSavedSearch'](arg1);
+}
+
 export function ExecuteLinearTicketWithBoatmanMode(arg1, arg2, arg3) {
   return window['go']['main']['App']['ExecuteLinearTicketWithBoatmanMode'](arg1, arg2, arg3);
 }
 
+export function ExportDiagnostics(arg1, arg2) {
+  return window['go']['main']['App']['ExportDiagnostics'](arg1, arg2);
+}
+
 export function FetchLinearTicketsForBoatmanMode(arg1, arg2) {
   return window['go']['main']['App']['FetchLinearTicketsForBoatmanMode'](arg1, arg2);
 }
@@ -74,6 +94,10 @@ export function GCloudVerifyVertexAIAccess(arg1, arg2) {
   return window['go']['main']['App']['GCloudVerifyVertexAIAccess'](arg1, arg2);
 }
 
+export function GeneratePostmortem(arg1) {
+  return window['go']['main']['App']['GeneratePostmortem'](arg1);
+}
+
 export function GetAgentMessages(arg1) {
   return window['go']['main']['App']['GetAgentMessages'](arg1);
 }
@@ -122,6 +146,10 @@ export function GetKeepCompletedAgents() {
   return window['go']['main']['App']['GetKeepCompletedAgents']();
 }
 
+export function GetLogDirectory() {
+  return window['go']['main']['App']['GetLogDirectory']();
+}
+
 export function GetMCPPresets() {
   return window['go']['main']['App']['GetMCPPresets']();
 }
@@ -158,14 +186,74 @@ export function GetProject(arg1) {
   return window['go']['main']['App']['GetProject'](arg1);
 }
 
+export function GetProjectClaudeMD(arg1) {
+  return window['go']['main']['App']['GetProjectClaudeMD'](arg1);
+}
+
+export function GetProjectDefaults(arg1) {
+  return window['go']['main']['App']['GetProjectDefaults'](arg1);
+}
+
+export function GetProjectMemory(arg1) {
+  return window['go']['main']['App']['GetProjectMemory'](arg1);
+}
+
+export function GetProjectMonitoringRules(arg1) {
+  return window['go']['main']['App']['GetProjectMonitoringRules'](arg1);
+}
+
+export function GetProjectSystemPrompt(arg1) {
+  return window['go']['main']['App']['GetProjectSystemPrompt'](arg1);
+}
+
+export function GetProjectToolPolicy(arg1) {
+  return window['go']['main']['App']['GetProjectToolPolicy'](arg1);
+}
+
+export function GetProposedChanges(arg1) {
+  return window['go']['main']['App']['GetProposedChanges'](arg1);
+}
+
+export function GetRecentLogs(arg1, arg2) {
+  return window['go']['main']['App']['GetRecentLogs'](arg1, arg2);
+}
+
 export function GetRecentProjects(arg1) {
   return window['go']['main']['App']['GetRecentProjects'](arg1);
 }
 
+export function GetSavedSearches() {
+  return window['go']['main']['App']['GetSavedSearches']();
+}
+
+export function GetSessionEncryption() {
+  return window['go']['main']['App']['GetSessionEncryption']();
+}
+
+export function GetSessionGitStatus(arg1) {
+  return window['go']['main']['App']['GetSessionGitStatus'](arg1);
+}
+
 export function GetSessionStats() {
   return window['go']['main']['App']['GetSessionStats']();
 }
 
+export function GetSessionTurns(arg1) {
+  return window['go']['main']['App']['GetSessionTurns'](arg1);
+}
+
+export function GetSessionViews() {
+  return window['go']['main']['App']['GetSessionViews']();
+}
+
+export function GetSessionWorkspaceInfo(arg1) {
+  return window['go']['main']['App']['GetSessionWorkspaceInfo'](arg1);
+}
+
+export function GetSessionsNeedingAttention() {
+  return window['go']['main']['App']['GetSessionsNeedingAttention']();
+}
+
 export function GetSideBySideDiff(arg1) {
   return window['go']['main']['App']['GetSideBySideDiff'](arg1);
 }
@@ -234,6 +322,14 @@ export function ParseDiff(arg1) {
   return window['go']['main']['App']['ParseDiff'](arg1);
 }
 
+export function ReadWorkspaceFile(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ReadWorkspaceFile'](arg1, arg2, arg3);
+}
+
+export function RecordIncidentResolutionStep(arg1, arg2) {
+  return window['go']['main']['App']['RecordIncidentResolutionStep'](arg1, arg2);
+}
+
 export function RejectAgentAction(arg1, arg2) {
   return window['go']['main']['App']['RejectAgentAction'](arg1, arg2);
 }
@@ -250,6 +346,18 @@ export function RemoveSessionTag(arg1, arg2) {
   return window['go']['main']['App']['RemoveSessionTag'](arg1, arg2);
 }
 
+export function ResolveIncident(arg1, arg2) {
+  return window['go']['main']['App']['ResolveIncident'](arg1, arg2);
+}
+
+export function RunSavedSearch(arg1) {
+  return window['go']['main']['App']['RunSavedSearch'](arg1);
+}
+
+export function SaveSearch(arg1) {
+  return window['go']['main']['App']['SaveSearch'](arg1);
+}
+
 export function SearchSessions(arg1) {
   return window['go']['main']['App']['SearchSessions'](arg1);
 }
@@ -262,18 +370,86 @@ export function SendAgentMessage(arg1, arg2) {
   return window['go']['main']['App']['SendAgentMessage'](arg1, arg2);
 }
 
+export function SendAgentMessageWithAttachments(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['SendAgentMessageWithAttachments'](arg1, arg2, arg3, arg4);
+}
+
 export function SendNotification(arg1, arg2) {
   return window['go']['main']['App']['SendNotification'](arg1, arg2);
 }
 
+export function SetLogLevel(arg1) {
+  return window['go']['main']['App']['SetLogLevel'](arg1);
+}
+
 export function SetPreferences(arg1) {
   return window['go']['main']['App']['SetPreferences'](arg1);
 }
 
+export function SetProjectClaudeMD(arg1, arg2) {
+  return window['go']['main']['App']['SetProjectClaudeMD'](arg1, arg2);
+}
+
+export function SetProjectDefaults(arg1, arg2) {
+  return window['go']['main']['App']['SetProjectDefaults'](arg1, arg2);
+}
+
+export function SetProjectMemory(arg1, arg2) {
+  return window['go']['main']['App']['SetProjectMemory'](arg1, arg2);
+}
+
+export function SetProjectMonitoringRules(arg1, arg2) {
+  return window['go']['main']['App']['SetProjectMonitoringRules'](arg1, arg2);
+}
+
+export function SetProjectSystemPrompt(arg1, arg2) {
+  return window['go']['main']['App']['SetProjectSystemPrompt'](arg1, arg2);
+}
+
+export function SetProjectToolPolicy(arg1, arg2) {
+  return window['go']['main']['App']['SetProjectToolPolicy'](arg1, arg2);
+}
+
+export function SetSavedSearchPinned(arg1, arg2) {
+  return window['go']['main']['App']['SetSavedSearchPinned'](arg1, arg2);
+}
+
+export function SetSessionAdditionalPaths(arg1, arg2) {
+  return window['go']['main']['App']['SetSessionAdditionalPaths'](arg1, arg2);
+}
+
+export function SetSessionApprovalMode(arg1, arg2) {
+  return window['go']['main']['App']['SetSessionApprovalMode'](arg1, arg2);
+}
+
+export function SetSessionEncryption(arg1, arg2) {
+  return window['go']['main']['App']['SetSessionEncryption'](arg1, arg2);
+}
+
 export function SetSessionFavorite(arg1, arg2) {
   return window['go']['main']['App']['SetSessionFavorite'](arg1, arg2);
 }
 
+export function SetSessionModel(arg1, arg2) {
+  return window['go']['main']['App']['SetSessionModel'](arg1, arg2);
+}
+
+export function SetSessionPlanMode(arg1, arg2) {
+  return window['go']['main']['App']['SetSessionPlanMode'](arg1, arg2);
+}
+
+export function SetSessionSystemPrompt(arg1, arg2) {
+  return window['go']['main']['App']['SetSessionSystemPrompt'](arg1, arg2);
+}
+
+export function SetSessionToolPolicy(arg1, arg2) {
+  return window['go']['main']['App']['SetSessionToolPolicy'](arg1, arg2);
+}
+
+export function SetWindowFocused(arg1) {
+  return window['go']['main']['App']['SetWindowFocused'](arg1);
+}
+
 export function StartAgentSession(arg1) {
   return window['go']['main']['App']['StartAgentSession'](arg1);
 }
@@ -294,6 +470,10 @@ export function StreamBoatmanModeExecution(arg1, arg2, arg3, arg4, arg5) {
   return window['go']['main']['App']['StreamBoatmanModeExecution'](arg1, arg2, arg3, arg4, arg5);
 }
 
+export function UnlockSessions(arg1) {
+  return window['go']['main']['App']['UnlockSessions'](arg1);
+}
+
 export function UpdateMCPServer(arg1) {
   return window['go']['main']['App']['UpdateMCPServer'](arg1);
 }
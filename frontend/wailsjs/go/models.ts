@@ -57,6 +57,36 @@ export namespace agent {
 	        this.totalCost = source["totalCost"];
 	    }
 	}
+	export class ImageAttachment {
+	    data?: string;
+	    path?: string;
+	    name?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImageAttachment(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.data = source["data"];
+	        this.path = source["path"];
+	        this.name = source["name"];
+	    }
+	}
+	export class ToolPolicy {
+	    allowed?: string[];
+	    denied?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new ToolPolicy(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.allowed = source["allowed"];
+	        this.denied = source["denied"];
+	    }
+	}
 	export class ToolResult {
 	    toolId: string;
 	    content: string;
@@ -165,6 +195,57 @@ export namespace agent {
 		}
 	}
 	
+	export class ProjectDefaults {
+	    model?: string;
+	    approvalMode?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProjectDefaults(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.model = source["model"];
+	        this.approvalMode = source["approvalMode"];
+	    }
+	}
+	export class SessionAttention {
+	    sessionId: string;
+	    projectPath: string;
+	    status: string;
+	    // Go type: time
+	    since: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new SessionAttention(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.sessionId = source["sessionId"];
+	        this.projectPath = source["projectPath"];
+	        this.status = source["status"];
+	        this.since = this.convertValues(source["since"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class Task {
 	    id: string;
 	    subject: string;
@@ -185,6 +266,52 @@ export namespace agent {
 	        this.metadata = source["metadata"];
 	    }
 	}
+	export class Turn {
+	    id: string;
+	    userMessage?: Message;
+	    messages: Message[];
+	    // Go type: time
+	    startedAt: any;
+	    // Go type: time
+	    completedAt?: any;
+	    durationMs: number;
+	    cost: CostInfo;
+	    running?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Turn(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.userMessage = this.convertValues(source["userMessage"], Message);
+	        this.messages = this.convertValues(source["messages"], Message);
+	        this.startedAt = this.convertValues(source["startedAt"], null);
+	        this.completedAt = this.convertValues(source["completedAt"], null);
+	        this.durationMs = source["durationMs"];
+	        this.cost = this.convertValues(source["cost"], CostInfo);
+	        this.running = source["running"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	
 
 }
@@ -211,6 +338,126 @@ export namespace config {
 	        this.enabled = source["enabled"];
 	    }
 	}
+	export class MonitoringRule {
+	    name: string;
+	    source?: string;
+	    tags?: string[];
+	    minSeverity?: string;
+	    cooldownSeconds?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new MonitoringRule(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.source = source["source"];
+	        this.tags = source["tags"];
+	        this.minSeverity = source["minSeverity"];
+	        this.cooldownSeconds = source["cooldownSeconds"];
+	    }
+	}
+	export class MonitoringRules {
+	    pollIntervalSeconds?: number;
+	    bugsnagProjectIds?: string[];
+	    rules: MonitoringRule[];
+	
+	    static createFrom(source: any = {}) {
+	        return new MonitoringRules(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.pollIntervalSeconds = source["pollIntervalSeconds"];
+	        this.bugsnagProjectIds = source["bugsnagProjectIds"];
+	        this.rules = this.convertValues(source["rules"], MonitoringRule);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SavedSearch {
+	    id: string;
+	    name: string;
+	    filter: SearchFilter;
+	    pinned?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SavedSearch(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.filter = this.convertValues(source["filter"], SearchFilter);
+	        this.pinned = source["pinned"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SearchFilter {
+	    query?: string;
+	    tags?: string[];
+	    projectPath?: string;
+	    isFavorite?: boolean;
+	    fromDate?: string;
+	    toDate?: string;
+	    updatedWithinDays?: number;
+	    content?: string;
+	    roles?: string[];
+	    hasToolError?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SearchFilter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.query = source["query"];
+	        this.tags = source["tags"];
+	        this.projectPath = source["projectPath"];
+	        this.isFavorite = source["isFavorite"];
+	        this.fromDate = source["fromDate"];
+	        this.toDate = source["toDate"];
+	        this.updatedWithinDays = source["updatedWithinDays"];
+	        this.content = source["content"];
+	        this.roles = source["roles"];
+	        this.hasToolError = source["hasToolError"];
+	    }
+	}
 	export class UserPreferences {
 	    apiKey: string;
 	    authMethod: string;
@@ -292,6 +539,31 @@ export namespace config {
 
 }
 
+export namespace diagnostics {
+	
+	export class Options {
+	    logs: boolean;
+	    config: boolean;
+	    cliVersion: boolean;
+	    sessionStats: boolean;
+	    mcpConfig: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Options(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.logs = source["logs"];
+	        this.config = source["config"];
+	        this.cliVersion = source["cliVersion"];
+	        this.sessionStats = source["sessionStats"];
+	        this.mcpConfig = source["mcpConfig"];
+	    }
+	}
+
+}
+
 export namespace diff {
 	
 	export class DiffComment {
@@ -334,6 +606,20 @@ export namespace diff {
 	        this.newNum = source["newNum"];
 	    }
 	}
+	export class HighlightRange {
+	    start: number;
+	    end: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new HighlightRange(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.start = source["start"];
+	        this.end = source["end"];
+	    }
+	}
 	export class Hunk {
 	    id?: string;
 	    oldStart: number;
@@ -428,6 +714,9 @@ export namespace diff {
 	    rightNum?: number;
 	    rightContent?: string;
 	    type: string;
+	    whitespaceOnly?: boolean;
+	    leftHighlights?: HighlightRange[];
+	    rightHighlights?: HighlightRange[];
 	
 	    static createFrom(source: any = {}) {
 	        return new SideBySideLine(source);
@@ -440,7 +729,72 @@ export namespace diff {
 	        this.rightNum = source["rightNum"];
 	        this.rightContent = source["rightContent"];
 	        this.type = source["type"];
+	        this.whitespaceOnly = source["whitespaceOnly"];
+	        this.leftHighlights = this.convertValues(source["leftHighlights"], HighlightRange);
+	        this.rightHighlights = this.convertValues(source["rightHighlights"], HighlightRange);
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace logging {
+	
+	export class Entry {
+	    // Go type: time
+	    time: any;
+	    level: string;
+	    module: string;
+	    message: string;
+	    attrs?: Record<string, string>;
+	
+	    static createFrom(source: any = {}) {
+	        return new Entry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.time = this.convertValues(source["time"], null);
+	        this.level = source["level"];
+	        this.module = source["module"];
+	        this.message = source["message"];
+	        this.attrs = source["attrs"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 
 }
@@ -536,6 +890,10 @@ export namespace main {
 	    isFavorite?: boolean;
 	    fromDate: string;
 	    toDate: string;
+	    content?: string;
+	    roles?: string[];
+	    hasToolError?: boolean;
+	    updatedWithinDays?: number;
 	
 	    static createFrom(source: any = {}) {
 	        return new SearchSessionsRequest(source);
@@ -549,6 +907,10 @@ export namespace main {
 	        this.isFavorite = source["isFavorite"];
 	        this.fromDate = source["fromDate"];
 	        this.toDate = source["toDate"];
+	        this.content = source["content"];
+	        this.roles = source["roles"];
+	        this.hasToolError = source["hasToolError"];
+	        this.updatedWithinDays = source["updatedWithinDays"];
 	    }
 	}
 	export class SearchSessionsResponse {
@@ -581,6 +943,20 @@ export namespace main {
 	        this.status = source["status"];
 	    }
 	}
+	export class SessionEncryptionStatus {
+	    mode: string;
+	    locked: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SessionEncryptionStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mode = source["mode"];
+	        this.locked = source["locked"];
+	    }
+	}
 
 }
 
@@ -613,6 +989,42 @@ export namespace mcp {
 
 export namespace project {
 	
+	export class ClaudeMD {
+	    path: string;
+	    content: string;
+	    exists: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ClaudeMD(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.content = source["content"];
+	        this.exists = source["exists"];
+	    }
+	}
+	export class FilePreview {
+	    path: string;
+	    size: number;
+	    content: string;
+	    binary: boolean;
+	    truncated: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new FilePreview(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.size = source["size"];
+	        this.content = source["content"];
+	        this.binary = source["binary"];
+	        this.truncated = source["truncated"];
+	    }
+	}
 	export class Project {
 	    id: string;
 	    name: string;
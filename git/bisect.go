@@ -0,0 +1,183 @@
+package git
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BisectStatus describes the state of a bisect session
+type BisectStatus struct {
+	Active        bool   `json:"active"`
+	CurrentCommit string `json:"currentCommit,omitempty"` // Commit checked out for testing
+	Remaining     int    `json:"remaining"`               // Revisions left to test
+	Steps         int    `json:"steps"`                   // Roughly how many steps remain
+	Done          bool   `json:"done"`
+	Culprit       string `json:"culprit,omitempty"` // First bad commit once found
+	Output        string `json:"output,omitempty"`  // Raw output of the last bisect command
+}
+
+var (
+	bisectRemainingPattern = regexp.MustCompile(`Bisecting: (\d+) revisions? left to test after this \(roughly (\d+) steps?\)`)
+	bisectCulpritPattern   = regexp.MustCompile(`(?m)^([0-9a-f]{7,40}) is the first bad commit`)
+	bisectLogCulprit       = regexp.MustCompile(`(?m)^# first bad commit: \[([0-9a-f]{7,40})\]`)
+	bisectVarPattern       = regexp.MustCompile(`(?m)^bisect_(nr|steps)=(\d+)$`)
+)
+
+// BisectStart begins a bisect between a known bad and a known good commit.
+// An empty bad defaults to HEAD.
+func (r *Repository) BisectStart(bad, good string) (*BisectStatus, error) {
+	if strings.TrimSpace(good) == "" {
+		return nil, fmt.Errorf("a known good commit is required")
+	}
+	if strings.TrimSpace(bad) == "" {
+		bad = "HEAD"
+	}
+	for _, ref := range []string{bad, good} {
+		if err := checkArg("ref", ref); err != nil {
+			return nil, err
+		}
+	}
+
+	output, err := r.runGit("bisect", "start", bad, good)
+	if err != nil {
+		return nil, err
+	}
+	return r.bisectStatusFromOutput(output)
+}
+
+// BisectMarkGood marks the current commit as good and moves to the next candidate
+func (r *Repository) BisectMarkGood() (*BisectStatus, error) {
+	return r.bisectStep("good")
+}
+
+// BisectMarkBad marks the current commit as bad and moves to the next candidate
+func (r *Repository) BisectMarkBad() (*BisectStatus, error) {
+	return r.bisectStep("bad")
+}
+
+// BisectSkip skips the current commit when it cannot be tested
+func (r *Repository) BisectSkip() (*BisectStatus, error) {
+	return r.bisectStep("skip")
+}
+
+// bisectStep runs a bisect subcommand and parses the resulting state
+func (r *Repository) bisectStep(term string) (*BisectStatus, error) {
+	if !r.IsBisecting() {
+		return nil, fmt.Errorf("no bisect in progress")
+	}
+
+	output, err := r.runGit("bisect", term)
+	if err != nil {
+		return nil, err
+	}
+	return r.bisectStatusFromOutput(output)
+}
+
+// BisectRun automates the bisect using a shell command as the test.
+// The command should exit 0 for good commits, 125 to skip, and any other code for bad.
+func (r *Repository) BisectRun(command string) (*BisectStatus, error) {
+	if strings.TrimSpace(command) == "" {
+		return nil, fmt.Errorf("test command is required")
+	}
+	if !r.IsBisecting() {
+		return nil, fmt.Errorf("no bisect in progress")
+	}
+
+	output, err := r.runGit("bisect", "run", "sh", "-c", command)
+	status, statusErr := r.bisectStatusFromOutput(output)
+	if statusErr != nil {
+		return nil, statusErr
+	}
+	if err != nil && !status.Done {
+		return nil, err
+	}
+	return status, nil
+}
+
+// GetBisectStatus returns the state of the current bisect, if any.
+// It's rebuilt from the bisect log and refs, since the output of the
+// command that last moved the bisect isn't kept.
+func (r *Repository) GetBisectStatus() (*BisectStatus, error) {
+	log, err := r.runGit("bisect", "log")
+	if err != nil {
+		return &BisectStatus{Active: false}, nil
+	}
+
+	status, err := r.bisectStatusFromOutput("")
+	if err != nil {
+		return nil, err
+	}
+
+	if match := bisectLogCulprit.FindStringSubmatch(log); match != nil {
+		status.Done = true
+		status.Culprit = match[1]
+		return status, nil
+	}
+
+	// Ask rev-list for the same counts bisect prints after each step
+	bad, err := r.runGit("rev-parse", "--verify", "--quiet", "refs/bisect/bad")
+	if err != nil {
+		return status, nil
+	}
+	goods, err := r.runGit("for-each-ref", "--format=%(objectname)", "refs/bisect/good-*")
+	if err != nil || goods == "" {
+		return status, nil
+	}
+	args := []string{"rev-list", "--bisect-vars", bad, "--not"}
+	args = append(args, strings.Fields(goods)...)
+	vars, err := r.runGit(args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, match := range bisectVarPattern.FindAllStringSubmatch(vars, -1) {
+		n, _ := strconv.Atoi(match[2])
+		if match[1] == "nr" {
+			status.Remaining = n
+		} else {
+			status.Steps = n
+		}
+	}
+	return status, nil
+}
+
+// BisectReset ends the bisect and returns to the original branch
+func (r *Repository) BisectReset() error {
+	_, err := r.runGit("bisect", "reset")
+	return err
+}
+
+// IsBisecting checks if a bisect is in progress
+func (r *Repository) IsBisecting() bool {
+	_, err := r.runGit("bisect", "log")
+	return err == nil
+}
+
+// bisectStatusFromOutput builds a status from bisect command output and the checked out commit
+func (r *Repository) bisectStatusFromOutput(output string) (*BisectStatus, error) {
+	status := &BisectStatus{
+		Active: true,
+		Output: output,
+	}
+
+	if match := bisectCulpritPattern.FindStringSubmatch(output); match != nil {
+		status.Done = true
+		status.Culprit = match[1]
+	}
+
+	// Only the last progress line reflects the current state
+	if matches := bisectRemainingPattern.FindAllStringSubmatch(output, -1); len(matches) > 0 {
+		last := matches[len(matches)-1]
+		status.Remaining, _ = strconv.Atoi(last[1])
+		status.Steps, _ = strconv.Atoi(last[2])
+	}
+
+	head, err := r.runGit("rev-parse", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	status.CurrentCommit = head
+
+	return status, nil
+}
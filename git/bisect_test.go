@@ -0,0 +1,177 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Helper function to create a linear history where the given commit number introduces a regression
+func createBisectRepo(t *testing.T, commits, badFrom int) (string, []string, func()) {
+	t.Helper()
+
+	tmpDir, cleanup := createTestRepo(t)
+	var hashes []string
+	for i := 1; i <= commits; i++ {
+		state := "good"
+		if i >= badFrom {
+			state = "bad"
+		}
+		createFile(t, tmpDir, "state.txt", state+"\n")
+		createFile(t, tmpDir, "counter.txt", fmt.Sprintf("%d\n", i))
+		commitChanges(t, tmpDir, fmt.Sprintf("commit %d", i))
+		hashes = append(hashes, headHash(t, tmpDir))
+	}
+	return tmpDir, hashes, cleanup
+}
+
+func TestBisect_Manual(t *testing.T) {
+	tmpDir, hashes, cleanup := createBisectRepo(t, 8, 5)
+	defer cleanup()
+
+	repo := NewRepository(tmpDir)
+	status, err := repo.BisectStart("", hashes[0])
+	if err != nil {
+		t.Fatalf("BisectStart() error = %v", err)
+	}
+	if !status.Active || status.CurrentCommit == "" {
+		t.Fatalf("Expected active bisect, got %+v", status)
+	}
+
+	// Walk the bisect by checking state.txt at each step
+	for i := 0; i < 10 && !status.Done; i++ {
+		content, err := repo.runGit("show", "HEAD:state.txt")
+		if err != nil {
+			t.Fatalf("Failed to read state: %v", err)
+		}
+		if content == "good" {
+			status, err = repo.BisectMarkGood()
+		} else {
+			status, err = repo.BisectMarkBad()
+		}
+		if err != nil {
+			t.Fatalf("Bisect step error = %v", err)
+		}
+	}
+
+	if !status.Done {
+		t.Fatal("Expected bisect to finish")
+	}
+	if !strings.HasPrefix(hashes[4], status.Culprit) {
+		t.Errorf("Expected culprit %s, got %s", hashes[4], status.Culprit)
+	}
+
+	if err := repo.BisectReset(); err != nil {
+		t.Fatalf("BisectReset() error = %v", err)
+	}
+	if repo.IsBisecting() {
+		t.Error("Expected bisect to be reset")
+	}
+}
+
+func TestBisect_Run(t *testing.T) {
+	tmpDir, hashes, cleanup := createBisectRepo(t, 6, 3)
+	defer cleanup()
+
+	repo := NewRepository(tmpDir)
+	if _, err := repo.BisectStart("HEAD", hashes[0]); err != nil {
+		t.Fatalf("BisectStart() error = %v", err)
+	}
+	defer repo.BisectReset()
+
+	status, err := repo.BisectRun("grep -q good state.txt")
+	if err != nil {
+		t.Fatalf("BisectRun() error = %v", err)
+	}
+	if !status.Done || !strings.HasPrefix(hashes[2], status.Culprit) {
+		t.Errorf("Expected culprit %s, got %+v", hashes[2], status)
+	}
+}
+
+func TestBisect_Errors(t *testing.T) {
+	tmpDir, _, cleanup := createBisectRepo(t, 2, 2)
+	defer cleanup()
+
+	repo := NewRepository(tmpDir)
+
+	if _, err := repo.BisectStart("HEAD", ""); err == nil {
+		t.Error("Expected error without good commit")
+	}
+	if _, err := repo.BisectStart("--term-bad=broken", "HEAD~1"); err == nil {
+		t.Error("Expected error for an option as a ref")
+	}
+	if _, err := repo.BisectMarkGood(); err == nil {
+		t.Error("Expected error when no bisect is in progress")
+	}
+	if _, err := repo.BisectRun("true"); err == nil {
+		t.Error("Expected error when no bisect is in progress")
+	}
+
+	status, err := repo.GetBisectStatus()
+	if err != nil {
+		t.Fatalf("GetBisectStatus() error = %v", err)
+	}
+	if status.Active {
+		t.Error("Expected inactive bisect status")
+	}
+}
+
+func TestBisectRemainingParsing(t *testing.T) {
+	tmpDir, hashes, cleanup := createBisectRepo(t, 10, 7)
+	defer cleanup()
+
+	repo := NewRepository(tmpDir)
+	status, err := repo.BisectStart("HEAD", hashes[0])
+	if err != nil {
+		t.Fatalf("BisectStart() error = %v", err)
+	}
+	defer repo.BisectReset()
+
+	if status.Remaining == 0 || status.Steps == 0 {
+		t.Errorf("Expected remaining revisions and steps to be parsed, got %+v", status)
+	}
+}
+
+func TestGetBisectStatus(t *testing.T) {
+	tmpDir, hashes, cleanup := createBisectRepo(t, 10, 7)
+	defer cleanup()
+
+	repo := NewRepository(tmpDir)
+	started, err := repo.BisectStart("HEAD", hashes[0])
+	if err != nil {
+		t.Fatalf("BisectStart() error = %v", err)
+	}
+	defer repo.BisectReset()
+
+	status, err := repo.GetBisectStatus()
+	if err != nil {
+		t.Fatalf("GetBisectStatus() error = %v", err)
+	}
+	if !status.Active || status.Done {
+		t.Fatalf("Expected an active, unfinished bisect, got %+v", status)
+	}
+	if status.Remaining != started.Remaining || status.Steps != started.Steps {
+		t.Errorf("Expected %d remaining in %d steps, got %+v", started.Remaining, started.Steps, status)
+	}
+	if status.CurrentCommit != started.CurrentCommit {
+		t.Errorf("Expected current commit %s, got %s", started.CurrentCommit, status.CurrentCommit)
+	}
+
+	if _, err := repo.BisectRun("grep -q good state.txt"); err != nil {
+		t.Fatalf("BisectRun() error = %v", err)
+	}
+
+	status, err = repo.GetBisectStatus()
+	if err != nil {
+		t.Fatalf("GetBisectStatus() error = %v", err)
+	}
+	if !status.Active || !status.Done {
+		t.Fatalf("Expected a finished bisect, got %+v", status)
+	}
+	if !strings.HasPrefix(hashes[6], status.Culprit) {
+		t.Errorf("Expected culprit %s, got %s", hashes[6], status.Culprit)
+	}
+	if status.Remaining != 0 {
+		t.Errorf("Expected nothing left to test, got %d", status.Remaining)
+	}
+}
@@ -0,0 +1,47 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"boatman/diff"
+)
+
+// DiffRefs returns the changes on head since it branched from base, parsed for
+// the diff viewer. An empty head compares against the working tree instead.
+// Paths, when given, limit the diff to those files or directories.
+func (r *Repository) DiffRefs(base, head string, paths ...string) ([]diff.FileDiff, error) {
+	if base == "" {
+		return nil, fmt.Errorf("base ref is required")
+	}
+	for _, ref := range []string{base, head} {
+		if err := checkArg("ref", ref); err != nil {
+			return nil, err
+		}
+	}
+
+	args := []string{"diff", "--no-color", "--no-ext-diff"}
+	if head == "" {
+		args = append(args, base)
+	} else {
+		args = append(args, base+"..."+head)
+	}
+	args = append(args, "--")
+	args = append(args, paths...)
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.path
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git diff: %w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("git diff: %w", err)
+	}
+
+	return diff.ParseUnifiedDiff(string(output))
+}
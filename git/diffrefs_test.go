@@ -0,0 +1,77 @@
+package git
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestDiffRefs(t *testing.T) {
+	repoDir, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	run := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repoDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+
+	createFile(t, repoDir, "a.txt", "one\ntwo\n")
+	createFile(t, repoDir, "b.txt", "bee\n")
+	commitChanges(t, repoDir, "initial")
+	run("branch", "-M", "main")
+
+	run("checkout", "-q", "-b", "feature")
+	createFile(t, repoDir, "a.txt", "one\nTWO\n")
+	createFile(t, repoDir, "c.txt", "new\n")
+	commitChanges(t, repoDir, "feature work")
+
+	// Changes on main after the branch point are not part of the feature diff
+	run("checkout", "-q", "main")
+	createFile(t, repoDir, "b.txt", "changed on main\n")
+	commitChanges(t, repoDir, "main work")
+
+	repo := NewRepository(repoDir)
+
+	diffs, err := repo.DiffRefs("main", "feature")
+	if err != nil {
+		t.Fatalf("DiffRefs() error = %v", err)
+	}
+	if len(diffs) != 2 {
+		t.Fatalf("Expected 2 changed files, got %d: %+v", len(diffs), diffs)
+	}
+	if diffs[0].NewPath != "a.txt" || diffs[1].NewPath != "c.txt" || !diffs[1].IsNew {
+		t.Errorf("Unexpected files: %s, %s (new=%v)", diffs[0].NewPath, diffs[1].NewPath, diffs[1].IsNew)
+	}
+
+	// Paths limit the diff
+	diffs, err = repo.DiffRefs("main", "feature", "c.txt")
+	if err != nil {
+		t.Fatalf("DiffRefs() error = %v", err)
+	}
+	if len(diffs) != 1 || diffs[0].NewPath != "c.txt" {
+		t.Errorf("Expected only c.txt, got %+v", diffs)
+	}
+
+	// An empty head compares against the working tree
+	createFile(t, repoDir, "b.txt", "edited\n")
+	diffs, err = repo.DiffRefs("HEAD", "")
+	if err != nil {
+		t.Fatalf("DiffRefs() error = %v", err)
+	}
+	if len(diffs) != 1 || diffs[0].NewPath != "b.txt" {
+		t.Errorf("Expected working tree change to b.txt, got %+v", diffs)
+	}
+
+	if _, err := repo.DiffRefs("main", "no-such-branch"); err == nil {
+		t.Error("Expected error for unknown ref")
+	}
+	if _, err := repo.DiffRefs("--output=/tmp/x", "main"); err == nil {
+		t.Error("Expected error for option-like ref")
+	}
+	if _, err := repo.DiffRefs("", "main"); err == nil {
+		t.Error("Expected error for missing base")
+	}
+}
@@ -1,9 +1,13 @@
 package git
 
 import (
+	"errors"
+	"fmt"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // Repository provides git operations for a repository
@@ -113,62 +117,214 @@ func (r *Repository) GetStagedDiff() (string, error) {
 
 // StageFile stages a file
 func (r *Repository) StageFile(filePath string) error {
-	cmd := exec.Command("git", "add", filePath)
-	cmd.Dir = r.path
-	return cmd.Run()
+	return r.Stage(filePath)
 }
 
 // UnstageFile unstages a file
 func (r *Repository) UnstageFile(filePath string) error {
-	cmd := exec.Command("git", "reset", "HEAD", filePath)
-	cmd.Dir = r.path
-	return cmd.Run()
+	return r.Unstage(filePath)
+}
+
+// Stage adds files to the index. With no paths it stages every change,
+// including new and deleted files.
+func (r *Repository) Stage(paths ...string) error {
+	if len(paths) == 0 {
+		_, err := r.runGit("add", "--all")
+		return err
+	}
+	_, err := r.runGit(append([]string{"add", "--"}, paths...)...)
+	return err
+}
+
+// Unstage removes files from the index, keeping their changes in the working
+// tree. With no paths it unstages everything.
+func (r *Repository) Unstage(paths ...string) error {
+	if !r.hasCommits() {
+		// Nothing to reset to yet, so drop the files from the index instead
+		args := []string{"rm", "--cached", "-r", "-q", "--"}
+		if len(paths) == 0 {
+			paths = []string{"."}
+		}
+		_, err := r.runGit(append(args, paths...)...)
+		return err
+	}
+	_, err := r.runGit(append([]string{"reset", "-q", "HEAD", "--"}, paths...)...)
+	return err
 }
 
-// Commit creates a commit with the given message
+// Commit creates a commit of the staged changes with the given message
 func (r *Repository) Commit(message string) error {
-	cmd := exec.Command("git", "commit", "-m", message)
-	cmd.Dir = r.path
-	return cmd.Run()
+	if strings.TrimSpace(message) == "" {
+		return fmt.Errorf("commit message is required")
+	}
+	_, err := r.runGit("commit", "-m", message)
+	return err
+}
+
+// CreateBranch creates a branch at startPoint without switching to it. An
+// empty startPoint branches from HEAD.
+func (r *Repository) CreateBranch(name, startPoint string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("branch name is required")
+	}
+	if err := r.checkBranchName(name); err != nil {
+		return err
+	}
+	if err := checkArg("start point", startPoint); err != nil {
+		return err
+	}
+
+	args := []string{"branch", name}
+	if startPoint != "" {
+		args = append(args, startPoint)
+	}
+	_, err := r.runGit(args...)
+	return err
+}
+
+// Checkout switches the working tree to a branch or commit. Local changes
+// that would be overwritten make it fail rather than being lost.
+func (r *Repository) Checkout(ref string) error {
+	if strings.TrimSpace(ref) == "" {
+		return fmt.Errorf("branch or commit is required")
+	}
+	if err := checkArg("ref", ref); err != nil {
+		return err
+	}
+	_, err := r.runGit("checkout", ref, "--")
+	return err
 }
 
 // GetCommitHistory returns recent commits
 func (r *Repository) GetCommitHistory(limit int) ([]Commit, error) {
-	format := "%H|%an|%ae|%at|%s"
-	cmd := exec.Command("git", "log", "-n", string(rune(limit)), "--format="+format)
-	cmd.Dir = r.path
-	output, err := cmd.Output()
+	return r.Log(limit)
+}
+
+// logFieldSeparator splits the fields of a log line; subjects can't contain it
+const logFieldSeparator = "\x1f"
+
+// Log returns the latest n commits on the current branch, newest first.
+// Zero or less returns the whole history.
+func (r *Repository) Log(n int) ([]Commit, error) {
+	commits := []Commit{}
+	if !r.hasCommits() {
+		return commits, nil
+	}
+
+	format := strings.Join([]string{"%H", "%an", "%ae", "%at", "%s"}, logFieldSeparator)
+	args := []string{"log", "--format=" + format}
+	if n > 0 {
+		args = append(args, "-n", strconv.Itoa(n))
+	}
+	output, err := r.runGit(args...)
 	if err != nil {
 		return nil, err
 	}
 
-	commits := []Commit{}
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		if line == "" {
-			continue
-		}
-		parts := strings.SplitN(line, "|", 5)
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.SplitN(line, logFieldSeparator, 5)
 		if len(parts) < 5 {
 			continue
 		}
-		commits = append(commits, Commit{
+		commit := Commit{
 			Hash:        parts[0],
 			AuthorName:  parts[1],
 			AuthorEmail: parts[2],
 			Message:     parts[4],
-		})
+		}
+		if seconds, err := strconv.ParseInt(parts[3], 10, 64); err == nil {
+			commit.Date = time.Unix(seconds, 0)
+		}
+		commits = append(commits, commit)
 	}
 
 	return commits, nil
 }
 
+// TopLevel returns the top directory of the work tree the repository's directory is in
+func (r *Repository) TopLevel() (string, error) {
+	top, err := r.runGit("rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return filepath.FromSlash(top), nil
+}
+
+// Prefix returns the repository's directory relative to the top of its work
+// tree in slash form with a trailing slash, or "" at the top. Paths from
+// GetStatus are relative to the top, so they start with the prefix when
+// they're inside the directory.
+func (r *Repository) Prefix() (string, error) {
+	return r.runGit("rev-parse", "--show-prefix")
+}
+
+// ShowFile returns a file's content at ref, with the path relative to the
+// repository's directory. It fails if the file doesn't exist at ref.
+func (r *Repository) ShowFile(ref, path string) (string, error) {
+	cmd := exec.Command("git", "show", ref+":./"+filepath.ToSlash(path))
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git show: %w", err)
+	}
+	return string(output), nil
+}
+
+// BlobID returns the id of a file's blob at ref, with the path relative to
+// the repository's directory. The blob can be read back with ReadBlob even
+// after ref moves on.
+func (r *Repository) BlobID(ref, path string) (string, error) {
+	return r.runGit("rev-parse", "--verify", "--quiet", ref+":./"+filepath.ToSlash(path))
+}
+
+// HashObject stores a file's current content in the object database and
+// returns its blob id, for reading back with ReadBlob. Blobs nothing refers
+// to are pruned by git gc once they expire.
+func (r *Repository) HashObject(path string) (string, error) {
+	return r.runGit("hash-object", "-w", "--", path)
+}
+
+// IsIgnored reports whether git ignores a path, relative to the repository's
+// directory. Paths outside the work tree are an error.
+func (r *Repository) IsIgnored(path string) (bool, error) {
+	cmd := exec.Command("git", "check-ignore", "--quiet", "--", path)
+	cmd.Dir = r.path
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		return false, nil
+	default:
+		return false, fmt.Errorf("git check-ignore: %w", err)
+	}
+}
+
+// ReadBlob returns the content of a blob
+func (r *Repository) ReadBlob(id string) ([]byte, error) {
+	cmd := exec.Command("git", "cat-file", "blob", id)
+	cmd.Dir = r.path
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git cat-file: %w", err)
+	}
+	return output, nil
+}
+
+// hasCommits reports whether HEAD points at a commit, which a new repository lacks
+func (r *Repository) hasCommits() bool {
+	_, err := r.runGit("rev-parse", "--verify", "--quiet", "HEAD")
+	return err == nil
+}
+
 // Commit represents a git commit
 type Commit struct {
-	Hash        string `json:"hash"`
-	AuthorName  string `json:"authorName"`
-	AuthorEmail string `json:"authorEmail"`
-	Message     string `json:"message"`
+	Hash        string    `json:"hash"`
+	AuthorName  string    `json:"authorName"`
+	AuthorEmail string    `json:"authorEmail"`
+	Message     string    `json:"message"` // Subject line
+	Date        time.Time `json:"date"`
 }
 
 // DiscardChanges discards changes to a file
@@ -182,3 +338,39 @@ func (r *Repository) DiscardChanges(filePath string) error {
 func (r *Repository) GetFilePath(relativePath string) string {
 	return filepath.Join(r.path, relativePath)
 }
+
+// checkArg rejects a ref, remote, or commit that git would read as an
+// option, e.g. "--upload-pack=<command>"
+func checkArg(kind, value string) error {
+	if strings.HasPrefix(value, "-") {
+		return fmt.Errorf("invalid %s %q", kind, value)
+	}
+	return nil
+}
+
+// checkBranchName rejects names git doesn't allow for a branch
+func (r *Repository) checkBranchName(name string) error {
+	if err := checkArg("branch name", name); err != nil {
+		return err
+	}
+	if _, err := r.runGit("check-ref-format", "--branch", name); err != nil {
+		return fmt.Errorf("invalid branch name: %s", name)
+	}
+	return nil
+}
+
+// runGit runs a git command in the repository and returns its trimmed combined output.
+// On failure the output is included in the returned error.
+func (r *Repository) runGit(args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.path
+	output, err := cmd.CombinedOutput()
+	out := strings.TrimSpace(string(output))
+	if err != nil {
+		if out != "" {
+			return out, fmt.Errorf("git %s: %w: %s", args[0], err, out)
+		}
+		return out, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
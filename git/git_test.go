@@ -769,3 +769,261 @@ func TestGetDiff_ErrorHandling(t *testing.T) {
 		}
 	})
 }
+
+func TestStageAndUnstage(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	createFile(t, repoPath, "a.txt", "a")
+	createFile(t, repoPath, "b.txt", "b")
+	repo := NewRepository(repoPath)
+
+	// Unstaging works before the first commit
+	if err := repo.Stage("a.txt", "b.txt"); err != nil {
+		t.Fatalf("Stage() error = %v", err)
+	}
+	if err := repo.Unstage("b.txt"); err != nil {
+		t.Fatalf("Unstage() error = %v", err)
+	}
+	status, _ := repo.GetStatus()
+	if len(status.Added) != 1 || len(status.Untracked) != 1 {
+		t.Fatalf("Expected one staged and one untracked file, got %+v", status)
+	}
+
+	commitChanges(t, repoPath, "Initial commit")
+	createFile(t, repoPath, "a.txt", "changed")
+	createFile(t, repoPath, "c.txt", "c")
+
+	if err := repo.Stage(); err != nil {
+		t.Fatalf("Stage() error = %v", err)
+	}
+	staged, _ := repo.GetStagedDiff()
+	if !strings.Contains(staged, "a.txt") || !strings.Contains(staged, "c.txt") {
+		t.Errorf("Expected every change staged, got %s", staged)
+	}
+
+	if err := repo.Unstage(); err != nil {
+		t.Fatalf("Unstage() error = %v", err)
+	}
+	if staged, _ := repo.GetStagedDiff(); staged != "" {
+		t.Errorf("Expected nothing staged, got %s", staged)
+	}
+
+	if err := repo.Stage("missing.txt"); err == nil {
+		t.Error("Expected error staging a missing file")
+	}
+}
+
+func TestCommit_Errors(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	createFile(t, repoPath, "README.md", "# Test")
+	commitChanges(t, repoPath, "Initial commit")
+	repo := NewRepository(repoPath)
+
+	if err := repo.Commit("  "); err == nil {
+		t.Error("Expected error for an empty message")
+	}
+
+	err := repo.Commit("Nothing staged")
+	if err == nil {
+		t.Fatal("Expected error with nothing staged")
+	}
+	if !strings.Contains(err.Error(), "nothing") {
+		t.Errorf("Expected git's explanation in the error, got %v", err)
+	}
+}
+
+func TestCreateBranchAndCheckout(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	createFile(t, repoPath, "file.txt", "one")
+	commitChanges(t, repoPath, "First commit")
+	first := headHash(t, repoPath)
+	createFile(t, repoPath, "file.txt", "two")
+	commitChanges(t, repoPath, "Second commit")
+
+	repo := NewRepository(repoPath)
+	original, _ := repo.GetCurrentBranch()
+
+	if err := repo.CreateBranch("feature", ""); err != nil {
+		t.Fatalf("CreateBranch() error = %v", err)
+	}
+	if branch, _ := repo.GetCurrentBranch(); branch != original {
+		t.Errorf("Expected to stay on %s, got %s", original, branch)
+	}
+
+	if err := repo.CreateBranch("old", first); err != nil {
+		t.Fatalf("CreateBranch() error = %v", err)
+	}
+	if err := repo.Checkout("old"); err != nil {
+		t.Fatalf("Checkout() error = %v", err)
+	}
+	if branch, _ := repo.GetCurrentBranch(); branch != "old" {
+		t.Errorf("Expected branch old, got %s", branch)
+	}
+	content, _ := os.ReadFile(filepath.Join(repoPath, "file.txt"))
+	if string(content) != "one" {
+		t.Errorf("Expected the first commit's file, got %q", content)
+	}
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"empty name", repo.CreateBranch("", "")},
+		{"invalid name", repo.CreateBranch("bad..name", "")},
+		{"existing branch", repo.CreateBranch("feature", "")},
+		{"option as start point", repo.CreateBranch("other", "--orphan=x")},
+		{"empty ref", repo.Checkout("")},
+		{"option as ref", repo.Checkout("--orphan=x")},
+		{"unknown ref", repo.Checkout("no-such-branch")},
+	}
+	for _, tt := range tests {
+		if tt.err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
+
+func TestLog(t *testing.T) {
+	repoPath, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	repo := NewRepository(repoPath)
+	commits, err := repo.Log(5)
+	if err != nil {
+		t.Fatalf("Log() error = %v", err)
+	}
+	if len(commits) != 0 {
+		t.Errorf("Expected no commits in a new repository, got %d", len(commits))
+	}
+
+	for i := 1; i <= 3; i++ {
+		createFile(t, repoPath, "file.txt", strings.Repeat("x", i))
+		commitChanges(t, repoPath, "Commit | with separator")
+	}
+
+	commits, err = repo.Log(2)
+	if err != nil {
+		t.Fatalf("Log() error = %v", err)
+	}
+	if len(commits) != 2 {
+		t.Fatalf("Expected 2 commits, got %d", len(commits))
+	}
+	if commits[0].Message != "Commit | with separator" {
+		t.Errorf("Expected the full subject, got %q", commits[0].Message)
+	}
+	if commits[0].Date.IsZero() {
+		t.Error("Expected the commit date")
+	}
+
+	if all, _ := repo.Log(0); len(all) != 3 {
+		t.Errorf("Expected the whole history, got %d commits", len(all))
+	}
+}
+
+func TestShowFile(t *testing.T) {
+	dir, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	createFile(t, dir, "sub/file.txt", "committed\n\n")
+	commitChanges(t, dir, "Add file")
+	createFile(t, dir, "sub/file.txt", "changed\n")
+
+	repo := NewRepository(dir)
+	content, err := repo.ShowFile("HEAD", filepath.Join("sub", "file.txt"))
+	if err != nil {
+		t.Fatalf("ShowFile failed: %v", err)
+	}
+	if content != "committed\n\n" {
+		t.Errorf("Expected the committed content untrimmed, got %q", content)
+	}
+
+	if _, err := repo.ShowFile("HEAD", "missing.txt"); err == nil {
+		t.Error("Expected an error for a file missing at the ref")
+	}
+}
+
+func TestTopLevelAndPrefix(t *testing.T) {
+	dir, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	sub := filepath.Join(dir, "a", "b")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	want, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatalf("Failed to resolve %s: %v", dir, err)
+	}
+
+	for _, tt := range []struct {
+		path   string
+		prefix string
+	}{
+		{dir, ""},
+		{sub, "a/b/"},
+	} {
+		repo := NewRepository(tt.path)
+		top, err := repo.TopLevel()
+		if err != nil {
+			t.Fatalf("TopLevel failed: %v", err)
+		}
+		if top != want {
+			t.Errorf("Expected top level %s from %s, got %s", want, tt.path, top)
+		}
+		prefix, err := repo.Prefix()
+		if err != nil {
+			t.Fatalf("Prefix failed: %v", err)
+		}
+		if prefix != tt.prefix {
+			t.Errorf("Expected prefix %q from %s, got %q", tt.prefix, tt.path, prefix)
+		}
+	}
+}
+
+func TestBlobs(t *testing.T) {
+	dir, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	createFile(t, dir, "file.txt", "committed\n")
+	commitChanges(t, dir, "Add file")
+
+	repo := NewRepository(dir)
+	id, err := repo.BlobID("HEAD", "file.txt")
+	if err != nil {
+		t.Fatalf("BlobID failed: %v", err)
+	}
+
+	// The blob outlives later commits
+	createFile(t, dir, "file.txt", "changed\n")
+	commitChanges(t, dir, "Change file")
+	content, err := repo.ReadBlob(id)
+	if err != nil {
+		t.Fatalf("ReadBlob failed: %v", err)
+	}
+	if string(content) != "committed\n" {
+		t.Errorf("Expected the committed content, got %q", content)
+	}
+
+	if _, err := repo.BlobID("HEAD", "missing.txt"); err == nil {
+		t.Error("Expected an error for a file missing at the ref")
+	}
+
+	// Uncommitted content can be stored and read back too
+	createFile(t, dir, "file.txt", "uncommitted\n")
+	id, err = repo.HashObject("file.txt")
+	if err != nil {
+		t.Fatalf("HashObject failed: %v", err)
+	}
+	createFile(t, dir, "file.txt", "overwritten\n")
+	if content, err := repo.ReadBlob(id); err != nil || string(content) != "uncommitted\n" {
+		t.Errorf("Expected the stored content, got %q (%v)", content, err)
+	}
+}
@@ -0,0 +1,134 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ApplyResult describes the outcome of a cherry-pick or revert
+type ApplyResult struct {
+	Success   bool     `json:"success"`
+	Operation string   `json:"operation"` // "cherry-pick" or "revert"
+	Commit    string   `json:"commit"`
+	Conflicts []string `json:"conflicts,omitempty"` // Files with unresolved conflicts
+}
+
+// ConflictVersions holds the three sides of a conflicted file for a three-way view
+type ConflictVersions struct {
+	Path   string `json:"path"`
+	Base   string `json:"base"`   // Common ancestor (stage 1)
+	Ours   string `json:"ours"`   // Current branch (stage 2)
+	Theirs string `json:"theirs"` // Commit being applied (stage 3)
+}
+
+// CherryPick applies the changes introduced by a commit onto the current branch.
+// If the commit does not apply cleanly, the repository is left mid cherry-pick and
+// the conflicted files are returned; call AbortCherryPick or resolve and ContinueCherryPick.
+func (r *Repository) CherryPick(hash string) (*ApplyResult, error) {
+	return r.applyCommit("cherry-pick", hash)
+}
+
+// RevertCommit creates a new commit that undoes the changes of the given commit.
+// Conflicts are reported the same way as CherryPick.
+func (r *Repository) RevertCommit(hash string) (*ApplyResult, error) {
+	return r.applyCommit("revert", hash)
+}
+
+// applyCommit runs cherry-pick or revert and detects conflicts
+func (r *Repository) applyCommit(operation, hash string) (*ApplyResult, error) {
+	if strings.TrimSpace(hash) == "" {
+		return nil, fmt.Errorf("commit hash is required")
+	}
+	if err := checkArg("commit", hash); err != nil {
+		return nil, err
+	}
+	// Resolve it first, so only a commit's full hash reaches cherry-pick or revert
+	commit, err := r.runGit("rev-parse", "--verify", "--quiet", "--end-of-options", hash+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("unknown commit %q", hash)
+	}
+
+	result := &ApplyResult{
+		Operation: operation,
+		Commit:    hash,
+	}
+
+	_, err = r.runGit(operation, "--no-edit", commit)
+	if err == nil {
+		result.Success = true
+		return result, nil
+	}
+
+	conflicts, conflictErr := r.GetConflictedFiles()
+	if conflictErr != nil || len(conflicts) == 0 {
+		return nil, err
+	}
+
+	result.Conflicts = conflicts
+	return result, nil
+}
+
+// GetConflictedFiles returns files with unresolved merge conflicts
+func (r *Repository) GetConflictedFiles() ([]string, error) {
+	output, err := r.runGit("diff", "--name-only", "--diff-filter=U")
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// GetConflictVersions returns the base, ours, and theirs contents of a conflicted file.
+// Sides that do not exist (e.g. a file added on only one side) are returned empty.
+func (r *Repository) GetConflictVersions(filePath string) (*ConflictVersions, error) {
+	versions := &ConflictVersions{Path: filePath}
+
+	stages := []*string{&versions.Base, &versions.Ours, &versions.Theirs}
+	found := false
+	for i, dest := range stages {
+		cmd := exec.Command("git", "show", fmt.Sprintf(":%d:%s", i+1, filePath))
+		cmd.Dir = r.path
+		output, err := cmd.Output()
+		if err != nil {
+			continue
+		}
+		*dest = string(output)
+		found = true
+	}
+
+	if !found {
+		return nil, fmt.Errorf("file is not conflicted: %s", filePath)
+	}
+	return versions, nil
+}
+
+// AbortCherryPick cancels an in-progress cherry-pick
+func (r *Repository) AbortCherryPick() error {
+	_, err := r.runGit("cherry-pick", "--abort")
+	return err
+}
+
+// ContinueCherryPick commits a cherry-pick after conflicts have been resolved and staged
+func (r *Repository) ContinueCherryPick() error {
+	_, err := r.runGit("-c", "core.editor=true", "cherry-pick", "--continue")
+	return err
+}
+
+// AbortRevert cancels an in-progress revert
+func (r *Repository) AbortRevert() error {
+	_, err := r.runGit("revert", "--abort")
+	return err
+}
+
+// ContinueRevert commits a revert after conflicts have been resolved and staged
+func (r *Repository) ContinueRevert() error {
+	_, err := r.runGit("-c", "core.editor=true", "revert", "--continue")
+	return err
+}
@@ -0,0 +1,154 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Helper function to get the HEAD commit hash
+func headHash(t *testing.T, dir string) string {
+	t.Helper()
+
+	cmd := exec.Command("git", "rev-parse", "HEAD")
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("Failed to get HEAD: %v", err)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// Helper function to run a git command in the test repo
+func gitRun(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, output)
+	}
+}
+
+func TestCherryPick_Clean(t *testing.T) {
+	tmpDir, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	createFile(t, tmpDir, "base.txt", "base\n")
+	commitChanges(t, tmpDir, "base")
+	gitRun(t, tmpDir, "branch", "-M", "main")
+
+	gitRun(t, tmpDir, "checkout", "-b", "fix")
+	createFile(t, tmpDir, "fix.txt", "fix\n")
+	commitChanges(t, tmpDir, "fix")
+	fixHash := headHash(t, tmpDir)
+
+	gitRun(t, tmpDir, "checkout", "main")
+
+	repo := NewRepository(tmpDir)
+	result, err := repo.CherryPick(fixHash)
+	if err != nil {
+		t.Fatalf("CherryPick() error = %v", err)
+	}
+	if !result.Success || len(result.Conflicts) != 0 {
+		t.Errorf("Expected clean cherry-pick, got %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "fix.txt")); err != nil {
+		t.Error("Expected cherry-picked file to exist")
+	}
+}
+
+func TestCherryPick_Conflict(t *testing.T) {
+	tmpDir, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	createFile(t, tmpDir, "file.txt", "original\n")
+	commitChanges(t, tmpDir, "base")
+	gitRun(t, tmpDir, "branch", "-M", "main")
+
+	gitRun(t, tmpDir, "checkout", "-b", "fix")
+	createFile(t, tmpDir, "file.txt", "theirs\n")
+	commitChanges(t, tmpDir, "fix")
+	fixHash := headHash(t, tmpDir)
+
+	gitRun(t, tmpDir, "checkout", "main")
+	createFile(t, tmpDir, "file.txt", "ours\n")
+	commitChanges(t, tmpDir, "main change")
+
+	repo := NewRepository(tmpDir)
+	result, err := repo.CherryPick(fixHash)
+	if err != nil {
+		t.Fatalf("CherryPick() error = %v", err)
+	}
+	if result.Success {
+		t.Error("Expected conflicting cherry-pick to not succeed")
+	}
+	if len(result.Conflicts) != 1 || result.Conflicts[0] != "file.txt" {
+		t.Fatalf("Expected conflict on file.txt, got %v", result.Conflicts)
+	}
+
+	versions, err := repo.GetConflictVersions("file.txt")
+	if err != nil {
+		t.Fatalf("GetConflictVersions() error = %v", err)
+	}
+	if versions.Base != "original\n" || versions.Ours != "ours\n" || versions.Theirs != "theirs\n" {
+		t.Errorf("Unexpected conflict versions: %+v", versions)
+	}
+
+	if err := repo.AbortCherryPick(); err != nil {
+		t.Fatalf("AbortCherryPick() error = %v", err)
+	}
+	conflicts, err := repo.GetConflictedFiles()
+	if err != nil {
+		t.Fatalf("GetConflictedFiles() error = %v", err)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("Expected no conflicts after abort, got %v", conflicts)
+	}
+}
+
+func TestRevertCommit(t *testing.T) {
+	tmpDir, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	createFile(t, tmpDir, "file.txt", "v1\n")
+	commitChanges(t, tmpDir, "v1")
+	createFile(t, tmpDir, "file.txt", "v2\n")
+	commitChanges(t, tmpDir, "v2")
+	badHash := headHash(t, tmpDir)
+
+	repo := NewRepository(tmpDir)
+	result, err := repo.RevertCommit(badHash)
+	if err != nil {
+		t.Fatalf("RevertCommit() error = %v", err)
+	}
+	if !result.Success || result.Operation != "revert" {
+		t.Errorf("Expected successful revert, got %+v", result)
+	}
+
+	content, _ := os.ReadFile(filepath.Join(tmpDir, "file.txt"))
+	if string(content) != "v1\n" {
+		t.Errorf("Expected file to be reverted to v1, got %q", content)
+	}
+}
+
+func TestCherryPick_InvalidHash(t *testing.T) {
+	tmpDir, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	createFile(t, tmpDir, "file.txt", "v1\n")
+	commitChanges(t, tmpDir, "v1")
+
+	repo := NewRepository(tmpDir)
+	if _, err := repo.CherryPick(""); err == nil {
+		t.Error("Expected error for empty hash")
+	}
+	if _, err := repo.CherryPick("deadbeef"); err == nil {
+		t.Error("Expected error for unknown hash")
+	}
+	if _, err := repo.RevertCommit("--abort"); err == nil {
+		t.Error("Expected error for an option as the hash")
+	}
+}
@@ -0,0 +1,239 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// remoteTimeout bounds a push, pull, or fetch so an unreachable remote can't hang the app
+const remoteTimeout = 2 * time.Minute
+
+// AheadBehind counts the commits the current branch and its upstream don't share
+type AheadBehind struct {
+	Upstream string `json:"upstream,omitempty"` // e.g. "origin/main"; empty if the branch has none
+	Ahead    int    `json:"ahead"`              // Local commits not pushed yet
+	Behind   int    `json:"behind"`             // Upstream commits not pulled yet
+}
+
+// RemoteInfo describes a git remote URL split into its host, owner, and repository
+type RemoteInfo struct {
+	URL   string `json:"url"`
+	Host  string `json:"host"`  // e.g. "github.com"
+	Owner string `json:"owner"` // Organization or user; nested groups are joined with "/"
+	Repo  string `json:"repo"`
+}
+
+// Slug returns "owner/repo", or "" when the remote couldn't be parsed
+func (r RemoteInfo) Slug() string {
+	if r.Owner == "" || r.Repo == "" {
+		return ""
+	}
+	return r.Owner + "/" + r.Repo
+}
+
+// WebURL returns the https URL for browsing the repository
+func (r RemoteInfo) WebURL() string {
+	if r.Host == "" || r.Slug() == "" {
+		return ""
+	}
+	return "https://" + r.Host + "/" + r.Slug()
+}
+
+// ParseRemoteURL parses https, ssh, and scp-style (git@host:owner/repo.git) remote URLs
+func ParseRemoteURL(remote string) (RemoteInfo, error) {
+	info := RemoteInfo{URL: remote}
+	remote = strings.TrimSpace(remote)
+	if remote == "" {
+		return info, fmt.Errorf("empty remote URL")
+	}
+
+	var host, path string
+	if strings.Contains(remote, "://") {
+		u, err := url.Parse(remote)
+		if err != nil {
+			return info, fmt.Errorf("invalid remote URL %q: %w", remote, err)
+		}
+		host, path = u.Hostname(), u.Path
+	} else if i := strings.Index(remote, ":"); i > 0 && !strings.Contains(remote[:i], "/") {
+		// scp-style: [user@]host:owner/repo.git
+		host, path = remote[:i], remote[i+1:]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+	} else {
+		return info, fmt.Errorf("unsupported remote URL %q", remote)
+	}
+
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	slash := strings.LastIndex(path, "/")
+	if host == "" || slash <= 0 || slash == len(path)-1 {
+		return info, fmt.Errorf("remote URL %q has no owner/repo path", remote)
+	}
+
+	info.Host = strings.ToLower(host)
+	info.Owner = path[:slash]
+	info.Repo = path[slash+1:]
+	return info, nil
+}
+
+// GetRemoteURL returns the URL of the named remote
+func (r *Repository) GetRemoteURL(name string) (string, error) {
+	if err := checkArg("remote", name); err != nil {
+		return "", err
+	}
+	return r.runGit("remote", "get-url", name)
+}
+
+// GetDefaultBranch returns the branch the origin remote points HEAD at. Without
+// that ref it falls back to a local main or master branch, then the current branch.
+func (r *Repository) GetDefaultBranch() (string, error) {
+	if ref, err := r.runGit("symbolic-ref", "--short", "refs/remotes/origin/HEAD"); err == nil && ref != "" {
+		return strings.TrimPrefix(ref, "origin/"), nil
+	}
+
+	for _, branch := range []string{"main", "master"} {
+		if _, err := r.runGit("rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err == nil {
+			return branch, nil
+		}
+	}
+
+	// A new repository has no commits yet, so read the unborn branch name
+	return r.runGit("symbolic-ref", "--short", "HEAD")
+}
+
+// Fetch downloads new commits from a remote, pruning deleted branches. An
+// empty remote fetches from the current branch's default remote.
+func (r *Repository) Fetch(remote string) error {
+	if err := checkArg("remote", remote); err != nil {
+		return err
+	}
+	args := []string{"fetch", "--prune"}
+	if remote != "" {
+		args = append(args, remote)
+	}
+	_, err := r.runGitRemote(args...)
+	return err
+}
+
+// Pull fast-forwards the current branch to a remote branch. Empty arguments
+// pull from the branch's upstream. Diverged branches fail instead of merging.
+func (r *Repository) Pull(remote, branch string) error {
+	if branch != "" && remote == "" {
+		return fmt.Errorf("a remote is required to pull a branch")
+	}
+	if err := checkArg("remote", remote); err != nil {
+		return err
+	}
+	if branch != "" {
+		if err := r.checkBranchName(branch); err != nil {
+			return err
+		}
+	}
+	args := []string{"pull", "--ff-only"}
+	if remote != "" {
+		args = append(args, remote)
+	}
+	if branch != "" {
+		args = append(args, branch)
+	}
+	_, err := r.runGitRemote(args...)
+	return err
+}
+
+// Push sends a branch to a remote and makes it the branch's upstream. An empty
+// remote means "origin" and an empty branch the current one. Push never forces.
+func (r *Repository) Push(remote, branch string) error {
+	if remote == "" {
+		remote = "origin"
+	}
+	if branch == "" {
+		current, err := r.GetCurrentBranch()
+		if err != nil {
+			return fmt.Errorf("failed to read current branch: %w", err)
+		}
+		if current == "HEAD" {
+			return fmt.Errorf("can't push a detached HEAD")
+		}
+		branch = current
+	}
+	if err := checkArg("remote", remote); err != nil {
+		return err
+	}
+	if err := r.checkBranchName(branch); err != nil {
+		return err
+	}
+	_, err := r.runGitRemote("push", "--set-upstream", remote, branch)
+	return err
+}
+
+// GetAheadBehind compares the current branch with its counterpart on remote,
+// or with its upstream when remote is empty. It uses what was last fetched.
+// A branch with nothing to compare against reports no upstream.
+func (r *Repository) GetAheadBehind(remote string) (*AheadBehind, error) {
+	var upstream string
+	if remote == "" {
+		ref, err := r.runGit("rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
+		if err != nil {
+			return &AheadBehind{}, nil
+		}
+		upstream = ref
+	} else {
+		if err := checkArg("remote", remote); err != nil {
+			return nil, err
+		}
+		branch, err := r.GetCurrentBranch()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read current branch: %w", err)
+		}
+		upstream = remote + "/" + branch
+		if _, err := r.runGit("rev-parse", "--verify", "--quiet", "refs/remotes/"+upstream); err != nil {
+			return &AheadBehind{}, nil
+		}
+	}
+
+	output, err := r.runGit("rev-list", "--left-right", "--count", "HEAD..."+upstream)
+	if err != nil {
+		return nil, err
+	}
+	counts := strings.Fields(output)
+	if len(counts) != 2 {
+		return nil, fmt.Errorf("unexpected rev-list output: %q", output)
+	}
+
+	result := &AheadBehind{Upstream: upstream}
+	result.Ahead, _ = strconv.Atoi(counts[0])
+	result.Behind, _ = strconv.Atoi(counts[1])
+	return result, nil
+}
+
+// runGitRemote runs a git command that talks to a remote. The user's SSH agent
+// and credential helpers are passed through, but git must not prompt for a
+// password since there is no terminal to answer it; the timeout covers
+// anything else that waits for input.
+func (r *Repository) runGitRemote(args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = r.path
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	output, err := cmd.CombinedOutput()
+	out := strings.TrimSpace(string(output))
+	if ctx.Err() == context.DeadlineExceeded {
+		return out, fmt.Errorf("git %s: timed out after %v", args[0], remoteTimeout)
+	}
+	if err != nil {
+		if out != "" {
+			return out, fmt.Errorf("git %s: %w: %s", args[0], err, out)
+		}
+		return out, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
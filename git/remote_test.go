@@ -0,0 +1,216 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseRemoteURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		host    string
+		owner   string
+		repo    string
+		wantErr bool
+	}{
+		{"https://github.com/philjestin/boatmanapp.git", "github.com", "philjestin", "boatmanapp", false},
+		{"https://github.com/philjestin/boatmanapp", "github.com", "philjestin", "boatmanapp", false},
+		{"git@github.com:philjestin/boatmanapp.git", "github.com", "philjestin", "boatmanapp", false},
+		{"ssh://git@GitHub.com:22/philjestin/boatmanapp.git", "github.com", "philjestin", "boatmanapp", false},
+		{"https://gitlab.com/group/subgroup/project.git", "gitlab.com", "group/subgroup", "project", false},
+		{"/srv/git/project.git", "", "", "", true},
+		{"https://github.com/justowner", "", "", "", true},
+		{"", "", "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			info, err := ParseRemoteURL(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRemoteURL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if info.Host != tt.host || info.Owner != tt.owner || info.Repo != tt.repo {
+				t.Errorf("Expected %s %s/%s, got %s %s/%s", tt.host, tt.owner, tt.repo, info.Host, info.Owner, info.Repo)
+			}
+		})
+	}
+}
+
+func TestRemoteInfoWebURL(t *testing.T) {
+	info, _ := ParseRemoteURL("git@github.com:philjestin/boatmanapp.git")
+	if got := info.WebURL(); got != "https://github.com/philjestin/boatmanapp" {
+		t.Errorf("Expected web URL, got %q", got)
+	}
+	if got := (RemoteInfo{}).WebURL(); got != "" {
+		t.Errorf("Expected empty web URL, got %q", got)
+	}
+}
+
+func TestGetRemoteURLAndDefaultBranch(t *testing.T) {
+	repoDir, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	repo := NewRepository(repoDir)
+
+	if _, err := repo.GetRemoteURL("origin"); err == nil {
+		t.Error("Expected error for missing remote")
+	}
+
+	exec.Command("git", "-C", repoDir, "checkout", "-q", "-b", "trunk").Run()
+	branch, err := repo.GetDefaultBranch()
+	if err != nil || branch != "trunk" {
+		t.Errorf("Expected unborn branch trunk, got %q (%v)", branch, err)
+	}
+
+	createFile(t, repoDir, "README.md", "hello")
+	commitChanges(t, repoDir, "initial")
+	exec.Command("git", "-C", repoDir, "branch", "main").Run()
+	exec.Command("git", "-C", repoDir, "remote", "add", "origin", "git@github.com:acme/widgets.git").Run()
+
+	url, err := repo.GetRemoteURL("origin")
+	if err != nil || url != "git@github.com:acme/widgets.git" {
+		t.Errorf("Expected origin URL, got %q (%v)", url, err)
+	}
+
+	// A local main branch wins over the current branch
+	if branch, _ := repo.GetDefaultBranch(); branch != "main" {
+		t.Errorf("Expected main, got %q", branch)
+	}
+
+	// origin/HEAD wins when it is set
+	exec.Command("git", "-C", repoDir, "update-ref", "refs/remotes/origin/trunk", "HEAD").Run()
+	exec.Command("git", "-C", repoDir, "symbolic-ref", "refs/remotes/origin/HEAD", "refs/remotes/origin/trunk").Run()
+	if branch, _ := repo.GetDefaultBranch(); branch != "trunk" {
+		t.Errorf("Expected trunk from origin/HEAD, got %q", branch)
+	}
+}
+
+// createClonedRepo creates a bare remote with one commit and a clone of it, returning the clone and remote paths
+func createClonedRepo(t *testing.T) (string, string) {
+	t.Helper()
+
+	source, cleanup := createTestRepo(t)
+	t.Cleanup(cleanup)
+	createFile(t, source, "README.md", "# Test")
+	commitChanges(t, source, "Initial commit")
+
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	clone := filepath.Join(t.TempDir(), "clone")
+	for _, args := range [][]string{
+		{"clone", "--bare", source, remote},
+		{"clone", remote, clone},
+		{"-C", clone, "config", "user.email", "test@example.com"},
+		{"-C", clone, "config", "user.name", "Test User"},
+	} {
+		if output, err := exec.Command("git", args...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v: %s", args, err, output)
+		}
+	}
+	return clone, remote
+}
+
+func TestPushPullAndAheadBehind(t *testing.T) {
+	clone, remote := createClonedRepo(t)
+	repo := NewRepository(clone)
+
+	status, err := repo.GetAheadBehind("")
+	if err != nil {
+		t.Fatalf("GetAheadBehind() error = %v", err)
+	}
+	if status.Upstream == "" || status.Ahead != 0 || status.Behind != 0 {
+		t.Fatalf("Expected an up-to-date upstream, got %+v", status)
+	}
+
+	createFile(t, clone, "a.txt", "a")
+	commitChanges(t, clone, "Local commit")
+	if status, _ := repo.GetAheadBehind("origin"); status.Ahead != 1 || status.Behind != 0 {
+		t.Errorf("Expected 1 ahead, got %+v", status)
+	}
+
+	if err := repo.Push("", ""); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	if status, _ := repo.GetAheadBehind(""); status.Ahead != 0 {
+		t.Errorf("Expected nothing ahead after pushing, got %+v", status)
+	}
+
+	// Someone else pushes to the remote
+	other := filepath.Join(t.TempDir(), "other")
+	if output, err := exec.Command("git", "clone", remote, other).CombinedOutput(); err != nil {
+		t.Fatalf("clone failed: %v: %s", err, output)
+	}
+	exec.Command("git", "-C", other, "config", "user.email", "other@example.com").Run()
+	exec.Command("git", "-C", other, "config", "user.name", "Other").Run()
+	createFile(t, other, "b.txt", "b")
+	commitChanges(t, other, "Remote commit")
+	if output, err := exec.Command("git", "-C", other, "push").CombinedOutput(); err != nil {
+		t.Fatalf("push failed: %v: %s", err, output)
+	}
+
+	if err := repo.Fetch("origin"); err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if status, _ := repo.GetAheadBehind(""); status.Behind != 1 {
+		t.Errorf("Expected 1 behind after fetching, got %+v", status)
+	}
+
+	if err := repo.Pull("", ""); err != nil {
+		t.Fatalf("Pull() error = %v", err)
+	}
+	if status, _ := repo.GetAheadBehind(""); status.Behind != 0 || status.Ahead != 0 {
+		t.Errorf("Expected up to date after pulling, got %+v", status)
+	}
+	if _, err := os.Stat(filepath.Join(clone, "b.txt")); err != nil {
+		t.Error("Expected the remote commit's file")
+	}
+}
+
+func TestRemoteErrors(t *testing.T) {
+	clone, _ := createClonedRepo(t)
+	repo := NewRepository(clone)
+
+	if err := repo.Push("nowhere", ""); err == nil {
+		t.Error("Expected error pushing to an unknown remote")
+	}
+	if err := repo.Pull("", "main"); err == nil {
+		t.Error("Expected error pulling a branch without a remote")
+	}
+
+	// Options passed as a remote or branch must not reach git
+	marker := filepath.Join(t.TempDir(), "injected")
+	upload := "--upload-pack=touch " + marker + "; git-upload-pack"
+	if err := repo.Fetch(upload); err == nil {
+		t.Error("Expected error fetching from an option")
+	}
+	if err := repo.Pull(upload, ""); err == nil {
+		t.Error("Expected error pulling from an option")
+	}
+	if err := repo.Push("origin", "--force"); err == nil {
+		t.Error("Expected error pushing an option as the branch")
+	}
+	if err := repo.Pull("origin", "main..bad"); err == nil {
+		t.Error("Expected error pulling an invalid branch name")
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Errorf("Expected the injected command not to run, got %v", err)
+	}
+
+	// A new branch has nothing to compare against yet
+	if err := repo.CreateBranch("feature", ""); err != nil {
+		t.Fatalf("CreateBranch() error = %v", err)
+	}
+	if err := repo.Checkout("feature"); err != nil {
+		t.Fatalf("Checkout() error = %v", err)
+	}
+	for _, remote := range []string{"", "origin"} {
+		status, err := repo.GetAheadBehind(remote)
+		if err != nil || status.Upstream != "" {
+			t.Errorf("GetAheadBehind(%q): expected no upstream, got %+v, %v", remote, status, err)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Snapshot records the state of a working tree so it can be put back later
+type Snapshot struct {
+	Head      string    `json:"head"`            // Commit checked out when the snapshot was taken
+	Stash     string    `json:"stash,omitempty"` // Stash commit holding uncommitted changes; empty if the tree was clean
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Stash saves uncommitted changes, including untracked files, and cleans the
+// working tree. It reports false when there was nothing to stash.
+func (r *Repository) Stash(message string) (bool, error) {
+	args := []string{"stash", "push", "--include-untracked"}
+	if message != "" {
+		args = append(args, "-m", message)
+	}
+	output, err := r.runGit(args...)
+	if err != nil {
+		return false, err
+	}
+	return !strings.Contains(output, "No local changes to save"), nil
+}
+
+// StashPop applies the latest stash and removes it from the stash list
+func (r *Repository) StashPop() error {
+	_, err := r.runGit("stash", "pop")
+	return err
+}
+
+// StashApply applies a stash, given as a stash commit or a reference like
+// "stash@{1}", restoring staged changes as staged
+func (r *Repository) StashApply(ref string) error {
+	if strings.TrimSpace(ref) == "" {
+		return fmt.Errorf("stash is required")
+	}
+	_, err := r.runGit("stash", "apply", "--index", ref)
+	return err
+}
+
+// StashDrop removes the stash with the given commit hash from the stash list.
+// A stash that is already gone is not an error.
+func (r *Repository) StashDrop(hash string) error {
+	output, err := r.runGit("stash", "list", "--format=%H")
+	if err != nil {
+		return err
+	}
+	for i, line := range strings.Split(output, "\n") {
+		if line == hash {
+			_, err := r.runGit("stash", "drop", fmt.Sprintf("stash@{%d}", i))
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateSnapshot records HEAD and stashes a copy of the uncommitted changes,
+// leaving the working tree as it was
+func (r *Repository) CreateSnapshot(message string) (*Snapshot, error) {
+	head, err := r.runGit("rev-parse", "HEAD")
+	if err != nil {
+		return nil, fmt.Errorf("a snapshot needs a commit to return to: %w", err)
+	}
+	snapshot := &Snapshot{Head: head, CreatedAt: time.Now()}
+
+	stashed, err := r.Stash(message)
+	if err != nil || !stashed {
+		return snapshot, err
+	}
+
+	hash, err := r.runGit("rev-parse", "stash@{0}")
+	if err != nil {
+		if popErr := r.StashPop(); popErr != nil {
+			return nil, fmt.Errorf("failed to restore stashed changes: %w", popErr)
+		}
+		return nil, err
+	}
+	snapshot.Stash = hash
+
+	// Put the changes back; the stash keeps a copy
+	if err := r.StashApply(hash); err != nil {
+		return nil, fmt.Errorf("failed to restore the working tree, changes are kept in stash %s: %w", hash, err)
+	}
+	return snapshot, nil
+}
+
+// WriteWorkTree records the working tree as it is now, including untracked
+// files git doesn't ignore, as a tree object and returns its id. The index
+// and working tree are left alone. Files can be read back from the tree with
+// BlobID and ReadBlob, even after they change on disk.
+func (r *Repository) WriteWorkTree() (string, error) {
+	index, err := r.runGit("rev-parse", "--git-path", "index")
+	if err != nil {
+		return "", err
+	}
+	if !filepath.IsAbs(index) {
+		index = filepath.Join(r.path, index)
+	}
+
+	// Stage everything into a copy of the index, so only files changed
+	// since it was written are hashed
+	dir, err := os.MkdirTemp("", "boatman-index-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	tempIndex := filepath.Join(dir, "index")
+	if data, err := os.ReadFile(index); err == nil {
+		if err := os.WriteFile(tempIndex, data, 0600); err != nil {
+			return "", err
+		}
+	}
+
+	var tree string
+	for _, args := range [][]string{{"add", "--all"}, {"write-tree"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = r.path
+		cmd.Env = append(os.Environ(), "GIT_INDEX_FILE="+tempIndex)
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return "", fmt.Errorf("git %s: %w: %s", args[0], err, strings.TrimSpace(string(output)))
+		}
+		tree = strings.TrimSpace(string(output))
+	}
+	return tree, nil
+}
+
+// RestoreSnapshot discards everything done since a snapshot: the current
+// branch is reset to the snapshot's commit, untracked files are removed, and
+// the snapshot's uncommitted changes are applied again. Ignored files are kept.
+func (r *Repository) RestoreSnapshot(snapshot Snapshot) error {
+	if snapshot.Head == "" {
+		return fmt.Errorf("snapshot has no commit")
+	}
+	if _, err := r.runGit("reset", "--hard", snapshot.Head); err != nil {
+		return err
+	}
+	if _, err := r.runGit("clean", "-fd"); err != nil {
+		return err
+	}
+	if snapshot.Stash != "" {
+		return r.StashApply(snapshot.Stash)
+	}
+	return nil
+}
+
+// DropSnapshot forgets a snapshot that is no longer needed
+func (r *Repository) DropSnapshot(snapshot Snapshot) error {
+	if snapshot.Stash == "" {
+		return nil
+	}
+	return r.StashDrop(snapshot.Stash)
+}
@@ -0,0 +1,197 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// readFile returns a file's contents, or "" if it doesn't exist
+func readFile(t *testing.T, dir, name string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(dir, name))
+	if os.IsNotExist(err) {
+		return ""
+	}
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", name, err)
+	}
+	return string(content)
+}
+
+func TestStashAndPop(t *testing.T) {
+	tmpDir, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	createFile(t, tmpDir, "file.txt", "original")
+	commitChanges(t, tmpDir, "Initial commit")
+	repo := NewRepository(tmpDir)
+
+	if stashed, err := repo.Stash("nothing"); err != nil || stashed {
+		t.Fatalf("Expected nothing to stash, got %v, %v", stashed, err)
+	}
+
+	createFile(t, tmpDir, "file.txt", "changed")
+	createFile(t, tmpDir, "new.txt", "new")
+	stashed, err := repo.Stash("work in progress")
+	if err != nil || !stashed {
+		t.Fatalf("Stash() = %v, %v", stashed, err)
+	}
+	if readFile(t, tmpDir, "file.txt") != "original" || readFile(t, tmpDir, "new.txt") != "" {
+		t.Fatal("Expected a clean working tree after stashing")
+	}
+
+	if err := repo.StashPop(); err != nil {
+		t.Fatalf("StashPop() error = %v", err)
+	}
+	if readFile(t, tmpDir, "file.txt") != "changed" || readFile(t, tmpDir, "new.txt") != "new" {
+		t.Error("Expected the stashed changes back")
+	}
+	if err := repo.StashPop(); err == nil {
+		t.Error("Expected error popping an empty stash")
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	tmpDir, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	createFile(t, tmpDir, "file.txt", "original")
+	commitChanges(t, tmpDir, "Initial commit")
+	repo := NewRepository(tmpDir)
+
+	// The user's work in progress stays in place
+	createFile(t, tmpDir, "file.txt", "user edit")
+	createFile(t, tmpDir, "notes.txt", "user notes")
+	snapshot, err := repo.CreateSnapshot("before run")
+	if err != nil {
+		t.Fatalf("CreateSnapshot() error = %v", err)
+	}
+	if snapshot.Stash == "" || snapshot.Head != headHash(t, tmpDir) {
+		t.Fatalf("Expected a stash and the current HEAD, got %+v", snapshot)
+	}
+	if readFile(t, tmpDir, "file.txt") != "user edit" || readFile(t, tmpDir, "notes.txt") != "user notes" {
+		t.Fatal("Expected the working tree unchanged by the snapshot")
+	}
+
+	// The agent edits, adds, and commits
+	createFile(t, tmpDir, "file.txt", "agent edit")
+	createFile(t, tmpDir, "agent.txt", "generated")
+	commitChanges(t, tmpDir, "Agent commit")
+	createFile(t, tmpDir, "scratch.txt", "scratch")
+
+	if err := repo.RestoreSnapshot(*snapshot); err != nil {
+		t.Fatalf("RestoreSnapshot() error = %v", err)
+	}
+	if headHash(t, tmpDir) != snapshot.Head {
+		t.Error("Expected HEAD back at the snapshot")
+	}
+	if readFile(t, tmpDir, "file.txt") != "user edit" || readFile(t, tmpDir, "notes.txt") != "user notes" {
+		t.Error("Expected the user's changes restored")
+	}
+	if readFile(t, tmpDir, "agent.txt") != "" || readFile(t, tmpDir, "scratch.txt") != "" {
+		t.Error("Expected the agent's files removed")
+	}
+
+	if err := repo.DropSnapshot(*snapshot); err != nil {
+		t.Fatalf("DropSnapshot() error = %v", err)
+	}
+	if list, _ := repo.runGit("stash", "list"); list != "" {
+		t.Errorf("Expected the stash dropped, got %q", list)
+	}
+	if err := repo.DropSnapshot(*snapshot); err != nil {
+		t.Errorf("Expected dropping twice to succeed, got %v", err)
+	}
+}
+
+func TestSnapshot_CleanTree(t *testing.T) {
+	tmpDir, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	repo := NewRepository(tmpDir)
+	if _, err := repo.CreateSnapshot("no commits"); err == nil {
+		t.Error("Expected error without a commit")
+	}
+
+	createFile(t, tmpDir, "file.txt", "original")
+	commitChanges(t, tmpDir, "Initial commit")
+
+	snapshot, err := repo.CreateSnapshot("clean")
+	if err != nil {
+		t.Fatalf("CreateSnapshot() error = %v", err)
+	}
+	if snapshot.Stash != "" {
+		t.Errorf("Expected no stash for a clean tree, got %s", snapshot.Stash)
+	}
+
+	createFile(t, tmpDir, "file.txt", "agent edit")
+	if err := repo.RestoreSnapshot(*snapshot); err != nil {
+		t.Fatalf("RestoreSnapshot() error = %v", err)
+	}
+	if readFile(t, tmpDir, "file.txt") != "original" {
+		t.Error("Expected the agent's edit discarded")
+	}
+}
+
+func TestWriteWorkTree(t *testing.T) {
+	tmpDir, cleanup := createTestRepo(t)
+	defer cleanup()
+
+	createFile(t, tmpDir, ".gitignore", ".env\n")
+	createFile(t, tmpDir, "file.txt", "original")
+	createFile(t, tmpDir, "staged.txt", "staged")
+	commitChanges(t, tmpDir, "Initial commit")
+	createFile(t, tmpDir, "file.txt", "uncommitted")
+	createFile(t, tmpDir, "new.txt", "untracked")
+	createFile(t, tmpDir, ".env", "SECRET=1")
+	if err := os.Mkdir(filepath.Join(tmpDir, "sub"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+
+	// Run from a subdirectory; the whole work tree is written
+	repo := NewRepository(filepath.Join(tmpDir, "sub"))
+	tree, err := repo.WriteWorkTree()
+	if err != nil {
+		t.Fatalf("WriteWorkTree() error = %v", err)
+	}
+
+	createFile(t, tmpDir, "file.txt", "changed later")
+	for path, want := range map[string]string{"../file.txt": "uncommitted", "../new.txt": "untracked", "../staged.txt": "staged"} {
+		id, err := repo.BlobID(tree, path)
+		if err != nil {
+			t.Fatalf("BlobID(%s) error = %v", path, err)
+		}
+		if content, err := repo.ReadBlob(id); err != nil || string(content) != want {
+			t.Errorf("Expected %s to hold %q, got %q, %v", path, want, content, err)
+		}
+	}
+	if _, err := repo.BlobID(tree, "../.env"); err == nil {
+		t.Error("Expected ignored files left out of the tree")
+	}
+
+	// The real index is untouched
+	status, err := repo.GetStatus()
+	if err != nil {
+		t.Fatalf("GetStatus() error = %v", err)
+	}
+	if len(status.Added) != 0 || len(status.Untracked) != 1 {
+		t.Errorf("Expected nothing staged, got %+v", status)
+	}
+}
+
+func TestIsIgnored(t *testing.T) {
+	tmpDir, cleanup := createTestRepo(t)
+	defer cleanup()
+	createFile(t, tmpDir, ".gitignore", ".env\n")
+
+	repo := NewRepository(tmpDir)
+	if ignored, err := repo.IsIgnored(".env"); err != nil || !ignored {
+		t.Errorf("Expected .env ignored, got %v, %v", ignored, err)
+	}
+	if ignored, err := repo.IsIgnored("main.go"); err != nil || ignored {
+		t.Errorf("Expected main.go not ignored, got %v, %v", ignored, err)
+	}
+	if _, err := repo.IsIgnored(filepath.Join(t.TempDir(), "x")); err == nil {
+		t.Error("Expected an error for a path outside the repository")
+	}
+}
@@ -0,0 +1,225 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRecent is how many entries are kept in memory for GetRecentLogs
+const maxRecent = 2000
+
+// Entry is a logged record, as returned to the frontend
+type Entry struct {
+	Time    time.Time         `json:"time"`
+	Level   string            `json:"level"`
+	Module  string            `json:"module"`
+	Message string            `json:"message"`
+	Attrs   map[string]string `json:"attrs,omitempty"`
+}
+
+var (
+	level = new(slog.LevelVar)
+
+	mu     sync.Mutex
+	out    io.Writer = os.Stdout
+	file   *rotatingFile
+	recent []Entry
+	next   int // Where the next entry goes once recent is full
+)
+
+// For returns the logger for a module, e.g. "agent" or "notify". Loggers can
+// be created before Init; they pick up its output when it runs.
+func For(module string) *slog.Logger {
+	return slog.New(&handler{
+		module: module,
+		text:   slog.NewTextHandler(writer{}, &slog.HandlerOptions{Level: level}),
+	}).With("module", module)
+}
+
+// DefaultDir returns the directory logs rotate in (~/.boatman/logs)
+func DefaultDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".boatman", "logs"), nil
+}
+
+// Init starts writing logs to boatman.log in dir as well as stdout, rotating
+// the file once it grows past maxSize bytes
+func Init(dir string, maxSize int64, keep int) error {
+	f, err := openRotatingFile(filepath.Join(dir, "boatman.log"), maxSize, keep)
+	if err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if file != nil {
+		file.Close()
+	}
+	file = f
+	out = io.MultiWriter(os.Stdout, f)
+	return nil
+}
+
+// Close stops writing logs to the log file
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	out = os.Stdout
+	if file == nil {
+		return nil
+	}
+	err := file.Close()
+	file = nil
+	return err
+}
+
+// ParseLevel parses "debug", "info", "warn" or "error"
+func ParseLevel(name string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+		return 0, fmt.Errorf("invalid log level %q: use debug, info, warn, or error", name)
+	}
+	return l, nil
+}
+
+// SetLevel sets the lowest level logged
+func SetLevel(name string) error {
+	l, err := ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	level.Set(l)
+	return nil
+}
+
+// Level returns the lowest level logged
+func Level() string {
+	return strings.ToLower(level.Level().String())
+}
+
+// Recent returns up to limit of the latest entries at or above minLevel,
+// oldest first. A limit of zero or less returns all of them.
+func Recent(limit int, minLevel string) ([]Entry, error) {
+	lowest := slog.LevelDebug
+	if minLevel != "" {
+		l, err := ParseLevel(minLevel)
+		if err != nil {
+			return nil, err
+		}
+		lowest = l
+	}
+
+	mu.Lock()
+	ordered := make([]Entry, 0, len(recent))
+	ordered = append(ordered, recent[next:]...)
+	ordered = append(ordered, recent[:next]...)
+	mu.Unlock()
+
+	var entries []Entry
+	for _, entry := range ordered {
+		if l, err := ParseLevel(entry.Level); err == nil && l >= lowest {
+			entries = append(entries, entry)
+		}
+	}
+	if limit > 0 && len(entries) > limit {
+		entries = entries[len(entries)-limit:]
+	}
+	return entries, nil
+}
+
+// record keeps an entry for Recent, dropping the oldest once full
+func record(entry Entry) {
+	mu.Lock()
+	defer mu.Unlock()
+	if len(recent) < maxRecent {
+		recent = append(recent, entry)
+		return
+	}
+	recent[next] = entry
+	next = (next + 1) % maxRecent
+}
+
+// writer writes to wherever logs currently go
+type writer struct{}
+
+func (writer) Write(p []byte) (int, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	return out.Write(p)
+}
+
+// handler writes records as text and keeps them for Recent
+type handler struct {
+	module string
+	text   slog.Handler
+	attrs  []slog.Attr
+	group  string
+}
+
+func (h *handler) Enabled(ctx context.Context, l slog.Level) bool {
+	return h.text.Enabled(ctx, l)
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	entry := Entry{
+		Time:    r.Time,
+		Level:   strings.ToLower(r.Level.String()),
+		Module:  h.module,
+		Message: r.Message,
+	}
+	add := func(a slog.Attr) {
+		if a.Key == "module" || a.Key == "" {
+			return
+		}
+		if entry.Attrs == nil {
+			entry.Attrs = make(map[string]string)
+		}
+		entry.Attrs[a.Key] = a.Value.String()
+	}
+	for _, a := range h.attrs {
+		add(a)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		if h.group != "" {
+			a.Key = h.group + "." + a.Key
+		}
+		add(a)
+		return true
+	})
+	record(entry)
+
+	return h.text.Handle(ctx, r)
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.text = h.text.WithAttrs(attrs)
+	clone.attrs = append([]slog.Attr(nil), h.attrs...)
+	for _, a := range attrs {
+		if h.group != "" {
+			a.Key = h.group + "." + a.Key
+		}
+		clone.attrs = append(clone.attrs, a)
+	}
+	return &clone
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.text = h.text.WithGroup(name)
+	if h.group != "" {
+		name = h.group + "." + name
+	}
+	clone.group = name
+	return &clone
+}
@@ -0,0 +1,133 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// resetRecent clears the kept entries and restores the level afterwards
+func resetRecent(t *testing.T) {
+	t.Helper()
+	saved := level.Level()
+	mu.Lock()
+	recent, next = nil, 0
+	mu.Unlock()
+	t.Cleanup(func() {
+		level.Set(saved)
+		mu.Lock()
+		recent, next = nil, 0
+		mu.Unlock()
+	})
+}
+
+func TestRecent(t *testing.T) {
+	resetRecent(t)
+	if err := SetLevel("debug"); err != nil {
+		t.Fatalf("SetLevel failed: %v", err)
+	}
+
+	logger := For("agent").With("session", "s1")
+	logger.Debug("claude event", "type", "result")
+	logger.Warn("Failed to save session", "error", "disk full")
+	For("notify").Error("Failed to send notification")
+
+	tests := []struct {
+		name     string
+		limit    int
+		level    string
+		expected []string
+	}{
+		{name: "all", expected: []string{"claude event", "Failed to save session", "Failed to send notification"}},
+		{name: "warnings and up", level: "warn", expected: []string{"Failed to save session", "Failed to send notification"}},
+		{name: "limit keeps the latest", limit: 1, expected: []string{"Failed to send notification"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := Recent(tt.limit, tt.level)
+			if err != nil {
+				t.Fatalf("Recent failed: %v", err)
+			}
+			var messages []string
+			for _, entry := range entries {
+				messages = append(messages, entry.Message)
+			}
+			if strings.Join(messages, "|") != strings.Join(tt.expected, "|") {
+				t.Errorf("Expected %q, got %q", tt.expected, messages)
+			}
+		})
+	}
+
+	entries, _ := Recent(0, "warn")
+	warning := entries[0]
+	if warning.Module != "agent" || warning.Level != "warn" {
+		t.Errorf("Expected an agent warning, got %+v", warning)
+	}
+	if warning.Attrs["session"] != "s1" || warning.Attrs["error"] != "disk full" {
+		t.Errorf("Expected the session and error attributes, got %v", warning.Attrs)
+	}
+	if _, ok := warning.Attrs["module"]; ok {
+		t.Error("Expected the module kept out of the attributes")
+	}
+
+	if _, err := Recent(0, "loud"); err == nil {
+		t.Error("Expected an error for an unknown level")
+	}
+}
+
+func TestLevelFiltering(t *testing.T) {
+	resetRecent(t)
+	if err := SetLevel("info"); err != nil {
+		t.Fatalf("SetLevel failed: %v", err)
+	}
+	if Level() != "info" {
+		t.Errorf("Expected info, got %q", Level())
+	}
+
+	For("agent").Debug("noisy")
+	if entries, _ := Recent(0, ""); len(entries) != 0 {
+		t.Errorf("Expected debug entries dropped at info, got %+v", entries)
+	}
+
+	if err := SetLevel("verbose"); err == nil {
+		t.Error("Expected an error for an unknown level")
+	}
+}
+
+func TestRecentWrapsAround(t *testing.T) {
+	resetRecent(t)
+	for i := 0; i < maxRecent+3; i++ {
+		record(Entry{Level: "info", Message: string(rune('a' + i%26))})
+	}
+
+	entries, _ := Recent(0, "")
+	if len(entries) != maxRecent {
+		t.Fatalf("Expected %d entries, got %d", maxRecent, len(entries))
+	}
+	// The first three were dropped, so the oldest kept is the fourth
+	if entries[0].Message != "d" {
+		t.Errorf("Expected the oldest kept entry first, got %q", entries[0].Message)
+	}
+}
+
+func TestInitWritesLogFile(t *testing.T) {
+	resetRecent(t)
+	dir := t.TempDir()
+	if err := Init(dir, 1<<20, 2); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	For("app").Warn("Failed to install update", "error", "timeout")
+	if err := Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "boatman.log"))
+	if err != nil {
+		t.Fatalf("Failed to read log file: %v", err)
+	}
+	if line := string(data); !strings.Contains(line, `msg="Failed to install update"`) || !strings.Contains(line, "module=app") {
+		t.Errorf("Expected the entry in the log file, got %q", line)
+	}
+}
@@ -2,6 +2,10 @@ package main
 
 import (
 	"embed"
+	"os"
+
+	"boatman/agent"
+	"boatman/memory"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -12,7 +16,20 @@ import (
 //go:embed all:frontend/dist
 var assets embed.FS
 
+// version is the release version, set at build time with -ldflags "-X main.version=1.2.3"
+var version = "dev"
+
 func main() {
+	// Sessions run this binary as a claude hook to enforce filesystem guardrails
+	if len(os.Args) > 1 && os.Args[1] == agent.GuardHookCommand {
+		os.Exit(agent.GuardHookMain(os.Args[2:]))
+	}
+
+	// ...and as the MCP server whose tool adds to a project's memory
+	if len(os.Args) > 1 && os.Args[1] == memory.ServerCommand {
+		os.Exit(memory.ServerMain(os.Args[2:]))
+	}
+
 	// Create an instance of the app structure
 	app := NewApp()
 
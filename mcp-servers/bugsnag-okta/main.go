@@ -1,210 +1,532 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"boatman/mcp-servers/internal/framework"
+	"boatman/mcp-servers/internal/mcpserver"
+)
+
+// serverVersion is the version reported in initialize and server_info
+const serverVersion = "1.0.0"
+
+const (
+	// maxPageSize is the largest page the Bugsnag API returns
+	maxPageSize = 100
+	// maxResultsLimit caps max_results so one call can't page through a whole project
+	maxResultsLimit = 1000
 )
 
 // BugsnagMCPServer implements MCP protocol for Bugsnag with Okta OAuth
 type BugsnagMCPServer struct {
 	accessToken string
+	config      *framework.Config
+	logger      *mcpserver.Logger
+	client      *framework.HTTPClient
 }
 
 func main() {
+	cfg, err := framework.Load(os.Args[1:], framework.Options{
+		Name: "bugsnag-okta",
+		Env: map[string]string{
+			framework.KeyConfig:         "BUGSNAG_MCP_CONFIG",
+			framework.KeySite:           "BUGSNAG_API_HOST",
+			framework.KeyOrg:            "BUGSNAG_ORG_ID",
+			framework.KeyDefaultProject: "BUGSNAG_PROJECT_ID",
+			framework.KeyCacheTTL:       "BUGSNAG_MCP_CACHE_TTL",
+			framework.KeyMaxConcurrency: "BUGSNAG_MCP_MAX_CONCURRENCY",
+			framework.KeyRequestTimeout: "BUGSNAG_MCP_REQUEST_TIMEOUT",
+			framework.KeyMaxResults:     "BUGSNAG_MCP_MAX_RESULTS",
+			framework.KeyLogFile:        "BUGSNAG_MCP_LOG_FILE",
+			framework.KeyLogLevel:       "BUGSNAG_MCP_LOG_LEVEL",
+			framework.KeyHTTPTimeout:    "BUGSNAG_MCP_HTTP_TIMEOUT",
+			framework.KeyMaxRetries:     "BUGSNAG_MCP_MAX_RETRIES",
+			framework.KeyRateLimit:      "BUGSNAG_MCP_RATE_LIMIT",
+			framework.KeyMaxResultSize:  "BUGSNAG_MCP_MAX_RESULT_SIZE",
+		},
+		Defaults: framework.Config{
+			Site:           "api.bugsnag.com",
+			MaxConcurrency: 4,
+			RequestTimeout: 2 * time.Minute,
+			MaxResults:     50,
+			LogLevel:       "info",
+			HTTPTimeout:    30 * time.Second,
+			MaxRetries:     3,
+			RateLimit:      10,
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// stdout carries only JSON-RPC frames; everything else goes to the log
+	logger, protocolOut, err := mcpserver.SetupLogging(cfg.LogFile, cfg.LogLevel)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	accessToken := os.Getenv("OKTA_ACCESS_TOKEN")
 	if accessToken == "" {
 		log.Fatal("OKTA_ACCESS_TOKEN environment variable is required")
 	}
 
-	server := &BugsnagMCPServer{
+	bugsnag := &BugsnagMCPServer{
 		accessToken: accessToken,
-	}
+		client:      framework.NewHTTPClient(cfg),
+		config:      cfg,
+		logger:      logger,
+	}
+
+	server := mcpserver.New(mcpserver.Options{
+		Name:           "bugsnag-okta-mcp",
+		Version:        serverVersion,
+		Logger:         logger,
+		Out:            protocolOut,
+		MaxConcurrency: cfg.MaxConcurrency,
+		RequestTimeout: cfg.RequestTimeout,
+		Cache:          framework.NewCache(cfg.CacheTTL),
+		MaxResultSize:  cfg.MaxResultSize,
+	})
+	bugsnag.registerTools(server)
 
 	// Read MCP requests from stdin, write responses to stdout
-	decoder := json.NewDecoder(os.Stdin)
-	encoder := json.NewEncoder(os.Stdout)
-
-	for {
-		var request map[string]interface{}
-		if err := decoder.Decode(&request); err != nil {
-			if err == io.EOF {
-				break
-			}
-			log.Printf("Error decoding request: %v", err)
-			continue
-		}
-
-		response := server.handleRequest(request)
-		if err := encoder.Encode(response); err != nil {
-			log.Printf("Error encoding response: %v", err)
-		}
+	if err := server.Serve(os.Stdin); err != nil {
+		os.Exit(1)
 	}
 }
 
-func (s *BugsnagMCPServer) handleRequest(request map[string]interface{}) map[string]interface{} {
-	method, ok := request["method"].(string)
-	if !ok {
-		return s.errorResponse("invalid method")
-	}
+// projectArgs are the arguments of tools scoped to a project
+type projectArgs struct {
+	ProjectID string `json:"project_id"`
+}
 
-	switch method {
-	case "initialize":
-		return s.handleInitialize(request)
-	case "tools/list":
-		return s.handleToolsList()
-	case "tools/call":
-		return s.handleToolsCall(request)
-	default:
-		return s.errorResponse(fmt.Sprintf("unknown method: %s", method))
-	}
+// listErrorsArgs are the arguments of bugsnag_list_errors
+type listErrorsArgs struct {
+	projectArgs
+	Filters    errorFilters `json:"filters"`
+	MaxResults int          `json:"max_results"`
 }
 
-func (s *BugsnagMCPServer) handleInitialize(request map[string]interface{}) map[string]interface{} {
-	return map[string]interface{}{
-		"jsonrpc": "2.0",
-		"id":      request["id"],
-		"result": map[string]interface{}{
-			"protocolVersion": "1.0.0",
-			"serverInfo": map[string]interface{}{
-				"name":    "bugsnag-okta-mcp",
-				"version": "1.0.0",
-			},
-			"capabilities": map[string]interface{}{
-				"tools": map[string]interface{}{},
-			},
-		},
-	}
+// errorFilters are the bugsnag_list_errors filters, each translated into a
+// Bugsnag filters[...] query parameter
+type errorFilters struct {
+	ReleaseStage string `json:"release_stage"`
+	Severity     string `json:"severity"`
+	Since        string `json:"since"`
+	Search       string `json:"search"`
+}
+
+// errorArgs are the arguments of tools about one error
+type errorArgs struct {
+	projectArgs
+	ErrorID string `json:"error_id"`
+}
+
+// stacktraceArgs are the arguments of bugsnag_get_stacktrace
+type stacktraceArgs struct {
+	errorArgs
+	InProjectOnly bool `json:"in_project_only"`
+}
+
+// updateErrorArgs are the arguments of bugsnag_update_error
+type updateErrorArgs struct {
+	errorArgs
+	Status            string `json:"status"`
+	SnoozeHours       int    `json:"snooze_hours"`
+	SnoozeOccurrences int    `json:"snooze_occurrences"`
 }
 
-func (s *BugsnagMCPServer) handleToolsList() map[string]interface{} {
-	tools := []map[string]interface{}{
-		{
-			"name":        "bugsnag_list_projects",
-			"description": "List all Bugsnag projects",
-			"inputSchema": map[string]interface{}{
-				"type":       "object",
-				"properties": map[string]interface{}{},
+// commentArgs are the arguments of bugsnag_add_comment
+type commentArgs struct {
+	errorArgs
+	Message string `json:"message"`
+}
+
+// errorOperations maps bugsnag_update_error statuses to Bugsnag error operations
+var errorOperations = map[string]string{
+	"open":    "open",
+	"fixed":   "fix",
+	"ignored": "ignore",
+	"snoozed": "snooze",
+}
+
+// projectIDSchema and errorIDSchema describe the arguments most tools share
+var (
+	projectIDSchema = map[string]interface{}{
+		"type":        "string",
+		"description": "Bugsnag project ID (defaults to the configured project)",
+	}
+	errorIDSchema = map[string]interface{}{
+		"type":        "string",
+		"description": "Error ID",
+	}
+)
+
+// registerTools adds the Bugsnag tools to the server. Read-only tools are
+// cached, so identical calls share a result; tools that change an error and
+// server_info are not.
+func (s *BugsnagMCPServer) registerTools(server *mcpserver.Server) {
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "bugsnag_list_projects",
+		Description: "List all Bugsnag projects",
+	}, func(ctx context.Context, _ struct{}) (interface{}, error) {
+		return s.listProjects(ctx)
+	})
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "bugsnag_list_errors",
+		Description: "List recent errors for a project",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"project_id": projectIDSchema,
+				"filters": map[string]interface{}{
+					"type":        "object",
+					"description": "Optional filters",
+					"properties": map[string]interface{}{
+						"release_stage": map[string]interface{}{
+							"type":        "string",
+							"description": "Release stage, e.g. production",
+						},
+						"severity": map[string]interface{}{
+							"type": "string",
+							"enum": []string{"error", "warning", "info"},
+						},
+						"since": map[string]interface{}{
+							"type":        "string",
+							"description": "Only errors seen since this time: a relative period like 7d or an ISO 8601 timestamp",
+						},
+						"search": map[string]interface{}{
+							"type":        "string",
+							"description": "Free-text search across error classes and messages",
+						},
+					},
+				},
+				"max_results": map[string]interface{}{
+					"type":        "integer",
+					"description": fmt.Sprintf("Maximum number of errors to return, following pages as needed (default %d, at most %d)", s.config.MaxResults, maxResultsLimit),
+				},
 			},
 		},
-		{
-			"name":        "bugsnag_list_errors",
-			"description": "List recent errors for a project",
-			"inputSchema": map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"project_id": map[string]interface{}{
-						"type":        "string",
-						"description": "Bugsnag project ID",
-					},
-					"filters": map[string]interface{}{
-						"type":        "object",
-						"description": "Optional filters (release_stage, severity, etc.)",
-					},
+	}, s.listErrors)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "bugsnag_get_error",
+		Description: "Get detailed information about a specific error",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"project_id": projectIDSchema,
+				"error_id":   errorIDSchema,
+			},
+			"required": []string{"error_id"},
+		},
+	}, s.getError)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "bugsnag_get_stacktrace",
+		Description: "Get the exception chain of an error's latest event as structured stack frames (file, line, method, in-project flag)",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"project_id": projectIDSchema,
+				"error_id":   errorIDSchema,
+				"in_project_only": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Only include frames from the project's own code (default false)",
 				},
-				"required": []string{"project_id"},
 			},
+			"required": []string{"error_id"},
 		},
-		{
-			"name":        "bugsnag_get_error",
-			"description": "Get detailed information about a specific error",
-			"inputSchema": map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"project_id": map[string]interface{}{
-						"type":        "string",
-						"description": "Bugsnag project ID",
-					},
-					"error_id": map[string]interface{}{
-						"type":        "string",
-						"description": "Error ID",
-					},
+	}, s.getStacktrace)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "bugsnag_get_latest_event_stacktrace",
+		Description: fmt.Sprintf("Summarize an error's latest event: release stage, app version, and each exception's class, message, and up to %d in-project stack frames. Prefer this over bugsnag_get_error for triage, as it is a fraction of the size.", maxSummaryFrames),
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"project_id": projectIDSchema,
+				"error_id":   errorIDSchema,
+			},
+			"required": []string{"error_id"},
+		},
+	}, s.getLatestEventStacktrace)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "bugsnag_list_events",
+		Description: "List events (occurrences) for a specific error",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"project_id": projectIDSchema,
+				"error_id":   errorIDSchema,
+			},
+			"required": []string{"error_id"},
+		},
+	}, s.listEvents)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "bugsnag_update_error",
+		Description: "Change an error's status: reopen it, mark it fixed, ignore it, or snooze it until it occurs again",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"project_id": projectIDSchema,
+				"error_id":   errorIDSchema,
+				"status": map[string]interface{}{
+					"type": "string",
+					"enum": []string{"open", "fixed", "ignored", "snoozed"},
+				},
+				"snooze_hours": map[string]interface{}{
+					"type":        "integer",
+					"description": "For snoozed: reopen the error if it occurs after this many hours",
+				},
+				"snooze_occurrences": map[string]interface{}{
+					"type":        "integer",
+					"description": "For snoozed: reopen the error after this many more occurrences",
 				},
-				"required": []string{"project_id", "error_id"},
 			},
+			"required": []string{"error_id", "status"},
 		},
-		{
-			"name":        "bugsnag_list_events",
-			"description": "List events (occurrences) for a specific error",
-			"inputSchema": map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"project_id": map[string]interface{}{
-						"type":        "string",
-						"description": "Bugsnag project ID",
-					},
-					"error_id": map[string]interface{}{
-						"type":        "string",
-						"description": "Error ID",
-					},
+		NoCache: true,
+	}, s.updateError)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "bugsnag_add_comment",
+		Description: "Add a comment to an error, e.g. to record triage findings",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"project_id": projectIDSchema,
+				"error_id":   errorIDSchema,
+				"message": map[string]interface{}{
+					"type":        "string",
+					"description": "Comment text",
 				},
-				"required": []string{"project_id", "error_id"},
 			},
+			"required": []string{"error_id", "message"},
 		},
-	}
-
-	return map[string]interface{}{
-		"jsonrpc": "2.0",
-		"result": map[string]interface{}{
-			"tools": tools,
+		NoCache: true,
+	}, s.addComment)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "bugsnag_list_comments",
+		Description: "List the comments on an error",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"project_id": projectIDSchema,
+				"error_id":   errorIDSchema,
+			},
+			"required": []string{"error_id"},
 		},
-	}
+		// Not cached so comments added during a session show up
+		NoCache: true,
+	}, s.listComments)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "server_info",
+		Description: "Report this MCP server's version, build info, and which credentials are configured",
+		NoCache:     true,
+	}, func(context.Context, struct{}) (interface{}, error) {
+		return s.serverInfo()
+	})
 }
 
-func (s *BugsnagMCPServer) handleToolsCall(request map[string]interface{}) map[string]interface{} {
-	params, ok := request["params"].(map[string]interface{})
-	if !ok {
-		return s.errorResponse("invalid params")
+func (s *BugsnagMCPServer) listProjects(ctx context.Context) (interface{}, error) {
+	// List the configured organization's projects, or the user's organizations
+	// so the agent can find one
+	url := fmt.Sprintf("https://%s/user/organizations", s.config.Site)
+	if s.config.Org != "" {
+		url = fmt.Sprintf("https://%s/organizations/%s/projects?per_page=%d", s.config.Site, s.config.Org, s.config.MaxResults)
 	}
 
-	name, ok := params["name"].(string)
-	if !ok {
-		return s.errorResponse("missing tool name")
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	arguments, _ := params["arguments"].(map[string]interface{})
+	req.Header.Set("Authorization", "Bearer "+s.accessToken)
+	req.Header.Set("X-Version", "2")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Bugsnag API error: %s - %s", resp.Status, string(bodyBytes))
+	}
 
 	var result interface{}
-	var err error
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
 
-	switch name {
-	case "bugsnag_list_projects":
-		result, err = s.listProjects()
-	case "bugsnag_list_errors":
-		result, err = s.listErrors(arguments)
-	case "bugsnag_get_error":
-		result, err = s.getError(arguments)
-	case "bugsnag_list_events":
-		result, err = s.listEvents(arguments)
-	default:
-		return s.errorResponse(fmt.Sprintf("unknown tool: %s", name))
+func (s *BugsnagMCPServer) listErrors(ctx context.Context, args listErrorsArgs) (interface{}, error) {
+	projectID := s.projectID(args.ProjectID)
+	if projectID == "" {
+		return nil, mcpserver.InvalidParams("project_id is required")
 	}
 
+	maxResults := args.MaxResults
+	if maxResults <= 0 {
+		maxResults = s.config.MaxResults
+	}
+	if maxResults > maxResultsLimit {
+		maxResults = maxResultsLimit
+	}
+
+	query, err := args.Filters.query()
 	if err != nil {
-		return s.errorResponse(err.Error())
+		return nil, err
+	}
+	query.Set("per_page", strconv.Itoa(min(maxResults, maxPageSize)))
+
+	// Follow the Link header until there are enough errors or no more pages
+	next := fmt.Sprintf("https://%s/projects/%s/errors?%s", s.config.Site, url.PathEscape(projectID), query.Encode())
+	items := []interface{}{}
+	var totalCount interface{}
+	for next != "" && len(items) < maxResults {
+		page, resp, err := s.getPage(ctx, next)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page...)
+		if total, err := strconv.Atoi(resp.Header.Get("X-Total-Count")); err == nil {
+			totalCount = total
+		}
+		if next = nextPageURL(resp.Header.Get("Link")); next != "" && !s.isAPIURL(next) {
+			s.logger.Warnf("Ignoring next page on another host: %s", next)
+			next = ""
+		}
+	}
+
+	truncated := next != "" || len(items) > maxResults
+	if len(items) > maxResults {
+		items = items[:maxResults]
 	}
 
 	return map[string]interface{}{
-		"jsonrpc": "2.0",
-		"id":      request["id"],
-		"result": map[string]interface{}{
-			"content": []map[string]interface{}{
-				{
-					"type": "text",
-					"text": fmt.Sprintf("%v", result),
-				},
-			},
-		},
+		"errors":     items,
+		"count":      len(items),
+		"totalCount": totalCount,
+		"truncated":  truncated,
+	}, nil
+}
+
+// query translates the filters into Bugsnag filters[field][][type|value]
+// query parameters
+func (f errorFilters) query() (url.Values, error) {
+	query := url.Values{}
+	add := func(field, value string) {
+		query.Add("filters["+field+"][][type]", "eq")
+		query.Add("filters["+field+"][][value]", value)
+	}
+
+	if f.ReleaseStage != "" {
+		add("app.release_stage", f.ReleaseStage)
+	}
+	if f.Severity != "" {
+		switch f.Severity {
+		case "error", "warning", "info":
+			add("event.severity", f.Severity)
+		default:
+			return nil, mcpserver.InvalidParams("severity must be error, warning, or info, got %q", f.Severity)
+		}
+	}
+	if f.Since != "" {
+		add("event.since", f.Since)
+	}
+	if f.Search != "" {
+		add("search", f.Search)
+	}
+	return query, nil
+}
+
+// getPage fetches one page of a list endpoint, returning its items and the
+// response so the caller can read the pagination headers
+func (s *BugsnagMCPServer) getPage(ctx context.Context, pageURL string) ([]interface{}, *http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", pageURL, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+s.accessToken)
+	req.Header.Set("X-Version", "2")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, nil, fmt.Errorf("Bugsnag API error: %s - %s", resp.Status, string(bodyBytes))
+	}
+
+	var page []interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		return nil, nil, err
+	}
+
+	return page, resp, nil
+}
+
+// nextPageURL returns the rel="next" target of a Link header, or "" on the
+// last page
+func nextPageURL(link string) string {
+	for _, part := range strings.Split(link, ",") {
+		segments := strings.Split(part, ";")
+		target := strings.TrimSpace(segments[0])
+		if len(segments) < 2 || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+			continue
+		}
+		for _, param := range segments[1:] {
+			if strings.TrimSpace(param) == `rel="next"` {
+				return target[1 : len(target)-1]
+			}
+		}
 	}
+	return ""
+}
+
+// isAPIURL reports whether rawURL points at the configured Bugsnag API host,
+// so the access token is never sent anywhere else
+func (s *BugsnagMCPServer) isAPIURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	return err == nil && u.Scheme == "https" && u.Host == s.config.Site
 }
 
-func (s *BugsnagMCPServer) listProjects() (interface{}, error) {
-	url := "https://api.bugsnag.com/user/organizations"
+func (s *BugsnagMCPServer) getError(ctx context.Context, args errorArgs) (interface{}, error) {
+	projectID := s.projectID(args.ProjectID)
+	errorID := args.ErrorID
+
+	if projectID == "" || errorID == "" {
+		return nil, mcpserver.InvalidParams("project_id and error_id are required")
+	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	url := fmt.Sprintf("https://%s/projects/%s/errors/%s", s.config.Site, projectID, errorID)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -212,8 +534,7 @@ func (s *BugsnagMCPServer) listProjects() (interface{}, error) {
 	req.Header.Set("Authorization", "Bearer "+s.accessToken)
 	req.Header.Set("X-Version", "2")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := s.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -232,15 +553,136 @@ func (s *BugsnagMCPServer) listProjects() (interface{}, error) {
 	return result, nil
 }
 
-func (s *BugsnagMCPServer) listErrors(args map[string]interface{}) (interface{}, error) {
-	projectID, ok := args["project_id"].(string)
-	if !ok || projectID == "" {
-		return nil, fmt.Errorf("project_id is required")
+// StackFrame is a single frame of an exception's stacktrace
+type StackFrame struct {
+	File      string `json:"file"`
+	Line      int    `json:"line"`
+	Column    int    `json:"column,omitempty"`
+	Method    string `json:"method"`
+	InProject bool   `json:"inProject"`
+}
+
+// ExceptionInfo is one exception in an event's exception chain
+type ExceptionInfo struct {
+	ErrorClass string       `json:"errorClass"`
+	Message    string       `json:"message"`
+	Frames     []StackFrame `json:"frames"`
+	// OmittedFrames counts the frames left out of a summary
+	OmittedFrames int `json:"omittedFrames,omitempty"`
+}
+
+// StacktraceSummary is a compact view of an error's latest event
+type StacktraceSummary struct {
+	EventID      string          `json:"eventId"`
+	ReceivedAt   string          `json:"receivedAt,omitempty"`
+	Severity     string          `json:"severity,omitempty"`
+	Unhandled    bool            `json:"unhandled"`
+	Context      string          `json:"context,omitempty"`
+	ReleaseStage string          `json:"releaseStage,omitempty"`
+	AppVersion   string          `json:"appVersion,omitempty"`
+	Exceptions   []ExceptionInfo `json:"exceptions"`
+}
+
+const (
+	// maxSummaryFrames is the number of frames a summary keeps per exception
+	maxSummaryFrames = 10
+	// maxSummaryMessage is the length a summary truncates exception messages to
+	maxSummaryMessage = 500
+)
+
+// latestEvent is the part of an error's latest event the stacktrace tools read
+type latestEvent struct {
+	ID         string `json:"id"`
+	ReceivedAt string `json:"received_at"`
+	Severity   string `json:"severity"`
+	Unhandled  bool   `json:"unhandled"`
+	Context    string `json:"context"`
+	App        struct {
+		ReleaseStage string `json:"releaseStage"`
+		Version      string `json:"version"`
+	} `json:"app"`
+	Exceptions []struct {
+		ErrorClass string `json:"error_class"`
+		Message    string `json:"message"`
+		Stacktrace []struct {
+			File         string `json:"file"`
+			LineNumber   int    `json:"line_number"`
+			ColumnNumber int    `json:"column_number"`
+			Method       string `json:"method"`
+			InProject    bool   `json:"in_project"`
+		} `json:"stacktrace"`
+	} `json:"exceptions"`
+}
+
+// exceptions flattens the event's exception chain into frames that map onto
+// project files
+func (e *latestEvent) exceptions(inProjectOnly bool) []ExceptionInfo {
+	exceptions := make([]ExceptionInfo, 0, len(e.Exceptions))
+	for _, exc := range e.Exceptions {
+		info := ExceptionInfo{
+			ErrorClass: exc.ErrorClass,
+			Message:    exc.Message,
+			Frames:     []StackFrame{},
+		}
+		for _, frame := range exc.Stacktrace {
+			if inProjectOnly && !frame.InProject {
+				continue
+			}
+			info.Frames = append(info.Frames, StackFrame{
+				File:      frame.File,
+				Line:      frame.LineNumber,
+				Column:    frame.ColumnNumber,
+				Method:    frame.Method,
+				InProject: frame.InProject,
+			})
+		}
+		exceptions = append(exceptions, info)
+	}
+	return exceptions
+}
+
+// summary keeps the event's context and up to maxSummaryFrames in-project
+// frames per exception. An exception without in-project frames keeps its top
+// frames instead, so the summary still shows where it was thrown.
+func (e *latestEvent) summary() StacktraceSummary {
+	all := e.exceptions(false)
+	inProject := e.exceptions(true)
+
+	exceptions := make([]ExceptionInfo, 0, len(all))
+	for i, info := range inProject {
+		if len(info.Frames) == 0 {
+			info.Frames = all[i].Frames
+		}
+		omitted := len(all[i].Frames) - len(info.Frames)
+		if len(info.Frames) > maxSummaryFrames {
+			omitted += len(info.Frames) - maxSummaryFrames
+			info.Frames = info.Frames[:maxSummaryFrames]
+		}
+		info.OmittedFrames = omitted
+
+		if message := []rune(info.Message); len(message) > maxSummaryMessage {
+			info.Message = string(message[:maxSummaryMessage]) + "..."
+		}
+		exceptions = append(exceptions, info)
 	}
 
-	url := fmt.Sprintf("https://api.bugsnag.com/projects/%s/errors", projectID)
+	return StacktraceSummary{
+		EventID:      e.ID,
+		ReceivedAt:   e.ReceivedAt,
+		Severity:     e.Severity,
+		Unhandled:    e.Unhandled,
+		Context:      e.Context,
+		ReleaseStage: e.App.ReleaseStage,
+		AppVersion:   e.App.Version,
+		Exceptions:   exceptions,
+	}
+}
 
-	req, err := http.NewRequest("GET", url, nil)
+// fetchLatestEvent fetches the newest event of an error
+func (s *BugsnagMCPServer) fetchLatestEvent(ctx context.Context, projectID, errorID string) (*latestEvent, error) {
+	url := fmt.Sprintf("https://%s/projects/%s/errors/%s/latest_event", s.config.Site, projectID, errorID)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -248,8 +690,7 @@ func (s *BugsnagMCPServer) listErrors(args map[string]interface{}) (interface{},
 	req.Header.Set("Authorization", "Bearer "+s.accessToken)
 	req.Header.Set("X-Version", "2")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := s.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -260,25 +701,57 @@ func (s *BugsnagMCPServer) listErrors(args map[string]interface{}) (interface{},
 		return nil, fmt.Errorf("Bugsnag API error: %s - %s", resp.Status, string(bodyBytes))
 	}
 
-	var result interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	var event latestEvent
+	if err := json.NewDecoder(resp.Body).Decode(&event); err != nil {
 		return nil, err
 	}
+	return &event, nil
+}
 
-	return result, nil
+func (s *BugsnagMCPServer) getStacktrace(ctx context.Context, args stacktraceArgs) (interface{}, error) {
+	projectID := s.projectID(args.ProjectID)
+	errorID := args.ErrorID
+
+	if projectID == "" || errorID == "" {
+		return nil, mcpserver.InvalidParams("project_id and error_id are required")
+	}
+
+	event, err := s.fetchLatestEvent(ctx, projectID, errorID)
+	if err != nil {
+		return nil, err
+	}
+
+	return map[string]interface{}{
+		"eventId":    event.ID,
+		"exceptions": event.exceptions(args.InProjectOnly),
+	}, nil
+}
+
+func (s *BugsnagMCPServer) getLatestEventStacktrace(ctx context.Context, args errorArgs) (interface{}, error) {
+	projectID := s.projectID(args.ProjectID)
+	if projectID == "" || args.ErrorID == "" {
+		return nil, mcpserver.InvalidParams("project_id and error_id are required")
+	}
+
+	event, err := s.fetchLatestEvent(ctx, projectID, args.ErrorID)
+	if err != nil {
+		return nil, err
+	}
+
+	return event.summary(), nil
 }
 
-func (s *BugsnagMCPServer) getError(args map[string]interface{}) (interface{}, error) {
-	projectID, _ := args["project_id"].(string)
-	errorID, _ := args["error_id"].(string)
+func (s *BugsnagMCPServer) listEvents(ctx context.Context, args errorArgs) (interface{}, error) {
+	projectID := s.projectID(args.ProjectID)
+	errorID := args.ErrorID
 
 	if projectID == "" || errorID == "" {
-		return nil, fmt.Errorf("project_id and error_id are required")
+		return nil, mcpserver.InvalidParams("project_id and error_id are required")
 	}
 
-	url := fmt.Sprintf("https://api.bugsnag.com/projects/%s/errors/%s", projectID, errorID)
+	url := fmt.Sprintf("https://%s/projects/%s/errors/%s/events?per_page=%d", s.config.Site, projectID, errorID, s.config.MaxResults)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -286,8 +759,7 @@ func (s *BugsnagMCPServer) getError(args map[string]interface{}) (interface{}, e
 	req.Header.Set("Authorization", "Bearer "+s.accessToken)
 	req.Header.Set("X-Version", "2")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := s.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -306,35 +778,97 @@ func (s *BugsnagMCPServer) getError(args map[string]interface{}) (interface{}, e
 	return result, nil
 }
 
-func (s *BugsnagMCPServer) listEvents(args map[string]interface{}) (interface{}, error) {
-	projectID, _ := args["project_id"].(string)
-	errorID, _ := args["error_id"].(string)
+func (s *BugsnagMCPServer) updateError(ctx context.Context, args updateErrorArgs) (interface{}, error) {
+	projectID := s.projectID(args.ProjectID)
+	if projectID == "" || args.ErrorID == "" {
+		return nil, mcpserver.InvalidParams("project_id and error_id are required")
+	}
 
-	if projectID == "" || errorID == "" {
-		return nil, fmt.Errorf("project_id and error_id are required")
+	operation, ok := errorOperations[args.Status]
+	if !ok {
+		return nil, mcpserver.InvalidParams("status must be open, fixed, ignored, or snoozed, got %q", args.Status)
+	}
+	body := map[string]interface{}{"operation": operation}
+
+	if args.Status == "snoozed" {
+		switch {
+		case args.SnoozeHours > 0 && args.SnoozeOccurrences > 0:
+			return nil, mcpserver.InvalidParams("set only one of snooze_hours and snooze_occurrences")
+		case args.SnoozeHours > 0:
+			body["reopen_rules"] = map[string]interface{}{
+				"reopen_if": "occurs_after",
+				"seconds":   args.SnoozeHours * 3600,
+			}
+		case args.SnoozeOccurrences > 0:
+			body["reopen_rules"] = map[string]interface{}{
+				"reopen_if":              "n_additional_occurrences",
+				"additional_occurrences": args.SnoozeOccurrences,
+			}
+		default:
+			return nil, mcpserver.InvalidParams("snoozed requires snooze_hours or snooze_occurrences")
+		}
+	}
+
+	url := fmt.Sprintf("https://%s/projects/%s/errors/%s", s.config.Site, projectID, args.ErrorID)
+	return s.send(ctx, "PATCH", url, body)
+}
+
+func (s *BugsnagMCPServer) addComment(ctx context.Context, args commentArgs) (interface{}, error) {
+	projectID := s.projectID(args.ProjectID)
+	if projectID == "" || args.ErrorID == "" || args.Message == "" {
+		return nil, mcpserver.InvalidParams("project_id, error_id, and message are required")
+	}
+
+	url := fmt.Sprintf("https://%s/projects/%s/errors/%s/comments", s.config.Site, projectID, args.ErrorID)
+	return s.send(ctx, "POST", url, map[string]interface{}{"message": args.Message})
+}
+
+func (s *BugsnagMCPServer) listComments(ctx context.Context, args errorArgs) (interface{}, error) {
+	projectID := s.projectID(args.ProjectID)
+	if projectID == "" || args.ErrorID == "" {
+		return nil, mcpserver.InvalidParams("project_id and error_id are required")
 	}
 
-	url := fmt.Sprintf("https://api.bugsnag.com/projects/%s/errors/%s/events", projectID, errorID)
+	url := fmt.Sprintf("https://%s/projects/%s/errors/%s/comments?per_page=%d", s.config.Site, projectID, args.ErrorID, s.config.MaxResults)
+	return s.send(ctx, "GET", url, nil)
+}
 
-	req, err := http.NewRequest("GET", url, nil)
+// send makes an API request with an optional JSON body and decodes the
+// response. Any 2xx status is a success, as writes answer 201 or 204.
+func (s *BugsnagMCPServer) send(ctx context.Context, method, url string, body interface{}) (interface{}, error) {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+s.accessToken)
 	req.Header.Set("X-Version", "2")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := s.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		return nil, fmt.Errorf("Bugsnag API error: %s - %s", resp.Status, string(bodyBytes))
 	}
+	if resp.StatusCode == http.StatusNoContent {
+		return map[string]interface{}{"success": true}, nil
+	}
 
 	var result interface{}
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
@@ -344,12 +878,35 @@ func (s *BugsnagMCPServer) listEvents(args map[string]interface{}) (interface{},
 	return result, nil
 }
 
-func (s *BugsnagMCPServer) errorResponse(message string) map[string]interface{} {
-	return map[string]interface{}{
-		"jsonrpc": "2.0",
-		"error": map[string]interface{}{
-			"code":    -32603,
-			"message": message,
-		},
+// projectID returns the project_id argument, falling back to the configured default project
+func (s *BugsnagMCPServer) projectID(projectID string) string {
+	if projectID != "" {
+		return projectID
 	}
+	return s.config.DefaultProject
+}
+
+func (s *BugsnagMCPServer) serverInfo() (interface{}, error) {
+	info := map[string]interface{}{
+		"name":             "bugsnag-okta-mcp",
+		"version":          serverVersion,
+		"protocolVersions": mcpserver.SupportedProtocolVersions,
+		"credentials": map[string]interface{}{
+			"oktaAccessToken": s.accessToken != "",
+		},
+		"apiHost":        s.config.Site,
+		"org":            s.config.Org,
+		"defaultProject": s.config.DefaultProject,
+		"cacheTtl":       s.config.CacheTTL.String(),
+		"maxConcurrency": s.config.MaxConcurrency,
+		"requestTimeout": s.config.RequestTimeout.String(),
+		"maxResults":     s.config.MaxResults,
+		"httpTimeout":    s.config.HTTPTimeout.String(),
+		"maxRetries":     s.config.MaxRetries,
+		"rateLimit":      s.config.RateLimit,
+		"maxResultSize":  s.config.MaxResultSize,
+	}
+
+	mcpserver.AddBuildInfo(info)
+	return info, nil
 }
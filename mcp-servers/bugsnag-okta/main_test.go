@@ -0,0 +1,307 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"boatman/mcp-servers/internal/framework"
+	"boatman/mcp-servers/internal/mcpserver"
+)
+
+func TestErrorFiltersQuery(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters errorFilters
+		want    string
+		wantErr bool
+	}{
+		{
+			name:    "none",
+			filters: errorFilters{},
+			want:    "",
+		},
+		{
+			name:    "release stage and severity",
+			filters: errorFilters{ReleaseStage: "production", Severity: "error"},
+			want:    "filters[app.release_stage][][type]=eq&filters[app.release_stage][][value]=production&filters[event.severity][][type]=eq&filters[event.severity][][value]=error",
+		},
+		{
+			name:    "since and search",
+			filters: errorFilters{Since: "7d", Search: "NoMethodError"},
+			want:    "filters[event.since][][type]=eq&filters[event.since][][value]=7d&filters[search][][type]=eq&filters[search][][value]=NoMethodError",
+		},
+		{
+			name:    "unknown severity",
+			filters: errorFilters{Severity: "fatal"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := tt.filters.query()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("query failed: %v", err)
+			}
+			if got, _ := url.QueryUnescape(query.Encode()); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNextPageURL(t *testing.T) {
+	tests := []struct {
+		link string
+		want string
+	}{
+		{"", ""},
+		{`<https://api.bugsnag.com/projects/1/errors?offset=30&per_page=30>; rel="next"`, "https://api.bugsnag.com/projects/1/errors?offset=30&per_page=30"},
+		{`<https://api.bugsnag.com/a?page=1>; rel="prev", <https://api.bugsnag.com/a?page=3>; rel="next"`, "https://api.bugsnag.com/a?page=3"},
+		{`<https://api.bugsnag.com/a?page=1>; rel="prev"`, ""},
+		{`https://api.bugsnag.com/a; rel="next"`, ""},
+	}
+
+	for _, tt := range tests {
+		if got := nextPageURL(tt.link); got != tt.want {
+			t.Errorf("nextPageURL(%q) = %q, want %q", tt.link, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateError_InvalidParams(t *testing.T) {
+	s := &BugsnagMCPServer{config: &framework.Config{Site: "api.bugsnag.com", DefaultProject: "p1"}}
+	tests := []struct {
+		name string
+		args updateErrorArgs
+	}{
+		{"missing error", updateErrorArgs{Status: "fixed"}},
+		{"unknown status", updateErrorArgs{errorArgs: errorArgs{ErrorID: "e1"}, Status: "resolved"}},
+		{"snooze without a rule", updateErrorArgs{errorArgs: errorArgs{ErrorID: "e1"}, Status: "snoozed"}},
+		{"snooze with both rules", updateErrorArgs{errorArgs: errorArgs{ErrorID: "e1"}, Status: "snoozed", SnoozeHours: 1, SnoozeOccurrences: 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.updateError(context.Background(), tt.args)
+			var mcpErr *mcpserver.Error
+			if !errors.As(err, &mcpErr) || mcpErr.Code != mcpserver.CodeInvalidParams {
+				t.Errorf("Expected an invalid params error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLatestEventSummary(t *testing.T) {
+	frames := `{"file":"vendor/gems/rack.rb","line_number":1,"method":"call","in_project":false}`
+	for i := 0; i < maxSummaryFrames+2; i++ {
+		frames += fmt.Sprintf(`,{"file":"app/models/user.rb","line_number":%d,"method":"save","in_project":true}`, i)
+	}
+	data := `{
+		"id": "ev1",
+		"severity": "error",
+		"unhandled": true,
+		"app": {"releaseStage": "production", "version": "1.2.3"},
+		"exceptions": [
+			{"error_class": "NoMethodError", "message": "` + strings.Repeat("x", maxSummaryMessage+10) + `", "stacktrace": [` + frames + `]},
+			{"error_class": "Net::ReadTimeout", "message": "timeout", "stacktrace": [{"file": "lib/net.rb", "line_number": 9, "method": "read"}]}
+		]
+	}`
+
+	var event latestEvent
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		t.Fatal(err)
+	}
+	summary := event.summary()
+
+	if summary.EventID != "ev1" || summary.ReleaseStage != "production" || summary.AppVersion != "1.2.3" || !summary.Unhandled {
+		t.Errorf("Expected the event context to be kept, got %+v", summary)
+	}
+	if len(summary.Exceptions) != 2 {
+		t.Fatalf("Expected both exceptions, got %d", len(summary.Exceptions))
+	}
+
+	first := summary.Exceptions[0]
+	if len(first.Frames) != maxSummaryFrames || first.OmittedFrames != 3 {
+		t.Errorf("Expected %d frames with 3 omitted, got %d with %d omitted", maxSummaryFrames, len(first.Frames), first.OmittedFrames)
+	}
+	for _, frame := range first.Frames {
+		if !frame.InProject {
+			t.Errorf("Expected only in-project frames, got %+v", frame)
+		}
+	}
+	if len(first.Message) != maxSummaryMessage+3 {
+		t.Errorf("Expected the message to be truncated, got %d characters", len(first.Message))
+	}
+
+	// Without in-project frames the top frames are kept
+	if second := summary.Exceptions[1]; len(second.Frames) != 1 || second.Frames[0].File != "lib/net.rb" {
+		t.Errorf("Expected the top frame to be kept, got %+v", second.Frames)
+	}
+}
+
+// serveAPI sends the requests the server makes to handler instead of
+// Bugsnag, returning the requests it received
+func serveAPI(t *testing.T, handler http.HandlerFunc) *[]*http.Request {
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	target, _ := url.Parse(server.URL)
+	transport := http.DefaultTransport
+	http.DefaultTransport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		req = req.Clone(req.Context())
+		req.URL.Scheme = target.Scheme
+		req.URL.Host = target.Host
+		return transport.RoundTrip(req)
+	})
+	t.Cleanup(func() { http.DefaultTransport = transport })
+	return &requests
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newTestServer() *BugsnagMCPServer {
+	return &BugsnagMCPServer{
+		accessToken: "token",
+		config:      &framework.Config{Site: "api.bugsnag.com", DefaultProject: "p1"},
+		client:      framework.NewHTTPClient(&framework.Config{HTTPTimeout: 5 * time.Second}),
+	}
+}
+
+func TestGetStacktrace(t *testing.T) {
+	requests := serveAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{
+			"id": "ev1",
+			"exceptions": [
+				{"error_class": "NoMethodError", "message": "undefined method 'name' for nil", "stacktrace": [
+					{"file": "app/models/user.rb", "line_number": 12, "column_number": 5, "method": "display_name", "in_project": true},
+					{"file": "vendor/gems/rack.rb", "line_number": 40, "method": "call", "in_project": false}
+				]},
+				{"error_class": "Net::ReadTimeout", "message": "timeout", "stacktrace": [
+					{"file": "lib/net.rb", "line_number": 9, "method": "read"}
+				]}
+			]
+		}`)
+	})
+	s := newTestServer()
+
+	result, err := s.getStacktrace(context.Background(), stacktraceArgs{errorArgs: errorArgs{ErrorID: "e1"}})
+	if err != nil {
+		t.Fatalf("getStacktrace failed: %v", err)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("Expected one request, got %d", len(*requests))
+	}
+	req := (*requests)[0]
+	if req.Method != "GET" || req.Host != "api.bugsnag.com" || req.URL.Path != "/projects/p1/errors/e1/latest_event" {
+		t.Errorf("Expected GET api.bugsnag.com/projects/p1/errors/e1/latest_event, got %s %s%s", req.Method, req.Host, req.URL.Path)
+	}
+	if req.Header.Get("Authorization") != "Bearer token" || req.Header.Get("X-Version") != "2" {
+		t.Errorf("Expected the access token and API version to be sent, got %v", req.Header)
+	}
+
+	stacktrace := result.(map[string]interface{})
+	if stacktrace["eventId"] != "ev1" {
+		t.Errorf("Expected event ev1, got %v", stacktrace["eventId"])
+	}
+	exceptions := stacktrace["exceptions"].([]ExceptionInfo)
+	if len(exceptions) != 2 || exceptions[0].ErrorClass != "NoMethodError" || exceptions[1].ErrorClass != "Net::ReadTimeout" {
+		t.Fatalf("Expected the exception chain in order, got %+v", exceptions)
+	}
+	want := StackFrame{File: "app/models/user.rb", Line: 12, Column: 5, Method: "display_name", InProject: true}
+	if len(exceptions[0].Frames) != 2 || exceptions[0].Frames[0] != want {
+		t.Errorf("Expected every frame starting with %+v, got %+v", want, exceptions[0].Frames)
+	}
+}
+
+func TestGetStacktrace_InProjectOnly(t *testing.T) {
+	requests := serveAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id": "ev1", "exceptions": [
+			{"error_class": "NoMethodError", "stacktrace": [
+				{"file": "vendor/gems/rack.rb", "line_number": 40, "method": "call", "in_project": false},
+				{"file": "app/models/user.rb", "line_number": 12, "method": "display_name", "in_project": true}
+			]},
+			{"error_class": "Net::ReadTimeout", "stacktrace": [
+				{"file": "lib/net.rb", "line_number": 9, "method": "read"}
+			]}
+		]}`)
+	})
+	s := newTestServer()
+
+	result, err := s.getStacktrace(context.Background(), stacktraceArgs{
+		errorArgs:     errorArgs{projectArgs: projectArgs{ProjectID: "p2"}, ErrorID: "e1"},
+		InProjectOnly: true,
+	})
+	if err != nil {
+		t.Fatalf("getStacktrace failed: %v", err)
+	}
+	if path := (*requests)[0].URL.Path; path != "/projects/p2/errors/e1/latest_event" {
+		t.Errorf("Expected the project argument over the default, got %s", path)
+	}
+
+	exceptions := result.(map[string]interface{})["exceptions"].([]ExceptionInfo)
+	if len(exceptions) != 2 {
+		t.Fatalf("Expected both exceptions, got %+v", exceptions)
+	}
+	if frames := exceptions[0].Frames; len(frames) != 1 || frames[0].File != "app/models/user.rb" {
+		t.Errorf("Expected only the in-project frame, got %+v", frames)
+	}
+	if frames := exceptions[1].Frames; frames == nil || len(frames) != 0 {
+		t.Errorf("Expected no frames, as an empty list, got %#v", frames)
+	}
+}
+
+func TestGetStacktrace_APIError(t *testing.T) {
+	serveAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"errors": ["Error not found"]}`, http.StatusNotFound)
+	})
+	s := newTestServer()
+
+	_, err := s.getStacktrace(context.Background(), stacktraceArgs{errorArgs: errorArgs{ErrorID: "missing"}})
+	if err == nil || !strings.Contains(err.Error(), "404") || !strings.Contains(err.Error(), "Error not found") {
+		t.Errorf("Expected the API error to be returned, got %v", err)
+	}
+}
+
+func TestGetStacktrace_InvalidParams(t *testing.T) {
+	requests := serveAPI(t, func(w http.ResponseWriter, r *http.Request) {})
+	s := newTestServer()
+	s.config.DefaultProject = ""
+
+	for _, args := range []stacktraceArgs{
+		{errorArgs: errorArgs{ErrorID: "e1"}},
+		{errorArgs: errorArgs{projectArgs: projectArgs{ProjectID: "p1"}}},
+	} {
+		_, err := s.getStacktrace(context.Background(), args)
+		var mcpErr *mcpserver.Error
+		if !errors.As(err, &mcpErr) || mcpErr.Code != mcpserver.CodeInvalidParams {
+			t.Errorf("Expected an invalid params error for %+v, got %v", args, err)
+		}
+	}
+	if len(*requests) != 0 {
+		t.Errorf("Expected no requests, got %d", len(*requests))
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// errorContextLines is how many lines before each error line are kept
+	errorContextLines = 5
+	// maxLogLineLength cuts off very long lines, such as minified output
+	maxLogLineLength = 500
+)
+
+// timestampPattern matches the timestamp GitHub prefixes each log line with
+var timestampPattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?Z `)
+
+// trimLog cuts a job log down to what explains a failure: each ##[error]
+// line with the lines just before it, and the last tailLines lines. Skipped
+// stretches are marked so the agent knows the log is incomplete.
+func trimLog(log string, tailLines int) string {
+	lines := strings.Split(strings.TrimRight(log, "\n"), "\n")
+	keep := make([]bool, len(lines))
+	for i, line := range lines {
+		line = timestampPattern.ReplaceAllString(line, "")
+		if len(line) > maxLogLineLength {
+			line = strings.ToValidUTF8(line[:maxLogLineLength], "") + "..."
+		}
+		lines[i] = line
+
+		if strings.Contains(line, "##[error]") {
+			for j := max(0, i-errorContextLines); j <= i; j++ {
+				keep[j] = true
+			}
+		}
+	}
+	for i := max(0, len(lines)-tailLines); i < len(lines); i++ {
+		keep[i] = true
+	}
+
+	var out strings.Builder
+	skipped := 0
+	for i, line := range lines {
+		if !keep[i] {
+			skipped++
+			continue
+		}
+		if skipped > 0 {
+			fmt.Fprintf(&out, "... (%d lines skipped)\n", skipped)
+			skipped = 0
+		}
+		out.WriteString(line)
+		out.WriteString("\n")
+	}
+	return strings.TrimRight(out.String(), "\n")
+}
@@ -0,0 +1,469 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"boatman/mcp-servers/internal/framework"
+	"boatman/mcp-servers/internal/mcpserver"
+)
+
+// serverVersion is the version reported in initialize and server_info
+const serverVersion = "1.0.0"
+
+const (
+	// maxPageSize is the largest page the GitHub API returns
+	maxPageSize = 100
+	// defaultTailLines is how many lines from the end of a failed job's log
+	// are kept when the caller doesn't say
+	defaultTailLines = 80
+)
+
+// CIMCPServer implements MCP protocol for GitHub Actions
+type CIMCPServer struct {
+	token  string
+	config *framework.Config
+	client *framework.HTTPClient
+}
+
+func main() {
+	cfg, err := framework.Load(os.Args[1:], framework.Options{
+		Name: "ci",
+		Env: map[string]string{
+			framework.KeyConfig:         "CI_MCP_CONFIG",
+			framework.KeySite:           "GITHUB_API_HOST",
+			framework.KeyOrg:            "CI_MCP_OWNER",
+			framework.KeyDefaultProject: "CI_MCP_REPO",
+			framework.KeyMaxConcurrency: "CI_MCP_MAX_CONCURRENCY",
+			framework.KeyRequestTimeout: "CI_MCP_REQUEST_TIMEOUT",
+			framework.KeyMaxResults:     "CI_MCP_MAX_RESULTS",
+			framework.KeyLogFile:        "CI_MCP_LOG_FILE",
+			framework.KeyLogLevel:       "CI_MCP_LOG_LEVEL",
+			framework.KeyHTTPTimeout:    "CI_MCP_HTTP_TIMEOUT",
+			framework.KeyMaxRetries:     "CI_MCP_MAX_RETRIES",
+			framework.KeyRateLimit:      "CI_MCP_RATE_LIMIT",
+			framework.KeyMaxResultSize:  "CI_MCP_MAX_RESULT_SIZE",
+		},
+		Defaults: framework.Config{
+			Site:           "api.github.com",
+			MaxConcurrency: 4,
+			RequestTimeout: 2 * time.Minute,
+			MaxResults:     20,
+			LogLevel:       "info",
+			HTTPTimeout:    30 * time.Second,
+			MaxRetries:     3,
+			RateLimit:      10,
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// stdout carries only JSON-RPC frames; everything else goes to the log
+	logger, protocolOut, err := mcpserver.SetupLogging(cfg.LogFile, cfg.LogLevel)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		token = os.Getenv("GH_TOKEN")
+	}
+	if token == "" {
+		log.Fatal("GITHUB_TOKEN or GH_TOKEN environment variable is required")
+	}
+
+	ci := &CIMCPServer{
+		token:  token,
+		config: cfg,
+		client: framework.NewHTTPClient(cfg),
+	}
+
+	server := mcpserver.New(mcpserver.Options{
+		Name:           "ci-mcp",
+		Version:        serverVersion,
+		Logger:         logger,
+		Out:            protocolOut,
+		MaxConcurrency: cfg.MaxConcurrency,
+		RequestTimeout: cfg.RequestTimeout,
+		MaxResultSize:  cfg.MaxResultSize,
+	})
+	ci.registerTools(server)
+
+	// Read MCP requests from stdin, write responses to stdout
+	if err := server.Serve(os.Stdin); err != nil {
+		os.Exit(1)
+	}
+}
+
+// repoArgs are the arguments of tools scoped to a repository
+type repoArgs struct {
+	Repo string `json:"repo"`
+}
+
+// listRunsArgs are the arguments of ci_list_runs
+type listRunsArgs struct {
+	repoArgs
+	Branch   string `json:"branch"`
+	Status   string `json:"status"`
+	Workflow string `json:"workflow"`
+}
+
+// runArgs are the arguments of tools about one workflow run
+type runArgs struct {
+	repoArgs
+	RunID int64 `json:"run_id"`
+}
+
+// failedLogsArgs are the arguments of ci_get_failed_logs
+type failedLogsArgs struct {
+	runArgs
+	TailLines int `json:"tail_lines"`
+}
+
+// repoSchema and runIDSchema describe the arguments most tools share
+var (
+	repoSchema = map[string]interface{}{
+		"type":        "string",
+		"description": "Repository as owner/name (defaults to the configured repository)",
+	}
+	runIDSchema = map[string]interface{}{
+		"type":        "integer",
+		"description": "Workflow run ID",
+	}
+)
+
+// registerTools adds the CI tools to the server. None of them are cached, as
+// runs change state while the agent watches them.
+func (s *CIMCPServer) registerTools(server *mcpserver.Server) {
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "ci_list_runs",
+		Description: "List recent GitHub Actions workflow runs, newest first, with their status and conclusion",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"repo": repoSchema,
+				"branch": map[string]interface{}{
+					"type":        "string",
+					"description": "Only runs on this branch",
+				},
+				"status": map[string]interface{}{
+					"type":        "string",
+					"description": "Only runs with this status or conclusion, e.g. failure, success, in_progress, queued",
+				},
+				"workflow": map[string]interface{}{
+					"type":        "string",
+					"description": "Only runs of this workflow, by file name (e.g. ci.yml) or ID",
+				},
+			},
+		},
+		NoCache: true,
+	}, s.listRuns)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "ci_get_failed_logs",
+		Description: "Get the logs of a run's failed jobs, trimmed to the error lines and the end of each log",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"repo":   repoSchema,
+				"run_id": runIDSchema,
+				"tail_lines": map[string]interface{}{
+					"type":        "integer",
+					"description": fmt.Sprintf("Lines kept from the end of each log (default %d)", defaultTailLines),
+				},
+			},
+			"required": []string{"run_id"},
+		},
+		NoCache: true,
+	}, s.getFailedLogs)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "ci_rerun_failed_jobs",
+		Description: "Re-run the failed jobs of a workflow run, and the jobs that depend on them",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"repo":   repoSchema,
+				"run_id": runIDSchema,
+			},
+			"required": []string{"run_id"},
+		},
+		NoCache: true,
+	}, s.rerunFailedJobs)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "server_info",
+		Description: "Report this MCP server's version, build info, and which credentials are configured",
+		NoCache:     true,
+	}, func(context.Context, struct{}) (interface{}, error) {
+		return s.serverInfo(), nil
+	})
+}
+
+// WorkflowRun is a compact view of a workflow run
+type WorkflowRun struct {
+	ID         int64  `json:"id"`
+	Workflow   string `json:"workflow"`
+	Title      string `json:"title"`
+	Branch     string `json:"branch"`
+	Commit     string `json:"commit"`
+	Event      string `json:"event"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion,omitempty"`
+	Attempt    int    `json:"attempt"`
+	CreatedAt  string `json:"createdAt"`
+	URL        string `json:"url"`
+}
+
+func (s *CIMCPServer) listRuns(ctx context.Context, args listRunsArgs) (interface{}, error) {
+	repo, err := s.repo(args.Repo)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Set("per_page", strconv.Itoa(min(s.config.MaxResults, maxPageSize)))
+	if args.Branch != "" {
+		params.Set("branch", args.Branch)
+	}
+	if args.Status != "" {
+		params.Set("status", args.Status)
+	}
+
+	path := "/repos/" + repo + "/actions/runs"
+	if args.Workflow != "" {
+		path = "/repos/" + repo + "/actions/workflows/" + url.PathEscape(args.Workflow) + "/runs"
+	}
+
+	var result struct {
+		TotalCount   int `json:"total_count"`
+		WorkflowRuns []struct {
+			ID           int64  `json:"id"`
+			Name         string `json:"name"`
+			DisplayTitle string `json:"display_title"`
+			HeadBranch   string `json:"head_branch"`
+			HeadSHA      string `json:"head_sha"`
+			Event        string `json:"event"`
+			Status       string `json:"status"`
+			Conclusion   string `json:"conclusion"`
+			RunAttempt   int    `json:"run_attempt"`
+			CreatedAt    string `json:"created_at"`
+			HTMLURL      string `json:"html_url"`
+		} `json:"workflow_runs"`
+	}
+	if err := s.getJSON(ctx, path+"?"+params.Encode(), &result); err != nil {
+		return nil, err
+	}
+
+	runs := make([]WorkflowRun, 0, len(result.WorkflowRuns))
+	for _, run := range result.WorkflowRuns {
+		commit := run.HeadSHA
+		if len(commit) > 7 {
+			commit = commit[:7]
+		}
+		runs = append(runs, WorkflowRun{
+			ID:         run.ID,
+			Workflow:   run.Name,
+			Title:      run.DisplayTitle,
+			Branch:     run.HeadBranch,
+			Commit:     commit,
+			Event:      run.Event,
+			Status:     run.Status,
+			Conclusion: run.Conclusion,
+			Attempt:    run.RunAttempt,
+			CreatedAt:  run.CreatedAt,
+			URL:        run.HTMLURL,
+		})
+	}
+
+	return map[string]interface{}{
+		"repo":       repo,
+		"totalCount": result.TotalCount,
+		"runs":       runs,
+	}, nil
+}
+
+func (s *CIMCPServer) getFailedLogs(ctx context.Context, args failedLogsArgs) (interface{}, error) {
+	repo, err := s.repo(args.Repo)
+	if err != nil {
+		return nil, err
+	}
+	if args.RunID <= 0 {
+		return nil, mcpserver.InvalidParams("run_id is required")
+	}
+	tailLines := args.TailLines
+	if tailLines <= 0 {
+		tailLines = defaultTailLines
+	}
+
+	var result struct {
+		Jobs []struct {
+			ID         int64  `json:"id"`
+			Name       string `json:"name"`
+			Conclusion string `json:"conclusion"`
+			HTMLURL    string `json:"html_url"`
+			Steps      []struct {
+				Name       string `json:"name"`
+				Conclusion string `json:"conclusion"`
+			} `json:"steps"`
+		} `json:"jobs"`
+	}
+	path := fmt.Sprintf("/repos/%s/actions/runs/%d/jobs?filter=latest&per_page=%d", repo, args.RunID, maxPageSize)
+	if err := s.getJSON(ctx, path, &result); err != nil {
+		return nil, err
+	}
+
+	var out strings.Builder
+	failed := 0
+	for _, job := range result.Jobs {
+		if job.Conclusion != "failure" && job.Conclusion != "timed_out" {
+			continue
+		}
+		failed++
+
+		var steps []string
+		for _, step := range job.Steps {
+			if step.Conclusion == "failure" || step.Conclusion == "timed_out" {
+				steps = append(steps, step.Name)
+			}
+		}
+		fmt.Fprintf(&out, "## %s (%s) %s\n", job.Name, job.Conclusion, job.HTMLURL)
+		if len(steps) > 0 {
+			fmt.Fprintf(&out, "Failed steps: %s\n", strings.Join(steps, ", "))
+		}
+
+		logText, err := s.getText(ctx, fmt.Sprintf("/repos/%s/actions/jobs/%d/logs", repo, job.ID))
+		if err != nil {
+			// Logs expire, so report the rest of the failures anyway
+			fmt.Fprintf(&out, "Logs unavailable: %v\n\n", err)
+			continue
+		}
+		out.WriteString(trimLog(logText, tailLines))
+		out.WriteString("\n\n")
+	}
+
+	if failed == 0 {
+		return fmt.Sprintf("Run %d has no failed jobs", args.RunID), nil
+	}
+	return strings.TrimRight(out.String(), "\n"), nil
+}
+
+func (s *CIMCPServer) rerunFailedJobs(ctx context.Context, args runArgs) (interface{}, error) {
+	repo, err := s.repo(args.Repo)
+	if err != nil {
+		return nil, err
+	}
+	if args.RunID <= 0 {
+		return nil, mcpserver.InvalidParams("run_id is required")
+	}
+
+	path := fmt.Sprintf("/repos/%s/actions/runs/%d/rerun-failed-jobs", repo, args.RunID)
+	resp, err := s.do(ctx, "POST", path)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	return fmt.Sprintf("Re-running the failed jobs of run %d in %s", args.RunID, repo), nil
+}
+
+// repo returns the repository argument as owner/name, falling back to the
+// configured repository and prefixing a bare name with the configured owner
+func (s *CIMCPServer) repo(repo string) (string, error) {
+	if repo == "" {
+		repo = s.config.DefaultProject
+	}
+	if repo != "" && !strings.Contains(repo, "/") && s.config.Org != "" {
+		repo = s.config.Org + "/" + repo
+	}
+
+	owner, name, ok := strings.Cut(repo, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return "", mcpserver.InvalidParams("repo must be owner/name, got %q", repo)
+	}
+	return url.PathEscape(owner) + "/" + url.PathEscape(name), nil
+}
+
+// getJSON fetches an API path and decodes the JSON response into result
+func (s *CIMCPServer) getJSON(ctx context.Context, path string, result interface{}) error {
+	resp, err := s.do(ctx, "GET", path)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+// getText fetches an API path with a plain text response, such as job logs,
+// which GitHub serves through a redirect
+func (s *CIMCPServer) getText(ctx context.Context, path string) (string, error) {
+	resp, err := s.do(ctx, "GET", path)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// do makes an API request, returning the response if it succeeded
+func (s *CIMCPServer) do(ctx context.Context, method, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, "https://"+s.config.Site+path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("GitHub API error: %s - %s", resp.Status, string(bodyBytes))
+	}
+	return resp, nil
+}
+
+func (s *CIMCPServer) serverInfo() map[string]interface{} {
+	info := map[string]interface{}{
+		"name":             "ci-mcp",
+		"version":          serverVersion,
+		"protocolVersions": mcpserver.SupportedProtocolVersions,
+		"credentials": map[string]interface{}{
+			"githubToken": s.token != "",
+		},
+		"apiHost":        s.config.Site,
+		"owner":          s.config.Org,
+		"defaultRepo":    s.config.DefaultProject,
+		"maxConcurrency": s.config.MaxConcurrency,
+		"requestTimeout": s.config.RequestTimeout.String(),
+		"maxResults":     s.config.MaxResults,
+		"httpTimeout":    s.config.HTTPTimeout.String(),
+		"maxRetries":     s.config.MaxRetries,
+		"rateLimit":      s.config.RateLimit,
+		"maxResultSize":  s.config.MaxResultSize,
+	}
+
+	mcpserver.AddBuildInfo(info)
+	return info
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"boatman/mcp-servers/internal/framework"
+	"boatman/mcp-servers/internal/mcpserver"
+)
+
+func TestTrimLog(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 30; i++ {
+		line := fmt.Sprintf("2024-03-10T12:00:%02d.1234567Z line %d", i, i)
+		if i == 10 {
+			line = "2024-03-10T12:00:10.1234567Z ##[error]Process completed with exit code 1."
+		}
+		lines = append(lines, line)
+	}
+
+	want := strings.Join([]string{
+		"... (4 lines skipped)",
+		"line 5", "line 6", "line 7", "line 8", "line 9",
+		"##[error]Process completed with exit code 1.",
+		"... (17 lines skipped)",
+		"line 28", "line 29", "line 30",
+	}, "\n")
+	if got := trimLog(strings.Join(lines, "\n")+"\n", 3); got != want {
+		t.Errorf("Unexpected trimmed log:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Short logs are kept whole
+	if got := trimLog("a\nb\n", 10); got != "a\nb" {
+		t.Errorf("Expected the whole log, got %q", got)
+	}
+}
+
+func TestRepo(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  framework.Config
+		repo    string
+		want    string
+		wantErr bool
+	}{
+		{name: "full name", repo: "acme/web", want: "acme/web"},
+		{name: "configured repo", config: framework.Config{DefaultProject: "acme/api"}, want: "acme/api"},
+		{name: "configured owner", config: framework.Config{Org: "acme"}, repo: "web", want: "acme/web"},
+		{name: "no owner", repo: "web", wantErr: true},
+		{name: "missing", wantErr: true},
+		{name: "too many parts", repo: "acme/web/extra", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &CIMCPServer{config: &tt.config}
+			got, err := s.repo(tt.repo)
+			if tt.wantErr {
+				var mcpErr *mcpserver.Error
+				if !errors.As(err, &mcpErr) || mcpErr.Code != mcpserver.CodeInvalidParams {
+					t.Errorf("Expected an invalid params error, got %q, %v", got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Expected %s, got %s (%v)", tt.want, got, err)
+			}
+		})
+	}
+}
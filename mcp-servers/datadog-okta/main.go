@@ -2,236 +2,521 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"boatman/mcp-servers/internal/framework"
+	"boatman/mcp-servers/internal/mcpserver"
 )
 
+// serverVersion is the version reported in initialize and server_info
+const serverVersion = "1.0.0"
+
 // DatadogMCPServer implements MCP protocol for Datadog with Okta OAuth
 type DatadogMCPServer struct {
 	accessToken string
 	site        string
+	config      *framework.Config
+	client      *framework.HTTPClient
 }
 
 func main() {
+	cfg, err := framework.Load(os.Args[1:], framework.Options{
+		Name: "datadog-okta",
+		Env: map[string]string{
+			framework.KeyConfig:         "DATADOG_MCP_CONFIG",
+			framework.KeySite:           "DD_SITE",
+			framework.KeyOrg:            "DD_ORG",
+			framework.KeyCacheTTL:       "DATADOG_MCP_CACHE_TTL",
+			framework.KeyMaxConcurrency: "DATADOG_MCP_MAX_CONCURRENCY",
+			framework.KeyRequestTimeout: "DATADOG_MCP_REQUEST_TIMEOUT",
+			framework.KeyMaxResults:     "DATADOG_MCP_MAX_RESULTS",
+			framework.KeyLogFile:        "DATADOG_MCP_LOG_FILE",
+			framework.KeyLogLevel:       "DATADOG_MCP_LOG_LEVEL",
+			framework.KeyHTTPTimeout:    "DATADOG_MCP_HTTP_TIMEOUT",
+			framework.KeyMaxRetries:     "DATADOG_MCP_MAX_RETRIES",
+			framework.KeyRateLimit:      "DATADOG_MCP_RATE_LIMIT",
+			framework.KeyMaxResultSize:  "DATADOG_MCP_MAX_RESULT_SIZE",
+		},
+		Defaults: framework.Config{
+			Site:           "datadoghq.com",
+			MaxConcurrency: 4,
+			RequestTimeout: 2 * time.Minute,
+			MaxResults:     50,
+			LogLevel:       "info",
+			HTTPTimeout:    30 * time.Second,
+			MaxRetries:     3,
+			RateLimit:      10,
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// stdout carries only JSON-RPC frames; everything else goes to the log
+	logger, protocolOut, err := mcpserver.SetupLogging(cfg.LogFile, cfg.LogLevel)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	accessToken := os.Getenv("OKTA_ACCESS_TOKEN")
 	if accessToken == "" {
 		log.Fatal("OKTA_ACCESS_TOKEN environment variable is required")
 	}
 
-	site := os.Getenv("DD_SITE")
-	if site == "" {
-		site = "datadoghq.com"
-	}
-
-	server := &DatadogMCPServer{
+	datadog := &DatadogMCPServer{
 		accessToken: accessToken,
-		site:        site,
+		client:      framework.NewHTTPClient(cfg),
+		site:        cfg.Site,
+		config:      cfg,
 	}
 
-	// Read MCP requests from stdin, write responses to stdout
-	decoder := json.NewDecoder(os.Stdin)
-	encoder := json.NewEncoder(os.Stdout)
-
-	for {
-		var request map[string]interface{}
-		if err := decoder.Decode(&request); err != nil {
-			if err == io.EOF {
-				break
-			}
-			log.Printf("Error decoding request: %v", err)
-			continue
-		}
+	server := mcpserver.New(mcpserver.Options{
+		Name:           "datadog-okta-mcp",
+		Version:        serverVersion,
+		Logger:         logger,
+		Out:            protocolOut,
+		MaxConcurrency: cfg.MaxConcurrency,
+		RequestTimeout: cfg.RequestTimeout,
+		Cache:          framework.NewCache(cfg.CacheTTL),
+		MaxResultSize:  cfg.MaxResultSize,
+	})
+	datadog.registerTools(server)
 
-		response := server.handleRequest(request)
-		if err := encoder.Encode(response); err != nil {
-			log.Printf("Error encoding response: %v", err)
-		}
+	// Read MCP requests from stdin, write responses to stdout
+	if err := server.Serve(os.Stdin); err != nil {
+		os.Exit(1)
 	}
 }
 
-func (s *DatadogMCPServer) handleRequest(request map[string]interface{}) map[string]interface{} {
-	method, ok := request["method"].(string)
-	if !ok {
-		return s.errorResponse("invalid method")
-	}
+// queryLogsArgs are the arguments of datadog_query_logs
+type queryLogsArgs struct {
+	Query string `json:"query"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
 
-	switch method {
-	case "initialize":
-		return s.handleInitialize(request)
-	case "tools/list":
-		return s.handleToolsList()
-	case "tools/call":
-		return s.handleToolsCall(request)
-	default:
-		return s.errorResponse(fmt.Sprintf("unknown method: %s", method))
-	}
+// aggregateLogsArgs are the arguments of datadog_aggregate_logs
+type aggregateLogsArgs struct {
+	GroupBy string  `json:"group_by"`
+	Query   string  `json:"query"`
+	From    string  `json:"from"`
+	To      string  `json:"to"`
+	Limit   float64 `json:"limit"`
 }
 
-func (s *DatadogMCPServer) handleInitialize(request map[string]interface{}) map[string]interface{} {
-	return map[string]interface{}{
-		"jsonrpc": "2.0",
-		"id":      request["id"],
-		"result": map[string]interface{}{
-			"protocolVersion": "1.0.0",
-			"serverInfo": map[string]interface{}{
-				"name":    "datadog-okta-mcp",
-				"version": "1.0.0",
+// listMonitorsArgs are the arguments of datadog_list_monitors
+type listMonitorsArgs struct {
+	Tags string `json:"tags"`
+}
+
+// getMetricsArgs are the arguments of datadog_get_metrics
+type getMetricsArgs struct {
+	Query string `json:"query"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// muteMonitorArgs are the arguments of datadog_mute_monitor and
+// datadog_unmute_monitor
+type muteMonitorArgs struct {
+	MonitorID int64  `json:"monitor_id"`
+	Scope     string `json:"scope"`
+	Duration  string `json:"duration"`
+}
+
+// listIncidentsArgs are the arguments of datadog_list_incidents
+type listIncidentsArgs struct {
+	State string `json:"state"`
+	Query string `json:"query"`
+}
+
+// incidentArgs are the arguments of datadog_get_incident
+type incidentArgs struct {
+	IncidentID string `json:"incident_id"`
+}
+
+// searchTracesArgs are the arguments of datadog_search_traces
+type searchTracesArgs struct {
+	Service     string `json:"service"`
+	Query       string `json:"query"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	MinDuration string `json:"min_duration"`
+	ErrorsOnly  bool   `json:"errors_only"`
+}
+
+// serviceSummaryArgs are the arguments of datadog_get_service_summary
+type serviceSummaryArgs struct {
+	Service string `json:"service"`
+	Env     string `json:"env"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+}
+
+// monitorIDSchema and scopeSchema describe the monitor muting arguments
+var (
+	monitorIDSchema = map[string]interface{}{
+		"type":        "integer",
+		"description": "Monitor ID",
+	}
+	scopeSchema = map[string]interface{}{
+		"type":        "string",
+		"description": "Scope to (un)mute, e.g. 'host:web-1' (default: the whole monitor)",
+	}
+)
+
+// registerTools adds the Datadog tools to the server. Read-only tools are
+// cached, so identical calls share a result; tools that change a monitor and
+// server_info are not.
+func (s *DatadogMCPServer) registerTools(server *mcpserver.Server) {
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "datadog_query_logs",
+		Description: "Query Datadog logs with a search query",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "Log search query",
+				},
+				"from": map[string]interface{}{
+					"type":        "string",
+					"description": "Start time (ISO 8601 or relative like '15m')",
+				},
+				"to": map[string]interface{}{
+					"type":        "string",
+					"description": "End time (ISO 8601 or 'now')",
+				},
 			},
-			"capabilities": map[string]interface{}{
-				"tools": map[string]interface{}{},
+			"required": []string{"query"},
+		},
+	}, s.queryLogs)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "datadog_aggregate_logs",
+		Description: "Count Datadog logs grouped by service, status, or a custom facet over a time range",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"group_by": map[string]interface{}{
+					"type":        "string",
+					"description": "Facet to group by (e.g., 'service', 'status', '@http.status_code')",
+				},
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "Log search query to filter on (default '*')",
+				},
+				"from": map[string]interface{}{
+					"type":        "string",
+					"description": "Start time (ISO 8601 or relative like 'now-1h', default 'now-15m')",
+				},
+				"to": map[string]interface{}{
+					"type":        "string",
+					"description": "End time (ISO 8601 or 'now', default 'now')",
+				},
+				"limit": map[string]interface{}{
+					"type":        "number",
+					"description": "Maximum number of groups to return (default 10)",
+				},
 			},
+			"required": []string{"group_by"},
 		},
-	}
-}
-
-func (s *DatadogMCPServer) handleToolsList() map[string]interface{} {
-	tools := []map[string]interface{}{
-		{
-			"name":        "datadog_query_logs",
-			"description": "Query Datadog logs with a search query",
-			"inputSchema": map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"query": map[string]interface{}{
-						"type":        "string",
-						"description": "Log search query",
-					},
-					"from": map[string]interface{}{
-						"type":        "string",
-						"description": "Start time (ISO 8601 or relative like '15m')",
-					},
-					"to": map[string]interface{}{
-						"type":        "string",
-						"description": "End time (ISO 8601 or 'now')",
-					},
+	}, s.aggregateLogs)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "datadog_list_monitors",
+		Description: "List Datadog monitors and their status",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"tags": map[string]interface{}{
+					"type":        "string",
+					"description": "Filter by tags (comma-separated)",
 				},
-				"required": []string{"query"},
 			},
 		},
-		{
-			"name":        "datadog_list_monitors",
-			"description": "List Datadog monitors and their status",
-			"inputSchema": map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"tags": map[string]interface{}{
-						"type":        "string",
-						"description": "Filter by tags (comma-separated)",
-					},
+	}, s.listMonitors)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "datadog_get_metrics",
+		Description: "Query Datadog metrics",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "Metrics query (e.g., 'avg:system.cpu.user{*}')",
+				},
+				"from": map[string]interface{}{
+					"type":        "string",
+					"description": "Start time: relative like '15m' or 'now-1h', a unix timestamp, or ISO 8601 (default 'now-1h')",
+				},
+				"to": map[string]interface{}{
+					"type":        "string",
+					"description": "End time: 'now', relative, a unix timestamp, or ISO 8601 (default 'now')",
 				},
 			},
+			"required": []string{"query"},
 		},
-		{
-			"name":        "datadog_get_metrics",
-			"description": "Query Datadog metrics",
-			"inputSchema": map[string]interface{}{
-				"type": "object",
-				"properties": map[string]interface{}{
-					"query": map[string]interface{}{
-						"type":        "string",
-						"description": "Metrics query (e.g., 'avg:system.cpu.user{*}')",
-					},
-					"from": map[string]interface{}{
-						"type":        "string",
-						"description": "Start time (unix timestamp or relative)",
-					},
-					"to": map[string]interface{}{
-						"type":        "string",
-						"description": "End time (unix timestamp or 'now')",
-					},
+	}, s.getMetrics)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "datadog_mute_monitor",
+		Description: "Mute a monitor, or one scope of it, so it stops notifying",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"monitor_id": monitorIDSchema,
+				"scope":      scopeSchema,
+				"duration": map[string]interface{}{
+					"type":        "string",
+					"description": "How long to mute for, e.g. '30m' or '4h' (default: until unmuted)",
 				},
-				"required": []string{"query", "from", "to"},
 			},
+			"required": []string{"monitor_id"},
 		},
-	}
-
-	return map[string]interface{}{
-		"jsonrpc": "2.0",
-		"result": map[string]interface{}{
-			"tools": tools,
+		NoCache: true,
+	}, s.muteMonitor)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "datadog_unmute_monitor",
+		Description: "Unmute a monitor, or one scope of it",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"monitor_id": monitorIDSchema,
+				"scope":      scopeSchema,
+			},
+			"required": []string{"monitor_id"},
 		},
-	}
+		NoCache: true,
+	}, s.unmuteMonitor)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "datadog_list_incidents",
+		Description: "List Datadog incidents, optionally filtered by state or a search query",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"state": map[string]interface{}{
+					"type": "string",
+					"enum": []string{"active", "stable", "resolved"},
+				},
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "Incident search query, e.g. 'severity:SEV-1'",
+				},
+			},
+		},
+	}, s.listIncidents)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "datadog_get_incident",
+		Description: "Get an incident's details: state, severity, commander, timeline fields, and customer impact",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"incident_id": map[string]interface{}{
+					"type":        "string",
+					"description": "Incident ID (UUID or public ID)",
+				},
+			},
+			"required": []string{"incident_id"},
+		},
+	}, s.getIncident)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "datadog_search_traces",
+		Description: "Search APM spans, slowest first, returning a compact list of trace IDs, resources, durations, and statuses",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"service": map[string]interface{}{
+					"type":        "string",
+					"description": "Only spans of this service",
+				},
+				"query": map[string]interface{}{
+					"type":        "string",
+					"description": "Span search query, e.g. 'resource_name:\"GET /users\" env:production'",
+				},
+				"from": map[string]interface{}{
+					"type":        "string",
+					"description": "Start time (ISO 8601 or relative like 'now-1h', default 'now-15m')",
+				},
+				"to": map[string]interface{}{
+					"type":        "string",
+					"description": "End time (ISO 8601 or 'now', default 'now')",
+				},
+				"min_duration": map[string]interface{}{
+					"type":        "string",
+					"description": "Only spans at least this slow, e.g. '500ms' or '2s'",
+				},
+				"errors_only": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Only spans with an error status",
+				},
+			},
+		},
+	}, s.searchTraces)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "datadog_get_service_summary",
+		Description: "Summarize a service's APM traffic per resource: request count, error rate, and average and p95 latency",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"service": map[string]interface{}{
+					"type":        "string",
+					"description": "Service name",
+				},
+				"env": map[string]interface{}{
+					"type":        "string",
+					"description": "Environment, e.g. production (default: all)",
+				},
+				"from": map[string]interface{}{
+					"type":        "string",
+					"description": "Start time (ISO 8601 or relative like 'now-1h', default 'now-1h')",
+				},
+				"to": map[string]interface{}{
+					"type":        "string",
+					"description": "End time (ISO 8601 or 'now', default 'now')",
+				},
+			},
+			"required": []string{"service"},
+		},
+	}, s.getServiceSummary)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "server_info",
+		Description: "Report this MCP server's version, build info, and which credentials are configured",
+		NoCache:     true,
+	}, func(context.Context, struct{}) (interface{}, error) {
+		return s.serverInfo()
+	})
 }
 
-func (s *DatadogMCPServer) handleToolsCall(request map[string]interface{}) map[string]interface{} {
-	params, ok := request["params"].(map[string]interface{})
-	if !ok {
-		return s.errorResponse("invalid params")
+func (s *DatadogMCPServer) queryLogs(ctx context.Context, args queryLogsArgs) (interface{}, error) {
+	query := args.Query
+	if query == "" {
+		return nil, mcpserver.InvalidParams("query is required")
 	}
 
-	name, ok := params["name"].(string)
-	if !ok {
-		return s.errorResponse("missing tool name")
-	}
+	url := fmt.Sprintf("https://api.%s/api/v2/logs/events/search", s.site)
 
-	arguments, _ := params["arguments"].(map[string]interface{})
+	// Build request body, leaving Datadog's defaults for an unset time range
+	filter := map[string]interface{}{"query": query}
+	if args.From != "" {
+		filter["from"] = args.From
+	}
+	if args.To != "" {
+		filter["to"] = args.To
+	}
+	body := map[string]interface{}{
+		"filter": filter,
+		"page": map[string]interface{}{
+			"limit": s.config.MaxResults,
+		},
+	}
 
-	var result interface{}
-	var err error
-
-	switch name {
-	case "datadog_query_logs":
-		result, err = s.queryLogs(arguments)
-	case "datadog_list_monitors":
-		result, err = s.listMonitors(arguments)
-	case "datadog_get_metrics":
-		result, err = s.getMetrics(arguments)
-	default:
-		return s.errorResponse(fmt.Sprintf("unknown tool: %s", name))
+	// A search, so it is safe to retry despite being a POST
+	bodyBytes, _ := json.Marshal(body)
+	req, err := http.NewRequestWithContext(framework.Idempotent(ctx), "POST", url, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, err
 	}
 
+	req.Header.Set("Authorization", "Bearer "+s.accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
 	if err != nil {
-		return s.errorResponse(err.Error())
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	return map[string]interface{}{
-		"jsonrpc": "2.0",
-		"id":      request["id"],
-		"result": map[string]interface{}{
-			"content": []map[string]interface{}{
-				{
-					"type": "text",
-					"text": fmt.Sprintf("%v", result),
-				},
-			},
-		},
+	if resp.StatusCode != http.StatusOK {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("Datadog API error: %s - %s", resp.Status, string(bodyBytes))
 	}
-}
 
-func (s *DatadogMCPServer) queryLogs(args map[string]interface{}) (interface{}, error) {
-	query, _ := args["query"].(string)
-	if query == "" {
-		return nil, fmt.Errorf("query is required")
+	var result map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
 	}
 
-	url := fmt.Sprintf("https://api.%s/api/v2/logs/events/search", s.site)
+	return result, nil
+}
 
-	req, err := http.NewRequest("POST", url, nil)
-	if err != nil {
-		return nil, err
+func (s *DatadogMCPServer) aggregateLogs(ctx context.Context, args aggregateLogsArgs) (interface{}, error) {
+	groupBy := args.GroupBy
+	if groupBy == "" {
+		return nil, mcpserver.InvalidParams("group_by is required")
 	}
 
-	req.Header.Set("Authorization", "Bearer "+s.accessToken)
-	req.Header.Set("Content-Type", "application/json")
+	query := args.Query
+	if query == "" {
+		query = "*"
+	}
+	from := args.From
+	if from == "" {
+		from = "now-15m"
+	}
+	to := args.To
+	if to == "" {
+		to = "now"
+	}
+	limit := 10
+	if args.Limit > 0 {
+		limit = int(args.Limit)
+	}
+	if limit > s.config.MaxResults {
+		limit = s.config.MaxResults
+	}
+
+	url := fmt.Sprintf("https://api.%s/api/v2/logs/analytics/aggregate", s.site)
 
-	// Build request body
+	// Count matching logs per facet value, largest groups first
 	body := map[string]interface{}{
+		"compute": []map[string]interface{}{
+			{"aggregation": "count", "type": "total"},
+		},
 		"filter": map[string]interface{}{
 			"query": query,
-			"from":  args["from"],
-			"to":    args["to"],
+			"from":  from,
+			"to":    to,
+		},
+		"group_by": []map[string]interface{}{
+			{
+				"facet": groupBy,
+				"limit": limit,
+				"sort": map[string]interface{}{
+					"aggregation": "count",
+					"order":       "desc",
+				},
+			},
 		},
 	}
 
 	bodyBytes, _ := json.Marshal(body)
-	req.Body = io.NopCloser(bytes.NewReader(bodyBytes))
+	req, err := http.NewRequestWithContext(framework.Idempotent(ctx), "POST", url, bytes.NewReader(bodyBytes))
+	if err != nil {
+		return nil, err
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	req.Header.Set("Authorization", "Bearer "+s.accessToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -242,26 +527,45 @@ func (s *DatadogMCPServer) queryLogs(args map[string]interface{}) (interface{},
 		return nil, fmt.Errorf("Datadog API error: %s - %s", resp.Status, string(bodyBytes))
 	}
 
-	var result map[string]interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	var result struct {
+		Data struct {
+			Buckets []struct {
+				By       map[string]interface{} `json:"by"`
+				Computes map[string]interface{} `json:"computes"`
+			} `json:"buckets"`
+		} `json:"data"`
+	}
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&result); err != nil {
 		return nil, err
 	}
 
-	return result, nil
+	// Return a compact "value: count" breakdown rather than the raw buckets
+	var out strings.Builder
+	fmt.Fprintf(&out, "Log counts for %q grouped by %s (%s to %s):\n", query, groupBy, from, to)
+	if len(result.Data.Buckets) == 0 {
+		out.WriteString("No matching logs")
+		return out.String(), nil
+	}
+	for _, bucket := range result.Data.Buckets {
+		fmt.Fprintf(&out, "%v: %v\n", bucket.By[groupBy], bucket.Computes["c0"])
+	}
+
+	return strings.TrimRight(out.String(), "\n"), nil
 }
 
-func (s *DatadogMCPServer) listMonitors(args map[string]interface{}) (interface{}, error) {
+func (s *DatadogMCPServer) listMonitors(ctx context.Context, args listMonitorsArgs) (interface{}, error) {
 	url := fmt.Sprintf("https://api.%s/api/v1/monitor", s.site)
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	req.Header.Set("Authorization", "Bearer "+s.accessToken)
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := s.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -280,50 +584,396 @@ func (s *DatadogMCPServer) listMonitors(args map[string]interface{}) (interface{
 	return result, nil
 }
 
-func (s *DatadogMCPServer) getMetrics(args map[string]interface{}) (interface{}, error) {
-	query, _ := args["query"].(string)
-	from, _ := args["from"].(string)
-	to, _ := args["to"].(string)
-
-	if query == "" || from == "" || to == "" {
-		return nil, fmt.Errorf("query, from, and to are required")
+func (s *DatadogMCPServer) getMetrics(ctx context.Context, args getMetricsArgs) (interface{}, error) {
+	if args.Query == "" {
+		return nil, mcpserver.InvalidParams("query is required")
 	}
 
-	url := fmt.Sprintf("https://api.%s/api/v1/query?query=%s&from=%s&to=%s", s.site, query, from, to)
+	fromArg, toArg := args.From, args.To
+	if fromArg == "" {
+		fromArg = "now-1h"
+	}
+	if toArg == "" {
+		toArg = "now"
+	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	// The metrics API wants unix timestamps
+	now := time.Now()
+	from, err := parseTime(fromArg, now)
+	if err != nil {
+		return nil, mcpserver.InvalidParams("from: %v", err)
+	}
+	to, err := parseTime(toArg, now)
 	if err != nil {
+		return nil, mcpserver.InvalidParams("to: %v", err)
+	}
+	if from >= to {
+		return nil, mcpserver.InvalidParams("from (%s) must be before to (%s)", fromArg, toArg)
+	}
+
+	params := url.Values{}
+	params.Set("query", args.Query)
+	params.Set("from", strconv.FormatInt(from, 10))
+	params.Set("to", strconv.FormatInt(to, 10))
+
+	return s.send(ctx, "GET", fmt.Sprintf("https://api.%s/api/v1/query?%s", s.site, params.Encode()), nil)
+}
+
+func (s *DatadogMCPServer) muteMonitor(ctx context.Context, args muteMonitorArgs) (interface{}, error) {
+	if args.MonitorID <= 0 {
+		return nil, mcpserver.InvalidParams("monitor_id is required")
+	}
+
+	body := map[string]interface{}{}
+	if args.Scope != "" {
+		body["scope"] = args.Scope
+	}
+	if args.Duration != "" {
+		duration, err := time.ParseDuration(args.Duration)
+		if err != nil || duration <= 0 {
+			return nil, mcpserver.InvalidParams("duration must be a positive duration like 30m or 4h, got %q", args.Duration)
+		}
+		body["end"] = time.Now().Add(duration).Unix()
+	}
+
+	url := fmt.Sprintf("https://api.%s/api/v1/monitor/%d/mute", s.site, args.MonitorID)
+	return s.send(ctx, "POST", url, body)
+}
+
+func (s *DatadogMCPServer) unmuteMonitor(ctx context.Context, args muteMonitorArgs) (interface{}, error) {
+	if args.MonitorID <= 0 {
+		return nil, mcpserver.InvalidParams("monitor_id is required")
+	}
+
+	body := map[string]interface{}{"all_scopes": true}
+	if args.Scope != "" {
+		body = map[string]interface{}{"scope": args.Scope}
+	}
+
+	url := fmt.Sprintf("https://api.%s/api/v1/monitor/%d/unmute", s.site, args.MonitorID)
+	return s.send(ctx, "POST", url, body)
+}
+
+func (s *DatadogMCPServer) listIncidents(ctx context.Context, args listIncidentsArgs) (interface{}, error) {
+	var terms []string
+	switch args.State {
+	case "":
+	case "active", "stable", "resolved":
+		terms = append(terms, "state:"+args.State)
+	default:
+		return nil, mcpserver.InvalidParams("state must be active, stable, or resolved, got %q", args.State)
+	}
+	if args.Query != "" {
+		terms = append(terms, args.Query)
+	}
+
+	// The search endpoint is only needed to filter
+	params := url.Values{}
+	params.Set("page[size]", strconv.Itoa(s.config.MaxResults))
+	endpoint := fmt.Sprintf("https://api.%s/api/v2/incidents", s.site)
+	if len(terms) > 0 {
+		params.Set("query", strings.Join(terms, " AND "))
+		endpoint += "/search"
+	}
+
+	return s.send(ctx, "GET", endpoint+"?"+params.Encode(), nil)
+}
+
+func (s *DatadogMCPServer) getIncident(ctx context.Context, args incidentArgs) (interface{}, error) {
+	if args.IncidentID == "" {
+		return nil, mcpserver.InvalidParams("incident_id is required")
+	}
+
+	incidentURL := fmt.Sprintf("https://api.%s/api/v2/incidents/%s", s.site, url.PathEscape(args.IncidentID))
+	return s.send(ctx, "GET", incidentURL, nil)
+}
+
+// maxSpanPageSize is the most spans the span search API returns at once
+const maxSpanPageSize = 1000
+
+// TraceSpan is one span of a datadog_search_traces result
+type TraceSpan struct {
+	TraceID    string  `json:"traceId"`
+	SpanID     string  `json:"spanId"`
+	Service    string  `json:"service"`
+	Resource   string  `json:"resource"`
+	DurationMs float64 `json:"durationMs"`
+	Status     string  `json:"status,omitempty"`
+	Timestamp  string  `json:"timestamp,omitempty"`
+}
+
+func (s *DatadogMCPServer) searchTraces(ctx context.Context, args searchTracesArgs) (interface{}, error) {
+	var terms []string
+	if args.Service != "" {
+		terms = append(terms, "service:"+args.Service)
+	}
+	if args.Query != "" {
+		terms = append(terms, args.Query)
+	}
+	if args.MinDuration != "" {
+		duration, err := time.ParseDuration(args.MinDuration)
+		if err != nil || duration <= 0 {
+			return nil, mcpserver.InvalidParams("min_duration must be a positive duration like 500ms or 2s, got %q", args.MinDuration)
+		}
+		// Span durations are in nanoseconds
+		terms = append(terms, fmt.Sprintf("@duration:>=%d", duration.Nanoseconds()))
+	}
+	if args.ErrorsOnly {
+		terms = append(terms, "status:error")
+	}
+	query := "*"
+	if len(terms) > 0 {
+		query = strings.Join(terms, " ")
+	}
+
+	from, to := args.From, args.To
+	if from == "" {
+		from = "now-15m"
+	}
+	if to == "" {
+		to = "now"
+	}
+
+	body := map[string]interface{}{
+		"data": map[string]interface{}{
+			"type": "search_request",
+			"attributes": map[string]interface{}{
+				"filter": map[string]interface{}{
+					"query": query,
+					"from":  from,
+					"to":    to,
+				},
+				"sort": "-@duration",
+				"page": map[string]interface{}{
+					"limit": min(s.config.MaxResults, maxSpanPageSize),
+				},
+			},
+		},
+	}
+
+	var result struct {
+		Data []struct {
+			Attributes struct {
+				TraceID        string                 `json:"trace_id"`
+				SpanID         string                 `json:"span_id"`
+				Service        string                 `json:"service"`
+				ResourceName   string                 `json:"resource_name"`
+				StartTimestamp string                 `json:"start_timestamp"`
+				Custom         map[string]interface{} `json:"custom"`
+				Attributes     map[string]interface{} `json:"attributes"`
+			} `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := s.sendInto(framework.Idempotent(ctx), "POST", fmt.Sprintf("https://api.%s/api/v2/spans/events/search", s.site), body, &result); err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Authorization", "Bearer "+s.accessToken)
+	// Keep just enough of each span to pick a trace to dig into
+	spans := make([]TraceSpan, 0, len(result.Data))
+	for _, span := range result.Data {
+		attrs := span.Attributes
+		duration, _ := attrs.Custom["duration"].(float64)
+		status, _ := attrs.Attributes["status"].(string)
+		spans = append(spans, TraceSpan{
+			TraceID:    attrs.TraceID,
+			SpanID:     attrs.SpanID,
+			Service:    attrs.Service,
+			Resource:   attrs.ResourceName,
+			DurationMs: duration / float64(time.Millisecond),
+			Status:     status,
+			Timestamp:  attrs.StartTimestamp,
+		})
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	return map[string]interface{}{
+		"query": query,
+		"from":  from,
+		"to":    to,
+		"spans": spans,
+	}, nil
+}
+
+func (s *DatadogMCPServer) getServiceSummary(ctx context.Context, args serviceSummaryArgs) (interface{}, error) {
+	if args.Service == "" {
+		return nil, mcpserver.InvalidParams("service is required")
+	}
+
+	query := "service:" + args.Service
+	if args.Env != "" {
+		query += " env:" + args.Env
+	}
+	from, to := args.From, args.To
+	if from == "" {
+		from = "now-1h"
+	}
+	if to == "" {
+		to = "now"
+	}
+	limit := min(s.config.MaxResults, 100)
+
+	// Traffic and latency per resource, then errors per resource
+	traffic, err := s.aggregateSpans(ctx, query, from, to, limit, []map[string]interface{}{
+		{"aggregation": "count", "type": "total"},
+		{"aggregation": "avg", "metric": "@duration", "type": "total"},
+		{"aggregation": "pc95", "metric": "@duration", "type": "total"},
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
+	errorCounts, err := s.aggregateSpans(ctx, query+" status:error", from, to, limit, []map[string]interface{}{
+		{"aggregation": "count", "type": "total"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	errorsByResource := make(map[string]float64)
+	for _, bucket := range errorCounts {
+		errorsByResource[fmt.Sprint(bucket.By["resource_name"])] = bucket.number("c0")
+	}
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Datadog API error: %s - %s", resp.Status, string(bodyBytes))
+	// Return a compact line per resource rather than the raw buckets
+	var out strings.Builder
+	fmt.Fprintf(&out, "APM summary for %q (%s to %s), busiest resources first:\n", query, from, to)
+	if len(traffic) == 0 {
+		out.WriteString("No matching spans")
+		return out.String(), nil
+	}
+	for _, bucket := range traffic {
+		resource := fmt.Sprint(bucket.By["resource_name"])
+		requests := bucket.number("c0")
+		errorCount := errorsByResource[resource]
+		errorRate := 0.0
+		if requests > 0 {
+			errorRate = errorCount / requests * 100
+		}
+		fmt.Fprintf(&out, "%s: %.0f requests, %.0f errors (%.1f%%), avg %.1fms, p95 %.1fms\n",
+			resource, requests, errorCount, errorRate,
+			bucket.number("c1")/float64(time.Millisecond), bucket.number("c2")/float64(time.Millisecond))
 	}
 
-	var result interface{}
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	return strings.TrimRight(out.String(), "\n"), nil
+}
+
+// spanBucket is one group of a span aggregation
+type spanBucket struct {
+	By       map[string]interface{} `json:"by"`
+	Computes map[string]interface{} `json:"computes"`
+}
+
+// number returns a computed value of the bucket, or 0 if it is missing
+func (b spanBucket) number(compute string) float64 {
+	value, _ := b.Computes[compute].(float64)
+	return value
+}
+
+// aggregateSpans runs the computes over the matching spans grouped by
+// resource, busiest first. Computes are named c0, c1, ... in order.
+func (s *DatadogMCPServer) aggregateSpans(ctx context.Context, query, from, to string, limit int, computes []map[string]interface{}) ([]spanBucket, error) {
+	body := map[string]interface{}{
+		"data": map[string]interface{}{
+			"type": "aggregate_request",
+			"attributes": map[string]interface{}{
+				"compute": computes,
+				"filter": map[string]interface{}{
+					"query": query,
+					"from":  from,
+					"to":    to,
+				},
+				"group_by": []map[string]interface{}{
+					{
+						"facet": "resource_name",
+						"limit": limit,
+						"sort": map[string]interface{}{
+							"aggregation": "count",
+							"order":       "desc",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	var result struct {
+		Data []struct {
+			Attributes spanBucket `json:"attributes"`
+		} `json:"data"`
+	}
+	if err := s.sendInto(framework.Idempotent(ctx), "POST", fmt.Sprintf("https://api.%s/api/v2/spans/analytics/aggregate", s.site), body, &result); err != nil {
 		return nil, err
 	}
 
+	buckets := make([]spanBucket, 0, len(result.Data))
+	for _, item := range result.Data {
+		buckets = append(buckets, item.Attributes)
+	}
+	return buckets, nil
+}
+
+// send makes an API request with an optional JSON body and returns the
+// decoded response
+func (s *DatadogMCPServer) send(ctx context.Context, method, url string, body interface{}) (interface{}, error) {
+	var result interface{}
+	if err := s.sendInto(ctx, method, url, body, &result); err != nil {
+		return nil, err
+	}
 	return result, nil
 }
 
-func (s *DatadogMCPServer) errorResponse(message string) map[string]interface{} {
-	return map[string]interface{}{
-		"jsonrpc": "2.0",
-		"error": map[string]interface{}{
-			"code":    -32603,
-			"message": message,
+// sendInto makes an API request with an optional JSON body and decodes the
+// response into result. Any 2xx status is a success.
+func (s *DatadogMCPServer) sendInto(ctx context.Context, method, url string, body, result interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+s.accessToken)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("Datadog API error: %s - %s", resp.Status, string(bodyBytes))
+	}
+
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+func (s *DatadogMCPServer) serverInfo() (interface{}, error) {
+	info := map[string]interface{}{
+		"name":             "datadog-okta-mcp",
+		"version":          serverVersion,
+		"protocolVersions": mcpserver.SupportedProtocolVersions,
+		"credentials": map[string]interface{}{
+			"oktaAccessToken": s.accessToken != "",
 		},
+		"site":           s.site,
+		"cacheTtl":       s.config.CacheTTL.String(),
+		"maxConcurrency": s.config.MaxConcurrency,
+		"requestTimeout": s.config.RequestTimeout.String(),
+		"maxResults":     s.config.MaxResults,
+		"httpTimeout":    s.config.HTTPTimeout.String(),
+		"maxRetries":     s.config.MaxRetries,
+		"rateLimit":      s.config.RateLimit,
+		"maxResultSize":  s.config.MaxResultSize,
 	}
+
+	mcpserver.AddBuildInfo(info)
+	return info, nil
 }
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"boatman/mcp-servers/internal/framework"
+	"boatman/mcp-servers/internal/mcpserver"
+)
+
+func TestTools_InvalidParams(t *testing.T) {
+	s := &DatadogMCPServer{site: "datadoghq.com", config: &framework.Config{MaxResults: 50}}
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		call func() (interface{}, error)
+	}{
+		{"aggregate without a group", func() (interface{}, error) {
+			return s.aggregateLogs(ctx, aggregateLogsArgs{Query: "status:error"})
+		}},
+		{"mute without a monitor", func() (interface{}, error) {
+			return s.muteMonitor(ctx, muteMonitorArgs{})
+		}},
+		{"mute with a bad duration", func() (interface{}, error) {
+			return s.muteMonitor(ctx, muteMonitorArgs{MonitorID: 1, Duration: "tomorrow"})
+		}},
+		{"mute with a negative duration", func() (interface{}, error) {
+			return s.muteMonitor(ctx, muteMonitorArgs{MonitorID: 1, Duration: "-1h"})
+		}},
+		{"unmute without a monitor", func() (interface{}, error) {
+			return s.unmuteMonitor(ctx, muteMonitorArgs{Scope: "host:web-1"})
+		}},
+		{"unknown incident state", func() (interface{}, error) {
+			return s.listIncidents(ctx, listIncidentsArgs{State: "open"})
+		}},
+		{"incident without an id", func() (interface{}, error) {
+			return s.getIncident(ctx, incidentArgs{})
+		}},
+		{"traces with a bad min duration", func() (interface{}, error) {
+			return s.searchTraces(ctx, searchTracesArgs{Service: "web", MinDuration: "slow"})
+		}},
+		{"service summary without a service", func() (interface{}, error) {
+			return s.getServiceSummary(ctx, serviceSummaryArgs{Env: "production"})
+		}},
+		{"metrics with an unparseable time", func() (interface{}, error) {
+			return s.getMetrics(ctx, getMetricsArgs{Query: "avg:system.cpu.user{*}", From: "yesterday"})
+		}},
+		{"metrics ending before they start", func() (interface{}, error) {
+			return s.getMetrics(ctx, getMetricsArgs{Query: "avg:system.cpu.user{*}", From: "now", To: "now-1h"})
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.call()
+			var mcpErr *mcpserver.Error
+			if !errors.As(err, &mcpErr) || mcpErr.Code != mcpserver.CodeInvalidParams {
+				t.Errorf("Expected an invalid params error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "now", want: now},
+		{value: "15m", want: now.Add(-15 * time.Minute)},
+		{value: "now-1h", want: now.Add(-time.Hour)},
+		{value: "now-2d", want: now.Add(-48 * time.Hour)},
+		{value: "1w", want: now.Add(-7 * 24 * time.Hour)},
+		{value: "1710000000", want: time.Unix(1710000000, 0)},
+		{value: "2024-03-09T08:30:00Z", want: time.Date(2024, 3, 9, 8, 30, 0, 0, time.UTC)},
+		{value: "2024-03-09T08:30:00-05:00", want: time.Date(2024, 3, 9, 13, 30, 0, 0, time.UTC)},
+		{value: "2024-03-09", want: time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)},
+		{value: "yesterday", wantErr: true},
+		{value: "now+1h", wantErr: true},
+		{value: "15x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseTime(tt.value, now)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseTime(%q): expected an error, got %d", tt.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseTime(%q) failed: %v", tt.value, err)
+		} else if got != tt.want.Unix() {
+			t.Errorf("parseTime(%q) = %d, want %d", tt.value, got, tt.want.Unix())
+		}
+	}
+}
+
+// serveAPI sends the requests the servers make to handler instead of
+// Datadog, whatever host they're for, returning the requests it received
+func serveAPI(t *testing.T, handler http.HandlerFunc) *[]*http.Request {
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	target, _ := url.Parse(server.URL)
+	transport := http.DefaultTransport
+	http.DefaultTransport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		req = req.Clone(req.Context())
+		req.URL.Scheme = target.Scheme
+		req.URL.Host = target.Host
+		return transport.RoundTrip(req)
+	})
+	t.Cleanup(func() { http.DefaultTransport = transport })
+	return &requests
+}
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestAggregateLogs(t *testing.T) {
+	var body map[string]interface{}
+	requests := serveAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("Failed to decode request body: %v", err)
+		}
+		fmt.Fprint(w, `{"data": {"buckets": [
+			{"by": {"service": "web"}, "computes": {"c0": 1200}},
+			{"by": {"service": "worker"}, "computes": {"c0": 34}}
+		]}}`)
+	})
+	s := &DatadogMCPServer{
+		accessToken: "token",
+		site:        "datadoghq.eu",
+		config:      &framework.Config{MaxResults: 5},
+		client:      framework.NewHTTPClient(&framework.Config{HTTPTimeout: 5 * time.Second}),
+	}
+
+	result, err := s.aggregateLogs(context.Background(), aggregateLogsArgs{GroupBy: "service", Query: "status:error", From: "now-1h", Limit: 20})
+	if err != nil {
+		t.Fatalf("aggregateLogs failed: %v", err)
+	}
+
+	if len(*requests) != 1 {
+		t.Fatalf("Expected one request, got %d", len(*requests))
+	}
+	req := (*requests)[0]
+	if req.Method != "POST" || req.Host != "api.datadoghq.eu" || req.URL.Path != "/api/v2/logs/analytics/aggregate" {
+		t.Errorf("Expected POST api.datadoghq.eu/api/v2/logs/analytics/aggregate, got %s %s%s", req.Method, req.Host, req.URL.Path)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer token" {
+		t.Errorf("Expected the access token to be sent, got %q", got)
+	}
+
+	filter, _ := body["filter"].(map[string]interface{})
+	if filter["query"] != "status:error" || filter["from"] != "now-1h" || filter["to"] != "now" {
+		t.Errorf("Expected the query over now-1h to now, got %v", filter)
+	}
+	groupBy, _ := body["group_by"].([]interface{})
+	if len(groupBy) != 1 {
+		t.Fatalf("Expected one group_by, got %v", body["group_by"])
+	}
+	group := groupBy[0].(map[string]interface{})
+	if group["facet"] != "service" || group["limit"] != float64(5) {
+		t.Errorf("Expected service groups limited to max results, got %v", group)
+	}
+
+	want := "Log counts for \"status:error\" grouped by service (now-1h to now):\nweb: 1200\nworker: 34"
+	if result != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, result)
+	}
+}
+
+func TestAggregateLogs_NoMatches(t *testing.T) {
+	serveAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data": {"buckets": []}}`)
+	})
+	s := &DatadogMCPServer{
+		site:   "datadoghq.com",
+		config: &framework.Config{MaxResults: 50},
+		client: framework.NewHTTPClient(&framework.Config{HTTPTimeout: 5 * time.Second}),
+	}
+
+	result, err := s.aggregateLogs(context.Background(), aggregateLogsArgs{GroupBy: "status"})
+	if err != nil {
+		t.Fatalf("aggregateLogs failed: %v", err)
+	}
+	if got, _ := result.(string); !strings.HasSuffix(got, "No matching logs") || !strings.Contains(got, `"*"`) {
+		t.Errorf("Expected no matching logs for every log, got %q", result)
+	}
+}
+
+func TestAggregateLogs_APIError(t *testing.T) {
+	serveAPI(t, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"errors": ["Invalid facet"]}`, http.StatusBadRequest)
+	})
+	s := &DatadogMCPServer{
+		site:   "datadoghq.com",
+		config: &framework.Config{MaxResults: 50},
+		client: framework.NewHTTPClient(&framework.Config{HTTPTimeout: 5 * time.Second}),
+	}
+
+	_, err := s.aggregateLogs(context.Background(), aggregateLogsArgs{GroupBy: "@nope"})
+	if err == nil || !strings.Contains(err.Error(), "400") || !strings.Contains(err.Error(), "Invalid facet") {
+		t.Errorf("Expected the API error to be returned, got %v", err)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// relativeTimePattern matches relative times like 15m, now-1h, or now-2d
+var relativeTimePattern = regexp.MustCompile(`^(?:now-)?(\d+)(s|m|h|d|w)$`)
+
+// timeUnits maps relative time units to their length
+var timeUnits = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// timeLayouts are the absolute time formats parseTime accepts
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// parseTime converts a time argument into a unix timestamp. It accepts
+// "now", relative times counting back from now ("15m", "now-1h", "now-2d"),
+// unix timestamps in seconds, and ISO 8601 dates and times, which are UTC
+// unless they carry an offset.
+func parseTime(value string, now time.Time) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "now" {
+		return now.Unix(), nil
+	}
+
+	if match := relativeTimePattern.FindStringSubmatch(value); match != nil {
+		n, err := strconv.Atoi(match[1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid time %q: %w", value, err)
+		}
+		return now.Add(-time.Duration(n) * timeUnits[match[2]]).Unix(), nil
+	}
+
+	if ts, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return ts, nil
+	}
+
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.Unix(), nil
+		}
+	}
+
+	return 0, fmt.Errorf("invalid time %q: use now, a relative time like 15m or now-1h, a unix timestamp, or an ISO 8601 time like 2024-01-02T15:04:05Z", value)
+}
@@ -0,0 +1,30 @@
+package framework
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_Expiry(t *testing.T) {
+	now := time.Now()
+	cache := NewCache(time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.Set("a", "value")
+	if v, ok := cache.Get("a"); !ok || v != "value" {
+		t.Errorf("Expected cached value, got %v (ok=%v)", v, ok)
+	}
+
+	now = now.Add(2 * time.Minute)
+	if _, ok := cache.Get("a"); ok {
+		t.Error("Expected entry to expire")
+	}
+}
+
+func TestCache_Disabled(t *testing.T) {
+	cache := NewCache(0)
+	cache.Set("a", "value")
+	if _, ok := cache.Get("a"); ok {
+		t.Error("Expected a zero TTL to disable caching")
+	}
+}
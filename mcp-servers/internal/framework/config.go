@@ -0,0 +1,240 @@
+// Package framework holds the pieces shared by the bundled MCP servers.
+package framework
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Setting keys, used both as config file fields and in Options.Env
+const (
+	KeyConfig         = "config"
+	KeySite           = "site"
+	KeyOrg            = "org"
+	KeyDefaultProject = "defaultProject"
+	KeyCacheTTL       = "cacheTtl"
+	KeyMaxConcurrency = "maxConcurrency"
+	KeyRequestTimeout = "requestTimeout"
+	KeyMaxResults     = "maxResults"
+	KeyLogFile        = "logFile"
+	KeyLogLevel       = "logLevel"
+	KeyHTTPTimeout    = "httpTimeout"
+	KeyMaxRetries     = "maxRetries"
+	KeyRateLimit      = "rateLimit"
+	KeyMaxResultSize  = "maxResultSize"
+)
+
+// Config holds the settings shared by the MCP servers
+type Config struct {
+	Site           string
+	Org            string
+	DefaultProject string
+	CacheTTL       time.Duration
+	MaxConcurrency int
+	RequestTimeout time.Duration
+	MaxResults     int
+	LogFile        string
+	LogLevel       string
+	HTTPTimeout    time.Duration
+	MaxRetries     int
+	RateLimit      int
+	MaxResultSize  int
+}
+
+// Options describes how a server loads its configuration
+type Options struct {
+	// Name is used as the flag set name in usage output
+	Name string
+	// Env maps setting keys to the environment variables used as a fallback
+	Env map[string]string
+	// Defaults are used for settings that are not set anywhere else
+	Defaults Config
+}
+
+// fileConfig is the JSON config file format. Durations are strings like "30s".
+type fileConfig struct {
+	Site           string `json:"site,omitempty"`
+	Org            string `json:"org,omitempty"`
+	DefaultProject string `json:"defaultProject,omitempty"`
+	CacheTTL       string `json:"cacheTtl,omitempty"`
+	MaxConcurrency int    `json:"maxConcurrency,omitempty"`
+	RequestTimeout string `json:"requestTimeout,omitempty"`
+	MaxResults     int    `json:"maxResults,omitempty"`
+	LogFile        string `json:"logFile,omitempty"`
+	LogLevel       string `json:"logLevel,omitempty"`
+	HTTPTimeout    string `json:"httpTimeout,omitempty"`
+	MaxRetries     int    `json:"maxRetries,omitempty"`
+	RateLimit      int    `json:"rateLimit,omitempty"`
+	MaxResultSize  int    `json:"maxResultSize,omitempty"`
+}
+
+// Load builds the configuration from command-line flags, an optional JSON
+// config file (--config), environment variables, and defaults, in that order
+// of precedence.
+func Load(args []string, opts Options) (*Config, error) {
+	fs := flag.NewFlagSet(opts.Name, flag.ContinueOnError)
+	configPath := fs.String("config", "", "Path to a JSON config file")
+	site := fs.String("site", "", "API site or host")
+	org := fs.String("org", "", "Organization ID")
+	defaultProject := fs.String("default-project", "", "Project used when a tool call does not name one")
+	cacheTTL := fs.Duration("cache-ttl", 0, "Cache tool results for this long (0 disables caching)")
+	maxConcurrency := fs.Int("max-concurrency", 0, "Maximum number of tool calls handled at once")
+	requestTimeout := fs.Duration("request-timeout", 0, "Maximum time a tool call may take")
+	maxResults := fs.Int("max-results", 0, "Maximum number of results requested from the API")
+	logFile := fs.String("log-file", "", "Write logs to this file instead of stderr")
+	logLevel := fs.String("log-level", "", "Log level: debug, info, warn, or error")
+	httpTimeout := fs.Duration("http-timeout", 0, "Maximum time a single API request may take")
+	maxRetries := fs.Int("max-retries", 0, "Times a rate-limited or failed API request is retried")
+	rateLimit := fs.Int("rate-limit", 0, "Maximum API requests per second to each host (0 for no limit)")
+	maxResultSize := fs.Int("max-result-size", 0, "Maximum size of a tool result in bytes; larger results are trimmed")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	if !set["config"] {
+		*configPath = os.Getenv(opts.Env[KeyConfig])
+	}
+
+	var file fileConfig
+	if *configPath != "" {
+		data, err := os.ReadFile(*configPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		if err := json.Unmarshal(data, &file); err != nil {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+	}
+
+	r := resolver{set: set, env: opts.Env}
+	cfg := &Config{
+		Site:           r.str("site", *site, file.Site, KeySite, opts.Defaults.Site),
+		Org:            r.str("org", *org, file.Org, KeyOrg, opts.Defaults.Org),
+		DefaultProject: r.str("default-project", *defaultProject, file.DefaultProject, KeyDefaultProject, opts.Defaults.DefaultProject),
+		LogFile:        r.str("log-file", *logFile, file.LogFile, KeyLogFile, opts.Defaults.LogFile),
+		LogLevel:       r.str("log-level", *logLevel, file.LogLevel, KeyLogLevel, opts.Defaults.LogLevel),
+	}
+
+	var err error
+	if cfg.CacheTTL, err = r.duration("cache-ttl", *cacheTTL, file.CacheTTL, KeyCacheTTL, opts.Defaults.CacheTTL); err != nil {
+		return nil, err
+	}
+	if cfg.RequestTimeout, err = r.duration("request-timeout", *requestTimeout, file.RequestTimeout, KeyRequestTimeout, opts.Defaults.RequestTimeout); err != nil {
+		return nil, err
+	}
+	if cfg.MaxConcurrency, err = r.integer("max-concurrency", *maxConcurrency, file.MaxConcurrency, KeyMaxConcurrency, opts.Defaults.MaxConcurrency); err != nil {
+		return nil, err
+	}
+	if cfg.MaxResults, err = r.integer("max-results", *maxResults, file.MaxResults, KeyMaxResults, opts.Defaults.MaxResults); err != nil {
+		return nil, err
+	}
+	if cfg.HTTPTimeout, err = r.duration("http-timeout", *httpTimeout, file.HTTPTimeout, KeyHTTPTimeout, opts.Defaults.HTTPTimeout); err != nil {
+		return nil, err
+	}
+	if cfg.MaxRetries, err = r.integer("max-retries", *maxRetries, file.MaxRetries, KeyMaxRetries, opts.Defaults.MaxRetries); err != nil {
+		return nil, err
+	}
+	if cfg.RateLimit, err = r.integer("rate-limit", *rateLimit, file.RateLimit, KeyRateLimit, opts.Defaults.RateLimit); err != nil {
+		return nil, err
+	}
+	if cfg.MaxResultSize, err = r.integer("max-result-size", *maxResultSize, file.MaxResultSize, KeyMaxResultSize, opts.Defaults.MaxResultSize); err != nil {
+		return nil, err
+	}
+
+	if cfg.MaxConcurrency < 1 {
+		return nil, fmt.Errorf("max concurrency must be at least 1")
+	}
+	if cfg.RequestTimeout <= 0 {
+		return nil, fmt.Errorf("request timeout must be positive")
+	}
+	if cfg.MaxResults < 1 {
+		return nil, fmt.Errorf("max results must be at least 1")
+	}
+	if cfg.CacheTTL < 0 {
+		return nil, fmt.Errorf("cache TTL cannot be negative")
+	}
+	if cfg.HTTPTimeout < 0 || cfg.MaxRetries < 0 || cfg.RateLimit < 0 {
+		return nil, fmt.Errorf("HTTP timeout, max retries, and rate limit cannot be negative")
+	}
+	if cfg.MaxResultSize < 0 {
+		return nil, fmt.Errorf("max result size cannot be negative")
+	}
+
+	return cfg, nil
+}
+
+// resolver picks each setting from the first source that provides it
+type resolver struct {
+	set map[string]bool
+	env map[string]string
+}
+
+func (r resolver) envValue(key string) string {
+	if name := r.env[key]; name != "" {
+		return os.Getenv(name)
+	}
+	return ""
+}
+
+func (r resolver) str(flagName, flagValue, fileValue, key, def string) string {
+	if r.set[flagName] {
+		return flagValue
+	}
+	if fileValue != "" {
+		return fileValue
+	}
+	if v := r.envValue(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func (r resolver) duration(flagName string, flagValue time.Duration, fileValue, key string, def time.Duration) (time.Duration, error) {
+	if r.set[flagName] {
+		return flagValue, nil
+	}
+
+	source, value := "config file", fileValue
+	if value == "" {
+		source, value = r.env[key], r.envValue(key)
+	}
+	if value == "" {
+		return def, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s in %s: %w", key, source, err)
+	}
+	return d, nil
+}
+
+func (r resolver) integer(flagName string, flagValue, fileValue int, key string, def int) (int, error) {
+	if r.set[flagName] {
+		return flagValue, nil
+	}
+	if fileValue != 0 {
+		return fileValue, nil
+	}
+
+	value := r.envValue(key)
+	if value == "" {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s in %s: %w", key, r.env[key], err)
+	}
+	return n, nil
+}
@@ -0,0 +1,128 @@
+package framework
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testOptions() Options {
+	return Options{
+		Name: "test",
+		Env: map[string]string{
+			KeyConfig:         "TEST_MCP_CONFIG",
+			KeySite:           "TEST_SITE",
+			KeyMaxConcurrency: "TEST_MAX_CONCURRENCY",
+			KeyRequestTimeout: "TEST_REQUEST_TIMEOUT",
+		},
+		Defaults: Config{
+			Site:           "default.example.com",
+			MaxConcurrency: 4,
+			RequestTimeout: time.Minute,
+			MaxResults:     50,
+			LogLevel:       "info",
+		},
+	}
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := Load(nil, testOptions())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Site != "default.example.com" {
+		t.Errorf("Expected default site, got %q", cfg.Site)
+	}
+	if cfg.MaxConcurrency != 4 {
+		t.Errorf("Expected MaxConcurrency = 4, got %d", cfg.MaxConcurrency)
+	}
+	if cfg.RequestTimeout != time.Minute {
+		t.Errorf("Expected RequestTimeout = 1m, got %v", cfg.RequestTimeout)
+	}
+	if cfg.CacheTTL != 0 {
+		t.Errorf("Expected caching disabled, got %v", cfg.CacheTTL)
+	}
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	data := `{"site": "file.example.com", "maxConcurrency": 8, "cacheTtl": "30s"}`
+	if err := os.WriteFile(configPath, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	t.Setenv("TEST_SITE", "env.example.com")
+	t.Setenv("TEST_MAX_CONCURRENCY", "2")
+	t.Setenv("TEST_REQUEST_TIMEOUT", "45s")
+
+	cfg, err := Load([]string{"--config", configPath, "--max-concurrency", "16"}, testOptions())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	// Flags beat the config file
+	if cfg.MaxConcurrency != 16 {
+		t.Errorf("Expected flag MaxConcurrency = 16, got %d", cfg.MaxConcurrency)
+	}
+	// The config file beats env vars
+	if cfg.Site != "file.example.com" {
+		t.Errorf("Expected file site, got %q", cfg.Site)
+	}
+	if cfg.CacheTTL != 30*time.Second {
+		t.Errorf("Expected CacheTTL = 30s, got %v", cfg.CacheTTL)
+	}
+	// Env vars beat defaults
+	if cfg.RequestTimeout != 45*time.Second {
+		t.Errorf("Expected env RequestTimeout = 45s, got %v", cfg.RequestTimeout)
+	}
+}
+
+func TestLoad_ConfigFromEnv(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte(`{"org": "acme"}`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	t.Setenv("TEST_MCP_CONFIG", configPath)
+
+	cfg, err := Load(nil, testOptions())
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Org != "acme" {
+		t.Errorf("Expected org from config file, got %q", cfg.Org)
+	}
+}
+
+func TestLoad_Errors(t *testing.T) {
+	badConfig := filepath.Join(t.TempDir(), "bad.json")
+	if err := os.WriteFile(badConfig, []byte(`{not json`), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		env  string
+	}{
+		{"missing config file", []string{"--config", "/nonexistent/config.json"}, ""},
+		{"corrupt config file", []string{"--config", badConfig}, ""},
+		{"zero concurrency", []string{"--max-concurrency", "0"}, ""},
+		{"zero max results", []string{"--max-results", "0"}, ""},
+		{"negative max results", []string{"--max-results=-1"}, ""},
+		{"invalid env duration", nil, "soon"},
+		{"unknown flag", []string{"--nope"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != "" {
+				t.Setenv("TEST_REQUEST_TIMEOUT", tt.env)
+			}
+			if _, err := Load(tt.args, testOptions()); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
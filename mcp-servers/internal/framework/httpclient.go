@@ -0,0 +1,213 @@
+package framework
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// retryBaseDelay is the wait before the first retry, doubling after each
+	retryBaseDelay = 500 * time.Millisecond
+	// maxRetryDelay caps the backoff and any Retry-After the API asks for;
+	// a longer Retry-After returns the response rather than waiting
+	maxRetryDelay = 30 * time.Second
+)
+
+// HTTPClient is the HTTP client the servers make every API request with. It
+// times out each attempt, retries rate-limited and failed requests with
+// exponential backoff that honors Retry-After, and spaces out requests to
+// each host.
+type HTTPClient struct {
+	client     *http.Client
+	maxRetries int
+	interval   time.Duration
+
+	mu       sync.Mutex
+	limiters map[string]*rateLimiter
+
+	// baseDelay and maxDelay are fields so tests don't have to wait
+	baseDelay time.Duration
+	maxDelay  time.Duration
+}
+
+// NewHTTPClient creates a client from the HTTP settings of cfg
+func NewHTTPClient(cfg *Config) *HTTPClient {
+	c := &HTTPClient{
+		client:     &http.Client{Timeout: cfg.HTTPTimeout},
+		maxRetries: cfg.MaxRetries,
+		limiters:   make(map[string]*rateLimiter),
+		baseDelay:  retryBaseDelay,
+		maxDelay:   maxRetryDelay,
+	}
+	if cfg.RateLimit > 0 {
+		c.interval = time.Second / time.Duration(cfg.RateLimit)
+	}
+	return c
+}
+
+// idempotentKey marks a context whose requests are safe to repeat
+type idempotentKey struct{}
+
+// Idempotent marks requests made with the returned context as safe to retry
+// after a server error even though their method isn't, e.g. a search sent
+// as a POST
+func Idempotent(ctx context.Context) context.Context {
+	return context.WithValue(ctx, idempotentKey{}, true)
+}
+
+// Do sends the request, retrying it while the API answers 429, or for
+// idempotent requests a 5xx or a network error. The response of the last
+// attempt is returned. Requests with a body are only retried if it can be
+// rewound, as it can for bodies passed to http.NewRequest as a bytes.Reader.
+func (c *HTTPClient) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	canRetry := req.Body == nil || req.GetBody != nil
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		if err := c.limiter(req.URL.Host).wait(ctx); err != nil {
+			return nil, err
+		}
+
+		resp, err := c.client.Do(req)
+		if !canRetry || attempt >= c.maxRetries || !c.shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		delay, ok := c.retryDelay(attempt, resp)
+		if !ok {
+			return resp, err
+		}
+		if resp != nil {
+			// Drain the body so the connection can be reused
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// shouldRetry reports whether an attempt's outcome is worth retrying
+func (c *HTTPClient) shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if err != nil {
+		return req.Context().Err() == nil && isIdempotent(req)
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return true
+	case resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented:
+		return isIdempotent(req)
+	default:
+		return false
+	}
+}
+
+// isIdempotent reports whether repeating the request can't change anything
+// more than sending it once
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	marked, _ := req.Context().Value(idempotentKey{}).(bool)
+	return marked
+}
+
+// retryDelay returns how long to wait before retrying, preferring the
+// response's Retry-After. It returns false if the API asks for a longer wait
+// than the client is willing to make.
+func (c *HTTPClient) retryDelay(attempt int, resp *http.Response) (time.Duration, bool) {
+	if resp != nil {
+		if delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			return delay, delay <= c.maxDelay
+		}
+	}
+
+	delay := c.baseDelay << attempt
+	if delay > c.maxDelay || delay <= 0 {
+		delay = c.maxDelay
+	}
+	return delay, true
+}
+
+// parseRetryAfter reads a Retry-After header, either in seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if delay := at.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// limiter returns the rate limiter for a host
+func (c *HTTPClient) limiter(host string) *rateLimiter {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l, ok := c.limiters[host]
+	if !ok {
+		l = &rateLimiter{interval: c.interval}
+		c.limiters[host] = l
+	}
+	return l
+}
+
+// rateLimiter spaces requests at least interval apart
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// wait blocks until the next request may be sent, reserving its slot
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l.interval <= 0 {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,147 @@
+package framework
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestHTTPClient returns a client that retries without waiting long
+func newTestHTTPClient(maxRetries, rateLimit int) *HTTPClient {
+	c := NewHTTPClient(&Config{HTTPTimeout: 5 * time.Second, MaxRetries: maxRetries, RateLimit: rateLimit})
+	c.baseDelay = time.Millisecond
+	c.maxDelay = 50 * time.Millisecond
+	return c
+}
+
+// statusServer answers each request with the next status, then 200
+func statusServer(t *testing.T, headers http.Header, statuses ...int) (*httptest.Server, *int32) {
+	t.Helper()
+	calls := new(int32)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(atomic.AddInt32(calls, 1))
+		body, _ := io.ReadAll(r.Body)
+		if n <= len(statuses) {
+			for key, values := range headers {
+				w.Header()[key] = values
+			}
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server, calls
+}
+
+func TestHTTPClient_Retries(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		idempotent bool
+		headers    http.Header
+		statuses   []int
+		wantStatus int
+		wantCalls  int32
+	}{
+		{"rate limited", "POST", false, http.Header{"Retry-After": {"0"}}, []int{429, 429}, 200, 3},
+		{"server error on GET", "GET", false, nil, []int{502}, 200, 2},
+		{"server error on POST", "POST", false, nil, []int{500}, 500, 1},
+		{"server error on an idempotent POST", "POST", true, nil, []int{503}, 200, 2},
+		{"client error", "GET", false, nil, []int{404}, 404, 1},
+		{"out of retries", "GET", false, nil, []int{500, 500, 500, 500}, 500, 3},
+		{"Retry-After too long", "GET", false, http.Header{"Retry-After": {"3600"}}, []int{429}, 429, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, calls := statusServer(t, tt.headers, tt.statuses...)
+			ctx := context.Background()
+			if tt.idempotent {
+				ctx = Idempotent(ctx)
+			}
+			req, err := http.NewRequestWithContext(ctx, tt.method, server.URL, bytes.NewReader([]byte("payload")))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resp, err := newTestHTTPClient(2, 0).Do(req)
+			if err != nil {
+				t.Fatalf("Do failed: %v", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+			if got := atomic.LoadInt32(calls); got != tt.wantCalls {
+				t.Errorf("Expected %d calls, got %d", tt.wantCalls, got)
+			}
+			if resp.StatusCode == http.StatusOK {
+				if body, _ := io.ReadAll(resp.Body); string(body) != "payload" {
+					t.Errorf("Expected the body to be resent, got %q", body)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPClient_RateLimit(t *testing.T) {
+	server, _ := statusServer(t, nil)
+	client := newTestHTTPClient(0, 20)
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		req, _ := http.NewRequest("GET", server.URL, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("Do failed: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	// At 20 requests per second, the 4th request waits at least 150ms
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("Expected requests to be spaced out, took %v", elapsed)
+	}
+}
+
+func TestHTTPClient_CancelDuringBackoff(t *testing.T) {
+	server, _ := statusServer(t, http.Header{"Retry-After": {"1"}}, 429, 429)
+	client := newTestHTTPClient(2, 0)
+	client.maxDelay = time.Minute
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, "GET", server.URL, nil)
+	if _, err := client.Do(req); err != context.DeadlineExceeded {
+		t.Errorf("Expected the deadline to end the backoff, got %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"", 0, false},
+		{"5", 5 * time.Second, true},
+		{"Sun, 10 Mar 2024 12:00:30 GMT", 30 * time.Second, true},
+		{"Sun, 10 Mar 2024 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(tt.value, now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
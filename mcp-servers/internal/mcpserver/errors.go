@@ -0,0 +1,46 @@
+package mcpserver
+
+import (
+	"errors"
+	"fmt"
+)
+
+// JSON-RPC error codes
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Error is a JSON-RPC error. Tool handlers return one to choose the code
+// sent to the client; any other error is sent as an internal error.
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// NewError creates a JSON-RPC error with the given code
+func NewError(code int, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// InvalidParams reports arguments a tool can't work with, e.g. a missing required one
+func InvalidParams(format string, args ...interface{}) *Error {
+	return &Error{Code: CodeInvalidParams, Message: fmt.Sprintf(format, args...)}
+}
+
+// asError converts a handler's error to the JSON-RPC error sent back
+func asError(err error) *Error {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+	return &Error{Code: CodeInternalError, Message: err.Error()}
+}
@@ -0,0 +1,102 @@
+package mcpserver
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+// logLevel controls which messages are written to the log
+type logLevel int
+
+const (
+	levelDebug logLevel = iota
+	levelInfo
+	levelWarn
+	levelError
+)
+
+// parseLogLevel converts a level name into a logLevel
+func parseLogLevel(name string) (logLevel, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return levelDebug, nil
+	case "info", "":
+		return levelInfo, nil
+	case "warn", "warning":
+		return levelWarn, nil
+	case "error":
+		return levelError, nil
+	default:
+		return levelInfo, fmt.Errorf("unknown log level: %s", name)
+	}
+}
+
+// Logger writes leveled log messages to stderr or a log file, never stdout
+type Logger struct {
+	level  logLevel
+	logger *log.Logger
+}
+
+// NewLogger creates a logger writing messages at or above level to out
+func NewLogger(out io.Writer, level string) (*Logger, error) {
+	lvl, err := parseLogLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	return &Logger{level: lvl, logger: log.New(out, "", log.LstdFlags)}, nil
+}
+
+func (l *Logger) logf(level logLevel, prefix, format string, args ...interface{}) {
+	if level < l.level {
+		return
+	}
+	l.logger.Printf(prefix+format, args...)
+}
+
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.logf(levelDebug, "DEBUG ", format, args...)
+}
+
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.logf(levelInfo, "INFO ", format, args...)
+}
+
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.logf(levelWarn, "WARN ", format, args...)
+}
+
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.logf(levelError, "ERROR ", format, args...)
+}
+
+// SetupLogging routes all logging away from stdout and returns the writer
+// reserved for JSON-RPC frames. Stray writes to os.Stdout (e.g. fmt.Println)
+// are redirected to the log so they cannot corrupt the MCP stream.
+func SetupLogging(logFile, level string) (*Logger, io.Writer, error) {
+	if _, err := parseLogLevel(level); err != nil {
+		return nil, nil, err
+	}
+
+	logOut := os.Stderr
+	if logFile != "" {
+		f, err := os.OpenFile(logFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		logOut = f
+	}
+
+	logger, err := NewLogger(logOut, level)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	protocolOut := os.Stdout
+	os.Stdout = logOut
+	log.SetOutput(logOut)
+
+	return logger, protocolOut, nil
+}
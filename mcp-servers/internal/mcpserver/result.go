@@ -0,0 +1,168 @@
+package mcpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// defaultMaxResultSize is the largest tool result, in bytes, sent when
+	// neither the tool nor the server sets a limit
+	defaultMaxResultSize = 64 * 1024
+	// maxSummaryKeys is the number of object keys a result summary lists
+	maxSummaryKeys = 20
+)
+
+// toolResult builds the content of a tools/call result. Strings are sent as
+// they are. Anything else is sent as JSON, after a text block summarizing
+// its shape. Results over limit bytes are trimmed: arrays, or the largest
+// array in an object, keep as many leading items as fit, and anything else
+// is cut off, with the summary saying what was left out.
+func toolResult(result interface{}, limit int) map[string]interface{} {
+	if text, ok := result.(string); ok {
+		if cut, truncated := truncateText(text, limit); truncated {
+			text = fmt.Sprintf("%s\n... (truncated, %d of %d bytes shown)", cut, len(cut), len(text))
+		}
+		return textContent(text)
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return textContent(fmt.Sprintf("%v", result))
+	}
+
+	// Round-trip through JSON so structs are summarized like maps
+	var value interface{}
+	json.Unmarshal(data, &value)
+
+	summary := summarize(value)
+	if len(data) > limit {
+		var note string
+		data, note = shrink(value, data, limit)
+		summary += " " + note
+	}
+	return textContent(summary, string(data))
+}
+
+// textContent returns a result holding one text block per string
+func textContent(texts ...string) map[string]interface{} {
+	content := make([]map[string]interface{}, len(texts))
+	for i, text := range texts {
+		content[i] = map[string]interface{}{"type": "text", "text": text}
+	}
+	return map[string]interface{}{"content": content}
+}
+
+// summarize describes the shape of a decoded JSON value in a sentence
+func summarize(value interface{}) string {
+	switch v := value.(type) {
+	case []interface{}:
+		return fmt.Sprintf("Result: array of %d items.", len(v))
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		fields := make([]string, 0, maxSummaryKeys)
+		for _, key := range keys {
+			if len(fields) == maxSummaryKeys {
+				fields = append(fields, fmt.Sprintf("and %d more", len(keys)-maxSummaryKeys))
+				break
+			}
+			if items, ok := v[key].([]interface{}); ok {
+				key = fmt.Sprintf("%s (%d items)", key, len(items))
+			}
+			fields = append(fields, key)
+		}
+		if len(fields) == 0 {
+			return "Result: empty object."
+		}
+		return fmt.Sprintf("Result: object with %s.", strings.Join(fields, ", "))
+	default:
+		return "Result: JSON value."
+	}
+}
+
+// shrink trims a value whose JSON encoding, data, is over limit bytes,
+// returning the trimmed JSON and a note saying what was left out
+func shrink(value interface{}, data []byte, limit int) ([]byte, string) {
+	switch v := value.(type) {
+	case []interface{}:
+		if n, trimmed := fitItems(len(v), limit, func(n int) interface{} { return v[:n] }); n > 0 {
+			return trimmed, fmt.Sprintf("Showing the first %d of %d items to stay under %d bytes.", n, len(v), limit)
+		}
+	case map[string]interface{}:
+		if key := largestArray(v); key != "" {
+			items := v[key].([]interface{})
+			copied := make(map[string]interface{}, len(v))
+			for k, val := range v {
+				copied[k] = val
+			}
+			n, trimmed := fitItems(len(items), limit, func(n int) interface{} {
+				copied[key] = items[:n]
+				return copied
+			})
+			if n > 0 {
+				return trimmed, fmt.Sprintf("Showing the first %d of %d %s to stay under %d bytes.", n, len(items), key, limit)
+			}
+		}
+	}
+
+	cut, _ := truncateText(string(data), limit)
+	return []byte(cut), fmt.Sprintf("The JSON below is cut off after %d of %d bytes.", len(cut), len(data))
+}
+
+// fitItems finds the most leading items, at least one, whose encoding by
+// build fits in limit bytes, returning the count and the encoding, or 0 if
+// not even one item fits
+func fitItems(total, limit int, build func(n int) interface{}) (int, []byte) {
+	encode := func(n int) []byte {
+		data, _ := json.Marshal(build(n))
+		return data
+	}
+
+	lo, hi := 0, total
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		if len(encode(mid)) <= limit {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+	if lo == 0 {
+		return 0, nil
+	}
+	return lo, encode(lo)
+}
+
+// largestArray returns the key of the object's longest array, or ""; ties
+// go to the first key alphabetically
+func largestArray(object map[string]interface{}) string {
+	largest, size := "", 0
+	for key, value := range object {
+		items, ok := value.([]interface{})
+		if ok && (len(items) > size || (len(items) == size && size > 0 && key < largest)) {
+			largest, size = key, len(items)
+		}
+	}
+	return largest
+}
+
+// truncateText cuts text to at most limit bytes without splitting a UTF-8
+// character, reporting whether anything was cut
+func truncateText(text string, limit int) (string, bool) {
+	if len(text) <= limit {
+		return text, false
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return text[:cut], true
+}
@@ -0,0 +1,122 @@
+package mcpserver
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// resultTexts returns the text of each content block of a tool result
+func resultTexts(t *testing.T, result map[string]interface{}) []string {
+	t.Helper()
+	content, ok := result["content"].([]map[string]interface{})
+	if !ok {
+		t.Fatalf("Unexpected result %v", result)
+	}
+	texts := make([]string, len(content))
+	for i, block := range content {
+		if block["type"] != "text" {
+			t.Errorf("Expected a text block, got %v", block["type"])
+		}
+		texts[i], _ = block["text"].(string)
+	}
+	return texts
+}
+
+func TestToolResult(t *testing.T) {
+	type event struct {
+		ID    string `json:"id"`
+		Count int    `json:"count"`
+	}
+	events := make([]interface{}, 50)
+	for i := range events {
+		events[i] = event{ID: "event", Count: i}
+	}
+
+	tests := []struct {
+		name        string
+		result      interface{}
+		limit       int
+		wantSummary string
+		wantJSON    string
+		wantItems   int
+	}{
+		{
+			name:        "struct",
+			result:      event{ID: "e1", Count: 2},
+			limit:       1000,
+			wantSummary: "Result: object with count, id.",
+			wantJSON:    `{"id":"e1","count":2}`,
+		},
+		{
+			name:        "array over the limit",
+			result:      events,
+			limit:       200,
+			wantSummary: "Result: array of 50 items. Showing the first 7 of 50 items to stay under 200 bytes.",
+			wantItems:   7,
+		},
+		{
+			name:        "object with an array over the limit",
+			result:      map[string]interface{}{"events": events, "total": 50, "tags": []string{"a"}},
+			limit:       200,
+			wantSummary: "Result: object with events (50 items), tags (1 items), total. Showing the first 6 of 50 events to stay under 200 bytes.",
+		},
+		{
+			name:        "value that can't be trimmed",
+			result:      map[string]interface{}{"message": strings.Repeat("x", 100)},
+			limit:       50,
+			wantSummary: "Result: object with message. The JSON below is cut off after 50 of 114 bytes.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			texts := resultTexts(t, toolResult(tt.result, tt.limit))
+			if len(texts) != 2 {
+				t.Fatalf("Expected a summary and a JSON block, got %q", texts)
+			}
+			if texts[0] != tt.wantSummary {
+				t.Errorf("Expected summary %q, got %q", tt.wantSummary, texts[0])
+			}
+			if tt.wantJSON != "" && texts[1] != tt.wantJSON {
+				t.Errorf("Expected JSON %s, got %s", tt.wantJSON, texts[1])
+			}
+			if len(texts[1]) > tt.limit {
+				t.Errorf("Expected at most %d bytes, got %d", tt.limit, len(texts[1]))
+			}
+			if tt.wantItems > 0 {
+				var items []event
+				if err := json.Unmarshal([]byte(texts[1]), &items); err != nil || len(items) != tt.wantItems {
+					t.Errorf("Expected %d valid items, got %d (%v)", tt.wantItems, len(items), err)
+				}
+			}
+		})
+	}
+}
+
+func TestToolResult_Text(t *testing.T) {
+	if texts := resultTexts(t, toolResult("hello", 100)); len(texts) != 1 || texts[0] != "hello" {
+		t.Errorf("Expected the text as is, got %q", texts)
+	}
+
+	// Multi-byte characters are never split
+	texts := resultTexts(t, toolResult(strings.Repeat("é", 10), 5))
+	if want := "éé\n... (truncated, 4 of 20 bytes shown)"; texts[0] != want {
+		t.Errorf("Expected %q, got %q", want, texts[0])
+	}
+}
+
+func TestServe_MaxResultSize(t *testing.T) {
+	server, out, _ := newTestServer(t, nil)
+	AddTool(server, Tool{Name: "big", MaxResultSize: 10}, func(context.Context, struct{}) (interface{}, error) {
+		return strings.Repeat("a", 100), nil
+	})
+	responses := serveLines(t, server, out,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"big"}}`,
+	)
+
+	if text := responses["1"].Result.Content[0].Text; !strings.HasPrefix(text, "aaaaaaaaaa\n... (truncated") {
+		t.Errorf("Expected the tool's own limit to apply, got %q", text)
+	}
+}
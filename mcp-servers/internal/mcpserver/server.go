@@ -0,0 +1,539 @@
+// Package mcpserver implements the MCP protocol over stdio for the bundled
+// servers: the JSON-RPC request loop, tool registration, and error responses.
+package mcpserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"boatman/mcp-servers/internal/framework"
+)
+
+// SupportedProtocolVersions lists the MCP protocol versions the servers speak, newest first
+var SupportedProtocolVersions = []string{"2025-06-18", "2025-03-26", "2024-11-05"}
+
+// Tool describes a tool as listed by tools/list
+type Tool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"inputSchema"`
+	// NoCache keeps the tool's results out of the cache, for tools that
+	// change something or report live state
+	NoCache bool `json:"-"`
+	// MaxResultSize caps the tool's result in bytes, overriding
+	// Options.MaxResultSize
+	MaxResultSize int `json:"-"`
+}
+
+// handlerFunc runs a tool with its raw arguments
+type handlerFunc func(ctx context.Context, args json.RawMessage) (interface{}, error)
+
+// registeredTool is a tool and the handler that runs it
+type registeredTool struct {
+	Tool
+	handler handlerFunc
+}
+
+// Options configures a Server
+type Options struct {
+	Name    string // Reported in initialize as serverInfo.name, e.g. "bugsnag-okta-mcp"
+	Version string
+	Logger  *Logger
+	// Out receives the JSON-RPC frames; see SetupLogging
+	Out io.Writer
+	// MaxConcurrency bounds how many tool calls run at once
+	MaxConcurrency int
+	// RequestTimeout bounds each tool call, including waiting for a worker
+	RequestTimeout time.Duration
+	// Cache, when set, shares results between identical tool calls
+	Cache *framework.Cache
+	// MaxResultSize caps tool results in bytes; larger results are trimmed.
+	// Defaults to defaultMaxResultSize.
+	MaxResultSize int
+}
+
+// Server answers MCP requests, running tool calls concurrently
+type Server struct {
+	opts   Options
+	tools  []*registeredTool
+	byName map[string]*registeredTool
+
+	encoder    *json.Encoder
+	writeMu    sync.Mutex
+	sem        chan struct{}
+	inflight   map[string]*inflightRequest
+	inflightMu sync.Mutex
+	wg         sync.WaitGroup
+}
+
+// request is an incoming JSON-RPC request or notification
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"` // Echoed as sent; absent for notifications
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// response is an outgoing JSON-RPC response
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// inflightRequest tracks a tool call that is still running
+type inflightRequest struct {
+	cancel    context.CancelFunc
+	cancelled bool
+}
+
+// requestIDKey is the context key holding the id of the request being handled
+type requestIDKey struct{}
+
+// RequestID returns the JSON-RPC id of the request a handler is serving, as sent
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// New creates a server with no tools registered
+func New(opts Options) *Server {
+	if opts.MaxConcurrency < 1 {
+		opts.MaxConcurrency = 1
+	}
+	return &Server{
+		opts:     opts,
+		byName:   make(map[string]*registeredTool),
+		encoder:  json.NewEncoder(opts.Out),
+		sem:      make(chan struct{}, opts.MaxConcurrency),
+		inflight: make(map[string]*inflightRequest),
+	}
+}
+
+// AddTool registers a tool whose arguments are decoded into Args. Arguments
+// that don't fit Args are rejected with an invalid params error.
+func AddTool[Args any](s *Server, tool Tool, handler func(ctx context.Context, args Args) (interface{}, error)) {
+	s.addTool(tool, func(ctx context.Context, raw json.RawMessage) (interface{}, error) {
+		var args Args
+		if len(raw) > 0 && string(raw) != "null" {
+			if err := json.Unmarshal(raw, &args); err != nil {
+				return nil, InvalidParams("invalid arguments for %s: %v", tool.Name, err)
+			}
+		}
+		return handler(ctx, args)
+	})
+}
+
+func (s *Server) addTool(tool Tool, handler handlerFunc) {
+	if tool.InputSchema == nil {
+		tool.InputSchema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	registered := &registeredTool{Tool: tool, handler: handler}
+	if existing, ok := s.byName[tool.Name]; ok {
+		*existing = *registered // Registering a name again replaces the tool in place
+		return
+	}
+	s.tools = append(s.tools, registered)
+	s.byName[tool.Name] = registered
+}
+
+// Serve reads requests from in until it is closed, then waits for running
+// tool calls to finish. Requests are newline-delimited, so a malformed frame
+// is answered with a parse error rather than leaving the decoder stuck on it.
+func (s *Server) Serve(in io.Reader) error {
+	reader := bufio.NewReader(in)
+	s.opts.Logger.Infof("%s server started", s.opts.Name)
+
+	var err error
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if frame := bytes.TrimSpace(line); len(frame) > 0 {
+			s.handleFrame(frame)
+		}
+
+		if readErr != nil {
+			if readErr != io.EOF {
+				s.opts.Logger.Errorf("Error reading stdin: %v", readErr)
+				err = readErr
+			}
+			break
+		}
+	}
+
+	// Let in-flight tool calls finish before returning
+	s.wg.Wait()
+	return err
+}
+
+// handleFrame handles one line of input: a request, a notification, or a
+// batch of them
+func (s *Server) handleFrame(frame []byte) {
+	if frame[0] == '[' {
+		s.handleBatch(frame)
+		return
+	}
+
+	req, errResp := parseRequest(frame)
+	if errResp != nil {
+		s.send(errResp)
+		return
+	}
+	s.dispatch(req, s.send)
+}
+
+// handleBatch answers a batch with one array holding a response for each
+// request in it, once they have all finished. Notifications get no entry, and
+// a batch of only notifications gets no response at all.
+func (s *Server) handleBatch(frame []byte) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(frame, &items); err != nil {
+		s.opts.Logger.Errorf("Error decoding batch: %v", err)
+		s.send(errorResponse(nil, CodeParseError, "parse error: invalid JSON"))
+		return
+	}
+	if len(items) == 0 {
+		s.send(errorResponse(nil, CodeInvalidRequest, "empty batch"))
+		return
+	}
+
+	var mu sync.Mutex
+	var pending sync.WaitGroup
+	responses := []*response{}
+	reply := func(resp *response) {
+		if resp != nil {
+			mu.Lock()
+			responses = append(responses, resp)
+			mu.Unlock()
+		}
+		pending.Done()
+	}
+
+	for _, item := range items {
+		req, errResp := parseRequest(item)
+		if errResp != nil {
+			pending.Add(1)
+			reply(errResp)
+			continue
+		}
+		if !req.isNotification() {
+			pending.Add(1)
+		}
+		s.dispatch(req, reply)
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		pending.Wait()
+		if len(responses) > 0 {
+			s.sendFrame(responses)
+		}
+	}()
+}
+
+// parseRequest decodes and checks a request or notification, returning the
+// error response to send instead when it isn't valid JSON-RPC 2.0
+func parseRequest(raw []byte) (request, *response) {
+	var req request
+	if !json.Valid(raw) {
+		return req, errorResponse(nil, CodeParseError, "parse error: invalid JSON")
+	}
+	if err := json.Unmarshal(raw, &req); err != nil {
+		return req, errorResponse(nil, CodeInvalidRequest, "invalid request: "+err.Error())
+	}
+
+	// Ids are strings, numbers, or null; anything else can't be echoed back
+	if len(req.ID) > 0 {
+		switch req.ID[0] {
+		case '{', '[', 't', 'f':
+			return req, errorResponse(nil, CodeInvalidRequest, "invalid request id")
+		}
+	}
+	if req.JSONRPC != "2.0" {
+		return req, errorResponse(req.ID, CodeInvalidRequest, `jsonrpc must be "2.0"`)
+	}
+	if req.Method == "" {
+		return req, errorResponse(req.ID, CodeInvalidRequest, "missing method")
+	}
+	return req, nil
+}
+
+// isNotification reports whether the message expects no response
+func (req request) isNotification() bool {
+	return len(req.ID) == 0
+}
+
+// dispatch handles a checked message, passing its response to reply once.
+// reply is never called for notifications, and is called with nil for
+// cancelled requests. Tool calls run in their own goroutine, bounded by the
+// concurrency cap, so one slow upstream query does not block other requests.
+// Everything else is answered inline.
+func (s *Server) dispatch(req request, reply func(*response)) {
+	s.opts.Logger.Debugf("Received %s request (id %s)", req.Method, req.ID)
+	if req.isNotification() {
+		s.handleNotification(req)
+		return
+	}
+
+	if req.Method != "tools/call" {
+		result, err := s.handleRequest(context.Background(), req)
+		reply(newResponse(req.ID, result, err))
+		return
+	}
+
+	key := string(req.ID)
+	ctx, cancel := context.WithTimeout(context.Background(), s.opts.RequestTimeout)
+	ctx = context.WithValue(ctx, requestIDKey{}, key)
+
+	s.inflightMu.Lock()
+	s.inflight[key] = &inflightRequest{cancel: cancel}
+	s.inflightMu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.finishRequest(key)
+
+		select {
+		case s.sem <- struct{}{}:
+			defer func() { <-s.sem }()
+		case <-ctx.Done():
+			if s.wasCancelled(key) {
+				reply(nil)
+			} else {
+				reply(errorResponse(req.ID, CodeInternalError, "request timed out waiting for a free worker"))
+			}
+			return
+		}
+
+		result, err := s.handleRequest(ctx, req)
+
+		// Cancelled requests must not be answered
+		if s.wasCancelled(key) {
+			reply(nil)
+			return
+		}
+		reply(newResponse(req.ID, result, err))
+	}()
+}
+
+// handleNotification handles messages without an id, which never get a response
+func (s *Server) handleNotification(req request) {
+	switch req.Method {
+	case "notifications/cancelled":
+	case "notifications/initialized":
+		s.opts.Logger.Debugf("Client finished initializing")
+		return
+	default:
+		s.opts.Logger.Debugf("Ignoring notification %s", req.Method)
+		return
+	}
+
+	var params struct {
+		RequestID json.RawMessage `json:"requestId"`
+	}
+	json.Unmarshal(req.Params, &params)
+	key := string(params.RequestID)
+
+	s.inflightMu.Lock()
+	defer s.inflightMu.Unlock()
+	if inflight, ok := s.inflight[key]; ok {
+		s.opts.Logger.Infof("Cancelling request %s", key)
+		inflight.cancelled = true
+		inflight.cancel()
+	}
+}
+
+func (s *Server) wasCancelled(key string) bool {
+	s.inflightMu.Lock()
+	defer s.inflightMu.Unlock()
+	inflight, ok := s.inflight[key]
+	return ok && inflight.cancelled
+}
+
+func (s *Server) finishRequest(key string) {
+	s.inflightMu.Lock()
+	defer s.inflightMu.Unlock()
+	if inflight, ok := s.inflight[key]; ok {
+		inflight.cancel()
+		delete(s.inflight, key)
+	}
+}
+
+// newResponse builds the response to a request: its result, or err as a
+// JSON-RPC error. Errors that aren't an *Error are reported as internal errors.
+func newResponse(id json.RawMessage, result interface{}, err error) *response {
+	if err != nil {
+		return &response{JSONRPC: "2.0", ID: nullID(id), Error: asError(err)}
+	}
+	return &response{JSONRPC: "2.0", ID: nullID(id), Result: result}
+}
+
+// errorResponse builds an error response; a nil id is sent as null
+func errorResponse(id json.RawMessage, code int, message string) *response {
+	return &response{JSONRPC: "2.0", ID: nullID(id), Error: NewError(code, message)}
+}
+
+// nullID returns id, or null for requests whose id couldn't be read
+func nullID(id json.RawMessage) json.RawMessage {
+	if len(id) == 0 {
+		return json.RawMessage("null")
+	}
+	return id
+}
+
+// send writes a response frame; nil, for a cancelled request, writes nothing
+func (s *Server) send(resp *response) {
+	if resp != nil {
+		s.sendFrame(resp)
+	}
+}
+
+// sendFrame writes one JSON-RPC frame; concurrent tool calls share stdout
+func (s *Server) sendFrame(frame interface{}) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := s.encoder.Encode(frame); err != nil {
+		s.opts.Logger.Errorf("Error encoding response: %v", err)
+	}
+}
+
+func (s *Server) handleRequest(ctx context.Context, req request) (interface{}, error) {
+	switch req.Method {
+	case "initialize":
+		return s.handleInitialize(req)
+	case "ping":
+		return map[string]interface{}{}, nil
+	case "tools/list":
+		return map[string]interface{}{"tools": s.listTools()}, nil
+	case "tools/call":
+		return s.handleToolsCall(ctx, req)
+	default:
+		return nil, NewError(CodeMethodNotFound, fmt.Sprintf("unknown method: %s", req.Method))
+	}
+}
+
+func (s *Server) handleInitialize(req request) (interface{}, error) {
+	var params struct {
+		ProtocolVersion string `json:"protocolVersion"`
+	}
+	json.Unmarshal(req.Params, &params)
+
+	return map[string]interface{}{
+		"protocolVersion": negotiateProtocolVersion(params.ProtocolVersion),
+		"serverInfo": map[string]interface{}{
+			"name":    s.opts.Name,
+			"version": s.opts.Version,
+		},
+		"capabilities": map[string]interface{}{
+			"tools": map[string]interface{}{},
+		},
+	}, nil
+}
+
+// negotiateProtocolVersion returns the client's requested version if supported,
+// otherwise the newest version this server supports
+func negotiateProtocolVersion(requested string) string {
+	for _, v := range SupportedProtocolVersions {
+		if v == requested {
+			return v
+		}
+	}
+	return SupportedProtocolVersions[0]
+}
+
+// listTools returns the registered tools in registration order
+func (s *Server) listTools() []Tool {
+	tools := make([]Tool, len(s.tools))
+	for i, tool := range s.tools {
+		tools[i] = tool.Tool
+	}
+	return tools
+}
+
+func (s *Server) handleToolsCall(ctx context.Context, req request) (interface{}, error) {
+	var params struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		return nil, InvalidParams("invalid params: %v", err)
+	}
+	if params.Name == "" {
+		return nil, InvalidParams("missing tool name")
+	}
+
+	tool, ok := s.byName[params.Name]
+	if !ok {
+		return nil, InvalidParams("unknown tool: %s", params.Name)
+	}
+
+	// Identical calls to cacheable tools share a result
+	cacheKey := ""
+	if s.opts.Cache != nil && !tool.NoCache {
+		cacheKey = toolCacheKey(params.Name, params.Arguments)
+		if cached, ok := s.opts.Cache.Get(cacheKey); ok {
+			s.opts.Logger.Debugf("Cache hit for %s", params.Name)
+			return toolResult(cached, s.maxResultSize(tool)), nil
+		}
+	}
+
+	result, err := tool.handler(ctx, params.Arguments)
+	if err != nil {
+		s.opts.Logger.Warnf("Tool %s failed (request %s): %v", params.Name, RequestID(ctx), err)
+		return nil, err
+	}
+
+	if cacheKey != "" {
+		s.opts.Cache.Set(cacheKey, result)
+	}
+	return toolResult(result, s.maxResultSize(tool)), nil
+}
+
+// toolCacheKey identifies a tool call by its name and arguments, with the
+// arguments re-encoded so key order doesn't matter
+func toolCacheKey(name string, raw json.RawMessage) string {
+	var args interface{}
+	json.Unmarshal(raw, &args)
+	argBytes, _ := json.Marshal(args)
+	return name + ":" + string(argBytes)
+}
+
+// maxResultSize returns the result size limit of a tool
+func (s *Server) maxResultSize(tool *registeredTool) int {
+	if tool.MaxResultSize > 0 {
+		return tool.MaxResultSize
+	}
+	if s.opts.MaxResultSize > 0 {
+		return s.opts.MaxResultSize
+	}
+	return defaultMaxResultSize
+}
+
+// AddBuildInfo adds the Go version and VCS details the binary was built
+// with to a server_info result
+func AddBuildInfo(info map[string]interface{}) {
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return
+	}
+	info["goVersion"] = build.GoVersion
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			info["revision"] = setting.Value
+		case "vcs.time":
+			info["buildTime"] = setting.Value
+		case "vcs.modified":
+			info["modified"] = setting.Value == "true"
+		}
+	}
+}
@@ -0,0 +1,286 @@
+package mcpserver
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"boatman/mcp-servers/internal/framework"
+)
+
+// testResponse is a decoded response frame
+type testResponse struct {
+	ID     json.RawMessage `json:"id"`
+	Result struct {
+		ProtocolVersion string `json:"protocolVersion"`
+		Tools           []Tool `json:"tools"`
+		Content         []struct {
+			Text string `json:"text"`
+		} `json:"content"`
+	} `json:"result"`
+	Error *Error `json:"error"`
+}
+
+// greetArgs are the arguments of the test greet tool
+type greetArgs struct {
+	Name  string `json:"name"`
+	Times int    `json:"times"`
+}
+
+// newTestServer returns a server with a few test tools and the buffer its
+// responses are written to
+func newTestServer(t *testing.T, cache *framework.Cache) (*Server, *bytes.Buffer, *int32) {
+	t.Helper()
+	logger, err := NewLogger(io.Discard, "error")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	server := New(Options{
+		Name:           "test-mcp",
+		Version:        "1.2.3",
+		Logger:         logger,
+		Out:            out,
+		MaxConcurrency: 1,
+		RequestTimeout: 5 * time.Second,
+		Cache:          cache,
+	})
+
+	calls := new(int32)
+	AddTool(server, Tool{Name: "greet", Description: "Greets someone"}, func(ctx context.Context, args greetArgs) (interface{}, error) {
+		atomic.AddInt32(calls, 1)
+		if args.Name == "" {
+			return nil, InvalidParams("name is required")
+		}
+		return strings.Repeat("hello "+args.Name+" ", args.Times), nil
+	})
+	AddTool(server, Tool{Name: "request_id", NoCache: true}, func(ctx context.Context, _ struct{}) (interface{}, error) {
+		return RequestID(ctx), nil
+	})
+	AddTool(server, Tool{Name: "fail"}, func(context.Context, struct{}) (interface{}, error) {
+		return nil, io.ErrUnexpectedEOF
+	})
+	return server, out, calls
+}
+
+// serveLines runs the server over the given request frames and returns the
+// responses by id
+func serveLines(t *testing.T, server *Server, out *bytes.Buffer, lines ...string) map[string]testResponse {
+	t.Helper()
+	if err := server.Serve(strings.NewReader(strings.Join(lines, "\n") + "\n")); err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+
+	responses := make(map[string]testResponse)
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		var resp testResponse
+		if err := json.Unmarshal(scanner.Bytes(), &resp); err != nil {
+			t.Fatalf("Invalid response %q: %v", scanner.Text(), err)
+		}
+		responses[string(resp.ID)] = resp
+	}
+	return responses
+}
+
+func TestServe_Protocol(t *testing.T) {
+	server, out, _ := newTestServer(t, nil)
+	responses := serveLines(t, server, out,
+		`{"jsonrpc":"2.0","id":"init-1","method":"initialize","params":{"protocolVersion":"2025-03-26"}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`not json`,
+		`{"jsonrpc":"2.0","id":2,"method":"ping"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":4,"method":"resources/list"}`,
+	)
+
+	if len(responses) != 5 {
+		t.Fatalf("Expected 5 responses, got %d: %v", len(responses), responses)
+	}
+	if resp := responses["null"]; resp.Error == nil || resp.Error.Code != CodeParseError {
+		t.Errorf("Expected a parse error with a null id, got %+v", resp.Error)
+	}
+	if init := responses[`"init-1"`]; init.Result.ProtocolVersion != "2025-03-26" {
+		t.Errorf("Expected the requested protocol version with the string id echoed, got %+v", init)
+	}
+	if ping, ok := responses["2"]; !ok || ping.Error != nil {
+		t.Errorf("Expected a ping result, got %+v", ping)
+	}
+
+	var names []string
+	for _, tool := range responses["3"].Result.Tools {
+		names = append(names, tool.Name)
+		if tool.InputSchema["type"] != "object" {
+			t.Errorf("Expected a default object schema for %s, got %v", tool.Name, tool.InputSchema)
+		}
+	}
+	if got := strings.Join(names, ","); got != "greet,request_id,fail" {
+		t.Errorf("Expected tools in registration order, got %s", got)
+	}
+
+	if resp := responses["4"]; resp.Error == nil || resp.Error.Code != CodeMethodNotFound {
+		t.Errorf("Expected method not found, got %+v", resp.Error)
+	}
+}
+
+func TestServe_ToolCalls(t *testing.T) {
+	server, out, _ := newTestServer(t, nil)
+	responses := serveLines(t, server, out,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"greet","arguments":{"name":"ada","times":2}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"greet","arguments":{"name":"ada","times":"two"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"greet","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"missing"}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"fail"}}`,
+		`{"jsonrpc":"2.0","id":"req-6","method":"tools/call","params":{"name":"request_id"}}`,
+	)
+
+	if content := responses["1"].Result.Content; len(content) != 1 || content[0].Text != "hello ada hello ada " {
+		t.Errorf("Expected the decoded arguments to be used, got %+v", responses["1"])
+	}
+
+	tests := []struct {
+		id   string
+		code int
+	}{
+		{"2", CodeInvalidParams}, // Arguments that don't decode
+		{"3", CodeInvalidParams}, // Returned by the handler
+		{"4", CodeInvalidParams}, // Unknown tool
+		{"5", CodeInternalError}, // Any other error
+	}
+	for _, tt := range tests {
+		if resp := responses[tt.id]; resp.Error == nil || resp.Error.Code != tt.code {
+			t.Errorf("Request %s: expected error code %d, got %+v", tt.id, tt.code, resp.Error)
+		}
+	}
+
+	if content := responses[`"req-6"`].Result.Content; len(content) != 1 || content[0].Text != `"req-6"` {
+		t.Errorf("Expected the handler to see its request id, got %+v", responses[`"req-6"`])
+	}
+}
+
+func TestServe_Cache(t *testing.T) {
+	server, out, calls := newTestServer(t, framework.NewCache(time.Minute))
+	responses := serveLines(t, server, out,
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"greet","arguments":{"name":"ada","times":1}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"greet","arguments":{"times":1,"name":"ada"}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"request_id"}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"request_id"}}`,
+	)
+
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("Expected identical calls to share a result, got %d calls", got)
+	}
+	if responses["2"].Result.Content[0].Text != "hello ada " {
+		t.Errorf("Expected the cached result, got %+v", responses["2"])
+	}
+	if responses["4"].Result.Content[0].Text != "4" {
+		t.Errorf("Expected an uncached tool to run again, got %+v", responses["4"])
+	}
+}
+
+func TestServe_Cancel(t *testing.T) {
+	server, out, _ := newTestServer(t, nil)
+	started := make(chan struct{})
+	AddTool(server, Tool{Name: "wait"}, func(ctx context.Context, _ struct{}) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	in, w := io.Pipe()
+	done := make(chan error)
+	go func() { done <- server.Serve(in) }()
+
+	io.WriteString(w, `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"wait"}}`+"\n")
+	<-started
+	io.WriteString(w, `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":7}}`+"\n")
+	w.Close()
+
+	if err := <-done; err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected no response to a cancelled request, got %s", out.String())
+	}
+}
+
+func TestServe_InvalidRequests(t *testing.T) {
+	server, out, _ := newTestServer(t, nil)
+	responses := serveLines(t, server, out,
+		`{"id":1,"method":"ping"}`,
+		`{"jsonrpc":"2.0","id":2}`,
+		`{"jsonrpc":"2.0","id":{"a":1},"method":"ping"}`,
+		`{"jsonrpc":"2.0","method":"tools/call","params":{"name":"greet","arguments":{"name":"ada"}}}`,
+	)
+
+	// Notifications are never answered, even tool calls
+	if len(responses) != 3 {
+		t.Fatalf("Expected 3 responses, got %d: %v", len(responses), responses)
+	}
+	for _, id := range []string{"1", "2", "null"} {
+		if resp := responses[id]; resp.Error == nil || resp.Error.Code != CodeInvalidRequest {
+			t.Errorf("Request %s: expected an invalid request error, got %+v", id, resp.Error)
+		}
+	}
+}
+
+func TestServe_Batch(t *testing.T) {
+	server, out, _ := newTestServer(t, nil)
+	input := strings.Join([]string{
+		`[{"jsonrpc":"2.0","id":1,"method":"ping"},` +
+			`{"jsonrpc":"2.0","method":"notifications/initialized"},` +
+			`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"greet","arguments":{"name":"ada","times":1}}},` +
+			`{"jsonrpc":"2.0","id":3,"method":"nope"},` +
+			`42]`,
+		`[{"jsonrpc":"2.0","method":"notifications/initialized"}]`,
+		`[]`,
+	}, "\n") + "\n"
+	if err := server.Serve(strings.NewReader(input)); err != nil {
+		t.Fatalf("Serve failed: %v", err)
+	}
+
+	// The batch is answered once all of its requests finish, so the empty
+	// batch error may be written first
+	var batch []testResponse
+	var empty testResponse
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected a batch response and an empty batch error, got %q", out.String())
+	}
+	for _, line := range lines {
+		target := interface{}(&empty)
+		if strings.HasPrefix(line, "[") {
+			target = &batch
+		}
+		if err := json.Unmarshal([]byte(line), target); err != nil {
+			t.Fatalf("Invalid response %q: %v", line, err)
+		}
+	}
+
+	byID := make(map[string]testResponse)
+	for _, resp := range batch {
+		byID[string(resp.ID)] = resp
+	}
+	if len(batch) != 4 {
+		t.Fatalf("Expected a response for each request in the batch, got %+v", batch)
+	}
+	if byID["1"].Error != nil || byID["2"].Result.Content[0].Text != "hello ada " {
+		t.Errorf("Unexpected batch results: %+v", batch)
+	}
+	if byID["3"].Error == nil || byID["3"].Error.Code != CodeMethodNotFound {
+		t.Errorf("Expected method not found in the batch, got %+v", byID["3"].Error)
+	}
+	if byID["null"].Error == nil || byID["null"].Error.Code != CodeInvalidRequest {
+		t.Errorf("Expected an invalid request for the non-object entry, got %+v", byID["null"].Error)
+	}
+
+	if empty.Error == nil || empty.Error.Code != CodeInvalidRequest {
+		t.Errorf("Expected an invalid request error for the empty batch, got %+v", empty.Error)
+	}
+}
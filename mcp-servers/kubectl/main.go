@@ -0,0 +1,579 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"boatman/mcp-servers/internal/framework"
+	"boatman/mcp-servers/internal/mcpserver"
+)
+
+// serverVersion is the version reported in initialize and server_info
+const serverVersion = "1.0.0"
+
+const (
+	// defaultLogLines is how many log lines are returned when the caller
+	// doesn't say
+	defaultLogLines = 100
+	// maxLogLines caps the log lines a single call can return
+	maxLogLines = 1000
+)
+
+// namePattern matches Kubernetes object names (RFC 1123 subdomains). Names
+// are checked before they reach kubectl so they can't be read as flags.
+var namePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+
+// KubectlMCPServer implements MCP protocol for read-only kubectl access
+type KubectlMCPServer struct {
+	config *framework.Config
+	// kubeContext is the kubeconfig context to use, or "" for the current one
+	kubeContext string
+	// namespaces are the namespaces tools may read
+	namespaces []string
+	// run runs kubectl with the given arguments, returning its stdout
+	run func(ctx context.Context, args ...string) ([]byte, error)
+}
+
+func main() {
+	cfg, err := framework.Load(os.Args[1:], framework.Options{
+		Name: "kubectl",
+		Env: map[string]string{
+			framework.KeyConfig:         "KUBECTL_MCP_CONFIG",
+			framework.KeyDefaultProject: "KUBECTL_MCP_DEFAULT_NAMESPACE",
+			framework.KeyMaxConcurrency: "KUBECTL_MCP_MAX_CONCURRENCY",
+			framework.KeyRequestTimeout: "KUBECTL_MCP_REQUEST_TIMEOUT",
+			framework.KeyMaxResults:     "KUBECTL_MCP_MAX_RESULTS",
+			framework.KeyLogFile:        "KUBECTL_MCP_LOG_FILE",
+			framework.KeyLogLevel:       "KUBECTL_MCP_LOG_LEVEL",
+			framework.KeyMaxResultSize:  "KUBECTL_MCP_MAX_RESULT_SIZE",
+		},
+		Defaults: framework.Config{
+			DefaultProject: "default",
+			MaxConcurrency: 4,
+			RequestTimeout: time.Minute,
+			MaxResults:     50,
+			LogLevel:       "info",
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// stdout carries only JSON-RPC frames; everything else goes to the log
+	logger, protocolOut, err := mcpserver.SetupLogging(cfg.LogFile, cfg.LogLevel)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if _, err := exec.LookPath("kubectl"); err != nil {
+		log.Fatal("kubectl must be installed and on the PATH")
+	}
+
+	kubectl := &KubectlMCPServer{
+		config:      cfg,
+		kubeContext: os.Getenv("KUBECTL_MCP_CONTEXT"),
+		namespaces:  parseNamespaces(os.Getenv("KUBECTL_MCP_NAMESPACES"), cfg.DefaultProject),
+		run:         runKubectl,
+	}
+
+	server := mcpserver.New(mcpserver.Options{
+		Name:           "kubectl-mcp",
+		Version:        serverVersion,
+		Logger:         logger,
+		Out:            protocolOut,
+		MaxConcurrency: cfg.MaxConcurrency,
+		RequestTimeout: cfg.RequestTimeout,
+		MaxResultSize:  cfg.MaxResultSize,
+	})
+	kubectl.registerTools(server)
+
+	// Read MCP requests from stdin, write responses to stdout
+	if err := server.Serve(os.Stdin); err != nil {
+		os.Exit(1)
+	}
+}
+
+// parseNamespaces splits a comma-separated namespace list, falling back to
+// just the default namespace
+func parseNamespaces(list, defaultNamespace string) []string {
+	var namespaces []string
+	for _, ns := range strings.Split(list, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	if len(namespaces) == 0 {
+		namespaces = []string{defaultNamespace}
+	}
+	return namespaces
+}
+
+// runKubectl runs kubectl, returning its stdout, or its stderr as the error
+func runKubectl(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "kubectl", args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("kubectl %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("kubectl %s: %w", args[0], err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// namespaceArgs are the arguments of tools scoped to a namespace
+type namespaceArgs struct {
+	Namespace string `json:"namespace"`
+}
+
+// getPodsArgs are the arguments of k8s_get_pods
+type getPodsArgs struct {
+	namespaceArgs
+	Selector string `json:"selector"`
+}
+
+// podArgs are the arguments of tools about one pod
+type podArgs struct {
+	namespaceArgs
+	Pod string `json:"pod"`
+}
+
+// eventsArgs are the arguments of k8s_get_events
+type eventsArgs struct {
+	namespaceArgs
+	Pod          string `json:"pod"`
+	WarningsOnly bool   `json:"warnings_only"`
+}
+
+// logsArgs are the arguments of k8s_tail_logs
+type logsArgs struct {
+	podArgs
+	Container string `json:"container"`
+	Lines     int    `json:"lines"`
+	Since     string `json:"since"`
+	Previous  bool   `json:"previous"`
+}
+
+// registerTools adds the kubectl tools to the server. Every tool is
+// read-only; none of them can change the cluster.
+func (s *KubectlMCPServer) registerTools(server *mcpserver.Server) {
+	namespaceSchema := map[string]interface{}{
+		"type":        "string",
+		"description": fmt.Sprintf("Namespace, one of %s (default %s)", strings.Join(s.namespaces, ", "), s.config.DefaultProject),
+	}
+	podSchema := map[string]interface{}{
+		"type":        "string",
+		"description": "Pod name",
+	}
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "k8s_get_pods",
+		Description: "List pods with their phase, readiness, restarts, age, and why any container isn't running",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"namespace": namespaceSchema,
+				"selector": map[string]interface{}{
+					"type":        "string",
+					"description": "Label selector, e.g. app=web",
+				},
+			},
+		},
+		NoCache: true,
+	}, s.getPods)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "k8s_describe_pod",
+		Description: "Describe a pod: containers, state, probes, volumes, and recent events",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"namespace": namespaceSchema,
+				"pod":       podSchema,
+			},
+			"required": []string{"pod"},
+		},
+		NoCache: true,
+	}, s.describePod)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "k8s_get_events",
+		Description: "List a namespace's recent events, newest first, optionally for one pod or only warnings",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"namespace": namespaceSchema,
+				"pod": map[string]interface{}{
+					"type":        "string",
+					"description": "Only events about this pod",
+				},
+				"warnings_only": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Only Warning events",
+				},
+			},
+		},
+		NoCache: true,
+	}, s.getEvents)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "k8s_tail_logs",
+		Description: "Get the last lines of a pod's logs",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"namespace": namespaceSchema,
+				"pod":       podSchema,
+				"container": map[string]interface{}{
+					"type":        "string",
+					"description": "Container name (required for pods with several containers)",
+				},
+				"lines": map[string]interface{}{
+					"type":        "integer",
+					"description": fmt.Sprintf("Lines to return (default %d, at most %d)", defaultLogLines, maxLogLines),
+				},
+				"since": map[string]interface{}{
+					"type":        "string",
+					"description": "Only logs newer than this, e.g. 10m or 1h",
+				},
+				"previous": map[string]interface{}{
+					"type":        "boolean",
+					"description": "Logs of the previous, crashed container instead of the current one",
+				},
+			},
+			"required": []string{"pod"},
+		},
+		NoCache: true,
+	}, s.tailLogs)
+
+	mcpserver.AddTool(server, mcpserver.Tool{
+		Name:        "server_info",
+		Description: "Report this MCP server's version, build info, and which namespaces it can read",
+		NoCache:     true,
+	}, func(context.Context, struct{}) (interface{}, error) {
+		return s.serverInfo(), nil
+	})
+}
+
+// PodSummary is a compact view of a pod
+type PodSummary struct {
+	Name     string   `json:"name"`
+	Phase    string   `json:"phase"`
+	Ready    string   `json:"ready"`
+	Restarts int      `json:"restarts"`
+	Age      string   `json:"age"`
+	Node     string   `json:"node,omitempty"`
+	Problems []string `json:"problems,omitempty"`
+}
+
+func (s *KubectlMCPServer) getPods(ctx context.Context, args getPodsArgs) (interface{}, error) {
+	namespace, err := s.namespace(args.Namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	kubectlArgs := []string{"get", "pods", "--output=json"}
+	if args.Selector != "" {
+		kubectlArgs = append(kubectlArgs, "--selector="+args.Selector)
+	}
+	out, err := s.kubectl(ctx, namespace, kubectlArgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name              string    `json:"name"`
+				CreationTimestamp time.Time `json:"creationTimestamp"`
+			} `json:"metadata"`
+			Spec struct {
+				NodeName string `json:"nodeName"`
+			} `json:"spec"`
+			Status struct {
+				Phase             string `json:"phase"`
+				Reason            string `json:"reason"`
+				ContainerStatuses []struct {
+					Name         string `json:"name"`
+					Ready        bool   `json:"ready"`
+					RestartCount int    `json:"restartCount"`
+					State        struct {
+						Waiting *struct {
+							Reason string `json:"reason"`
+						} `json:"waiting"`
+						Terminated *struct {
+							Reason   string `json:"reason"`
+							ExitCode int    `json:"exitCode"`
+						} `json:"terminated"`
+					} `json:"state"`
+				} `json:"containerStatuses"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+
+	now := time.Now()
+	pods := make([]PodSummary, 0, len(list.Items))
+	for _, item := range list.Items {
+		pod := PodSummary{
+			Name:  item.Metadata.Name,
+			Phase: item.Status.Phase,
+			Age:   formatAge(now.Sub(item.Metadata.CreationTimestamp)),
+			Node:  item.Spec.NodeName,
+		}
+		if item.Status.Reason != "" {
+			pod.Problems = append(pod.Problems, item.Status.Reason)
+		}
+
+		ready := 0
+		for _, container := range item.Status.ContainerStatuses {
+			if container.Ready {
+				ready++
+			}
+			pod.Restarts += container.RestartCount
+			if waiting := container.State.Waiting; waiting != nil && waiting.Reason != "" {
+				pod.Problems = append(pod.Problems, fmt.Sprintf("%s: %s", container.Name, waiting.Reason))
+			}
+			if terminated := container.State.Terminated; terminated != nil && terminated.ExitCode != 0 {
+				pod.Problems = append(pod.Problems, fmt.Sprintf("%s: %s (exit %d)", container.Name, terminated.Reason, terminated.ExitCode))
+			}
+		}
+		pod.Ready = fmt.Sprintf("%d/%d", ready, len(item.Status.ContainerStatuses))
+		pods = append(pods, pod)
+	}
+
+	return map[string]interface{}{
+		"namespace": namespace,
+		"pods":      pods,
+	}, nil
+}
+
+func (s *KubectlMCPServer) describePod(ctx context.Context, args podArgs) (interface{}, error) {
+	namespace, err := s.namespace(args.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkName("pod", args.Pod, true); err != nil {
+		return nil, err
+	}
+
+	out, err := s.kubectl(ctx, namespace, "describe", "pod", args.Pod)
+	if err != nil {
+		return nil, err
+	}
+	return string(out), nil
+}
+
+// Event is a compact view of a Kubernetes event
+type Event struct {
+	LastSeen string `json:"lastSeen"`
+	Type     string `json:"type"`
+	Reason   string `json:"reason"`
+	Object   string `json:"object"`
+	Message  string `json:"message"`
+	Count    int    `json:"count,omitempty"`
+}
+
+func (s *KubectlMCPServer) getEvents(ctx context.Context, args eventsArgs) (interface{}, error) {
+	namespace, err := s.namespace(args.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkName("pod", args.Pod, false); err != nil {
+		return nil, err
+	}
+
+	var selectors []string
+	if args.Pod != "" {
+		selectors = append(selectors, "involvedObject.kind=Pod", "involvedObject.name="+args.Pod)
+	}
+	if args.WarningsOnly {
+		selectors = append(selectors, "type=Warning")
+	}
+	kubectlArgs := []string{"get", "events", "--output=json"}
+	if len(selectors) > 0 {
+		kubectlArgs = append(kubectlArgs, "--field-selector="+strings.Join(selectors, ","))
+	}
+	out, err := s.kubectl(ctx, namespace, kubectlArgs...)
+	if err != nil {
+		return nil, err
+	}
+
+	var list struct {
+		Items []struct {
+			Type           string    `json:"type"`
+			Reason         string    `json:"reason"`
+			Message        string    `json:"message"`
+			Count          int       `json:"count"`
+			LastTimestamp  time.Time `json:"lastTimestamp"`
+			EventTime      time.Time `json:"eventTime"`
+			InvolvedObject struct {
+				Kind string `json:"kind"`
+				Name string `json:"name"`
+			} `json:"involvedObject"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+
+	type timedEvent struct {
+		at time.Time
+		Event
+	}
+	events := make([]timedEvent, 0, len(list.Items))
+	for _, item := range list.Items {
+		// Newer event sources only set eventTime
+		at := item.LastTimestamp
+		if at.IsZero() {
+			at = item.EventTime
+		}
+		events = append(events, timedEvent{at: at, Event: Event{
+			LastSeen: at.Format(time.RFC3339),
+			Type:     item.Type,
+			Reason:   item.Reason,
+			Object:   strings.ToLower(item.InvolvedObject.Kind) + "/" + item.InvolvedObject.Name,
+			Message:  item.Message,
+			Count:    item.Count,
+		}})
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].at.After(events[j].at) })
+
+	limit := min(len(events), s.config.MaxResults)
+	newest := make([]Event, limit)
+	for i := range newest {
+		newest[i] = events[i].Event
+	}
+	return map[string]interface{}{
+		"namespace": namespace,
+		"total":     len(events),
+		"events":    newest,
+	}, nil
+}
+
+func (s *KubectlMCPServer) tailLogs(ctx context.Context, args logsArgs) (interface{}, error) {
+	namespace, err := s.namespace(args.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkName("pod", args.Pod, true); err != nil {
+		return nil, err
+	}
+	if err := checkName("container", args.Container, false); err != nil {
+		return nil, err
+	}
+
+	lines := args.Lines
+	if lines <= 0 {
+		lines = defaultLogLines
+	}
+	if lines > maxLogLines {
+		lines = maxLogLines
+	}
+
+	kubectlArgs := []string{"logs", args.Pod, fmt.Sprintf("--tail=%d", lines)}
+	if args.Container != "" {
+		kubectlArgs = append(kubectlArgs, "--container="+args.Container)
+	}
+	if args.Since != "" {
+		since, err := time.ParseDuration(args.Since)
+		if err != nil || since <= 0 {
+			return nil, mcpserver.InvalidParams("since must be a positive duration like 10m or 1h, got %q", args.Since)
+		}
+		kubectlArgs = append(kubectlArgs, "--since="+since.String())
+	}
+	if args.Previous {
+		kubectlArgs = append(kubectlArgs, "--previous")
+	}
+
+	out, err := s.kubectl(ctx, namespace, kubectlArgs...)
+	if err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return "No log lines", nil
+	}
+	return string(out), nil
+}
+
+// namespace returns the namespace argument, falling back to the default, if
+// it is one the server may read
+func (s *KubectlMCPServer) namespace(namespace string) (string, error) {
+	if namespace == "" {
+		namespace = s.config.DefaultProject
+	}
+	for _, allowed := range s.namespaces {
+		if namespace == allowed {
+			return namespace, nil
+		}
+	}
+	return "", mcpserver.InvalidParams("namespace %q is not allowed; use one of %s", namespace, strings.Join(s.namespaces, ", "))
+}
+
+// checkName checks that a name argument is a valid Kubernetes name
+func checkName(arg, name string, required bool) error {
+	if name == "" {
+		if required {
+			return mcpserver.InvalidParams("%s is required", arg)
+		}
+		return nil
+	}
+	if len(name) > 253 || !namePattern.MatchString(name) {
+		return mcpserver.InvalidParams("invalid %s name %q", arg, name)
+	}
+	return nil
+}
+
+// kubectl runs a kubectl command in a namespace, and the configured context
+func (s *KubectlMCPServer) kubectl(ctx context.Context, namespace string, args ...string) ([]byte, error) {
+	args = append(args, "--namespace="+namespace)
+	if s.kubeContext != "" {
+		args = append(args, "--context="+s.kubeContext)
+	}
+	return s.run(ctx, args...)
+}
+
+// formatAge formats a duration the way kubectl shows ages, e.g. 5m or 3d
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
+
+func (s *KubectlMCPServer) serverInfo() map[string]interface{} {
+	info := map[string]interface{}{
+		"name":             "kubectl-mcp",
+		"version":          serverVersion,
+		"protocolVersions": mcpserver.SupportedProtocolVersions,
+		"context":          s.kubeContext,
+		"namespaces":       s.namespaces,
+		"defaultNamespace": s.config.DefaultProject,
+		"maxConcurrency":   s.config.MaxConcurrency,
+		"requestTimeout":   s.config.RequestTimeout.String(),
+		"maxResults":       s.config.MaxResults,
+		"maxResultSize":    s.config.MaxResultSize,
+	}
+
+	mcpserver.AddBuildInfo(info)
+	return info
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"boatman/mcp-servers/internal/framework"
+	"boatman/mcp-servers/internal/mcpserver"
+)
+
+// newTestServer returns a server that records kubectl arguments instead of
+// running kubectl
+func newTestServer(output string) (*KubectlMCPServer, *[]string) {
+	var got []string
+	s := &KubectlMCPServer{
+		config:      &framework.Config{DefaultProject: "web", MaxResults: 2},
+		kubeContext: "prod",
+		namespaces:  []string{"web", "jobs"},
+		run: func(_ context.Context, args ...string) ([]byte, error) {
+			got = args
+			return []byte(output), nil
+		},
+	}
+	return s, &got
+}
+
+func TestTools_KubectlArgs(t *testing.T) {
+	tests := []struct {
+		name string
+		call func(s *KubectlMCPServer) (interface{}, error)
+		want string
+	}{
+		{
+			name: "get pods",
+			call: func(s *KubectlMCPServer) (interface{}, error) {
+				return s.getPods(context.Background(), getPodsArgs{Selector: "app=web"})
+			},
+			want: "get pods --output=json --selector=app=web --namespace=web --context=prod",
+		},
+		{
+			name: "describe pod",
+			call: func(s *KubectlMCPServer) (interface{}, error) {
+				return s.describePod(context.Background(), podArgs{namespaceArgs{"jobs"}, "worker-1"})
+			},
+			want: "describe pod worker-1 --namespace=jobs --context=prod",
+		},
+		{
+			name: "pod warnings",
+			call: func(s *KubectlMCPServer) (interface{}, error) {
+				return s.getEvents(context.Background(), eventsArgs{Pod: "web-1", WarningsOnly: true})
+			},
+			want: "get events --output=json --field-selector=involvedObject.kind=Pod,involvedObject.name=web-1,type=Warning --namespace=web --context=prod",
+		},
+		{
+			name: "logs capped",
+			call: func(s *KubectlMCPServer) (interface{}, error) {
+				return s.tailLogs(context.Background(), logsArgs{
+					podArgs:   podArgs{Pod: "web-1"},
+					Container: "app",
+					Lines:     5000,
+					Since:     "10m",
+					Previous:  true,
+				})
+			},
+			want: "logs web-1 --tail=1000 --container=app --since=10m0s --previous --namespace=web --context=prod",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, got := newTestServer(`{"items":[]}`)
+			if _, err := tt.call(s); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if args := strings.Join(*got, " "); args != tt.want {
+				t.Errorf("Expected kubectl %s, got kubectl %s", tt.want, args)
+			}
+		})
+	}
+}
+
+func TestTools_InvalidParams(t *testing.T) {
+	tests := []struct {
+		name string
+		call func(s *KubectlMCPServer) (interface{}, error)
+	}{
+		{
+			name: "namespace not allowed",
+			call: func(s *KubectlMCPServer) (interface{}, error) {
+				return s.getPods(context.Background(), getPodsArgs{namespaceArgs: namespaceArgs{"kube-system"}})
+			},
+		},
+		{
+			name: "missing pod",
+			call: func(s *KubectlMCPServer) (interface{}, error) {
+				return s.describePod(context.Background(), podArgs{})
+			},
+		},
+		{
+			name: "pod that looks like a flag",
+			call: func(s *KubectlMCPServer) (interface{}, error) {
+				return s.tailLogs(context.Background(), logsArgs{podArgs: podArgs{Pod: "--all-namespaces"}})
+			},
+		},
+		{
+			name: "bad since",
+			call: func(s *KubectlMCPServer) (interface{}, error) {
+				return s.tailLogs(context.Background(), logsArgs{podArgs: podArgs{Pod: "web-1"}, Since: "yesterday"})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, got := newTestServer("")
+			_, err := tt.call(s)
+			var mcpErr *mcpserver.Error
+			if !errors.As(err, &mcpErr) || mcpErr.Code != mcpserver.CodeInvalidParams {
+				t.Errorf("Expected an invalid params error, got %v", err)
+			}
+			if *got != nil {
+				t.Errorf("Expected kubectl not to run, got kubectl %s", strings.Join(*got, " "))
+			}
+		})
+	}
+}
+
+func TestGetEvents_NewestFirst(t *testing.T) {
+	s, _ := newTestServer(`{"items":[
+		{"type":"Normal","reason":"Pulled","lastTimestamp":"2024-03-10T12:00:00Z","involvedObject":{"kind":"Pod","name":"a"}},
+		{"type":"Warning","reason":"BackOff","eventTime":"2024-03-10T12:05:00Z","involvedObject":{"kind":"Pod","name":"a"}},
+		{"type":"Normal","reason":"Started","lastTimestamp":"2024-03-10T12:01:00Z","involvedObject":{"kind":"Pod","name":"a"}}
+	]}`)
+
+	result, err := s.getEvents(context.Background(), eventsArgs{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	events := result.(map[string]interface{})["events"].([]Event)
+	if len(events) != 2 || events[0].Reason != "BackOff" || events[1].Reason != "Started" {
+		t.Errorf("Expected the two newest events, got %+v", events)
+	}
+	if events[0].Object != "pod/a" {
+		t.Errorf("Expected object pod/a, got %s", events[0].Object)
+	}
+}
+
+func TestParseNamespaces(t *testing.T) {
+	if got := parseNamespaces(" web, jobs ,,", "default"); strings.Join(got, ",") != "web,jobs" {
+		t.Errorf("Expected web,jobs, got %q", got)
+	}
+	if got := parseNamespaces("", "default"); strings.Join(got, ",") != "default" {
+		t.Errorf("Expected just the default namespace, got %q", got)
+	}
+}
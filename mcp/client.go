@@ -0,0 +1,452 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// protocolVersion is the MCP revision sent during initialization. Servers
+// answer with the version they speak, which is accepted as long as the
+// tools/call shape is unchanged.
+const protocolVersion = "2025-06-18"
+
+// DefaultCallTimeout bounds a tool call made outside an agent turn
+const DefaultCallTimeout = 60 * time.Second
+
+// maxStderrTail is how much server stderr is kept to explain a crash
+const maxStderrTail = 2048
+
+// ToolContent is one item of a tool call's result
+type ToolContent struct {
+	Type     string `json:"type"` // "text", "image", "audio", "resource", ...
+	Text     string `json:"text,omitempty"`
+	Data     string `json:"data,omitempty"` // Base64 for images and audio
+	MimeType string `json:"mimeType,omitempty"`
+	// Resource is an embedded resource, left as the server sent it
+	Resource map[string]interface{} `json:"resource,omitempty"`
+}
+
+// ToolResult is the result of a tools/call request
+type ToolResult struct {
+	Content []ToolContent `json:"content"`
+	// StructuredContent is the tool's JSON output, when it declares an output schema
+	StructuredContent map[string]interface{} `json:"structuredContent,omitempty"`
+	// IsError reports a failure inside the tool, e.g. rejected credentials
+	IsError bool `json:"isError,omitempty"`
+}
+
+// Tool describes a tool a server exposes, as listed by tools/list
+type Tool struct {
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	// InputSchema is the JSON Schema of the tool's arguments
+	InputSchema map[string]interface{} `json:"inputSchema"`
+	// OutputSchema is the JSON Schema of the tool's structured output, if it declares one
+	OutputSchema map[string]interface{} `json:"outputSchema,omitempty"`
+	// Annotations are hints about the tool's behavior, e.g. readOnlyHint
+	Annotations map[string]interface{} `json:"annotations,omitempty"`
+}
+
+// maxToolPages bounds how many pages of tools/list are followed, in case a
+// server keeps returning a cursor
+const maxToolPages = 100
+
+// rpcMessage is a JSON-RPC 2.0 request, response, or notification
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  interface{}     `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is a JSON-RPC error object
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// Client talks to one MCP server over stdio
+type Client struct {
+	name   string
+	def    ServerDef
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stderr *tailBuffer
+
+	writeMu sync.Mutex
+	mu      sync.Mutex
+	nextID  int64
+	pending map[int64]chan rpcMessage
+	done    chan struct{}
+	err     error // Why the server exited, set before done closes
+}
+
+// startClient launches a server and performs the MCP initialize handshake
+func startClient(ctx context.Context, name string, def ServerDef) (*Client, error) {
+	cmd := exec.Command(def.Command, def.Args...)
+	cmd.Env = os.Environ()
+	for key, value := range def.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	stderr := &tailBuffer{max: maxStderrTail}
+	cmd.Stderr = stderr
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start MCP server %s: %w", name, err)
+	}
+
+	c := &Client{
+		name:    name,
+		def:     def,
+		cmd:     cmd,
+		stdin:   stdin,
+		stderr:  stderr,
+		pending: make(map[int64]chan rpcMessage),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop(stdout)
+
+	if err := c.initialize(ctx); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("failed to initialize MCP server %s: %w", name, err)
+	}
+	return c, nil
+}
+
+// initialize negotiates the protocol with the server
+func (c *Client) initialize(ctx context.Context) error {
+	params := map[string]interface{}{
+		"protocolVersion": protocolVersion,
+		"capabilities":    map[string]interface{}{},
+		"clientInfo": map[string]interface{}{
+			"name":    "boatman",
+			"version": "1.0.0",
+		},
+	}
+	if _, err := c.request(ctx, "initialize", params); err != nil {
+		return err
+	}
+	return c.write(rpcMessage{JSONRPC: "2.0", Method: "notifications/initialized"})
+}
+
+// CallTool runs a tool on the server. A tool that fails on its own terms
+// returns a result with IsError set rather than an error.
+func (c *Client) CallTool(ctx context.Context, tool string, args map[string]interface{}) (*ToolResult, error) {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	raw, err := c.request(ctx, "tools/call", map[string]interface{}{
+		"name":      tool,
+		"arguments": args,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result ToolResult
+	if err := json.Unmarshal(raw, &result); err != nil {
+		return nil, fmt.Errorf("invalid tools/call result: %w", err)
+	}
+	return &result, nil
+}
+
+// ListTools returns every tool the server exposes, following tools/list pages
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	tools := []Tool{}
+	cursor := ""
+	for page := 0; page < maxToolPages; page++ {
+		params := map[string]interface{}{}
+		if cursor != "" {
+			params["cursor"] = cursor
+		}
+		raw, err := c.request(ctx, "tools/list", params)
+		if err != nil {
+			return nil, err
+		}
+
+		var result struct {
+			Tools      []Tool `json:"tools"`
+			NextCursor string `json:"nextCursor"`
+		}
+		if err := json.Unmarshal(raw, &result); err != nil {
+			return nil, fmt.Errorf("invalid tools/list result: %w", err)
+		}
+		tools = append(tools, result.Tools...)
+		if result.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = result.NextCursor
+	}
+	return nil, fmt.Errorf("tools/list returned more than %d pages", maxToolPages)
+}
+
+// Ping checks that the server is still answering requests
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.request(ctx, "ping", nil)
+	return err
+}
+
+// request sends a request and waits for its response
+func (c *Client) request(ctx context.Context, method string, params interface{}) (json.RawMessage, error) {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return nil, c.err
+	}
+	c.nextID++
+	id := c.nextID
+	reply := make(chan rpcMessage, 1)
+	c.pending[id] = reply
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.write(rpcMessage{JSONRPC: "2.0", ID: &id, Method: method, Params: params}); err != nil {
+		return nil, err
+	}
+
+	select {
+	case msg := <-reply:
+		if msg.Error != nil {
+			return nil, fmt.Errorf("%s: %w", method, msg.Error)
+		}
+		return msg.Result, nil
+	case <-c.done:
+		return nil, c.exitErr()
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%s: %w", method, ctx.Err())
+	}
+}
+
+// write sends one newline-delimited JSON-RPC message
+func (c *Client) write(msg rpcMessage) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := c.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write to MCP server %s: %w", c.name, err)
+	}
+	return nil
+}
+
+// readLoop routes responses to waiting requests until the server exits
+func (c *Client) readLoop(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	for scanner.Scan() {
+		var msg rpcMessage
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+			// Servers sometimes log to stdout; skip anything that isn't JSON-RPC
+			continue
+		}
+
+		switch {
+		case msg.ID != nil && msg.Method != "":
+			c.answerServerRequest(msg)
+		case msg.ID != nil:
+			c.mu.Lock()
+			reply, ok := c.pending[*msg.ID]
+			c.mu.Unlock()
+			if ok {
+				reply <- msg
+			}
+		}
+		// Notifications (logging, progress) are ignored
+	}
+
+	waitErr := c.cmd.Wait()
+	c.mu.Lock()
+	c.err = fmt.Errorf("MCP server %s exited", c.name)
+	if waitErr != nil {
+		c.err = fmt.Errorf("MCP server %s exited: %w", c.name, waitErr)
+	}
+	c.mu.Unlock()
+	close(c.done)
+}
+
+// answerServerRequest replies to requests the server makes of the client.
+// Only ping is supported; there is no sampling or elicitation outside a session.
+func (c *Client) answerServerRequest(msg rpcMessage) {
+	reply := rpcMessage{JSONRPC: "2.0", ID: msg.ID}
+	if msg.Method == "ping" {
+		reply.Result = json.RawMessage("{}")
+	} else {
+		reply.Error = &rpcError{Code: -32601, Message: "method not found: " + msg.Method}
+	}
+	c.write(reply)
+}
+
+// exitErr describes why the server stopped, including the end of its stderr
+func (c *Client) exitErr() error {
+	c.mu.Lock()
+	err := c.err
+	c.mu.Unlock()
+	if tail := strings.TrimSpace(c.stderr.String()); tail != "" {
+		return fmt.Errorf("%w: %s", err, tail)
+	}
+	return err
+}
+
+// Alive reports whether the server process is still running
+func (c *Client) Alive() bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Close stops the server. Closing stdin asks it to exit; it is killed if it
+// hasn't within a few seconds.
+func (c *Client) Close() error {
+	c.stdin.Close()
+	select {
+	case <-c.done:
+	case <-time.After(3 * time.Second):
+		c.cmd.Process.Kill()
+		<-c.done
+	}
+	return nil
+}
+
+// tailBuffer keeps the last max bytes written to it
+type tailBuffer struct {
+	mu   sync.Mutex
+	max  int
+	data []byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.data = append(b.data, p...)
+	if len(b.data) > b.max {
+		b.data = b.data[len(b.data)-b.max:]
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.data)
+}
+
+// CallTool runs a tool on a configured server outside of any agent session.
+// The server is launched on first use and kept running for later calls; it is
+// restarted if it exited or its configuration changed.
+func (m *Manager) CallTool(ctx context.Context, serverName, tool string, args map[string]interface{}) (*ToolResult, error) {
+	def, err := m.serverDef(serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := m.client(ctx, serverName, def)
+	if err != nil {
+		return nil, err
+	}
+	return client.CallTool(ctx, tool, args)
+}
+
+// ListServerTools returns the tools a configured server exposes, so they can
+// be inspected before the server is enabled for sessions. The server is
+// launched as for CallTool, or the running one is used.
+func (m *Manager) ListServerTools(ctx context.Context, serverName string) ([]Tool, error) {
+	def, err := m.serverDef(serverName)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := m.client(ctx, serverName, def)
+	if err != nil {
+		return nil, err
+	}
+	return client.ListTools(ctx)
+}
+
+// client returns a running client for the server, starting one if needed.
+// A server started with StartServer is used while it is running.
+func (m *Manager) client(ctx context.Context, name string, def ServerDef) (*Client, error) {
+	m.clientsMu.Lock()
+	defer m.clientsMu.Unlock()
+
+	if sup, ok := m.supervised[name]; ok && sup.client != nil && sup.client.Alive() {
+		return sup.client, nil
+	}
+
+	if existing, ok := m.clients[name]; ok {
+		if existing.Alive() && sameServerDef(existing.def, def) {
+			return existing, nil
+		}
+		existing.Close()
+		delete(m.clients, name)
+	}
+
+	client, err := startClient(ctx, name, def)
+	if err != nil {
+		return nil, err
+	}
+	if m.clients == nil {
+		m.clients = make(map[string]*Client)
+	}
+	m.clients[name] = client
+	return client, nil
+}
+
+// CloseClients stops every server launched for direct tool calls
+func (m *Manager) CloseClients() {
+	m.clientsMu.Lock()
+	clients := m.clients
+	m.clients = nil
+	m.clientsMu.Unlock()
+
+	for _, client := range clients {
+		client.Close()
+	}
+}
+
+// sameServerDef reports whether two definitions launch the same process
+func sameServerDef(a, b ServerDef) bool {
+	if serverKey(a.Command, a.Args) != serverKey(b.Command, b.Args) || len(a.Env) != len(b.Env) {
+		return false
+	}
+	for key, value := range a.Env {
+		if other, ok := b.Env[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,257 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestHelperMCPServer is not a real test. It runs as a fake MCP server when
+// launched by the client tests with BOATMAN_MCP_HELPER set.
+// helperTools are the tools the fake server lists, over two pages
+var helperTools = []map[string]interface{}{
+	{
+		"name":        "echo",
+		"description": "Returns its text argument",
+		"inputSchema": map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"text": map[string]interface{}{"type": "string"}},
+			"required":   []string{"text"},
+		},
+		"annotations": map[string]interface{}{"readOnlyHint": true},
+	},
+	{"name": "pid", "inputSchema": map[string]interface{}{"type": "object"}},
+	{"name": "token", "inputSchema": map[string]interface{}{"type": "object"}},
+	{"name": "crash", "inputSchema": map[string]interface{}{"type": "object"}},
+}
+
+func TestHelperMCPServer(t *testing.T) {
+	if os.Getenv("BOATMAN_MCP_HELPER") != "1" {
+		return
+	}
+
+	// Noise on stdout must be skipped by the client
+	fmt.Println("fake server starting")
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var msg struct {
+			ID     *int64 `json:"id"`
+			Method string `json:"method"`
+			Params struct {
+				Name      string                 `json:"name"`
+				Arguments map[string]interface{} `json:"arguments"`
+				Cursor    string                 `json:"cursor"`
+			} `json:"params"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil || msg.ID == nil {
+			continue
+		}
+
+		var result interface{}
+		switch {
+		case msg.Method == "initialize":
+			result = map[string]interface{}{"protocolVersion": "2024-11-05", "capabilities": map[string]interface{}{}}
+		case msg.Method == "ping" && os.Getenv("FAIL_PING") == "":
+			result = map[string]interface{}{}
+		case msg.Method == "tools/list" && msg.Params.Cursor == "":
+			result = map[string]interface{}{"tools": helperTools[:2], "nextCursor": "page-2"}
+		case msg.Method == "tools/list":
+			result = map[string]interface{}{"tools": helperTools[2:]}
+		case msg.Params.Name == "echo":
+			text, _ := msg.Params.Arguments["text"].(string)
+			result = map[string]interface{}{"content": []map[string]string{{"type": "text", "text": text}}}
+		case msg.Params.Name == "pid":
+			result = map[string]interface{}{"content": []map[string]string{{"type": "text", "text": fmt.Sprint(os.Getpid())}}}
+		case msg.Params.Name == "token":
+			result = map[string]interface{}{
+				"content": []map[string]string{{"type": "text", "text": "token rejected"}},
+				"isError": os.Getenv("API_TOKEN") != "secret",
+			}
+		case msg.Params.Name == "crash":
+			fmt.Fprintln(os.Stderr, "panic: boom")
+			os.Exit(2)
+		default:
+			out, _ := json.Marshal(map[string]interface{}{
+				"jsonrpc": "2.0", "id": *msg.ID,
+				"error": map[string]interface{}{"code": -32602, "message": "unknown tool " + msg.Params.Name},
+			})
+			fmt.Println(string(out))
+			continue
+		}
+
+		out, _ := json.Marshal(map[string]interface{}{"jsonrpc": "2.0", "id": *msg.ID, "result": result})
+		fmt.Println(string(out))
+	}
+	os.Exit(0)
+}
+
+// setupHelperServer configures the fake server as "fake" in a temp MCP config
+func setupHelperServer(t *testing.T, env map[string]string) *Manager {
+	t.Helper()
+	t.Setenv("BOATMAN_MCP_HELPER", "1")
+
+	m := &Manager{configPath: filepath.Join(t.TempDir(), "mcp.json")}
+	err := m.AddServer(Server{
+		Name:    "fake",
+		Command: os.Args[0],
+		Args:    []string{"-test.run=^TestHelperMCPServer$"},
+		Env:     env,
+	})
+	if err != nil {
+		t.Fatalf("AddServer failed: %v", err)
+	}
+	t.Cleanup(m.CloseClients)
+	return m
+}
+
+func callText(t *testing.T, m *Manager, tool string, args map[string]interface{}) string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	result, err := m.CallTool(ctx, "fake", tool, args)
+	if err != nil {
+		t.Fatalf("CallTool(%s) failed: %v", tool, err)
+	}
+	if len(result.Content) != 1 || result.Content[0].Type != "text" {
+		t.Fatalf("Expected one text content item, got %+v", result.Content)
+	}
+	return result.Content[0].Text
+}
+
+func TestCallTool(t *testing.T) {
+	m := setupHelperServer(t, nil)
+
+	if got := callText(t, m, "echo", map[string]interface{}{"text": "hello"}); got != "hello" {
+		t.Errorf("Expected echo of hello, got %q", got)
+	}
+
+	// The server is reused between calls
+	first := callText(t, m, "pid", nil)
+	if second := callText(t, m, "pid", nil); second != first {
+		t.Errorf("Expected the server to be reused, got pids %s and %s", first, second)
+	}
+
+	_, err := m.CallTool(context.Background(), "fake", "missing", nil)
+	if err == nil || !strings.Contains(err.Error(), "unknown tool missing") {
+		t.Errorf("Expected the server's error to be returned, got %v", err)
+	}
+
+	_, err = m.CallTool(context.Background(), "nope", "echo", nil)
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected unknown server error, got %v", err)
+	}
+}
+
+func TestCallToolPassesEnv(t *testing.T) {
+	ctx := context.Background()
+
+	m := setupHelperServer(t, map[string]string{"API_TOKEN": "secret"})
+	result, err := m.CallTool(ctx, "fake", "token", nil)
+	if err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+	if result.IsError {
+		t.Error("Expected the configured token to be accepted")
+	}
+
+	// Changing the config restarts the server with the new environment
+	if err := m.UpdateServer(Server{
+		Name:    "fake",
+		Command: os.Args[0],
+		Args:    []string{"-test.run=^TestHelperMCPServer$"},
+		Env:     map[string]string{"API_TOKEN": "wrong"},
+	}); err != nil {
+		t.Fatalf("UpdateServer failed: %v", err)
+	}
+	result, err = m.CallTool(ctx, "fake", "token", nil)
+	if err != nil {
+		t.Fatalf("CallTool failed: %v", err)
+	}
+	if !result.IsError {
+		t.Error("Expected the changed token to be rejected")
+	}
+}
+
+func TestCallToolRestartsCrashedServer(t *testing.T) {
+	m := setupHelperServer(t, nil)
+
+	first := callText(t, m, "pid", nil)
+
+	_, err := m.CallTool(context.Background(), "fake", "crash", nil)
+	if err == nil || !strings.Contains(err.Error(), "panic: boom") {
+		t.Fatalf("Expected crash error with stderr, got %v", err)
+	}
+
+	if second := callText(t, m, "pid", nil); second == first {
+		t.Error("Expected a new server process after the crash")
+	}
+}
+
+func TestListServerTools(t *testing.T) {
+	m := setupHelperServer(t, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	tools, err := m.ListServerTools(ctx, "fake")
+	if err != nil {
+		t.Fatalf("ListServerTools failed: %v", err)
+	}
+
+	// Both pages are returned
+	var names []string
+	for _, tool := range tools {
+		names = append(names, tool.Name)
+	}
+	if got := strings.Join(names, ","); got != "echo,pid,token,crash" {
+		t.Fatalf("Expected the tools from both pages, got %s", got)
+	}
+
+	echo := tools[0]
+	if echo.Description != "Returns its text argument" || echo.InputSchema["type"] != "object" {
+		t.Errorf("Unexpected echo tool: %+v", echo)
+	}
+	if props, _ := echo.InputSchema["properties"].(map[string]interface{}); props["text"] == nil {
+		t.Errorf("Expected the text argument in the schema, got %v", echo.InputSchema)
+	}
+	if echo.Annotations["readOnlyHint"] != true {
+		t.Errorf("Expected the read-only hint, got %v", echo.Annotations)
+	}
+
+	// The server stays up for tool calls
+	if got := callText(t, m, "echo", map[string]interface{}{"text": "hi"}); got != "hi" {
+		t.Errorf("Expected echo of hi, got %q", got)
+	}
+
+	if _, err := m.ListServerTools(ctx, "nope"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected unknown server error, got %v", err)
+	}
+}
+
+func TestSameServerDef(t *testing.T) {
+	base := ServerDef{Command: "npx", Args: []string{"-y", "server"}, Env: map[string]string{"TOKEN": "a"}}
+
+	tests := []struct {
+		name     string
+		other    ServerDef
+		expected bool
+	}{
+		{"identical", ServerDef{Command: "npx", Args: []string{"-y", "server"}, Env: map[string]string{"TOKEN": "a"}}, true},
+		{"different args", ServerDef{Command: "npx", Args: []string{"server"}, Env: map[string]string{"TOKEN": "a"}}, false},
+		{"different env value", ServerDef{Command: "npx", Args: []string{"-y", "server"}, Env: map[string]string{"TOKEN": "b"}}, false},
+		{"missing env", ServerDef{Command: "npx", Args: []string{"-y", "server"}}, false},
+	}
+
+	for _, tt := range tests {
+		if got := sameServerDef(base, tt.other); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+}
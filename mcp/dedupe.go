@@ -0,0 +1,209 @@
+package mcp
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DuplicateServerError is returned when a server's command and args match an
+// existing entry that is configured under a different name
+type DuplicateServerError struct {
+	Name     string
+	Existing string
+}
+
+func (e *DuplicateServerError) Error() string {
+	return fmt.Sprintf("server %q duplicates existing server %q", e.Name, e.Existing)
+}
+
+// DuplicateGroup is a set of configured servers that share the same command and args
+type DuplicateGroup struct {
+	Names   []string `json:"names"`
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+}
+
+// serverKey identifies a server by what it runs rather than by its name
+func serverKey(command string, args []string) string {
+	return command + "\x00" + strings.Join(args, "\x00")
+}
+
+// findDuplicate returns the name of another entry that runs the same command and args
+func findDuplicate(config *Config, server Server) (string, bool) {
+	if config == nil {
+		return "", false
+	}
+
+	key := serverKey(server.Command, server.Args)
+	names := make([]string, 0, len(config.McpServers))
+	for name := range config.McpServers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		def := config.McpServers[name]
+		if name != server.Name && serverKey(def.Command, def.Args) == key {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// mergeEnv combines two env maps. When override is true, non-empty values in
+// extra replace those in base; otherwise they only fill in missing or empty values.
+func mergeEnv(base, extra map[string]string, override bool) map[string]string {
+	if len(base) == 0 && len(extra) == 0 {
+		return nil
+	}
+
+	merged := make(map[string]string, len(base)+len(extra))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range extra {
+		if v == "" {
+			if _, ok := merged[k]; !ok {
+				merged[k] = v
+			}
+			continue
+		}
+		if override || merged[k] == "" {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// FindDuplicate returns the name of a configured server that runs the same
+// command and args as server under a different name
+func (m *Manager) FindDuplicate(server Server) (string, bool, error) {
+	config, err := m.loadConfig()
+	if err != nil && !os.IsNotExist(err) {
+		return "", false, err
+	}
+
+	name, found := findDuplicate(config, server)
+	return name, found, nil
+}
+
+// MergeServer merges server into the existing entry named existing, renaming
+// the entry to server.Name. Non-empty env values from server take precedence.
+func (m *Manager) MergeServer(existing string, server Server) error {
+	return m.updateConfig(func(config *Config, exists bool) (bool, error) {
+		if !exists {
+			return false, os.ErrNotExist
+		}
+		def, ok := config.McpServers[existing]
+		if !ok {
+			return false, fmt.Errorf("server %q not found", existing)
+		}
+
+		name := server.Name
+		if name == "" {
+			name = existing
+		}
+
+		delete(config.McpServers, existing)
+		config.McpServers[name] = ServerDef{
+			Command: def.Command,
+			Args:    def.Args,
+			Env:     mergeEnv(def.Env, server.Env, true),
+		}
+		return true, nil
+	})
+}
+
+// RenameServer renames a configured server
+func (m *Manager) RenameServer(oldName, newName string) error {
+	if newName == "" {
+		return fmt.Errorf("new server name is required")
+	}
+
+	return m.updateConfig(func(config *Config, exists bool) (bool, error) {
+		if !exists {
+			return false, os.ErrNotExist
+		}
+		def, ok := config.McpServers[oldName]
+		if !ok {
+			return false, fmt.Errorf("server %q not found", oldName)
+		}
+		if oldName == newName {
+			return false, nil
+		}
+		if _, exists := config.McpServers[newName]; exists {
+			return false, fmt.Errorf("server %q already exists", newName)
+		}
+
+		delete(config.McpServers, oldName)
+		config.McpServers[newName] = def
+		return true, nil
+	})
+}
+
+// FindDuplicates returns every group of configured servers that share the same command and args
+func (m *Manager) FindDuplicates() ([]DuplicateGroup, error) {
+	config, err := m.loadConfig()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []DuplicateGroup{}, nil
+		}
+		return nil, err
+	}
+
+	return duplicateGroups(config), nil
+}
+
+// Dedupe collapses each group of duplicate servers into the alphabetically first
+// name, filling in env values missing from that entry from the others.
+// It returns the groups that were merged.
+func (m *Manager) Dedupe() ([]DuplicateGroup, error) {
+	groups := []DuplicateGroup{}
+	err := m.updateConfig(func(config *Config, exists bool) (bool, error) {
+		groups = duplicateGroups(config)
+		for _, group := range groups {
+			keep := group.Names[0]
+			def := config.McpServers[keep]
+			for _, name := range group.Names[1:] {
+				def.Env = mergeEnv(def.Env, config.McpServers[name].Env, false)
+				delete(config.McpServers, name)
+			}
+			config.McpServers[keep] = def
+		}
+		return len(groups) > 0, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// duplicateGroups groups servers by command and args, returning only groups with
+// more than one member. Names within a group and the groups themselves are sorted.
+func duplicateGroups(config *Config) []DuplicateGroup {
+	byKey := make(map[string]*DuplicateGroup)
+	for name, def := range config.McpServers {
+		key := serverKey(def.Command, def.Args)
+		group, ok := byKey[key]
+		if !ok {
+			group = &DuplicateGroup{Command: def.Command, Args: def.Args}
+			byKey[key] = group
+		}
+		group.Names = append(group.Names, name)
+	}
+
+	groups := []DuplicateGroup{}
+	for _, group := range byKey {
+		if len(group.Names) > 1 {
+			sort.Strings(group.Names)
+			groups = append(groups, *group)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].Names[0] < groups[j].Names[0]
+	})
+
+	return groups
+}
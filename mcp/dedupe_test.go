@@ -0,0 +1,186 @@
+package mcp
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func newDedupeTestManager(t *testing.T) *Manager {
+	t.Helper()
+	return &Manager{
+		configPath: filepath.Join(t.TempDir(), "config.json"),
+	}
+}
+
+// TestAddServer_DetectsDuplicate tests that adding the same command under a new name is rejected
+func TestAddServer_DetectsDuplicate(t *testing.T) {
+	manager := newDedupeTestManager(t)
+
+	if err := manager.AddServer(Server{Name: "github", Command: "npx", Args: []string{"-y", "gh"}}); err != nil {
+		t.Fatalf("AddServer() failed: %v", err)
+	}
+
+	err := manager.AddServer(Server{Name: "gh", Command: "npx", Args: []string{"-y", "gh"}})
+	var dupErr *DuplicateServerError
+	if !errors.As(err, &dupErr) {
+		t.Fatalf("AddServer() error = %v, want *DuplicateServerError", err)
+	}
+	if dupErr.Name != "gh" || dupErr.Existing != "github" {
+		t.Errorf("DuplicateServerError = %+v, want Name=gh Existing=github", dupErr)
+	}
+
+	// Different args are not duplicates
+	if err := manager.AddServer(Server{Name: "gh-enterprise", Command: "npx", Args: []string{"-y", "gh", "--enterprise"}}); err != nil {
+		t.Errorf("AddServer() with different args failed: %v", err)
+	}
+
+	// Re-adding under the same name still overwrites
+	if err := manager.AddServer(Server{Name: "github", Command: "npx", Args: []string{"-y", "gh"}, Env: map[string]string{"TOKEN": "x"}}); err != nil {
+		t.Errorf("AddServer() with same name failed: %v", err)
+	}
+}
+
+// TestFindDuplicate tests looking up a duplicate without modifying the config
+func TestFindDuplicate(t *testing.T) {
+	manager := newDedupeTestManager(t)
+
+	if _, found, err := manager.FindDuplicate(Server{Name: "a", Command: "cmd"}); err != nil || found {
+		t.Errorf("FindDuplicate() on empty config = found %v, err %v", found, err)
+	}
+
+	manager.AddServer(Server{Name: "a", Command: "cmd"})
+
+	name, found, err := manager.FindDuplicate(Server{Name: "b", Command: "cmd"})
+	if err != nil {
+		t.Fatalf("FindDuplicate() failed: %v", err)
+	}
+	if !found || name != "a" {
+		t.Errorf("FindDuplicate() = %q, %v, want a, true", name, found)
+	}
+}
+
+// TestMergeServer tests merging a new server into an existing duplicate
+func TestMergeServer(t *testing.T) {
+	manager := newDedupeTestManager(t)
+
+	manager.AddServer(Server{
+		Name:    "dd",
+		Command: "npx",
+		Args:    []string{"datadog"},
+		Env:     map[string]string{"DD_API_KEY": "old", "DD_SITE": "datadoghq.com"},
+	})
+
+	err := manager.MergeServer("dd", Server{
+		Name:    "datadog",
+		Command: "npx",
+		Args:    []string{"datadog"},
+		Env:     map[string]string{"DD_API_KEY": "new", "DD_APP_KEY": "app", "DD_SITE": ""},
+	})
+	if err != nil {
+		t.Fatalf("MergeServer() failed: %v", err)
+	}
+
+	config, err := manager.loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() failed: %v", err)
+	}
+	if _, ok := config.McpServers["dd"]; ok {
+		t.Error("Old entry should have been renamed")
+	}
+
+	want := map[string]string{"DD_API_KEY": "new", "DD_APP_KEY": "app", "DD_SITE": "datadoghq.com"}
+	if got := config.McpServers["datadog"].Env; !reflect.DeepEqual(got, want) {
+		t.Errorf("Merged env = %v, want %v", got, want)
+	}
+
+	if err := manager.MergeServer("missing", Server{Name: "x"}); err == nil {
+		t.Error("MergeServer() into missing server should return error")
+	}
+}
+
+// TestRenameServer tests renaming a server
+func TestRenameServer(t *testing.T) {
+	manager := newDedupeTestManager(t)
+	manager.AddServer(Server{Name: "a", Command: "cmd-a"})
+	manager.AddServer(Server{Name: "b", Command: "cmd-b"})
+
+	if err := manager.RenameServer("a", "b"); err == nil {
+		t.Error("RenameServer() onto existing name should return error")
+	}
+	if err := manager.RenameServer("missing", "c"); err == nil {
+		t.Error("RenameServer() of missing server should return error")
+	}
+	if err := manager.RenameServer("a", "c"); err != nil {
+		t.Fatalf("RenameServer() failed: %v", err)
+	}
+
+	config, _ := manager.loadConfig()
+	if _, ok := config.McpServers["a"]; ok {
+		t.Error("Old name should be removed")
+	}
+	if config.McpServers["c"].Command != "cmd-a" {
+		t.Errorf("Renamed server command = %q, want cmd-a", config.McpServers["c"].Command)
+	}
+}
+
+// TestDedupe tests collapsing duplicates in an existing config
+func TestDedupe(t *testing.T) {
+	manager := newDedupeTestManager(t)
+
+	// Write duplicates directly, as older configs may already contain them
+	err := manager.saveConfig(&Config{McpServers: map[string]ServerDef{
+		"github":    {Command: "npx", Args: []string{"gh"}, Env: map[string]string{"TOKEN": ""}},
+		"gh":        {Command: "npx", Args: []string{"gh"}, Env: map[string]string{"TOKEN": "secret", "HOST": "github.com"}},
+		"gh-copy":   {Command: "npx", Args: []string{"gh"}},
+		"postgres":  {Command: "npx", Args: []string{"pg"}},
+		"unrelated": {Command: "python", Args: []string{"gh"}},
+	}})
+	if err != nil {
+		t.Fatalf("saveConfig() failed: %v", err)
+	}
+
+	groups, err := manager.FindDuplicates()
+	if err != nil {
+		t.Fatalf("FindDuplicates() failed: %v", err)
+	}
+	if len(groups) != 1 || !reflect.DeepEqual(groups[0].Names, []string{"gh", "gh-copy", "github"}) {
+		t.Fatalf("FindDuplicates() = %+v, want one group [gh gh-copy github]", groups)
+	}
+
+	merged, err := manager.Dedupe()
+	if err != nil {
+		t.Fatalf("Dedupe() failed: %v", err)
+	}
+	if len(merged) != 1 {
+		t.Errorf("Dedupe() merged %d groups, want 1", len(merged))
+	}
+
+	config, _ := manager.loadConfig()
+	if len(config.McpServers) != 3 {
+		t.Errorf("Config has %d servers after dedupe, want 3", len(config.McpServers))
+	}
+	want := map[string]string{"TOKEN": "secret", "HOST": "github.com"}
+	if got := config.McpServers["gh"].Env; !reflect.DeepEqual(got, want) {
+		t.Errorf("Kept env = %v, want %v", got, want)
+	}
+
+	// A second pass finds nothing
+	if groups, _ := manager.FindDuplicates(); len(groups) != 0 {
+		t.Errorf("FindDuplicates() after dedupe = %+v, want none", groups)
+	}
+}
+
+// TestDedupe_NoConfig tests dedupe when no config file exists
+func TestDedupe_NoConfig(t *testing.T) {
+	manager := newDedupeTestManager(t)
+
+	groups, err := manager.Dedupe()
+	if err != nil {
+		t.Fatalf("Dedupe() failed: %v", err)
+	}
+	if len(groups) != 0 {
+		t.Errorf("Dedupe() = %+v, want none", groups)
+	}
+}
@@ -0,0 +1,229 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"boatman/persist"
+)
+
+// SyncResult reports what an import or export between MCP configs did.
+// Servers already in the target are never overwritten.
+type SyncResult struct {
+	Added     []string `json:"added"`
+	Unchanged []string `json:"unchanged"` // Already in the target with the same definition
+	// Conflicts are in the target under the same name with a different
+	// definition, and were left as they are
+	Conflicts []string `json:"conflicts"`
+	// Duplicates run the same command as a target server with another name
+	Duplicates []string `json:"duplicates"`
+	// Unsupported entries have no command, e.g. remote servers
+	Unsupported []string `json:"unsupported"`
+}
+
+// ClaudeDesktopConfigPath returns where Claude Desktop keeps its config:
+// ~/Library/Application Support/Claude on macOS, %APPDATA%\Claude on
+// Windows and ~/.config/Claude on Linux
+func ClaudeDesktopConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "Claude", "claude_desktop_config.json"), nil
+}
+
+// ImportFromClaudeDesktop adds the servers configured in Claude Desktop
+// that aren't configured here yet
+func (m *Manager) ImportFromClaudeDesktop() (*SyncResult, error) {
+	path, err := m.desktopPath()
+	if err != nil {
+		return nil, err
+	}
+	desktop, err := readDesktopConfig(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("no Claude Desktop config found at %s", path)
+		}
+		return nil, err
+	}
+	servers, unsupported, err := desktopServers(desktop)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *SyncResult
+	err = m.updateConfig(func(config *Config, exists bool) (bool, error) {
+		result = mergeServers(config.McpServers, servers)
+		result.Unsupported = unsupported
+		return len(result.Added) > 0, nil
+	})
+	return result, err
+}
+
+// ExportToClaudeDesktop adds the servers configured here to Claude Desktop's
+// config, keeping its other settings and servers as they are
+func (m *Manager) ExportToClaudeDesktop() (*SyncResult, error) {
+	config, err := m.loadConfig()
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if config == nil {
+		config = &Config{}
+	}
+
+	path, err := m.desktopPath()
+	if err != nil {
+		return nil, err
+	}
+	desktop, err := readDesktopConfig(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if desktop == nil {
+		desktop = make(map[string]json.RawMessage)
+	}
+	existing, unsupported, err := desktopServers(desktop)
+	if err != nil {
+		return nil, err
+	}
+
+	// Entries Claude Desktop has that can't be read as a command still take their name
+	targets := make(map[string]ServerDef, len(existing)+len(unsupported))
+	for name, def := range existing {
+		targets[name] = def
+	}
+	for _, name := range unsupported {
+		targets[name] = ServerDef{}
+	}
+
+	result := mergeServers(targets, config.McpServers)
+	if len(result.Added) == 0 {
+		return result, nil
+	}
+
+	// Add the new entries to the raw servers so fields this package doesn't
+	// know about survive
+	var rawServers map[string]json.RawMessage
+	if raw, ok := desktop["mcpServers"]; ok {
+		if err := json.Unmarshal(raw, &rawServers); err != nil {
+			return nil, fmt.Errorf("invalid mcpServers in %s: %w", path, err)
+		}
+	}
+	if rawServers == nil {
+		rawServers = make(map[string]json.RawMessage)
+	}
+	for _, name := range result.Added {
+		raw, err := json.Marshal(config.McpServers[name])
+		if err != nil {
+			return nil, err
+		}
+		rawServers[name] = raw
+	}
+	raw, err := json.Marshal(rawServers)
+	if err != nil {
+		return nil, err
+	}
+	desktop["mcpServers"] = raw
+
+	return result, writeDesktopConfig(path, desktop)
+}
+
+// desktopPath returns the Claude Desktop config this manager syncs with
+func (m *Manager) desktopPath() (string, error) {
+	if m.desktopConfigPath != "" {
+		return m.desktopConfigPath, nil
+	}
+	return ClaudeDesktopConfigPath()
+}
+
+// mergeServers adds the servers from source that target doesn't have by name
+// or by command, reporting what happened to each
+func mergeServers(target, source map[string]ServerDef) *SyncResult {
+	result := &SyncResult{
+		Added:       []string{},
+		Unchanged:   []string{},
+		Conflicts:   []string{},
+		Duplicates:  []string{},
+		Unsupported: []string{},
+	}
+
+	names := make([]string, 0, len(source))
+	for name := range source {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		def := source[name]
+		if existing, ok := target[name]; ok {
+			if sameServerDef(existing, def) {
+				result.Unchanged = append(result.Unchanged, name)
+			} else {
+				result.Conflicts = append(result.Conflicts, name)
+			}
+			continue
+		}
+		server := Server{Name: name, Command: def.Command, Args: def.Args}
+		if _, found := findDuplicate(&Config{McpServers: target}, server); found {
+			result.Duplicates = append(result.Duplicates, name)
+			continue
+		}
+		target[name] = def
+		result.Added = append(result.Added, name)
+	}
+	return result
+}
+
+// readDesktopConfig reads Claude Desktop's config, keeping every top-level
+// setting so it can be written back unchanged
+func readDesktopConfig(path string) (map[string]json.RawMessage, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var desktop map[string]json.RawMessage
+	if err := json.Unmarshal(data, &desktop); err != nil {
+		return nil, fmt.Errorf("invalid Claude Desktop config %s: %w", path, err)
+	}
+	if desktop == nil {
+		desktop = make(map[string]json.RawMessage)
+	}
+	return desktop, nil
+}
+
+// desktopServers returns the command servers in a Claude Desktop config and
+// the names of entries without a command
+func desktopServers(desktop map[string]json.RawMessage) (map[string]ServerDef, []string, error) {
+	servers := make(map[string]ServerDef)
+	unsupported := []string{}
+	raw, ok := desktop["mcpServers"]
+	if !ok {
+		return servers, unsupported, nil
+	}
+
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, nil, fmt.Errorf("invalid mcpServers in Claude Desktop config: %w", err)
+	}
+	for name, entry := range entries {
+		var def ServerDef
+		if err := json.Unmarshal(entry, &def); err != nil || def.Command == "" {
+			unsupported = append(unsupported, name)
+			continue
+		}
+		servers[name] = def
+	}
+	sort.Strings(unsupported)
+	return servers, unsupported, nil
+}
+
+// writeDesktopConfig writes Claude Desktop's config, creating its directory if needed
+func writeDesktopConfig(path string, desktop map[string]json.RawMessage) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return persist.WriteJSON(path, desktop, 0644)
+}
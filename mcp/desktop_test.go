@@ -0,0 +1,161 @@
+package mcp
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// setupDesktopManager returns a manager with its own config and a Claude
+// Desktop config holding desktopJSON, if not empty
+func setupDesktopManager(t *testing.T, desktopJSON string) *Manager {
+	t.Helper()
+	dir := t.TempDir()
+	m := &Manager{
+		configPath:        filepath.Join(dir, "mcp.json"),
+		desktopConfigPath: filepath.Join(dir, "Claude", "claude_desktop_config.json"),
+	}
+	if desktopJSON != "" {
+		if err := os.MkdirAll(filepath.Dir(m.desktopConfigPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(m.desktopConfigPath, []byte(desktopJSON), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return m
+}
+
+const testDesktopConfig = `{
+  "globalShortcut": "Ctrl+Space",
+  "mcpServers": {
+    "filesystem": {"command": "npx", "args": ["-y", "@modelcontextprotocol/server-filesystem", "/tmp"]},
+    "github": {"command": "npx", "args": ["-y", "@modelcontextprotocol/server-github"], "env": {"GITHUB_TOKEN": "desktop"}},
+    "fs-copy": {"command": "uvx", "args": ["mcp-server-fetch"]},
+    "remote": {"url": "https://example.com/mcp"}
+  }
+}`
+
+func TestImportFromClaudeDesktop(t *testing.T) {
+	m := setupDesktopManager(t, testDesktopConfig)
+	addTestServers(t, m,
+		Server{Name: "github", Command: "npx", Args: []string{"-y", "@modelcontextprotocol/server-github"}, Env: map[string]string{"GITHUB_TOKEN": "mine"}},
+		Server{Name: "fetch", Command: "uvx", Args: []string{"mcp-server-fetch"}},
+	)
+
+	result, err := m.ImportFromClaudeDesktop()
+	if err != nil {
+		t.Fatalf("ImportFromClaudeDesktop failed: %v", err)
+	}
+	want := &SyncResult{
+		Added:       []string{"filesystem"},
+		Unchanged:   []string{},
+		Conflicts:   []string{"github"},
+		Duplicates:  []string{"fs-copy"},
+		Unsupported: []string{"remote"},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Unexpected result %+v, want %+v", result, want)
+	}
+
+	// The conflicting server keeps its own settings
+	config, err := m.loadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config.McpServers) != 3 || config.McpServers["github"].Env["GITHUB_TOKEN"] != "mine" {
+		t.Errorf("Unexpected servers after import: %+v", config.McpServers)
+	}
+
+	// Importing again changes nothing
+	result, err = m.ImportFromClaudeDesktop()
+	if err != nil {
+		t.Fatalf("Second import failed: %v", err)
+	}
+	if len(result.Added) != 0 || !reflect.DeepEqual(result.Unchanged, []string{"filesystem"}) {
+		t.Errorf("Expected nothing new on the second import, got %+v", result)
+	}
+}
+
+func TestImportFromClaudeDesktop_Missing(t *testing.T) {
+	m := setupDesktopManager(t, "")
+	if _, err := m.ImportFromClaudeDesktop(); err == nil || !strings.Contains(err.Error(), "no Claude Desktop config") {
+		t.Errorf("Expected a missing config error, got %v", err)
+	}
+}
+
+func TestExportToClaudeDesktop(t *testing.T) {
+	m := setupDesktopManager(t, testDesktopConfig)
+	addTestServers(t, m,
+		Server{Name: "memory", Command: "npx", Args: []string{"-y", "@modelcontextprotocol/server-memory"}},
+		Server{Name: "github", Command: "npx", Args: []string{"-y", "@modelcontextprotocol/server-github"}, Env: map[string]string{"GITHUB_TOKEN": "mine"}},
+		Server{Name: "remote", Command: "remote-bridge"},
+	)
+
+	result, err := m.ExportToClaudeDesktop()
+	if err != nil {
+		t.Fatalf("ExportToClaudeDesktop failed: %v", err)
+	}
+	if !reflect.DeepEqual(result.Added, []string{"memory"}) || !reflect.DeepEqual(result.Conflicts, []string{"github", "remote"}) {
+		t.Errorf("Unexpected result %+v", result)
+	}
+
+	data, err := os.ReadFile(m.desktopConfigPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var desktop struct {
+		GlobalShortcut string                            `json:"globalShortcut"`
+		McpServers     map[string]map[string]interface{} `json:"mcpServers"`
+	}
+	if err := json.Unmarshal(data, &desktop); err != nil {
+		t.Fatalf("Exported config is invalid: %v", err)
+	}
+	if desktop.GlobalShortcut != "Ctrl+Space" {
+		t.Error("Expected Claude Desktop's other settings to be kept")
+	}
+	if len(desktop.McpServers) != 5 || desktop.McpServers["memory"]["command"] != "npx" {
+		t.Errorf("Unexpected servers after export: %v", desktop.McpServers)
+	}
+	if desktop.McpServers["remote"]["url"] != "https://example.com/mcp" {
+		t.Error("Expected the remote server to be left as it was")
+	}
+	if env, _ := desktop.McpServers["github"]["env"].(map[string]interface{}); env["GITHUB_TOKEN"] != "desktop" {
+		t.Errorf("Expected the conflicting server to keep Claude Desktop's settings, got %v", env)
+	}
+}
+
+func TestExportToClaudeDesktop_CreatesConfig(t *testing.T) {
+	m := setupDesktopManager(t, "")
+	addTestServers(t, m, Server{Name: "memory", Command: "npx", Args: []string{"-y", "@modelcontextprotocol/server-memory"}})
+
+	result, err := m.ExportToClaudeDesktop()
+	if err != nil {
+		t.Fatalf("ExportToClaudeDesktop failed: %v", err)
+	}
+	if !reflect.DeepEqual(result.Added, []string{"memory"}) {
+		t.Errorf("Expected memory to be added, got %+v", result)
+	}
+
+	other := &Manager{configPath: filepath.Join(t.TempDir(), "mcp.json"), desktopConfigPath: m.desktopConfigPath}
+	if _, err := other.ImportFromClaudeDesktop(); err != nil {
+		t.Fatalf("Importing the exported config failed: %v", err)
+	}
+	servers, err := other.GetServers()
+	if err != nil || len(servers) != 1 || servers[0].Name != "memory" {
+		t.Errorf("Expected memory to round-trip, got %+v (%v)", servers, err)
+	}
+}
+
+// addTestServers adds servers to a manager's config, failing the test on error
+func addTestServers(t *testing.T, m *Manager, servers ...Server) {
+	t.Helper()
+	for _, server := range servers {
+		if err := m.AddServer(server); err != nil {
+			t.Fatalf("AddServer(%s) failed: %v", server.Name, err)
+		}
+	}
+}
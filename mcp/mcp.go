@@ -4,6 +4,10 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
+
+	"boatman/persist"
 )
 
 // Server represents an MCP server configuration
@@ -14,6 +18,8 @@ type Server struct {
 	Args        []string          `json:"args,omitempty"`
 	Env         map[string]string `json:"env,omitempty"`
 	Enabled     bool              `json:"enabled"`
+	// EnvVars describes the environment a preset needs; only set on presets
+	EnvVars []EnvVar `json:"envVars,omitempty"`
 }
 
 // Config represents the MCP configuration file structure
@@ -31,6 +37,20 @@ type ServerDef struct {
 // Manager manages MCP server configurations
 type Manager struct {
 	configPath string
+	// desktopConfigPath overrides where Claude Desktop's config is looked for
+	desktopConfigPath string
+
+	// clients are servers launched for direct tool calls, reused between calls
+	clientsMu sync.Mutex
+	clients   map[string]*Client
+
+	// supervised are servers started with StartServer, kept running until stopped
+	supervised map[string]*supervisor
+	onStatus   func(ServerStatus)
+
+	// Supervision timing; zero uses the defaults
+	healthInterval time.Duration
+	restartDelay   time.Duration
 }
 
 // NewManager creates a new MCP manager
@@ -72,36 +92,33 @@ func (m *Manager) GetServers() ([]Server, error) {
 	return servers, nil
 }
 
-// AddServer adds a new MCP server
+// AddServer adds a new MCP server. If another entry already runs the same
+// command and args, a *DuplicateServerError is returned so the caller can
+// merge or rename instead.
 func (m *Manager) AddServer(server Server) error {
-	config, err := m.loadConfig()
-	if err != nil && !os.IsNotExist(err) {
-		return err
-	}
-	if config == nil {
-		config = &Config{
-			McpServers: make(map[string]ServerDef),
+	return m.updateConfig(func(config *Config, exists bool) (bool, error) {
+		if existing, found := findDuplicate(config, server); found {
+			return false, &DuplicateServerError{Name: server.Name, Existing: existing}
 		}
-	}
 
-	config.McpServers[server.Name] = ServerDef{
-		Command: server.Command,
-		Args:    server.Args,
-		Env:     server.Env,
-	}
-
-	return m.saveConfig(config)
+		config.McpServers[server.Name] = ServerDef{
+			Command: server.Command,
+			Args:    server.Args,
+			Env:     server.Env,
+		}
+		return true, nil
+	})
 }
 
 // RemoveServer removes an MCP server
 func (m *Manager) RemoveServer(name string) error {
-	config, err := m.loadConfig()
-	if err != nil {
-		return err
-	}
-
-	delete(config.McpServers, name)
-	return m.saveConfig(config)
+	return m.updateConfig(func(config *Config, exists bool) (bool, error) {
+		if !exists {
+			return false, os.ErrNotExist
+		}
+		delete(config.McpServers, name)
+		return true, nil
+	})
 }
 
 // UpdateServer updates an MCP server configuration
@@ -132,12 +149,58 @@ func (m *Manager) saveConfig(config *Config) error {
 		return err
 	}
 
-	data, err := json.MarshalIndent(config, "", "  ")
-	if err != nil {
+	return persist.WriteJSON(m.configPath, config, 0644)
+}
+
+// updateConfig loads the MCP configuration file, applies change, and saves
+// it if change reports it changed, holding the file's lock throughout so
+// a concurrent change isn't lost. A missing file is passed to change as an
+// empty config, with exists false.
+func (m *Manager) updateConfig(change func(config *Config, exists bool) (bool, error)) error {
+	dir := filepath.Dir(m.configPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
 		return err
 	}
 
-	return os.WriteFile(m.configPath, data, 0644)
+	return persist.Update(m.configPath, 0644, func(old []byte) ([]byte, error) {
+		config := &Config{}
+		if old != nil {
+			if err := json.Unmarshal(old, config); err != nil {
+				return nil, err
+			}
+		}
+		if config.McpServers == nil {
+			config.McpServers = make(map[string]ServerDef)
+		}
+
+		changed, err := change(config, old != nil)
+		if err != nil || !changed {
+			return nil, err
+		}
+		return json.MarshalIndent(config, "", "  ")
+	})
+}
+
+// WriteConfigSubset writes a config file at path containing only the named servers.
+// Names that are not configured are ignored.
+func (m *Manager) WriteConfigSubset(names []string, path string) error {
+	config, err := m.loadConfig()
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	subset := &Config{
+		McpServers: make(map[string]ServerDef),
+	}
+	if config != nil {
+		for _, name := range names {
+			if def, ok := config.McpServers[name]; ok {
+				subset.McpServers[name] = def
+			}
+		}
+	}
+
+	return (&Manager{configPath: path}).saveConfig(subset)
 }
 
 // GetConfigPath returns the path to the MCP config file
@@ -171,7 +234,17 @@ func GetPresetServers() []Server {
 			Description: "GitHub integration",
 			Command:     "npx",
 			Args:        []string{"-y", "@anthropic/mcp-server-github"},
-			Enabled:     false,
+			EnvVars: []EnvVar{
+				{
+					Name:        "GITHUB_PERSONAL_ACCESS_TOKEN",
+					Description: "GitHub personal access token (classic or fine-grained)",
+					Required:    true,
+					Pattern:     `^(gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})$`,
+					Example:     "ghp_...",
+					Secret:      true,
+				},
+			},
+			Enabled: false,
 		},
 		{
 			Name:        "postgres",
@@ -190,6 +263,28 @@ func GetPresetServers() []Server {
 				"DD_APP_KEY": "",
 				"DD_SITE":    "datadoghq.com",
 			},
+			EnvVars: []EnvVar{
+				{
+					Name:        "DD_API_KEY",
+					Description: "Datadog API key (Organization Settings > API Keys)",
+					Required:    true,
+					Pattern:     `^[0-9a-f]{32}$`,
+					Secret:      true,
+				},
+				{
+					Name:        "DD_APP_KEY",
+					Description: "Datadog application key (Organization Settings > Application Keys)",
+					Required:    true,
+					Pattern:     `^[0-9a-f]{40}$`,
+					Secret:      true,
+				},
+				{
+					Name:        "DD_SITE",
+					Description: "Datadog site your organization is hosted on",
+					Pattern:     `^(datadoghq\.com|us3\.datadoghq\.com|us5\.datadoghq\.com|datadoghq\.eu|ap1\.datadoghq\.com|ddog-gov\.com)$`,
+					Example:     "datadoghq.com",
+				},
+			},
 			Enabled: false,
 		},
 		{
@@ -200,6 +295,15 @@ func GetPresetServers() []Server {
 			Env: map[string]string{
 				"BUGSNAG_API_KEY": "",
 			},
+			EnvVars: []EnvVar{
+				{
+					Name:        "BUGSNAG_API_KEY",
+					Description: "Bugsnag personal auth token (My Account > Personal auth tokens)",
+					Required:    true,
+					Pattern:     `^[0-9a-f-]{32,36}$`,
+					Secret:      true,
+				},
+			},
 			Enabled: false,
 		},
 		{
@@ -210,6 +314,16 @@ func GetPresetServers() []Server {
 			Env: map[string]string{
 				"LINEAR_API_KEY": "",
 			},
+			EnvVars: []EnvVar{
+				{
+					Name:        "LINEAR_API_KEY",
+					Description: "Linear personal API key (Settings > API)",
+					Required:    true,
+					Pattern:     `^lin_api_[A-Za-z0-9]+$`,
+					Example:     "lin_api_...",
+					Secret:      true,
+				},
+			},
 			Enabled: false,
 		},
 		{
@@ -221,6 +335,23 @@ func GetPresetServers() []Server {
 				"SLACK_BOT_TOKEN": "",
 				"SLACK_TEAM_ID":   "",
 			},
+			EnvVars: []EnvVar{
+				{
+					Name:        "SLACK_BOT_TOKEN",
+					Description: "Slack bot user OAuth token",
+					Required:    true,
+					Pattern:     `^xoxb-[A-Za-z0-9-]+$`,
+					Example:     "xoxb-...",
+					Secret:      true,
+				},
+				{
+					Name:        "SLACK_TEAM_ID",
+					Description: "Slack workspace (team) ID",
+					Required:    true,
+					Pattern:     `^T[A-Z0-9]+$`,
+					Example:     "T01234567",
+				},
+			},
 			Enabled: false,
 		},
 	}
@@ -857,6 +857,35 @@ func TestConcurrentAccess(t *testing.T) {
 	}
 }
 
+// TestConcurrentAdds tests that servers added at once, e.g. from two
+// windows, are all kept
+func TestConcurrentAdds(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "concurrent_config.json")
+
+	done := make(chan bool, 10)
+	for i := 0; i < 10; i++ {
+		go func(i int) {
+			manager := &Manager{configPath: configPath}
+			server := Server{Name: fmt.Sprintf("server-%d", i), Command: fmt.Sprintf("cmd-%d", i)}
+			if err := manager.AddServer(server); err != nil {
+				t.Errorf("Concurrent AddServer() failed: %v", err)
+			}
+			done <- true
+		}(i)
+	}
+	for i := 0; i < 10; i++ {
+		<-done
+	}
+
+	servers, err := (&Manager{configPath: configPath}).GetServers()
+	if err != nil {
+		t.Fatalf("GetServers() failed: %v", err)
+	}
+	if len(servers) != 10 {
+		t.Errorf("GetServers() returned %d servers, want 10", len(servers))
+	}
+}
+
 // TestEmptyServerFields tests handling of servers with empty optional fields
 func TestEmptyServerFields(t *testing.T) {
 	tempDir := t.TempDir()
@@ -911,7 +940,7 @@ func TestLargeConfig(t *testing.T) {
 		server := Server{
 			Name:    fmt.Sprintf("server-%d", i),
 			Command: "cmd",
-			Args:    []string{"arg1", "arg2", "arg3"},
+			Args:    []string{"arg1", "arg2", fmt.Sprintf("arg-%d", i)},
 		}
 		if err := manager.AddServer(server); err != nil {
 			t.Fatalf("AddServer() failed at index %d: %v", i, err)
@@ -968,3 +997,77 @@ func TestSpecialCharactersInServerData(t *testing.T) {
 		t.Errorf("Env[JSON_STRING] not preserved correctly: %v", s.Env["JSON_STRING"])
 	}
 }
+
+// TestWriteConfigSubset tests writing a filtered config with only selected servers
+func TestWriteConfigSubset(t *testing.T) {
+	tempDir := t.TempDir()
+	manager := &Manager{
+		configPath: filepath.Join(tempDir, "config.json"),
+	}
+
+	for _, name := range []string{"alpha", "beta", "gamma"} {
+		if err := manager.AddServer(Server{Name: name, Command: name + "-cmd"}); err != nil {
+			t.Fatalf("AddServer(%s) failed: %v", name, err)
+		}
+	}
+
+	subsetPath := filepath.Join(tempDir, "subset", "config.json")
+	if err := manager.WriteConfigSubset([]string{"gamma", "alpha", "missing"}, subsetPath); err != nil {
+		t.Fatalf("WriteConfigSubset() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(subsetPath)
+	if err != nil {
+		t.Fatalf("Failed to read subset config: %v", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatalf("Failed to parse subset config: %v", err)
+	}
+
+	if len(config.McpServers) != 2 {
+		t.Fatalf("Subset has %d servers, want 2", len(config.McpServers))
+	}
+	if config.McpServers["alpha"].Command != "alpha-cmd" {
+		t.Errorf("alpha command = %q, want %q", config.McpServers["alpha"].Command, "alpha-cmd")
+	}
+	if _, ok := config.McpServers["beta"]; ok {
+		t.Error("Subset should not contain beta")
+	}
+
+	// The original config is left untouched
+	servers, err := manager.GetServers()
+	if err != nil {
+		t.Fatalf("GetServers() failed: %v", err)
+	}
+	if len(servers) != 3 {
+		t.Errorf("Original config has %d servers, want 3", len(servers))
+	}
+}
+
+// TestWriteConfigSubset_NoConfig tests writing a subset when no config exists
+func TestWriteConfigSubset_NoConfig(t *testing.T) {
+	tempDir := t.TempDir()
+	manager := &Manager{
+		configPath: filepath.Join(tempDir, "config.json"),
+	}
+
+	subsetPath := filepath.Join(tempDir, "subset.json")
+	if err := manager.WriteConfigSubset([]string{"alpha"}, subsetPath); err != nil {
+		t.Fatalf("WriteConfigSubset() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(subsetPath)
+	if err != nil {
+		t.Fatalf("Failed to read subset config: %v", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		t.Fatalf("Failed to parse subset config: %v", err)
+	}
+	if len(config.McpServers) != 0 {
+		t.Errorf("Subset has %d servers, want 0", len(config.McpServers))
+	}
+}
@@ -0,0 +1,125 @@
+package mcp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// EnvVar describes an environment variable a preset server needs
+type EnvVar struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required"`
+	// Pattern is a regular expression a non-empty value must match
+	Pattern string `json:"pattern,omitempty"`
+	Example string `json:"example,omitempty"`
+	// Secret marks values the UI should mask
+	Secret bool `json:"secret,omitempty"`
+}
+
+// EnvVarProblem describes why a single environment value was rejected
+type EnvVarProblem struct {
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
+
+// PresetValidationError is returned when a preset's environment values are invalid
+type PresetValidationError struct {
+	Preset   string
+	Problems []EnvVarProblem
+}
+
+func (e *PresetValidationError) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		msgs[i] = p.Name + ": " + p.Message
+	}
+	return fmt.Sprintf("invalid settings for preset %q: %s", e.Preset, strings.Join(msgs, "; "))
+}
+
+// GetPreset returns the preset server with the given name
+func GetPreset(name string) (Server, bool) {
+	for _, preset := range GetPresetServers() {
+		if preset.Name == name {
+			return preset, true
+		}
+	}
+	return Server{}, false
+}
+
+// ValidatePresetEnv checks env against the preset's declared variables and
+// returns a *PresetValidationError listing every problem found
+func ValidatePresetEnv(preset Server, env map[string]string) error {
+	var problems []EnvVarProblem
+
+	for _, spec := range preset.EnvVars {
+		value := strings.TrimSpace(env[spec.Name])
+		if value == "" {
+			if spec.Required {
+				problems = append(problems, EnvVarProblem{Name: spec.Name, Message: "is required"})
+			}
+			continue
+		}
+
+		if spec.Pattern == "" {
+			continue
+		}
+		re, err := regexp.Compile(spec.Pattern)
+		if err != nil {
+			return fmt.Errorf("preset %q has invalid pattern for %s: %w", preset.Name, spec.Name, err)
+		}
+		if !re.MatchString(value) {
+			msg := "does not match the expected format"
+			if spec.Example != "" {
+				msg += fmt.Sprintf(" (e.g. %s)", spec.Example)
+			}
+			problems = append(problems, EnvVarProblem{Name: spec.Name, Message: msg})
+		}
+	}
+
+	if len(problems) > 0 {
+		return &PresetValidationError{Preset: preset.Name, Problems: problems}
+	}
+	return nil
+}
+
+// InstantiatePreset validates envValues against the named preset and, if they
+// are valid, adds the preset to the MCP config. Preset defaults are used for
+// any values left empty.
+func (m *Manager) InstantiatePreset(name string, envValues map[string]string) (Server, error) {
+	preset, ok := GetPreset(name)
+	if !ok {
+		return Server{}, fmt.Errorf("unknown preset: %s", name)
+	}
+
+	env := make(map[string]string, len(preset.Env)+len(envValues))
+	for k, v := range preset.Env {
+		env[k] = v
+	}
+	for k, v := range envValues {
+		if v = strings.TrimSpace(v); v != "" {
+			env[k] = v
+		}
+	}
+
+	if err := ValidatePresetEnv(preset, env); err != nil {
+		return Server{}, err
+	}
+
+	server := Server{
+		Name:        preset.Name,
+		Description: preset.Description,
+		Command:     preset.Command,
+		Args:        preset.Args,
+		Enabled:     true,
+	}
+	if len(env) > 0 {
+		server.Env = env
+	}
+
+	if err := m.AddServer(server); err != nil {
+		return Server{}, err
+	}
+	return server, nil
+}
@@ -0,0 +1,130 @@
+package mcp
+
+import (
+	"errors"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestPresetEnvVars tests that preset metadata is consistent
+func TestPresetEnvVars(t *testing.T) {
+	for _, preset := range GetPresetServers() {
+		for _, spec := range preset.EnvVars {
+			if spec.Name == "" {
+				t.Errorf("Preset %s has env var with empty name", preset.Name)
+			}
+			if spec.Description == "" {
+				t.Errorf("Preset %s env var %s has no description", preset.Name, spec.Name)
+			}
+			if spec.Pattern == "" {
+				continue
+			}
+			re, err := regexp.Compile(spec.Pattern)
+			if err != nil {
+				t.Errorf("Preset %s env var %s has invalid pattern: %v", preset.Name, spec.Name, err)
+				continue
+			}
+
+			// Defaults must satisfy their own patterns
+			if def := preset.Env[spec.Name]; def != "" && !re.MatchString(def) {
+				t.Errorf("Preset %s default %q for %s does not match its pattern", preset.Name, def, spec.Name)
+			}
+		}
+	}
+}
+
+// TestValidatePresetEnv tests validation of required values and patterns
+func TestValidatePresetEnv(t *testing.T) {
+	preset, ok := GetPreset("github")
+	if !ok {
+		t.Fatal("GetPreset(github) not found")
+	}
+
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"classic token", "ghp_" + strings.Repeat("a", 36), false},
+		{"fine-grained token", "github_pat_" + strings.Repeat("B", 82), false},
+		{"missing", "", true},
+		{"whitespace", "   ", true},
+		{"wrong format", "not-a-token", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePresetEnv(preset, map[string]string{"GITHUB_PERSONAL_ACCESS_TOKEN": tt.value})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePresetEnv() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestInstantiatePreset tests creating a server from a preset
+func TestInstantiatePreset(t *testing.T) {
+	manager := &Manager{
+		configPath: filepath.Join(t.TempDir(), "config.json"),
+	}
+
+	server, err := manager.InstantiatePreset("datadog", map[string]string{
+		"DD_API_KEY": strings.Repeat("a", 32),
+		"DD_APP_KEY": strings.Repeat("b", 40),
+	})
+	if err != nil {
+		t.Fatalf("InstantiatePreset() failed: %v", err)
+	}
+	if server.Env["DD_SITE"] != "datadoghq.com" {
+		t.Errorf("DD_SITE = %q, want preset default datadoghq.com", server.Env["DD_SITE"])
+	}
+
+	config, err := manager.loadConfig()
+	if err != nil {
+		t.Fatalf("loadConfig() failed: %v", err)
+	}
+	def, ok := config.McpServers["datadog"]
+	if !ok {
+		t.Fatal("datadog server was not written to config")
+	}
+	if def.Env["DD_APP_KEY"] != strings.Repeat("b", 40) {
+		t.Errorf("DD_APP_KEY = %q, want the supplied value", def.Env["DD_APP_KEY"])
+	}
+}
+
+// TestInstantiatePreset_Invalid tests that invalid input is rejected before writing
+func TestInstantiatePreset_Invalid(t *testing.T) {
+	manager := &Manager{
+		configPath: filepath.Join(t.TempDir(), "config.json"),
+	}
+
+	_, err := manager.InstantiatePreset("datadog", map[string]string{
+		"DD_API_KEY": "short",
+		"DD_SITE":    "example.com",
+	})
+
+	var validationErr *PresetValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("InstantiatePreset() error = %v, want *PresetValidationError", err)
+	}
+
+	problems := make(map[string]bool)
+	for _, p := range validationErr.Problems {
+		problems[p.Name] = true
+	}
+	for _, name := range []string{"DD_API_KEY", "DD_APP_KEY", "DD_SITE"} {
+		if !problems[name] {
+			t.Errorf("Expected a problem for %s, got %+v", name, validationErr.Problems)
+		}
+	}
+
+	if _, err := manager.loadConfig(); err == nil {
+		t.Error("Config should not be written when validation fails")
+	}
+
+	if _, err := manager.InstantiatePreset("nope", nil); err == nil {
+		t.Error("InstantiatePreset() with unknown preset should return error")
+	}
+}
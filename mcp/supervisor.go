@@ -0,0 +1,324 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// States of a supervised server
+const (
+	StateStopped    = "stopped"
+	StateRunning    = "running"
+	StateRestarting = "restarting" // Exited or stopped answering; waiting to start again
+	StateFailed     = "failed"     // Gave up after repeated crashes
+)
+
+// Supervision defaults
+const (
+	defaultHealthInterval = 30 * time.Second
+	defaultRestartDelay   = time.Second // Doubled after each consecutive failure
+	maxRestartDelay       = 30 * time.Second
+	healthTimeout         = 10 * time.Second
+	startTimeout          = 30 * time.Second
+	// maxRestarts is how many times in a row a server may fail before it is
+	// left stopped; a server that stays up for stableRunTime starts counting again
+	maxRestarts   = 5
+	stableRunTime = time.Minute
+)
+
+// ServerStatus reports the state of a server started with StartServer
+type ServerStatus struct {
+	Name        string    `json:"name"`
+	State       string    `json:"state"`
+	PID         int       `json:"pid,omitempty"`
+	StartedAt   time.Time `json:"startedAt,omitempty"`
+	LastHealthy time.Time `json:"lastHealthy,omitempty"` // Last answered health check, or the handshake
+	Restarts    int       `json:"restarts"`
+	LastError   string    `json:"lastError,omitempty"`
+}
+
+// supervisor keeps one server running, restarting it when it crashes or
+// stops answering health checks
+type supervisor struct {
+	name string
+	def  ServerDef
+	stop chan struct{} // Closed to stop the server
+	done chan struct{} // Closed once the server has stopped for good
+
+	// status and client are guarded by the manager's clientsMu
+	status ServerStatus
+	client *Client
+}
+
+// SetStatusHandler sets the callback for state changes of supervised servers
+func (m *Manager) SetStatusHandler(handler func(ServerStatus)) {
+	m.clientsMu.Lock()
+	defer m.clientsMu.Unlock()
+	m.onStatus = handler
+}
+
+// StartServer launches a configured server and keeps it running until
+// StopServer, restarting it if it crashes or fails its health checks. It
+// returns once the server has completed the initialize handshake. Starting a
+// server that is already running does nothing unless its configuration changed.
+func (m *Manager) StartServer(name string) error {
+	def, err := m.serverDef(name)
+	if err != nil {
+		return err
+	}
+
+	m.clientsMu.Lock()
+	existing := m.supervised[name]
+	if existing != nil && existing.status.State != StateFailed && sameServerDef(existing.def, def) {
+		m.clientsMu.Unlock()
+		return nil
+	}
+	m.clientsMu.Unlock()
+	if existing != nil {
+		m.StopServer(name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
+	defer cancel()
+	client, err := startClient(ctx, name, def)
+	if err != nil {
+		m.setStatus(&supervisor{name: name}, ServerStatus{Name: name, State: StateFailed, LastError: err.Error()})
+		return err
+	}
+
+	sup := &supervisor{
+		name: name,
+		def:  def,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	m.clientsMu.Lock()
+	if m.supervised == nil {
+		m.supervised = make(map[string]*supervisor)
+	}
+	m.supervised[name] = sup
+	// Direct tool calls use the supervised server from now on
+	adhoc := m.clients[name]
+	delete(m.clients, name)
+	m.clientsMu.Unlock()
+	if adhoc != nil {
+		adhoc.Close()
+	}
+
+	m.setRunning(sup, client, 0)
+	go m.supervise(sup, client)
+	return nil
+}
+
+// StopServer stops a server started with StartServer
+func (m *Manager) StopServer(name string) error {
+	m.clientsMu.Lock()
+	sup := m.supervised[name]
+	delete(m.supervised, name)
+	m.clientsMu.Unlock()
+	if sup == nil {
+		return nil
+	}
+
+	close(sup.stop)
+	<-sup.done
+
+	m.clientsMu.Lock()
+	restarts := sup.status.Restarts
+	m.clientsMu.Unlock()
+	m.setStatus(sup, ServerStatus{Name: name, State: StateStopped, Restarts: restarts})
+	return nil
+}
+
+// StopServers stops every server started with StartServer
+func (m *Manager) StopServers() {
+	m.clientsMu.Lock()
+	names := make([]string, 0, len(m.supervised))
+	for name := range m.supervised {
+		names = append(names, name)
+	}
+	m.clientsMu.Unlock()
+
+	for _, name := range names {
+		m.StopServer(name)
+	}
+}
+
+// GetServerStatus returns the state of a server; servers that were never
+// started, or were stopped, report StateStopped
+func (m *Manager) GetServerStatus(name string) ServerStatus {
+	m.clientsMu.Lock()
+	defer m.clientsMu.Unlock()
+	if sup, ok := m.supervised[name]; ok {
+		return sup.status
+	}
+	return ServerStatus{Name: name, State: StateStopped}
+}
+
+// GetServerStatuses returns the state of every server started with StartServer
+func (m *Manager) GetServerStatuses() []ServerStatus {
+	m.clientsMu.Lock()
+	defer m.clientsMu.Unlock()
+	statuses := make([]ServerStatus, 0, len(m.supervised))
+	for _, sup := range m.supervised {
+		statuses = append(statuses, sup.status)
+	}
+	return statuses
+}
+
+// supervise watches a running server and restarts it with backoff until it
+// is stopped or fails too many times in a row
+func (m *Manager) supervise(sup *supervisor, client *Client) {
+	defer close(sup.done)
+
+	failures := 0
+	restarts := 0
+	for {
+		var lastErr error
+		if client != nil {
+			started := time.Now()
+			lastErr = m.watch(sup, client)
+			client.Close()
+			if lastErr == nil {
+				return // Stopped
+			}
+			if time.Since(started) >= stableRunTime {
+				failures = 0
+			}
+		}
+
+		failures++
+		if failures > maxRestarts {
+			m.setStatus(sup, ServerStatus{
+				Name:      sup.name,
+				State:     StateFailed,
+				Restarts:  restarts,
+				LastError: fmt.Sprintf("gave up after %d failures: %v", maxRestarts, lastErr),
+			})
+			return
+		}
+		m.setStatus(sup, ServerStatus{Name: sup.name, State: StateRestarting, Restarts: restarts, LastError: lastErr.Error()})
+
+		select {
+		case <-sup.stop:
+			return
+		case <-time.After(m.restartDelayFor(failures)):
+		}
+
+		client, lastErr = m.restart(sup)
+		restarts++
+		if client == nil {
+			m.setStatus(sup, ServerStatus{Name: sup.name, State: StateRestarting, Restarts: restarts, LastError: lastErr.Error()})
+			continue
+		}
+		m.setRunning(sup, client, restarts)
+	}
+}
+
+// restart launches the server again, giving up early if it is stopped meanwhile
+func (m *Manager) restart(sup *supervisor) (*Client, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), startTimeout)
+	defer cancel()
+	go func() {
+		select {
+		case <-sup.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return startClient(ctx, sup.name, sup.def)
+}
+
+// watch health-checks a running server until it exits, stops answering, or
+// is stopped, which returns nil
+func (m *Manager) watch(sup *supervisor, client *Client) error {
+	interval := m.healthInterval
+	if interval <= 0 {
+		interval = defaultHealthInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sup.stop:
+			return nil
+		case <-client.done:
+			return client.exitErr()
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), healthTimeout)
+			err := client.Ping(ctx)
+			cancel()
+			if err != nil {
+				return fmt.Errorf("health check failed: %w", err)
+			}
+			m.clientsMu.Lock()
+			sup.status.LastHealthy = time.Now()
+			m.clientsMu.Unlock()
+		}
+	}
+}
+
+// restartDelayFor returns how long to wait before restarting after failures consecutive failures
+func (m *Manager) restartDelayFor(failures int) time.Duration {
+	delay := m.restartDelay
+	if delay <= 0 {
+		delay = defaultRestartDelay
+	}
+	for i := 1; i < failures && delay < maxRestartDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRestartDelay {
+		delay = maxRestartDelay
+	}
+	return delay
+}
+
+// setRunning records a newly started server as the supervised one
+func (m *Manager) setRunning(sup *supervisor, client *Client, restarts int) {
+	now := time.Now()
+	m.setStatusClient(sup, client, ServerStatus{
+		Name:        sup.name,
+		State:       StateRunning,
+		PID:         client.cmd.Process.Pid,
+		StartedAt:   now,
+		LastHealthy: now,
+		Restarts:    restarts,
+	})
+}
+
+// setStatus records a supervised server's state and reports it
+func (m *Manager) setStatus(sup *supervisor, status ServerStatus) {
+	m.setStatusClient(sup, nil, status)
+}
+
+// setStatusClient records a supervised server's state along with the client
+// to use for tool calls, nil unless it is running, and reports the state
+func (m *Manager) setStatusClient(sup *supervisor, client *Client, status ServerStatus) {
+	m.clientsMu.Lock()
+	sup.status = status
+	sup.client = client
+	handler := m.onStatus
+	m.clientsMu.Unlock()
+
+	if handler != nil {
+		handler(status)
+	}
+}
+
+// serverDef looks up a server's definition in the config
+func (m *Manager) serverDef(name string) (ServerDef, error) {
+	config, err := m.loadConfig()
+	if err != nil && !os.IsNotExist(err) {
+		return ServerDef{}, err
+	}
+	if config != nil {
+		if def, ok := config.McpServers[name]; ok {
+			return def, nil
+		}
+	}
+	return ServerDef{}, fmt.Errorf("MCP server not found: %s", name)
+}
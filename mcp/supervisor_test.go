@@ -0,0 +1,166 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// setupSupervisedServer returns a manager for the fake server with fast
+// supervision, stopping its servers when the test ends
+func setupSupervisedServer(t *testing.T, env map[string]string) *Manager {
+	t.Helper()
+	m := setupHelperServer(t, env)
+	m.healthInterval = 20 * time.Millisecond
+	m.restartDelay = time.Millisecond
+	t.Cleanup(m.StopServers)
+	return m
+}
+
+// waitForState waits for the fake server to reach a state
+func waitForState(t *testing.T, m *Manager, state string) ServerStatus {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		status := m.GetServerStatus("fake")
+		if status.State == state {
+			return status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s, last status %+v", state, status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStartStopServer(t *testing.T) {
+	m := setupSupervisedServer(t, nil)
+
+	var mu sync.Mutex
+	var states []string
+	m.SetStatusHandler(func(status ServerStatus) {
+		mu.Lock()
+		defer mu.Unlock()
+		states = append(states, status.State)
+	})
+
+	if status := m.GetServerStatus("fake"); status.State != StateStopped {
+		t.Errorf("Expected a server that was never started to be stopped, got %s", status.State)
+	}
+
+	if err := m.StartServer("fake"); err != nil {
+		t.Fatalf("StartServer failed: %v", err)
+	}
+	status := m.GetServerStatus("fake")
+	if status.State != StateRunning || status.PID == 0 {
+		t.Fatalf("Expected a running server with a pid, got %+v", status)
+	}
+
+	// Tool calls go to the supervised server
+	if pid := callText(t, m, "pid", nil); pid != fmt.Sprint(status.PID) {
+		t.Errorf("Expected tool calls to use pid %d, got %s", status.PID, pid)
+	}
+
+	// Health checks keep succeeding
+	time.Sleep(100 * time.Millisecond)
+	if later := m.GetServerStatus("fake"); later.State != StateRunning || !later.LastHealthy.After(status.LastHealthy) {
+		t.Errorf("Expected passing health checks, got %+v", later)
+	}
+
+	// Starting again leaves the running server alone
+	if err := m.StartServer("fake"); err != nil {
+		t.Fatalf("Second StartServer failed: %v", err)
+	}
+	if again := m.GetServerStatus("fake"); again.PID != status.PID {
+		t.Errorf("Expected the same server, got pids %d and %d", status.PID, again.PID)
+	}
+
+	if err := m.StopServer("fake"); err != nil {
+		t.Fatalf("StopServer failed: %v", err)
+	}
+	if stopped := m.GetServerStatus("fake"); stopped.State != StateStopped {
+		t.Errorf("Expected the server to be stopped, got %s", stopped.State)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got := strings.Join(states, ","); got != "running,stopped" {
+		t.Errorf("Expected running then stopped, got %s", got)
+	}
+
+	if err := m.StartServer("nope"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Expected unknown server error, got %v", err)
+	}
+}
+
+func TestServerRestartsAfterCrash(t *testing.T) {
+	m := setupSupervisedServer(t, nil)
+	if err := m.StartServer("fake"); err != nil {
+		t.Fatalf("StartServer failed: %v", err)
+	}
+	first := m.GetServerStatus("fake")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := m.CallTool(ctx, "fake", "crash", nil); err == nil {
+		t.Fatal("Expected the crash to fail the call")
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		status := m.GetServerStatus("fake")
+		if status.State == StateRunning && status.PID != first.PID {
+			if status.Restarts != 1 {
+				t.Errorf("Expected 1 restart, got %d", status.Restarts)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for a restart, last status %+v", status)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got := callText(t, m, "echo", map[string]interface{}{"text": "back"}); got != "back" {
+		t.Errorf("Expected the restarted server to answer, got %q", got)
+	}
+}
+
+func TestServerFailsHealthChecks(t *testing.T) {
+	m := setupSupervisedServer(t, map[string]string{"FAIL_PING": "1"})
+	if err := m.StartServer("fake"); err != nil {
+		t.Fatalf("StartServer failed: %v", err)
+	}
+
+	status := waitForState(t, m, StateFailed)
+	if status.Restarts != maxRestarts || !strings.Contains(status.LastError, "health check failed") {
+		t.Errorf("Expected %d restarts and a health check error, got %+v", maxRestarts, status)
+	}
+
+	// A failed server can be started again
+	if err := m.StartServer("fake"); err != nil {
+		t.Fatalf("StartServer after failing failed: %v", err)
+	}
+	waitForState(t, m, StateRunning)
+}
+
+func TestRestartDelay(t *testing.T) {
+	m := &Manager{}
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{4, 8 * time.Second},
+		{10, maxRestartDelay},
+	}
+	for _, tt := range tests {
+		if got := m.restartDelayFor(tt.failures); got != tt.want {
+			t.Errorf("restartDelayFor(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
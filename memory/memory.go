@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// MaxSize bounds a project's notes, since all of them go into every prompt
+const MaxSize = 16 << 10
+
+// promptHeader introduces the notes in the system prompt
+const promptHeader = "Project memory: notes about this project kept across sessions, " +
+	"such as architecture facts and conventions. Follow them, and use the " +
+	ToolName + " tool to add durable facts worth knowing in future sessions."
+
+// Store keeps each project's notes in a Markdown file
+type Store struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// DefaultDir returns where project notes are kept
+func DefaultDir() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(homeDir, ".boatman", "memory"), nil
+}
+
+// NewStore returns a store keeping notes in dir
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir returns the directory notes are kept in
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// path returns the notes file for a project
+func (s *Store) path(projectPath string) string {
+	sum := sha256.Sum256([]byte(filepath.Clean(projectPath)))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:8])+".md")
+}
+
+// Load returns a project's notes, empty if it has none
+func (s *Store) Load(projectPath string) (string, error) {
+	data, err := os.ReadFile(s.path(projectPath))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read project memory: %w", err)
+	}
+	return string(data), nil
+}
+
+// Save replaces a project's notes. Empty notes remove them.
+func (s *Store) Save(projectPath, notes string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	notes = strings.TrimSpace(notes)
+	path := s.path(projectPath)
+	if notes == "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to clear project memory: %w", err)
+		}
+		return nil
+	}
+	if len(notes) > MaxSize {
+		return fmt.Errorf("project memory can't be over %d KB", MaxSize>>10)
+	}
+
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(notes+"\n"), 0600); err != nil {
+		return fmt.Errorf("failed to save project memory: %w", err)
+	}
+	return nil
+}
+
+// Append adds a note to the end of a project's notes as a list item
+func (s *Store) Append(projectPath, note string) error {
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return fmt.Errorf("note can't be empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.path(projectPath)
+	var size int64
+	if info, err := os.Stat(path); err == nil {
+		size = info.Size()
+	}
+	item := "- " + strings.ReplaceAll(note, "\n", "\n  ") + "\n"
+	if size+int64(len(item)) > MaxSize {
+		return fmt.Errorf("project memory is full (%d KB); ask the user to tidy it up", MaxSize>>10)
+	}
+
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open project memory: %w", err)
+	}
+	if _, err := file.WriteString(item); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to append to project memory: %w", err)
+	}
+	return file.Close()
+}
+
+// Prompt returns the system prompt addition for a project's notes, empty if
+// it has none
+func Prompt(notes string) string {
+	notes = strings.TrimSpace(notes)
+	if notes == "" {
+		return ""
+	}
+	return promptHeader + "\n\n" + notes
+}
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStoreSaveAndAppend(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	if notes, err := store.Load("/project"); err != nil || notes != "" {
+		t.Fatalf("Expected no notes for a new project, got %q, %v", notes, err)
+	}
+
+	if err := store.Save("/project", "  Uses Go modules.\n"); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := store.Append("/project/", "Run make lint\nbefore pushing"); err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+
+	notes, err := store.Load("/project")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	expected := "Uses Go modules.\n- Run make lint\n  before pushing\n"
+	if notes != expected {
+		t.Errorf("Expected notes %q, got %q", expected, notes)
+	}
+
+	// Projects don't share notes
+	if other, _ := store.Load("/other"); other != "" {
+		t.Errorf("Expected no notes for another project, got %q", other)
+	}
+
+	// Saving nothing clears them
+	if err := store.Save("/project", " "); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if notes, _ := store.Load("/project"); notes != "" {
+		t.Errorf("Expected cleared notes, got %q", notes)
+	}
+}
+
+func TestStoreLimits(t *testing.T) {
+	store := NewStore(t.TempDir())
+
+	if err := store.Append("/project", "  "); err == nil {
+		t.Error("Expected an empty note to be rejected")
+	}
+	if err := store.Save("/project", strings.Repeat("x", MaxSize+1)); err == nil {
+		t.Error("Expected notes over MaxSize to be rejected")
+	}
+
+	if err := store.Save("/project", strings.Repeat("x", MaxSize-10)); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := store.Append("/project", "one note too many"); err == nil {
+		t.Error("Expected Append to fail once the notes are full")
+	}
+}
+
+func TestPrompt(t *testing.T) {
+	if prompt := Prompt(" \n"); prompt != "" {
+		t.Errorf("Expected no prompt without notes, got %q", prompt)
+	}
+	prompt := Prompt("- Uses Go modules.\n")
+	if !strings.HasPrefix(prompt, promptHeader) || !strings.HasSuffix(prompt, "\n\n- Uses Go modules.") {
+		t.Errorf("Expected the header followed by the notes, got %q", prompt)
+	}
+}
@@ -0,0 +1,182 @@
+package memory
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ServerCommand is the argument that runs the Boatman binary as the memory MCP server
+const ServerCommand = "memory-mcp"
+
+// ServerName is the memory server's name in claude's MCP config
+const ServerName = "boatman-memory"
+
+// ToolName is the tool the model calls to add a note
+const ToolName = "remember"
+
+// QualifiedToolName is the tool's name as claude reports and permits it
+const QualifiedToolName = "mcp__" + ServerName + "__" + ToolName
+
+// protocolVersion is the MCP revision the server speaks
+const protocolVersion = "2025-06-18"
+
+// JSON-RPC error codes
+const (
+	errParse          = -32700
+	errMethodNotFound = -32601
+	errInvalidParams  = -32602
+)
+
+// request is a JSON-RPC request or notification read from claude
+type request struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// response is a JSON-RPC response written back
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// rpcError is a JSON-RPC error object
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// rememberTool describes the remember tool for tools/list
+var rememberTool = map[string]interface{}{
+	"name": ToolName,
+	"description": "Save a durable note about this project, such as an architecture fact or a convention, " +
+		"to the project memory shared by every future session. Keep notes short and specific; " +
+		"don't save anything temporary or secret.",
+	"inputSchema": map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"note": map[string]interface{}{
+				"type":        "string",
+				"description": "The fact to remember, in one or two sentences",
+			},
+		},
+		"required": []string{"note"},
+	},
+}
+
+// MCPConfig returns inline claude MCP config that runs executable as the
+// memory server for a project
+func MCPConfig(executable, dir, projectPath string) (string, error) {
+	data, err := json.Marshal(map[string]interface{}{
+		"mcpServers": map[string]interface{}{
+			ServerName: map[string]interface{}{
+				"command": executable,
+				"args":    []string{ServerCommand, "--dir", dir, "--project", projectPath},
+			},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// Serve answers MCP requests from in on out, one JSON message per line,
+// adding notes to projectPath's memory, until in is closed
+func Serve(store *Store, projectPath string, in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4<<20)
+	encoder := json.NewEncoder(out)
+
+	for scanner.Scan() {
+		var req request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			if err := encoder.Encode(response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{Code: errParse, Message: err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+		// Notifications such as notifications/initialized get no reply
+		if len(req.ID) == 0 {
+			continue
+		}
+
+		resp := response{JSONRPC: "2.0", ID: req.ID}
+		resp.Result, resp.Error = handle(store, projectPath, req)
+		if err := encoder.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// handle answers one request
+func handle(store *Store, projectPath string, req request) (interface{}, *rpcError) {
+	switch req.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"protocolVersion": protocolVersion,
+			"capabilities":    map[string]interface{}{"tools": map[string]interface{}{}},
+			"serverInfo":      map[string]interface{}{"name": ServerName, "version": "1.0.0"},
+		}, nil
+	case "ping":
+		return map[string]interface{}{}, nil
+	case "tools/list":
+		return map[string]interface{}{"tools": []interface{}{rememberTool}}, nil
+	case "tools/call":
+		var params struct {
+			Name      string `json:"name"`
+			Arguments struct {
+				Note string `json:"note"`
+			} `json:"arguments"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, &rpcError{Code: errInvalidParams, Message: err.Error()}
+		}
+		if params.Name != ToolName {
+			return nil, &rpcError{Code: errInvalidParams, Message: "unknown tool: " + params.Name}
+		}
+		// Failures go back to the model as tool errors it can act on
+		if err := store.Append(projectPath, params.Arguments.Note); err != nil {
+			return toolResult(err.Error(), true), nil
+		}
+		return toolResult("Saved to project memory.", false), nil
+	default:
+		return nil, &rpcError{Code: errMethodNotFound, Message: "method not found: " + req.Method}
+	}
+}
+
+// toolResult is a tools/call result with one text item
+func toolResult(text string, isError bool) map[string]interface{} {
+	return map[string]interface{}{
+		"content": []map[string]interface{}{{"type": "text", "text": text}},
+		"isError": isError,
+	}
+}
+
+// ServerMain runs the memory MCP server with command-line args and returns
+// the process exit code
+func ServerMain(args []string) int {
+	fs := flag.NewFlagSet(ServerCommand, flag.ContinueOnError)
+	dir := fs.String("dir", "", "Directory project notes are kept in")
+	project := fs.String("project", "", "Project whose notes the tool adds to")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *dir == "" || *project == "" {
+		fmt.Fprintln(os.Stderr, "memory server: --dir and --project are required")
+		return 2
+	}
+
+	if err := Serve(NewStore(*dir), *project, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "memory server: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestServe(t *testing.T) {
+	store := NewStore(t.TempDir())
+	in := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"remember","arguments":{"note":"Tests use testify"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"remember","arguments":{"note":""}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"resources/list"}`,
+		`not json`,
+	}, "\n")
+
+	var out bytes.Buffer
+	if err := Serve(store, "/project", strings.NewReader(in), &out); err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+
+	type reply struct {
+		Result map[string]interface{} `json:"result"`
+		Error  *rpcError              `json:"error"`
+	}
+	var responses []reply
+	decoder := json.NewDecoder(&out)
+	for decoder.More() {
+		var resp reply
+		if err := decoder.Decode(&resp); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		responses = append(responses, resp)
+	}
+
+	// The notification gets no reply
+	if len(responses) != 6 {
+		t.Fatalf("Expected 6 responses, got %d: %s", len(responses), out.String())
+	}
+	if responses[0].Result["serverInfo"].(map[string]interface{})["name"] != ServerName {
+		t.Errorf("Expected initialize to name the server, got %v", responses[0].Result)
+	}
+	if tools := responses[1].Result["tools"].([]interface{}); len(tools) != 1 || tools[0].(map[string]interface{})["name"] != ToolName {
+		t.Errorf("Expected tools/list to return %s, got %v", ToolName, tools)
+	}
+	if responses[2].Result["isError"] != false {
+		t.Errorf("Expected the note to be saved, got %v", responses[2].Result)
+	}
+	if responses[3].Result["isError"] != true {
+		t.Errorf("Expected an empty note to fail as a tool error, got %v", responses[3].Result)
+	}
+	if responses[4].Error == nil || responses[4].Error.Code != errMethodNotFound {
+		t.Errorf("Expected unknown methods to fail, got %+v", responses[4])
+	}
+	if responses[5].Error == nil || responses[5].Error.Code != errParse {
+		t.Errorf("Expected a parse error, got %+v", responses[5])
+	}
+
+	if notes, _ := store.Load("/project"); notes != "- Tests use testify\n" {
+		t.Errorf("Expected the note appended, got %q", notes)
+	}
+}
+
+func TestMCPConfig(t *testing.T) {
+	config, err := MCPConfig("/usr/bin/boatman", "/home/me/.boatman/memory", "/project")
+	if err != nil {
+		t.Fatalf("MCPConfig() error = %v", err)
+	}
+
+	var parsed struct {
+		MCPServers map[string]struct {
+			Command string   `json:"command"`
+			Args    []string `json:"args"`
+		} `json:"mcpServers"`
+	}
+	if err := json.Unmarshal([]byte(config), &parsed); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+	server := parsed.MCPServers[ServerName]
+	expected := []string{ServerCommand, "--dir", "/home/me/.boatman/memory", "--project", "/project"}
+	if server.Command != "/usr/bin/boatman" || strings.Join(server.Args, " ") != strings.Join(expected, " ") {
+		t.Errorf("Unexpected server config %+v", server)
+	}
+}
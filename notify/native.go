@@ -0,0 +1,48 @@
+package notify
+
+import (
+	"fmt"
+	"os/exec"
+	"runtime"
+)
+
+// windowsToastScript shows a toast with the title and body passed through the environment
+const windowsToastScript = `[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $template.GetElementsByTagName('text')
+$text.Item(0).AppendChild($template.CreateTextNode($env:BOATMAN_NOTIFY_TITLE)) > $null
+$text.Item(1).AppendChild($template.CreateTextNode($env:BOATMAN_NOTIFY_BODY)) > $null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('Boatman').Show([Windows.UI.Notifications.ToastNotification]::new($template))`
+
+// Native shows a notification through the operating system's notification center
+func Native(title, body string) error {
+	cmd, err := nativeCommand(runtime.GOOS, title, body)
+	if err != nil {
+		return err
+	}
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", cmd.Path, err, output)
+	}
+	return nil
+}
+
+// nativeCommand builds the platform command that shows a notification. Title
+// and body are passed as arguments or environment, never spliced into scripts.
+func nativeCommand(goos, title, body string) (*exec.Cmd, error) {
+	switch goos {
+	case "darwin":
+		return exec.Command("osascript",
+			"-e", "on run argv",
+			"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+			"-e", "end run",
+			title, body,
+		), nil
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return exec.Command("notify-send", "--app-name=Boatman", "--", title, body), nil
+	case "windows":
+		cmd := exec.Command("powershell", "-NoProfile", "-NonInteractive", "-Command", windowsToastScript)
+		cmd.Env = append(cmd.Environ(), "BOATMAN_NOTIFY_TITLE="+title, "BOATMAN_NOTIFY_BODY="+body)
+		return cmd, nil
+	}
+	return nil, fmt.Errorf("notifications are not supported on %s", goos)
+}
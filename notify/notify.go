@@ -0,0 +1,257 @@
+package notify
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"boatman/logging"
+)
+
+// logger logs notifications that fail to send
+var logger = logging.For("notify")
+
+// Event is a session change a user can be notified about
+type Event string
+
+const (
+	EventWaiting   Event = "waiting"   // The session needs approval
+	EventCompleted Event = "completed" // A long turn finished
+	EventError     Event = "error"     // The session errored
+	EventAlert     Event = "alert"     // A firefighter alert came in
+	EventCost      Event = "cost"      // A session's cost reached the threshold
+)
+
+// Settings controls which events notify and when notifications are held back
+type Settings struct {
+	Enabled     bool
+	OnWaiting   bool
+	OnCompleted bool
+	OnError     bool
+	OnAlert     bool
+	// CostThreshold is the session cost in USD that notifies once reached; zero is off
+	CostThreshold float64
+	// MinTurnDuration is how long a turn must run before its completion notifies
+	MinTurnDuration time.Duration
+	// OnlyWhenUnfocused skips notifications while the app window has focus
+	OnlyWhenUnfocused bool
+	// Quiet hours as "HH:MM" in local time. The window may wrap past midnight.
+	QuietHoursEnabled bool
+	QuietHoursStart   string
+	QuietHoursEnd     string
+}
+
+// wants reports whether the settings enable notifications for event
+func (s Settings) wants(event Event) bool {
+	if !s.Enabled {
+		return false
+	}
+	switch event {
+	case EventWaiting:
+		return s.OnWaiting
+	case EventCompleted:
+		return s.OnCompleted
+	case EventError:
+		return s.OnError
+	case EventAlert:
+		return s.OnAlert
+	case EventCost:
+		return s.CostThreshold > 0
+	}
+	return false
+}
+
+// InQuietHours reports whether now falls inside the quiet hours window
+func (s Settings) InQuietHours(now time.Time) bool {
+	if !s.QuietHoursEnabled {
+		return false
+	}
+	start, err := parseClock(s.QuietHoursStart)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(s.QuietHoursEnd)
+	if err != nil || start == end {
+		return false
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	if start < end {
+		return minute >= start && minute < end
+	}
+	// Wraps past midnight, e.g. 22:00-07:00
+	return minute >= start || minute < end
+}
+
+// parseClock parses "HH:MM" into minutes after midnight
+func parseClock(clock string) (int, error) {
+	parts := strings.Split(strings.TrimSpace(clock), ":")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", clock)
+	}
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil || hour < 0 || hour > 23 {
+		return 0, fmt.Errorf("invalid hour in %q", clock)
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, fmt.Errorf("invalid minute in %q", clock)
+	}
+	return hour*60 + minute, nil
+}
+
+// ValidateClock checks a quiet hours time is formatted as "HH:MM"
+func ValidateClock(clock string) error {
+	_, err := parseClock(clock)
+	return err
+}
+
+// Sender delivers a notification to the desktop
+type Sender func(title, body string) error
+
+// Dispatcher turns session status changes into desktop notifications
+type Dispatcher struct {
+	mu          sync.Mutex
+	settings    func() Settings
+	focused     func() bool
+	send        Sender
+	now         func() time.Time
+	turnStarted map[string]time.Time
+	// costNotified is the threshold each session's cost last notified at
+	costNotified map[string]float64
+}
+
+// NewDispatcher creates a dispatcher that reads settings and window focus on
+// every status change and delivers notifications with send
+func NewDispatcher(settings func() Settings, focused func() bool, send Sender) *Dispatcher {
+	return &Dispatcher{
+		settings:     settings,
+		focused:      focused,
+		send:         send,
+		now:          time.Now,
+		turnStarted:  make(map[string]time.Time),
+		costNotified: make(map[string]float64),
+	}
+}
+
+// StatusChanged records a session status change and notifies if the settings
+// ask for it. label names the session in the notification. Delivery happens in
+// the background so callers holding locks aren't blocked.
+func (d *Dispatcher) StatusChanged(sessionID, label, status string) {
+	event, elapsed, ok := d.track(sessionID, status)
+	if !ok {
+		return
+	}
+
+	settings := d.settings()
+	if !settings.wants(event) {
+		return
+	}
+	if event == EventCompleted && elapsed < settings.MinTurnDuration {
+		return
+	}
+	if settings.OnlyWhenUnfocused && d.focused != nil && d.focused() {
+		return
+	}
+	if settings.InQuietHours(d.now()) {
+		return
+	}
+
+	title, body := message(event, label, elapsed)
+	d.deliver(title, body)
+}
+
+// Alert notifies about an incoming firefighter alert if the settings ask for
+// it. Like Notify, it is sent even while the app has focus.
+func (d *Dispatcher) Alert(title, body string) {
+	if !d.settings().wants(EventAlert) {
+		return
+	}
+	d.Notify(title, body)
+}
+
+// CostChanged records a session's total cost and notifies once it reaches
+// the cost threshold. A session notifies once per threshold, so raising the
+// threshold lets it notify again.
+func (d *Dispatcher) CostChanged(sessionID, label string, cost float64) {
+	settings := d.settings()
+	if !settings.wants(EventCost) || cost < settings.CostThreshold {
+		return
+	}
+
+	d.mu.Lock()
+	notified := d.costNotified[sessionID] == settings.CostThreshold
+	d.costNotified[sessionID] = settings.CostThreshold
+	d.mu.Unlock()
+	if notified {
+		return
+	}
+
+	d.Notify("Cost threshold reached", fmt.Sprintf("%s has spent $%.2f, over your $%.2f threshold", label, cost, settings.CostThreshold))
+}
+
+// Notify sends a notification that isn't about a session's status, such as an
+// incoming alert. It is sent even while the app has focus, but not when
+// notifications are off or during quiet hours.
+func (d *Dispatcher) Notify(title, body string) {
+	settings := d.settings()
+	if !settings.Enabled || settings.InQuietHours(d.now()) {
+		return
+	}
+	d.deliver(title, body)
+}
+
+// deliver sends a notification in the background
+func (d *Dispatcher) deliver(title, body string) {
+	go func() {
+		if err := d.send(title, body); err != nil {
+			logger.Warn("Failed to send notification", "error", err)
+		}
+	}()
+}
+
+// track updates turn timing for a session and maps its status to an event
+func (d *Dispatcher) track(sessionID, status string) (Event, time.Duration, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := d.now()
+	started, running := d.turnStarted[sessionID]
+
+	switch status {
+	case "running":
+		// Rate limit and offline waits resume the same turn
+		if !running {
+			d.turnStarted[sessionID] = now
+		}
+		return "", 0, false
+	case "waiting":
+		return EventWaiting, 0, true
+	case "idle":
+		if !running {
+			return "", 0, false
+		}
+		delete(d.turnStarted, sessionID)
+		return EventCompleted, now.Sub(started), true
+	case "error":
+		delete(d.turnStarted, sessionID)
+		return EventError, 0, true
+	case "stopped":
+		delete(d.turnStarted, sessionID)
+	}
+	return "", 0, false
+}
+
+// message builds the notification text for an event
+func message(event Event, label string, elapsed time.Duration) (string, string) {
+	switch event {
+	case EventWaiting:
+		return "Approval needed", label + " is waiting for your approval"
+	case EventCompleted:
+		return "Task finished", fmt.Sprintf("%s finished after %s", label, elapsed.Round(time.Second))
+	default:
+		return "Session error", label + " stopped with an error"
+	}
+}
@@ -0,0 +1,317 @@
+package notify
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInQuietHours(t *testing.T) {
+	at := func(clock string) time.Time {
+		parsed, err := time.Parse("15:04", clock)
+		if err != nil {
+			t.Fatalf("bad test time %q: %v", clock, err)
+		}
+		return parsed
+	}
+
+	tests := []struct {
+		name     string
+		enabled  bool
+		start    string
+		end      string
+		now      string
+		expected bool
+	}{
+		{"disabled", false, "22:00", "08:00", "23:00", false},
+		{"inside same-day window", true, "12:00", "14:00", "13:30", true},
+		{"start is inclusive", true, "12:00", "14:00", "12:00", true},
+		{"end is exclusive", true, "12:00", "14:00", "14:00", false},
+		{"outside same-day window", true, "12:00", "14:00", "15:00", false},
+		{"before midnight in wrapping window", true, "22:00", "08:00", "23:15", true},
+		{"after midnight in wrapping window", true, "22:00", "08:00", "03:00", true},
+		{"outside wrapping window", true, "22:00", "08:00", "12:00", false},
+		{"empty window", true, "09:00", "09:00", "09:00", false},
+		{"invalid start", true, "25:00", "08:00", "23:00", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Settings{QuietHoursEnabled: tt.enabled, QuietHoursStart: tt.start, QuietHoursEnd: tt.end}
+			if got := s.InQuietHours(at(tt.now)); got != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidateClock(t *testing.T) {
+	tests := []struct {
+		clock string
+		valid bool
+	}{
+		{"00:00", true},
+		{"23:59", true},
+		{" 7:05 ", true},
+		{"24:00", false},
+		{"12:60", false},
+		{"noon", false},
+		{"12", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		err := ValidateClock(tt.clock)
+		if (err == nil) != tt.valid {
+			t.Errorf("ValidateClock(%q): expected valid=%v, got error %v", tt.clock, tt.valid, err)
+		}
+	}
+}
+
+// recorder collects sent notifications
+type recorder struct {
+	mu    sync.Mutex
+	sent  []string
+	wg    sync.WaitGroup
+	clock time.Time
+}
+
+func (r *recorder) send(title, body string) error {
+	defer r.wg.Done()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, title+": "+body)
+	return nil
+}
+
+func newTestDispatcher(settings Settings, focused bool) (*Dispatcher, *recorder) {
+	r := &recorder{clock: time.Date(2026, 1, 1, 12, 0, 0, 0, time.Local)}
+	d := NewDispatcher(func() Settings { return settings }, func() bool { return focused }, r.send)
+	d.now = func() time.Time { return r.clock }
+	return d, r
+}
+
+func TestDispatcherEvents(t *testing.T) {
+	all := Settings{Enabled: true, OnWaiting: true, OnCompleted: true, OnError: true, MinTurnDuration: time.Minute}
+
+	tests := []struct {
+		name     string
+		settings Settings
+		focused  bool
+		statuses []string
+		turn     time.Duration // Time between the first and last status
+		expected []string
+	}{
+		{
+			name:     "long turn completes",
+			settings: all,
+			statuses: []string{"running", "idle"},
+			turn:     2 * time.Minute,
+			expected: []string{"Task finished: api finished after 2m0s"},
+		},
+		{
+			name:     "short turn is skipped",
+			settings: all,
+			statuses: []string{"running", "idle"},
+			turn:     10 * time.Second,
+		},
+		{
+			name:     "idle without a turn is skipped",
+			settings: all,
+			statuses: []string{"idle"},
+		},
+		{
+			name:     "waiting for approval",
+			settings: all,
+			statuses: []string{"running", "waiting"},
+			expected: []string{"Approval needed: api is waiting for your approval"},
+		},
+		{
+			name:     "error",
+			settings: all,
+			statuses: []string{"running", "error"},
+			expected: []string{"Session error: api stopped with an error"},
+		},
+		{
+			name:     "event turned off",
+			settings: Settings{Enabled: true, OnCompleted: true},
+			statuses: []string{"running", "error"},
+		},
+		{
+			name:     "notifications disabled",
+			settings: Settings{OnWaiting: true},
+			statuses: []string{"waiting"},
+		},
+		{
+			name:     "focused window",
+			settings: Settings{Enabled: true, OnWaiting: true, OnlyWhenUnfocused: true},
+			focused:  true,
+			statuses: []string{"waiting"},
+		},
+		{
+			name:     "quiet hours",
+			settings: Settings{Enabled: true, OnWaiting: true, QuietHoursEnabled: true, QuietHoursStart: "11:00", QuietHoursEnd: "13:00"},
+			statuses: []string{"waiting"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, r := newTestDispatcher(tt.settings, tt.focused)
+			r.wg.Add(len(tt.expected))
+
+			for i, status := range tt.statuses {
+				if i == len(tt.statuses)-1 {
+					r.clock = r.clock.Add(tt.turn)
+				}
+				d.StatusChanged("s1", "api", status)
+			}
+			r.wg.Wait()
+
+			if strings.Join(r.sent, "\n") != strings.Join(tt.expected, "\n") {
+				t.Errorf("Expected %v, got %v", tt.expected, r.sent)
+			}
+		})
+	}
+}
+
+func TestDispatcherTurnSurvivesRetries(t *testing.T) {
+	d, r := newTestDispatcher(Settings{Enabled: true, OnCompleted: true, MinTurnDuration: time.Minute}, false)
+	r.wg.Add(1)
+
+	d.StatusChanged("s1", "api", "running")
+	r.clock = r.clock.Add(50 * time.Second)
+	d.StatusChanged("s1", "api", "rate-limited")
+	d.StatusChanged("s1", "api", "running")
+	r.clock = r.clock.Add(20 * time.Second)
+	d.StatusChanged("s1", "api", "idle")
+	r.wg.Wait()
+
+	if len(r.sent) != 1 || !strings.Contains(r.sent[0], "1m10s") {
+		t.Errorf("Expected one notification timed from the first run, got %v", r.sent)
+	}
+}
+
+func TestDispatcherNotify(t *testing.T) {
+	// Sent while focused, unlike status notifications
+	d, r := newTestDispatcher(Settings{Enabled: true, OnlyWhenUnfocused: true}, true)
+	r.wg.Add(1)
+	d.Notify("Datadog alert", "Checkout 5xx spike")
+	r.wg.Wait()
+	if len(r.sent) != 1 || r.sent[0] != "Datadog alert: Checkout 5xx spike" {
+		t.Errorf("Expected the alert notification, got %v", r.sent)
+	}
+
+	for _, settings := range []Settings{
+		{},
+		{Enabled: true, QuietHoursEnabled: true, QuietHoursStart: "11:00", QuietHoursEnd: "13:00"},
+	} {
+		d, r := newTestDispatcher(settings, false)
+		d.Notify("Datadog alert", "Checkout 5xx spike")
+		if len(r.sent) != 0 {
+			t.Errorf("Expected no notification with %+v, got %v", settings, r.sent)
+		}
+	}
+}
+
+func TestDispatcherAlert(t *testing.T) {
+	d, r := newTestDispatcher(Settings{Enabled: true, OnAlert: true, OnlyWhenUnfocused: true}, true)
+	r.wg.Add(1)
+	d.Alert("Bugsnag alert", "NoMethodError in checkout")
+	r.wg.Wait()
+	if len(r.sent) != 1 {
+		t.Errorf("Expected the alert notification, got %v", r.sent)
+	}
+
+	d, r = newTestDispatcher(Settings{Enabled: true}, false)
+	d.Alert("Bugsnag alert", "NoMethodError in checkout")
+	if len(r.sent) != 0 {
+		t.Errorf("Expected no notification with alerts turned off, got %v", r.sent)
+	}
+}
+
+func TestDispatcherCostChanged(t *testing.T) {
+	settings := Settings{Enabled: true, CostThreshold: 5}
+	d := NewDispatcher(func() Settings { return settings }, nil, nil)
+	r := &recorder{}
+	d.send = r.send
+
+	r.wg.Add(1)
+	for _, cost := range []float64{1, 4.99, 5.2, 6, 7} {
+		d.CostChanged("s1", "api", cost)
+	}
+	r.wg.Wait()
+	if len(r.sent) != 1 || r.sent[0] != "Cost threshold reached: api has spent $5.20, over your $5.00 threshold" {
+		t.Errorf("Expected one notification on crossing the threshold, got %v", r.sent)
+	}
+
+	// Raising the threshold lets the session notify again
+	settings.CostThreshold = 10
+	r.wg.Add(1)
+	d.CostChanged("s1", "api", 9)
+	d.CostChanged("s1", "api", 12)
+	r.wg.Wait()
+	if len(r.sent) != 2 {
+		t.Errorf("Expected a second notification over the new threshold, got %v", r.sent)
+	}
+
+	settings.CostThreshold = 0
+	d.CostChanged("s2", "web", 100)
+	if len(r.sent) != 2 {
+		t.Errorf("Expected no notification with the threshold off, got %v", r.sent)
+	}
+}
+
+func TestNativeCommand(t *testing.T) {
+	title, body := `Done "now"`, "$(rm -rf ~)"
+
+	tests := []struct {
+		goos     string
+		name     string
+		contains []string
+	}{
+		{"darwin", "osascript", []string{title, body}},
+		{"linux", "notify-send", []string{"--", title, body}},
+		{"windows", "powershell", []string{"-NoProfile"}},
+	}
+
+	for _, tt := range tests {
+		cmd, err := nativeCommand(tt.goos, title, body)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.goos, err)
+		}
+		if !strings.HasSuffix(cmd.Args[0], tt.name) {
+			t.Errorf("%s: expected %s, got %s", tt.goos, tt.name, cmd.Args[0])
+		}
+		for _, want := range tt.contains {
+			found := false
+			for _, arg := range cmd.Args {
+				if arg == want {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("%s: expected argument %q in %v", tt.goos, want, cmd.Args)
+			}
+		}
+	}
+
+	cmd, _ := nativeCommand("windows", title, body)
+	if !containsEnv(cmd.Env, "BOATMAN_NOTIFY_BODY="+body) {
+		t.Error("Expected the windows body to be passed through the environment")
+	}
+
+	if _, err := nativeCommand("plan9", title, body); err == nil {
+		t.Error("Expected an error for an unsupported OS")
+	}
+}
+
+func containsEnv(env []string, entry string) bool {
+	for _, e := range env {
+		if e == entry {
+			return true
+		}
+	}
+	return false
+}
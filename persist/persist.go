@@ -0,0 +1,105 @@
+// Package persist writes files so that a crash, or another process writing
+// the same file, never leaves them half written.
+package persist
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// WriteFile replaces path's contents with data. The data goes to a temp file
+// in the same directory, which is synced and renamed over path, so readers
+// see either the old file or the new one. An advisory lock on path+".lock"
+// keeps concurrent writers, in this process or another, from interleaving.
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	unlock, err := lock(path + ".lock")
+	if err != nil {
+		return fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	defer unlock()
+
+	return writeFile(path, data, perm)
+}
+
+// Update replaces path's contents with what fn returns given its current
+// contents, nil when path doesn't exist. WriteFile's lock is held from the
+// read through the write, so concurrent updates build on each other rather
+// than the last one undoing the rest. If fn fails, or returns nil, the
+// file is left as it is.
+func Update(path string, perm os.FileMode, fn func(old []byte) ([]byte, error)) error {
+	unlock, err := lock(path + ".lock")
+	if err != nil {
+		return fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+	defer unlock()
+
+	old, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	data, err := fn(old)
+	if err != nil || data == nil {
+		return err
+	}
+	return writeFile(path, data, perm)
+}
+
+// writeFile does WriteFile's work for a caller holding the lock
+func writeFile(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	// Only reached before the rename, which leaves nothing to remove
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// WriteJSON writes v to path as indented JSON with WriteFile
+func WriteJSON(path string, v interface{}, perm os.FileMode) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return WriteFile(path, data, perm)
+}
+
+// lock takes an exclusive advisory lock on the file at lockPath, creating it
+// if needed, and returns the function that releases it
+func lock(lockPath string) (func(), error) {
+	f, err := os.OpenFile(lockPath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
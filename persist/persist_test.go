@@ -0,0 +1,122 @@
+package persist
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	if err := WriteFile(path, []byte("new"), 0600); err != nil {
+		t.Fatalf("WriteFile failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "new" {
+		t.Fatalf("Expected the file replaced, got %q (%v)", data, err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			t.Errorf("Expected no temp files left, found %s", entry.Name())
+		}
+	}
+
+	if err := WriteFile(filepath.Join(dir, "missing", "config.json"), []byte("{}"), 0644); err == nil {
+		t.Error("Expected an error writing into a missing directory")
+	}
+}
+
+func TestWriteJSONConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "projects.json")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Big enough that an unlocked, in-place write would tear
+			v := map[string]string{"writer": fmt.Sprint(i), "padding": strings.Repeat(fmt.Sprint(i), 10000)}
+			if err := WriteJSON(path, v, 0644); err != nil {
+				t.Errorf("WriteJSON failed: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+	var v map[string]string
+	if err := json.Unmarshal(data, &v); err != nil {
+		t.Fatalf("Expected one writer's complete JSON, got an error: %v", err)
+	}
+	if v["padding"] != strings.Repeat(v["writer"], 10000) {
+		t.Errorf("Expected one writer's data throughout, got writer %s", v["writer"])
+	}
+}
+
+func TestUpdateConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "counter.json")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := Update(path, 0600, func(old []byte) ([]byte, error) {
+				count := 0
+				if old != nil {
+					if err := json.Unmarshal(old, &count); err != nil {
+						return nil, err
+					}
+				}
+				return json.Marshal(count + 1)
+			})
+			if err != nil {
+				t.Errorf("Update failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "20" {
+		t.Errorf("Expected every update counted, got %q (%v)", data, err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+}
+
+func TestUpdateUnchanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	if err := Update(path, 0644, func(old []byte) ([]byte, error) { return nil, nil }); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	failed := fmt.Errorf("bad data")
+	if err := Update(path, 0644, func(old []byte) ([]byte, error) { return []byte("new"), failed }); err != failed {
+		t.Errorf("Expected fn's error, got %v", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old" {
+		t.Errorf("Expected the file left as it was, got %q", data)
+	}
+}
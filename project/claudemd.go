@@ -0,0 +1,58 @@
+package project
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// claudeMDPaths are where claude looks for a project's instructions, in the
+// order Boatman prefers them
+var claudeMDPaths = []string{"CLAUDE.md", filepath.Join(".claude", "CLAUDE.md")}
+
+// ClaudeMD is a project's CLAUDE.md, the instructions claude reads at the
+// start of every session
+type ClaudeMD struct {
+	// Path is relative to the project; it's where a new file will be created
+	// if none exists yet
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	Exists  bool   `json:"exists"`
+}
+
+// ReadClaudeMD returns the workspace's CLAUDE.md, or where one would be
+// created if it has none
+func (w *Workspace) ReadClaudeMD() (*ClaudeMD, error) {
+	for _, path := range claudeMDPaths {
+		content, err := os.ReadFile(filepath.Join(w.path, path))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		return &ClaudeMD{Path: path, Content: string(content), Exists: true}, nil
+	}
+	return &ClaudeMD{Path: claudeMDPaths[0]}, nil
+}
+
+// WriteClaudeMD replaces the workspace's CLAUDE.md, creating it at the
+// project root if it has none
+func (w *Workspace) WriteClaudeMD(content string) error {
+	if info, err := os.Stat(w.path); err != nil || !info.IsDir() {
+		return fmt.Errorf("project directory not found: %s", w.path)
+	}
+
+	current, err := w.ReadClaudeMD()
+	if err != nil {
+		return err
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(filepath.Join(w.path, current.Path)); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.WriteFile(filepath.Join(w.path, current.Path), []byte(content), mode); err != nil {
+		return fmt.Errorf("failed to write %s: %w", current.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestClaudeMD_Missing(t *testing.T) {
+	tmpDir := t.TempDir()
+	ws := NewWorkspace(tmpDir)
+
+	md, err := ws.ReadClaudeMD()
+	if err != nil {
+		t.Fatalf("ReadClaudeMD() error = %v", err)
+	}
+	if md.Exists || md.Path != "CLAUDE.md" || md.Content != "" {
+		t.Errorf("Expected a missing CLAUDE.md at the root, got %+v", md)
+	}
+
+	if err := ws.WriteClaudeMD("# Conventions\n"); err != nil {
+		t.Fatalf("WriteClaudeMD() error = %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(tmpDir, "CLAUDE.md"))
+	if err != nil || string(content) != "# Conventions\n" {
+		t.Errorf("Expected CLAUDE.md created at the root, got %q, %v", content, err)
+	}
+}
+
+func TestClaudeMD_InClaudeDir(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, ".claude", "CLAUDE.md")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("Use tabs."), 0600); err != nil {
+		t.Fatal(err)
+	}
+	ws := NewWorkspace(tmpDir)
+
+	md, err := ws.ReadClaudeMD()
+	if err != nil {
+		t.Fatalf("ReadClaudeMD() error = %v", err)
+	}
+	if !md.Exists || md.Path != filepath.Join(".claude", "CLAUDE.md") || md.Content != "Use tabs." {
+		t.Errorf("Expected the .claude/CLAUDE.md file, got %+v", md)
+	}
+
+	// Edits go to the existing file and keep its permissions
+	if err := ws.WriteClaudeMD("Use spaces."); err != nil {
+		t.Fatalf("WriteClaudeMD() error = %v", err)
+	}
+	if ws.FileExists("CLAUDE.md") {
+		t.Error("Expected no CLAUDE.md created at the root")
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected permissions kept, got %v, %v", info, err)
+	}
+	if content, _ := ws.ReadFile(md.Path); content != "Use spaces." {
+		t.Errorf("Expected the edit saved, got %q", content)
+	}
+}
+
+func TestClaudeMD_MissingProject(t *testing.T) {
+	ws := NewWorkspace(filepath.Join(t.TempDir(), "gone"))
+	if err := ws.WriteClaudeMD("notes"); err == nil {
+		t.Error("Expected writing to a missing project to fail")
+	}
+}
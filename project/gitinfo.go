@@ -0,0 +1,91 @@
+package project
+
+import (
+	"os"
+	"time"
+
+	"boatman/git"
+)
+
+// GitMetadata records where a project's repository lives and its default branch
+type GitMetadata struct {
+	RemoteURL     string    `json:"remoteUrl,omitempty"`
+	Host          string    `json:"host,omitempty"`  // e.g. "github.com"
+	Owner         string    `json:"owner,omitempty"` // Organization or user
+	Repo          string    `json:"repo,omitempty"`
+	WebURL        string    `json:"webUrl,omitempty"` // https link for deep links and PRs
+	DefaultBranch string    `json:"defaultBranch,omitempty"`
+	RefreshedAt   time.Time `json:"refreshedAt"`
+}
+
+// detectGitMetadata reads the origin remote and default branch of the repository
+// at path. It returns nil when path isn't a git repository.
+func detectGitMetadata(path string) *GitMetadata {
+	repo := git.NewRepository(path)
+	if !repo.IsGitRepo() {
+		return nil
+	}
+
+	meta := &GitMetadata{RefreshedAt: time.Now()}
+	if branch, err := repo.GetDefaultBranch(); err == nil {
+		meta.DefaultBranch = branch
+	}
+
+	if remoteURL, err := repo.GetRemoteURL("origin"); err == nil {
+		meta.RemoteURL = remoteURL
+		if info, err := git.ParseRemoteURL(remoteURL); err == nil {
+			meta.Host = info.Host
+			meta.Owner = info.Owner
+			meta.Repo = info.Repo
+			meta.WebURL = info.WebURL()
+		}
+	}
+
+	return meta
+}
+
+// setGitMetadata stores git metadata and updates the display name to match
+func (p *Project) setGitMetadata(meta *GitMetadata) {
+	p.Git = meta
+	p.DisplayName = p.Name
+	if meta != nil && meta.Owner != "" && meta.Repo != "" {
+		p.DisplayName = meta.Owner + "/" + meta.Repo
+	}
+}
+
+// RefreshGitMetadata re-reads a project's remote and default branch
+func (pm *ProjectManager) RefreshGitMetadata(id string) (*Project, error) {
+	pm.mu.RLock()
+	var path string
+	for _, p := range pm.projects {
+		if p.ID == id {
+			path = p.Path
+			break
+		}
+	}
+	pm.mu.RUnlock()
+	if path == "" {
+		return nil, os.ErrNotExist
+	}
+
+	// Run git outside the lock; it can be slow on large repositories
+	meta := detectGitMetadata(path)
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	var project Project
+	err := pm.update(func(projects []Project) ([]Project, error) {
+		for i := range projects {
+			if projects[i].ID == id {
+				projects[i].setGitMetadata(meta)
+				project = projects[i]
+				return projects, nil
+			}
+		}
+		return nil, os.ErrNotExist
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
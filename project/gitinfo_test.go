@@ -0,0 +1,80 @@
+package project
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+)
+
+func TestAddProjectRecordsGitMetadata(t *testing.T) {
+	pm, tempDir := setupTestProjectManager(t)
+	defer os.RemoveAll(tempDir)
+
+	dir := createTestDir(t)
+	defer os.RemoveAll(dir)
+
+	// Plain directories have no git metadata
+	project, err := pm.AddProject(dir)
+	if err != nil {
+		t.Fatalf("AddProject() error = %v", err)
+	}
+	if project.Git != nil {
+		t.Errorf("Expected no git metadata, got %+v", project.Git)
+	}
+	if project.DisplayName != project.Name {
+		t.Errorf("Expected display name %q, got %q", project.Name, project.DisplayName)
+	}
+
+	for _, args := range [][]string{
+		{"init", "-q", "-b", "main"},
+		{"remote", "add", "origin", "https://github.com/acme/widgets.git"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if err := cmd.Run(); err != nil {
+			t.Fatalf("git %v: %v", args, err)
+		}
+	}
+
+	// Reopening the project refreshes the metadata
+	project, err = pm.AddProject(dir)
+	if err != nil {
+		t.Fatalf("AddProject() error = %v", err)
+	}
+	if project.Git == nil {
+		t.Fatal("Expected git metadata")
+	}
+	if project.Git.Host != "github.com" || project.Git.Owner != "acme" || project.Git.Repo != "widgets" {
+		t.Errorf("Unexpected remote: %+v", project.Git)
+	}
+	if project.Git.DefaultBranch != "main" {
+		t.Errorf("Expected default branch main, got %q", project.Git.DefaultBranch)
+	}
+	if project.Git.WebURL != "https://github.com/acme/widgets" {
+		t.Errorf("Expected web URL, got %q", project.Git.WebURL)
+	}
+	if project.DisplayName != "acme/widgets" {
+		t.Errorf("Expected display name acme/widgets, got %q", project.DisplayName)
+	}
+
+	cmd := exec.Command("git", "remote", "set-url", "origin", "git@gitlab.com:acme/gadgets.git")
+	cmd.Dir = dir
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+
+	refreshed, err := pm.RefreshGitMetadata(project.ID)
+	if err != nil {
+		t.Fatalf("RefreshGitMetadata() error = %v", err)
+	}
+	if refreshed.Git.Host != "gitlab.com" || refreshed.DisplayName != "acme/gadgets" {
+		t.Errorf("Expected refreshed remote, got %+v (%s)", refreshed.Git, refreshed.DisplayName)
+	}
+	if stored, _ := pm.GetProject(project.ID); stored.DisplayName != "acme/gadgets" {
+		t.Errorf("Expected refreshed metadata to be stored, got %q", stored.DisplayName)
+	}
+
+	if _, err := pm.RefreshGitMetadata("missing"); err != os.ErrNotExist {
+		t.Errorf("Expected ErrNotExist, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package project
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultPreviewBytes is how much of a file a preview reads when no limit is given
+const DefaultPreviewBytes = 256 << 10
+
+// binarySniffBytes is how much of a file is checked for NUL bytes, as git does
+const binarySniffBytes = 8000
+
+// FilePreview is the start of a workspace file, for showing it or giving it
+// to the agent
+type FilePreview struct {
+	// Path is relative to the workspace root the file is in
+	Path    string `json:"path"`
+	Size    int64  `json:"size"`
+	Content string `json:"content"`
+	// Binary files have no content
+	Binary    bool `json:"binary"`
+	Truncated bool `json:"truncated"`
+}
+
+// PreviewFile reads up to maxBytes of a file in the workspace. The path is
+// relative to the workspace path, or absolute within any of its roots;
+// paths outside them are refused.
+func (w *Workspace) PreviewFile(path string, maxBytes int) (*FilePreview, error) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultPreviewBytes
+	}
+	fullPath, relPath, err := w.resolve(path)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("%s is a directory", relPath)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, int64(maxBytes)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", relPath, err)
+	}
+
+	preview := &FilePreview{Path: relPath, Size: info.Size(), Truncated: info.Size() > int64(len(data))}
+	if isBinary(data, preview.Truncated) {
+		preview.Binary = true
+		return preview, nil
+	}
+	if preview.Truncated {
+		data = trimPartialRune(data)
+	}
+	preview.Content = string(data)
+	return preview, nil
+}
+
+// resolve returns the absolute path of a file in the workspace and its path
+// relative to the root it's in
+func (w *Workspace) resolve(path string) (string, string, error) {
+	fullPath := path
+	if !filepath.IsAbs(path) {
+		fullPath = filepath.Join(w.path, path)
+	}
+	fullPath = filepath.Clean(fullPath)
+
+	for _, root := range w.Roots() {
+		rel, err := filepath.Rel(filepath.Clean(root), fullPath)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		return fullPath, filepath.ToSlash(rel), nil
+	}
+	return "", "", fmt.Errorf("%s is outside the workspace", path)
+}
+
+// isBinary reports whether data looks like the start of a binary file: it
+// has a NUL byte or isn't UTF-8, allowing for a rune cut off by truncation
+func isBinary(data []byte, truncated bool) bool {
+	if bytes.IndexByte(data[:min(len(data), binarySniffBytes)], 0) >= 0 {
+		return true
+	}
+	if truncated {
+		data = trimPartialRune(data)
+	}
+	return !utf8.Valid(data)
+}
+
+// trimPartialRune drops an incomplete UTF-8 sequence from the end of data
+func trimPartialRune(data []byte) []byte {
+	for i := len(data) - 1; i >= 0 && i >= len(data)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(data[i]) {
+			if !utf8.FullRune(data[i:]) {
+				return data[:i]
+			}
+			break
+		}
+	}
+	return data
+}
@@ -0,0 +1,62 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPreviewFile(t *testing.T) {
+	tmpDir := t.TempDir()
+	other := t.TempDir()
+	files := map[string]string{
+		"main.go":          "package main\n",
+		"logo.png":         "\x89PNG\r\n\x1a\n\x00\x00",
+		"notes/long.txt":   strings.Repeat("a", 10) + "é",
+		"notes/latin1.txt": "caf\xe9",
+	}
+	for name, content := range files {
+		path := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(other, "api.go"), []byte("package api\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ws := NewWorkspace(tmpDir, other)
+
+	tests := []struct {
+		name      string
+		path      string
+		maxBytes  int
+		expected  FilePreview
+		wantError bool
+	}{
+		{"text", "main.go", 0, FilePreview{Path: "main.go", Size: 13, Content: "package main\n"}, false},
+		{"binary", "logo.png", 0, FilePreview{Path: "logo.png", Size: 10, Binary: true}, false},
+		{"not utf-8", "notes/latin1.txt", 0, FilePreview{Path: "notes/latin1.txt", Size: 4, Binary: true}, false},
+		// The cut falls inside the é, which is dropped rather than taken for binary
+		{"truncated", "notes/long.txt", 11, FilePreview{Path: "notes/long.txt", Size: 12, Content: "aaaaaaaaaa", Truncated: true}, false},
+		{"other root", filepath.Join(other, "api.go"), 0, FilePreview{Path: "api.go", Size: 12, Content: "package api\n"}, false},
+		{"outside", "../escape.txt", 0, FilePreview{}, true},
+		{"directory", "notes", 0, FilePreview{}, true},
+		{"missing", "missing.go", 0, FilePreview{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preview, err := ws.PreviewFile(tt.path, tt.maxBytes)
+			if (err != nil) != tt.wantError {
+				t.Fatalf("PreviewFile() error = %v, wantError %v", err, tt.wantError)
+			}
+			if err == nil && *preview != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, *preview)
+			}
+		})
+	}
+}
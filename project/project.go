@@ -6,6 +6,8 @@ import (
 	"path/filepath"
 	"sync"
 	"time"
+
+	"boatman/persist"
 )
 
 // Project represents a project/workspace
@@ -16,6 +18,9 @@ type Project struct {
 	Description string    `json:"description,omitempty"`
 	LastOpened  time.Time `json:"lastOpened"`
 	CreatedAt   time.Time `json:"createdAt"`
+	// DisplayName is the remote's owner/repo when known, otherwise Name
+	DisplayName string       `json:"displayName,omitempty"`
+	Git         *GitMetadata `json:"git,omitempty"` // Nil when the project isn't a git repository
 }
 
 // ProjectManager manages projects and workspaces
@@ -65,21 +70,32 @@ func (pm *ProjectManager) load() error {
 	return json.Unmarshal(data, &pm.projects)
 }
 
-// save writes projects to disk
-func (pm *ProjectManager) save() error {
-	data, err := json.MarshalIndent(pm.projects, "", "  ")
-	if err != nil {
-		return err
-	}
+// update applies change to the projects as saved, which another window
+// may have changed since they were loaded, and keeps the result. The file
+// stays locked from the read through the write. A file that doesn't parse
+// is replaced.
+// Note: This method expects the caller to hold pm.mu lock
+func (pm *ProjectManager) update(change func(projects []Project) ([]Project, error)) error {
+	return persist.Update(pm.storagePath, 0644, func(old []byte) ([]byte, error) {
+		projects := pm.projects
+		if old != nil {
+			var saved []Project
+			if err := json.Unmarshal(old, &saved); err == nil {
+				projects = saved
+			}
+		}
 
-	return os.WriteFile(pm.storagePath, data, 0644)
+		projects, err := change(projects)
+		if err != nil {
+			return nil, err
+		}
+		pm.projects = projects
+		return json.MarshalIndent(projects, "", "  ")
+	})
 }
 
 // AddProject adds or updates a project
 func (pm *ProjectManager) AddProject(path string) (*Project, error) {
-	pm.mu.Lock()
-	defer pm.mu.Unlock()
-
 	// Check if path exists
 	info, err := os.Stat(path)
 	if err != nil {
@@ -89,32 +105,45 @@ func (pm *ProjectManager) AddProject(path string) (*Project, error) {
 		return nil, os.ErrInvalid
 	}
 
-	// Check if project already exists
-	for i, p := range pm.projects {
-		if p.Path == path {
-			pm.projects[i].LastOpened = time.Now()
-			pm.save()
-			return &pm.projects[i], nil
+	// Opening a project refreshes its git metadata
+	gitMeta := detectGitMetadata(path)
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	var project Project
+	err = pm.update(func(projects []Project) ([]Project, error) {
+		// Check if project already exists
+		for i, p := range projects {
+			if p.Path == path {
+				projects[i].LastOpened = time.Now()
+				projects[i].setGitMetadata(gitMeta)
+				project = projects[i]
+				return projects, nil
+			}
 		}
-	}
 
-	// Create new project
-	project := Project{
-		ID:         filepath.Base(path) + "-" + time.Now().Format("20060102150405"),
-		Name:       filepath.Base(path),
-		Path:       path,
-		LastOpened: time.Now(),
-		CreatedAt:  time.Now(),
-	}
+		// Create new project
+		project = Project{
+			ID:         filepath.Base(path) + "-" + time.Now().Format("20060102150405"),
+			Name:       filepath.Base(path),
+			Path:       path,
+			LastOpened: time.Now(),
+			CreatedAt:  time.Now(),
+		}
+		project.setGitMetadata(gitMeta)
 
-	pm.projects = append([]Project{project}, pm.projects...)
+		projects = append([]Project{project}, projects...)
 
-	// Limit recent projects
-	if len(pm.projects) > pm.recentLimit {
-		pm.projects = pm.projects[:pm.recentLimit]
+		// Limit recent projects
+		if len(projects) > pm.recentLimit {
+			projects = projects[:pm.recentLimit]
+		}
+		return projects, nil
+	})
+	if err != nil {
+		return nil, err
 	}
-
-	pm.save()
 	return &project, nil
 }
 
@@ -123,14 +152,14 @@ func (pm *ProjectManager) RemoveProject(id string) error {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
-	for i, p := range pm.projects {
-		if p.ID == id {
-			pm.projects = append(pm.projects[:i], pm.projects[i+1:]...)
-			return pm.save()
+	return pm.update(func(projects []Project) ([]Project, error) {
+		for i, p := range projects {
+			if p.ID == id {
+				return append(projects[:i], projects[i+1:]...), nil
+			}
 		}
-	}
-
-	return os.ErrNotExist
+		return nil, os.ErrNotExist
+	})
 }
 
 // GetProject returns a project by ID
@@ -190,14 +219,15 @@ func (pm *ProjectManager) UpdateProject(project Project) error {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
-	for i, p := range pm.projects {
-		if p.ID == project.ID {
-			pm.projects[i] = project
-			return pm.save()
+	return pm.update(func(projects []Project) ([]Project, error) {
+		for i, p := range projects {
+			if p.ID == project.ID {
+				projects[i] = project
+				return projects, nil
+			}
 		}
-	}
-
-	return os.ErrNotExist
+		return nil, os.ErrNotExist
+	})
 }
 
 // ValidatePath checks if a path is a valid project directory
@@ -682,6 +682,38 @@ func TestProjectPersistence(t *testing.T) {
 	}
 }
 
+func TestProjectPersistence_TwoWindows(t *testing.T) {
+	pm1, tempDir := setupTestProjectManager(t)
+	defer os.RemoveAll(tempDir)
+
+	// A second window loaded the same, empty, list of projects
+	pm2 := &ProjectManager{
+		projects:    []Project{},
+		recentLimit: 10,
+		storagePath: pm1.storagePath,
+	}
+
+	dir1 := createTestDir(t)
+	defer os.RemoveAll(dir1)
+	dir2 := createTestDir(t)
+	defer os.RemoveAll(dir2)
+
+	if _, err := pm1.AddProject(dir1); err != nil {
+		t.Fatalf("AddProject failed: %v", err)
+	}
+	if _, err := pm2.AddProject(dir2); err != nil {
+		t.Fatalf("AddProject failed: %v", err)
+	}
+
+	pm3 := &ProjectManager{storagePath: pm1.storagePath}
+	if err := pm3.load(); err != nil {
+		t.Fatalf("Failed to load projects: %v", err)
+	}
+	if len(pm3.projects) != 2 {
+		t.Errorf("Expected both windows' projects saved, got %d", len(pm3.projects))
+	}
+}
+
 func TestLoadProjects_FileNotExists(t *testing.T) {
 	pm, tempDir := setupTestProjectManager(t)
 	defer os.RemoveAll(tempDir)
@@ -14,14 +14,37 @@ type WorkspaceInfo struct {
 	Languages  []string `json:"languages"`
 }
 
-// Workspace provides utilities for working with project workspaces
+// Workspace provides utilities for working with project workspaces. Besides
+// its path, a workspace can span additional roots, such as a backend repo
+// next to a frontend one.
 type Workspace struct {
-	path string
+	path            string
+	additionalPaths []string
 }
 
 // NewWorkspace creates a new workspace instance
-func NewWorkspace(path string) *Workspace {
-	return &Workspace{path: path}
+func NewWorkspace(path string, additionalPaths ...string) *Workspace {
+	return &Workspace{path: path, additionalPaths: additionalPaths}
+}
+
+// Roots returns every directory the workspace spans, its path first
+func (w *Workspace) Roots() []string {
+	return append([]string{w.path}, w.additionalPaths...)
+}
+
+// GetRootsInfo returns information about each of the workspace's roots, in
+// the order of Roots
+func (w *Workspace) GetRootsInfo() ([]*WorkspaceInfo, error) {
+	roots := w.Roots()
+	infos := make([]*WorkspaceInfo, 0, len(roots))
+	for _, root := range roots {
+		info, err := NewWorkspace(root).GetInfo()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
 }
 
 // GetInfo returns information about the workspace
@@ -506,6 +506,36 @@ func TestFileExists_Subdirectory(t *testing.T) {
 	}
 }
 
+func TestGetRootsInfo(t *testing.T) {
+	frontend := t.TempDir()
+	backend := t.TempDir()
+	if err := os.WriteFile(filepath.Join(frontend, "package.json"), []byte("{}"), 0644); err != nil {
+		t.Fatalf("Failed to create package.json: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(backend, "go.mod"), []byte("module api"), 0644); err != nil {
+		t.Fatalf("Failed to create go.mod: %v", err)
+	}
+
+	ws := NewWorkspace(frontend, backend)
+	if roots := ws.Roots(); len(roots) != 2 || roots[0] != frontend || roots[1] != backend {
+		t.Errorf("Expected roots [%s %s], got %v", frontend, backend, roots)
+	}
+
+	infos, err := ws.GetRootsInfo()
+	if err != nil {
+		t.Fatalf("GetRootsInfo() failed: %v", err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("Expected info for 2 roots, got %d", len(infos))
+	}
+	if infos[0].Path != frontend || !containsString(infos[0].Languages, "javascript") {
+		t.Errorf("Expected the frontend root first, got %+v", infos[0])
+	}
+	if infos[1].Path != backend || !containsString(infos[1].Languages, "go") {
+		t.Errorf("Expected the backend root second, got %+v", infos[1])
+	}
+}
+
 // Helper function to check if a slice contains a string
 func containsString(slice []string, str string) bool {
 	for _, item := range slice {
@@ -0,0 +1,111 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// cliStore keeps secrets through the platform's credential command: security
+// on macOS and secret-tool (libsecret) elsewhere. Secrets are passed on
+// stdin, never as arguments other processes could see.
+type cliStore struct {
+	service string
+	goos    string
+	// run runs a command with the given stdin and returns its stdout
+	run func(cmd *exec.Cmd, stdin string) ([]byte, error)
+}
+
+// newCLIStore returns a store using goos's credential command
+func newCLIStore(service, goos string) *cliStore {
+	return &cliStore{service: service, goos: goos, run: runCommand}
+}
+
+// runCommand runs cmd, returning stderr in the error if it fails
+func runCommand(cmd *exec.Cmd, stdin string) ([]byte, error) {
+	var stderr strings.Builder
+	cmd.Stdin = strings.NewReader(stdin)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return output, fmt.Errorf("%s: %w: %s", cmd.Path, err, strings.TrimSpace(stderr.String()))
+	}
+	return output, nil
+}
+
+// Get returns the secret stored under name
+func (s *cliStore) Get(name string) (string, error) {
+	var cmd *exec.Cmd
+	if s.goos == "darwin" {
+		cmd = exec.Command("security", "find-generic-password", "-s", s.service, "-a", name, "-w")
+	} else {
+		cmd = exec.Command("secret-tool", "lookup", "service", s.service, "account", name)
+	}
+
+	output, err := s.run(cmd, "")
+	if err != nil {
+		if isNotFound(s.goos, err) {
+			return "", ErrNotFound
+		}
+		return "", fmt.Errorf("failed to read %s from the keychain: %w", name, err)
+	}
+	// secret-tool succeeds with no output when nothing matches
+	if len(output) == 0 && s.goos != "darwin" {
+		return "", ErrNotFound
+	}
+	return strings.TrimSuffix(string(output), "\n"), nil
+}
+
+// Set stores value under name
+func (s *cliStore) Set(name, value string) error {
+	var cmd *exec.Cmd
+	var stdin string
+	if s.goos == "darwin" {
+		// security's interactive mode reads the command, and so the secret,
+		// from stdin
+		cmd = exec.Command("security", "-i")
+		stdin = fmt.Sprintf("add-generic-password -U -s %s -a %s -w %s\n", quote(s.service), quote(name), quote(value))
+	} else {
+		cmd = exec.Command("secret-tool", "store", "--label", fmt.Sprintf("%s %s", s.service, name), "service", s.service, "account", name)
+		stdin = value
+	}
+
+	if _, err := s.run(cmd, stdin); err != nil {
+		return fmt.Errorf("failed to save %s to the keychain: %w", name, err)
+	}
+	return nil
+}
+
+// Delete removes the secret stored under name
+func (s *cliStore) Delete(name string) error {
+	var cmd *exec.Cmd
+	if s.goos == "darwin" {
+		cmd = exec.Command("security", "delete-generic-password", "-s", s.service, "-a", name)
+	} else {
+		cmd = exec.Command("secret-tool", "clear", "service", s.service, "account", name)
+	}
+
+	if _, err := s.run(cmd, ""); err != nil && !isNotFound(s.goos, err) {
+		return fmt.Errorf("failed to remove %s from the keychain: %w", name, err)
+	}
+	return nil
+}
+
+// isNotFound reports whether a command failed because nothing was stored.
+// security exits with 44 and secret-tool with 1.
+func isNotFound(goos string, err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	if goos == "darwin" {
+		return exitErr.ExitCode() == 44
+	}
+	return exitErr.ExitCode() == 1
+}
+
+// quote single-quotes s for security's interactive mode
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
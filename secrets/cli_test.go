@@ -0,0 +1,125 @@
+package secrets
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// recordingRun records the commands a store runs and answers with output
+func recordingRun(output string, err error, calls *[]string) func(*exec.Cmd, string) ([]byte, error) {
+	return func(cmd *exec.Cmd, stdin string) ([]byte, error) {
+		call := strings.Join(cmd.Args, " ")
+		if stdin != "" {
+			call += " <<< " + stdin
+		}
+		*calls = append(*calls, call)
+		return []byte(output), err
+	}
+}
+
+func TestCLIStoreCommands(t *testing.T) {
+	tests := []struct {
+		name     string
+		goos     string
+		op       func(s *cliStore) error
+		expected string
+	}{
+		{
+			name:     "darwin get",
+			goos:     "darwin",
+			op:       func(s *cliStore) error { _, err := s.Get("apiKey"); return err },
+			expected: "security find-generic-password -s boatman -a apiKey -w",
+		},
+		{
+			name:     "darwin set passes the secret on stdin",
+			goos:     "darwin",
+			op:       func(s *cliStore) error { return s.Set("apiKey", "sk-it's") },
+			expected: `security -i <<< add-generic-password -U -s 'boatman' -a 'apiKey' -w 'sk-it'\''s'` + "\n",
+		},
+		{
+			name:     "darwin delete",
+			goos:     "darwin",
+			op:       func(s *cliStore) error { return s.Delete("apiKey") },
+			expected: "security delete-generic-password -s boatman -a apiKey",
+		},
+		{
+			name:     "linux get",
+			goos:     "linux",
+			op:       func(s *cliStore) error { _, err := s.Get("apiKey"); return err },
+			expected: "secret-tool lookup service boatman account apiKey",
+		},
+		{
+			name:     "linux set passes the secret on stdin",
+			goos:     "linux",
+			op:       func(s *cliStore) error { return s.Set("apiKey", "sk-secret") },
+			expected: "secret-tool store --label boatman apiKey service boatman account apiKey <<< sk-secret",
+		},
+		{
+			name:     "linux delete",
+			goos:     "linux",
+			op:       func(s *cliStore) error { return s.Delete("apiKey") },
+			expected: "secret-tool clear service boatman account apiKey",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []string
+			store := newCLIStore("boatman", tt.goos)
+			store.run = recordingRun("sk-secret\n", nil, &calls)
+
+			if err := tt.op(store); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(calls) != 1 || calls[0] != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, calls)
+			}
+		})
+	}
+}
+
+func TestCLIStoreGet(t *testing.T) {
+	var calls []string
+	store := newCLIStore("boatman", "darwin")
+	store.run = recordingRun("sk-secret\n", nil, &calls)
+
+	value, err := store.Get("apiKey")
+	if err != nil || value != "sk-secret" {
+		t.Errorf("Expected the secret without its newline, got %q, %v", value, err)
+	}
+
+	// secret-tool finds nothing without failing
+	store = newCLIStore("boatman", "linux")
+	store.run = recordingRun("", nil, &calls)
+	if _, err := store.Get("apiKey"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	store.run = recordingRun("", errors.New("no secret service"), &calls)
+	if _, err := store.Get("apiKey"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected a read error, got %v", err)
+	}
+}
+
+func TestParseRef(t *testing.T) {
+	tests := []struct {
+		value string
+		name  string
+		ok    bool
+	}{
+		{Ref("apiKey"), "apiKey", true},
+		{"sk-ant-plaintext", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			name, ok := ParseRef(tt.value)
+			if name != tt.name || ok != tt.ok {
+				t.Errorf("Expected (%q, %v), got (%q, %v)", tt.name, tt.ok, name, ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+//go:build !windows
+
+package secrets
+
+import (
+	"os/exec"
+	"runtime"
+)
+
+// platformKeychain returns the command-backed store if its command is installed
+func platformKeychain(service string) Store {
+	command := "secret-tool"
+	if runtime.GOOS == "darwin" {
+		command = "security"
+	}
+	if _, err := exec.LookPath(command); err != nil {
+		return nil
+	}
+	return newCLIStore(service, runtime.GOOS)
+}
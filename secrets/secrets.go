@@ -0,0 +1,44 @@
+package secrets
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrNotFound is returned when nothing is stored under a name
+var ErrNotFound = errors.New("secret not found")
+
+// refPrefix marks a config value that names a keychain entry instead of
+// holding the secret itself
+const refPrefix = "keychain:"
+
+// Store keeps secrets in the operating system's credential store
+type Store interface {
+	// Get returns the secret stored under name, or ErrNotFound
+	Get(name string) (string, error)
+	// Set stores value under name, replacing what was there
+	Set(name, value string) error
+	// Delete removes the secret stored under name, if any
+	Delete(name string) error
+}
+
+// Keychain returns the credential store for service: the macOS Keychain,
+// Windows Credential Manager, or the Secret Service through libsecret. It
+// returns nil when the system has none Boatman can use, so callers can keep
+// secrets in their config instead.
+func Keychain(service string) Store {
+	return platformKeychain(service)
+}
+
+// Ref returns the config value that refers to the secret stored under name
+func Ref(name string) string {
+	return refPrefix + name
+}
+
+// ParseRef returns the name a config value refers to, if it is a reference
+func ParseRef(value string) (string, bool) {
+	if !strings.HasPrefix(value, refPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(value, refPrefix), true
+}
@@ -0,0 +1,78 @@
+package stream
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrNotJSON is returned for output lines that aren't JSON, e.g. verbose logging
+var ErrNotJSON = errors.New("line is not a JSON event")
+
+// Decode parses one line of stream-json output into its typed event. Event
+// types this package doesn't know decode as *Unknown. An error means the line
+// isn't JSON (ErrNotJSON) or a known event has fields of the wrong shape.
+func Decode(line []byte) (Event, error) {
+	line = bytes.TrimSpace(line)
+	if !json.Valid(line) {
+		return nil, ErrNotJSON
+	}
+
+	var header Header
+	if err := json.Unmarshal(line, &header); err != nil {
+		// Valid JSON that isn't an object, e.g. a bare string
+		return nil, ErrNotJSON
+	}
+
+	event := newEvent(header.Type)
+	if event == nil {
+		return &Unknown{Header: header, Raw: append(json.RawMessage(nil), line...)}, nil
+	}
+	if err := json.Unmarshal(line, event); err != nil {
+		return nil, fmt.Errorf("invalid %s event: %w", header.Type, err)
+	}
+	return event, nil
+}
+
+// newEvent returns an empty event to decode the given type into, or nil for
+// an unknown type
+func newEvent(eventType string) Event {
+	switch eventType {
+	case TypeSystem:
+		return &System{}
+	case TypeUser:
+		return &User{}
+	case TypeAssistant:
+		return &Assistant{}
+	case TypeContentBlockStart:
+		return &ContentBlockStart{}
+	case TypeContentBlockDelta:
+		return &ContentBlockDelta{}
+	case TypeContentBlockStop:
+		return &ContentBlockStop{}
+	case TypeMessageStart:
+		return &MessageStart{}
+	case TypeMessageDelta:
+		return &MessageDelta{}
+	case TypeMessageStop:
+		return &MessageStop{}
+	case TypeResult:
+		return &Result{}
+	case TypeToolUse:
+		return &ToolUse{}
+	case TypeToolResult:
+		return &ToolResult{}
+	case TypeControlRequest:
+		return &ControlRequest{}
+	case TypeInputRequest:
+		return &InputRequest{}
+	case TypeTaskCreate, TypeTaskUpdate:
+		return &TaskEvent{}
+	case TypeAgentOutput:
+		return &AgentOutput{}
+	case TypeError:
+		return &Error{}
+	}
+	return nil
+}
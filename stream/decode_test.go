@@ -0,0 +1,212 @@
+package stream
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDecodeEventTypes(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		check func(*testing.T, Event)
+	}{
+		{
+			name: "system",
+			line: `{"type":"system","subtype":"init","session_id":"conv-1","model":"sonnet"}`,
+			check: func(t *testing.T, e Event) {
+				ev := e.(*System)
+				if ev.SessionID != "conv-1" || ev.Subtype != "init" {
+					t.Errorf("Unexpected system event %+v", ev)
+				}
+			},
+		},
+		{
+			name: "assistant",
+			line: `{"type":"assistant","message":{"role":"assistant","content":[{"type":"text","text":"Hi"},{"type":"tool_use","id":"t1","name":"Read","input":{"file_path":"a.go"}}]}}`,
+			check: func(t *testing.T, e Event) {
+				ev := e.(*Assistant)
+				if len(ev.Message.Content) != 2 {
+					t.Fatalf("Expected 2 content blocks, got %d", len(ev.Message.Content))
+				}
+				if ev.Message.Content[0].Text != "Hi" {
+					t.Errorf("Expected text Hi, got %q", ev.Message.Content[0].Text)
+				}
+				if tool := ev.Message.Content[1]; tool.Name != "Read" || tool.Input["file_path"] != "a.go" {
+					t.Errorf("Unexpected tool block %+v", tool)
+				}
+			},
+		},
+		{
+			name: "content block delta",
+			line: `{"type":"content_block_delta","index":1,"delta":{"type":"text_delta","text":"chunk"}}`,
+			check: func(t *testing.T, e Event) {
+				ev := e.(*ContentBlockDelta)
+				if ev.Index != 1 || ev.Delta.Text != "chunk" {
+					t.Errorf("Unexpected delta %+v", ev)
+				}
+			},
+		},
+		{
+			name: "message delta with usage",
+			line: `{"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"input_tokens":10,"output_tokens":20}}`,
+			check: func(t *testing.T, e Event) {
+				ev := e.(*MessageDelta)
+				if ev.Delta.StopReason != "end_turn" {
+					t.Errorf("Expected stop reason end_turn, got %q", ev.Delta.StopReason)
+				}
+				if u := ev.EventUsage(); u == nil || u.InputTokens != 10 || u.OutputTokens != 20 {
+					t.Errorf("Unexpected usage %+v", u)
+				}
+			},
+		},
+		{
+			name: "message start usage in message",
+			line: `{"type":"message_start","message":{"usage":{"input_tokens":5,"cache_read_input_tokens":100}}}`,
+			check: func(t *testing.T, e Event) {
+				ev := e.(*MessageStart)
+				if ev.EventUsage() != nil {
+					t.Error("Expected no top-level usage")
+				}
+				if u := ev.Message.Usage; u == nil || u.InputTokens != 5 || u.CacheReadInputTokens != 100 {
+					t.Errorf("Unexpected message usage %+v", u)
+				}
+			},
+		},
+		{
+			name: "result with text",
+			line: `{"type":"result","subtype":"success","is_error":false,"result":"All done","session_id":"conv-2","total_cost_usd":0.25}`,
+			check: func(t *testing.T, e Event) {
+				ev := e.(*Result)
+				if ev.Result.Text != "All done" || ev.SessionID != "conv-2" || ev.TotalCostUSD != 0.25 {
+					t.Errorf("Unexpected result %+v", ev)
+				}
+			},
+		},
+		{
+			name: "result with object",
+			line: `{"type":"result","result":{"session_id":"conv-3","usage":{"output_tokens":7}}}`,
+			check: func(t *testing.T, e Event) {
+				ev := e.(*Result)
+				if ev.Result.Text != "" || ev.Result.SessionID != "conv-3" {
+					t.Errorf("Unexpected result %+v", ev.Result)
+				}
+				if ev.Result.Usage == nil || ev.Result.Usage.OutputTokens != 7 {
+					t.Errorf("Unexpected result usage %+v", ev.Result.Usage)
+				}
+			},
+		},
+		{
+			name: "tool use",
+			line: `{"type":"tool_use","name":"Bash","id":"t1","input":{"command":"ls"}}`,
+			check: func(t *testing.T, e Event) {
+				ev := e.(*ToolUse)
+				if ev.Name != "Bash" || ev.ID != "t1" || ev.Input["command"] != "ls" {
+					t.Errorf("Unexpected tool use %+v", ev)
+				}
+			},
+		},
+		{
+			name: "tool use with legacy field names",
+			line: `{"type":"tool_use","tool_name":"Grep","tool_id":"t2"}`,
+			check: func(t *testing.T, e Event) {
+				ev := e.(*ToolUse)
+				if ev.Name != "Grep" || ev.ID != "t2" {
+					t.Errorf("Expected legacy names to decode, got %+v", ev)
+				}
+			},
+		},
+		{
+			name: "tool result with string content",
+			line: `{"type":"tool_result","tool_use_id":"t1","content":"output","is_error":true}`,
+			check: func(t *testing.T, e Event) {
+				ev := e.(*ToolResult)
+				if ev.ToolUseID != "t1" || ev.Content != "output" || !ev.IsError {
+					t.Errorf("Unexpected tool result %+v", ev)
+				}
+			},
+		},
+		{
+			name: "tool result with block content",
+			line: `{"type":"tool_result","tool_id":"t1","content":[{"type":"text","text":"one "},{"type":"text","text":"two"}]}`,
+			check: func(t *testing.T, e Event) {
+				ev := e.(*ToolResult)
+				if ev.ToolUseID != "t1" || ev.Content != "one two" {
+					t.Errorf("Unexpected tool result %+v", ev)
+				}
+			},
+		},
+		{
+			name: "control request",
+			line: `{"type":"control_request","request_id":"r1","request":{"subtype":"can_use_tool","tool_name":"Write","input":{"file_path":"a.go"}}}`,
+			check: func(t *testing.T, e Event) {
+				ev := e.(*ControlRequest)
+				if ev.RequestID != "r1" || ev.Request.Subtype != "can_use_tool" || ev.Request.Input["file_path"] != "a.go" {
+					t.Errorf("Unexpected control request %+v", ev)
+				}
+			},
+		},
+		{
+			name: "task update",
+			line: `{"type":"task_update","task":{"id":"task-1","subject":"Build","status":"completed"}}`,
+			check: func(t *testing.T, e Event) {
+				ev := e.(*TaskEvent)
+				if ev.EventType() != TypeTaskUpdate || ev.Task == nil || ev.Task.Status != "completed" {
+					t.Errorf("Unexpected task event %+v", ev)
+				}
+			},
+		},
+		{
+			name: "error object",
+			line: `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`,
+			check: func(t *testing.T, e Event) {
+				ev := e.(*Error)
+				if ev.Error.Message != "Overloaded" || ev.Error.Type != "overloaded_error" {
+					t.Errorf("Unexpected error %+v", ev.Error)
+				}
+			},
+		},
+		{
+			name: "error string",
+			line: `{"type":"error","error":"boom"}`,
+			check: func(t *testing.T, e Event) {
+				if msg := e.(*Error).Error.Message; msg != "boom" {
+					t.Errorf("Expected message boom, got %q", msg)
+				}
+			},
+		},
+		{
+			name: "unknown type",
+			line: `{"type":"stream_event","event":{}}`,
+			check: func(t *testing.T, e Event) {
+				ev := e.(*Unknown)
+				if ev.EventType() != "stream_event" || len(ev.Raw) == 0 {
+					t.Errorf("Unexpected unknown event %+v", ev)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event, err := Decode([]byte(tt.line))
+			if err != nil {
+				t.Fatalf("Decode failed: %v", err)
+			}
+			tt.check(t, event)
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	for _, line := range []string{"Loading...", "[debug] starting", `"just a string"`, ""} {
+		if _, err := Decode([]byte(line)); !errors.Is(err, ErrNotJSON) {
+			t.Errorf("Expected ErrNotJSON for %q, got %v", line, err)
+		}
+	}
+
+	_, err := Decode([]byte(`{"type":"tool_use","name":42}`))
+	if err == nil || errors.Is(err, ErrNotJSON) {
+		t.Errorf("Expected an invalid event error, got %v", err)
+	}
+}
@@ -0,0 +1,365 @@
+package stream
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Event types printed by claude with --output-format stream-json
+const (
+	TypeSystem            = "system"
+	TypeUser              = "user"
+	TypeAssistant         = "assistant"
+	TypeContentBlockStart = "content_block_start"
+	TypeContentBlockDelta = "content_block_delta"
+	TypeContentBlockStop  = "content_block_stop"
+	TypeMessageStart      = "message_start"
+	TypeMessageDelta      = "message_delta"
+	TypeMessageStop       = "message_stop"
+	TypeResult            = "result"
+	TypeToolUse           = "tool_use"
+	TypeToolResult        = "tool_result"
+	TypeControlRequest    = "control_request"
+	TypeInputRequest      = "input_request"
+	TypeTaskCreate        = "task_create"
+	TypeTaskUpdate        = "task_update"
+	TypeAgentOutput       = "agent_output"
+	TypeError             = "error"
+)
+
+// Event is one decoded line of stream-json output. Use a type switch on the
+// pointer types in this package, e.g. *ToolUse, to handle each kind.
+type Event interface {
+	// EventType is the event's "type" field
+	EventType() string
+	// EventUsage is the token usage reported at the top level of the event, if any
+	EventUsage() *Usage
+}
+
+// Header holds the fields any event can carry
+type Header struct {
+	Type  string `json:"type"`
+	Usage *Usage `json:"usage,omitempty"`
+}
+
+// EventType implements Event
+func (h Header) EventType() string { return h.Type }
+
+// EventUsage implements Event
+func (h Header) EventUsage() *Usage { return h.Usage }
+
+// Usage is the token usage for a message or turn
+type Usage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
+}
+
+// Message is an API message carried by assistant, user, and message_* events
+type Message struct {
+	ID         string         `json:"id,omitempty"`
+	Role       string         `json:"role,omitempty"`
+	Model      string         `json:"model,omitempty"`
+	Content    []ContentBlock `json:"content,omitempty"`
+	StopReason string         `json:"stop_reason,omitempty"`
+	Usage      *Usage         `json:"usage,omitempty"`
+}
+
+// ContentBlock is one block of a message: text, a tool call, or a tool result
+type ContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+
+	// tool_use blocks
+	ID    string         `json:"id,omitempty"`
+	Name  string         `json:"name,omitempty"`
+	Input map[string]any `json:"input,omitempty"`
+
+	// tool_result blocks
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   Text   `json:"content,omitempty"`
+	IsError   bool   `json:"is_error,omitempty"`
+}
+
+// Delta is the change carried by content_block_delta and message_delta events
+type Delta struct {
+	Type        string `json:"type,omitempty"`
+	Text        string `json:"text,omitempty"`
+	PartialJSON string `json:"partial_json,omitempty"`
+	StopReason  string `json:"stop_reason,omitempty"`
+}
+
+// System reports session setup, including the conversation to resume with -r
+type System struct {
+	Header
+	Subtype        string `json:"subtype,omitempty"`
+	ConversationID string `json:"conversation_id,omitempty"`
+	SessionID      string `json:"session_id,omitempty"`
+	Model          string `json:"model,omitempty"`
+}
+
+// User echoes a user message, including tool results sent back to the model
+type User struct {
+	Header
+	Message *Message `json:"message,omitempty"`
+	// ParentToolUseID is the Task tool call of the sub-agent the message belongs to
+	ParentToolUseID string `json:"parent_tool_use_id,omitempty"`
+}
+
+// Assistant is a complete assistant message
+type Assistant struct {
+	Header
+	Message *Message `json:"message,omitempty"`
+	// ParentToolUseID is the Task tool call of the sub-agent the message belongs to
+	ParentToolUseID string `json:"parent_tool_use_id,omitempty"`
+}
+
+// ContentBlockStart begins a streamed content block
+type ContentBlockStart struct {
+	Header
+	Index        int           `json:"index"`
+	ContentBlock *ContentBlock `json:"content_block,omitempty"`
+}
+
+// ContentBlockDelta streams part of a content block
+type ContentBlockDelta struct {
+	Header
+	Index int   `json:"index"`
+	Delta Delta `json:"delta"`
+}
+
+// ContentBlockStop ends a streamed content block
+type ContentBlockStop struct {
+	Header
+	Index int `json:"index"`
+}
+
+// MessageStart begins a streamed message
+type MessageStart struct {
+	Header
+	Message *Message `json:"message,omitempty"`
+}
+
+// MessageDelta updates a streamed message, e.g. with its stop reason
+type MessageDelta struct {
+	Header
+	Delta Delta `json:"delta"`
+}
+
+// MessageStop ends a streamed message
+type MessageStop struct {
+	Header
+	Message *Message `json:"message,omitempty"`
+}
+
+// Result ends a turn
+type Result struct {
+	Header
+	Subtype      string      `json:"subtype,omitempty"`
+	IsError      bool        `json:"is_error,omitempty"`
+	Result       ResultValue `json:"result"`
+	SessionID    string      `json:"session_id,omitempty"`
+	TotalCostUSD float64     `json:"total_cost_usd,omitempty"`
+	DurationMS   int         `json:"duration_ms,omitempty"`
+	NumTurns     int         `json:"num_turns,omitempty"`
+	// AgentID and ParentToolUseID are set when a sub-agent, rather than the
+	// turn, finished
+	AgentID         string `json:"agent_id,omitempty"`
+	ParentToolUseID string `json:"parent_tool_use_id,omitempty"`
+}
+
+// ResultValue is a result's "result" field, which is either the final text or
+// an object describing the turn
+type ResultValue struct {
+	Text      string `json:"-"`
+	SessionID string `json:"session_id,omitempty"`
+	Usage     *Usage `json:"usage,omitempty"`
+}
+
+// UnmarshalJSON accepts either form of the result field
+func (r *ResultValue) UnmarshalJSON(data []byte) error {
+	if isJSONString(data) {
+		*r = ResultValue{}
+		return json.Unmarshal(data, &r.Text)
+	}
+	if isJSONNull(data) {
+		*r = ResultValue{}
+		return nil
+	}
+	type plain ResultValue
+	return json.Unmarshal(data, (*plain)(r))
+}
+
+// ToolUse is a tool call. Older CLIs name the fields tool_name and tool_id;
+// both spellings decode into Name and ID.
+type ToolUse struct {
+	Header
+	ID    string         `json:"id"`
+	Name  string         `json:"name"`
+	Input map[string]any `json:"input,omitempty"`
+}
+
+// UnmarshalJSON fills Name and ID from either spelling
+func (t *ToolUse) UnmarshalJSON(data []byte) error {
+	type plain ToolUse
+	var raw struct {
+		plain
+		ToolName string `json:"tool_name"`
+		ToolID   string `json:"tool_id"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*t = ToolUse(raw.plain)
+	if t.Name == "" {
+		t.Name = raw.ToolName
+	}
+	if t.ID == "" {
+		t.ID = raw.ToolID
+	}
+	return nil
+}
+
+// ToolResult is the output of a tool call. Older CLIs name the ID field tool_id.
+type ToolResult struct {
+	Header
+	ToolUseID string `json:"tool_use_id"`
+	Content   Text   `json:"content"`
+	IsError   bool   `json:"is_error,omitempty"`
+}
+
+// UnmarshalJSON fills ToolUseID from either spelling
+func (t *ToolResult) UnmarshalJSON(data []byte) error {
+	type plain ToolResult
+	var raw struct {
+		plain
+		ToolID string `json:"tool_id"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*t = ToolResult(raw.plain)
+	if t.ToolUseID == "" {
+		t.ToolUseID = raw.ToolID
+	}
+	return nil
+}
+
+// Text is content sent either as a string or as an array of text blocks; the
+// blocks' text is joined
+type Text string
+
+// UnmarshalJSON accepts either form of the content
+func (t *Text) UnmarshalJSON(data []byte) error {
+	if isJSONNull(data) {
+		*t = ""
+		return nil
+	}
+	if isJSONString(data) {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*t = Text(s)
+		return nil
+	}
+
+	var blocks []struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, block := range blocks {
+		b.WriteString(block.Text)
+	}
+	*t = Text(b.String())
+	return nil
+}
+
+// ControlRequest is a request from the CLI over the control protocol, such as
+// asking whether a tool may run
+type ControlRequest struct {
+	Header
+	RequestID string             `json:"request_id"`
+	Request   ControlRequestBody `json:"request"`
+}
+
+// ControlRequestBody describes what a control request asks for
+type ControlRequestBody struct {
+	Subtype   string         `json:"subtype"`
+	ToolName  string         `json:"tool_name,omitempty"`
+	ToolUseID string         `json:"tool_use_id,omitempty"`
+	Input     map[string]any `json:"input,omitempty"`
+}
+
+// InputRequest asks the user for approval
+type InputRequest struct {
+	Header
+}
+
+// TaskEvent creates or updates a task tracked by a team agent
+type TaskEvent struct {
+	Header
+	Task *Task `json:"task,omitempty"`
+}
+
+// Task is a unit of work in a task event
+type Task struct {
+	ID          string `json:"id,omitempty"`
+	Subject     string `json:"subject,omitempty"`
+	Description string `json:"description,omitempty"`
+	Status      string `json:"status,omitempty"`
+}
+
+// AgentOutput is text streamed by a sub-agent, identified by its agent ID or
+// the Task tool call that spawned it when the CLI provides them
+type AgentOutput struct {
+	Header
+	Text            string `json:"text"`
+	AgentID         string `json:"agent_id,omitempty"`
+	ParentToolUseID string `json:"parent_tool_use_id,omitempty"`
+}
+
+// Error reports a failure in the run
+type Error struct {
+	Header
+	Error ErrorInfo `json:"error"`
+}
+
+// ErrorInfo describes an error. A bare string decodes into Message.
+type ErrorInfo struct {
+	Type    string `json:"type,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// UnmarshalJSON accepts an error object or a bare message
+func (e *ErrorInfo) UnmarshalJSON(data []byte) error {
+	if isJSONString(data) {
+		*e = ErrorInfo{}
+		return json.Unmarshal(data, &e.Message)
+	}
+	if isJSONNull(data) {
+		*e = ErrorInfo{}
+		return nil
+	}
+	type plain ErrorInfo
+	return json.Unmarshal(data, (*plain)(e))
+}
+
+// Unknown is an event type this package doesn't model. Raw holds the whole line.
+type Unknown struct {
+	Header
+	Raw json.RawMessage `json:"-"`
+}
+
+func isJSONString(data []byte) bool {
+	trimmed := strings.TrimSpace(string(data))
+	return strings.HasPrefix(trimmed, `"`)
+}
+
+func isJSONNull(data []byte) bool {
+	return strings.TrimSpace(string(data)) == "null"
+}
@@ -0,0 +1,360 @@
+package updater
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Install replaces the running build with the pending update. Like Sparkle, the
+// swap happens on disk while the app runs and takes effect on the next launch.
+// It returns nil when there is nothing to install.
+func (u *Updater) Install() (*PendingUpdate, error) {
+	pending, err := u.Pending()
+	if err != nil || pending == nil {
+		return nil, err
+	}
+
+	// Re-verify in case the staged file changed since it was downloaded
+	sum, err := fileSHA256(pending.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read downloaded update: %w", err)
+	}
+	if !strings.EqualFold(sum, pending.SHA256) {
+		u.ClearPending()
+		return nil, fmt.Errorf("downloaded update failed verification; it has been discarded")
+	}
+	if err := verifyPending(u.publicKey, pending); err != nil {
+		u.ClearPending()
+		return nil, fmt.Errorf("downloaded update failed verification (%v); it has been discarded", err)
+	}
+
+	target, bundle, err := u.installTarget()
+	if err != nil {
+		return nil, err
+	}
+
+	staging := filepath.Join(filepath.Dir(target), "."+filepath.Base(target)+".update")
+	if err := os.RemoveAll(staging); err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(staging)
+
+	replacement, err := stageReplacement(pending, staging, bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	// A macOS bundle must also be signed by whoever signed the running one
+	if bundle {
+		if err := verifyCodeSignature(target, replacement); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := swapInPlace(target, replacement); err != nil {
+		return nil, err
+	}
+
+	u.ClearPending()
+	return pending, nil
+}
+
+// installTarget returns the path to replace: the .app bundle on macOS, or the
+// executable elsewhere. bundle reports which one it is.
+func (u *Updater) installTarget() (path string, bundle bool, err error) {
+	exe, err := u.executable()
+	if err != nil {
+		return "", false, err
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+
+	if runtime.GOOS == "darwin" {
+		marker := ".app" + string(filepath.Separator) + "Contents" + string(filepath.Separator) + "MacOS"
+		if i := strings.Index(exe, marker); i >= 0 {
+			return exe[:i+len(".app")], true, nil
+		}
+	}
+	return exe, false, nil
+}
+
+// stageReplacement prepares the new build in staging, next to the install
+// target so it can be moved into place with a rename
+func stageReplacement(pending *PendingUpdate, staging string, bundle bool) (string, error) {
+	if err := os.MkdirAll(staging, 0755); err != nil {
+		return "", err
+	}
+
+	if !strings.HasSuffix(strings.ToLower(pending.AssetName), ".zip") {
+		if bundle {
+			return "", fmt.Errorf("expected a zipped app bundle, got %s", pending.AssetName)
+		}
+		dest := filepath.Join(staging, "boatman")
+		if err := copyFile(pending.Path, dest, 0755); err != nil {
+			return "", err
+		}
+		return dest, nil
+	}
+
+	if err := extractZip(pending.Path, staging); err != nil {
+		return "", err
+	}
+
+	entries, err := os.ReadDir(staging)
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if bundle && entry.IsDir() && strings.HasSuffix(name, ".app") {
+			return filepath.Join(staging, name), nil
+		}
+		if !bundle && !entry.IsDir() && (name == "boatman" || name == "boatman.exe") {
+			return filepath.Join(staging, name), nil
+		}
+	}
+	return "", fmt.Errorf("update archive %s does not contain a boatman build", pending.AssetName)
+}
+
+// swapInPlace moves replacement to target, keeping the old build until the new one is in place
+func swapInPlace(target, replacement string) error {
+	backup := target + ".old"
+	if err := os.RemoveAll(backup); err != nil {
+		return err
+	}
+	if err := os.Rename(target, backup); err != nil {
+		return fmt.Errorf("failed to move current build aside: %w", err)
+	}
+	if err := os.Rename(replacement, target); err != nil {
+		// Put the old build back so the app still launches
+		if restoreErr := os.Rename(backup, target); restoreErr != nil {
+			return fmt.Errorf("failed to install update (%v) and to restore the previous build: %w", err, restoreErr)
+		}
+		return fmt.Errorf("failed to install update: %w", err)
+	}
+
+	// Windows can't delete a running executable; the leftover is removed next time
+	os.RemoveAll(backup)
+	return nil
+}
+
+// codesign runs the macOS codesign tool; replaced in tests
+var codesign = func(args ...string) ([]byte, error) {
+	return exec.Command("codesign", args...).CombinedOutput()
+}
+
+// verifyCodeSignature checks that the new bundle satisfies the running
+// bundle's designated requirement, as Sparkle does, so only a build from the
+// same signer can replace it. The running bundle must be signed.
+func verifyCodeSignature(current, replacement string) error {
+	requirement, err := designatedRequirement(current)
+	if err != nil {
+		return err
+	}
+	if out, err := codesign("--verify", "--deep", "--strict", "-R="+requirement, replacement); err != nil {
+		return fmt.Errorf("update is not signed like the running app: %s", strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// designatedRequirement returns the code requirement a bundle's signature
+// designates for builds that may stand in for it
+func designatedRequirement(bundle string) (string, error) {
+	if out, err := codesign("--verify", "--deep", "--strict", bundle); err != nil {
+		return "", fmt.Errorf("the running app isn't validly signed, so the update's signer can't be checked: %s", strings.TrimSpace(string(out)))
+	}
+	out, err := codesign("--display", "--requirements", "-", bundle)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the running app's code requirement: %s", strings.TrimSpace(string(out)))
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		if requirement, ok := strings.CutPrefix(strings.TrimSpace(line), "designated => "); ok {
+			return requirement, nil
+		}
+	}
+	return "", fmt.Errorf("the running app's signature has no designated requirement")
+}
+
+// Restart launches the installed build. The caller should quit afterwards.
+func (u *Updater) Restart() error {
+	target, bundle, err := u.installTarget()
+	if err != nil {
+		return err
+	}
+	if bundle {
+		return exec.Command("open", "-n", target).Start()
+	}
+	return exec.Command(target).Start()
+}
+
+// extractZip unpacks archive into dest, preserving modes and symlinks
+func extractZip(archive, dest string) error {
+	r, err := zip.OpenReader(archive)
+	if err != nil {
+		return fmt.Errorf("failed to open update archive: %w", err)
+	}
+	defer r.Close()
+
+	root, err := filepath.Abs(dest)
+	if err != nil {
+		return err
+	}
+
+	var links []string
+	for _, f := range r.File {
+		path := filepath.Join(root, f.Name)
+		if !withinRoot(root, path) {
+			return fmt.Errorf("update archive contains an invalid path: %s", f.Name)
+		}
+		// An earlier entry may have been a symlink; never write through one
+		if err := checkNoSymlinks(root, path); err != nil {
+			return fmt.Errorf("update archive contains an invalid path: %s: %w", f.Name, err)
+		}
+
+		mode := f.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case mode&os.ModeSymlink != 0:
+			if err := extractSymlink(f, root, path); err != nil {
+				return err
+			}
+			links = append(links, path)
+		default:
+			if err := extractFile(f, path, mode.Perm()); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Links through other links can leave root even though each stays
+	// inside it on its own, so check where they all lead
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return err
+	}
+	for _, link := range links {
+		resolved, err := filepath.EvalSymlinks(link)
+		if err != nil || !withinRoot(realRoot, resolved) {
+			rel, _ := filepath.Rel(root, link)
+			return fmt.Errorf("update archive contains a symlink out of the archive: %s", rel)
+		}
+	}
+	return nil
+}
+
+// withinRoot reports whether path is root or inside it
+func withinRoot(root, path string) bool {
+	return path == root || strings.HasPrefix(path, root+string(filepath.Separator))
+}
+
+// checkNoSymlinks returns an error if path, or a directory between root and
+// it, is a symlink, so extracting to path can't land outside root
+func checkNoSymlinks(root, path string) error {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return err
+	}
+	current := root
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		current = filepath.Join(current, part)
+		info, err := os.Lstat(current)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return fmt.Errorf("%s is a symlink", current)
+		}
+	}
+	return nil
+}
+
+// extractSymlink creates the link f describes at path. Its target must be
+// relative and stay inside root, as links within an app bundle do.
+func extractSymlink(f *zip.File, root, path string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	linkTarget, err := io.ReadAll(io.LimitReader(rc, 4096))
+	if err != nil {
+		return err
+	}
+	target := string(linkTarget)
+	if filepath.IsAbs(target) || !withinRoot(root, filepath.Join(filepath.Dir(path), target)) {
+		return fmt.Errorf("update archive contains a symlink out of the archive: %s -> %s", f.Name, target)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.Symlink(target, path)
+}
+
+func extractFile(f *zip.File, path string, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	out, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, rc); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func copyFile(src, dest string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
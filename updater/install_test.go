@@ -0,0 +1,185 @@
+package updater
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// zipEntry is a file, or with link set a symlink, to put in a test archive
+type zipEntry struct {
+	name, data string
+	link       bool
+}
+
+// writeZip writes entries to a new archive and returns its path
+func writeZip(t *testing.T, entries ...zipEntry) string {
+	t.Helper()
+	archive := filepath.Join(t.TempDir(), "update.zip")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	for _, e := range entries {
+		header := &zip.FileHeader{Name: e.name}
+		header.SetMode(0644)
+		if e.link {
+			header.SetMode(os.ModeSymlink | 0777)
+		}
+		entry, err := w.CreateHeader(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry.Write([]byte(e.data))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return archive
+}
+
+func TestInstallReplacesExecutable(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("binary swap test runs on Linux")
+	}
+
+	rs := newReleaseServer(t)
+	rs.addRelease("v1.1.0", false, []byte("new build"))
+	u := newTestUpdater(t, "1.0.0", rs)
+
+	exe := filepath.Join(t.TempDir(), "boatman")
+	if err := os.WriteFile(exe, []byte("old build"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	u.executable = func() (string, error) { return exe, nil }
+
+	// Nothing to install yet
+	if pending, err := u.Install(); err != nil || pending != nil {
+		t.Fatalf("Expected no-op install, got %+v (%v)", pending, err)
+	}
+
+	info, _ := u.Check(context.Background(), ChannelStable)
+	if _, err := u.Download(context.Background(), info); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	installed, err := u.Install()
+	if err != nil {
+		t.Fatalf("Install failed: %v", err)
+	}
+	if installed == nil || installed.Version != "1.1.0" {
+		t.Errorf("Expected 1.1.0 to be installed, got %+v", installed)
+	}
+
+	data, _ := os.ReadFile(exe)
+	if string(data) != "new build" {
+		t.Errorf("Expected executable to be replaced, got %q", data)
+	}
+	if stat, _ := os.Stat(exe); stat.Mode().Perm()&0100 == 0 {
+		t.Error("Expected installed build to be executable")
+	}
+	if _, err := os.Stat(exe + ".old"); !os.IsNotExist(err) {
+		t.Error("Expected backup to be removed")
+	}
+	if p, _ := u.Pending(); p != nil {
+		t.Error("Expected pending update to be cleared")
+	}
+}
+
+func TestInstallRejectsTamperedDownload(t *testing.T) {
+	rs := newReleaseServer(t)
+	rs.addRelease("v1.1.0", false, []byte("new build"))
+	u := newTestUpdater(t, "1.0.0", rs)
+
+	exe := filepath.Join(t.TempDir(), "boatman")
+	os.WriteFile(exe, []byte("old build"), 0755)
+	u.executable = func() (string, error) { return exe, nil }
+
+	info, _ := u.Check(context.Background(), ChannelStable)
+	pending, err := u.Download(context.Background(), info)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	os.WriteFile(pending.Path, []byte("tampered"), 0755)
+
+	if _, err := u.Install(); err == nil {
+		t.Error("Expected tampered download to be rejected")
+	}
+	data, _ := os.ReadFile(exe)
+	if string(data) != "old build" {
+		t.Errorf("Expected executable to be untouched, got %q", data)
+	}
+}
+
+func TestExtractZipRejectsTraversal(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "evil.zip")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	entry, _ := w.Create("../escape.txt")
+	entry.Write([]byte("x"))
+	w.Close()
+	f.Close()
+
+	dest := t.TempDir()
+	if err := extractZip(archive, dest); err == nil {
+		t.Error("Expected error for path traversal")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dest), "escape.txt")); !os.IsNotExist(err) {
+		t.Error("Expected no file outside the destination")
+	}
+}
+
+func TestExtractZipSymlinks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symlinks need privileges on Windows")
+	}
+
+	// Links inside the archive, like a framework's Versions/Current, are kept
+	dest := t.TempDir()
+	archive := writeZip(t,
+		zipEntry{name: "Boatman.app/Versions/A/lib", data: "lib"},
+		zipEntry{name: "Boatman.app/Versions/Current", data: "A", link: true},
+	)
+	if err := extractZip(archive, dest); err != nil {
+		t.Fatalf("extractZip failed: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(dest, "Boatman.app", "Versions", "Current", "lib")); err != nil || string(data) != "lib" {
+		t.Errorf("Expected the link to be extracted, got %q (%v)", data, err)
+	}
+
+	tests := []struct {
+		name    string
+		entries []zipEntry
+	}{
+		{"link out of the archive", []zipEntry{{name: "escape", data: "../outside", link: true}}},
+		{"absolute link", []zipEntry{{name: "escape", data: "/etc", link: true}}},
+		{"writing through a link", []zipEntry{
+			{name: "sub/x", data: "x"},
+			{name: "dir", data: "sub", link: true},
+			{name: "dir/y", data: "y"},
+		}},
+		{"link through a link", []zipEntry{
+			{name: "x/y/up", data: "../..", link: true},
+			{name: "escape", data: "x/y/up/..", link: true},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "staging")
+			if err := extractZip(writeZip(t, tt.entries...), dest); err == nil {
+				t.Error("Expected the archive to be rejected")
+			}
+			if _, err := os.Stat(filepath.Join(dest, "sub", "y")); !os.IsNotExist(err) {
+				t.Error("Expected nothing written through the link")
+			}
+		})
+	}
+}
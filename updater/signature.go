@@ -0,0 +1,53 @@
+package updater
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+// signatureAsset is the release asset holding the base64 ed25519 signature
+// of checksumsAsset
+const signatureAsset = checksumsAsset + ".sig"
+
+// releasePublicKey is the base64 ed25519 key release checksums are signed
+// with, set at build time with
+// -ldflags "-X boatman/updater.releasePublicKey=..."
+var releasePublicKey = ""
+
+// verifyChecksumsSignature checks that signature, the contents of a
+// release's signatureAsset, is publicKey's signature of checksums
+func verifyChecksumsSignature(publicKey string, checksums []byte, signature string) error {
+	if publicKey == "" {
+		return fmt.Errorf("this build has no release signing key; refusing to install an unverified build")
+	}
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return fmt.Errorf("this build's release signing key is invalid")
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(signature))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return fmt.Errorf("release %s is malformed", signatureAsset)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(key), checksums, sig) {
+		return fmt.Errorf("release %s is not signed by the release key; refusing to install", checksumsAsset)
+	}
+	return nil
+}
+
+// verifyPending re-checks a pending update against the signed checksums it
+// was downloaded with
+func verifyPending(publicKey string, pending *PendingUpdate) error {
+	if err := verifyChecksumsSignature(publicKey, []byte(pending.Checksums), pending.Signature); err != nil {
+		return err
+	}
+	expected, err := checksumFor([]byte(pending.Checksums), pending.AssetName)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(expected, pending.SHA256) {
+		return fmt.Errorf("downloaded update is not the signed build")
+	}
+	return nil
+}
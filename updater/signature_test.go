@@ -0,0 +1,140 @@
+package updater
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDownloadRequiresSignedChecksums(t *testing.T) {
+	rs := newReleaseServer(t)
+	rs.addRelease("v1.1.0", false, []byte("new build"))
+	u := newTestUpdater(t, "1.0.0", rs)
+
+	info, err := u.Check(context.Background(), ChannelStable)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if info.SignatureURL == "" {
+		t.Fatalf("Expected a signature URL, got %+v", info)
+	}
+
+	// Signed with another key
+	other, _, _ := ed25519.GenerateKey(nil)
+	u.publicKey = base64.StdEncoding.EncodeToString(other)
+	if _, err := u.Download(context.Background(), info); err == nil || !strings.Contains(err.Error(), "not signed") {
+		t.Errorf("Expected a signature error, got %v", err)
+	}
+
+	// A build without the release key can't verify anything
+	u.publicKey = ""
+	if _, err := u.Download(context.Background(), info); err == nil {
+		t.Error("Expected an error without a release key")
+	}
+
+	u.publicKey = rs.publicKey()
+	info.SignatureURL = ""
+	if _, err := u.Download(context.Background(), info); err == nil {
+		t.Error("Expected an error for a release without a signature")
+	}
+	if p, _ := u.Pending(); p != nil {
+		t.Errorf("Expected nothing pending, got %+v", p)
+	}
+}
+
+func TestInstallRejectsUnsignedPending(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("binary swap test runs on Unix")
+	}
+
+	rs := newReleaseServer(t)
+	rs.addRelease("v1.1.0", false, []byte("new build"))
+	u := newTestUpdater(t, "1.0.0", rs)
+
+	exe := filepath.Join(t.TempDir(), "boatman")
+	os.WriteFile(exe, []byte("old build"), 0755)
+	u.executable = func() (string, error) { return exe, nil }
+
+	info, _ := u.Check(context.Background(), ChannelStable)
+	pending, err := u.Download(context.Background(), info)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	// Swap in another build, recording its checksum as if it were the signed one
+	os.WriteFile(pending.Path, []byte("tampered"), 0755)
+	pending.SHA256, _ = fileSHA256(pending.Path)
+	data, _ := json.Marshal(pending)
+	os.WriteFile(u.pendingPath(), data, 0644)
+
+	if _, err := u.Install(); err == nil {
+		t.Error("Expected a build not in the signed checksums to be rejected")
+	}
+	if data, _ := os.ReadFile(exe); string(data) != "old build" {
+		t.Errorf("Expected executable to be untouched, got %q", data)
+	}
+}
+
+// fakeCodesign stands in for codesign, treating bundles in signers as signed
+// with that designated requirement and everything else as unsigned
+func fakeCodesign(t *testing.T, signers map[string]string) *[][]string {
+	t.Helper()
+	var calls [][]string
+	original := codesign
+	codesign = func(args ...string) ([]byte, error) {
+		calls = append(calls, args)
+		path := args[len(args)-1]
+		requirement, signed := signers[path]
+		if !signed {
+			return []byte(path + ": code object is not signed at all"), errors.New("exit status 1")
+		}
+		if args[0] == "--display" {
+			return []byte("Executable=" + path + "\ndesignated => " + requirement + "\n"), nil
+		}
+		for _, arg := range args {
+			if want, ok := strings.CutPrefix(arg, "-R="); ok && want != requirement {
+				return []byte(path + ": test-requirement: code failed to satisfy specified code requirement(s)"), errors.New("exit status 3")
+			}
+		}
+		return nil, nil
+	}
+	t.Cleanup(func() { codesign = original })
+	return &calls
+}
+
+func TestVerifyCodeSignature(t *testing.T) {
+	const teamRequirement = `anchor apple generic and certificate leaf[subject.OU] = "TEAM123"`
+	calls := fakeCodesign(t, map[string]string{
+		"/Applications/Boatman.app": teamRequirement,
+		"/tmp/same-team.app":        teamRequirement,
+		"/tmp/other-team.app":       `anchor apple generic and certificate leaf[subject.OU] = "OTHER"`,
+		"/tmp/adhoc.app":            `cdhash H"0123abcd"`,
+	})
+
+	if err := verifyCodeSignature("/Applications/Boatman.app", "/tmp/same-team.app"); err != nil {
+		t.Errorf("Expected a build from the same signer to pass, got %v", err)
+	}
+	last := (*calls)[len(*calls)-1]
+	if !slices.Contains(last, "-R="+teamRequirement) {
+		t.Errorf("Expected the update checked against the running app's requirement, got %q", last)
+	}
+
+	for _, replacement := range []string{"/tmp/other-team.app", "/tmp/adhoc.app", "/tmp/unsigned.app"} {
+		if err := verifyCodeSignature("/Applications/Boatman.app", replacement); err == nil {
+			t.Errorf("Expected %s to be rejected", replacement)
+		}
+	}
+
+	// Without a signed app to compare against nothing can be verified
+	if err := verifyCodeSignature("/tmp/unsigned.app", "/tmp/same-team.app"); err == nil {
+		t.Error("Expected an error when the running app is unsigned")
+	}
+}
@@ -0,0 +1,409 @@
+package updater
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Channel selects which releases are offered as updates
+type Channel string
+
+const (
+	ChannelStable Channel = "stable"
+	ChannelBeta   Channel = "beta"
+)
+
+// DefaultRepository is the GitHub repository releases are published to
+const DefaultRepository = "philjestin/boatmanapp"
+
+// checksumsAsset is the release asset listing SHA-256 sums of the other assets
+const checksumsAsset = "checksums.txt"
+
+// ErrDevelopmentBuild is returned when the running build has no release version to compare
+var ErrDevelopmentBuild = errors.New("development builds are not updated automatically")
+
+// UpdateInfo describes the result of an update check
+type UpdateInfo struct {
+	CurrentVersion  string    `json:"currentVersion"`
+	LatestVersion   string    `json:"latestVersion"`
+	UpdateAvailable bool      `json:"updateAvailable"`
+	Channel         Channel   `json:"channel"`
+	ReleaseName     string    `json:"releaseName,omitempty"`
+	ReleaseNotes    string    `json:"releaseNotes,omitempty"`
+	ReleaseURL      string    `json:"releaseUrl,omitempty"`
+	PublishedAt     time.Time `json:"publishedAt,omitempty"`
+	Prerelease      bool      `json:"prerelease"`
+	AssetName       string    `json:"assetName,omitempty"`
+	AssetURL        string    `json:"assetUrl,omitempty"`
+	ChecksumsURL    string    `json:"checksumsUrl,omitempty"`
+	SignatureURL    string    `json:"signatureUrl,omitempty"`
+}
+
+// PendingUpdate is a downloaded and verified update waiting to be installed
+type PendingUpdate struct {
+	Version      string    `json:"version"`
+	Path         string    `json:"path"`
+	AssetName    string    `json:"assetName"`
+	SHA256       string    `json:"sha256"`
+	DownloadedAt time.Time `json:"downloadedAt"`
+	// Checksums and Signature are the release files the download was
+	// verified against, kept to check it again before it's installed
+	Checksums string `json:"checksums,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// githubRelease is the subset of the GitHub releases API response we use
+type githubRelease struct {
+	TagName     string        `json:"tag_name"`
+	Name        string        `json:"name"`
+	Body        string        `json:"body"`
+	HTMLURL     string        `json:"html_url"`
+	Draft       bool          `json:"draft"`
+	Prerelease  bool          `json:"prerelease"`
+	PublishedAt time.Time     `json:"published_at"`
+	Assets      []githubAsset `json:"assets"`
+}
+
+type githubAsset struct {
+	Name               string `json:"name"`
+	BrowserDownloadURL string `json:"browser_download_url"`
+}
+
+// Updater checks GitHub releases for new builds and installs them
+type Updater struct {
+	currentVersion string
+	repository     string
+	apiBase        string
+	dir            string
+	client         *http.Client
+	// publicKey verifies release checksums; see releasePublicKey
+	publicKey string
+
+	// executable locates the running binary; replaced in tests
+	executable func() (string, error)
+}
+
+// New creates an updater for the running version, staging downloads in ~/.boatman/updates
+func New(currentVersion string) (*Updater, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Updater{
+		currentVersion: currentVersion,
+		repository:     DefaultRepository,
+		apiBase:        "https://api.github.com",
+		dir:            filepath.Join(homeDir, ".boatman", "updates"),
+		client:         &http.Client{Timeout: 10 * time.Minute},
+		publicKey:      releasePublicKey,
+		executable:     os.Executable,
+	}, nil
+}
+
+// CurrentVersion returns the version of the running build
+func (u *Updater) CurrentVersion() string {
+	return u.currentVersion
+}
+
+// Check compares the running version against the newest release on the channel
+func (u *Updater) Check(ctx context.Context, channel Channel) (*UpdateInfo, error) {
+	if channel == "" {
+		channel = ChannelStable
+	}
+	if channel != ChannelStable && channel != ChannelBeta {
+		return nil, fmt.Errorf("unknown update channel %q", channel)
+	}
+
+	current, err := parseVersion(u.currentVersion)
+	if err != nil {
+		return nil, ErrDevelopmentBuild
+	}
+
+	releases, err := u.fetchReleases(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &UpdateInfo{
+		CurrentVersion: u.currentVersion,
+		LatestVersion:  u.currentVersion,
+		Channel:        channel,
+	}
+
+	var latest *githubRelease
+	var latestVersion version
+	for i := range releases {
+		release := &releases[i]
+		if release.Draft || (release.Prerelease && channel != ChannelBeta) {
+			continue
+		}
+		v, err := parseVersion(release.TagName)
+		if err != nil {
+			continue
+		}
+		// Only offer releases that ship a build for this platform
+		if _, ok := platformAsset(release.Assets, runtime.GOOS, runtime.GOARCH); !ok {
+			continue
+		}
+		if latest == nil || v.compare(latestVersion) > 0 {
+			latest, latestVersion = release, v
+		}
+	}
+
+	if latest == nil || latestVersion.compare(current) <= 0 {
+		return info, nil
+	}
+
+	asset, _ := platformAsset(latest.Assets, runtime.GOOS, runtime.GOARCH)
+	info.LatestVersion = strings.TrimPrefix(latest.TagName, "v")
+	info.UpdateAvailable = true
+	info.ReleaseName = latest.Name
+	info.ReleaseNotes = latest.Body
+	info.ReleaseURL = latest.HTMLURL
+	info.PublishedAt = latest.PublishedAt
+	info.Prerelease = latest.Prerelease
+	info.AssetName = asset.Name
+	info.AssetURL = asset.BrowserDownloadURL
+	for _, a := range latest.Assets {
+		switch a.Name {
+		case checksumsAsset:
+			info.ChecksumsURL = a.BrowserDownloadURL
+		case signatureAsset:
+			info.SignatureURL = a.BrowserDownloadURL
+		}
+	}
+	return info, nil
+}
+
+// fetchReleases lists the repository's releases, newest first
+func (u *Updater) fetchReleases(ctx context.Context) ([]githubRelease, error) {
+	url := fmt.Sprintf("%s/repos/%s/releases?per_page=30", u.apiBase, u.repository)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for updates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("failed to check for updates: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var releases []githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, fmt.Errorf("failed to parse releases: %w", err)
+	}
+	return releases, nil
+}
+
+// platformAsset finds the release asset built for goos/goarch. Assets are named
+// boatman-<os>-<arch> with an optional extension; macOS builds may be universal.
+func platformAsset(assets []githubAsset, goos, goarch string) (githubAsset, bool) {
+	candidates := []string{fmt.Sprintf("boatman-%s-%s", goos, goarch)}
+	if goos == "darwin" {
+		candidates = append(candidates, "boatman-darwin-universal")
+	}
+
+	for _, prefix := range candidates {
+		for _, asset := range assets {
+			name := strings.ToLower(asset.Name)
+			if name == prefix || strings.HasPrefix(name, prefix+".") {
+				return asset, true
+			}
+		}
+	}
+	return githubAsset{}, false
+}
+
+// Download fetches the update's build, verifies it against the release
+// checksums, which must be signed with the release key, and records it as
+// pending so it is installed on restart.
+func (u *Updater) Download(ctx context.Context, info *UpdateInfo) (*PendingUpdate, error) {
+	if info == nil || !info.UpdateAvailable {
+		return nil, fmt.Errorf("no update available")
+	}
+	if info.ChecksumsURL == "" {
+		return nil, fmt.Errorf("release %s has no %s; refusing to install an unverified build", info.LatestVersion, checksumsAsset)
+	}
+
+	checksums, err := u.fetchAsset(ctx, info.ChecksumsURL, "checksums")
+	if err != nil {
+		return nil, err
+	}
+	if info.SignatureURL == "" {
+		return nil, fmt.Errorf("release %s has no %s; refusing to install an unverified build", info.LatestVersion, signatureAsset)
+	}
+	signature, err := u.fetchAsset(ctx, info.SignatureURL, "checksums signature")
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyChecksumsSignature(u.publicKey, checksums, string(signature)); err != nil {
+		return nil, err
+	}
+	expected, err := checksumFor(checksums, info.AssetName)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(u.dir, 0755); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(u.dir, info.AssetName)
+	actual, err := u.downloadFile(ctx, info.AssetURL, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.EqualFold(actual, expected) {
+		os.Remove(path)
+		return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", info.AssetName, expected, actual)
+	}
+
+	pending := &PendingUpdate{
+		Version:      info.LatestVersion,
+		Path:         path,
+		AssetName:    info.AssetName,
+		SHA256:       actual,
+		DownloadedAt: time.Now(),
+		Checksums:    string(checksums),
+		Signature:    string(signature),
+	}
+	data, err := json.MarshalIndent(pending, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(u.pendingPath(), data, 0644); err != nil {
+		return nil, err
+	}
+	return pending, nil
+}
+
+// fetchAsset downloads a small release asset, like the checksums file,
+// naming it what in errors
+func (u *Updater) fetchAsset(ctx context.Context, url, what string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", what, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", what, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", what, err)
+	}
+	return data, nil
+}
+
+// checksumFor reads the SHA-256 for assetName from a sha256sum-style checksums file
+func checksumFor(checksums []byte, assetName string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == assetName {
+			return fields[0], nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", fmt.Errorf("no checksum listed for %s", assetName)
+}
+
+// downloadFile saves url to path and returns the file's SHA-256
+func (u *Updater) downloadFile(ctx context.Context, url, path string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to download update: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to download update: %s", resp.Status)
+	}
+
+	tmp := path + ".part"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(f, hash), resp.Body); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to download update: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// pendingPath is where the pending update record is stored
+func (u *Updater) pendingPath() string {
+	return filepath.Join(u.dir, "pending.json")
+}
+
+// Pending returns the downloaded update waiting to be installed, or nil if there is none
+func (u *Updater) Pending() (*PendingUpdate, error) {
+	data, err := os.ReadFile(u.pendingPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var pending PendingUpdate
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return nil, err
+	}
+	return &pending, nil
+}
+
+// ClearPending discards a downloaded update
+func (u *Updater) ClearPending() error {
+	pending, err := u.Pending()
+	if err != nil || pending == nil {
+		return err
+	}
+	os.Remove(pending.Path)
+	if err := os.Remove(u.pendingPath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package updater
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// releaseServer serves a fake GitHub releases API with one build per release
+type releaseServer struct {
+	*httptest.Server
+	releases []githubRelease
+	builds   map[string][]byte
+	sums     map[string]string
+	key      ed25519.PrivateKey // Signs the checksums
+}
+
+func newReleaseServer(t *testing.T) *releaseServer {
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs := &releaseServer{builds: map[string][]byte{}, sums: map[string]string{}, key: key}
+	rs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/releases"):
+			json.NewEncoder(w).Encode(rs.releases)
+		case strings.HasSuffix(r.URL.Path, "/"+checksumsAsset):
+			w.Write(rs.checksums())
+		case strings.HasSuffix(r.URL.Path, "/"+signatureAsset):
+			fmt.Fprintln(w, base64.StdEncoding.EncodeToString(ed25519.Sign(rs.key, rs.checksums())))
+		default:
+			data, ok := rs.builds[filepath.Base(r.URL.Path)]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(data)
+		}
+	}))
+	t.Cleanup(rs.Close)
+	return rs
+}
+
+// checksums returns the release checksums file
+func (rs *releaseServer) checksums() []byte {
+	var lines []string
+	for name, sum := range rs.sums {
+		lines = append(lines, fmt.Sprintf("%s  %s\n", sum, name))
+	}
+	sort.Strings(lines)
+	return []byte(strings.Join(lines, ""))
+}
+
+// publicKey returns the base64 key the checksums are signed with
+func (rs *releaseServer) publicKey() string {
+	return base64.StdEncoding.EncodeToString(rs.key.Public().(ed25519.PublicKey))
+}
+
+// addRelease publishes a release with a build for the current platform
+func (rs *releaseServer) addRelease(tag string, prerelease bool, build []byte) {
+	name := fmt.Sprintf("boatman-%s-%s", runtime.GOOS, runtime.GOARCH)
+	assetName := tag + "-" + name
+	sum := sha256.Sum256(build)
+	rs.builds[assetName] = build
+	rs.sums[name] = hex.EncodeToString(sum[:])
+
+	rs.releases = append(rs.releases, githubRelease{
+		TagName:    tag,
+		Name:       "Boatman " + tag,
+		Prerelease: prerelease,
+		Assets: []githubAsset{
+			{Name: name, BrowserDownloadURL: rs.URL + "/download/" + assetName},
+			{Name: checksumsAsset, BrowserDownloadURL: rs.URL + "/download/" + tag + "/" + checksumsAsset},
+			{Name: signatureAsset, BrowserDownloadURL: rs.URL + "/download/" + tag + "/" + signatureAsset},
+		},
+	})
+}
+
+func newTestUpdater(t *testing.T, current string, rs *releaseServer) *Updater {
+	return &Updater{
+		currentVersion: current,
+		repository:     DefaultRepository,
+		apiBase:        rs.URL,
+		dir:            t.TempDir(),
+		client:         rs.Client(),
+		publicKey:      rs.publicKey(),
+		executable:     os.Executable,
+	}
+}
+
+func TestCheckChannels(t *testing.T) {
+	rs := newReleaseServer(t)
+	rs.addRelease("v1.1.0", false, []byte("stable build"))
+	rs.addRelease("v1.2.0-beta.1", true, []byte("beta build"))
+	rs.releases = append(rs.releases, githubRelease{TagName: "v9.0.0", Draft: true})
+	rs.releases = append(rs.releases, githubRelease{TagName: "v5.0.0"}) // No build for this platform
+
+	u := newTestUpdater(t, "1.0.0", rs)
+
+	info, err := u.Check(context.Background(), ChannelStable)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if !info.UpdateAvailable || info.LatestVersion != "1.1.0" {
+		t.Errorf("Expected stable update to 1.1.0, got %+v", info)
+	}
+	if info.ChecksumsURL == "" || info.AssetURL == "" {
+		t.Errorf("Expected asset and checksums URLs, got %+v", info)
+	}
+
+	info, err = u.Check(context.Background(), ChannelBeta)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if !info.UpdateAvailable || info.LatestVersion != "1.2.0-beta.1" || !info.Prerelease {
+		t.Errorf("Expected beta update to 1.2.0-beta.1, got %+v", info)
+	}
+
+	// Already up to date
+	u.currentVersion = "1.1.0"
+	info, err = u.Check(context.Background(), ChannelStable)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if info.UpdateAvailable {
+		t.Errorf("Expected no update, got %+v", info)
+	}
+
+	if _, err := u.Check(context.Background(), Channel("nightly")); err == nil {
+		t.Error("Expected error for unknown channel")
+	}
+
+	u.currentVersion = "dev"
+	if _, err := u.Check(context.Background(), ChannelStable); err != ErrDevelopmentBuild {
+		t.Errorf("Expected ErrDevelopmentBuild, got %v", err)
+	}
+}
+
+func TestDownloadVerifiesChecksum(t *testing.T) {
+	rs := newReleaseServer(t)
+	rs.addRelease("v1.1.0", false, []byte("new build"))
+	u := newTestUpdater(t, "1.0.0", rs)
+
+	info, err := u.Check(context.Background(), ChannelStable)
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+
+	pending, err := u.Download(context.Background(), info)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	data, err := os.ReadFile(pending.Path)
+	if err != nil || string(data) != "new build" {
+		t.Errorf("Expected downloaded build, got %q (%v)", data, err)
+	}
+
+	stored, err := u.Pending()
+	if err != nil || stored == nil || stored.Version != "1.1.0" {
+		t.Errorf("Expected pending update 1.1.0, got %+v (%v)", stored, err)
+	}
+
+	// A tampered build is rejected
+	for name := range rs.sums {
+		rs.sums[name] = strings.Repeat("0", 64)
+	}
+	if _, err := u.Download(context.Background(), info); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Expected checksum mismatch, got %v", err)
+	}
+
+	// Releases without checksums are refused
+	info.ChecksumsURL = ""
+	if _, err := u.Download(context.Background(), info); err == nil {
+		t.Error("Expected error for release without checksums")
+	}
+}
+
+func TestClearPending(t *testing.T) {
+	rs := newReleaseServer(t)
+	rs.addRelease("v1.1.0", false, []byte("new build"))
+	u := newTestUpdater(t, "1.0.0", rs)
+
+	info, _ := u.Check(context.Background(), ChannelStable)
+	pending, err := u.Download(context.Background(), info)
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+
+	if err := u.ClearPending(); err != nil {
+		t.Fatalf("ClearPending failed: %v", err)
+	}
+	if p, _ := u.Pending(); p != nil {
+		t.Errorf("Expected no pending update, got %+v", p)
+	}
+	if _, err := os.Stat(pending.Path); !os.IsNotExist(err) {
+		t.Error("Expected downloaded build to be removed")
+	}
+}
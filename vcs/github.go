@@ -0,0 +1,56 @@
+package vcs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+// GitHub opens pull requests through the GitHub REST API
+type GitHub struct {
+	apiBase string
+	owner   string
+	repo    string
+	token   string
+	client  *http.Client
+}
+
+// githubPullRequest is the subset of the GitHub pulls API response we use
+type githubPullRequest struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+	Title   string `json:"title"`
+}
+
+// Name implements Provider
+func (g *GitHub) Name() string {
+	return "github"
+}
+
+// CreatePullRequest implements Provider
+func (g *GitHub) CreatePullRequest(ctx context.Context, opts PullRequestOptions) (*PullRequest, error) {
+	url := fmt.Sprintf("%s/repos/%s/%s/pulls", g.apiBase, g.owner, g.repo)
+	headers := map[string]string{
+		"Accept":        "application/vnd.github+json",
+		"Authorization": "Bearer " + g.token,
+	}
+	payload := map[string]interface{}{
+		"title": opts.Title,
+		"body":  opts.Body,
+		"head":  opts.Head,
+		"base":  opts.Base,
+		"draft": opts.Draft,
+	}
+
+	var created githubPullRequest
+	if err := postJSON(ctx, g.client, url, headers, payload, &created); err != nil {
+		return nil, fmt.Errorf("failed to create pull request: %w", err)
+	}
+	return &PullRequest{
+		Number: created.Number,
+		URL:    created.HTMLURL,
+		Title:  created.Title,
+		Head:   opts.Head,
+		Base:   opts.Base,
+	}, nil
+}
@@ -0,0 +1,57 @@
+package vcs
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// GitLab opens merge requests through the GitLab REST API
+type GitLab struct {
+	apiBase string
+	project string // Full path, e.g. "group/subgroup/project"
+	token   string
+	client  *http.Client
+}
+
+// gitlabMergeRequest is the subset of the GitLab merge requests API response we use
+type gitlabMergeRequest struct {
+	IID    int    `json:"iid"`
+	WebURL string `json:"web_url"`
+	Title  string `json:"title"`
+}
+
+// Name implements Provider
+func (g *GitLab) Name() string {
+	return "gitlab"
+}
+
+// CreatePullRequest implements Provider, opening a merge request
+func (g *GitLab) CreatePullRequest(ctx context.Context, opts PullRequestOptions) (*PullRequest, error) {
+	endpoint := fmt.Sprintf("%s/projects/%s/merge_requests", g.apiBase, url.PathEscape(g.project))
+	headers := map[string]string{"PRIVATE-TOKEN": g.token}
+
+	title := opts.Title
+	if opts.Draft {
+		title = "Draft: " + title
+	}
+	payload := map[string]interface{}{
+		"title":         title,
+		"description":   opts.Body,
+		"source_branch": opts.Head,
+		"target_branch": opts.Base,
+	}
+
+	var created gitlabMergeRequest
+	if err := postJSON(ctx, g.client, endpoint, headers, payload, &created); err != nil {
+		return nil, fmt.Errorf("failed to create merge request: %w", err)
+	}
+	return &PullRequest{
+		Number: created.IID,
+		URL:    created.WebURL,
+		Title:  created.Title,
+		Head:   opts.Head,
+		Base:   opts.Base,
+	}, nil
+}
@@ -0,0 +1,109 @@
+package vcs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"boatman/git"
+)
+
+// ErrNoToken is returned when the code host's API token isn't configured
+var ErrNoToken = errors.New("no API token configured for this code host")
+
+// PullRequestOptions describes a pull request to open
+type PullRequestOptions struct {
+	Title string
+	Body  string
+	Head  string // Branch with the changes
+	Base  string // Branch to merge into
+	Draft bool
+}
+
+// PullRequest is an opened pull request, or merge request on GitLab
+type PullRequest struct {
+	Number int    `json:"number"`
+	URL    string `json:"url"`
+	Title  string `json:"title"`
+	Head   string `json:"head"`
+	Base   string `json:"base"`
+}
+
+// Provider opens pull requests on a code host
+type Provider interface {
+	// Name identifies the code host, e.g. "github"
+	Name() string
+	CreatePullRequest(ctx context.Context, opts PullRequestOptions) (*PullRequest, error)
+}
+
+// Tokens are the API tokens for each kind of code host
+type Tokens struct {
+	GitHub string
+	GitLab string
+}
+
+// requestTimeout bounds a call to a code host's API
+const requestTimeout = 30 * time.Second
+
+// NewProvider picks the provider for a repository's remote. Hosts other than
+// github.com and gitlab.com are recognized by name, e.g. "github.example.com"
+// for GitHub Enterprise.
+func NewProvider(remote git.RemoteInfo, tokens Tokens) (Provider, error) {
+	if remote.Slug() == "" {
+		return nil, fmt.Errorf("remote %q has no owner/repo", remote.URL)
+	}
+	client := &http.Client{Timeout: requestTimeout}
+
+	switch {
+	case strings.Contains(remote.Host, "github"):
+		if tokens.GitHub == "" {
+			return nil, fmt.Errorf("%w: add a GitHub token in settings", ErrNoToken)
+		}
+		apiBase := "https://api.github.com"
+		if remote.Host != "github.com" {
+			apiBase = "https://" + remote.Host + "/api/v3"
+		}
+		return &GitHub{apiBase: apiBase, owner: remote.Owner, repo: remote.Repo, token: tokens.GitHub, client: client}, nil
+	case strings.Contains(remote.Host, "gitlab"):
+		if tokens.GitLab == "" {
+			return nil, fmt.Errorf("%w: add a GitLab token in settings", ErrNoToken)
+		}
+		return &GitLab{apiBase: "https://" + remote.Host + "/api/v4", project: remote.Slug(), token: tokens.GitLab, client: client}, nil
+	default:
+		return nil, fmt.Errorf("unsupported code host: %s", remote.Host)
+	}
+}
+
+// postJSON sends a JSON request and decodes a successful response into out
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, payload, out interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,154 @@
+package vcs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"boatman/git"
+)
+
+// apiServer records the request it receives and replies with a fixed response
+type apiServer struct {
+	*httptest.Server
+	path    string
+	headers http.Header
+	payload map[string]interface{}
+}
+
+func newAPIServer(t *testing.T, status int, response string) *apiServer {
+	s := &apiServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.path = r.URL.EscapedPath()
+		s.headers = r.Header.Clone()
+		json.NewDecoder(r.Body).Decode(&s.payload)
+		w.WriteHeader(status)
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestNewProvider(t *testing.T) {
+	tokens := Tokens{GitHub: "gh-token", GitLab: "gl-token"}
+	tests := []struct {
+		remote  string
+		tokens  Tokens
+		name    string
+		apiBase string
+		wantErr error
+	}{
+		{"git@github.com:philjestin/boatmanapp.git", tokens, "github", "https://api.github.com", nil},
+		{"https://github.example.com/team/app.git", tokens, "github", "https://github.example.com/api/v3", nil},
+		{"https://gitlab.com/group/sub/project.git", tokens, "gitlab", "https://gitlab.com/api/v4", nil},
+		{"git@github.com:philjestin/boatmanapp.git", Tokens{GitLab: "gl-token"}, "", "", ErrNoToken},
+		{"https://bitbucket.org/team/app.git", tokens, "", "", errors.New("unsupported")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.remote, func(t *testing.T) {
+			remote, err := git.ParseRemoteURL(tt.remote)
+			if err != nil {
+				t.Fatalf("ParseRemoteURL failed: %v", err)
+			}
+			provider, err := NewProvider(remote, tt.tokens)
+			if tt.wantErr != nil {
+				if err == nil {
+					t.Fatal("Expected an error")
+				}
+				if errors.Is(tt.wantErr, ErrNoToken) && !errors.Is(err, ErrNoToken) {
+					t.Errorf("Expected ErrNoToken, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewProvider failed: %v", err)
+			}
+			if provider.Name() != tt.name {
+				t.Errorf("Expected %s, got %s", tt.name, provider.Name())
+			}
+
+			var apiBase string
+			switch p := provider.(type) {
+			case *GitHub:
+				apiBase = p.apiBase
+			case *GitLab:
+				apiBase = p.apiBase
+			}
+			if apiBase != tt.apiBase {
+				t.Errorf("Expected API %s, got %s", tt.apiBase, apiBase)
+			}
+		})
+	}
+}
+
+func TestGitHubCreatePullRequest(t *testing.T) {
+	server := newAPIServer(t, http.StatusCreated, `{"number":42,"html_url":"https://github.com/o/r/pull/42","title":"Add export"}`)
+	github := &GitHub{apiBase: server.URL, owner: "o", repo: "r", token: "secret", client: server.Client()}
+
+	pr, err := github.CreatePullRequest(context.Background(), PullRequestOptions{
+		Title: "Add export",
+		Body:  "Adds session export",
+		Head:  "feature/export",
+		Base:  "main",
+	})
+	if err != nil {
+		t.Fatalf("CreatePullRequest failed: %v", err)
+	}
+	if pr.Number != 42 || pr.URL != "https://github.com/o/r/pull/42" || pr.Base != "main" {
+		t.Errorf("Unexpected pull request: %+v", pr)
+	}
+	if server.path != "/repos/o/r/pulls" {
+		t.Errorf("Expected the pulls endpoint, got %s", server.path)
+	}
+	if server.headers.Get("Authorization") != "Bearer secret" {
+		t.Errorf("Expected the token, got %q", server.headers.Get("Authorization"))
+	}
+	if server.payload["head"] != "feature/export" || server.payload["body"] != "Adds session export" {
+		t.Errorf("Unexpected payload: %v", server.payload)
+	}
+}
+
+func TestGitLabCreateMergeRequest(t *testing.T) {
+	server := newAPIServer(t, http.StatusCreated, `{"iid":7,"web_url":"https://gitlab.com/g/s/p/-/merge_requests/7","title":"Draft: Fix login"}`)
+	gitlab := &GitLab{apiBase: server.URL, project: "g/s/p", token: "secret", client: server.Client()}
+
+	mr, err := gitlab.CreatePullRequest(context.Background(), PullRequestOptions{
+		Title: "Fix login",
+		Head:  "fix/login",
+		Base:  "main",
+		Draft: true,
+	})
+	if err != nil {
+		t.Fatalf("CreatePullRequest failed: %v", err)
+	}
+	if mr.Number != 7 || mr.Title != "Draft: Fix login" {
+		t.Errorf("Unexpected merge request: %+v", mr)
+	}
+	if server.path != "/projects/g%2Fs%2Fp/merge_requests" {
+		t.Errorf("Expected the escaped project path, got %s", server.path)
+	}
+	if server.headers.Get("PRIVATE-TOKEN") != "secret" {
+		t.Errorf("Expected the token, got %q", server.headers.Get("PRIVATE-TOKEN"))
+	}
+	if server.payload["source_branch"] != "fix/login" || server.payload["target_branch"] != "main" {
+		t.Errorf("Unexpected payload: %v", server.payload)
+	}
+}
+
+func TestCreatePullRequestError(t *testing.T) {
+	server := newAPIServer(t, http.StatusUnprocessableEntity, `{"message":"A pull request already exists"}`)
+	github := &GitHub{apiBase: server.URL, owner: "o", repo: "r", token: "secret", client: server.Client()}
+
+	_, err := github.CreatePullRequest(context.Background(), PullRequestOptions{Title: "t", Head: "h", Base: "main"})
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	if want := "A pull request already exists"; !strings.Contains(err.Error(), want) {
+		t.Errorf("Expected the API's message in %q", err)
+	}
+}